@@ -1,43 +1,798 @@
 package main
 
 import (
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/alfredtm/gitops-squared/internal/api"
-	"github.com/alfredtm/gitops-squared/internal/oci"
+	"github.com/alfredtm/gitops-squared/internal/encryption"
+	"github.com/alfredtm/gitops-squared/internal/kms"
+	"github.com/alfredtm/gitops-squared/internal/signing"
+	"github.com/alfredtm/gitops-squared/pkg/admission"
+	"github.com/alfredtm/gitops-squared/pkg/api"
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/cost"
+	"github.com/alfredtm/gitops-squared/pkg/gitsource"
+	"github.com/alfredtm/gitops-squared/pkg/hooks"
+	"github.com/alfredtm/gitops-squared/pkg/images"
+	"github.com/alfredtm/gitops-squared/pkg/kube"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/notify"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+	"github.com/alfredtm/gitops-squared/pkg/secrets"
 )
 
 func main() {
+	logs, err := api.NewLogFilter(os.Stderr, os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		log.Fatalf("Configuring logging: %v", err)
+	}
+	logs.Install()
+
+	// Resource types come first: the configuration below names them.
+	if path := os.Getenv("RESOURCE_TYPES_CONFIG"); path != "" {
+		types, err := model.LoadResourceTypesConfig(path)
+		if err != nil {
+			log.Fatalf("Loading resource types: %v", err)
+		}
+		model.SetResourceTypes(types)
+	}
+
 	registryHost := envOrDefault("REGISTRY_HOST", "localhost:5000")
 	listenAddr := envOrDefault("LISTEN_ADDR", ":8080")
+	signingKeyPath := os.Getenv("CATALOG_SIGNING_KEY")
+	embeddedRegistry := os.Getenv("EMBEDDED_REGISTRY") == "true"
+
+	catalogOpts := api.CatalogOptions{
+		DeleteGracePeriod:    durationEnvOrDefault("DELETE_GRACE_PERIOD", 24*time.Hour),
+		PerResourceArtifacts: os.Getenv("PER_RESOURCE_ARTIFACTS") == "true",
+		PinResources:         os.Getenv("PIN_RESOURCE_ARTIFACTS") == "true",
+		PublishDebounce:      durationEnvOrDefault("CATALOG_PUBLISH_DEBOUNCE", 0),
+	}
+	var signingKey api.ManagedKey
+	if signingKeyPath != "" {
+		signer, err := newSigner(signingKeyPath)
+		if err != nil {
+			log.Fatalf("Loading catalog signing key: %v", err)
+		}
+		catalogOpts.Signer = signer
+		signingKey = signer
+	}
+
+	estimator, err := newCostEstimator()
+	if err != nil {
+		log.Fatalf("Configuring cost estimation: %v", err)
+	}
+	catalogOpts.CostEstimator = estimator
+
+	sharding, err := api.ParseSharding(os.Getenv("CATALOG_SHARDING"))
+	if err != nil {
+		log.Fatalf("Configuring catalog sharding: %v", err)
+	}
+	if sharding.Enabled() && catalogOpts.PinResources {
+		log.Fatalf("PIN_RESOURCE_ARTIFACTS can't be combined with CATALOG_SHARDING")
+	}
+	catalogOpts.Sharding = sharding
+
+	tagging, err := api.ParseCatalogTagging(os.Getenv("CATALOG_TAGS"), os.Getenv("CATALOG_CHANNELS"), os.Getenv("CATALOG_PUBLISH_CHANNEL"))
+	if err != nil {
+		log.Fatalf("Configuring catalog tags: %v", err)
+	}
+	catalogOpts.Tagging = tagging
+
+	if v := os.Getenv("CATALOG_GZIP_LEVEL"); v != "" {
+		level, err := strconv.Atoi(v)
+		if err != nil || level < gzip.BestSpeed || level > gzip.BestCompression {
+			log.Fatalf("Invalid CATALOG_GZIP_LEVEL %q: want %d-%d", v, gzip.BestSpeed, gzip.BestCompression)
+		}
+		catalogOpts.GzipLevel = level
+	}
+
+	if path := os.Getenv("CLUSTERS_CONFIG"); path != "" {
+		clusters, err := model.LoadClusterConfig(path)
+		if err != nil {
+			log.Fatalf("Loading cluster config: %v", err)
+		}
+		catalogOpts.Clusters = clusters.Clusters
+	}
 
-	ociClient := oci.NewClient(registryHost, "gitops-squared/resources")
-	catalog := api.NewCatalogManager(ociClient)
-	handler := api.NewHandler(ociClient, catalog)
+	if path := os.Getenv("MANIFEST_SCHEMAS_CONFIG"); path != "" {
+		schemas, err := kube.LoadSchemaValidator(path)
+		if err != nil {
+			log.Fatalf("Loading manifest schemas: %v", err)
+		}
+		log.Printf("Validating manifests against %d schemas", len(schemas.Kinds()))
+		catalogOpts.SchemaValidator = schemas
+	}
+	if path := os.Getenv("PUBLISH_HOOKS_CONFIG"); path != "" {
+		publishHooks, err := hooks.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Loading publish hooks: %v", err)
+		}
+		catalogOpts.PublishHooks = publishHooks
+	}
+
+	gitExporter, err := newGitExporter()
+	if err != nil {
+		log.Fatalf("Configuring Git export: %v", err)
+	}
+	catalogOpts.GitExporter = gitExporter
 
-	// Restore state from registry on startup.
+	storage, err := newStorage(registryHost, embeddedRegistry)
+	if err != nil {
+		log.Fatalf("Configuring storage backend: %v", err)
+	}
+	ociClient := oci.NewClientWithStorage(registryHost, "gitops-squared/resources", storage)
+	versions, err := oci.NewVersionGenerator(os.Getenv("VERSION_FORMAT"))
+	if err != nil {
+		log.Fatalf("Configuring version tags: %v", err)
+	}
+	ociClient.SetVersionGenerator(versions)
+	ociClient.SetReproducible(os.Getenv("REPRODUCIBLE_ARTIFACTS") == "true")
+	ociClient.SetOperationTimeout(durationEnvOrDefault("OCI_OPERATION_TIMEOUT", 30*time.Second))
+	discovery, err := oci.ParseRepoDiscovery(os.Getenv("REPO_DISCOVERY"))
+	if err != nil {
+		log.Fatalf("Configuring repository discovery: %v", err)
+	}
+	ociClient.SetRepoDiscovery(discovery)
+	if v := os.Getenv("ARTIFACT_FORMAT"); v != "" {
+		format, err := oci.ParseArtifactFormat(v)
+		if err != nil {
+			log.Fatalf("Configuring artifact format: %v", err)
+		}
+		ociClient.SetArtifactFormat(format)
+	}
+	if catalogOpts.Signer != nil {
+		ociClient.SetSigner(catalogOpts.Signer)
+	}
+	if ref := os.Getenv("ARTIFACT_VERIFY_KEY"); ref != "" {
+		verifier, err := newVerifier(ref)
+		if err != nil {
+			log.Fatalf("Loading artifact verification key: %v", err)
+		}
+		ociClient.SetVerifier(verifier)
+	}
+	encryptionKey, err := newDataKeys()
+	if err != nil {
+		log.Fatalf("Loading encryption keys: %v", err)
+	}
+	if encryptionKey != nil {
+		if os.Getenv("REPRODUCIBLE_ARTIFACTS") == "true" {
+			log.Fatalf("Encryption cannot be combined with REPRODUCIBLE_ARTIFACTS: encrypted artifacts never have identical digests")
+		}
+		ociClient.SetDataKeyProvider(encryptionKey)
+		log.Printf("Encrypting resource artifacts with %s key %s", encryptionKey.Backend(), encryptionKey.KeyID())
+	}
+	if v := os.Getenv("SPOOL_THRESHOLD_MB"); v != "" {
+		mb, err := strconv.Atoi(v)
+		if err != nil || mb < 0 {
+			log.Fatalf("Invalid SPOOL_THRESHOLD_MB %q: want a non-negative integer", v)
+		}
+		ociClient.SetSpoolThreshold(int64(mb) << 20)
+	}
+	if v := os.Getenv("EVENT_HISTORY_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			log.Fatalf("Invalid EVENT_HISTORY_SIZE %q: want a positive integer", v)
+		}
+		catalogOpts.Events = api.NewEventLog(ociClient, n)
+	} else {
+		catalogOpts.Events = api.NewEventLog(ociClient, 0)
+	}
+	catalog := api.NewCatalogManager(ociClient, catalogOpts)
+
+	handlerOpts := api.HandlerOptions{
+		ReadOnly:        os.Getenv("READ_ONLY") == "true",
+		ReadOnlyMessage: os.Getenv("READ_ONLY_MESSAGE"),
+		Logs:            logs,
+		Timeouts: api.RequestTimeouts{
+			Default: durationEnvOrDefault("REQUEST_TIMEOUT", 2*time.Minute),
+			Max:     durationEnvOrDefault("REQUEST_TIMEOUT_MAX", 10*time.Minute),
+		},
+		SigningKey:    signingKey,
+		EncryptionKey: encryptionKey,
+	}
+
+	if os.Getenv("ACCESS_LOG") == "true" {
+		accessLog := &api.AccessLogOptions{
+			SlowThreshold:  durationEnvOrDefault("ACCESS_LOG_SLOW", time.Second),
+			LargeThreshold: 1 << 20,
+			SampleRate:     1,
+		}
+		if v := os.Getenv("ACCESS_LOG_LARGE_BODY"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				log.Fatalf("Invalid ACCESS_LOG_LARGE_BODY %q: want a number of bytes", v)
+			}
+			accessLog.LargeThreshold = n
+		}
+		if v := os.Getenv("ACCESS_LOG_SAMPLE"); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 || f > 1 {
+				log.Fatalf("Invalid ACCESS_LOG_SAMPLE %q: want a fraction from 0 to 1", v)
+			}
+			accessLog.SampleRate = f
+		}
+		handlerOpts.AccessLog = accessLog
+	}
+	if v := os.Getenv("CORS_ALLOWED_ORIGINS"); v != "" {
+		cors := &api.CORSOptions{
+			AllowCredentials: os.Getenv("CORS_ALLOW_CREDENTIALS") == "true",
+			MaxAge:           durationEnvOrDefault("CORS_MAX_AGE", 10*time.Minute),
+		}
+		for _, origin := range strings.Split(v, ",") {
+			if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+				cors.AllowedOrigins = append(cors.AllowedOrigins, origin)
+			}
+		}
+		if cors.AllowCredentials && slices.Contains(cors.AllowedOrigins, "*") {
+			log.Fatalf("CORS_ALLOWED_ORIGINS=* can't be combined with CORS_ALLOW_CREDENTIALS; list the origins")
+		}
+		handlerOpts.CORS = cors
+	}
+	if v := os.Getenv("SESSION_SECRET"); v != "" {
+		sessions, err := auth.NewSessions([]byte(v))
+		if err != nil {
+			log.Fatalf("Invalid SESSION_SECRET: %v", err)
+		}
+		sessions.TTL = durationEnvOrDefault("SESSION_TTL", auth.DefaultSessionTTL)
+		sessions.CrossSite = handlerOpts.CORS != nil && handlerOpts.CORS.AllowCredentials
+		sessions.Insecure = os.Getenv("SESSION_COOKIE_INSECURE") == "true"
+		handlerOpts.Sessions = sessions
+	}
+	if v := os.Getenv("ADMIN_GROUPS"); v != "" {
+		for _, group := range strings.Split(v, ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				handlerOpts.AdminGroups = append(handlerOpts.AdminGroups, group)
+			}
+		}
+	}
+	if v := os.Getenv("BREAK_GLASS_GROUPS"); v != "" {
+		for _, group := range strings.Split(v, ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				handlerOpts.BreakGlassGroups = append(handlerOpts.BreakGlassGroups, group)
+			}
+		}
+	}
+	handlerOpts.UI = os.Getenv("UI_ENABLED") == "true"
+	if os.Getenv("DEBUG_ENDPOINTS") == "true" {
+		handlerOpts.Debug = true
+		if len(handlerOpts.AdminGroups) == 0 {
+			log.Printf("Warning: debug endpoints are open to every caller; set ADMIN_GROUPS to restrict them")
+		}
+	}
+	if os.Getenv("DRY_RUN_VALIDATION") == "true" {
+		kubeClient, err := newKubeClient()
+		if err != nil {
+			log.Fatalf("Configuring dry-run validation: %v", err)
+		}
+		handlerOpts.DryRunner = kubeClient
+	}
+	if os.Getenv("FLUX_STATUS") == "true" {
+		kubeClient, err := newKubeClient()
+		if err != nil {
+			log.Fatalf("Configuring flux status: %v", err)
+		}
+		handlerOpts.FluxStatus = &api.FluxStatusOptions{
+			Client:        kubeClient,
+			Source:        os.Getenv("FLUX_SOURCE_NAME"),
+			Kustomization: os.Getenv("FLUX_KUSTOMIZATION_NAME"),
+		}
+	}
+	if recipients := os.Getenv("SOPS_AGE_RECIPIENTS"); recipients != "" {
+		encryptor, err := secrets.NewSOPSEncryptor(recipients)
+		if err != nil {
+			log.Fatalf("Configuring SOPS encryption: %v", err)
+		}
+		handlerOpts.Encryptor = encryptor
+	}
+	if path := os.Getenv("SECRET_STORES_CONFIG"); path != "" {
+		stores, err := secrets.LoadStores(path)
+		if err != nil {
+			log.Fatalf("Loading secret stores: %v", err)
+		}
+		handlerOpts.SecretStores = stores
+	}
+	if path := os.Getenv("COMPANIONS_CONFIG"); path != "" {
+		companions, err := model.LoadCompanionsConfig(path)
+		if err != nil {
+			log.Fatalf("Loading companions config: %v", err)
+		}
+		handlerOpts.Companions = companions
+	}
+	if path := os.Getenv("DEFAULTS_CONFIG"); path != "" {
+		defaults, err := model.LoadDefaultsConfig(path)
+		if err != nil {
+			log.Fatalf("Loading defaults config: %v", err)
+		}
+		handlerOpts.Defaults = defaults
+	}
+	if path := os.Getenv("GIT_SOURCES_CONFIG"); path != "" {
+		sources, err := gitsource.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Loading git sources: %v", err)
+		}
+		handlerOpts.GitSources = sources
+	}
+	if path := os.Getenv("ADMISSION_WEBHOOKS_CONFIG"); path != "" {
+		webhooks, err := admission.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Loading admission webhooks: %v", err)
+		}
+		handlerOpts.Admission = webhooks
+	}
+	if path := os.Getenv("COOLDOWNS_CONFIG"); path != "" {
+		cooldowns, err := model.LoadCooldownConfig(path)
+		if err != nil {
+			log.Fatalf("Loading cooldowns: %v", err)
+		}
+		handlerOpts.Cooldowns = cooldowns
+	}
+	if path := os.Getenv("LINT_POLICY_CONFIG"); path != "" {
+		policy, err := model.LoadLintPolicy(path)
+		if err != nil {
+			log.Fatalf("Loading lint policy: %v", err)
+		}
+		handlerOpts.LintPolicy = policy
+	}
+	if path := os.Getenv("IMAGE_VERIFICATION_CONFIG"); path != "" {
+		imageConfig, err := images.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Loading image verification config: %v", err)
+		}
+		handlerOpts.Images = imageConfig
+	}
+	var notifiers *notify.Config
+	if path := os.Getenv("NOTIFICATIONS_CONFIG"); path != "" {
+		if notifiers, err = notify.LoadConfig(path); err != nil {
+			log.Fatalf("Loading notifiers: %v", err)
+		}
+	}
+	handlerOpts.Templates = api.NewTemplateStore(ociClient)
+	handlerOpts.Schedules = api.NewScheduleStore(ociClient)
+	handlerOpts.Freezes = api.NewFreezeStore(ociClient)
+	handlerOpts.Regions = api.NewRegionStore(ociClient)
+	handlerOpts.Locks = api.NewLockStore(ociClient)
+	handlerOpts.Namespaces = api.NewNamespaceStore(ociClient, catalog)
+	handlerOpts.Clusters = api.NewClusterStore(ociClient, durationEnvOrDefault("CLUSTER_HEARTBEAT_TIMEOUT", 5*time.Minute))
+	handlerOpts.Proposals = api.NewProposalStore(ociClient)
+	pullRequests, err := newPullRequests(gitExporter)
+	if err != nil {
+		log.Fatalf("Configuring proposal pull requests: %v", err)
+	}
+	handlerOpts.PullRequests = pullRequests
+	var jobOpts api.JobOptions
+	if v := os.Getenv("JOB_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			log.Fatalf("Invalid JOB_CONCURRENCY %q: want a positive integer", v)
+		}
+		jobOpts.Concurrency = n
+	}
+	if v := os.Getenv("JOB_RETENTION"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			log.Fatalf("Invalid JOB_RETENTION %q: want a positive integer", v)
+		}
+		jobOpts.Retain = n
+	}
+	handlerOpts.Jobs = api.NewJobManager(ociClient, jobOpts)
+	handlerOpts.APIKeys = api.NewAPIKeyStore(ociClient)
+	authn, err := newAuthMiddleware(handlerOpts.APIKeys, handlerOpts.Sessions, slices.Concat(handlerOpts.AdminGroups, handlerOpts.BreakGlassGroups))
+	if err != nil {
+		log.Fatalf("Configuring authentication: %v", err)
+	}
+	handlerOpts.Middleware = append(handlerOpts.Middleware, authn)
+	handler := api.NewHandler(ociClient, catalog, handlerOpts)
+
+	watch := api.WatchOptions{Interval: durationEnvOrDefault("OUT_OF_BAND_WATCH_INTERVAL", 0)}
+	switch policy := os.Getenv("OUT_OF_BAND_POLICY"); policy {
+	case "", "flag":
+	case "adopt":
+		watch.Adopt = true
+	default:
+		log.Fatalf("Configuring the out-of-band watcher: unknown OUT_OF_BAND_POLICY %q (want flag or adopt)", policy)
+	}
+
+	if soak := durationEnvOrDefault("CANARY_SOAK_PERIOD", 0); soak > 0 {
+		canary := api.CanaryOptions{
+			SoakPeriod: soak,
+			Target:     os.Getenv("CANARY_TARGET_CHANNEL"),
+			Interval:   durationEnvOrDefault("CANARY_CHECK_INTERVAL", 0),
+		}
+		if canary.Target == "" {
+			canary.Target = "stable"
+		}
+		switch {
+		case tagging.PublishChannel == "":
+			log.Fatalf("CANARY_SOAK_PERIOD needs CATALOG_PUBLISH_CHANNEL, e.g. canary")
+		case canary.Target == tagging.PublishChannel || !slices.Contains(tagging.Channels, canary.Target):
+			log.Fatalf("CANARY_TARGET_CHANNEL %q must be one of CATALOG_CHANNELS other than %q", canary.Target, tagging.PublishChannel)
+		}
+		handlerOpts.Canary = &canary
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "fsck" {
+		os.Exit(runFsck(catalog, handlerOpts.Namespaces, os.Args[2:]))
+	}
+
+	// Restore state from registry on startup. Namespaces go first so the
+	// catalog republished by catalog.Restore keeps their manifests, and
+	// events before anything that records new ones. Steps that fail, for
+	// example because the registry is not up yet, are retried in the
+	// background while changes are refused.
 	ctx := context.Background()
-	if err := catalog.Restore(ctx); err != nil {
-		log.Printf("Warning: failed to restore catalog from registry: %v", err)
-		log.Printf("Starting with empty catalog (registry may not be available yet)")
+	snapshotPath := os.Getenv("CATALOG_SNAPSHOT_PATH")
+	if snapshotPath != "" {
+		// A local snapshot serves reads at once; the registry's state
+		// replaces it as soon as the restore below succeeds.
+		if _, err := catalog.LoadSnapshot(snapshotPath); err != nil {
+			log.Printf("Warning: failed to load catalog snapshot: %v", err)
+		}
+	}
+	handler.Restore(ctx, []api.RestoreStep{
+		{Name: "events", Run: catalogOpts.Events.Restore},
+		{Name: "namespaces", Run: handlerOpts.Namespaces.Restore},
+		{Name: "catalog", Run: catalog.Restore},
+		{Name: "templates", Run: handlerOpts.Templates.Restore},
+		{Name: "schedules", Run: handlerOpts.Schedules.Restore},
+		{Name: "freeze windows", Run: handlerOpts.Freezes.Restore},
+		{Name: "regions", Run: handlerOpts.Regions.Restore},
+		{Name: "resource locks", Run: handlerOpts.Locks.Restore},
+		{Name: "cluster registrations", Run: handlerOpts.Clusters.Restore},
+		{Name: "canary hold", Run: handler.RestoreCanary},
+		{Name: "proposals", Run: handlerOpts.Proposals.Restore},
+		{Name: "API keys", Run: handlerOpts.APIKeys.Restore},
+		{Name: "jobs", Run: handlerOpts.Jobs.Restore},
+	}, api.RestoreOptions{
+		Backoff:    durationEnvOrDefault("RESTORE_RETRY_BACKOFF", 5*time.Second),
+		MaxBackoff: durationEnvOrDefault("RESTORE_RETRY_MAX_BACKOFF", 5*time.Minute),
+	})
+
+	go handler.RunExpiry(ctx, api.ExpiryOptions{
+		Interval:       durationEnvOrDefault("EXPIRY_CHECK_INTERVAL", time.Minute),
+		WarnBefore:     durationEnvOrDefault("EXPIRY_WARNING", time.Hour),
+		WarningWebhook: os.Getenv("EXPIRY_WARNING_WEBHOOK"),
+	})
+
+	if snapshotPath != "" {
+		go catalog.RunSnapshots(ctx, snapshotPath, durationEnvOrDefault("CATALOG_SNAPSHOT_INTERVAL", time.Minute))
+	}
+	go handler.RunSchedules(ctx, durationEnvOrDefault("SCHEDULE_CHECK_INTERVAL", 30*time.Second))
+	go catalogOpts.Events.Run(ctx, durationEnvOrDefault("EVENT_FLUSH_INTERVAL", 30*time.Second))
+	go handlerOpts.APIKeys.Run(ctx, durationEnvOrDefault("APIKEY_FLUSH_INTERVAL", 5*time.Minute))
+	if interval := durationEnvOrDefault("REPLICA_SYNC_INTERVAL", 0); interval > 0 {
+		go catalog.RunSync(ctx, interval)
+	}
+	if watch.Interval > 0 {
+		go handler.RunWatch(ctx, watch)
+	}
+	go handler.RunCanary(ctx)
+
+	if gitExporter != nil {
+		go gitExporter.Run(ctx)
+	}
+	if notifiers != nil {
+		go handler.RunNotifications(ctx, notifiers)
 	}
 
 	mux := http.NewServeMux()
 	handler.RegisterRoutes(mux)
+	if embeddedRegistry {
+		oci.NewRegistryServer(storage).RegisterRoutes(mux)
+	}
 
 	log.Printf("GitOps Squared API server listening on %s", listenAddr)
 	log.Printf("Registry: %s", registryHost)
-	if err := http.ListenAndServe(listenAddr, mux); err != nil {
+	if layout, ok := storage.(*oci.LayoutStorage); ok {
+		log.Printf("Storage: filesystem (%s)", layout.Root())
+	}
+	if embeddedRegistry {
+		log.Printf("Serving embedded registry at %s/v2/", listenAddr)
+	}
+	if err := http.ListenAndServe(listenAddr, handler.Wrap(mux)); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
 
+// runFsck implements the "fsck [-fix]" subcommand: it checks the registry
+// with the server's configuration, prints the report as JSON and exits 1 if
+// issues remain unfixed.
+func runFsck(catalog *api.CatalogManager, namespaces *api.NamespaceStore, args []string) int {
+	flags := flag.NewFlagSet("fsck", flag.ExitOnError)
+	fix := flags.Bool("fix", false, "repair the inconsistencies found")
+	flags.Parse(args)
+
+	// Namespaces are loaded so a republished catalog keeps their manifests.
+	ctx := context.Background()
+	if err := namespaces.Restore(ctx); err != nil {
+		log.Printf("Warning: failed to restore namespaces from registry: %v", err)
+	}
+
+	resp, err := catalog.Fsck(ctx, *fix)
+	if err != nil {
+		log.Printf("fsck: %v", err)
+		return 2
+	}
+	out, _ := json.MarshalIndent(resp, "", "  ")
+	fmt.Println(string(out))
+	for _, issue := range resp.Issues {
+		if !issue.Fixed {
+			return 1
+		}
+	}
+	return 0
+}
+
+// newAuthMiddleware authenticates callers. API keys issued by apiKeys are
+// always accepted as bearer tokens, and session cookies if sessions is set;
+// a session issued for an API key ends when the key is revoked or expires.
+// AUTH_PROXY_TRUSTED_CIDRS enables identity headers from an authenticating
+// proxy at those addresses (AUTH_PROXY_USER_HEADER and
+// AUTH_PROXY_GROUPS_HEADER override the header names). AUTH_REQUIRED=true
+// rejects anonymous requests except health checks, metrics, the embedded
+// registry, the web console's static files and the Git webhooks, which
+// check their own signatures. Members of the
+// IMPERSONATION_GROUPS may act as other users with impersonation headers,
+// but only in the privileged (admin, break-glass or impersonation) groups
+// they are in themselves.
+func newAuthMiddleware(apiKeys *api.APIKeyStore, sessions *auth.Sessions, privileged []string) (api.Middleware, error) {
+	opts := auth.Options{
+		Required:         os.Getenv("AUTH_REQUIRED") == "true",
+		Public:           []string{"/healthz", "/readyz", "/metrics", "/v2/", "/ui", "/api/v1/webhooks/"},
+		PrivilegedGroups: privileged,
+	}
+	for _, group := range strings.Split(os.Getenv("IMPERSONATION_GROUPS"), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			opts.Impersonators = append(opts.Impersonators, group)
+		}
+	}
+	if v := os.Getenv("AUTH_PROXY_TRUSTED_CIDRS"); v != "" {
+		cidrs, err := auth.ParseCIDRs(v)
+		if err != nil {
+			return nil, err
+		}
+		opts.Authenticators = append(opts.Authenticators, &auth.ProxyHeaders{
+			UserHeader:   os.Getenv("AUTH_PROXY_USER_HEADER"),
+			GroupsHeader: os.Getenv("AUTH_PROXY_GROUPS_HEADER"),
+			TrustedCIDRs: cidrs,
+		})
+	}
+	if opts.Required && len(opts.Authenticators) == 0 {
+		return nil, fmt.Errorf("AUTH_REQUIRED needs an authenticator, e.g. AUTH_PROXY_TRUSTED_CIDRS")
+	}
+	// API keys come first: a request from behind the proxy that sends one
+	// acts as the key. Sessions come next, so a browser behind the proxy
+	// keeps the identity its session was issued for.
+	first := []auth.Authenticator{apiKeys}
+	if sessions != nil {
+		sessions.Check = apiKeys.CheckSession
+		first = append(first, sessions)
+	}
+	opts.Authenticators = append(first, opts.Authenticators...)
+	return func(next http.Handler) http.Handler {
+		return auth.Middleware(next, opts)
+	}, nil
+}
+
+// newCostEstimator uses the price table at COST_PRICE_TABLE or the webhook
+// at COST_ESTIMATOR_URL. With neither set, costs are not estimated.
+func newCostEstimator() (cost.Estimator, error) {
+	if path := os.Getenv("COST_PRICE_TABLE"); path != "" {
+		return cost.LoadPriceTable(path)
+	}
+	if url := os.Getenv("COST_ESTIMATOR_URL"); url != "" {
+		return cost.NewWebhookEstimator(url), nil
+	}
+	return nil, nil
+}
+
+// kmsTimeout bounds the key service lookups made at startup.
+const kmsTimeout = 30 * time.Second
+
+// managedSigner signs catalogs and artifacts and can be rotated.
+type managedSigner interface {
+	api.CatalogSigner
+	api.ManagedKey
+}
+
+// newSigner loads the signing key ref: a PEM file, or a KMS key URI
+// (awskms://, gcpkms:// or vault://).
+func newSigner(ref string) (managedSigner, error) {
+	if !kms.IsURI(ref) {
+		return signing.LoadSigner(ref)
+	}
+	key, err := kms.Open(ref)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), kmsTimeout)
+	defer cancel()
+	return kms.NewSigner(ctx, key)
+}
+
+// newVerifier loads the verification key ref: a PEM public key, or the
+// KMS key URI signatures are made with.
+func newVerifier(ref string) (oci.ArtifactVerifier, error) {
+	if !kms.IsURI(ref) {
+		return signing.LoadVerifier(ref)
+	}
+	key, err := kms.Open(ref)
+	if err != nil {
+		return nil, err
+	}
+	return kms.NewVerifier(key), nil
+}
+
+// encryptionKeys wraps artifact data keys and can be rotated.
+type encryptionKeys interface {
+	oci.DataKeyProvider
+	api.ManagedKey
+}
+
+// newDataKeys uses the keyring at ENCRYPTION_KEYS_FILE or the KMS key at
+// ENCRYPTION_KEY. With neither set, artifacts are not encrypted.
+func newDataKeys() (encryptionKeys, error) {
+	path, ref := os.Getenv("ENCRYPTION_KEYS_FILE"), os.Getenv("ENCRYPTION_KEY")
+	switch {
+	case path != "" && ref != "":
+		return nil, fmt.Errorf("set ENCRYPTION_KEYS_FILE or ENCRYPTION_KEY, not both")
+	case path != "":
+		return encryption.LoadKeyring(path)
+	case ref != "":
+		key, err := kms.Open(ref)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), kmsTimeout)
+		defer cancel()
+		return kms.NewDataKeys(ctx, key)
+	default:
+		return nil, nil
+	}
+}
+
+// newStorage picks the artifact backend from STORAGE_BACKEND: "registry"
+// talks to REGISTRY_HOST, "filesystem" writes OCI image layouts under
+// STORAGE_PATH. The embedded registry serves local storage, so it defaults
+// to (and requires) the filesystem backend.
+func newStorage(registryHost string, embedded bool) (oci.Storage, error) {
+	defaultBackend := "registry"
+	if embedded {
+		defaultBackend = "filesystem"
+	}
+	switch backend := envOrDefault("STORAGE_BACKEND", defaultBackend); backend {
+	case "registry":
+		if embedded {
+			return nil, fmt.Errorf("EMBEDDED_REGISTRY requires STORAGE_BACKEND=filesystem")
+		}
+		return newRegistryStorage(registryHost)
+	case "filesystem":
+		return oci.NewLayoutStorage(envOrDefault("STORAGE_PATH", "./data/oci"))
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q (want registry or filesystem)", backend)
+	}
+}
+
+// newRegistryStorage configures registry access. REGISTRY_CREDENTIAL_PROVIDER
+// selects a token refresh flow for a managed registry ("ecr", "gcr", "acr"),
+// or "exec" to run REGISTRY_CREDENTIAL_COMMAND with
+// REGISTRY_CREDENTIAL_USERNAME and REGISTRY_CREDENTIAL_TTL. Managed
+// registries use HTTPS; REGISTRY_TLS overrides that either way.
+func newRegistryStorage(registryHost string) (oci.Storage, error) {
+	var opts oci.RegistryOptions
+	switch kind := os.Getenv("REGISTRY_CREDENTIAL_PROVIDER"); kind {
+	case "":
+	case "exec":
+		command := strings.Fields(os.Getenv("REGISTRY_CREDENTIAL_COMMAND"))
+		if len(command) == 0 {
+			return nil, fmt.Errorf("REGISTRY_CREDENTIAL_COMMAND is required for the exec credential provider")
+		}
+		opts.Credentials = &oci.ExecProvider{
+			Command:  command,
+			Username: os.Getenv("REGISTRY_CREDENTIAL_USERNAME"),
+			TTL:      durationEnvOrDefault("REGISTRY_CREDENTIAL_TTL", time.Hour),
+		}
+		opts.TLS = true
+	default:
+		provider, err := oci.NewCloudProvider(kind, registryHost)
+		if err != nil {
+			return nil, err
+		}
+		opts.Credentials = provider
+		opts.TLS = true
+	}
+	if v := os.Getenv("REGISTRY_TLS"); v != "" {
+		opts.TLS = v == "true"
+	}
+	return oci.NewRegistryStorage(registryHost, opts), nil
+}
+
+// newGitExporter mirrors the catalog into the Git repository at
+// GIT_EXPORT_URL, if set: branch GIT_EXPORT_BRANCH (default main), under
+// GIT_EXPORT_DIR (default resources), authenticated over HTTPS with
+// GIT_EXPORT_TOKEN and GIT_EXPORT_USERNAME.
+func newGitExporter() (*api.GitExporter, error) {
+	url := os.Getenv("GIT_EXPORT_URL")
+	if url == "" {
+		return nil, nil
+	}
+	mirror, err := gitsource.NewMirror(gitsource.MirrorOptions{
+		URL:      url,
+		Branch:   os.Getenv("GIT_EXPORT_BRANCH"),
+		Dir:      envOrDefault("GIT_EXPORT_DIR", "resources"),
+		Username: os.Getenv("GIT_EXPORT_USERNAME"),
+		Token:    os.Getenv("GIT_EXPORT_TOKEN"),
+		Committer: gitsource.Signature{
+			Name:  envOrDefault("GIT_EXPORT_COMMITTER_NAME", "gitops-squared"),
+			Email: envOrDefault("GIT_EXPORT_COMMITTER_EMAIL", "gitops-squared@users.noreply.gitops-squared"),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return api.NewGitExporter(api.GitExportOptions{
+		Mirror:      mirror,
+		EmailDomain: os.Getenv("GIT_EXPORT_EMAIL_DOMAIN"),
+	}), nil
+}
+
+// newPullRequests opens proposals as pull requests in the Git export's
+// repository if GIT_EXPORT_PROVIDER (github or gitlab) is set. The
+// repository is GIT_EXPORT_REPOSITORY on GIT_EXPORT_API_URL, accessed with
+// GIT_EXPORT_TOKEN; GIT_EXPORT_WEBHOOK_SECRET authenticates its webhooks.
+func newPullRequests(exporter *api.GitExporter) (*api.PullRequestOptions, error) {
+	provider := os.Getenv("GIT_EXPORT_PROVIDER")
+	if provider == "" {
+		return nil, nil
+	}
+	if exporter == nil {
+		return nil, fmt.Errorf("GIT_EXPORT_PROVIDER requires GIT_EXPORT_URL")
+	}
+	client, err := gitsource.NewPullRequests(provider,
+		os.Getenv("GIT_EXPORT_REPOSITORY"),
+		os.Getenv("GIT_EXPORT_API_URL"),
+		os.Getenv("GIT_EXPORT_TOKEN"),
+		os.Getenv("GIT_EXPORT_WEBHOOK_SECRET"))
+	if err != nil {
+		return nil, err
+	}
+	return &api.PullRequestOptions{Exporter: exporter, Client: client}, nil
+}
+
+// newKubeClient uses KUBE_API_SERVER (with optional KUBE_TOKEN_FILE and
+// KUBE_CA_FILE) if set, and the pod's service account otherwise.
+func newKubeClient() (*kube.Client, error) {
+	if server := os.Getenv("KUBE_API_SERVER"); server != "" {
+		return kube.NewClient(server, os.Getenv("KUBE_TOKEN_FILE"), os.Getenv("KUBE_CA_FILE"))
+	}
+	return kube.NewInClusterClient()
+}
+
 func envOrDefault(key, defaultValue string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
 	return defaultValue
 }
+
+func durationEnvOrDefault(key string, defaultValue time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: %v", key, v, err)
+	}
+	return d
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/api"
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/gitsource"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci/ocitest"
+)
+
+func TestAuthRequiredLetsSignedWebhooksThrough(t *testing.T) {
+	t.Setenv("AUTH_REQUIRED", "true")
+	t.Setenv("AUTH_PROXY_TRUSTED_CIDRS", "10.0.0.0/8")
+	t.Setenv("TEST_WEBHOOK_SECRET", "s3cret")
+
+	path := filepath.Join(t.TempDir(), "sources.yaml")
+	config := "sources:\n  - provider: github\n    repository: acme/specs\n    secretEnv: TEST_WEBHOOK_SECRET\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sources, err := gitsource.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, _ := ocitest.NewClient("gitops-squared/resources")
+	apiKeys := api.NewAPIKeyStore(client)
+	authn, err := newAuthMiddleware(apiKeys, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := api.NewHandler(client, api.NewCatalogManager(client, api.CatalogOptions{}), api.HandlerOptions{
+		GitSources: sources,
+		APIKeys:    apiKeys,
+		Middleware: []api.Middleware{authn},
+	})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	server := handler.Wrap(mux)
+
+	body := `{"zen":"Keep it logically awesome.","repository":{"full_name":"acme/specs"}}`
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(body))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/git", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", "ping")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("signed webhook: status %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/git", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", "ping")
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || strings.Contains(rec.Body.String(), "authentication required") {
+		t.Errorf("unsigned webhook: status %d, want 401 from the signature check: %s", rec.Code, rec.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/resources", nil)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous request: status %d, want 401", rec.Code)
+	}
+}
+
+func TestSessionEndsWithItsAPIKey(t *testing.T) {
+	client, _ := ocitest.NewClient("gitops-squared/resources")
+	apiKeys := api.NewAPIKeyStore(client)
+	sessions, err := auth.NewSessions([]byte(strings.Repeat("s", 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	authn, err := newAuthMiddleware(apiKeys, sessions, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := api.NewHandler(client, api.NewCatalogManager(client, api.CatalogOptions{}), api.HandlerOptions{
+		APIKeys:    apiKeys,
+		Sessions:   sessions,
+		Middleware: []api.Middleware{authn},
+	})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	server := handler.Wrap(mux)
+
+	key, err := apiKeys.Create(context.Background(), model.APIKey{Name: "console", CreatedAt: time.Now().UTC().Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/session", nil)
+	req.Header.Set("Authorization", "Bearer "+key.Token)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("creating session: status %d: %s", rec.Code, rec.Body)
+	}
+	cookies := rec.Result().Cookies()
+
+	whoami := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/whoami", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := whoami(); code != http.StatusOK {
+		t.Fatalf("whoami with the session: status %d, want 200", code)
+	}
+	if _, err := apiKeys.Delete(context.Background(), "console"); err != nil {
+		t.Fatal(err)
+	}
+	if code := whoami(); code != http.StatusUnauthorized {
+		t.Errorf("whoami after revoking the key: status %d, want 401", code)
+	}
+
+	// A key created again under the same name, even within the same
+	// second, doesn't revive the session.
+	if _, err := apiKeys.Create(context.Background(), model.APIKey{Name: "console", CreatedAt: time.Now().UTC().Format(time.RFC3339)}); err != nil {
+		t.Fatal(err)
+	}
+	if code := whoami(); code != http.StatusUnauthorized {
+		t.Errorf("whoami after recreating the key: status %d, want 401", code)
+	}
+}
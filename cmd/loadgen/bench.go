@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/alfredtm/gitops-squared/pkg/api"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+	"github.com/alfredtm/gitops-squared/pkg/oci/ocitest"
+)
+
+// runBenchmarks benchmarks the push path, catalog publishing and restore
+// against an in-memory registry, so results reflect the server's own CPU
+// and allocation cost rather than registry latency.
+func runBenchmarks(resources int) {
+	manifests := make(map[string][]byte, resources)
+	for i := 0; i < resources; i++ {
+		name := fmt.Sprintf("bench-%d", i)
+		req := model.ResourceRequest{
+			Name: name,
+			Spec: model.ResourceSpec{Type: "vm", Size: "small", Replicas: 1 + i%3},
+		}
+		manifest, err := req.ToKubernetesYAML("default", model.ManifestAnnotations{Version: "v1", Generation: 1})
+		if err != nil {
+			log.Fatalf("Rendering %s: %v", name, err)
+		}
+		manifests[name] = manifest
+	}
+	ctx := context.Background()
+
+	benchmarks := []struct {
+		name string
+		fn   func(b *testing.B)
+	}{
+		{"PushResource", func(b *testing.B) {
+			client, _ := ocitest.NewClient("gitops-squared/resources")
+			manifest := manifests["bench-0"]
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := client.PushResource(ctx, "default", "bench-0", manifest, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}},
+		{fmt.Sprintf("PushCatalog/%d", resources), func(b *testing.B) {
+			client, _ := ocitest.NewClient("gitops-squared/resources")
+			cm := api.NewCatalogManager(client, api.CatalogOptions{})
+			for name, manifest := range manifests {
+				cm.Set("default", name, manifest, api.ResourceMeta{})
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := cm.PushCatalog(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}},
+		{fmt.Sprintf("Restore/%d", resources), func(b *testing.B) {
+			client, _ := ocitest.NewClient("gitops-squared/resources")
+			seed(ctx, b, client, manifests)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cm := api.NewCatalogManager(client, api.CatalogOptions{})
+				if err := cm.Restore(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}},
+	}
+
+	for _, bm := range benchmarks {
+		result := testing.Benchmark(bm.fn)
+		fmt.Printf("%-20s %s %s\n", bm.name, result, result.MemString())
+	}
+}
+
+// seed pushes every manifest as a resource artifact.
+func seed(ctx context.Context, b *testing.B, client *oci.Client, manifests map[string][]byte) {
+	for name, manifest := range manifests {
+		if _, _, err := client.PushResource(ctx, "default", name, manifest, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Command loadgen drives synthetic create/delete traffic against a running
+// gitops-squared instance and reports request latency percentiles. With
+// -bench it instead benchmarks the catalog build, push and restore paths in
+// process, against an in-memory registry.
+//
+//	go run ./cmd/loadgen -url http://localhost:8080 -concurrency 8 -duration 30s
+//	go run ./cmd/loadgen -bench -resources 1000
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// headerFlags collects repeated -header "Name: value" flags.
+type headerFlags []string
+
+func (h *headerFlags) String() string     { return strings.Join(*h, ", ") }
+func (h *headerFlags) Set(v string) error { *h = append(*h, v); return nil }
+
+func main() {
+	var headers headerFlags
+	url := flag.String("url", "http://localhost:8080", "base URL of the API")
+	namespace := flag.String("namespace", "default", "namespace to create resources in")
+	prefix := flag.String("prefix", "loadgen", "name prefix of the generated resources")
+	concurrency := flag.Int("concurrency", 4, "number of concurrent workers")
+	duration := flag.Duration("duration", 30*time.Second, "how long to generate traffic")
+	bench := flag.Bool("bench", false, "run in-process benchmarks instead of generating traffic")
+	resources := flag.Int("resources", 500, "catalog size for -bench")
+	flag.Var(&headers, "header", `extra request header, "Name: value" (repeatable)`)
+	flag.Parse()
+
+	if *bench {
+		runBenchmarks(*resources)
+		return
+	}
+
+	header := make(http.Header)
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			log.Fatalf("Invalid -header %q: want \"Name: value\"", h)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	g := &generator{
+		client:    &http.Client{Timeout: time.Minute},
+		baseURL:   strings.TrimSuffix(*url, "/"),
+		namespace: *namespace,
+		header:    header,
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]int),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+
+	log.Printf("Generating traffic against %s with %d workers for %s", g.baseURL, *concurrency, *duration)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			g.run(ctx, fmt.Sprintf("%s-%d", *prefix, worker))
+		}(i)
+	}
+	wg.Wait()
+
+	g.report(os.Stdout, time.Since(start))
+}
+
+// generator creates and deletes resources in a loop and records latencies
+// per operation.
+type generator struct {
+	client    *http.Client
+	baseURL   string
+	namespace string
+	header    http.Header
+
+	mu        sync.Mutex
+	latencies map[string][]time.Duration // operation -> successful request latencies
+	errors    map[string]int             // operation -> failed requests
+}
+
+// run creates and deletes one resource per iteration until ctx is done.
+// Each worker uses its own name, so workers don't conflict.
+func (g *generator) run(ctx context.Context, name string) {
+	sizes := []string{"small", "medium", "large"}
+	for i := 0; ctx.Err() == nil; i++ {
+		body, _ := json.Marshal(map[string]any{
+			"name": name,
+			"spec": map[string]any{
+				"type":     "vm",
+				"size":     sizes[i%len(sizes)],
+				"replicas": 1 + i%3,
+			},
+		})
+		g.do(ctx, "create", http.MethodPost, "/api/v1/resources", body)
+		g.do(ctx, "delete", http.MethodDelete, "/api/v1/resources/"+name, nil)
+	}
+}
+
+// do sends one request and records its outcome. Requests cut short by the
+// end of the run are not counted.
+func (g *generator) do(ctx context.Context, op, method, path string, body []byte) {
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+path+"?namespace="+g.namespace, bytes.NewReader(body))
+	if err != nil {
+		log.Fatalf("Building request: %v", err)
+	}
+	for name, values := range g.header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+	resp, err := g.client.Do(req)
+	elapsed := time.Since(start)
+	if ctx.Err() != nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err != nil {
+		log.Printf("Warning: %s failed: %v", op, err)
+		g.errors[op]++
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		log.Printf("Warning: %s returned %d: %s", op, resp.StatusCode, bytes.TrimSpace(msg))
+		g.errors[op]++
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	g.latencies[op] = append(g.latencies[op], elapsed)
+}
+
+// report writes request counts, throughput and latency percentiles per
+// operation.
+func (g *generator) report(w io.Writer, elapsed time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	fmt.Fprintf(w, "%-8s %8s %8s %8s %10s %10s %10s %10s\n", "op", "ok", "errors", "req/s", "p50", "p90", "p99", "max")
+	for _, op := range []string{"create", "delete"} {
+		l := g.latencies[op]
+		sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+		fmt.Fprintf(w, "%-8s %8d %8d %8.1f %10s %10s %10s %10s\n",
+			op, len(l), g.errors[op], float64(len(l))/elapsed.Seconds(),
+			percentile(l, 50), percentile(l, 90), percentile(l, 99), percentile(l, 100))
+	}
+}
+
+// percentile returns the p-th percentile of sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := (len(sorted)*p+99)/100 - 1
+	return sorted[max(i, 0)].Round(time.Microsecond)
+}
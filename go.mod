@@ -3,13 +3,13 @@ module github.com/alfredtm/gitops-squared
 go 1.24.3
 
 require (
+	github.com/opencontainers/go-digest v1.0.0
 	github.com/opencontainers/image-spec v1.1.1
 	oras.land/oras-go/v2 v2.6.0
 	sigs.k8s.io/yaml v1.6.0
 )
 
 require (
-	github.com/opencontainers/go-digest v1.0.0 // indirect
 	go.yaml.in/yaml/v2 v2.4.2 // indirect
 	golang.org/x/sync v0.14.0 // indirect
 )
@@ -0,0 +1,162 @@
+// Package encryption holds the key-encryption keys that protect resource
+// artifacts in the registry.
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+)
+
+// keySize is the size of key-encryption and data keys: AES-256.
+const keySize = 32
+
+// keyringFile is the format of a keyring file.
+type keyringFile struct {
+	Current string `json:"current"`
+	Keys    []struct {
+		ID  string `json:"id"`
+		Key string `json:"key"` // base64, 32 bytes
+	} `json:"keys"`
+}
+
+// Keyring wraps data keys with AES-256-GCM key-encryption keys read from a
+// file. New data keys are wrapped with the current key; every key in the
+// file can unwrap, so rotating means adding a key, making it current and
+// keeping the old one for as long as artifacts encrypted with it are read.
+type Keyring struct {
+	path string
+
+	mu      sync.RWMutex
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// LoadKeyring reads a keyring file:
+//
+//	current: 2026-10
+//	keys:
+//	  - id: 2026-10
+//	    key: <base64 of 32 random bytes>
+//	  - id: 2025-04
+//	    key: ...
+func LoadKeyring(path string) (*Keyring, error) {
+	current, keys, err := readKeyring(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Keyring{path: path, current: current, keys: keys}, nil
+}
+
+func readKeyring(path string) (string, map[string]cipher.AEAD, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("reading encryption keys: %w", err)
+	}
+	var file keyringFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return "", nil, fmt.Errorf("parsing encryption keys %s: %w", path, err)
+	}
+
+	keys := make(map[string]cipher.AEAD, len(file.Keys))
+	for _, k := range file.Keys {
+		if k.ID == "" {
+			return "", nil, fmt.Errorf("encryption keys %s: key without id", path)
+		}
+		if _, dup := keys[k.ID]; dup {
+			return "", nil, fmt.Errorf("encryption keys %s: duplicate key %q", path, k.ID)
+		}
+		raw, err := base64.StdEncoding.DecodeString(k.Key)
+		if err != nil || len(raw) != keySize {
+			return "", nil, fmt.Errorf("encryption keys %s: key %q must be %d bytes, base64-encoded", path, k.ID, keySize)
+		}
+		aead, err := newGCM(raw)
+		if err != nil {
+			return "", nil, err
+		}
+		keys[k.ID] = aead
+	}
+	if _, ok := keys[file.Current]; !ok {
+		return "", nil, fmt.Errorf("encryption keys %s: current key %q is not listed", path, file.Current)
+	}
+	return file.Current, keys, nil
+}
+
+// Backend returns "file".
+func (kr *Keyring) Backend() string {
+	return "file"
+}
+
+// KeyID returns the ID of the current key.
+func (kr *Keyring) KeyID() string {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.current
+}
+
+// Rotate reads the keyring file again, so a key added and made current
+// there is used without a restart. It returns the current key's ID.
+func (kr *Keyring) Rotate(_ context.Context) (string, error) {
+	current, keys, err := readKeyring(kr.path)
+	if err != nil {
+		return "", err
+	}
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.current, kr.keys = current, keys
+	return current, nil
+}
+
+// DataKey returns a new random data key and the same key wrapped with the
+// current key.
+func (kr *Keyring) DataKey(_ context.Context) (key, wrapped []byte, keyID string, err error) {
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, "", fmt.Errorf("generating data key: %w", err)
+	}
+	kr.mu.RLock()
+	current, aead := kr.current, kr.keys[kr.current]
+	kr.mu.RUnlock()
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, "", fmt.Errorf("generating nonce: %w", err)
+	}
+	// The key ID is authenticated, so a wrapped key can't be passed off as
+	// wrapped by another key.
+	wrapped = aead.Seal(nonce, nonce, key, []byte(current))
+	return key, wrapped, current, nil
+}
+
+// UnwrapDataKey recovers a data key wrapped by DataKey with the key keyID.
+func (kr *Keyring) UnwrapDataKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	kr.mu.RLock()
+	aead, ok := kr.keys[keyID]
+	kr.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %q", keyID)
+	}
+	n := aead.NonceSize()
+	if len(wrapped) < n {
+		return nil, fmt.Errorf("wrapped data key too short")
+	}
+	key, err := aead.Open(nil, wrapped[:n], wrapped[n:], []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping data key with %q: %w", keyID, err)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,244 @@
+package kms
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/alfredtm/gitops-squared/internal/signing"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// dataKeySize is the size of data keys: AES-256.
+const dataKeySize = 32
+
+// maxCachedDataKeys bounds the unwrapped data keys DataKeys remembers, so
+// a restore doesn't call the service once per artifact twice over.
+const maxCachedDataKeys = 4096
+
+// DataKeys wraps artifact data keys with a KMS key. It satisfies
+// oci.DataKeyProvider.
+type DataKeys struct {
+	key Key
+
+	mu      sync.Mutex
+	current string
+	cache   map[string][]byte // key ID + wrapped key -> data key
+}
+
+// NewDataKeys returns a data key provider backed by key.
+func NewDataKeys(ctx context.Context, key Key) (*DataKeys, error) {
+	current, err := key.Current(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("looking up %s key: %w", key.Backend(), err)
+	}
+	return &DataKeys{key: key, current: current, cache: map[string][]byte{}}, nil
+}
+
+// Backend returns the key service.
+func (d *DataKeys) Backend() string {
+	return d.key.Backend()
+}
+
+// KeyID returns the key version new data keys are wrapped with.
+func (d *DataKeys) KeyID() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.current
+}
+
+// DataKey returns a new random data key, wrapped by the service.
+func (d *DataKeys) DataKey(ctx context.Context) (key, wrapped []byte, keyID string, err error) {
+	key = make([]byte, dataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, "", fmt.Errorf("generating data key: %w", err)
+	}
+	wrapped, keyID, err = d.key.Encrypt(ctx, key)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("wrapping data key: %w", err)
+	}
+	d.mu.Lock()
+	d.current = keyID
+	d.mu.Unlock()
+	return key, wrapped, keyID, nil
+}
+
+// UnwrapDataKey asks the service to unwrap a data key.
+func (d *DataKeys) UnwrapDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	cacheKey := keyID + "\x00" + string(wrapped)
+	d.mu.Lock()
+	key, ok := d.cache[cacheKey]
+	d.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	key, err := d.key.Decrypt(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping data key with %s: %w", keyID, err)
+	}
+	d.mu.Lock()
+	if len(d.cache) >= maxCachedDataKeys {
+		clear(d.cache)
+	}
+	d.cache[cacheKey] = key
+	d.mu.Unlock()
+	return key, nil
+}
+
+// Rotate rotates the key in the service. Data keys wrapped before stay
+// readable.
+func (d *DataKeys) Rotate(ctx context.Context) (string, error) {
+	keyID, err := d.key.Rotate(ctx)
+	if err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	d.current = keyID
+	d.mu.Unlock()
+	return keyID, nil
+}
+
+// Signer signs artifact digests with an asymmetric KMS key. It satisfies
+// oci.ArtifactSigner.
+type Signer struct {
+	key Key
+
+	mu        sync.RWMutex
+	keyID     string
+	pub       crypto.PublicKey
+	algorithm string
+}
+
+// NewSigner returns a signer backed by key, which must be an ed25519 or
+// ECDSA P-256 signing key.
+func NewSigner(ctx context.Context, key Key) (*Signer, error) {
+	s := &Signer{key: key}
+	keyID, err := key.Current(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("looking up %s key: %w", key.Backend(), err)
+	}
+	if err := s.use(ctx, keyID); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// use fetches the public key of keyID and signs with it from now on.
+func (s *Signer) use(ctx context.Context, keyID string) error {
+	pub, err := s.key.PublicKey(ctx, keyID)
+	if err != nil {
+		return fmt.Errorf("fetching public key: %w", err)
+	}
+	algorithm, err := signing.Algorithm(pub)
+	if err != nil {
+		return fmt.Errorf("signing key %s: %w", keyID, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyID, s.pub, s.algorithm = keyID, pub, algorithm
+	return nil
+}
+
+// Backend returns the key service.
+func (s *Signer) Backend() string {
+	return s.key.Backend()
+}
+
+// Algorithm returns the signature algorithm name.
+func (s *Signer) Algorithm() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.algorithm
+}
+
+// KeyID returns the key version signatures are made with.
+func (s *Signer) KeyID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keyID
+}
+
+// Sign has the service sign a digest string.
+func (s *Signer) Sign(ctx context.Context, digest string) ([]byte, string, error) {
+	signature, keyID, err := s.key.Sign(ctx, []byte(digest))
+	if err != nil {
+		return nil, "", fmt.Errorf("signing with %s key: %w", s.key.Backend(), err)
+	}
+	return signature, keyID, nil
+}
+
+// PublicKey returns the base64-encoded public key for verifiers: the raw
+// key for ed25519, as the file signer reports it, and PKIX DER otherwise.
+func (s *Signer) PublicKey() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if pub, ok := s.pub.(ed25519.PublicKey); ok {
+		return base64.StdEncoding.EncodeToString(pub)
+	}
+	der, err := x509.MarshalPKIXPublicKey(s.pub)
+	if err != nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+// Rotate creates a new key version in the service and signs with it.
+func (s *Signer) Rotate(ctx context.Context) (string, error) {
+	keyID, err := s.key.Rotate(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := s.use(ctx, keyID); err != nil {
+		return "", err
+	}
+	return keyID, nil
+}
+
+// Verifier checks signatures made by any version of a KMS key, fetching
+// and caching each version's public key. It satisfies oci.ArtifactVerifier.
+type Verifier struct {
+	key Key
+
+	mu   sync.Mutex
+	keys map[string]crypto.PublicKey
+}
+
+// NewVerifier returns a verifier for signatures made with key.
+func NewVerifier(key Key) *Verifier {
+	return &Verifier{key: key, keys: map[string]crypto.PublicKey{}}
+}
+
+// Verify checks a signature over a digest string. keyID is the version
+// recorded with the signature; signatures recorded without one are checked
+// against the current version.
+func (v *Verifier) Verify(ctx context.Context, keyID, digest string, signature []byte) error {
+	if keyID == "" {
+		current, err := v.key.Current(ctx)
+		if err != nil {
+			return fmt.Errorf("%w: looking up %s key: %v", oci.ErrVerifierUnavailable, v.key.Backend(), err)
+		}
+		keyID = current
+	}
+	v.mu.Lock()
+	pub, ok := v.keys[keyID]
+	v.mu.Unlock()
+	if !ok {
+		var err error
+		if pub, err = v.key.PublicKey(ctx, keyID); errors.Is(err, errForeignKey) {
+			return err
+		} else if err != nil {
+			return fmt.Errorf("%w: %v", oci.ErrVerifierUnavailable, err)
+		}
+		v.mu.Lock()
+		v.keys[keyID] = pub
+		v.mu.Unlock()
+	}
+	return signing.VerifySignature(pub, digest, signature)
+}
@@ -0,0 +1,152 @@
+package kms
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// awsKey is an AWS KMS key, used through the aws CLI (v2). A key's ID is
+// its ARN: AWS keeps rotated key material behind the same ARN, so
+// ciphertexts made before a rotation still decrypt with it. Binary fields
+// of the CLI's JSON output are base64, which encoding/json decodes into
+// []byte.
+type awsKey struct {
+	id     string // as configured: key ID, ARN or alias
+	region string // from the ARN, if given one
+
+	mu      sync.Mutex
+	arn     string
+	keySpec string
+}
+
+func newAWSKey(id string) *awsKey {
+	k := &awsKey{id: id}
+	// arn:aws:kms:<region>:<account>:key/<id>
+	if parts := strings.Split(id, ":"); len(parts) >= 6 && parts[0] == "arn" {
+		k.region = parts[3]
+	}
+	return k
+}
+
+func (k *awsKey) Backend() string {
+	return "awskms"
+}
+
+// aws runs an aws kms command with JSON output, passing data on stdin for
+// fileb:///dev/stdin arguments so it never shows up in a process list.
+func (k *awsKey) aws(ctx context.Context, stdin []byte, out any, args ...string) error {
+	args = append([]string{"kms"}, args...)
+	args = append(args, "--key-id", k.id, "--output", "json")
+	if k.region != "" {
+		args = append(args, "--region", k.region)
+	}
+	data, err := run(ctx, stdin, "aws", args...)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("parsing aws kms %s output: %w", args[1], err)
+	}
+	return nil
+}
+
+// describe looks up the key's ARN and spec once.
+func (k *awsKey) describe(ctx context.Context) (arn, keySpec string, err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.arn != "" {
+		return k.arn, k.keySpec, nil
+	}
+	var out struct {
+		KeyMetadata struct {
+			Arn     string `json:"Arn"`
+			KeySpec string `json:"KeySpec"`
+		} `json:"KeyMetadata"`
+	}
+	if err := k.aws(ctx, nil, &out, "describe-key"); err != nil {
+		return "", "", err
+	}
+	k.arn, k.keySpec = out.KeyMetadata.Arn, out.KeyMetadata.KeySpec
+	return k.arn, k.keySpec, nil
+}
+
+func (k *awsKey) Current(ctx context.Context) (string, error) {
+	arn, _, err := k.describe(ctx)
+	return arn, err
+}
+
+func (k *awsKey) Encrypt(ctx context.Context, plaintext []byte) ([]byte, string, error) {
+	var out struct {
+		CiphertextBlob []byte `json:"CiphertextBlob"`
+		KeyID          string `json:"KeyId"`
+	}
+	if err := k.aws(ctx, plaintext, &out, "encrypt", "--plaintext", "fileb:///dev/stdin"); err != nil {
+		return nil, "", err
+	}
+	return out.CiphertextBlob, out.KeyID, nil
+}
+
+func (k *awsKey) Decrypt(ctx context.Context, _ string, ciphertext []byte) ([]byte, error) {
+	var out struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	if err := k.aws(ctx, ciphertext, &out, "decrypt", "--ciphertext-blob", "fileb:///dev/stdin"); err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
+
+func (k *awsKey) Sign(ctx context.Context, message []byte) ([]byte, string, error) {
+	var out struct {
+		Signature []byte `json:"Signature"`
+		KeyID     string `json:"KeyId"`
+	}
+	if err := k.aws(ctx, message, &out, "sign", "--message", "fileb:///dev/stdin", "--message-type", "RAW", "--signing-algorithm", "ECDSA_SHA_256"); err != nil {
+		return nil, "", err
+	}
+	return out.Signature, out.KeyID, nil
+}
+
+func (k *awsKey) PublicKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	arn, _, err := k.describe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if keyID != arn {
+		return nil, fmt.Errorf("key %q: %w %s", keyID, errForeignKey, arn)
+	}
+	var out struct {
+		PublicKey []byte `json:"PublicKey"`
+	}
+	if err := k.aws(ctx, nil, &out, "get-public-key"); err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(out.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key of %s: %w", arn, err)
+	}
+	return key, nil
+}
+
+// Rotate rotates the key material of a symmetric key on demand. The ARN,
+// and so the key ID, stays the same.
+func (k *awsKey) Rotate(ctx context.Context) (string, error) {
+	arn, keySpec, err := k.describe(ctx)
+	if err != nil {
+		return "", err
+	}
+	if keySpec != "SYMMETRIC_DEFAULT" {
+		return "", fmt.Errorf("AWS KMS can't rotate %s keys; create a new key and configure it instead: %w", keySpec, errors.ErrUnsupported)
+	}
+	var out struct{}
+	if err := k.aws(ctx, nil, &out, "rotate-key-on-demand"); err != nil {
+		return "", err
+	}
+	return arn, nil
+}
@@ -0,0 +1,214 @@
+package kms
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/alfredtm/gitops-squared/internal/signing"
+)
+
+// gcpKey is a Cloud KMS key, used through the gcloud CLI. Key IDs are
+// version resource names (.../cryptoKeys/<k>/cryptoKeyVersions/<n>).
+// Ciphertexts name the version that made them, so any enabled version
+// decrypts.
+type gcpKey struct {
+	name string // projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>
+
+	mu      sync.Mutex
+	current string
+	keys    map[string]crypto.PublicKey
+}
+
+func newGCPKey(name string) (*gcpKey, error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 8 || parts[0] != "projects" || parts[2] != "locations" || parts[4] != "keyRings" || parts[6] != "cryptoKeys" {
+		return nil, fmt.Errorf("invalid Cloud KMS key %q: want projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>", name)
+	}
+	return &gcpKey{name: name, keys: map[string]crypto.PublicKey{}}, nil
+}
+
+func (k *gcpKey) Backend() string {
+	return "gcpkms"
+}
+
+// describe returns the key's purpose and primary version, which only
+// symmetric keys have.
+func (k *gcpKey) describe(ctx context.Context) (purpose, primary string, err error) {
+	data, err := run(ctx, nil, "gcloud", "kms", "keys", "describe", k.name, "--format=json")
+	if err != nil {
+		return "", "", err
+	}
+	var out struct {
+		Purpose string `json:"purpose"`
+		Primary struct {
+			Name string `json:"name"`
+		} `json:"primary"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return "", "", fmt.Errorf("parsing gcloud kms keys describe output: %w", err)
+	}
+	return out.Purpose, out.Primary.Name, nil
+}
+
+// latestVersion returns the newest enabled version, which asymmetric keys
+// sign with.
+func (k *gcpKey) latestVersion(ctx context.Context) (string, error) {
+	data, err := run(ctx, nil, "gcloud", "kms", "keys", "versions", "list", "--key", k.name,
+		"--filter=state=ENABLED", "--sort-by=~createTime", "--limit=1", "--format=json")
+	if err != nil {
+		return "", err
+	}
+	var out []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return "", fmt.Errorf("parsing gcloud kms keys versions list output: %w", err)
+	}
+	if len(out) == 0 {
+		return "", fmt.Errorf("key %s has no enabled versions", k.name)
+	}
+	return out[0].Name, nil
+}
+
+func (k *gcpKey) Current(ctx context.Context) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.current != "" {
+		return k.current, nil
+	}
+	_, primary, err := k.describe(ctx)
+	if err != nil {
+		return "", err
+	}
+	if primary == "" {
+		if primary, err = k.latestVersion(ctx); err != nil {
+			return "", err
+		}
+	}
+	k.current = primary
+	return primary, nil
+}
+
+func (k *gcpKey) Encrypt(ctx context.Context, plaintext []byte) ([]byte, string, error) {
+	keyID, err := k.Current(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	ciphertext, err := run(ctx, plaintext, "gcloud", "kms", "encrypt", "--key", k.name, "--plaintext-file=-", "--ciphertext-file=-")
+	if err != nil {
+		return nil, "", err
+	}
+	return ciphertext, keyID, nil
+}
+
+func (k *gcpKey) Decrypt(ctx context.Context, _ string, ciphertext []byte) ([]byte, error) {
+	return run(ctx, ciphertext, "gcloud", "kms", "decrypt", "--key", k.name, "--ciphertext-file=-", "--plaintext-file=-")
+}
+
+func (k *gcpKey) Sign(ctx context.Context, message []byte) ([]byte, string, error) {
+	keyID, err := k.Current(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	pub, err := k.PublicKey(ctx, keyID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	dir, err := os.MkdirTemp("", "gcpkms-")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(dir)
+	input, output := filepath.Join(dir, "input"), filepath.Join(dir, "signature")
+	if err := os.WriteFile(input, message, 0o600); err != nil {
+		return nil, "", err
+	}
+	args := []string{"kms", "asymmetric-sign", "--version", versionNumber(keyID), "--key", k.name,
+		"--input-file", input, "--signature-file", output}
+	if _, ok := pub.(ed25519.PublicKey); !ok {
+		args = append(args, "--digest-algorithm=sha256")
+	}
+	if _, err := run(ctx, nil, "gcloud", args...); err != nil {
+		return nil, "", err
+	}
+	signature, err := os.ReadFile(output)
+	if err != nil {
+		return nil, "", err
+	}
+	return signature, keyID, nil
+}
+
+func (k *gcpKey) PublicKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	if !strings.HasPrefix(keyID, k.name+"/cryptoKeyVersions/") {
+		return nil, fmt.Errorf("key %q: %w %s", keyID, errForeignKey, k.name)
+	}
+	k.mu.Lock()
+	pub, ok := k.keys[keyID]
+	k.mu.Unlock()
+	if ok {
+		return pub, nil
+	}
+
+	dir, err := os.MkdirTemp("", "gcpkms-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "key.pem")
+	if _, err := run(ctx, nil, "gcloud", "kms", "keys", "versions", "get-public-key", versionNumber(keyID),
+		"--key", k.name, "--output-file", output); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		return nil, err
+	}
+	pub, err = signing.ParsePublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("public key of %s: %w", keyID, err)
+	}
+	k.mu.Lock()
+	k.keys[keyID] = pub
+	k.mu.Unlock()
+	return pub, nil
+}
+
+// Rotate creates a new version. For symmetric keys it becomes the primary
+// version; asymmetric keys sign with their newest version.
+func (k *gcpKey) Rotate(ctx context.Context) (string, error) {
+	purpose, _, err := k.describe(ctx)
+	if err != nil {
+		return "", err
+	}
+	args := []string{"kms", "keys", "versions", "create", "--key", k.name, "--format=json"}
+	if purpose == "ENCRYPT_DECRYPT" {
+		args = append(args, "--primary")
+	}
+	data, err := run(ctx, nil, "gcloud", args...)
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return "", fmt.Errorf("parsing gcloud kms keys versions create output: %w", err)
+	}
+	k.mu.Lock()
+	k.current = out.Name
+	k.mu.Unlock()
+	return out.Name, nil
+}
+
+// versionNumber returns the last element of a version resource name.
+func versionNumber(keyID string) string {
+	return keyID[strings.LastIndex(keyID, "/")+1:]
+}
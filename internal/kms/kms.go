@@ -0,0 +1,99 @@
+// Package kms keeps signing and encryption keys in a key management
+// service: AWS KMS, Google Cloud KMS or the transit engine of HashiCorp
+// Vault. Key material never leaves the service. Every encryption and
+// signature reports the key version that made it, so artifacts can record
+// it and be read back after the key is rotated.
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Key is a key held by a key management service. Key IDs name a version
+// of the key, in the service's own terms.
+type Key interface {
+	// Backend returns the service: awskms, gcpkms or vault.
+	Backend() string
+
+	// Current returns the ID of the version new encryptions and signatures
+	// use.
+	Current(ctx context.Context) (string, error)
+
+	// Encrypt encrypts a small plaintext, such as a data key, with the
+	// current version.
+	Encrypt(ctx context.Context, plaintext []byte) (ciphertext []byte, keyID string, err error)
+
+	// Decrypt decrypts a ciphertext made by Encrypt with version keyID.
+	Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error)
+
+	// Sign signs message with the current version of an asymmetric key.
+	// ECDSA keys sign its SHA-256.
+	Sign(ctx context.Context, message []byte) (signature []byte, keyID string, err error)
+
+	// PublicKey returns the public key of version keyID of an asymmetric
+	// key. It fails for versions of other keys.
+	PublicKey(ctx context.Context, keyID string) (crypto.PublicKey, error)
+
+	// Rotate makes a new version current and returns its ID. Errors wrap
+	// errors.ErrUnsupported if the service can't rotate this kind of key.
+	Rotate(ctx context.Context) (string, error)
+}
+
+// errForeignKey is wrapped by PublicKey errors for key IDs that name
+// another key.
+var errForeignKey = errors.New("not a version of the configured key")
+
+// IsURI reports whether s names a KMS key rather than a file.
+func IsURI(s string) bool {
+	for _, scheme := range []string{"awskms://", "gcpkms://", "vault://"} {
+		if strings.HasPrefix(s, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Open returns the key named by uri:
+//
+//	awskms://<key ID, ARN or alias/name>
+//	gcpkms://projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>
+//	vault://<transit mount>/<key name>
+//
+// AWS and Cloud KMS are reached through the aws and gcloud CLIs and the
+// identity they are configured with. Vault is reached at VAULT_ADDR with
+// VAULT_TOKEN (and VAULT_NAMESPACE, if set).
+func Open(uri string) (Key, error) {
+	scheme, name, ok := strings.Cut(uri, "://")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid key URI %q", uri)
+	}
+	switch scheme {
+	case "awskms":
+		return newAWSKey(name), nil
+	case "gcpkms":
+		return newGCPKey(name)
+	case "vault":
+		return newVaultKey(name)
+	default:
+		return nil, fmt.Errorf("unknown key service %q (want awskms, gcpkms or vault)", scheme)
+	}
+}
+
+// run runs a CLI command with stdin and returns its stdout.
+func run(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running %s %s: %w: %s", name, strings.Join(args[:min(2, len(args))], " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,233 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/internal/signing"
+)
+
+// vaultKey is a key in a Vault transit secrets engine. Key IDs are
+// "<mount>/<name>:v<version>"; ciphertexts and signatures carry their
+// version too ("vault:v3:...").
+type vaultKey struct {
+	addr      string
+	token     string
+	namespace string
+	mount     string
+	name      string
+	client    *http.Client
+}
+
+func newVaultKey(path string) (*vaultKey, error) {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 || i == len(path)-1 {
+		return nil, fmt.Errorf("invalid Vault key %q: want <transit mount>/<key name>", path)
+	}
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return nil, fmt.Errorf("VAULT_ADDR is not set")
+	}
+	return &vaultKey{
+		addr:      strings.TrimSuffix(addr, "/"),
+		token:     os.Getenv("VAULT_TOKEN"),
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		mount:     path[:i],
+		name:      path[i+1:],
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (k *vaultKey) Backend() string {
+	return "vault"
+}
+
+// call makes a transit API request and decodes the response's data.
+func (k *vaultKey) call(ctx context.Context, method, op string, body, data any) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+	url := fmt.Sprintf("%s/v1/%s/%s/%s", k.addr, k.mount, op, k.name)
+	if op == "rotate" {
+		url = fmt.Sprintf("%s/v1/%s/keys/%s/rotate", k.addr, k.mount, k.name)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", k.token)
+	if k.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", k.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault transit %s: %w", op, err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil && err != io.EOF {
+		return fmt.Errorf("vault transit %s: decoding response: %w", op, err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("vault transit %s: %s: %s", op, resp.Status, strings.Join(out.Errors, "; "))
+	}
+	if data == nil {
+		return nil
+	}
+	if err := json.Unmarshal(out.Data, data); err != nil {
+		return fmt.Errorf("vault transit %s: decoding response: %w", op, err)
+	}
+	return nil
+}
+
+func (k *vaultKey) keyID(version int) string {
+	return fmt.Sprintf("%s/%s:v%d", k.mount, k.name, version)
+}
+
+// version parses a key ID of this key.
+func (k *vaultKey) version(keyID string) (string, error) {
+	v, ok := strings.CutPrefix(keyID, k.mount+"/"+k.name+":v")
+	if !ok {
+		return "", fmt.Errorf("key %q: %w %s/%s", keyID, errForeignKey, k.mount, k.name)
+	}
+	return v, nil
+}
+
+// versioned splits "vault:v<n>:<payload>" into the key ID of version n and
+// the payload.
+func (k *vaultKey) versioned(s string) (string, string, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" || !strings.HasPrefix(parts[1], "v") {
+		return "", "", fmt.Errorf("unexpected Vault transit output %q", s)
+	}
+	version, err := strconv.Atoi(parts[1][1:])
+	if err != nil {
+		return "", "", fmt.Errorf("unexpected Vault transit output %q", s)
+	}
+	return k.keyID(version), parts[2], nil
+}
+
+// readKey returns the key's type, latest version and public keys by
+// version.
+func (k *vaultKey) readKey(ctx context.Context) (keyType string, latest int, keys map[string]json.RawMessage, err error) {
+	var data struct {
+		Type          string                     `json:"type"`
+		LatestVersion int                        `json:"latest_version"`
+		Keys          map[string]json.RawMessage `json:"keys"`
+	}
+	if err := k.call(ctx, http.MethodGet, "keys", nil, &data); err != nil {
+		return "", 0, nil, err
+	}
+	return data.Type, data.LatestVersion, data.Keys, nil
+}
+
+func (k *vaultKey) Current(ctx context.Context) (string, error) {
+	_, latest, _, err := k.readKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	return k.keyID(latest), nil
+}
+
+func (k *vaultKey) Encrypt(ctx context.Context, plaintext []byte) ([]byte, string, error) {
+	var data struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	if err := k.call(ctx, http.MethodPost, "encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}, &data); err != nil {
+		return nil, "", err
+	}
+	keyID, _, err := k.versioned(data.Ciphertext)
+	if err != nil {
+		return nil, "", err
+	}
+	return []byte(data.Ciphertext), keyID, nil
+}
+
+func (k *vaultKey) Decrypt(ctx context.Context, _ string, ciphertext []byte) ([]byte, error) {
+	var data struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := k.call(ctx, http.MethodPost, "decrypt", map[string]string{"ciphertext": string(ciphertext)}, &data); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(data.Plaintext)
+}
+
+// Sign signs with the latest version. ECDSA keys hash with SHA-256 and
+// return ASN.1 signatures, Vault's defaults.
+func (k *vaultKey) Sign(ctx context.Context, message []byte) ([]byte, string, error) {
+	var data struct {
+		Signature string `json:"signature"`
+	}
+	if err := k.call(ctx, http.MethodPost, "sign", map[string]string{"input": base64.StdEncoding.EncodeToString(message)}, &data); err != nil {
+		return nil, "", err
+	}
+	keyID, payload, err := k.versioned(data.Signature)
+	if err != nil {
+		return nil, "", err
+	}
+	signature, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("decoding Vault signature: %w", err)
+	}
+	return signature, keyID, nil
+}
+
+func (k *vaultKey) PublicKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	version, err := k.version(keyID)
+	if err != nil {
+		return nil, err
+	}
+	keyType, _, keys, err := k.readKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var entry struct {
+		PublicKey string `json:"public_key"`
+	}
+	if raw, ok := keys[version]; !ok || json.Unmarshal(raw, &entry) != nil || entry.PublicKey == "" {
+		return nil, fmt.Errorf("key %s has no public key", keyID)
+	}
+	if keyType == "ed25519" {
+		raw, err := base64.StdEncoding.DecodeString(entry.PublicKey)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid public key of %s", keyID)
+		}
+		return ed25519.PublicKey(raw), nil
+	}
+	pub, err := signing.ParsePublicKey([]byte(entry.PublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("public key of %s: %w", keyID, err)
+	}
+	return pub, nil
+}
+
+func (k *vaultKey) Rotate(ctx context.Context) (string, error) {
+	if err := k.call(ctx, http.MethodPost, "rotate", nil, nil); err != nil {
+		return "", err
+	}
+	return k.Current(ctx)
+}
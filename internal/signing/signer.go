@@ -0,0 +1,200 @@
+package signing
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Signature algorithms.
+const (
+	AlgorithmEd25519   = "ed25519"
+	AlgorithmECDSAP256 = "ecdsa-p256-sha256"
+)
+
+// Signer signs artifact digests with an ed25519 key read from a file.
+type Signer struct {
+	path string
+
+	mu  sync.RWMutex
+	key ed25519.PrivateKey
+}
+
+// LoadSigner reads a PEM-encoded PKCS#8 ed25519 private key from path.
+func LoadSigner(path string) (*Signer, error) {
+	key, err := readPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{path: path, key: key}, nil
+}
+
+func readPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM encoded", path)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing signing key: %w", err)
+	}
+
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s is %T, want ed25519", path, key)
+	}
+	return edKey, nil
+}
+
+// Backend returns "file".
+func (s *Signer) Backend() string {
+	return "file"
+}
+
+// Algorithm returns the signature algorithm name.
+func (s *Signer) Algorithm() string {
+	return AlgorithmEd25519
+}
+
+// KeyID returns the fingerprint of the key.
+func (s *Signer) KeyID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Fingerprint(s.key.Public())
+}
+
+// Sign signs the given digest string (e.g. "sha256:...") and returns the
+// signature and the fingerprint of the key that made it.
+func (s *Signer) Sign(_ context.Context, digest string) ([]byte, string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return ed25519.Sign(s.key, []byte(digest)), Fingerprint(s.key.Public()), nil
+}
+
+// PublicKey returns the base64-encoded public key for verifiers.
+func (s *Signer) PublicKey() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// Rotate reads the key file again, so a key replaced on disk is used
+// without a restart. It returns the new key's fingerprint.
+func (s *Signer) Rotate(_ context.Context) (string, error) {
+	key, err := readPrivateKey(s.path)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.key = key
+	return Fingerprint(key.Public()), nil
+}
+
+// Verifier checks signatures made by a Signer, or by a KMS key whose
+// public key was exported to a file.
+type Verifier struct {
+	key crypto.PublicKey
+}
+
+// LoadVerifier reads a PEM-encoded PKIX ed25519 or ECDSA P-256 public key
+// from path.
+func LoadVerifier(path string) (*Verifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading verification key: %w", err)
+	}
+
+	key, err := ParsePublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("verification key %s: %w", path, err)
+	}
+	return &Verifier{key: key}, nil
+}
+
+// Verify checks a signature over the given digest string. Verifier holds
+// a single key, so keyID is not used.
+func (v *Verifier) Verify(_ context.Context, _, digest string, signature []byte) error {
+	return VerifySignature(v.key, digest, signature)
+}
+
+// ParsePublicKey parses a PEM-encoded PKIX ed25519 or ECDSA P-256 public
+// key.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key: %w", err)
+	}
+	if _, err := Algorithm(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Algorithm returns the signature algorithm of a public key: ed25519, or
+// ECDSA on P-256 over the SHA-256 of the digest string.
+func Algorithm(key crypto.PublicKey) (string, error) {
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		return AlgorithmEd25519, nil
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return AlgorithmECDSAP256, nil
+		}
+		return "", fmt.Errorf("ECDSA key on %s, want P-256", k.Curve.Params().Name)
+	default:
+		return "", fmt.Errorf("key is %T, want ed25519 or ECDSA P-256", key)
+	}
+}
+
+// VerifySignature checks a signature over the given digest string with key.
+// ECDSA signatures are ASN.1 DER, as AWS KMS, Cloud KMS and Vault return
+// them.
+func VerifySignature(key crypto.PublicKey, digest string, signature []byte) error {
+	var ok bool
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, []byte(digest), signature)
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256([]byte(digest))
+		ok = ecdsa.VerifyASN1(k, sum[:], signature)
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+	if !ok {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// Fingerprint identifies a public key: the first 16 hex digits of the
+// SHA-256 of its PKIX encoding.
+func Fingerprint(key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
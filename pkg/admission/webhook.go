@@ -0,0 +1,232 @@
+// Package admission calls external webhooks that review a candidate
+// resource before it is accepted. A webhook can reject the resource or, if
+// it is mutating, return a replacement spec.
+package admission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"sigs.k8s.io/yaml"
+)
+
+// Failure policies decide what happens when a webhook can't be reached or
+// returns garbage.
+const (
+	FailurePolicyFail   = "Fail"
+	FailurePolicyIgnore = "Ignore"
+)
+
+// defaultTimeout bounds a webhook call that sets no timeout.
+const defaultTimeout = 5 * time.Second
+
+// Webhook is an external reviewer.
+//
+//	webhooks:
+//	  - name: security-checks
+//	    url: https://policy.internal/review
+//	    types: [database]
+//	    mutating: true
+//	    failurePolicy: Ignore
+//	    timeout: 3s
+type Webhook struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+
+	// Types limits the webhook to some resource types. Empty means all.
+	Types []string `json:"types,omitempty"`
+
+	// Mutating webhooks may return a replacement spec. They run before
+	// validating ones, in configuration order.
+	Mutating bool `json:"mutating,omitempty"`
+
+	// FailurePolicy is Fail (the default) or Ignore.
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+
+	// Timeout is a Go duration. Defaults to 5s.
+	Timeout string `json:"timeout,omitempty"`
+
+	timeout time.Duration
+}
+
+// Config lists the configured webhooks.
+type Config struct {
+	Webhooks []Webhook `json:"webhooks"`
+
+	httpClient *http.Client
+}
+
+// LoadConfig reads a Config from a YAML file and applies defaults.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading admission webhooks: %w", err)
+	}
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing admission webhooks: %w", err)
+	}
+
+	for i := range cfg.Webhooks {
+		wh := &cfg.Webhooks[i]
+		if wh.Name == "" {
+			return nil, fmt.Errorf("admission webhook %d: name is required", i)
+		}
+		if wh.URL == "" {
+			return nil, fmt.Errorf("admission webhook %s: url is required", wh.Name)
+		}
+		switch wh.FailurePolicy {
+		case "":
+			wh.FailurePolicy = FailurePolicyFail
+		case FailurePolicyFail, FailurePolicyIgnore:
+		default:
+			return nil, fmt.Errorf("admission webhook %s: unknown failure policy %q (want Fail or Ignore)", wh.Name, wh.FailurePolicy)
+		}
+		wh.timeout = defaultTimeout
+		if wh.Timeout != "" {
+			if wh.timeout, err = time.ParseDuration(wh.Timeout); err != nil || wh.timeout <= 0 {
+				return nil, fmt.Errorf("admission webhook %s: invalid timeout %q", wh.Name, wh.Timeout)
+			}
+		}
+	}
+
+	// Mutating webhooks run first so validating ones see the final spec.
+	slices.SortStableFunc(cfg.Webhooks, func(a, b Webhook) int {
+		switch {
+		case a.Mutating == b.Mutating:
+			return 0
+		case a.Mutating:
+			return -1
+		}
+		return 1
+	})
+	cfg.httpClient = &http.Client{}
+	return &cfg, nil
+}
+
+// Review is the body POSTed to a webhook.
+type Review struct {
+	Namespace string             `json:"namespace"`
+	Name      string             `json:"name"`
+	Spec      model.ResourceSpec `json:"spec"`
+
+	// Manifest is the candidate PlatformResource YAML.
+	Manifest string `json:"manifest"`
+}
+
+// Response is what a webhook returns. Spec is honored only from mutating
+// webhooks.
+type Response struct {
+	Allowed bool                `json:"allowed"`
+	Message string              `json:"message,omitempty"`
+	Spec    *model.ResourceSpec `json:"spec,omitempty"`
+}
+
+// Decision records one webhook's verdict, for auditing.
+type Decision struct {
+	Webhook string
+	Allowed bool
+	Mutated bool
+	Message string
+	Err     error // set if the call failed and the failure policy ignored it
+}
+
+// DeniedError is returned when a webhook rejects a resource.
+type DeniedError struct {
+	Webhook string
+	Message string
+}
+
+func (e *DeniedError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("admission webhook %s denied the request", e.Webhook)
+	}
+	return fmt.Sprintf("admission webhook %s denied the request: %s", e.Webhook, e.Message)
+}
+
+// Applies reports whether the webhook reviews resources of the given type.
+func (wh *Webhook) Applies(resourceType string) bool {
+	return len(wh.Types) == 0 || slices.Contains(wh.Types, resourceType)
+}
+
+// Review sends review to every applicable webhook in turn. Each webhook sees
+// the spec as mutated by the ones before it, and render re-renders the
+// manifest after a mutation. It returns the final spec and every decision
+// made, including the denial if one webhook rejected the resource (which
+// also yields a *DeniedError).
+func (c *Config) Review(ctx context.Context, review Review, render func(model.ResourceSpec) ([]byte, error)) (model.ResourceSpec, []Decision, error) {
+	var decisions []Decision
+	for i := range c.Webhooks {
+		wh := &c.Webhooks[i]
+		if !wh.Applies(review.Spec.Type) {
+			continue
+		}
+
+		resp, err := c.call(ctx, wh, review)
+		if err != nil {
+			if wh.FailurePolicy == FailurePolicyIgnore {
+				decisions = append(decisions, Decision{Webhook: wh.Name, Allowed: true, Err: err})
+				continue
+			}
+			return review.Spec, decisions, err
+		}
+
+		decision := Decision{Webhook: wh.Name, Allowed: resp.Allowed, Message: resp.Message}
+		if !resp.Allowed {
+			decisions = append(decisions, decision)
+			return review.Spec, decisions, &DeniedError{Webhook: wh.Name, Message: resp.Message}
+		}
+		if wh.Mutating && resp.Spec != nil && !resp.Spec.Equal(review.Spec) {
+			review.Spec = *resp.Spec
+			manifest, err := render(review.Spec)
+			if err != nil {
+				return review.Spec, decisions, fmt.Errorf("rendering spec from admission webhook %s: %w", wh.Name, err)
+			}
+			review.Manifest = string(manifest)
+			decision.Mutated = true
+		}
+		decisions = append(decisions, decision)
+	}
+	return review.Spec, decisions, nil
+}
+
+// call POSTs a review to one webhook.
+func (c *Config) call(ctx context.Context, wh *Webhook, review Review) (Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, wh.timeout)
+	defer cancel()
+
+	body, err := json.Marshal(review)
+	if err != nil {
+		return Response{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return Response{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Response{}, fmt.Errorf("calling admission webhook %s: %w", wh.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Response{}, fmt.Errorf("admission webhook %s returned %s: %s", wh.Name, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var out Response
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Response{}, fmt.Errorf("parsing admission webhook %s response: %w", wh.Name, err)
+	}
+	return out, nil
+}
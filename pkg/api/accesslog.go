@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+)
+
+// AccessLogOptions configures the access log. Requests flagged slow or
+// large, and failures (5xx), are always logged; other requests are
+// sampled.
+type AccessLogOptions struct {
+	// SlowThreshold flags requests that take at least this long. Zero
+	// disables the flag.
+	SlowThreshold time.Duration
+
+	// LargeThreshold flags requests whose request or response body is at
+	// least this many bytes. Zero disables the flag.
+	LargeThreshold int64
+
+	// SampleRate is the fraction of other requests logged, from 0 to 1.
+	SampleRate float64
+}
+
+// accessEntry collects what the access log learns about a request from
+// inside the middleware stack.
+type accessEntry struct {
+	actor        string
+	impersonator string
+}
+
+type accessEntryKey struct{}
+
+// accessWriter records the status and size of a response.
+type accessWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (aw *accessWriter) WriteHeader(status int) {
+	if aw.status == 0 {
+		aw.status = status
+	}
+	aw.ResponseWriter.WriteHeader(status)
+}
+
+func (aw *accessWriter) Write(p []byte) (int, error) {
+	if aw.status == 0 {
+		aw.status = http.StatusOK
+	}
+	n, err := aw.ResponseWriter.Write(p)
+	aw.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (aw *accessWriter) Unwrap() http.ResponseWriter {
+	return aw.ResponseWriter
+}
+
+// countingBody counts the bytes of a request body the handler reads.
+type countingBody struct {
+	io.ReadCloser
+	bytes int64
+}
+
+func (cb *countingBody) Read(p []byte) (int, error) {
+	n, err := cb.ReadCloser.Read(p)
+	cb.bytes += int64(n)
+	return n, err
+}
+
+// accessLog logs one line per request: method, route, status, duration,
+// request and response body sizes, caller and request ID. routes, if
+// set, names the route as the pattern it matched rather than the path, so
+// lines for the same endpoint group together.
+func (h *Handler) accessLog(routes *http.ServeMux) Middleware {
+	opts := *h.accessLogOpts
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			entry := &accessEntry{}
+			aw := &accessWriter{ResponseWriter: w}
+			body := &countingBody{ReadCloser: r.Body}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = body
+			}
+			next.ServeHTTP(aw, r.WithContext(context.WithValue(r.Context(), accessEntryKey{}, entry)))
+
+			elapsed := time.Since(start)
+			status := aw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			var flags []string
+			if opts.SlowThreshold > 0 && elapsed >= opts.SlowThreshold {
+				flags = append(flags, "slow")
+			}
+			if opts.LargeThreshold > 0 && (body.bytes >= opts.LargeThreshold || aw.bytes >= opts.LargeThreshold) {
+				flags = append(flags, "large")
+			}
+			if len(flags) == 0 && status < 500 && rand.Float64() >= opts.SampleRate {
+				return
+			}
+
+			route := r.URL.Path
+			if routes != nil {
+				if _, pattern := routes.Handler(r); pattern != "" {
+					// Drop the method: the line starts with it.
+					_, path, found := strings.Cut(pattern, " ")
+					if !found {
+						path = pattern
+					}
+					route = path
+				}
+			}
+			actor := entry.actor
+			if actor == "" {
+				// Rejected before authentication finished.
+				actor = "-"
+			}
+			line := fmt.Sprintf("Access: %s %s status=%d duration=%s in=%d out=%d user=%s request=%s",
+				r.Method, route, status, elapsed.Round(time.Millisecond), body.bytes, aw.bytes, actor, RequestID(r.Context()))
+			if entry.impersonator != "" {
+				line += " impersonatedBy=" + entry.impersonator
+			}
+			if len(flags) > 0 {
+				line += " flags=" + strings.Join(flags, ",")
+			}
+			log.Print(line)
+		})
+	}
+}
+
+// noteCaller records the authenticated caller for the access log. Wrap
+// puts it inside the configured middleware, after authentication.
+func noteCaller(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if entry, ok := r.Context().Value(accessEntryKey{}).(*accessEntry); ok {
+			entry.actor = auth.Actor(r.Context())
+			if id, ok := auth.FromContext(r.Context()); ok {
+				entry.impersonator = id.ImpersonatedBy
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,414 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"sigs.k8s.io/yaml"
+)
+
+// MigrateResources handles POST /api/v1/admin/migrate.
+// It re-renders every resource stored with an older schema version at
+// model.CurrentSchemaVersion, keeping existing secrets, and publishes the
+// catalog once at the end. With ?dryRun=true it only reports what would change.
+// With ?async=true it runs as a background job.
+func (h *Handler) MigrateResources(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+	if !dryRun && !h.checkFreeze(w, r, "") {
+		return
+	}
+
+	if async(r) {
+		h.startJob(w, r, "migrate", func(ctx context.Context) (any, error) {
+			return h.migrate(ctx, dryRun), nil
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, h.migrate(r.Context(), dryRun))
+}
+
+// migrate runs a schema migration. It stops early if ctx is cancelled.
+func (h *Handler) migrate(ctx context.Context, dryRun bool) model.MigrationResponse {
+	ctx = withSource(ctx, SourceMigration)
+	result := model.MigrationResponse{
+		TargetVersion: model.CurrentSchemaVersion,
+		DryRun:        dryRun,
+		Migrated:      []string{},
+		Failed:        map[string]string{},
+	}
+
+	all := h.catalog.List()
+	keys := make([]string, 0, len(all))
+	for key := range all {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			break
+		}
+		namespace, name, _ := strings.Cut(key, "/")
+		data := all[key]
+
+		var pr model.PlatformResource
+		if err := yaml.Unmarshal(data, &pr); err != nil {
+			result.Failed[key] = "parsing stored manifest: " + err.Error()
+			continue
+		}
+
+		from, err := model.ParseSchemaVersion(pr.APIVersion)
+		if err != nil {
+			result.Failed[key] = err.Error()
+			continue
+		}
+		if from == model.CurrentSchemaVersion {
+			result.Unchanged++
+			continue
+		}
+		if dryRun {
+			result.Migrated = append(result.Migrated, key)
+			continue
+		}
+
+		req := model.ResourceRequest{APIVersion: pr.APIVersion, Name: name, Spec: pr.Spec}
+		if err := req.ConvertToCurrent(); err != nil {
+			result.Failed[key] = err.Error()
+			continue
+		}
+		if _, err := h.pushResource(ctx, namespace, &req, applyOptions{extra: secretDocuments(data)}); err != nil {
+			result.Failed[key] = err.Error()
+			continue
+		}
+		result.Migrated = append(result.Migrated, key)
+	}
+
+	// Publish what was migrated even if the migration was cancelled.
+	if !dryRun && len(result.Migrated) > 0 {
+		if err := h.catalog.PushCatalog(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("Warning: failed to push catalog: %v", err)
+		}
+	}
+
+	log.Printf("Migration to %s: %d migrated, %d unchanged, %d failed (dryRun=%t)",
+		model.CurrentSchemaVersion, len(result.Migrated), result.Unchanged, len(result.Failed), dryRun)
+	return result
+}
+
+// MigrateTypes handles POST /api/v1/admin/migrate-types.
+// It moves every resource of a deprecated type or size to its replacement,
+// or to toType and toSize, by pushing a new version of each, and publishes
+// the catalog once at the end. With ?dryRun=true it only reports what would
+// change. With ?async=true it runs as a background job.
+func (h *Handler) MigrateTypes(w http.ResponseWriter, r *http.Request) {
+	var req model.TypeMigrationRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	req = req.WithReplacements()
+	if err := req.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+	if !dryRun && !h.checkFreeze(w, r, "") {
+		return
+	}
+
+	if async(r) {
+		h.startJob(w, r, "migrate-types", func(ctx context.Context) (any, error) {
+			return h.migrateTypes(ctx, req, dryRun), nil
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, h.migrateTypes(r.Context(), req, dryRun))
+}
+
+// migrateTypes runs a type or size migration. It stops early if ctx is
+// cancelled.
+func (h *Handler) migrateTypes(ctx context.Context, m model.TypeMigrationRequest, dryRun bool) model.TypeMigrationResponse {
+	ctx = withSource(ctx, SourceMigration)
+	result := model.TypeMigrationResponse{
+		TypeMigrationRequest: m,
+		DryRun:               dryRun,
+		Migrated:             []string{},
+		Failed:               map[string]string{},
+	}
+
+	all := h.catalog.List()
+	keys := make([]string, 0, len(all))
+	for key := range all {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			break
+		}
+		namespace, name, _ := strings.Cut(key, "/")
+		data := all[key]
+
+		var pr model.PlatformResource
+		if err := yaml.Unmarshal(data, &pr); err != nil {
+			result.Failed[key] = "parsing stored manifest: " + err.Error()
+			continue
+		}
+		if !m.Matches(pr.Spec) {
+			continue
+		}
+
+		req := model.ResourceRequest{APIVersion: pr.APIVersion, Name: name, Spec: pr.Spec}
+		if err := req.ConvertToCurrent(); err != nil {
+			result.Failed[key] = err.Error()
+			continue
+		}
+		req.Spec = m.Apply(req.Spec)
+		if err := req.Validate(); err != nil {
+			result.Failed[key] = err.Error()
+			continue
+		}
+		if dryRun {
+			result.Migrated = append(result.Migrated, key)
+			continue
+		}
+		if _, err := h.pushResource(ctx, namespace, &req, applyOptions{extra: secretDocuments(data)}); err != nil {
+			result.Failed[key] = err.Error()
+			continue
+		}
+		result.Migrated = append(result.Migrated, key)
+	}
+
+	// Publish what was migrated even if the migration was cancelled.
+	if !dryRun && len(result.Migrated) > 0 {
+		if err := h.catalog.PushCatalog(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("Warning: failed to push catalog: %v", err)
+		}
+	}
+
+	log.Printf("Type migration (type=%q size=%q -> type=%q size=%q): %d migrated, %d failed (dryRun=%t)",
+		m.Type, m.Size, m.ToType, m.ToSize, len(result.Migrated), len(result.Failed), dryRun)
+	return result
+}
+
+// MigrateFormat handles POST /api/v1/admin/migrate-format.
+// It pushes a new version of every resource whose latest artifact is in
+// another format than the one this server writes, with the same documents
+// and annotations. With ?dryRun=true it only reports what would change.
+// With ?async=true it runs as a background job.
+func (h *Handler) MigrateFormat(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+	if !dryRun && !h.checkFreeze(w, r, "") {
+		return
+	}
+
+	if async(r) {
+		h.startJob(w, r, "migrate-format", func(ctx context.Context) (any, error) {
+			return h.migrateFormat(ctx, dryRun), nil
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, h.migrateFormat(r.Context(), dryRun))
+}
+
+// migrateFormat runs an artifact format migration. It stops early if ctx
+// is cancelled.
+func (h *Handler) migrateFormat(ctx context.Context, dryRun bool) model.FormatMigrationResponse {
+	ctx = withSource(ctx, SourceMigration)
+	target := h.ociClient.ArtifactFormat()
+	result := model.FormatMigrationResponse{
+		TargetFormat: target.String(),
+		DryRun:       dryRun,
+		Migrated:     []string{},
+		Failed:       map[string]string{},
+	}
+
+	all := h.catalog.List()
+	keys := make([]string, 0, len(all))
+	for key := range all {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			break
+		}
+		namespace, name, _ := strings.Cut(key, "/")
+		if err := h.migrateResourceFormat(ctx, namespace, name, dryRun, &result); err != nil {
+			result.Failed[key] = err.Error()
+		}
+	}
+
+	log.Printf("Format migration to %s: %d migrated, %d unchanged, %d failed (dryRun=%t)",
+		target, len(result.Migrated), result.Unchanged, len(result.Failed), dryRun)
+	return result
+}
+
+func (h *Handler) migrateResourceFormat(ctx context.Context, namespace, name string, dryRun bool, result *model.FormatMigrationResponse) error {
+	key := namespace + "/" + name
+	unlock := h.catalog.LockResource(namespace, name)
+	defer unlock()
+
+	head, ok, err := h.ociClient.HeadResource(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	if !ok || head.Deleted || head.Format == h.ociClient.ArtifactFormat() {
+		result.Unchanged++
+		return nil
+	}
+	if dryRun {
+		result.Migrated = append(result.Migrated, key)
+		return nil
+	}
+
+	if _, _, ok, err := h.ociClient.RepackResource(ctx, namespace, name, changeAnnotations(ctx)); err != nil {
+		return err
+	} else if !ok {
+		result.Unchanged++
+		return nil
+	}
+	if err := h.catalog.refreshResource(ctx, namespace, name); err != nil {
+		return fmt.Errorf("reloading migrated resource: %w", err)
+	}
+	result.Migrated = append(result.Migrated, key)
+	return nil
+}
+
+// Reencrypt handles POST /api/v1/admin/reencrypt.
+// It pushes a new version of every resource whose latest artifact is not
+// encrypted with the current key, with the same documents and annotations.
+// With ?dryRun=true it only reports what would change. With ?async=true it
+// runs as a background job.
+func (h *Handler) Reencrypt(w http.ResponseWriter, r *http.Request) {
+	if h.ociClient.EncryptionKeyID() == "" {
+		writeError(w, http.StatusConflict, "encryption is not configured")
+		return
+	}
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+	if !dryRun && !h.checkFreeze(w, r, "") {
+		return
+	}
+
+	if async(r) {
+		h.startJob(w, r, "reencrypt", func(ctx context.Context) (any, error) {
+			return h.reencrypt(ctx, dryRun), nil
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, h.reencrypt(r.Context(), dryRun))
+}
+
+// reencrypt runs a re-encryption. It stops early if ctx is cancelled.
+func (h *Handler) reencrypt(ctx context.Context, dryRun bool) model.ReencryptionResponse {
+	ctx = withSource(ctx, SourceMigration)
+	result := model.ReencryptionResponse{
+		KeyID:       h.ociClient.EncryptionKeyID(),
+		DryRun:      dryRun,
+		Reencrypted: []string{},
+		Failed:      map[string]string{},
+	}
+
+	all := h.catalog.List()
+	keys := make([]string, 0, len(all))
+	for key := range all {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			break
+		}
+		namespace, name, _ := strings.Cut(key, "/")
+		if err := h.reencryptResource(ctx, namespace, name, dryRun, &result); err != nil {
+			result.Failed[key] = err.Error()
+		}
+	}
+
+	log.Printf("Re-encryption with key %s: %d re-encrypted, %d unchanged, %d failed (dryRun=%t)",
+		result.KeyID, len(result.Reencrypted), result.Unchanged, len(result.Failed), dryRun)
+	return result
+}
+
+func (h *Handler) reencryptResource(ctx context.Context, namespace, name string, dryRun bool, result *model.ReencryptionResponse) error {
+	key := namespace + "/" + name
+	unlock := h.catalog.LockResource(namespace, name)
+	defer unlock()
+
+	head, ok, err := h.ociClient.HeadResource(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	if !ok || head.Deleted || head.KeyID == result.KeyID {
+		result.Unchanged++
+		return nil
+	}
+	if dryRun {
+		result.Reencrypted = append(result.Reencrypted, key)
+		return nil
+	}
+
+	if _, _, ok, err := h.ociClient.ReencryptResource(ctx, namespace, name, changeAnnotations(ctx)); err != nil {
+		return err
+	} else if !ok {
+		result.Unchanged++
+		return nil
+	}
+	if err := h.catalog.refreshResource(ctx, namespace, name); err != nil {
+		return fmt.Errorf("reloading re-encrypted resource: %w", err)
+	}
+	result.Reencrypted = append(result.Reencrypted, key)
+	return nil
+}
+
+// GetRegistryStatus handles GET /api/v1/admin/registry.
+// It probes the registry and reports reachability, capabilities, the number
+// of resource repositories and when the last push and pull succeeded.
+// Responds 503 if the registry is unreachable.
+func (h *Handler) GetRegistryStatus(w http.ResponseWriter, r *http.Request) {
+	status := h.ociClient.RegistryStatus(r.Context())
+
+	resp := model.RegistryStatusResponse{
+		Host:            status.Host,
+		Backend:         status.Backend,
+		Reachable:       status.Reachable,
+		Error:           status.Error,
+		APIVersion:      status.APIVersion,
+		ReferrersAPI:    status.ReferrersAPI,
+		RepositoryCount: status.RepositoryCount,
+		ArtifactFormat:  h.ociClient.ArtifactFormat().String(),
+		EncryptionKey:   h.ociClient.EncryptionKeyID(),
+	}
+	if !status.LastPush.IsZero() {
+		resp.LastPush = status.LastPush.UTC().Format(time.RFC3339)
+	}
+	if !status.LastPull.IsZero() {
+		resp.LastPull = status.LastPull.UTC().Format(time.RFC3339)
+	}
+
+	code := http.StatusOK
+	if !status.Reachable {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, resp)
+}
+
+// GetQuarantine handles GET /api/v1/admin/quarantine.
+// It lists resources whose latest artifact failed verification on restore
+// and was left out of the catalog.
+func (h *Handler) GetQuarantine(w http.ResponseWriter, _ *http.Request) {
+	quarantined := h.catalog.Quarantined()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"artifacts": quarantined,
+		"count":     len(quarantined),
+	})
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/oci/ocitest"
+)
+
+func TestMigrateTypesIsAdminOnly(t *testing.T) {
+	client, _ := ocitest.NewClient("gitops-squared/resources")
+	h := NewHandler(client, NewCatalogManager(client, CatalogOptions{}), HandlerOptions{AdminGroups: []string{"admins"}})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for _, tc := range []struct {
+		groups []string
+		want   int
+	}{
+		{[]string{"developers"}, http.StatusForbidden},
+		{[]string{"admins"}, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/migrate-types?dryRun=true", strings.NewReader(`{"type": "bucket", "toType": "database"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(auth.WithIdentity(context.Background(), auth.Identity{User: "alice", Groups: tc.groups}))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("caller in %v: status %d, want %d: %s", tc.groups, rec.Code, tc.want, rec.Body)
+		}
+	}
+}
+
+func TestTemplateWritesAreAdminOnly(t *testing.T) {
+	client, _ := ocitest.NewClient("gitops-squared/resources")
+	h := NewHandler(client, NewCatalogManager(client, CatalogOptions{}), HandlerOptions{AdminGroups: []string{"admins"}})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for _, tc := range []struct {
+		method, target, body string
+		groups               []string
+		want                 int
+	}{
+		{http.MethodPost, "/api/v1/templates", `{"name": "pg", "spec": {"type": "database", "size": "small"}}`, []string{"developers"}, http.StatusForbidden},
+		{http.MethodPost, "/api/v1/templates", `{"name": "pg", "spec": {"type": "database", "size": "small"}}`, []string{"admins"}, http.StatusCreated},
+		{http.MethodDelete, "/api/v1/templates/pg", "", []string{"developers"}, http.StatusForbidden},
+		{http.MethodDelete, "/api/v1/templates/pg", "", []string{"admins"}, http.StatusNoContent},
+	} {
+		req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(auth.WithIdentity(context.Background(), auth.Identity{User: "alice", Groups: tc.groups}))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s %s by caller in %v: status %d, want %d: %s", tc.method, tc.target, tc.groups, rec.Code, tc.want, rec.Body)
+		}
+	}
+}
+
+func TestPromoteCatalogIsAdminOnly(t *testing.T) {
+	client, _ := ocitest.NewClient("gitops-squared/resources")
+	h := NewHandler(client, NewCatalogManager(client, CatalogOptions{}), HandlerOptions{AdminGroups: []string{"admins"}})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/catalog/channels/stable/promote", strings.NewReader(`{"from": "canary"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(auth.WithIdentity(context.Background(), auth.Identity{User: "alice", Groups: []string{"developers"}}))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("promotion by a developer: status %d, want 403: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestCanaryOverridesAreAdminOnly(t *testing.T) {
+	client, _ := ocitest.NewClient("gitops-squared/resources")
+	h := NewHandler(client, NewCatalogManager(client, CatalogOptions{}), HandlerOptions{AdminGroups: []string{"admins"}})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for _, tc := range []struct {
+		method, target, body string
+	}{
+		{http.MethodPost, "/api/v1/catalog/canary/promote", ""},
+		{http.MethodPost, "/api/v1/catalog/canary/hold", `{"reason": "investigating"}`},
+		{http.MethodDelete, "/api/v1/catalog/canary/hold", ""},
+	} {
+		req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(auth.WithIdentity(context.Background(), auth.Identity{User: "alice", Groups: []string{"developers"}}))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s by a developer: status %d, want 403: %s", tc.method, tc.target, rec.Code, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/alfredtm/gitops-squared/pkg/admission"
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// admit sends req to the admission webhooks, applies any mutation to its
+// spec and re-validates it. Every decision is logged for auditing.
+func (h *Handler) admit(ctx context.Context, namespace string, req *model.ResourceRequest) error {
+	if h.admission == nil {
+		return nil
+	}
+
+	render := func(spec model.ResourceSpec) ([]byte, error) {
+		candidate := *req
+		candidate.Spec = spec
+		return candidate.ToKubernetesYAML(namespace, model.ManifestAnnotations{Version: "pending"})
+	}
+	spec := req.Spec.WithDefaults()
+	manifest, err := render(spec)
+	if err != nil {
+		return fmt.Errorf("generating YAML: %w", err)
+	}
+
+	review := admission.Review{
+		Namespace: namespace,
+		Name:      req.Name,
+		Spec:      spec,
+		Manifest:  string(manifest),
+	}
+	mutated, decisions, err := h.admission.Review(ctx, review, render)
+	for _, d := range decisions {
+		if d.Err != nil {
+			log.Printf("Warning: ignoring failed admission webhook %s for %s/%s: %v", d.Webhook, namespace, req.Name, d.Err)
+			continue
+		}
+		log.Printf("Audit: admission webhook %s on %s/%s by %s: allowed=%t mutated=%t message=%q",
+			d.Webhook, namespace, req.Name, auth.Actor(ctx), d.Allowed, d.Mutated, d.Message)
+	}
+	if err != nil {
+		var denied *admission.DeniedError
+		if errors.As(err, &denied) {
+			h.events.Record(ctx, model.Event{
+				Type:      model.EventAdmissionDenied,
+				Namespace: namespace,
+				Name:      req.Name,
+				Message:   denied.Error(),
+			})
+		}
+		return err
+	}
+
+	if !mutated.Equal(spec) {
+		candidate := *req
+		candidate.Spec = mutated
+		if err := candidate.Validate(); err != nil {
+			return fmt.Errorf("admission webhooks returned an invalid spec: %w", err)
+		}
+		req.Spec = mutated
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+	"oras.land/oras-go/v2/errdef"
+	"sigs.k8s.io/yaml"
+)
+
+// adoptedDocument is the part of a foreign manifest document adoption
+// looks at.
+type adoptedDocument struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	SOPS map[string]any `json:"sops"`
+}
+
+// errUnadoptable marks artifacts that can't be adopted as they are.
+var errUnadoptable = errors.New("artifact can't be adopted")
+
+// AdoptResource handles POST /api/v1/resources/{name}/adopt.
+// It takes over an artifact pushed to the resource's repository by another
+// tool, such as the oras CLI; see adoptArtifact. ?reference= selects the
+// artifact and defaults to "latest".
+func (h *Handler) AdoptResource(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	namespace, ok := resourceNamespace(w, r)
+	if !ok || !h.checkFreeze(w, r, namespace) || !h.checkNamespace(w, namespace) {
+		return
+	}
+	reference := r.URL.Query().Get("reference")
+	if reference == "" {
+		reference = "latest"
+	}
+
+	status := http.StatusCreated
+	if _, live := h.catalog.Get(namespace, name); live {
+		status = http.StatusOK
+	}
+	resp, err := h.adoptArtifact(r.Context(), namespace, name, reference)
+	switch {
+	case errors.Is(err, errdef.ErrNotFound):
+		writeError(w, http.StatusNotFound, "resource %q has no artifact %q in the registry", name, reference)
+		return
+	case errors.Is(err, errUnadoptable):
+		writeErrorCode(w, http.StatusUnprocessableEntity, model.CodeUnprocessable, "%v", err)
+		return
+	case err != nil:
+		writeApplyError(w, err)
+		return
+	}
+
+	writeJSON(w, status, resp)
+	log.Printf("Audit: adopted %s/%s from %s (version=%s, dropped=%v) by %s", namespace, name, resp.AdoptedDigest, resp.Version, resp.Dropped, auth.Actor(r.Context()))
+}
+
+// adoptArtifact adopts the artifact at reference in a resource repository:
+// it must hold a valid PlatformResource, which is pushed again as a new
+// version whose parent is the artifact. A live resource can only adopt an
+// artifact pushed over its latest version. ExternalSecret and
+// SOPS-encrypted Secret documents are carried over; other documents are
+// dropped and listed, and plaintext Secrets are refused. Artifacts that
+// can't be read or converted fail with errUnadoptable.
+func (h *Handler) adoptArtifact(ctx context.Context, namespace, name, reference string) (model.AdoptResponse, error) {
+	artifact, err := h.ociClient.PullForeignResource(ctx, namespace, name, reference)
+	if errors.Is(err, errdef.ErrNotFound) {
+		return model.AdoptResponse{}, err
+	}
+	if err != nil {
+		return model.AdoptResponse{}, fmt.Errorf("%w: reading %q: %v", errUnadoptable, reference, err)
+	}
+
+	req, extra, dropped, err := adoptedRequest(namespace, name, artifact)
+	if err != nil {
+		return model.AdoptResponse{}, err
+	}
+	h.defaults.Apply(namespace, &req)
+	if err := req.ConvertToCurrent(); err != nil {
+		return model.AdoptResponse{}, fmt.Errorf("%w: %v", errUnadoptable, err)
+	}
+	if err := req.Validate(); err != nil {
+		return model.AdoptResponse{}, err
+	}
+
+	resp, err := h.applyResourceWith(withSource(ctx, SourceAdoption), namespace, &req, applyOptions{
+		extra: extra,
+		adopt: artifact.Digest,
+	})
+	if err != nil {
+		return model.AdoptResponse{}, err
+	}
+	return model.AdoptResponse{ResourceResponse: resp, AdoptedDigest: artifact.Digest, Dropped: dropped}, nil
+}
+
+// adoptedRequest turns the documents of a foreign artifact into a request
+// and the companion documents carried over with it. The first document must
+// be the resource's PlatformResource.
+func adoptedRequest(namespace, name string, artifact oci.ForeignArtifact) (req model.ResourceRequest, extra [][]byte, dropped []string, err error) {
+	docs := splitDocuments(artifact.Manifest)
+	if len(docs) == 0 {
+		return req, nil, nil, fmt.Errorf("%w: %s holds no YAML documents", errUnadoptable, artifact.Digest)
+	}
+
+	var pr model.PlatformResource
+	if err := yaml.Unmarshal(docs[0], &pr); err != nil {
+		return req, nil, nil, fmt.Errorf("%w: %s: invalid PlatformResource: %v", errUnadoptable, artifact.Digest, err)
+	}
+	var e model.ValidationError
+	if pr.Kind != "PlatformResource" {
+		e.Errors = append(e.Errors, model.NewFieldError("kind", model.CodeWrongKind, model.Params{"kind": "PlatformResource"}))
+	}
+	if pr.Metadata.Name != name {
+		e.Errors = append(e.Errors, model.NewFieldError("metadata.name", model.CodeRepositoryMismatch, model.Params{"value": name}))
+	}
+	if pr.Metadata.Namespace != "" && pr.Metadata.Namespace != namespace {
+		e.Errors = append(e.Errors, model.NewFieldError("metadata.namespace", model.CodeRepositoryMismatch, model.Params{"value": namespace}))
+	}
+	if len(e.Errors) > 0 {
+		return req, nil, nil, &e
+	}
+
+	for _, doc := range docs[1:] {
+		var obj adoptedDocument
+		if err := yaml.Unmarshal(doc, &obj); err != nil {
+			return req, nil, nil, fmt.Errorf("artifact %s: invalid document: %w", artifact.Digest, err)
+		}
+		switch {
+		case obj.Kind == "ExternalSecret", obj.Kind == "Secret" && obj.SOPS != nil:
+			extra = append(extra, doc)
+		case obj.Kind == "Secret":
+			return req, nil, nil, fmt.Errorf("%w: %s: Secret %q is not SOPS-encrypted", errUnadoptable, artifact.Digest, obj.Metadata.Name)
+		default:
+			dropped = append(dropped, obj.Kind+"/"+obj.Metadata.Name)
+		}
+	}
+	return model.ResourceRequest{APIVersion: pr.APIVersion, Name: name, Spec: pr.Spec}, extra, dropped, nil
+}
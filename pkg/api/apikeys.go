@@ -0,0 +1,364 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// apiKeyPrefix starts every API key token, so the authenticator can tell
+// them from other bearer tokens.
+const apiKeyPrefix = "gsk_"
+
+// lastUsedResolution is how stale a key's last use may be before it is
+// recorded again.
+const lastUsedResolution = time.Minute
+
+// errAPIKeyExists is returned by APIKeyStore.Create for a name in use.
+var errAPIKeyExists = errors.New("API key already exists")
+
+// apiKeyRecord is a stored API key: its description, the SHA-256 of its
+// token and a random ID that sessions issued for it are bound to. Tokens
+// are random, so a fast hash is enough.
+type apiKeyRecord struct {
+	model.APIKey
+	Hash string `json:"hash"`
+	ID   string `json:"id"`
+}
+
+// APIKeyStore holds API keys in memory and persists them to the registry
+// as a single JSON document on every change. Last-use times are recorded
+// in memory and persisted by Flush. It authenticates requests that send a
+// key as a bearer token.
+type APIKeyStore struct {
+	ociClient oci.Interface
+	mu        sync.RWMutex
+	keys      map[string]*apiKeyRecord // name -> key
+	byHash    map[string]*apiKeyRecord
+	dirty     bool // last-use times changed since the last persist
+}
+
+// NewAPIKeyStore creates an empty API key store.
+func NewAPIKeyStore(client oci.Interface) *APIKeyStore {
+	return &APIKeyStore{
+		ociClient: client,
+		keys:      make(map[string]*apiKeyRecord),
+		byHash:    make(map[string]*apiKeyRecord),
+	}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// legacyKeyID derives the ID of a key stored before keys had one from its
+// token hash, so every replica restores the same ID.
+func legacyKeyID(hash string) string {
+	return hashToken("id:" + hash)[:32]
+}
+
+// Get returns a key by name.
+func (ks *APIKeyStore) Get(name string) (model.APIKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	k, ok := ks.keys[name]
+	if !ok {
+		return model.APIKey{}, false
+	}
+	return k.describe(time.Now()), true
+}
+
+// List returns all keys sorted by name.
+func (ks *APIKeyStore) List() []model.APIKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	now := time.Now()
+	list := make([]model.APIKey, 0, len(ks.keys))
+	for _, k := range ks.keys {
+		list = append(list, k.describe(now))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func (k *apiKeyRecord) describe(now time.Time) model.APIKey {
+	key := k.APIKey
+	key.Expired = k.expired(now)
+	return key
+}
+
+func (k *apiKeyRecord) expired(now time.Time) bool {
+	if k.ExpiresAt == "" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, k.ExpiresAt)
+	return err != nil || !now.Before(t)
+}
+
+// Create issues a new key and persists the set. It returns the key and its
+// token, which is not kept.
+func (ks *APIKeyStore) Create(ctx context.Context, key model.APIKey) (model.APIKeyResponse, error) {
+	secret, id := make([]byte, 32), make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return model.APIKeyResponse{}, fmt.Errorf("generating API key: %w", err)
+	}
+	if _, err := rand.Read(id); err != nil {
+		return model.APIKeyResponse{}, fmt.Errorf("generating API key: %w", err)
+	}
+	token := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	k := &apiKeyRecord{APIKey: key, Hash: hashToken(token), ID: hex.EncodeToString(id)}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if _, ok := ks.keys[key.Name]; ok {
+		return model.APIKeyResponse{}, errAPIKeyExists
+	}
+	ks.keys[key.Name] = k
+	ks.byHash[k.Hash] = k
+	if err := ks.persistLocked(ctx); err != nil {
+		delete(ks.keys, key.Name)
+		delete(ks.byHash, k.Hash)
+		return model.APIKeyResponse{}, err
+	}
+	return model.APIKeyResponse{APIKey: key, Token: token}, nil
+}
+
+// Delete revokes a key and persists the set. It reports whether the key
+// existed.
+func (ks *APIKeyStore) Delete(ctx context.Context, name string) (bool, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	k, ok := ks.keys[name]
+	if !ok {
+		return false, nil
+	}
+	delete(ks.keys, name)
+	delete(ks.byHash, k.Hash)
+	if err := ks.persistLocked(ctx); err != nil {
+		ks.keys[name] = k
+		ks.byHash[k.Hash] = k
+		return false, err
+	}
+	return true, nil
+}
+
+// Authenticate identifies requests sending an API key as a bearer token.
+// Other bearer tokens are left to other authenticators; unknown and expired
+// keys are rejected.
+func (ks *APIKeyStore) Authenticate(r *http.Request) (auth.Identity, bool, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || !strings.HasPrefix(token, apiKeyPrefix) {
+		return auth.Identity{}, false, nil
+	}
+
+	now := time.Now()
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	k, ok := ks.byHash[hashToken(token)]
+	if !ok {
+		return auth.Identity{}, false, fmt.Errorf("unknown API key")
+	}
+	if k.expired(now) {
+		return auth.Identity{}, false, fmt.Errorf("API key %s expired at %s", k.Name, k.ExpiresAt)
+	}
+	if last, err := time.Parse(time.RFC3339, k.LastUsedAt); err != nil || now.Sub(last) >= lastUsedResolution {
+		k.LastUsedAt = now.UTC().Format(time.RFC3339)
+		ks.dirty = true
+	}
+
+	scopes := k.Scopes
+	return auth.Identity{
+		User:     k.User(),
+		Groups:   k.Groups,
+		Method:   "apikey",
+		Scopes:   &scopes,
+		APIKey:   k.Name,
+		APIKeyID: k.ID,
+	}, true, nil
+}
+
+// CheckSession rejects a session issued for an API key once the key is
+// revoked or has expired. A key deleted and created again under the same
+// name has a new ID, so it doesn't revive sessions issued before.
+func (ks *APIKeyStore) CheckSession(id auth.Identity) error {
+	if id.APIKey == "" {
+		return nil
+	}
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	k, ok := ks.keys[id.APIKey]
+	if !ok {
+		return fmt.Errorf("API key %s of the session was revoked", id.APIKey)
+	}
+	if k.ID != id.APIKeyID {
+		return fmt.Errorf("API key %s of the session was revoked", id.APIKey)
+	}
+	if k.expired(time.Now()) {
+		return fmt.Errorf("API key %s of the session expired at %s", k.Name, k.ExpiresAt)
+	}
+	return nil
+}
+
+// Flush persists last-use times recorded since the last change.
+func (ks *APIKeyStore) Flush(ctx context.Context) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if !ks.dirty {
+		return nil
+	}
+	return ks.persistLocked(ctx)
+}
+
+// Run flushes last-use times every interval until ctx is done.
+func (ks *APIKeyStore) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ks.Flush(ctx); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+	}
+}
+
+func (ks *APIKeyStore) persistLocked(ctx context.Context) error {
+	list := make([]*apiKeyRecord, 0, len(ks.keys))
+	for _, k := range ks.keys {
+		list = append(list, k)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("encoding API keys: %w", err)
+	}
+	if err := ks.ociClient.PushAPIKeys(ctx, data); err != nil {
+		return fmt.Errorf("pushing API keys: %w", err)
+	}
+	ks.dirty = false
+	return nil
+}
+
+// Restore loads API keys from the registry.
+func (ks *APIKeyStore) Restore(ctx context.Context) error {
+	data, err := ks.ociClient.PullAPIKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("pulling API keys: %w", err)
+	}
+	if data == nil {
+		return nil
+	}
+
+	var list []*apiKeyRecord
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("parsing API keys: %w", err)
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	for _, k := range list {
+		if k.ID == "" {
+			k.ID = legacyKeyID(k.Hash)
+		}
+		ks.keys[k.Name] = k
+		ks.byHash[k.Hash] = k
+	}
+	log.Printf("Restored %d API keys from registry", len(list))
+	return nil
+}
+
+// CreateAPIKey handles POST /api/v1/admin/apikeys.
+// The response carries the key's token, which can't be retrieved again.
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req model.APIKeyRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	key := model.APIKey{
+		Name:      req.Name,
+		Groups:    req.Groups,
+		Scopes:    req.Scopes,
+		CreatedBy: auth.Actor(r.Context()),
+		CreatedAt: now.Format(time.RFC3339),
+	}
+	if expiresAt, ok := req.Expiry(now); ok {
+		key.ExpiresAt = expiresAt.Format(time.RFC3339)
+	}
+	resp, err := h.apiKeys.Create(r.Context(), key)
+	if errors.Is(err, errAPIKeyExists) {
+		writeError(w, http.StatusConflict, "API key %q already exists", req.Name)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, resp)
+	log.Printf("Audit: issued API key %s (groups=%v, scopes=%+v, expires=%q) by %s", key.Name, key.Groups, key.Scopes, key.ExpiresAt, key.CreatedBy)
+}
+
+// ListAPIKeys handles GET /api/v1/admin/apikeys.
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, _ *http.Request) {
+	keys := h.apiKeys.List()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"apiKeys": keys,
+		"count":   len(keys),
+	})
+}
+
+// GetAPIKey handles GET /api/v1/admin/apikeys/{name}.
+func (h *Handler) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	key, ok := h.apiKeys.Get(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "API key %q not found", name)
+		return
+	}
+	writeJSON(w, http.StatusOK, key)
+}
+
+// DeleteAPIKey handles DELETE /api/v1/admin/apikeys/{name}.
+// The key stops working at once.
+func (h *Handler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	existed, err := h.apiKeys.Delete(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	if !existed {
+		writeError(w, http.StatusNotFound, "API key %q not found", name)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("Audit: revoked API key %s by %s", name, auth.Actor(r.Context()))
+}
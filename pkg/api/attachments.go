@@ -0,0 +1,152 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// maxAttachmentSize bounds the size of a single attachment. Attachments
+// are documentation and reports, not binaries.
+const maxAttachmentSize = 10 << 20
+
+// attachmentKey matches attachment keys such as "README.md" or
+// "dashboard.json". Keys become part of an OCI tag.
+var attachmentKey = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// PutAttachment handles PUT /api/v1/resources/{name}/attachments/{key}.
+// The request body is stored as is, with its Content-Type.
+func (h *Handler) PutAttachment(w http.ResponseWriter, r *http.Request) {
+	name, key, namespace, ok := attachmentRequest(w, r)
+	if !ok || !h.checkFreeze(w, r, namespace) {
+		return
+	}
+	if _, ok := h.catalog.Get(namespace, name); !ok {
+		writeError(w, http.StatusNotFound, "resource %q not found", name)
+		return
+	}
+	if err := h.checkLock(r.Context(), namespace, name); err != nil {
+		writeApplyError(w, err)
+		return
+	}
+
+	mediaType := "application/octet-stream"
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if _, _, err := mime.ParseMediaType(ct); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid Content-Type %q", ct)
+			return
+		}
+		mediaType = ct
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAttachmentSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "attachment exceeds %d bytes", maxAttachmentSize)
+			return
+		}
+		writeError(w, http.StatusBadRequest, "reading attachment: %v", err)
+		return
+	}
+
+	attachment, err := h.ociClient.PushAttachment(r.Context(), namespace, name, key, mediaType, data)
+	if err != nil {
+		if errors.Is(err, errdef.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "resource %q not found", name)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "storing attachment: %v", err)
+		return
+	}
+
+	log.Printf("Audit: attachment %s on %s/%s (%d bytes) by %s", key, namespace, name, attachment.Size, auth.Actor(r.Context()))
+	writeJSON(w, http.StatusOK, attachmentResponse(attachment))
+}
+
+// GetAttachment handles GET /api/v1/resources/{name}/attachments/{key}.
+// It serves the attachment's content with the Content-Type it was
+// uploaded with.
+func (h *Handler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	name, key, namespace, ok := attachmentRequest(w, r)
+	if !ok {
+		return
+	}
+
+	data, attachment, err := h.ociClient.PullAttachment(r.Context(), namespace, name, key)
+	if err != nil {
+		switch {
+		case errors.Is(err, errdef.ErrNotFound):
+			writeError(w, http.StatusNotFound, "resource %q has no attachment %q", name, key)
+		case errors.Is(err, oci.ErrIntegrity):
+			writeError(w, http.StatusBadGateway, "attachment %q failed verification: %v", key, err)
+		default:
+			writeError(w, http.StatusInternalServerError, "reading attachment: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", attachment.MediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Docker-Content-Digest", attachment.Digest)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// ListAttachments handles GET /api/v1/resources/{name}/attachments.
+func (h *Handler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	namespace, ok := resourceNamespace(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := h.catalog.Get(namespace, name); !ok {
+		writeError(w, http.StatusNotFound, "resource %q not found", name)
+		return
+	}
+
+	attachments, err := h.ociClient.ListAttachments(r.Context(), namespace, name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "listing attachments: %v", err)
+		return
+	}
+	list := make([]model.AttachmentResponse, 0, len(attachments))
+	for _, a := range attachments {
+		list = append(list, attachmentResponse(a))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"attachments": list,
+		"count":       len(list),
+	})
+}
+
+// attachmentRequest reads and validates the path of an attachment request.
+func attachmentRequest(w http.ResponseWriter, r *http.Request) (name, key, namespace string, ok bool) {
+	name, key = r.PathValue("name"), r.PathValue("key")
+	if !attachmentKey.MatchString(key) {
+		writeError(w, http.StatusBadRequest, "invalid attachment key %q: use letters, digits, '.', '_' and '-' (at most 64)", key)
+		return "", "", "", false
+	}
+	namespace, ok = resourceNamespace(w, r)
+	return name, key, namespace, ok
+}
+
+func attachmentResponse(a oci.Attachment) model.AttachmentResponse {
+	return model.AttachmentResponse{
+		Key:       a.Key,
+		MediaType: a.MediaType,
+		Digest:    a.Digest,
+		Size:      a.Size,
+		Resource:  a.Resource,
+		CreatedAt: a.CreatedAt,
+	}
+}
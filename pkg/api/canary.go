@@ -0,0 +1,321 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// Defaults of CanaryOptions.
+const (
+	defaultCanaryTarget   = "stable"
+	defaultCanaryInterval = 30 * time.Second
+)
+
+// CanaryOptions configures automatic promotion of the catalogs published
+// to the publish channel, the canary, to a target channel.
+type CanaryOptions struct {
+	// SoakPeriod is how long every cluster following the canary channel
+	// must run a catalog and report ready before it is promoted.
+	SoakPeriod time.Duration
+
+	// Target is the channel catalogs are promoted to, "stable" by default.
+	Target string
+
+	// Interval is how often the canary is checked, 30s by default.
+	Interval time.Duration
+}
+
+// canaryRollout tracks the catalog soaking on the canary channel.
+type canaryRollout struct {
+	opts    CanaryOptions
+	channel string
+
+	mu     sync.Mutex
+	digest string    // catalog last seen on the canary channel
+	since  time.Time // when every canary cluster was first seen healthy on digest
+	hold   *model.CanaryHold
+}
+
+func newCanaryRollout(opts CanaryOptions, channel string) *canaryRollout {
+	if opts.Target == "" {
+		opts.Target = defaultCanaryTarget
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultCanaryInterval
+	}
+	return &canaryRollout{opts: opts, channel: channel}
+}
+
+// currentHold returns the hold, or nil if promotion isn't on hold.
+func (c *canaryRollout) currentHold() *model.CanaryHold {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hold
+}
+
+// setHold records a hold, or its release if hold is nil, in the registry.
+func (c *canaryRollout) setHold(ctx context.Context, client oci.Interface, hold *model.CanaryHold) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := json.Marshal(hold)
+	if err != nil {
+		return fmt.Errorf("encoding canary hold: %w", err)
+	}
+	if err := client.PushCanaryHold(ctx, data); err != nil {
+		return fmt.Errorf("pushing canary hold: %w", err)
+	}
+	c.hold = hold
+	return nil
+}
+
+// loadHold reads the hold from the registry, to pick up holds placed
+// through other replicas.
+func (c *canaryRollout) loadHold(ctx context.Context, client oci.Interface) error {
+	data, err := client.PullCanaryHold(ctx)
+	if err != nil {
+		return fmt.Errorf("pulling canary hold: %w", err)
+	}
+	var hold *model.CanaryHold
+	if data != nil {
+		if err := json.Unmarshal(data, &hold); err != nil {
+			return fmt.Errorf("parsing canary hold: %w", err)
+		}
+	}
+	c.mu.Lock()
+	c.hold = hold
+	c.mu.Unlock()
+	return nil
+}
+
+// RestoreCanary loads the hold on canary promotion from the registry. It
+// does nothing unless canary promotion is configured.
+func (h *Handler) RestoreCanary(ctx context.Context) error {
+	if h.canary == nil {
+		return nil
+	}
+	return h.canary.loadHold(ctx, h.ociClient)
+}
+
+// canaryStatus describes the canary rollout at now. The soak period starts
+// once every canary cluster runs the canary catalog and reports ready, and
+// starts over whenever one of them doesn't.
+func (h *Handler) canaryStatus(now time.Time) model.CanaryStatus {
+	c := h.canary
+	digest := h.catalog.channelDigest(c.channel)
+	clusters, healthy := h.canaryClusters(c.channel)
+
+	c.mu.Lock()
+	if digest != c.digest || !healthy {
+		c.digest, c.since = digest, time.Time{}
+	}
+	if healthy && c.since.IsZero() {
+		c.since = now
+	}
+	since, hold := c.since, c.hold
+	c.mu.Unlock()
+
+	status := model.CanaryStatus{
+		Channel:      c.channel,
+		Target:       c.opts.Target,
+		SoakPeriod:   c.opts.SoakPeriod.String(),
+		Digest:       digest,
+		TargetDigest: h.catalog.channelDigest(c.opts.Target),
+		Hold:         hold,
+		Clusters:     clusters,
+	}
+	if !since.IsZero() {
+		status.SoakingSince = since.UTC().Format(time.RFC3339)
+		status.PromoteAt = since.Add(c.opts.SoakPeriod).UTC().Format(time.RFC3339)
+	}
+	switch {
+	case digest == "" || digest == status.TargetDigest:
+		status.State = model.CanaryStateIdle
+	case hold != nil:
+		status.State = model.CanaryStateHeld
+	case len(clusters) == 0:
+		status.State = model.CanaryStateWaiting
+		status.Message = fmt.Sprintf("no registered cluster follows the %s channel", c.channel)
+	case !healthy:
+		status.State = model.CanaryStateWaiting
+		status.Message = "waiting for every canary cluster to run the catalog and report ready"
+	default:
+		status.State = model.CanaryStateSoaking
+	}
+	return status
+}
+
+// canaryClusters returns the registered clusters following channel, and
+// whether there are any and all of them are healthy.
+func (h *Handler) canaryClusters(channel string) ([]model.CanaryCluster, bool) {
+	names := h.clusters.Names()
+	sort.Strings(names)
+	clusters := []model.CanaryCluster{}
+	healthy := true
+	for _, name := range names {
+		if reg, ok := h.clusters.Get(name); !ok || reg.Channel != channel {
+			continue
+		}
+		resp, ok := h.clusterResponse(name)
+		if !ok {
+			continue
+		}
+		cluster := model.CanaryCluster{
+			Name:          name,
+			State:         resp.State,
+			RunningDigest: resp.RunningDigest,
+			Ready:         resp.Ready,
+			Healthy:       resp.State == model.ClusterStateCurrent && resp.Ready != nil && *resp.Ready,
+		}
+		healthy = healthy && cluster.Healthy
+		clusters = append(clusters, cluster)
+	}
+	return clusters, healthy && len(clusters) > 0
+}
+
+// RunCanary checks the canary every interval until ctx is done, and
+// promotes its catalog to the target channel once it has soaked. It does
+// nothing unless canary promotion is configured.
+func (h *Handler) RunCanary(ctx context.Context) {
+	if h.canary == nil {
+		return
+	}
+	ticker := time.NewTicker(h.canary.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := h.checkCanary(ctx, time.Now()); err != nil {
+				log.Printf("Warning: checking canary: %v", err)
+			}
+		}
+	}
+}
+
+// checkCanary re-reads the channels and the hold, and promotes the canary
+// catalog if it is due. Promotion waits while the API is read-only or
+// changes are frozen.
+func (h *Handler) checkCanary(ctx context.Context, now time.Time) error {
+	if err := h.catalog.refreshChannels(ctx); err != nil {
+		return err
+	}
+	if err := h.canary.loadHold(ctx, h.ociClient); err != nil {
+		return err
+	}
+	status := h.canaryStatus(now)
+	if status.State != model.CanaryStateSoaking {
+		return nil
+	}
+	promoteAt, _ := time.Parse(time.RFC3339, status.PromoteAt)
+	if now.Before(promoteAt) || h.readOnly() || h.frozen("", now) {
+		return nil
+	}
+
+	if _, err := h.catalog.Promote(ctx, status.Target, status.Digest); err != nil {
+		return fmt.Errorf("promoting %s to %s: %w", status.Digest, status.Target, err)
+	}
+	log.Printf("Audit: promoted catalog %s from %s to %s after soaking %s on %d clusters by canary",
+		status.Digest, status.Channel, status.Target, status.SoakPeriod, len(status.Clusters))
+	return nil
+}
+
+// canaryConfigured writes 404 and returns false unless canary promotion is
+// configured.
+func (h *Handler) canaryConfigured(w http.ResponseWriter) bool {
+	if h.canary == nil {
+		writeError(w, http.StatusNotFound, "canary promotion is not configured")
+		return false
+	}
+	return true
+}
+
+// GetCanary handles GET /api/v1/catalog/canary.
+func (h *Handler) GetCanary(w http.ResponseWriter, _ *http.Request) {
+	if !h.canaryConfigured(w) {
+		return
+	}
+	writeJSON(w, http.StatusOK, h.canaryStatus(time.Now()))
+}
+
+// PromoteCanary handles POST /api/v1/catalog/canary/promote.
+// It promotes the canary catalog to the target channel now, whatever the
+// soak period, the canary clusters or a hold say.
+func (h *Handler) PromoteCanary(w http.ResponseWriter, r *http.Request) {
+	if !h.canaryConfigured(w) || !h.checkFreeze(w, r, "") {
+		return
+	}
+	digest := h.catalog.channelDigest(h.canary.channel)
+	if digest == "" {
+		writeError(w, http.StatusConflict, "nothing has been published to the %s channel", h.canary.channel)
+		return
+	}
+
+	resp, err := h.catalog.Promote(r.Context(), h.canary.opts.Target, digest)
+	switch {
+	case errors.Is(err, errdef.ErrNotFound):
+		writeError(w, http.StatusNotFound, "catalog %s not found", digest)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadGateway, "promoting catalog: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+	log.Printf("Audit: promoted catalog %s from %s to %s, skipping the soak period, by %s", digest, h.canary.channel, h.canary.opts.Target, auth.Actor(r.Context()))
+}
+
+// HoldCanary handles POST /api/v1/catalog/canary/hold.
+// It stops canary catalogs from being promoted automatically until the
+// hold is released. Placing it again replaces the reason.
+func (h *Handler) HoldCanary(w http.ResponseWriter, r *http.Request) {
+	if !h.canaryConfigured(w) {
+		return
+	}
+	var hold model.CanaryHold
+	if err := decodeJSON(r, &hold); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	if err := hold.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	hold.HeldBy = auth.Actor(r.Context())
+	hold.HeldAt = time.Now().UTC().Format(time.RFC3339)
+
+	if err := h.canary.setHold(r.Context(), h.ociClient, &hold); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.canaryStatus(time.Now()))
+	log.Printf("Audit: held canary promotion (%s) by %s", hold.Reason, hold.HeldBy)
+}
+
+// ReleaseCanary handles DELETE /api/v1/catalog/canary/hold.
+func (h *Handler) ReleaseCanary(w http.ResponseWriter, r *http.Request) {
+	if !h.canaryConfigured(w) {
+		return
+	}
+	if h.canary.currentHold() == nil {
+		writeError(w, http.StatusNotFound, "canary promotion is not on hold")
+		return
+	}
+	if err := h.canary.setHold(r.Context(), h.ociClient, nil); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.canaryStatus(time.Now()))
+	log.Printf("Audit: released canary promotion hold by %s", auth.Actor(r.Context()))
+}
@@ -0,0 +1,1485 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/cost"
+	"github.com/alfredtm/gitops-squared/pkg/hooks"
+	"github.com/alfredtm/gitops-squared/pkg/kube"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/errdef"
+	"sigs.k8s.io/yaml"
+)
+
+// CatalogManager maintains an in-memory index of all resources
+// and assembles the Flux-consumable catalog tarball.
+type CatalogManager struct {
+	ociClient       oci.Interface
+	gracePeriod     time.Duration // how long soft-deleted resources stay restorable
+	signer          CatalogSigner
+	perResource     bool
+	pinResources    bool
+	estimator       cost.Estimator
+	mu              sync.RWMutex
+	resources       map[string][]byte                    // "namespace/name" -> YAML bytes
+	meta            map[string]ResourceMeta              // "namespace/name" -> registry metadata
+	deleted         map[string]deletedEntry              // "namespace/name" -> soft-deleted resource
+	quarantine      map[string]model.QuarantinedArtifact // "namespace/name" -> artifact that failed verification
+	status          model.CatalogResponse                // last successfully published catalog
+	provenance      model.CatalogProvenance              // provenance of status
+	tarGz           *oci.Spool                           // tarball of the last published catalog
+	publishedTags   []string                             // extra tags the last pushed catalog got
+	published       map[string][]byte                    // resources of the last published catalog
+	subsets         map[string]catalogSubset             // label selector -> filtered tarball of the published catalog
+	bundles         map[string][]byte                    // "namespace/name" -> YAML last published as a bundle
+	bundleDigests   map[string]string                    // "namespace/name" -> digest of the last published bundle
+	namespaces      map[string][]byte                    // namespace -> Namespace (and ResourceQuota) YAML
+	sharding        Sharding
+	tagging         CatalogTagging
+	channels        map[string]string // channel -> catalog digest it points at
+	gzipLevel       int
+	shards          map[string]publishedShard // shard -> last published version
+	clusters        []model.Cluster
+	clusterCatalogs map[string]publishedCluster // cluster -> last published catalog
+	gitExport       *GitExporter
+	hooks           *hooks.Config
+	schemas         *kube.SchemaValidator
+	events          *EventLog
+	debounce        time.Duration // how long PushCatalog waits to batch changes
+	pendingMu       sync.Mutex
+	pending         *publishBatch // publish waiting out the debounce window
+	publishMu       sync.Mutex    // serializes publishes and rollbacks, so an older snapshot can't replace a newer catalog
+	syncMu          sync.Mutex
+	syncedIndex     string // resource index digest at the last complete sync
+	locksMu         sync.Mutex
+	locks           map[string]*sync.Mutex // "namespace/name" -> writer lock
+	metrics         catalogMetrics
+}
+
+// namespaceManifestDir holds the Namespace manifests inside the catalog's
+// manifests/ directory.
+const namespaceManifestDir = "namespaces/"
+
+// resourceManifestPath returns the path of the resource with key
+// "namespace/name" inside the catalog's manifests/ directory, one directory
+// per namespace so that no two keys share a file.
+func resourceManifestPath(key string) string {
+	return key + ".yaml"
+}
+
+// ErrCatalogNotPublished is returned when no catalog has been published yet.
+var ErrCatalogNotPublished = errors.New("catalog has not been published yet")
+
+// ConflictError is returned when a write is based on a stale view of a
+// resource: the client's If-Match no longer matches, or another writer
+// pushed a new version to the registry since this server last saw it.
+type ConflictError struct {
+	Namespace      string
+	Name           string
+	Reason         string
+	CurrentVersion string
+	CurrentDigest  string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflict on %s/%s: %s", e.Namespace, e.Name, e.Reason)
+}
+
+// ExistsError is returned when a create names a resource that already
+// exists.
+type ExistsError struct {
+	Namespace      string
+	Name           string
+	CurrentVersion string
+	CurrentDigest  string
+}
+
+func (e *ExistsError) Error() string {
+	return fmt.Sprintf("resource %s/%s already exists", e.Namespace, e.Name)
+}
+
+// CatalogOptions configures a CatalogManager.
+type CatalogOptions struct {
+	// DeleteGracePeriod is how long deleted resources stay restorable.
+	// Zero means deletes are immediate.
+	DeleteGracePeriod time.Duration
+
+	// Signer, if set, signs every published catalog digest.
+	Signer CatalogSigner
+
+	// PerResourceArtifacts additionally publishes each resource as its own
+	// Flux-consumable bundle.
+	PerResourceArtifacts bool
+
+	// CostEstimator, if set, estimates each resource version's monthly
+	// cost and records it as an annotation.
+	CostEstimator cost.Estimator
+
+	// PinResources publishes each resource as its own bundle and has the
+	// catalog reference every bundle through an OCIRepository pinned to its
+	// digest, instead of inlining the manifests. It implies
+	// PerResourceArtifacts and can't be combined with Sharding.
+	PinResources bool
+
+	// Sharding, if enabled, publishes resources as separate catalog shards
+	// stitched together by a root catalog.
+	Sharding Sharding
+
+	// Tagging adds tags to every published catalog and configures the
+	// channels catalogs are promoted through.
+	Tagging CatalogTagging
+
+	// GzipLevel is the gzip compression level of catalog and bundle
+	// tarballs, from 1 (fastest) to 9 (smallest). Zero uses gzip's default.
+	GzipLevel int
+
+	// Clusters, if set, additionally publishes a catalog per target cluster
+	// holding the resources its selector matches.
+	Clusters []model.Cluster
+
+	// GitExporter, if set, mirrors every published catalog into a Git
+	// repository.
+	GitExporter *GitExporter
+
+	// SchemaValidator, if set, checks every generated manifest offline
+	// before it is pushed, and every manifest before the catalog is
+	// published.
+	SchemaValidator *kube.SchemaValidator
+
+	// PublishHooks, if set, run before each catalog publish, which fails if
+	// one of them does, and after it.
+	PublishHooks *hooks.Config
+
+	// Events records resource and catalog changes. If nil, an event log
+	// with the default size is used.
+	Events *EventLog
+
+	// PublishDebounce delays each catalog publish by this long so that
+	// changes made in the meantime go out in the same catalog. Zero
+	// publishes every change immediately.
+	PublishDebounce time.Duration
+}
+
+// ResourceMeta is registry metadata tracked alongside a resource's manifest.
+type ResourceMeta struct {
+	Version   string
+	Digest    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Cost      *model.CostEstimate
+	Images    map[string]string // parameter field -> digest-pinned image reference
+	ExpiresAt time.Time         // zero if the resource doesn't expire
+}
+
+// deletedEntry is a soft-deleted resource kept around until its grace period expires.
+type deletedEntry struct {
+	manifest  []byte
+	meta      ResourceMeta
+	deletedAt time.Time
+}
+
+// NewCatalogManager creates a new catalog manager.
+func NewCatalogManager(client oci.Interface, opts CatalogOptions) *CatalogManager {
+	gzipLevel := opts.GzipLevel
+	if gzipLevel == 0 {
+		gzipLevel = gzip.DefaultCompression
+	}
+	events := opts.Events
+	if events == nil {
+		events = NewEventLog(client, 0)
+	}
+	return &CatalogManager{
+		ociClient:       client,
+		gracePeriod:     opts.DeleteGracePeriod,
+		signer:          opts.Signer,
+		perResource:     opts.PerResourceArtifacts || opts.PinResources,
+		pinResources:    opts.PinResources,
+		estimator:       opts.CostEstimator,
+		resources:       make(map[string][]byte),
+		meta:            make(map[string]ResourceMeta),
+		deleted:         make(map[string]deletedEntry),
+		quarantine:      make(map[string]model.QuarantinedArtifact),
+		bundles:         make(map[string][]byte),
+		bundleDigests:   make(map[string]string),
+		sharding:        opts.Sharding,
+		tagging:         opts.Tagging,
+		channels:        make(map[string]string),
+		gzipLevel:       gzipLevel,
+		shards:          make(map[string]publishedShard),
+		clusters:        opts.Clusters,
+		clusterCatalogs: make(map[string]publishedCluster),
+		gitExport:       opts.GitExporter,
+		hooks:           opts.PublishHooks,
+		schemas:         opts.SchemaValidator,
+		events:          events,
+		debounce:        opts.PublishDebounce,
+		locks:           make(map[string]*sync.Mutex),
+	}
+}
+
+// refreshResource reloads one resource's state from its "latest" artifact.
+func (cm *CatalogManager) refreshResource(ctx context.Context, namespace, name string) error {
+	artifact, err := cm.ociClient.PullResource(ctx, namespace, name, "latest")
+	if errors.Is(err, oci.ErrIntegrity) {
+		cm.Quarantine(ctx, namespace, name, err)
+	}
+	if err != nil {
+		return err
+	}
+	meta := metaFromAnnotations(artifact)
+	cm.Set(namespace, name, artifact.Manifest, meta)
+	if artifact.Annotations[oci.AnnotationResourceDeleted] == "true" {
+		cm.markDeleted(namespace, name, meta.UpdatedAt)
+	}
+	return nil
+}
+
+// LockResource serializes writers of one resource. It returns the unlock
+// function.
+func (cm *CatalogManager) LockResource(namespace, name string) func() {
+	key := namespace + "/" + name
+	cm.locksMu.Lock()
+	l, ok := cm.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		cm.locks[key] = l
+	}
+	cm.locksMu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// CheckConflict returns a *ConflictError if a write to the resource would
+// be based on stale state. ifMatch, if set, is the version or digest the
+// client last read ("*" requires the resource to exist). The registry's
+// "latest" is also compared with the digest this catalog last pushed, to
+// catch writes from other replicas. Callers should hold LockResource.
+func (cm *CatalogManager) CheckConflict(ctx context.Context, namespace, name, ifMatch string) error {
+	meta, live := cm.Meta(namespace, name)
+
+	if ifMatch = strings.Trim(ifMatch, `"`); ifMatch != "" {
+		stale := !live || (ifMatch != "*" && ifMatch != meta.Version && ifMatch != meta.Digest)
+		if stale {
+			return &ConflictError{
+				Namespace:      namespace,
+				Name:           name,
+				Reason:         fmt.Sprintf("If-Match %q does not match the current version", ifMatch),
+				CurrentVersion: meta.Version,
+				CurrentDigest:  meta.Digest,
+			}
+		}
+	}
+
+	head, ok, err := cm.ociClient.HeadResource(ctx, namespace, name)
+	if err != nil {
+		return fmt.Errorf("checking registry for concurrent writes: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+
+	// A tombstone we don't know about only matters if we think the
+	// resource is live; otherwise any digest but our own is a competing write.
+	var conflict bool
+	if head.Deleted {
+		conflict = live && head.Digest != meta.Digest
+	} else {
+		conflict = head.Digest != meta.Digest
+	}
+	if !conflict || head.Digest == cm.quarantinedDigest(namespace, name) {
+		return nil
+	}
+
+	reason := "another writer pushed a new version"
+	if head.Deleted {
+		reason = "another writer deleted the resource"
+	}
+	// Pick up the competing write so the client's retry is based on it.
+	if err := cm.refreshResource(ctx, namespace, name); err != nil {
+		log.Printf("Warning: failed to refresh %s/%s after conflict: %v", namespace, name, err)
+	}
+	return &ConflictError{
+		Namespace:      namespace,
+		Name:           name,
+		Reason:         reason,
+		CurrentVersion: head.Version,
+		CurrentDigest:  head.Digest,
+	}
+}
+
+// checkAdoption returns a *ConflictError unless the registry's "latest" of
+// a resource is the artifact being adopted, a tombstone, or missing, so an
+// adoption doesn't bury a version pushed since the artifact was read.
+// Callers should hold LockResource.
+func (cm *CatalogManager) checkAdoption(ctx context.Context, namespace, name, digest string) error {
+	head, ok, err := cm.ociClient.HeadResource(ctx, namespace, name)
+	if err != nil {
+		return fmt.Errorf("checking registry for concurrent writes: %w", err)
+	}
+	if !ok || head.Deleted || head.Digest == digest {
+		return nil
+	}
+	return &ConflictError{
+		Namespace:      namespace,
+		Name:           name,
+		Reason:         fmt.Sprintf("latest is no longer the adopted artifact %s", digest),
+		CurrentVersion: head.Version,
+		CurrentDigest:  head.Digest,
+	}
+}
+
+// SetNamespaces replaces the Namespace manifests published with the
+// catalog, keyed by namespace name. It does not republish the catalog.
+func (cm *CatalogManager) SetNamespaces(manifests map[string][]byte) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.namespaces = manifests
+}
+
+// PerResourceArtifacts reports whether per-resource bundles are published.
+func (cm *CatalogManager) PerResourceArtifacts() bool {
+	return cm.perResource
+}
+
+// GracePeriod returns how long soft-deleted resources remain restorable.
+func (cm *CatalogManager) GracePeriod() time.Duration {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.gracePeriod
+}
+
+// SetGracePeriod changes how long soft-deleted resources remain
+// restorable. Shortening it purges resources deleted longer ago.
+func (cm *CatalogManager) SetGracePeriod(d time.Duration) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.gracePeriod = d
+	cm.purgeExpiredLocked()
+}
+
+// PublishDebounce returns how long catalog publishes wait to batch changes.
+func (cm *CatalogManager) PublishDebounce() time.Duration {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.debounce
+}
+
+// SetPublishDebounce changes how long catalog publishes wait to batch
+// changes. A publish already waiting keeps its window.
+func (cm *CatalogManager) SetPublishDebounce(d time.Duration) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.debounce = d
+}
+
+// Set adds or updates a resource in the catalog. A zero CreatedAt keeps the
+// resource's original creation time, if known.
+func (cm *CatalogManager) Set(namespace, name string, manifest []byte, meta ResourceMeta) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	key := namespace + "/" + name
+	if meta.UpdatedAt.IsZero() {
+		meta.UpdatedAt = time.Now().UTC()
+	}
+	if meta.CreatedAt.IsZero() {
+		meta.CreatedAt = cm.createdAtLocked(key, meta.UpdatedAt)
+	}
+	cm.resources[key] = manifest
+	cm.meta[key] = meta
+	delete(cm.deleted, key)
+	delete(cm.quarantine, key)
+	cm.metrics.changed(meta.UpdatedAt)
+}
+
+// Quarantine records that a resource's latest artifact failed verification
+// and was not loaded. Writing the resource again releases it.
+func (cm *CatalogManager) Quarantine(ctx context.Context, namespace, name string, reason error) {
+	q := model.QuarantinedArtifact{
+		Namespace:     namespace,
+		Name:          name,
+		Reason:        reason.Error(),
+		QuarantinedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	// The digest lets a new write replace the artifact without being
+	// taken for a competing write.
+	if head, ok, err := cm.ociClient.HeadResource(ctx, namespace, name); err == nil && ok {
+		q.Digest = head.Digest
+	}
+
+	cm.mu.Lock()
+	cm.quarantine[namespace+"/"+name] = q
+	cm.mu.Unlock()
+	cm.events.Record(ctx, model.Event{
+		Type:      model.EventResourceQuarantined,
+		Namespace: namespace,
+		Name:      name,
+		Digest:    q.Digest,
+		Message:   q.Reason,
+	})
+}
+
+// quarantinedDigest returns the digest of a resource's quarantined
+// artifact, or "".
+func (cm *CatalogManager) quarantinedDigest(namespace, name string) string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.quarantine[namespace+"/"+name].Digest
+}
+
+// Quarantined lists the quarantined resources, sorted.
+func (cm *CatalogManager) Quarantined() []model.QuarantinedArtifact {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	list := make([]model.QuarantinedArtifact, 0, len(cm.quarantine))
+	for _, q := range cm.quarantine {
+		list = append(list, q)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Namespace != list[j].Namespace {
+			return list[i].Namespace < list[j].Namespace
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// CreatedAt returns when a resource (live or soft-deleted) was first created,
+// or now for a new resource.
+func (cm *CatalogManager) CreatedAt(namespace, name string) time.Time {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.createdAtLocked(namespace+"/"+name, time.Now().UTC())
+}
+
+func (cm *CatalogManager) createdAtLocked(key string, fallback time.Time) time.Time {
+	if m, ok := cm.meta[key]; ok && !m.CreatedAt.IsZero() {
+		return m.CreatedAt
+	}
+	if d, ok := cm.deleted[key]; ok && !d.meta.CreatedAt.IsZero() {
+		return d.meta.CreatedAt
+	}
+	return fallback
+}
+
+// Meta returns a live resource's registry metadata.
+func (cm *CatalogManager) Meta(namespace, name string) (ResourceMeta, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	m, ok := cm.meta[namespace+"/"+name]
+	return m, ok
+}
+
+// Delete removes a resource from the catalog. If a grace period is configured,
+// the resource is kept as soft-deleted until the period expires.
+func (cm *CatalogManager) Delete(namespace, name string) {
+	cm.markDeleted(namespace, name, time.Now())
+}
+
+func (cm *CatalogManager) markDeleted(namespace, name string, deletedAt time.Time) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	key := namespace + "/" + name
+	manifest, ok := cm.resources[key]
+	meta := cm.meta[key]
+	delete(cm.resources, key)
+	delete(cm.meta, key)
+	if ok && cm.gracePeriod > 0 {
+		cm.deleted[key] = deletedEntry{manifest: manifest, meta: meta, deletedAt: deletedAt}
+	}
+	cm.metrics.changed(deletedAt)
+}
+
+// Get returns a resource's YAML from the catalog.
+func (cm *CatalogManager) Get(namespace, name string) ([]byte, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	data, ok := cm.resources[namespace+"/"+name]
+	return data, ok
+}
+
+// GetDeleted returns a soft-deleted resource's YAML and deletion time,
+// provided its grace period has not expired.
+func (cm *CatalogManager) GetDeleted(namespace, name string) ([]byte, time.Time, bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.purgeExpiredLocked()
+	entry, ok := cm.deleted[namespace+"/"+name]
+	return entry.manifest, entry.deletedAt, ok
+}
+
+// DeletedMeta returns a soft-deleted resource's registry metadata as it was
+// when the resource was deleted.
+func (cm *CatalogManager) DeletedMeta(namespace, name string) (ResourceMeta, bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.purgeExpiredLocked()
+	entry, ok := cm.deleted[namespace+"/"+name]
+	return entry.meta, ok
+}
+
+// List returns all resource names and their YAML.
+func (cm *CatalogManager) List() map[string][]byte {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	result := make(map[string][]byte, len(cm.resources))
+	for k, v := range cm.resources {
+		result[k] = v
+	}
+	return result
+}
+
+// ListDeleted returns all soft-deleted resource names and their deletion times.
+func (cm *CatalogManager) ListDeleted() map[string]time.Time {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.purgeExpiredLocked()
+	result := make(map[string]time.Time, len(cm.deleted))
+	for k, v := range cm.deleted {
+		result[k] = v.deletedAt
+	}
+	return result
+}
+
+// purgeExpiredLocked drops soft-deleted resources whose grace period has passed.
+// Callers must hold cm.mu for writing.
+func (cm *CatalogManager) purgeExpiredLocked() {
+	cutoff := time.Now().Add(-cm.gracePeriod)
+	for k, v := range cm.deleted {
+		if v.deletedAt.Before(cutoff) {
+			delete(cm.deleted, k)
+		}
+	}
+}
+
+// publishBatch is a catalog publish shared by the callers of PushCatalog
+// within one debounce window.
+type publishBatch struct {
+	done chan struct{}
+	err  error
+}
+
+// PushCatalog builds a tar.gz of all current manifests and pushes it to the registry.
+// Soft-deleted resources are excluded so Flux prunes them from the cluster.
+// With a publish debounce, it waits out the window and publishes once for
+// every caller within it; all of them get the same result.
+func (cm *CatalogManager) PushCatalog(ctx context.Context) error {
+	d := cm.PublishDebounce()
+	if d <= 0 {
+		return cm.publish(ctx)
+	}
+
+	cm.pendingMu.Lock()
+	batch := cm.pending
+	if batch == nil {
+		batch = &publishBatch{done: make(chan struct{})}
+		cm.pending = batch
+		// The publish must not fail because the first caller gave up.
+		publishCtx := context.WithoutCancel(ctx)
+		time.AfterFunc(d, func() {
+			cm.pendingMu.Lock()
+			cm.pending = nil
+			cm.pendingMu.Unlock()
+			batch.err = cm.publish(publishCtx)
+			close(batch.done)
+		})
+	}
+	cm.pendingMu.Unlock()
+
+	select {
+	case <-batch.done:
+		return batch.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// publish publishes the catalog now, recording a failure as an event.
+func (cm *CatalogManager) publish(ctx context.Context) error {
+	start := time.Now()
+	err := cm.pushCatalog(ctx)
+	cm.metrics.built(time.Since(start), err)
+	if err != nil {
+		cm.events.Record(ctx, model.Event{Type: model.EventCatalogPublishFailed, Message: err.Error()})
+		return err
+	}
+	return nil
+}
+
+// pushCatalog publishes the catalog. Publishes run one at a time, from the
+// snapshot to the recorded status, so the registry's latest tag, the
+// channels and the status always move forward together.
+func (cm *CatalogManager) pushCatalog(ctx context.Context) error {
+	cm.publishMu.Lock()
+	defer cm.publishMu.Unlock()
+
+	resources, metas := cm.snapshot()
+	cm.mu.RLock()
+	namespaces := cm.namespaces
+	cm.mu.RUnlock()
+
+	if err := cm.validateManifests(resources); err != nil {
+		return err
+	}
+	if err := cm.runPrePublishHooks(ctx, resources, namespaces); err != nil {
+		return err
+	}
+
+	// A partitioned root catalog holds only namespaces and the Flux objects
+	// of each shard; the resources themselves live in the shards. A pinned
+	// catalog likewise holds the Flux objects of each resource bundle.
+	rootResources := resources
+	var shardDigests map[string]string
+	var objects map[string][]byte
+	switch {
+	case cm.sharding.Enabled():
+		var err error
+		if shardDigests, err = cm.pushShards(ctx, resources); err != nil {
+			return err
+		}
+		if objects, err = cm.shardObjects(shardDigests); err != nil {
+			return err
+		}
+		rootResources = nil
+	case cm.pinResources:
+		bundleDigests, err := cm.pushPinnedBundles(ctx, resources)
+		if err != nil {
+			return err
+		}
+		if objects, err = cm.pinnedObjects(bundleDigests); err != nil {
+			return err
+		}
+		rootResources = nil
+	}
+
+	tarGz, err := cm.buildTarGz(rootResources, namespaces, objects)
+	if err != nil {
+		return fmt.Errorf("building catalog tarball: %w", err)
+	}
+
+	tags := cm.tagging.tags(tarGz.Digest().Encoded(), time.Now())
+	if cm.unchangedCatalog(tarGz, tags) {
+		tarGz.Close()
+		log.Printf("Catalog unchanged, not pushing (digest=%s)", cm.Status().Digest)
+		cm.pushDependents(ctx, resources, namespaces)
+		return nil
+	}
+	digest, version, err := cm.ociClient.PushCatalog(ctx, tarGz, cm.tagging.PublishChannel, tags...)
+	if err != nil {
+		tarGz.Close()
+		return fmt.Errorf("pushing catalog: %w", err)
+	}
+	if cm.tagging.PublishChannel != "" {
+		cm.setChannel(cm.tagging.PublishChannel, digest)
+	}
+
+	provenance := cm.buildProvenance(digest, version, resources, metas, shardDigests)
+	if err := cm.pushProvenance(ctx, provenance); err != nil {
+		tarGz.Close()
+		return err
+	}
+	if err := cm.recordStatus(ctx, digest, version, resources, shardDigests, tarGz, provenance); err != nil {
+		tarGz.Close()
+		return err
+	}
+	cm.mu.Lock()
+	cm.publishedTags = tags
+	cm.mu.Unlock()
+
+	if len(tags) > 0 {
+		log.Printf("Pushed catalog %s with %d resources (digest=%s, tags=%s)", version, len(resources), digest, strings.Join(tags, ","))
+	} else {
+		log.Printf("Pushed catalog %s with %d resources (digest=%s)", version, len(resources), digest)
+	}
+	noteProgress(ctx, "published catalog %s", version)
+	cm.events.Record(ctx, model.Event{
+		Type:    model.EventCatalogPublished,
+		Version: version,
+		Digest:  digest,
+		Message: fmt.Sprintf("%d resources", len(resources)),
+	})
+
+	cm.pushDependents(ctx, resources, namespaces)
+	if cm.gitExport != nil {
+		cm.gitExport.Enqueue(ctx, resources)
+	}
+	cm.runPostPublishHooks(ctx, digest, version, len(resources))
+	return nil
+}
+
+// unchangedCatalog reports whether tarGz is the catalog last published,
+// with the same tags and on the publish channel, so pushing it again
+// would only mint a new version of the same content.
+func (cm *CatalogManager) unchangedCatalog(tarGz *oci.Spool, tags []string) bool {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	if cm.tarGz == nil || cm.tarGz.Digest() != tarGz.Digest() || !slices.Equal(cm.publishedTags, tags) {
+		return false
+	}
+	channel := cm.tagging.PublishChannel
+	return channel == "" || cm.channels[channel] == cm.status.Digest
+}
+
+// pushDependents publishes the per-resource bundles and cluster catalogs
+// that changed. Both skip what is already published, so they also run
+// when the catalog itself is unchanged, retrying earlier failures.
+func (cm *CatalogManager) pushDependents(ctx context.Context, resources, namespaces map[string][]byte) {
+	if cm.perResource {
+		cm.pushBundles(ctx, resources)
+	}
+	if len(cm.clusters) > 0 {
+		cm.pushClusterCatalogs(ctx, resources, namespaces)
+	}
+}
+
+// validateManifests checks the manifests of the catalog about to be
+// published against the configured schemas, so a resource stored before
+// they were configured can't reach Flux malformed.
+func (cm *CatalogManager) validateManifests(resources map[string][]byte) error {
+	if cm.schemas == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(resources))
+	for key := range resources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := cm.schemas.Validate(resources[key]); err != nil {
+			return fmt.Errorf("resource %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// pushBundles publishes a per-resource bundle for every resource that changed
+// since it was last published. Removed resources get an empty bundle so Flux
+// prunes them.
+func (cm *CatalogManager) pushBundles(ctx context.Context, resources map[string][]byte) {
+	cm.mu.RLock()
+	published := make(map[string][]byte, len(cm.bundles))
+	for k, v := range cm.bundles {
+		published[k] = v
+	}
+	cm.mu.RUnlock()
+
+	changed := make(map[string][]byte)
+	for key, manifest := range resources {
+		if old, ok := published[key]; !ok || !bytes.Equal(old, manifest) {
+			changed[key] = manifest
+		}
+	}
+	for key := range published {
+		if _, ok := resources[key]; !ok {
+			changed[key] = nil
+		}
+	}
+
+	for key, manifest := range changed {
+		contents := map[string][]byte{}
+		if manifest != nil {
+			contents[key] = manifest
+		}
+		tarGz, err := cm.buildTarGz(contents, nil, nil)
+		if err != nil {
+			log.Printf("Warning: failed to build bundle for %s: %v", key, err)
+			continue
+		}
+
+		namespace, name, _ := strings.Cut(key, "/")
+		digest, _, err := cm.ociClient.PushResourceBundle(ctx, namespace, name, tarGz)
+		tarGz.Close()
+		if err != nil {
+			log.Printf("Warning: failed to push bundle for %s: %v", key, err)
+			continue
+		}
+
+		cm.mu.Lock()
+		if manifest != nil {
+			cm.bundles[key] = manifest
+			cm.bundleDigests[key] = digest
+		} else {
+			delete(cm.bundles, key)
+			delete(cm.bundleDigests, key)
+		}
+		cm.mu.Unlock()
+	}
+}
+
+// restoreBundles reads what the bundle of every resource repository,
+// live or tombstoned, last published, so the next publish only re-pushes
+// bundles that changed and empties those of resources deleted since.
+func (cm *CatalogManager) restoreBundles(ctx context.Context, repos []oci.ResourceInfo) {
+	restored := 0
+	for _, repo := range repos {
+		key := repo.Namespace + "/" + repo.Name
+		digest, tarGz, err := cm.ociClient.PullResourceBundle(ctx, repo.Namespace, repo.Name, "latest")
+		if errors.Is(err, errdef.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: failed to pull bundle of %s: %v", key, err)
+			continue
+		}
+		contents, err := readCatalogTarGz(tarGz.Reader())
+		tarGz.Close()
+		if err != nil {
+			log.Printf("Warning: failed to read bundle of %s: %v", key, err)
+			continue
+		}
+		manifest, ok := contents[key]
+		if !ok {
+			continue // emptied when the resource was removed
+		}
+
+		cm.mu.Lock()
+		cm.bundles[key] = manifest
+		cm.bundleDigests[key] = digest
+		cm.mu.Unlock()
+		restored++
+	}
+	log.Printf("Restored %d resource bundles from registry", restored)
+}
+
+// catalogRollback is a previously published catalog pulled for a
+// rollback.
+type catalogRollback struct {
+	reference    string
+	digest       string
+	version      string
+	target       map[string][]byte // manifests of the catalog, by "namespace/name"
+	shardDigests map[string]string
+	tarGz        *oci.Spool
+}
+
+// pullRollback pulls the catalog a version or digest refers to, with the
+// manifests of every resource it holds.
+func (cm *CatalogManager) pullRollback(ctx context.Context, reference string) (*catalogRollback, error) {
+	digest, tarGz, err := cm.ociClient.PullCatalog(ctx, reference)
+	if err != nil {
+		return nil, fmt.Errorf("pulling catalog %s: %w", reference, err)
+	}
+	target, err := readCatalogTarGz(tarGz.Reader())
+	if err != nil {
+		tarGz.Close()
+		return nil, fmt.Errorf("reading catalog %s: %w", reference, err)
+	}
+	shardDigests, err := cm.expandCatalog(ctx, tarGz, target)
+	if err != nil {
+		tarGz.Close()
+		return nil, fmt.Errorf("reading catalog %s: %w", reference, err)
+	}
+
+	version := reference
+	if strings.HasPrefix(reference, "sha256:") {
+		version = ""
+	}
+	return &catalogRollback{
+		reference:    reference,
+		digest:       digest,
+		version:      version,
+		target:       target,
+		shardDigests: shardDigests,
+		tarGz:        tarGz,
+	}, nil
+}
+
+// finishRollback makes a rolled back catalog current, once its resource
+// artifacts match it: its digest is re-tagged as latest and with the
+// publish channel, if any, so the rollback survives a restart.
+// On success the catalog takes over the rollback's tarball; otherwise it is
+// closed.
+func (cm *CatalogManager) finishRollback(ctx context.Context, rb *catalogRollback) (model.CatalogResponse, error) {
+	cm.publishMu.Lock()
+	defer cm.publishMu.Unlock()
+
+	if err := cm.ociClient.TagCatalog(ctx, rb.digest, "latest"); err != nil {
+		rb.tarGz.Close()
+		return model.CatalogResponse{}, err
+	}
+	if channel := cm.tagging.PublishChannel; channel != "" {
+		if err := cm.ociClient.TagCatalog(ctx, rb.digest, channel); err != nil {
+			rb.tarGz.Close()
+			return model.CatalogResponse{}, err
+		}
+		cm.setChannel(channel, rb.digest)
+	}
+
+	provenance, err := cm.rollbackProvenance(ctx, rb.digest, rb.version, rb.shardDigests)
+	if err != nil {
+		rb.tarGz.Close()
+		return model.CatalogResponse{}, err
+	}
+	if err := cm.recordStatus(ctx, rb.digest, rb.version, rb.target, rb.shardDigests, rb.tarGz, provenance); err != nil {
+		rb.tarGz.Close()
+		return model.CatalogResponse{}, err
+	}
+
+	log.Printf("Rolled back catalog to %s (digest=%s)", rb.reference, rb.digest)
+	cm.events.Record(ctx, model.Event{
+		Type:    model.EventCatalogRolledBack,
+		Version: rb.version,
+		Digest:  rb.digest,
+		Message: "rolled back to " + rb.reference,
+	})
+
+	if len(cm.clusters) > 0 {
+		cm.mu.RLock()
+		namespaces := cm.namespaces
+		cm.mu.RUnlock()
+		cm.pushClusterCatalogs(ctx, rb.target, namespaces)
+	}
+	return cm.Status(), nil
+}
+
+// tombstoneAnnotations returns the extra annotations pushed with a
+// tombstone: who deleted the resource and how, and its original creation
+// time, which a restore after a restart carries over.
+func (cm *CatalogManager) tombstoneAnnotations(ctx context.Context, namespace, name string) map[string]string {
+	annotations := changeAnnotations(ctx)
+	annotations[oci.AnnotationResourceCreatedAt] = cm.CreatedAt(namespace, name).Format(time.RFC3339)
+	return annotations
+}
+
+// resourceAnnotations returns the extra annotations pushed with every
+// resource artifact: who made the change and how, its schema version,
+// original creation time and, if an estimator is configured, its estimated
+// cost.
+func (cm *CatalogManager) resourceAnnotations(ctx context.Context, namespace, name string, manifest []byte) map[string]string {
+	annotations := changeAnnotations(ctx)
+	annotations[oci.AnnotationResourceSchemaVersion] = schemaVersionOf(manifest)
+	annotations[oci.AnnotationResourceCreatedAt] = cm.CreatedAt(namespace, name).Format(time.RFC3339)
+
+	if cm.estimator != nil {
+		var pr model.PlatformResource
+		if err := yaml.Unmarshal(manifest, &pr); err == nil {
+			estimate, err := cm.estimator.Estimate(ctx, namespace, pr.Spec)
+			if err != nil {
+				// A missing estimate shouldn't block the write.
+				log.Printf("Warning: estimating cost of %s/%s: %v", namespace, name, err)
+			} else {
+				annotations[oci.AnnotationResourceCostMonthly] = strconv.FormatFloat(estimate.Monthly, 'f', 2, 64)
+				annotations[oci.AnnotationResourceCostCurrency] = estimate.Currency
+			}
+		}
+	}
+	return annotations
+}
+
+// costFromAnnotations reads a cost estimate back from resource annotations.
+func costFromAnnotations(annotations map[string]string) *model.CostEstimate {
+	v, ok := annotations[oci.AnnotationResourceCostMonthly]
+	if !ok {
+		return nil
+	}
+	monthly, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil
+	}
+	return &model.CostEstimate{Monthly: monthly, Currency: annotations[oci.AnnotationResourceCostCurrency]}
+}
+
+// snapshot returns copies of the resources and their metadata, taken
+// together so each manifest matches its metadata.
+func (cm *CatalogManager) snapshot() (map[string][]byte, map[string]ResourceMeta) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	resources := make(map[string][]byte, len(cm.resources))
+	metas := make(map[string]ResourceMeta, len(cm.resources))
+	for k, v := range cm.resources {
+		resources[k] = v
+		metas[k] = cm.meta[k]
+	}
+	return resources, metas
+}
+
+// buildProvenance lists the resource artifact behind every manifest of a
+// published catalog.
+func (cm *CatalogManager) buildProvenance(digest, version string, resources map[string][]byte, metas map[string]ResourceMeta, shards map[string]string) model.CatalogProvenance {
+	p := model.CatalogProvenance{
+		Catalog:   digest,
+		Version:   version,
+		Reference: cm.ociClient.CatalogReference(digest),
+		BuiltAt:   time.Now().UTC().Format(time.RFC3339),
+		Builder:   "gitops-squared",
+		Resources: make([]model.ProvenanceResource, 0, len(resources)),
+		Shards:    shards,
+	}
+	for key, manifest := range resources {
+		namespace, name, _ := strings.Cut(key, "/")
+		r := model.ProvenanceResource{
+			Namespace:      namespace,
+			Name:           name,
+			ManifestDigest: fmt.Sprintf("sha256:%x", sha256.Sum256(manifest)),
+		}
+		if meta := metas[key]; meta.Digest != "" {
+			r.Version = meta.Version
+			r.Digest = meta.Digest
+			r.Reference = cm.ociClient.ResourceReference(namespace, name, meta.Digest)
+		}
+		p.Resources = append(p.Resources, r)
+	}
+	sort.Slice(p.Resources, func(i, j int) bool {
+		a, b := p.Resources[i], p.Resources[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return p
+}
+
+// pushProvenance attaches a provenance document to its catalog.
+func (cm *CatalogManager) pushProvenance(ctx context.Context, p model.CatalogProvenance) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("encoding provenance: %w", err)
+	}
+	if err := cm.ociClient.PushCatalogProvenance(ctx, p.Catalog, data); err != nil {
+		return fmt.Errorf("recording catalog provenance: %w", err)
+	}
+	return nil
+}
+
+// rollbackProvenance returns the provenance of a catalog being rolled back
+// to. Catalogs published before provenance was recorded get one built from
+// the resources as restored.
+func (cm *CatalogManager) rollbackProvenance(ctx context.Context, digest, version string, shards map[string]string) (model.CatalogProvenance, error) {
+	data, _, err := cm.ociClient.PullCatalogProvenance(ctx, digest)
+	if err != nil {
+		return model.CatalogProvenance{}, fmt.Errorf("pulling catalog provenance: %w", err)
+	}
+	if data != nil {
+		var p model.CatalogProvenance
+		if err := json.Unmarshal(data, &p); err != nil {
+			return model.CatalogProvenance{}, fmt.Errorf("parsing catalog provenance: %w", err)
+		}
+		return p, nil
+	}
+
+	resources, metas := cm.snapshot()
+	p := cm.buildProvenance(digest, version, resources, metas, shards)
+	if err := cm.pushProvenance(ctx, p); err != nil {
+		return model.CatalogProvenance{}, err
+	}
+	return p, nil
+}
+
+// Provenance returns the provenance of the last published catalog.
+func (cm *CatalogManager) Provenance() (model.CatalogProvenance, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.provenance, cm.provenance.Catalog != ""
+}
+
+// CatalogSigner signs catalog digests. It is satisfied by *signing.Signer
+// and *kms.Signer.
+type CatalogSigner interface {
+	oci.ArtifactSigner
+
+	// PublicKey returns the base64-encoded public key for verifiers.
+	PublicKey() string
+}
+
+// recordStatus signs a published catalog digest (if configured) and records
+// it, along with its tarball and provenance, as the current catalog. shards
+// holds the digest of each shard when the catalog is partitioned.
+func (cm *CatalogManager) recordStatus(ctx context.Context, digest, version string, resources map[string][]byte, shards map[string]string, tarGz *oci.Spool, provenance model.CatalogProvenance) error {
+	status := cm.catalogStatus(digest, version, resources, shards)
+	sig, err := cm.signStatus(ctx, &status)
+	if err != nil {
+		return fmt.Errorf("signing catalog: %w", err)
+	}
+	if sig != nil {
+		if err := cm.ociClient.PushCatalogSignature(ctx, digest, status.SignatureAlgorithm, status.SigningKey, sig); err != nil {
+			return fmt.Errorf("signing catalog: %w", err)
+		}
+	}
+	cm.setStatus(status, provenance, tarGz, resources)
+	return nil
+}
+
+// catalogStatus describes a catalog.
+func (cm *CatalogManager) catalogStatus(digest, version string, resources map[string][]byte, shards map[string]string) model.CatalogResponse {
+	status := model.CatalogResponse{
+		Digest:        digest,
+		Version:       version,
+		Reference:     cm.ociClient.CatalogReference(digest),
+		ResourceCount: len(resources),
+		BuiltAt:       time.Now().UTC().Format(time.RFC3339),
+		Resources:     make([]string, 0, len(resources)),
+		Shards:        shards,
+	}
+	for key := range resources {
+		status.Resources = append(status.Resources, key)
+	}
+	sort.Strings(status.Resources)
+	return status
+}
+
+// signStatus signs a catalog's digest, if a signer is configured, and
+// records the signature on status. It returns the signature, which the
+// caller pushes as needed.
+func (cm *CatalogManager) signStatus(ctx context.Context, status *model.CatalogResponse) ([]byte, error) {
+	if cm.signer == nil {
+		return nil, nil
+	}
+	sig, keyID, err := cm.signer.Sign(ctx, status.Digest)
+	if err != nil {
+		return nil, err
+	}
+	status.Signature = base64.StdEncoding.EncodeToString(sig)
+	status.SignatureAlgorithm = cm.signer.Algorithm()
+	status.SigningKey = keyID
+	status.PublicKey = cm.signer.PublicKey()
+	return sig, nil
+}
+
+// setStatus records a published catalog, taking over its tarball. The
+// tarball it replaces is closed once its last reader is done with it.
+func (cm *CatalogManager) setStatus(status model.CatalogResponse, provenance model.CatalogProvenance, tarGz *oci.Spool, resources map[string][]byte) {
+	cm.mu.Lock()
+	previous := cm.tarGz
+	cm.status = status
+	cm.provenance = provenance
+	cm.tarGz = tarGz
+	cm.published = resources
+	cm.subsets = nil
+	cm.mu.Unlock()
+	if previous != nil && previous != tarGz {
+		previous.Close()
+	}
+	cm.metrics.published(tarGz.Size())
+}
+
+// TarGz returns the digest and tarball of the last published catalog. The
+// caller must close the spool, which stays readable until then even if a
+// newer catalog replaces it.
+func (cm *CatalogManager) TarGz() (string, *oci.Spool, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	if cm.tarGz == nil {
+		return "", nil, ErrCatalogNotPublished
+	}
+	return cm.status.Digest, cm.tarGz.Retain(), nil
+}
+
+// Contents returns the files of the last published catalog tarball.
+func (cm *CatalogManager) Contents() (string, []catalogFile, error) {
+	digest, tarGz, err := cm.TarGz()
+	if err != nil {
+		return "", nil, err
+	}
+	defer tarGz.Close()
+	files, err := readTarGzFiles(tarGz.Reader())
+	if err != nil {
+		return "", nil, fmt.Errorf("reading catalog tarball: %w", err)
+	}
+	return digest, files, nil
+}
+
+// Status returns details of the last published catalog.
+func (cm *CatalogManager) Status() model.CatalogResponse {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.status
+}
+
+// Restore rebuilds the in-memory state from the registry on startup. It
+// fails, so nothing is pushed, while the catalog has versions in another
+// format than the client generates.
+func (cm *CatalogManager) Restore(ctx context.Context) error {
+	if err := cm.ociClient.CheckVersionFormat(ctx); err != nil {
+		return err
+	}
+	repos, err := cm.ociClient.ListResourceRepos(ctx)
+	if err != nil {
+		return fmt.Errorf("listing resource repos: %w", err)
+	}
+
+	restored, quarantined := 0, 0
+	keep := make(map[string]bool, len(repos)) // live copies to keep, e.g. from a snapshot
+	for _, repo := range repos {
+		artifact, err := cm.ociClient.PullResource(ctx, repo.Namespace, repo.Name, "latest")
+		if errors.Is(err, oci.ErrIntegrity) {
+			log.Printf("Warning: quarantined %s/%s: %v", repo.Namespace, repo.Name, err)
+			cm.Quarantine(ctx, repo.Namespace, repo.Name, err)
+			quarantined++
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: failed to pull %s/%s: %v", repo.Namespace, repo.Name, err)
+			keep[repo.Namespace+"/"+repo.Name] = true
+			continue
+		}
+
+		meta := metaFromAnnotations(artifact)
+		updatedAt := meta.UpdatedAt
+
+		if artifact.Annotations[oci.AnnotationResourceDeleted] == "true" {
+			// Tombstones carry the last manifest, so recently deleted
+			// resources come back as soft-deleted and stay restorable.
+			if grace := cm.GracePeriod(); !updatedAt.IsZero() && grace > 0 && time.Since(updatedAt) < grace {
+				cm.Set(repo.Namespace, repo.Name, artifact.Manifest, meta)
+				cm.markDeleted(repo.Namespace, repo.Name, updatedAt)
+			}
+			continue
+		}
+
+		cm.Set(repo.Namespace, repo.Name, artifact.Manifest, meta)
+		keep[repo.Namespace+"/"+repo.Name] = true
+		restored++
+	}
+	if dropped := cm.dropLive(keep); dropped > 0 {
+		log.Printf("Dropped %d resources that are no longer live in the registry", dropped)
+	}
+
+	log.Printf("Restored %d resources from registry", restored)
+	if quarantined > 0 {
+		log.Printf("Warning: %d resources failed verification and were not restored; see GET /api/v1/admin/quarantine", quarantined)
+	}
+	if cm.perResource {
+		cm.restoreBundles(ctx, repos)
+	}
+	if err := cm.refreshChannels(ctx); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return cm.PushCatalog(ctx)
+}
+
+// dropLive removes the live resources not in keep, such as ones loaded
+// from a snapshot that were deleted since, and returns how many it removed.
+func (cm *CatalogManager) dropLive(keep map[string]bool) int {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	dropped := 0
+	for key := range cm.resources {
+		if !keep[key] {
+			delete(cm.resources, key)
+			delete(cm.meta, key)
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		cm.metrics.changed(time.Now())
+	}
+	return dropped
+}
+
+// metaFromAnnotations reads registry metadata from a pulled artifact.
+func metaFromAnnotations(artifact oci.ResourceArtifact) ResourceMeta {
+	updatedAt, _ := time.Parse(time.RFC3339, artifact.Annotations[ocispec.AnnotationCreated])
+	createdAt, _ := time.Parse(time.RFC3339, artifact.Annotations[oci.AnnotationResourceCreatedAt])
+	expiresAt, _ := time.Parse(time.RFC3339, artifact.Annotations[oci.AnnotationResourceExpiresAt])
+	return ResourceMeta{
+		Version:   artifact.Annotations[oci.AnnotationResourceVersion],
+		Digest:    artifact.Digest,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+		Cost:      costFromAnnotations(artifact.Annotations),
+		Images:    imagesFromAnnotations(artifact.Annotations),
+		ExpiresAt: expiresAt,
+	}
+}
+
+// tarEpoch is the modification time of every catalog tarball entry, so
+// identical content always produces identical bytes (and digests).
+var tarEpoch = time.Unix(0, 0)
+
+// buildTarGz builds a catalog tarball into a spool, which spills to disk
+// if the tarball is large.
+func (cm *CatalogManager) buildTarGz(resources, namespaces, objects map[string][]byte) (*oci.Spool, error) {
+	tarGz := cm.ociClient.NewSpool()
+	if err := writeCatalogTarGz(tarGz, resources, namespaces, objects, cm.gzipLevel); err != nil {
+		tarGz.Close()
+		return nil, err
+	}
+	return tarGz, nil
+}
+
+// writeCatalogTarGz writes a Flux-consumable tarball of resource manifests,
+// Namespace manifests and, for a partitioned or pinned root catalog, the
+// Flux objects of each shard or resource bundle, keyed by their path under
+// manifests/. Output is deterministic: entries are sorted and carry fixed
+// timestamps and ownership.
+func writeCatalogTarGz(w io.Writer, resources, namespaces, objects map[string][]byte, gzipLevel int) error {
+	files := make(map[string][]byte, len(resources)+len(namespaces)+len(objects))
+	for name, manifest := range namespaces {
+		files[namespaceManifestDir+name+".yaml"] = manifest
+	}
+	for filename, data := range objects {
+		files[filename] = data
+	}
+	for key, manifest := range resources {
+		files[resourceManifestPath(key)] = manifest
+	}
+
+	filenames := make([]string, 0, len(files))
+	for filename := range files {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	gw, err := getGzipWriter(w, gzipLevel)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(gw)
+
+	for _, filename := range filenames {
+		if err := writeTarFile(tw, "manifests/"+filename, files[filename]); err != nil {
+			return err
+		}
+	}
+
+	// Write a kustomization.yaml that references all resources.
+	if err := writeTarFile(tw, "manifests/kustomization.yaml", buildKustomization(filenames)); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	putGzipWriter(gw, gzipLevel)
+	return nil
+}
+
+// gzipWriters recycles gzip writers, whose compressor state runs to
+// hundreds of KB, per compression level (offset by HuffmanOnly, the lowest).
+var gzipWriters [gzip.BestCompression - gzip.HuffmanOnly + 1]sync.Pool
+
+// getGzipWriter returns a pooled gzip writer at level writing to w.
+func getGzipWriter(w io.Writer, level int) (*gzip.Writer, error) {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return gzip.NewWriterLevel(w, level) // reports the invalid level
+	}
+	if gw, ok := gzipWriters[level-gzip.HuffmanOnly].Get().(*gzip.Writer); ok {
+		gw.Reset(w)
+		return gw, nil
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+// putGzipWriter returns a closed gzip writer to the pool.
+func putGzipWriter(gw *gzip.Writer, level int) {
+	gw.Reset(io.Discard)
+	gzipWriters[level-gzip.HuffmanOnly].Put(gw)
+}
+
+// writeTarFile writes one regular file with a fixed mode, owner and
+// timestamp.
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  tarEpoch,
+		Format:   tar.FormatUSTAR,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// catalogFile is a single file inside a catalog tarball.
+type catalogFile struct {
+	name string
+	data []byte
+}
+
+// readTarGzFiles returns every regular file in a tar.gz, in archive order.
+func readTarGzFiles(r io.Reader) ([]catalogFile, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	var files []catalogFile
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, catalogFile{name: hdr.Name, data: content})
+	}
+
+	return files, nil
+}
+
+// readCatalogTarGz extracts the resource manifests from a catalog tarball,
+// keyed by "namespace/name" as read from each manifest's metadata rather
+// than its path, so catalogs published with flat "<namespace>-<name>.yaml"
+// file names still read back. Shards of a partitioned catalog and bundles of
+// a pinned one are not followed; see expandCatalog.
+func readCatalogTarGz(r io.Reader) (map[string][]byte, error) {
+	files, err := readTarGzFiles(r)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make(map[string][]byte)
+	for _, f := range files {
+		if f.name == "manifests/kustomization.yaml" || strings.HasPrefix(f.name, "manifests/"+namespaceManifestDir) || strings.HasPrefix(f.name, "manifests/"+shardManifestDir) || strings.HasPrefix(f.name, "manifests/"+pinnedManifestDir) {
+			continue
+		}
+
+		var pr model.PlatformResource
+		if err := yaml.Unmarshal(f.data, &pr); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", f.name, err)
+		}
+		resources[pr.Metadata.Namespace+"/"+pr.Metadata.Name] = f.data
+	}
+
+	return resources, nil
+}
+
+// buildKustomization lists filenames, paths relative to manifests/, as the
+// resources of the catalog's kustomization.yaml.
+func buildKustomization(filenames []string) []byte {
+	var b bytes.Buffer
+	b.WriteString("apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n")
+	for _, f := range filenames {
+		b.WriteString("  - " + f + "\n")
+	}
+	if len(filenames) == 0 {
+		b.WriteString("  []\n")
+	}
+	return b.Bytes()
+}
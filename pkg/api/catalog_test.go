@@ -0,0 +1,245 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+	"github.com/alfredtm/gitops-squared/pkg/oci/ocitest"
+)
+
+// jitterStorage is an in-memory registry whose pushes and tags take a
+// random while, as a remote registry's do, so concurrent publishes finish
+// in any order.
+type jitterStorage struct{ *ocitest.Storage }
+
+func (s jitterStorage) Repository(ctx context.Context, repoPath string) (oci.Repository, error) {
+	repo, err := s.Storage.Repository(ctx, repoPath)
+	return jitterRepository{repo}, err
+}
+
+type jitterRepository struct{ oci.Repository }
+
+func jitter() { time.Sleep(time.Duration(rand.IntN(2000)) * time.Microsecond) }
+
+func (r jitterRepository) Push(ctx context.Context, expected ocispec.Descriptor, content io.Reader) error {
+	jitter()
+	return r.Repository.Push(ctx, expected, content)
+}
+
+func (r jitterRepository) Tag(ctx context.Context, desc ocispec.Descriptor, reference string) error {
+	jitter()
+	return r.Repository.Tag(ctx, desc, reference)
+}
+
+// Concurrent writers each add a resource and publish. However their
+// publishes interleave, the last one recorded must hold every resource and
+// be the one the registry's latest tag points at.
+func TestPushCatalogConcurrentWriters(t *testing.T) {
+	ctx := context.Background()
+	client := oci.NewClientWithStorage("registry.example", "gitops-squared/resources", jitterStorage{ocitest.NewStorage()})
+	cm := NewCatalogManager(client, CatalogOptions{})
+
+	const writers = 16
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("res-%02d", i)
+			cm.Set("default", name, []byte("name: "+name+"\n"), ResourceMeta{})
+			if err := cm.PushCatalog(ctx); err != nil {
+				t.Errorf("publishing %s: %v", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	status := cm.Status()
+	if status.ResourceCount != writers {
+		t.Errorf("status holds %d resources, want %d: %v", status.ResourceCount, writers, status.Resources)
+	}
+	head, err := client.CatalogHead(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != status.Digest {
+		t.Errorf("latest is %s, status is %s", head, status.Digest)
+	}
+	_, files, err := cm.Contents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < writers {
+		t.Errorf("published tarball holds %d files, want at least %d", len(files), writers)
+	}
+}
+
+// openFiles counts the process's open file descriptors.
+func openFiles(t *testing.T) int {
+	t.Helper()
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skipf("can't count open files: %v", err)
+	}
+	return len(fds)
+}
+
+// Catalogs that spill to disk release their temporary file once a newer
+// catalog replaces them and the last download of them is done.
+func TestPushCatalogReleasesSpools(t *testing.T) {
+	ctx := context.Background()
+	client, _ := ocitest.NewClient("gitops-squared/resources")
+	client.SetSpoolThreshold(1)
+	cm := NewCatalogManager(client, CatalogOptions{})
+
+	cm.Set("default", "res-0", []byte("name: res-0\n"), ResourceMeta{})
+	if err := cm.PushCatalog(ctx); err != nil {
+		t.Fatal(err)
+	}
+	before := openFiles(t)
+
+	_, held, err := cm.TarGz()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !held.OnDisk() {
+		t.Fatal("catalog tarball didn't spill to disk")
+	}
+	for i := 1; i <= 20; i++ {
+		name := fmt.Sprintf("res-%d", i)
+		cm.Set("default", name, []byte("name: "+name+"\n"), ResourceMeta{})
+		if err := cm.PushCatalog(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A download still in flight keeps reading the catalog it started on.
+	if _, err := readCatalogTarGz(held.Reader()); err != nil {
+		t.Errorf("reading a replaced catalog before closing it: %v", err)
+	}
+	held.Close()
+
+	if after := openFiles(t); after > before {
+		t.Errorf("%d files open after 20 publishes, %d before", after, before)
+	}
+}
+
+// Keys that only differ in where the namespace ends, such as team-a/db and
+// team/a-db, are published as separate files, so neither drops out of the
+// catalog.
+func TestWriteCatalogTarGzKeysByNamespace(t *testing.T) {
+	resources := map[string][]byte{
+		"team-a/db": []byte("metadata:\n  name: db\n  namespace: team-a\n"),
+		"team/a-db": []byte("metadata:\n  name: a-db\n  namespace: team\n"),
+	}
+	var buf bytes.Buffer
+	if err := writeCatalogTarGz(&buf, resources, nil, nil, gzip.DefaultCompression); err != nil {
+		t.Fatal(err)
+	}
+	tarGz := buf.Bytes()
+
+	files, err := readTarGzFiles(bytes.NewReader(tarGz))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.name)
+	}
+	want := []string{"manifests/team-a/db.yaml", "manifests/team/a-db.yaml", "manifests/kustomization.yaml"}
+	if !slices.Equal(names, want) {
+		t.Errorf("tarball holds %v, want %v", names, want)
+	}
+	if kustomization := string(files[len(files)-1].data); !strings.Contains(kustomization, "  - team-a/db.yaml\n") || !strings.Contains(kustomization, "  - team/a-db.yaml\n") {
+		t.Errorf("kustomization.yaml doesn't list both resources:\n%s", kustomization)
+	}
+
+	got, err := readCatalogTarGz(bytes.NewReader(tarGz))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["team-a/db"] == nil || got["team/a-db"] == nil {
+		t.Errorf("read back %d resources, want team-a/db and team/a-db", len(got))
+	}
+}
+
+// countingStorage is an in-memory registry counting tag operations, one
+// per pushed artifact version.
+type countingStorage struct {
+	*ocitest.Storage
+	mu   sync.Mutex
+	tags map[string]int // repository -> tags set
+}
+
+func (s *countingStorage) Repository(ctx context.Context, repoPath string) (oci.Repository, error) {
+	repo, err := s.Storage.Repository(ctx, repoPath)
+	return countingRepository{repo, s, repoPath}, err
+}
+
+func (s *countingStorage) count(repoPath string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tags[repoPath]
+}
+
+type countingRepository struct {
+	oci.Repository
+	storage  *countingStorage
+	repoPath string
+}
+
+func (r countingRepository) Tag(ctx context.Context, desc ocispec.Descriptor, reference string) error {
+	r.storage.mu.Lock()
+	r.storage.tags[r.repoPath]++
+	r.storage.mu.Unlock()
+	return r.Repository.Tag(ctx, desc, reference)
+}
+
+// Publishing twice without changes pushes the catalog once.
+func TestPushCatalogSkipsUnchanged(t *testing.T) {
+	ctx := context.Background()
+	storage := &countingStorage{Storage: ocitest.NewStorage(), tags: map[string]int{}}
+	client := oci.NewClientWithStorage("registry.example", "gitops-squared/resources", storage)
+	cm := NewCatalogManager(client, CatalogOptions{})
+
+	cm.Set("default", "res-0", []byte("name: res-0\n"), ResourceMeta{})
+	if err := cm.PushCatalog(ctx); err != nil {
+		t.Fatal(err)
+	}
+	first := cm.Status()
+	pushed := storage.count("gitops-squared/catalog")
+	if pushed == 0 {
+		t.Fatal("first publish tagged nothing")
+	}
+
+	if err := cm.PushCatalog(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := storage.count("gitops-squared/catalog"); got != pushed {
+		t.Errorf("unchanged publish set %d more tags", got-pushed)
+	}
+	if second := cm.Status(); second.Version != first.Version || second.Digest != first.Digest {
+		t.Errorf("unchanged publish moved the catalog from %s to %s", first.Version, second.Version)
+	}
+
+	cm.Set("default", "res-1", []byte("name: res-1\n"), ResourceMeta{})
+	if err := cm.PushCatalog(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := storage.count("gitops-squared/catalog"); got == pushed {
+		t.Error("publish of a changed catalog pushed nothing")
+	}
+}
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// catalogTagPattern is the tag grammar of the OCI distribution spec.
+var catalogTagPattern = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9._-]{0,127}$`)
+
+// buildIDLength is the number of hex digits of a catalog's {build} tag,
+// as long as a short Git commit hash.
+const buildIDLength = 12
+
+// CatalogTagging describes the tags a published catalog gets besides its
+// version tag and "latest". The zero value adds none.
+type CatalogTagging struct {
+	// Tags are rendered for every catalog: {timestamp} becomes its publish
+	// time in UTC (20261016T202411Z) and {build} the first 12 hex digits of
+	// its tarball's sha256, so identical content gets the same build ID.
+	Tags []string
+
+	// Channels are tags that clusters follow and promotion moves.
+	Channels []string
+
+	// PublishChannel, one of Channels, is moved to every new catalog.
+	PublishChannel string
+}
+
+// ParseCatalogTagging parses the comma-separated CATALOG_TAGS and
+// CATALOG_CHANNELS values and CATALOG_PUBLISH_CHANNEL. The publish channel
+// is added to the channels if they don't list it.
+func ParseCatalogTagging(tags, channels, publish string) (CatalogTagging, error) {
+	var t CatalogTagging
+	seen := make(map[string]bool)
+	for _, tag := range splitList(tags) {
+		if err := checkCatalogTag(tag, t.render(tag, strings.Repeat("0", buildIDLength), time.Time{})); err != nil {
+			return CatalogTagging{}, fmt.Errorf("CATALOG_TAGS: %w", err)
+		}
+		if seen[tag] {
+			return CatalogTagging{}, fmt.Errorf("CATALOG_TAGS: duplicate tag %q", tag)
+		}
+		seen[tag] = true
+		t.Tags = append(t.Tags, tag)
+	}
+
+	list := splitList(channels)
+	if publish != "" && !slices.Contains(list, publish) {
+		list = append(list, publish)
+	}
+	for _, channel := range list {
+		if err := checkCatalogTag(channel, channel); err != nil {
+			return CatalogTagging{}, fmt.Errorf("CATALOG_CHANNELS: %w", err)
+		}
+		if seen[channel] {
+			return CatalogTagging{}, fmt.Errorf("CATALOG_CHANNELS: %q is already a channel or tag", channel)
+		}
+		seen[channel] = true
+		t.Channels = append(t.Channels, channel)
+	}
+	t.PublishChannel = publish
+	return t, nil
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// checkCatalogTag checks a tag as configured and as rendered. Tags the
+// client pushes on its own are reserved: "latest", the v-prefixed version
+// tags and the sha256- tags of signatures and provenance.
+func checkCatalogTag(tag, rendered string) error {
+	switch {
+	case !catalogTagPattern.MatchString(rendered):
+		return fmt.Errorf("%q is not a valid tag (placeholders are {timestamp} and {build})", tag)
+	case tag == "latest", strings.HasPrefix(tag, "v"), strings.HasPrefix(tag, "sha256-"):
+		return fmt.Errorf("%q is reserved: latest, v... and sha256-... tags are set by the API", tag)
+	}
+	return nil
+}
+
+// render renders a tag template for a catalog.
+func (t CatalogTagging) render(tag, build string, at time.Time) string {
+	return strings.NewReplacer(
+		"{timestamp}", at.UTC().Format("20060102T150405Z"),
+		"{build}", build,
+	).Replace(tag)
+}
+
+// tags returns the extra tags of a catalog whose tarball has the sha256 hex
+// digest sum, published at at.
+func (t CatalogTagging) tags(sum string, at time.Time) []string {
+	if len(t.Tags) == 0 {
+		return nil
+	}
+	build := sum[:min(buildIDLength, len(sum))]
+	tags := make([]string, 0, len(t.Tags))
+	for _, tag := range t.Tags {
+		if rendered := t.render(tag, build, at); !slices.Contains(tags, rendered) {
+			tags = append(tags, rendered)
+		}
+	}
+	return tags
+}
+
+// isChannel reports whether channel is configured.
+func (t CatalogTagging) isChannel(channel string) bool {
+	return slices.Contains(t.Channels, channel)
+}
+
+// setChannel records the catalog a channel points at.
+func (cm *CatalogManager) setChannel(channel, digest string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.channels[channel] = digest
+}
+
+// channelDigest returns the catalog a channel was last seen pointing at, or
+// "" if it doesn't point at one.
+func (cm *CatalogManager) channelDigest(channel string) string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.channels[channel]
+}
+
+// Channels describes the configured catalog channels as last seen.
+func (cm *CatalogManager) Channels() []model.CatalogChannel {
+	channels := make([]model.CatalogChannel, 0, len(cm.tagging.Channels))
+	for _, name := range cm.tagging.Channels {
+		channel := model.CatalogChannel{Name: name, Publish: name == cm.tagging.PublishChannel}
+		if channel.Digest = cm.channelDigest(name); channel.Digest != "" {
+			channel.Reference = cm.ociClient.CatalogReference(channel.Digest)
+		}
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// refreshChannels reads where every channel points in the registry, to
+// pick up promotions made through other replicas.
+func (cm *CatalogManager) refreshChannels(ctx context.Context) error {
+	for _, channel := range cm.tagging.Channels {
+		digest, err := cm.ociClient.ResolveCatalog(ctx, channel)
+		if err != nil {
+			return fmt.Errorf("resolving channel %s: %w", channel, err)
+		}
+		cm.setChannel(channel, digest)
+	}
+	return nil
+}
+
+// Promote points a channel at the catalog a reference (a version, digest or
+// another channel) resolves to.
+func (cm *CatalogManager) Promote(ctx context.Context, channel, reference string) (model.CatalogChannel, error) {
+	digest, err := cm.ociClient.ResolveCatalog(ctx, reference)
+	if err != nil {
+		return model.CatalogChannel{}, err
+	}
+	if digest == "" {
+		return model.CatalogChannel{}, fmt.Errorf("catalog %s: %w", reference, errdef.ErrNotFound)
+	}
+	if err := cm.ociClient.TagCatalog(ctx, digest, channel); err != nil {
+		return model.CatalogChannel{}, err
+	}
+	cm.setChannel(channel, digest)
+
+	log.Printf("Promoted catalog %s to %s (digest=%s)", reference, channel, digest)
+	cm.events.Record(ctx, model.Event{
+		Type:    model.EventCatalogPromoted,
+		Digest:  digest,
+		Message: fmt.Sprintf("%s promoted from %s", channel, reference),
+	})
+	return model.CatalogChannel{
+		Name:      channel,
+		Digest:    digest,
+		Reference: cm.ociClient.CatalogReference(digest),
+		Publish:   channel == cm.tagging.PublishChannel,
+	}, nil
+}
+
+// ListCatalogChannels handles GET /api/v1/catalog/channels.
+func (h *Handler) ListCatalogChannels(w http.ResponseWriter, r *http.Request) {
+	if err := h.catalog.refreshChannels(r.Context()); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	channels := h.catalog.Channels()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"channels": channels,
+		"count":    len(channels),
+	})
+}
+
+// PromoteCatalog handles POST /api/v1/catalog/channels/{channel}/promote.
+// It moves the channel's tag to the catalog named by exactly one of from
+// (another channel), version or digest.
+func (h *Handler) PromoteCatalog(w http.ResponseWriter, r *http.Request) {
+	channel := r.PathValue("channel")
+	if !h.catalog.tagging.isChannel(channel) {
+		writeError(w, http.StatusNotFound, "catalog channel %q not found", channel)
+		return
+	}
+	var req model.CatalogPromoteRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+
+	var references []string
+	for _, ref := range []string{req.From, req.Version, req.Digest} {
+		if ref != "" {
+			references = append(references, ref)
+		}
+	}
+	if len(references) != 1 {
+		writeError(w, http.StatusBadRequest, "exactly one of from, version or digest is required")
+		return
+	}
+	switch {
+	case req.From == channel:
+		writeError(w, http.StatusBadRequest, "can't promote %s from itself", channel)
+		return
+	case req.From != "" && !h.catalog.tagging.isChannel(req.From):
+		writeError(w, http.StatusUnprocessableEntity, "catalog channel %q not found", req.From)
+		return
+	case req.Version != "" && !strings.HasPrefix(req.Version, "v"):
+		writeError(w, http.StatusBadRequest, "version %q is not a catalog version", req.Version)
+		return
+	case req.Digest != "" && !strings.HasPrefix(req.Digest, "sha256:"):
+		writeError(w, http.StatusBadRequest, "digest %q is not a sha256 digest", req.Digest)
+		return
+	}
+	if !h.checkFreeze(w, r, "") {
+		return
+	}
+
+	resp, err := h.catalog.Promote(r.Context(), channel, references[0])
+	switch {
+	case errors.Is(err, errdef.ErrNotFound):
+		writeError(w, http.StatusNotFound, "catalog %s not found", references[0])
+		return
+	case err != nil:
+		writeError(w, http.StatusBadGateway, "promoting catalog: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+	log.Printf("Audit: promoted catalog %s to %s (digest=%s) by %s", references[0], channel, resp.Digest, auth.Actor(r.Context()))
+}
+
+// GetChannelFlux handles GET /api/v1/catalog/channels/{channel}/flux.
+// It renders the OCIRepository/Kustomization pair a cluster applies to
+// follow a channel of the catalog.
+func (h *Handler) GetChannelFlux(w http.ResponseWriter, r *http.Request) {
+	channel := r.PathValue("channel")
+	if !h.catalog.tagging.isChannel(channel) {
+		writeError(w, http.StatusNotFound, "catalog channel %q not found", channel)
+		return
+	}
+
+	out, err := renderChannelFluxObjects(channel, h.ociClient.CatalogURL())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "rendering flux objects: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"sigs.k8s.io/yaml"
+)
+
+// publishedCluster is the last catalog pushed for a target cluster.
+type publishedCluster struct {
+	sum         [sha256.Size]byte // hash of the catalog's resources and namespaces
+	digest      string
+	version     string
+	resources   int
+	publishedAt time.Time
+}
+
+// pushClusterCatalogs publishes the catalog of every target cluster whose
+// subset of resources changed since it was last pushed. Failures are logged
+// and retried on the next publish, as for per-resource bundles.
+func (cm *CatalogManager) pushClusterCatalogs(ctx context.Context, resources, namespaces map[string][]byte) {
+	for _, cluster := range cm.clusters {
+		selected := selectClusterResources(cluster.Selector, resources)
+		clusterNamespaces := make(map[string][]byte)
+		for namespace, manifest := range namespaces {
+			if clusterHoldsNamespace(cluster.Selector, namespace, selected) {
+				clusterNamespaces[namespace] = manifest
+			}
+		}
+
+		contents := make(map[string][]byte, len(selected)+len(clusterNamespaces))
+		for key, manifest := range selected {
+			contents[key] = manifest
+		}
+		for namespace, manifest := range clusterNamespaces {
+			contents[namespaceManifestDir+namespace] = manifest
+		}
+		sum := shardSum(contents)
+
+		cm.mu.RLock()
+		published, ok := cm.clusterCatalogs[cluster.Name]
+		cm.mu.RUnlock()
+		if ok && published.sum == sum {
+			continue
+		}
+
+		tarGz, err := cm.buildTarGz(selected, clusterNamespaces, nil)
+		if err != nil {
+			log.Printf("Warning: failed to build catalog for cluster %s: %v", cluster.Name, err)
+			continue
+		}
+		digest, version, err := cm.ociClient.PushClusterCatalog(ctx, cluster.Name, tarGz)
+		tarGz.Close()
+		if err != nil {
+			log.Printf("Warning: failed to push catalog for cluster %s: %v", cluster.Name, err)
+			continue
+		}
+
+		cm.mu.Lock()
+		cm.clusterCatalogs[cluster.Name] = publishedCluster{
+			sum:         sum,
+			digest:      digest,
+			version:     version,
+			resources:   len(selected),
+			publishedAt: time.Now().UTC(),
+		}
+		cm.mu.Unlock()
+		log.Printf("Pushed catalog %s for cluster %s with %d resources (digest=%s)", version, cluster.Name, len(selected), digest)
+	}
+}
+
+// selectClusterResources returns the resources matched by a cluster's
+// selector.
+func selectClusterResources(selector model.ClusterSelector, resources map[string][]byte) map[string][]byte {
+	selected := make(map[string][]byte)
+	for key, manifest := range resources {
+		namespace, _, _ := strings.Cut(key, "/")
+		var pr model.PlatformResource
+		if err := yaml.Unmarshal(manifest, &pr); err != nil {
+			log.Printf("Warning: skipping unparseable manifest %s for cluster selection: %v", key, err)
+			continue
+		}
+		if selector.Matches(namespace, pr.Spec.Type, pr.Metadata.Labels) {
+			selected[key] = manifest
+		}
+	}
+	return selected
+}
+
+// clusterHoldsNamespace reports whether a cluster's catalog includes a
+// namespace: it is listed by the selector, or holds a selected resource.
+func clusterHoldsNamespace(selector model.ClusterSelector, namespace string, selected map[string][]byte) bool {
+	for _, ns := range selector.Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	for key := range selected {
+		if strings.HasPrefix(key, namespace+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Clusters returns every target cluster with its last published catalog.
+func (cm *CatalogManager) Clusters() []model.ClusterResponse {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	clusters := make([]model.ClusterResponse, 0, len(cm.clusters))
+	for _, cluster := range cm.clusters {
+		clusters = append(clusters, cm.clusterResponseLocked(cluster))
+	}
+	return clusters
+}
+
+// Cluster returns one target cluster.
+func (cm *CatalogManager) Cluster(name string) (model.ClusterResponse, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	for _, cluster := range cm.clusters {
+		if cluster.Name == name {
+			return cm.clusterResponseLocked(cluster), true
+		}
+	}
+	return model.ClusterResponse{}, false
+}
+
+func (cm *CatalogManager) clusterResponseLocked(cluster model.Cluster) model.ClusterResponse {
+	selector := cluster.Selector
+	resp := model.ClusterResponse{
+		Name:        cluster.Name,
+		Environment: cluster.Environment,
+		Selector:    &selector,
+		URL:         cm.ociClient.ClusterCatalogURL(cluster.Name),
+	}
+	if published, ok := cm.clusterCatalogs[cluster.Name]; ok {
+		resp.Digest = published.digest
+		resp.Version = published.version
+		resp.ResourceCount = published.resources
+		resp.PublishedAt = published.publishedAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// ListClusters handles GET /api/v1/clusters.
+// It lists target clusters and registered clusters, merged by name.
+func (h *Handler) ListClusters(w http.ResponseWriter, _ *http.Request) {
+	names := h.clusters.Names()
+	for _, target := range h.catalog.Clusters() {
+		if !slices.Contains(names, target.Name) {
+			names = append(names, target.Name)
+		}
+	}
+	sort.Strings(names)
+
+	clusters := make([]model.ClusterResponse, 0, len(names))
+	for _, name := range names {
+		if cluster, ok := h.clusterResponse(name); ok {
+			clusters = append(clusters, cluster)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"clusters": clusters,
+		"count":    len(clusters),
+	})
+}
+
+// GetCluster handles GET /api/v1/clusters/{cluster}.
+func (h *Handler) GetCluster(w http.ResponseWriter, r *http.Request) {
+	cluster, ok := h.clusterResponse(r.PathValue("cluster"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "cluster %q not found", r.PathValue("cluster"))
+		return
+	}
+	writeJSON(w, http.StatusOK, cluster)
+}
+
+// GetClusterFlux handles GET /api/v1/clusters/{cluster}/flux.
+// It renders the OCIRepository/Kustomization pair to apply on the target
+// cluster so it reconciles its own catalog.
+func (h *Handler) GetClusterFlux(w http.ResponseWriter, r *http.Request) {
+	cluster, ok := h.catalog.Cluster(r.PathValue("cluster"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "target cluster %q not found", r.PathValue("cluster"))
+		return
+	}
+
+	out, err := renderClusterFluxObjects(cluster.Name, cluster.URL)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "rendering flux objects: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
@@ -0,0 +1,301 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/kube"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// defaultHeartbeatTimeout is how long a registered cluster may go without a
+// heartbeat before it is reported as disconnected.
+const defaultHeartbeatTimeout = 5 * time.Minute
+
+// ClusterStore holds cluster registrations in memory and persists them to
+// the registry as a single JSON document on every change. Heartbeats are
+// kept in memory only; after a restart clusters are pending until they
+// next report.
+type ClusterStore struct {
+	ociClient        oci.Interface
+	heartbeatTimeout time.Duration
+	mu               sync.RWMutex
+	registrations    map[string]model.ClusterRegistration
+	heartbeats       map[string]clusterHeartbeat
+}
+
+// clusterHeartbeat is the last heartbeat received from a cluster.
+type clusterHeartbeat struct {
+	digest string
+	ready  *bool
+	at     time.Time
+}
+
+// NewClusterStore creates an empty cluster store. A zero heartbeatTimeout
+// uses the default of 5 minutes.
+func NewClusterStore(client oci.Interface, heartbeatTimeout time.Duration) *ClusterStore {
+	if heartbeatTimeout <= 0 {
+		heartbeatTimeout = defaultHeartbeatTimeout
+	}
+	return &ClusterStore{
+		ociClient:        client,
+		heartbeatTimeout: heartbeatTimeout,
+		registrations:    make(map[string]model.ClusterRegistration),
+		heartbeats:       make(map[string]clusterHeartbeat),
+	}
+}
+
+// Get returns a cluster registration by name.
+func (cs *ClusterStore) Get(name string) (model.ClusterRegistration, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	r, ok := cs.registrations[name]
+	return r, ok
+}
+
+// Names returns the names of all registered clusters.
+func (cs *ClusterStore) Names() []string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	names := make([]string, 0, len(cs.registrations))
+	for name := range cs.registrations {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Put creates or replaces a registration and persists the set. The
+// registration time of an existing cluster is kept.
+func (cs *ClusterStore) Put(ctx context.Context, r model.ClusterRegistration) (model.ClusterRegistration, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	prev, existed := cs.registrations[r.Name]
+	if existed {
+		r.RegisteredAt = prev.RegisteredAt
+	} else {
+		r.RegisteredAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	cs.registrations[r.Name] = r
+	if err := cs.persistLocked(ctx); err != nil {
+		if existed {
+			cs.registrations[r.Name] = prev
+		} else {
+			delete(cs.registrations, r.Name)
+		}
+		return model.ClusterRegistration{}, err
+	}
+	return r, nil
+}
+
+// Delete removes a registration and persists the set. It reports whether
+// the cluster was registered.
+func (cs *ClusterStore) Delete(ctx context.Context, name string) (bool, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	prev, ok := cs.registrations[name]
+	if !ok {
+		return false, nil
+	}
+	delete(cs.registrations, name)
+	if err := cs.persistLocked(ctx); err != nil {
+		cs.registrations[name] = prev
+		return true, err
+	}
+	delete(cs.heartbeats, name)
+	return true, nil
+}
+
+// Heartbeat records the catalog digest a registered cluster is running and,
+// if reported, whether it is ready. It reports whether the cluster is
+// registered.
+func (cs *ClusterStore) Heartbeat(name, digest string, ready *bool) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if _, ok := cs.registrations[name]; !ok {
+		return false
+	}
+	cs.heartbeats[name] = clusterHeartbeat{digest: digest, ready: ready, at: time.Now().UTC()}
+	return true
+}
+
+// state returns a registered cluster's last heartbeat and its state given
+// the digest it is expected to run.
+func (cs *ClusterStore) state(name, expectedDigest string) (clusterHeartbeat, string) {
+	cs.mu.RLock()
+	hb, ok := cs.heartbeats[name]
+	cs.mu.RUnlock()
+	switch {
+	case !ok:
+		return hb, model.ClusterStatePending
+	case time.Since(hb.at) > cs.heartbeatTimeout:
+		return hb, model.ClusterStateDisconnected
+	case hb.digest != expectedDigest:
+		return hb, model.ClusterStateStale
+	}
+	return hb, model.ClusterStateCurrent
+}
+
+func (cs *ClusterStore) persistLocked(ctx context.Context) error {
+	list := make([]model.ClusterRegistration, 0, len(cs.registrations))
+	for _, r := range cs.registrations {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("encoding cluster registrations: %w", err)
+	}
+	if err := cs.ociClient.PushClusterRegistrations(ctx, data); err != nil {
+		return fmt.Errorf("pushing cluster registrations: %w", err)
+	}
+	return nil
+}
+
+// Restore loads cluster registrations from the registry.
+func (cs *ClusterStore) Restore(ctx context.Context) error {
+	data, err := cs.ociClient.PullClusterRegistrations(ctx)
+	if err != nil {
+		return fmt.Errorf("pulling cluster registrations: %w", err)
+	}
+	if data == nil {
+		return nil
+	}
+
+	var list []model.ClusterRegistration
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("parsing cluster registrations: %w", err)
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	for _, r := range list {
+		cs.registrations[r.Name] = r
+	}
+	log.Printf("Restored %d cluster registrations from registry", len(list))
+	return nil
+}
+
+// clusterResponse describes the target or registered cluster called name.
+func (h *Handler) clusterResponse(name string) (model.ClusterResponse, bool) {
+	resp, isTarget := h.catalog.Cluster(name)
+	reg, registered := h.clusters.Get(name)
+	if !registered {
+		return resp, isTarget
+	}
+
+	resp.Name = reg.Name
+	if reg.Environment != "" {
+		resp.Environment = reg.Environment
+	}
+	resp.Registered = true
+	resp.RegisteredAt = reg.RegisteredAt
+
+	resp.Catalog = reg.Catalog
+	resp.Channel = reg.Channel
+	if resp.Catalog == "" && resp.Channel == "" && isTarget {
+		resp.Catalog = name
+	}
+	switch {
+	case resp.Channel != "":
+		resp.ExpectedDigest = h.catalog.channelDigest(resp.Channel)
+	case resp.Catalog == "":
+		resp.ExpectedDigest = h.catalog.Status().Digest
+	default:
+		if target, ok := h.catalog.Cluster(resp.Catalog); ok {
+			resp.ExpectedDigest = target.Digest
+		}
+	}
+
+	hb, state := h.clusters.state(name, resp.ExpectedDigest)
+	resp.State = state
+	resp.RunningDigest = hb.digest
+	resp.Ready = hb.ready
+	if !hb.at.IsZero() {
+		resp.LastHeartbeat = hb.at.Format(time.RFC3339)
+	}
+	return resp, true
+}
+
+// RegisterCluster handles POST /api/v1/clusters.
+func (h *Handler) RegisterCluster(w http.ResponseWriter, r *http.Request) {
+	var reg model.ClusterRegistration
+	if err := decodeJSON(r, &reg); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	if err := reg.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if reg.Catalog != "" {
+		if _, ok := h.catalog.Cluster(reg.Catalog); !ok {
+			writeError(w, http.StatusUnprocessableEntity, "catalog %q is not a target cluster", reg.Catalog)
+			return
+		}
+	}
+	if reg.Channel != "" && !h.catalog.tagging.isChannel(reg.Channel) {
+		writeError(w, http.StatusUnprocessableEntity, "catalog channel %q not found", reg.Channel)
+		return
+	}
+
+	_, existed := h.clusters.Get(reg.Name)
+	if _, err := h.clusters.Put(r.Context(), reg); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	status := http.StatusCreated
+	if existed {
+		status = http.StatusOK
+	}
+	resp, _ := h.clusterResponse(reg.Name)
+	writeJSON(w, status, resp)
+	log.Printf("Registered cluster %s", reg.Name)
+}
+
+// DeregisterCluster handles DELETE /api/v1/clusters/{cluster}.
+func (h *Handler) DeregisterCluster(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("cluster")
+	ok, err := h.clusters.Delete(r.Context(), name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "cluster %q is not registered", name)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	log.Printf("Deregistered cluster %s", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ClusterHeartbeat handles POST /api/v1/clusters/{cluster}/heartbeat.
+// It records the catalog digest the cluster is running and returns the
+// cluster's state, including the digest it is expected to run.
+func (h *Handler) ClusterHeartbeat(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("cluster")
+	var hb model.ClusterHeartbeat
+	if err := decodeJSON(r, &hb); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	if hb.Digest == "" {
+		writeError(w, http.StatusBadRequest, "digest is required")
+		return
+	}
+
+	if !h.clusters.Heartbeat(name, kube.RevisionDigest(hb.Digest), hb.Ready) {
+		writeError(w, http.StatusNotFound, "cluster %q is not registered", name)
+		return
+	}
+	resp, _ := h.clusterResponse(name)
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// cooldownOverrideHeader carries an admin's reason for changing a resource
+// during its cooldown.
+const cooldownOverrideHeader = "X-Cooldown-Override"
+
+// CooldownError is returned for a change to a resource that already
+// changed as often as its cooldown allows.
+type CooldownError struct {
+	Namespace   string
+	Name        string
+	Cooldown    model.Cooldown
+	NextAllowed time.Time
+}
+
+func (e *CooldownError) Error() string {
+	return fmt.Sprintf("%s/%s changed %d times in the last %s (cooldown %q); next change allowed at %s; admins may set %s to override",
+		e.Namespace, e.Name, e.Cooldown.MaxChanges, e.Cooldown.Window, e.Cooldown.Name,
+		e.NextAllowed.UTC().Format(time.RFC3339), cooldownOverrideHeader)
+}
+
+// cooldownTracker remembers when each resource recently changed. It is
+// per replica; after a restart only the last change, from the registry,
+// is known.
+type cooldownTracker struct {
+	mu      sync.Mutex
+	changes map[string][]time.Time // "namespace/name" -> recent change times, oldest first
+}
+
+// record notes a change to the resource with key at t, forgetting changes
+// older than keep.
+func (t *cooldownTracker) record(key string, at time.Time, keep time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.changes == nil {
+		t.changes = make(map[string][]time.Time)
+	}
+	t.changes[key] = append(recentChanges(t.changes[key], at.Add(-keep)), at)
+}
+
+// since returns the recorded changes to the resource with key after from.
+func (t *cooldownTracker) since(key string, from time.Time) []time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return recentChanges(t.changes[key], from)
+}
+
+func recentChanges(changes []time.Time, from time.Time) []time.Time {
+	i := 0
+	for i < len(changes) && !changes[i].After(from) {
+		i++
+	}
+	return slices.Clone(changes[i:])
+}
+
+// cooldownExempt lists the sources of changes cooldowns don't apply to:
+// ones the platform makes itself rather than a client.
+var cooldownExempt = []string{SourceSchedule, SourceExpiry, SourceMigration}
+
+type cooldownOverrideKey struct{}
+
+// withCooldownOverride carries the request's cooldown override reason, if
+// any, into its context.
+func withCooldownOverride(r *http.Request) *http.Request {
+	reason := r.Header.Get(cooldownOverrideHeader)
+	if reason == "" {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), cooldownOverrideKey{}, reason))
+}
+
+// checkCooldown returns a *CooldownError if a resource of resourceType
+// can't change now because of its cooldown. Callers hold the resource's
+// writer lock and call recordChange once the change is pushed.
+func (h *Handler) checkCooldown(ctx context.Context, namespace, name, resourceType string) error {
+	cooldown, ok := h.cooldowns.For(namespace, resourceType)
+	if !ok || slices.Contains(cooldownExempt, requestOrigin(ctx).source) {
+		return nil
+	}
+
+	now := time.Now()
+	from := now.Add(-cooldown.WindowDuration())
+	changes := h.changes.since(namespace+"/"+name, from)
+	if len(changes) == 0 {
+		// Nothing recorded since the start: fall back to the registry.
+		if meta, ok := h.catalog.Meta(namespace, name); ok && meta.UpdatedAt.After(from) {
+			changes = []time.Time{meta.UpdatedAt}
+		}
+	}
+	if len(changes) < cooldown.MaxChanges {
+		return nil
+	}
+
+	if reason, ok := ctx.Value(cooldownOverrideKey{}).(string); ok {
+		if h.inAdminGroup(ctx) {
+			log.Printf("Audit: cooldown %q overridden for %s/%s by %s: %s", cooldown.Name, namespace, name, auth.Actor(ctx), reason)
+			return nil
+		}
+		log.Printf("Warning: %s may not override cooldown %q: not in an admin group", auth.Actor(ctx), cooldown.Name)
+	}
+	return &CooldownError{
+		Namespace: namespace,
+		Name:      name,
+		Cooldown:  cooldown,
+		// The oldest change in the window leaving it frees a slot.
+		NextAllowed: changes[len(changes)-cooldown.MaxChanges].Add(cooldown.WindowDuration()),
+	}
+}
+
+// recordChange notes a change to a resource for its cooldown.
+func (h *Handler) recordChange(namespace, name, resourceType string) {
+	if cooldown, ok := h.cooldowns.For(namespace, resourceType); ok {
+		h.changes.record(namespace+"/"+name, time.Now(), cooldown.WindowDuration())
+	}
+}
+
+// writeCooldownError writes a 429 with the time the next change is allowed,
+// also as Retry-After.
+func writeCooldownError(w http.ResponseWriter, e *CooldownError) {
+	wait := time.Until(e.NextAllowed)
+	w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+	writeJSON(w, http.StatusTooManyRequests, map[string]string{
+		"error":         e.Error(),
+		"code":          model.CodeCooldown,
+		"cooldown":      e.Cooldown.Name,
+		"nextAllowedAt": e.NextAllowed.UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultCORSMaxAge is how long browsers may cache a preflight response.
+const defaultCORSMaxAge = 10 * time.Minute
+
+// corsExposedHeaders are the response headers a page on another origin may
+// read.
+var corsExposedHeaders = []string{"ETag", "Location", "Retry-After", "X-Request-ID", "X-Resource-Version", "X-Catalog-Digest", "X-Catalog-Resources"}
+
+// CORSOptions lets browser pages on other origins call the API.
+type CORSOptions struct {
+	// AllowedOrigins are origins such as "https://console.example.com",
+	// matched exactly. "*" allows any origin, but not with credentials.
+	AllowedOrigins []string
+
+	// AllowCredentials lets pages send cookies, such as the session
+	// cookie, and Authorization headers.
+	AllowCredentials bool
+
+	// MaxAge bounds how long preflight responses are cached. Defaults to
+	// 10m.
+	MaxAge time.Duration
+}
+
+func (o *CORSOptions) allows(origin string) bool {
+	return slices.Contains(o.AllowedOrigins, origin) || (!o.AllowCredentials && slices.Contains(o.AllowedOrigins, "*"))
+}
+
+// CORS answers preflight requests and adds CORS headers to responses for
+// allowed origins. Wrap puts it outside the configured middleware, since
+// browsers send preflights without credentials. Requests from other origins
+// get no CORS headers, so browsers keep their pages from reading the
+// response; their preflights get 403.
+func (h *Handler) CORS(next http.Handler) http.Handler {
+	opts := h.cors
+	maxAge := opts.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultCORSMaxAge
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !opts.allows(origin) {
+			if preflight {
+				writeError(w, http.StatusForbidden, "origin %s is not allowed", origin)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if opts.AllowCredentials || !slices.Contains(opts.AllowedOrigins, "*") {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+		if opts.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE")
+		if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		}
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
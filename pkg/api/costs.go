@@ -0,0 +1,51 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// GetNamespaceCosts handles GET /api/v1/namespaces/{namespace}/costs.
+// It sums the cost estimates recorded on a namespace's live resources.
+func (h *Handler) GetNamespaceCosts(w http.ResponseWriter, r *http.Request) {
+	namespace := r.PathValue("namespace")
+	if err := model.ValidateNamespace(namespace); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	resp := model.NamespaceCostsResponse{
+		Namespace: namespace,
+		Totals:    map[string]float64{},
+		Resources: []model.ResourceCost{},
+	}
+	for key := range h.catalog.List() {
+		ns, name, _ := strings.Cut(key, "/")
+		if ns != namespace {
+			continue
+		}
+		meta, _ := h.catalog.Meta(ns, name)
+		if meta.Cost == nil {
+			resp.Unestimated = append(resp.Unestimated, name)
+			continue
+		}
+		resp.Resources = append(resp.Resources, model.ResourceCost{
+			Name:     name,
+			Monthly:  meta.Cost.Monthly,
+			Currency: meta.Cost.Currency,
+		})
+		resp.Totals[meta.Cost.Currency] += meta.Cost.Monthly
+	}
+
+	for currency, total := range resp.Totals {
+		resp.Totals[currency] = math.Round(total*100) / 100
+	}
+	sort.Slice(resp.Resources, func(i, j int) bool { return resp.Resources[i].Name < resp.Resources[j].Name })
+	sort.Strings(resp.Unestimated)
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+// startTime is when the process started, for uptime in GET /debug/vars.
+var startTime = time.Now()
+
+// registerDebugRoutes registers the pprof profiles under /debug/pprof/
+// and GET /debug/vars, all admin-only.
+func (h *Handler) registerDebugRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /debug/pprof/", h.adminOnly(pprof.Index))
+	mux.HandleFunc("GET /debug/pprof/cmdline", h.adminOnly(pprof.Cmdline))
+	mux.HandleFunc("GET /debug/pprof/profile", h.adminOnly(pprof.Profile))
+	mux.HandleFunc("GET /debug/pprof/symbol", h.adminOnly(pprof.Symbol))
+	mux.HandleFunc("POST /debug/pprof/symbol", h.adminOnly(pprof.Symbol))
+	mux.HandleFunc("GET /debug/pprof/trace", h.adminOnly(pprof.Trace))
+	mux.HandleFunc("GET /debug/vars", h.adminOnly(h.GetDebugVars))
+}
+
+// GetDebugVars handles GET /debug/vars.
+// It reports the process's goroutines and memory, the catalog's size and
+// the registry client's operation counts, read without contacting the
+// registry so it answers even while the registry is stuck.
+func (h *Handler) GetDebugVars(w http.ResponseWriter, _ *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	m := &h.catalog.metrics
+	m.mu.Lock()
+	size, buildDuration, failures := m.size, m.buildDuration, m.failures
+	m.mu.Unlock()
+	status := h.catalog.Status()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"uptime":     time.Since(startTime).Round(time.Second).String(),
+		"goroutines": runtime.NumGoroutine(),
+		"memory": map[string]any{
+			"heapAllocBytes":   mem.HeapAlloc,
+			"heapInuseBytes":   mem.HeapInuse,
+			"heapObjects":      mem.HeapObjects,
+			"sysBytes":         mem.Sys,
+			"totalAllocBytes":  mem.TotalAlloc,
+			"gcCycles":         mem.NumGC,
+			"gcPauseTotalSecs": time.Duration(mem.PauseTotalNs).Seconds(),
+		},
+		"catalog": map[string]any{
+			"version":         status.Version,
+			"resources":       status.ResourceCount,
+			"liveResources":   len(h.catalog.List()),
+			"sizeBytes":       size,
+			"buildDuration":   buildDuration.String(),
+			"publishFailures": failures,
+		},
+		"registry": h.ociClient.Stats(),
+	})
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// strictJSON reports whether a request's JSON body must not contain
+// unknown fields. Clients opt out with ?strict=false.
+func strictJSON(r *http.Request) bool {
+	return r.URL.Query().Get("strict") != "false"
+}
+
+// decodeJSON decodes a request's JSON body into v, rejecting unknown
+// fields unless the request opted out.
+func decodeJSON(r *http.Request, v any) error {
+	return decodeJSONFrom(r, r.Body, v)
+}
+
+// unmarshalJSON decodes data on behalf of a request, like decodeJSON.
+func unmarshalJSON(r *http.Request, data []byte, v any) error {
+	return decodeJSONFrom(r, bytes.NewReader(data), v)
+}
+
+func decodeJSONFrom(r *http.Request, body io.Reader, v any) error {
+	dec := json.NewDecoder(body)
+	if strictJSON(r) {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return explainUnknownField(err, v)
+	}
+	return nil
+}
+
+// explainUnknownField turns the decoder's unknown field error into one
+// suggesting the known field the client probably meant.
+func explainUnknownField(err error, v any) error {
+	field, ok := strings.CutPrefix(err.Error(), `json: unknown field "`)
+	if !ok {
+		return err
+	}
+	field = strings.TrimSuffix(field, `"`)
+
+	msg := fmt.Sprintf("unknown field %q", field)
+	if suggestion := closestField(field, jsonFields(reflect.TypeOf(v), nil)); suggestion != "" {
+		msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+	}
+	return fmt.Errorf("%s; send ?strict=false to ignore unknown fields", msg)
+}
+
+// jsonFields collects the JSON field names of t and the types nested in
+// it.
+func jsonFields(t reflect.Type, seen map[reflect.Type]bool) []string {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if seen == nil {
+		seen = make(map[reflect.Type]bool)
+	}
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" && !f.Anonymous {
+			name = f.Name
+		}
+		if name != "" {
+			fields = append(fields, name)
+		}
+		fields = append(fields, jsonFields(f.Type, seen)...)
+	}
+	return fields
+}
+
+// closestField returns the field within edit distance 2 of name, if any.
+func closestField(name string, fields []string) string {
+	best, bestDistance := "", 3
+	for _, f := range fields {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(f)); d < bestDistance {
+			best, bestDistance = f, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Damerau-Levenshtein distance between a and b with
+// adjacent transpositions, so "szie" is one edit away from "size".
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
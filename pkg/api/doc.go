@@ -0,0 +1,29 @@
+// Package api serves the gitops-squared HTTP API and maintains the
+// Flux-consumable catalog built from the resources stored in an OCI
+// registry.
+//
+// To embed the API in another server, create an oci.Client (or any other
+// oci.Interface), a CatalogManager with NewCatalogManager and a Handler
+// with NewHandler, restore the catalog from the registry, then register the
+// routes on a mux and serve it through Handler.Wrap:
+//
+//	client := oci.NewClient("registry.example.com", "gitops-squared/resources")
+//	catalog := api.NewCatalogManager(client, api.CatalogOptions{DeleteGracePeriod: 24 * time.Hour})
+//	handler := api.NewHandler(client, catalog, api.HandlerOptions{})
+//	if err := catalog.Restore(ctx); err != nil {
+//		log.Printf("starting with an empty catalog: %v", err)
+//	}
+//	mux := http.NewServeMux()
+//	handler.RegisterRoutes(mux)
+//	http.ListenAndServe(":8080", handler.Wrap(mux))
+//
+// Errors callers may want to handle are typed: *ConflictError for stale
+// writes, *ExistsError for creates of existing resources,
+// *model.ValidationError for invalid requests, oci.ErrIntegrity for
+// artifacts that fail verification, ErrCatalogNotPublished, and
+// ErrJobNotFound and ErrJobFinished from JobManager.Cancel.
+//
+// Options for optional features, such as admission webhooks, SOPS
+// encryption or the Kubernetes client, take types from the pkg/admission,
+// pkg/secrets and pkg/kube packages. Leaving them nil turns the feature off.
+package api
@@ -0,0 +1,331 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// Default event log limits.
+const (
+	defaultEventHistory = 1000
+	maxEventPage        = 1000
+
+	// eventSubscriberBuffer is how many events a stream may fall behind
+	// before it is closed. The client reconnects and backfills from the
+	// history.
+	eventSubscriberBuffer = 64
+)
+
+// EventLog keeps the most recent resource and catalog events in memory,
+// fans them out to live streams and periodically persists them to the
+// registry, so clients can backfill what they missed.
+type EventLog struct {
+	ociClient oci.Interface
+	size      int
+
+	mu          sync.RWMutex
+	events      []model.Event // oldest first
+	lastID      int64
+	dirty       bool
+	subscribers map[chan model.Event]struct{}
+}
+
+// NewEventLog creates an empty event log keeping the last size events.
+// Zero keeps the default of 1000.
+func NewEventLog(client oci.Interface, size int) *EventLog {
+	if size <= 0 {
+		size = defaultEventHistory
+	}
+	return &EventLog{
+		ociClient:   client,
+		size:        size,
+		subscribers: make(map[chan model.Event]struct{}),
+	}
+}
+
+// Record stamps e with an ID, the time and, unless set, the caller of ctx,
+// and appends it. Event IDs are microsecond timestamps, bumped where needed
+// so they keep increasing, also across restarts.
+func (el *EventLog) Record(ctx context.Context, e model.Event) model.Event {
+	now := time.Now().UTC()
+	if e.Actor == "" {
+		e.Actor = auth.Actor(ctx)
+	}
+	e.Time = now.Format(time.RFC3339)
+
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	e.ID = max(el.lastID+1, now.UnixMicro())
+	el.lastID = e.ID
+	el.events = append(el.events, e)
+	if len(el.events) > el.size {
+		el.events = el.events[len(el.events)-el.size:]
+	}
+	el.dirty = true
+
+	for ch := range el.subscribers {
+		select {
+		case ch <- e:
+		default:
+			// Too far behind: drop the stream rather than block writers.
+			delete(el.subscribers, ch)
+			close(ch)
+		}
+	}
+	return e
+}
+
+// Since returns the events after ID after and at or after time since,
+// oldest first. Zero arguments don't filter.
+func (el *EventLog) Since(after int64, since time.Time) []model.Event {
+	el.mu.RLock()
+	defer el.mu.RUnlock()
+	i := sort.Search(len(el.events), func(i int) bool { return el.events[i].ID > after })
+	list := []model.Event{}
+	for _, e := range el.events[i:] {
+		if !since.IsZero() && e.ID < since.UnixMicro() {
+			continue
+		}
+		list = append(list, e)
+	}
+	return list
+}
+
+// LastID returns the ID of the latest event, or 0.
+func (el *EventLog) LastID() int64 {
+	el.mu.RLock()
+	defer el.mu.RUnlock()
+	return el.lastID
+}
+
+// Subscribe returns a channel receiving every event recorded from now on.
+// The channel is closed if the subscriber falls too far behind. Call
+// unsubscribe when done.
+func (el *EventLog) Subscribe() (events <-chan model.Event, unsubscribe func()) {
+	ch := make(chan model.Event, eventSubscriberBuffer)
+	el.mu.Lock()
+	el.subscribers[ch] = struct{}{}
+	el.mu.Unlock()
+	return ch, func() {
+		el.mu.Lock()
+		defer el.mu.Unlock()
+		if _, ok := el.subscribers[ch]; ok {
+			delete(el.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Flush persists the events to the registry if any were recorded since
+// the last flush.
+func (el *EventLog) Flush(ctx context.Context) error {
+	el.mu.Lock()
+	if !el.dirty {
+		el.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(el.events)
+	el.dirty = false
+	el.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encoding events: %w", err)
+	}
+
+	if err := el.ociClient.PushEvents(ctx, data); err != nil {
+		el.mu.Lock()
+		el.dirty = true
+		el.mu.Unlock()
+		return fmt.Errorf("pushing events: %w", err)
+	}
+	return nil
+}
+
+// Run flushes the events every interval until ctx is cancelled. Events
+// recorded since the last flush are lost if the server stops.
+func (el *EventLog) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := el.Flush(ctx); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+	}
+}
+
+// Restore loads the events persisted by the last flush.
+func (el *EventLog) Restore(ctx context.Context) error {
+	data, err := el.ociClient.PullEvents(ctx)
+	if err != nil {
+		return fmt.Errorf("pulling events: %w", err)
+	}
+	if data == nil {
+		return nil
+	}
+
+	var list []model.Event
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("parsing events: %w", err)
+	}
+
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	// Events recorded since startup come after the restored ones.
+	el.events = append(list, el.events...)
+	if len(el.events) > el.size {
+		el.events = el.events[len(el.events)-el.size:]
+	}
+	if n := len(list); n > 0 {
+		el.lastID = max(el.lastID, list[n-1].ID)
+	}
+	log.Printf("Restored %d events from registry", len(list))
+	return nil
+}
+
+// eventFilter selects events by namespace and type.
+type eventFilter struct {
+	namespace string
+	types     map[string]bool
+}
+
+func parseEventFilter(r *http.Request) eventFilter {
+	f := eventFilter{namespace: r.URL.Query().Get("namespace")}
+	for _, t := range r.URL.Query()["type"] {
+		if f.types == nil {
+			f.types = make(map[string]bool)
+		}
+		f.types[t] = true
+	}
+	return f
+}
+
+func (f eventFilter) match(e model.Event) bool {
+	return (f.namespace == "" || e.Namespace == f.namespace) && (f.types == nil || f.types[e.Type])
+}
+
+// parseEventCursor reads where to resume from: an event ID or an RFC 3339
+// time.
+func parseEventCursor(v string) (after int64, since time.Time, err error) {
+	if v == "" {
+		return 0, time.Time{}, nil
+	}
+	if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return id, time.Time{}, nil
+	}
+	since, err = time.Parse(time.RFC3339, v)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("invalid since %q: want an event ID or an RFC 3339 time", v)
+	}
+	return 0, since, nil
+}
+
+// GetEventHistory handles GET /api/v1/events/history.
+// ?since= is an event ID (exclusive) or an RFC 3339 time (inclusive);
+// ?namespace= and ?type= (repeatable) filter; ?limit= caps the page.
+func (h *Handler) GetEventHistory(w http.ResponseWriter, r *http.Request) {
+	after, since, err := parseEventCursor(r.URL.Query().Get("since"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	limit := maxEventPage
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "invalid limit %q", v)
+			return
+		}
+		limit = min(n, maxEventPage)
+	}
+
+	filter := parseEventFilter(r)
+	events := []model.Event{}
+	for _, e := range h.events.Since(after, since) {
+		if !filter.match(e) {
+			continue
+		}
+		if len(events) == limit {
+			break
+		}
+		events = append(events, e)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"events": events,
+		"count":  len(events),
+	})
+}
+
+// StreamEvents handles GET /api/v1/events.
+// It streams events as server-sent events. A reconnecting client sends
+// Last-Event-ID (or ?since=) and first receives the events it missed.
+func (h *Handler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	cursor := r.Header.Get("Last-Event-ID")
+	if cursor == "" {
+		cursor = r.URL.Query().Get("since")
+	}
+	after, since, err := parseEventCursor(cursor)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	filter := parseEventFilter(r)
+
+	// Subscribe before backfilling so nothing falls in between.
+	live, unsubscribe := h.events.Subscribe()
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	last := after
+	send := func(e model.Event) bool {
+		if e.ID <= last {
+			return true
+		}
+		last = e.ID
+		if !filter.match(e) {
+			return true
+		}
+		data, _ := json.Marshal(e)
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	if cursor != "" {
+		for _, e := range h.events.Since(after, since) {
+			if !send(e) {
+				return
+			}
+		}
+	}
+	rc.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-live:
+			if !ok || !send(e) {
+				return
+			}
+		}
+	}
+}
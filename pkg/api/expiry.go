@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ExpiryOptions configures RunExpiry.
+type ExpiryOptions struct {
+	// Interval is how often expiries are checked.
+	Interval time.Duration
+
+	// WarnBefore is how long before expiry the warning webhook is called.
+	WarnBefore time.Duration
+
+	// WarningWebhook, if set, receives a POST when a resource is about to
+	// expire.
+	WarningWebhook string
+}
+
+// expiryWarning is the JSON body posted to the warning webhook.
+type expiryWarning struct {
+	Event     string `json:"event"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+// RunExpiry deletes expired resources every opts.Interval until ctx is done.
+// Expired resources are deleted like any other: a tombstone is pushed and
+// they stay restorable for the delete grace period. Nothing expires while
+// the API is read-only, the namespace is frozen or the resource locked.
+func (h *Handler) RunExpiry(ctx context.Context, opts ExpiryOptions) {
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	warned := make(map[string]time.Time) // "namespace/name" -> expiry warned about
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.expireResources(ctx, opts, warned)
+		}
+	}
+}
+
+func (h *Handler) expireResources(ctx context.Context, opts ExpiryOptions, warned map[string]time.Time) {
+	if h.readOnly() {
+		return
+	}
+	ctx = withSource(ctx, SourceExpiry)
+	now := time.Now()
+	for key := range h.catalog.List() {
+		namespace, name, _ := strings.Cut(key, "/")
+		meta, ok := h.catalog.Meta(namespace, name)
+		if !ok || meta.ExpiresAt.IsZero() {
+			continue
+		}
+		if h.frozen(namespace, now) || h.locked(namespace, name) {
+			continue // expires once the freeze ends or the lock is released
+		}
+
+		if !now.Before(meta.ExpiresAt) {
+			resp, err := h.deleteResource(ctx, namespace, name, "")
+			if err != nil {
+				log.Printf("Warning: failed to expire %s: %v", key, err)
+				continue
+			}
+			delete(warned, key)
+			log.Printf("Expired resource %s (tombstone version=%s)", key, resp.Version)
+			continue
+		}
+
+		if opts.WarningWebhook == "" || opts.WarnBefore <= 0 || now.Before(meta.ExpiresAt.Add(-opts.WarnBefore)) {
+			continue
+		}
+		if warned[key].Equal(meta.ExpiresAt) {
+			continue
+		}
+		warning := expiryWarning{
+			Event:     "resource.expiring",
+			Namespace: namespace,
+			Name:      name,
+			Version:   meta.Version,
+			ExpiresAt: meta.ExpiresAt.UTC().Format(time.RFC3339),
+		}
+		if err := postJSON(ctx, opts.WarningWebhook, warning); err != nil {
+			log.Printf("Warning: failed to send expiry warning for %s: %v", key, err)
+			continue
+		}
+		warned[key] = meta.ExpiresAt
+	}
+}
+
+// webhookClient is used for outgoing webhook calls.
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// postJSON POSTs v to url and fails on non-2xx responses.
+func postJSON(ctx context.Context, url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+)
+
+// fluxNamespace is where generated Flux objects live.
+const fluxNamespace = "flux-system"
+
+// maxObjectName is the longest Kubernetes object name.
+const maxObjectName = 253
+
+// resourceFluxName returns the name of the Flux objects reconciling a
+// resource's bundle. Joining namespace and name with "-" alone is
+// ambiguous (team-a/db and team/a-db), so a hash of the resource's key is
+// appended, and the readable part is truncated to keep the name within the
+// object-name limit.
+func resourceFluxName(namespace, name string) string {
+	suffix := fmt.Sprintf("-%x", sha256.Sum256([]byte(namespace+"/"+name)))[:9]
+	base := "gitops-squared-" + namespace + "-" + name
+	if len(base) > maxObjectName-len(suffix) {
+		base = base[:maxObjectName-len(suffix)]
+	}
+	return base + suffix
+}
+
+// renderFluxObjects renders the OCIRepository and Kustomization pair that
+// reconciles a single resource bundle, as a multi-document YAML stream.
+func renderFluxObjects(namespace, name, url string) ([]byte, error) {
+	return renderFluxPair(resourceFluxName(namespace, name), url, map[string]any{"tag": "latest"})
+}
+
+// renderShardFluxObjects renders the OCIRepository and Kustomization pair
+// that reconciles one catalog shard, pinned to the shard's digest so each
+// root catalog version is a consistent snapshot.
+func renderShardFluxObjects(shard, url, digest string) ([]byte, error) {
+	return renderFluxPair("gitops-squared-shard-"+shard, url, map[string]any{"digest": digest})
+}
+
+// renderPinnedFluxObjects renders the OCIRepository and Kustomization pair
+// that reconciles a single resource bundle pinned to digest, for inclusion
+// in a pinned root catalog.
+func renderPinnedFluxObjects(namespace, name, url, digest string) ([]byte, error) {
+	return renderFluxPair(resourceFluxName(namespace, name), url, map[string]any{"digest": digest})
+}
+
+// renderClusterFluxObjects renders the OCIRepository and Kustomization pair
+// a target cluster applies to reconcile its own catalog.
+func renderClusterFluxObjects(cluster, url string) ([]byte, error) {
+	return renderFluxPair("gitops-squared-cluster-"+cluster, url, map[string]any{"tag": "latest"})
+}
+
+// renderChannelFluxObjects renders the OCIRepository and Kustomization pair
+// a cluster applies to reconcile the catalog channel it follows.
+func renderChannelFluxObjects(channel, url string) ([]byte, error) {
+	return renderFluxPair("gitops-squared-channel-"+channel, url, map[string]any{"tag": channel})
+}
+
+// renderFluxPair renders an OCIRepository following ref and the
+// Kustomization applying its manifests/ directory, both named objName.
+func renderFluxPair(objName, url string, ref map[string]any) ([]byte, error) {
+	ociRepo := map[string]any{
+		"apiVersion": "source.toolkit.fluxcd.io/v1",
+		"kind":       "OCIRepository",
+		"metadata": map[string]any{
+			"name":      objName,
+			"namespace": fluxNamespace,
+		},
+		"spec": map[string]any{
+			"interval": "10s",
+			"url":      url,
+			"insecure": true,
+			"ref":      ref,
+		},
+	}
+
+	kustomization := map[string]any{
+		"apiVersion": "kustomize.toolkit.fluxcd.io/v1",
+		"kind":       "Kustomization",
+		"metadata": map[string]any{
+			"name":      objName,
+			"namespace": fluxNamespace,
+		},
+		"spec": map[string]any{
+			"interval": "30s",
+			"sourceRef": map[string]any{
+				"kind": "OCIRepository",
+				"name": objName,
+			},
+			"path":    "./manifests",
+			"prune":   true,
+			"wait":    true,
+			"timeout": "1m",
+		},
+	}
+
+	var out []byte
+	for i, obj := range []map[string]any{ociRepo, kustomization} {
+		doc, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			out = append(out, "---\n"...)
+		}
+		out = append(out, doc...)
+	}
+	return out, nil
+}
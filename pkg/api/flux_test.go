@@ -0,0 +1,60 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alfredtm/gitops-squared/pkg/oci/ocitest"
+)
+
+// Resources whose namespace and name only differ in where the "-" falls
+// get Flux objects of their own, within the object-name limit.
+func TestResourceFluxName(t *testing.T) {
+	a := resourceFluxName("team-a", "db")
+	b := resourceFluxName("team", "a-db")
+	if a == b {
+		t.Errorf("team-a/db and team/a-db are both named %s", a)
+	}
+	if !strings.HasPrefix(a, "gitops-squared-team-a-db-") {
+		t.Errorf("team-a/db is named %s", a)
+	}
+
+	pairA, err := renderFluxObjects("team-a", "db", "oci://registry.example/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pairB, err := renderFluxObjects("team", "a-db", "oci://registry.example/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(pairA), "name: "+a+"\n") || !strings.Contains(string(pairB), "name: "+b+"\n") {
+		t.Errorf("rendered objects don't use the resources' names:\n%s\n%s", pairA, pairB)
+	}
+
+	long := strings.Repeat("a", maxObjectName)
+	if got := resourceFluxName(long, long); len(got) != maxObjectName {
+		t.Errorf("name of a resource with long names is %d characters, want %d", len(got), maxObjectName)
+	}
+}
+
+// A pinned root catalog holds separate Flux objects for resources whose
+// namespace and name only differ in where the "-" falls, so each one's
+// digest is reconciled.
+func TestPinnedObjectsDistinctNames(t *testing.T) {
+	client, _ := ocitest.NewClient("gitops-squared/resources")
+	cm := NewCatalogManager(client, CatalogOptions{})
+
+	objects, err := cm.pinnedObjects(map[string]string{
+		"team-a/db": "sha256:" + strings.Repeat("a", 64),
+		"team/a-db": "sha256:" + strings.Repeat("b", 64),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{"team-a/db": resourceFluxName("team-a", "db"), "team/a-db": resourceFluxName("team", "a-db")} {
+		out := string(objects[pinnedManifestDir+key+".yaml"])
+		if !strings.Contains(out, "name: "+want+"\n") {
+			t.Errorf("pinned objects of %s aren't named %s:\n%s", key, want, out)
+		}
+	}
+}
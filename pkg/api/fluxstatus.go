@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/alfredtm/gitops-squared/pkg/kube"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// Default names of the catalog's Flux objects, as in deploy/flux.
+const (
+	defaultFluxSource        = "gitops-squared-catalog"
+	defaultFluxKustomization = "gitops-squared-resources"
+)
+
+// FluxStatusOptions configures GET /api/v1/flux/status.
+type FluxStatusOptions struct {
+	// Client reads Flux objects from the cluster.
+	Client *kube.Client
+
+	// Source and Kustomization name the catalog's OCIRepository and
+	// Kustomization in flux-system. They default to the names in deploy/flux.
+	Source        string
+	Kustomization string
+}
+
+// GetFluxStatus handles GET /api/v1/flux/status.
+// It reports whether Flux has pulled the latest published catalog and
+// applied it, including every shard of a partitioned catalog.
+func (h *Handler) GetFluxStatus(w http.ResponseWriter, r *http.Request) {
+	if h.fluxStatus == nil {
+		writeError(w, http.StatusNotFound, "flux status is not enabled")
+		return
+	}
+
+	catalog := h.catalog.Status()
+	if catalog.Digest == "" {
+		writeError(w, http.StatusNotFound, "%v", ErrCatalogNotPublished)
+		return
+	}
+
+	ctx := r.Context()
+	resp := model.FluxStatusResponse{
+		CatalogDigest: catalog.Digest,
+		Source:        h.fluxSourceStatus(ctx, h.fluxStatus.Source, catalog.Digest),
+		Kustomization: h.fluxKustomizationStatus(ctx, h.fluxStatus.Kustomization, catalog.Digest),
+	}
+	resp.Converged = converged(resp.Source, resp.Kustomization)
+
+	shards := make([]string, 0, len(catalog.Shards))
+	for shard := range catalog.Shards {
+		shards = append(shards, shard)
+	}
+	sort.Strings(shards)
+	for _, shard := range shards {
+		digest := catalog.Shards[shard]
+		name := "gitops-squared-shard-" + shard
+		status := model.FluxShardStatus{
+			Shard:         shard,
+			Digest:        digest,
+			Source:        h.fluxSourceStatus(ctx, name, digest),
+			Kustomization: h.fluxKustomizationStatus(ctx, name, digest),
+		}
+		resp.Converged = resp.Converged && converged(status.Source, status.Kustomization)
+		resp.Shards = append(resp.Shards, status)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) fluxSourceStatus(ctx context.Context, name, digest string) model.FluxObjectStatus {
+	status, err := h.fluxStatus.Client.OCIRepositoryStatus(ctx, fluxNamespace, name)
+	return fluxObjectStatus(name, digest, status, err)
+}
+
+func (h *Handler) fluxKustomizationStatus(ctx context.Context, name, digest string) model.FluxObjectStatus {
+	status, err := h.fluxStatus.Client.KustomizationStatus(ctx, fluxNamespace, name)
+	return fluxObjectStatus(name, digest, status, err)
+}
+
+func fluxObjectStatus(name, digest string, status kube.FluxStatus, err error) model.FluxObjectStatus {
+	out := model.FluxObjectStatus{Name: name}
+	if err != nil {
+		if !errors.Is(err, kube.ErrNotFound) {
+			out.Error = err.Error()
+		}
+		return out
+	}
+	out.Found = true
+	out.Ready = status.Ready && !status.Stale
+	out.Revision = status.Revision
+	out.Current = status.Revision != "" && kube.RevisionDigest(status.Revision) == digest
+	out.Reason = status.Reason
+	out.Message = status.Message
+	return out
+}
+
+// converged reports whether a source pulled, and its Kustomization applied,
+// the latest digest.
+func converged(source, kustomization model.FluxObjectStatus) bool {
+	return source.Current && kustomization.Current && kustomization.Ready
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// breakGlassHeader carries the reason for a change made during a freeze.
+const breakGlassHeader = "X-Break-Glass"
+
+// FreezeStore holds change-freeze windows in memory and persists them to
+// the registry as a single JSON document on every change.
+type FreezeStore struct {
+	ociClient oci.Interface
+	mu        sync.RWMutex
+	windows   map[string]model.FreezeWindow
+}
+
+// NewFreezeStore creates an empty freeze store.
+func NewFreezeStore(client oci.Interface) *FreezeStore {
+	return &FreezeStore{
+		ociClient: client,
+		windows:   make(map[string]model.FreezeWindow),
+	}
+}
+
+// Get returns a window by name.
+func (fs *FreezeStore) Get(name string) (model.FreezeWindow, bool) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	f, ok := fs.windows[name]
+	return f, ok
+}
+
+// List returns all windows sorted by name.
+func (fs *FreezeStore) List() []model.FreezeWindow {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	list := make([]model.FreezeWindow, 0, len(fs.windows))
+	for _, f := range fs.windows {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Active returns an active window covering namespace, whose environment
+// is env, at now. An empty namespace matches any active window.
+func (fs *FreezeStore) Active(namespace, env string, now time.Time) (model.FreezeStatus, bool) {
+	for _, f := range fs.List() {
+		if !f.Covers(namespace, env) {
+			continue
+		}
+		if status := f.Status(now); status.Active {
+			return status, true
+		}
+	}
+	return model.FreezeStatus{}, false
+}
+
+// Put creates or replaces a window and persists the set.
+func (fs *FreezeStore) Put(ctx context.Context, f model.FreezeWindow) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	prev, existed := fs.windows[f.Name]
+	fs.windows[f.Name] = f
+	if err := fs.persistLocked(ctx); err != nil {
+		if existed {
+			fs.windows[f.Name] = prev
+		} else {
+			delete(fs.windows, f.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a window and persists the set. It reports whether the
+// window existed.
+func (fs *FreezeStore) Delete(ctx context.Context, name string) (bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	prev, ok := fs.windows[name]
+	if !ok {
+		return false, nil
+	}
+	delete(fs.windows, name)
+	if err := fs.persistLocked(ctx); err != nil {
+		fs.windows[name] = prev
+		return true, err
+	}
+	return true, nil
+}
+
+func (fs *FreezeStore) persistLocked(ctx context.Context) error {
+	list := make([]model.FreezeWindow, 0, len(fs.windows))
+	for _, f := range fs.windows {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("encoding freeze windows: %w", err)
+	}
+	if err := fs.ociClient.PushFreezes(ctx, data); err != nil {
+		return fmt.Errorf("pushing freeze windows: %w", err)
+	}
+	return nil
+}
+
+// Restore loads freeze windows from the registry.
+func (fs *FreezeStore) Restore(ctx context.Context) error {
+	data, err := fs.ociClient.PullFreezes(ctx)
+	if err != nil {
+		return fmt.Errorf("pulling freeze windows: %w", err)
+	}
+	if data == nil {
+		return nil
+	}
+
+	var list []model.FreezeWindow
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("parsing freeze windows: %w", err)
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for _, f := range list {
+		fs.windows[f.Name] = f
+	}
+	log.Printf("Restored %d freeze windows from registry", len(list))
+	return nil
+}
+
+// checkFreeze reports whether a mutating request may change namespace (""
+// for a change to every namespace). During a freeze it writes a 423 and
+// returns false, unless the request carries an X-Break-Glass reason and the
+// caller may break glass, in which case it is let through and logged for
+// audit. Anyone else sending the header gets a 403.
+func (h *Handler) checkFreeze(w http.ResponseWriter, r *http.Request, namespace string) bool {
+	status, frozen := h.freezes.Active(namespace, h.lint.Environment(namespace), time.Now())
+	if !frozen {
+		return true
+	}
+	if reason := r.Header.Get(breakGlassHeader); reason != "" {
+		if !h.mayBreakGlass(r.Context()) {
+			log.Printf("Warning: %s denied break-glass %s %s during freeze %q: not in a break-glass or admin group",
+				auth.Actor(r.Context()), r.Method, r.URL.Path, status.Name)
+			writeErrorCode(w, http.StatusForbidden, model.CodeForbidden, "%s may not break a freeze: not in a break-glass or admin group", auth.Actor(r.Context()))
+			return false
+		}
+		log.Printf("Audit: break-glass %s %s by %s (namespace %q) during freeze %q: %s",
+			r.Method, r.URL.Path, auth.Actor(r.Context()), namespace, status.Name, reason)
+		return true
+	}
+
+	scope := fmt.Sprintf("namespace %q is", namespace)
+	if namespace == "" {
+		scope = "changes are"
+	}
+	writeJSON(w, http.StatusLocked, map[string]any{
+		"error":  fmt.Sprintf("%s frozen by %q until %s", scope, status.Name, status.ActiveUntil),
+		"code":   model.CodeChangeFrozen,
+		"freeze": status,
+	})
+	return false
+}
+
+// mayBreakGlass reports whether the caller may override a freeze: members
+// of a break-glass group, if any are configured, and admins.
+func (h *Handler) mayBreakGlass(ctx context.Context) bool {
+	if len(h.breakGlass) == 0 {
+		return h.inAdminGroup(ctx)
+	}
+	id, ok := auth.FromContext(ctx)
+	return ok && slices.ContainsFunc(id.Groups, func(group string) bool {
+		return slices.Contains(h.breakGlass, group) || slices.Contains(h.adminGroups, group)
+	})
+}
+
+// frozen reports whether background jobs must leave namespace alone.
+func (h *Handler) frozen(namespace string, now time.Time) bool {
+	_, ok := h.freezes.Active(namespace, h.lint.Environment(namespace), now)
+	return ok
+}
+
+// CreateFreeze handles POST /api/v1/admin/freezes.
+// It creates or replaces a freeze window.
+func (h *Handler) CreateFreeze(w http.ResponseWriter, r *http.Request) {
+	var f model.FreezeWindow
+	if err := decodeJSON(r, &f); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	if err := f.Validate(h.lint); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := h.freezes.Put(r.Context(), f); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, f.Status(time.Now()))
+	log.Printf("Audit: saved freeze window %s by %s", f.Name, auth.Actor(r.Context()))
+}
+
+// DeleteFreeze handles DELETE /api/v1/admin/freezes/{name}.
+func (h *Handler) DeleteFreeze(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	existed, err := h.freezes.Delete(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	if !existed {
+		writeError(w, http.StatusNotFound, "freeze window %q not found", name)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("Audit: deleted freeze window %s by %s", name, auth.Actor(r.Context()))
+}
+
+// ListFreezes handles GET /api/v1/freezes.
+// With ?namespace= only the windows covering that namespace are listed.
+func (h *Handler) ListFreezes(w http.ResponseWriter, r *http.Request) {
+	namespace := r.URL.Query().Get("namespace")
+	env := h.lint.Environment(namespace)
+	now := time.Now()
+	freezes := []model.FreezeStatus{}
+	for _, f := range h.freezes.List() {
+		if namespace == "" || f.Covers(namespace, env) {
+			freezes = append(freezes, f.Status(now))
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"freezes": freezes,
+		"count":   len(freezes),
+	})
+}
+
+// GetFreeze handles GET /api/v1/freezes/{name}.
+func (h *Handler) GetFreeze(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	f, ok := h.freezes.Get(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "freeze window %q not found", name)
+		return
+	}
+	writeJSON(w, http.StatusOK, f.Status(time.Now()))
+}
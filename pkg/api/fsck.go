@@ -0,0 +1,262 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// Fsck cross-checks the resource index, the resource repositories and the
+// published catalog. With fix it repairs what it can: missing latest tags
+// are pointed at the newest version, the index is rewritten to match the
+// repositories, and catalog drift is repaired by reloading resources from
+// the registry and republishing. Repositories holding no versions are only
+// reported.
+func (cm *CatalogManager) Fsck(ctx context.Context, fix bool) (model.FsckResponse, error) {
+	resp := model.FsckResponse{Fix: fix, Issues: []model.FsckIssue{}}
+	checkIndex := cm.ociClient.Discovery() != oci.RepoDiscoveryCatalog
+
+	indexed := make(map[string]oci.IndexEntry)
+	indexComplete := false
+	if checkIndex {
+		entries, complete, err := cm.ociClient.Index(ctx)
+		if err != nil {
+			return resp, fmt.Errorf("reading resource index: %w", err)
+		}
+		for _, e := range entries {
+			indexed[e.Namespace+"/"+e.Name] = e
+		}
+		indexComplete = complete
+	}
+
+	repos, err := cm.ociClient.ListRegistryRepos(ctx)
+	listed := err == nil
+	if !listed {
+		if !checkIndex {
+			return resp, err
+		}
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("%v; checking indexed repositories only", err))
+		repos = nil
+		for key, e := range indexed {
+			repos = append(repos, oci.ResourceInfo{Repository: key, Namespace: e.Namespace, Name: e.Name})
+		}
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Repository < repos[j].Repository })
+	resp.Repositories = len(repos)
+
+	var (
+		indexSet      []oci.IndexEntry
+		indexRemove   []string
+		indexIssues   []int // positions in resp.Issues fixed by the index repair
+		catalogIssues []int
+	)
+	issue := func(kind, key, format string, args ...any) int {
+		resp.Issues = append(resp.Issues, model.FsckIssue{Kind: kind, Resource: key, Detail: fmt.Sprintf(format, args...)})
+		return len(resp.Issues) - 1
+	}
+
+	live := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, repo := range repos {
+		key := repo.Namespace + "/" + repo.Name
+		seen[key] = true
+
+		head, ok, err := cm.ociClient.HeadResource(ctx, repo.Namespace, repo.Name)
+		if err != nil {
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("resolving %s: %v", key, err))
+			continue
+		}
+		if !ok {
+			head, ok = cm.fsckLatest(ctx, &resp, repo, fix, issue)
+			if !ok {
+				if _, inIndex := indexed[key]; inIndex {
+					indexIssues = append(indexIssues, issue(model.FsckIndexOrphan, key, "repository holds no versions"))
+					indexRemove = append(indexRemove, key)
+				}
+				continue
+			}
+		}
+
+		if !head.Deleted {
+			live[key] = true
+		}
+		if !checkIndex {
+			continue
+		}
+		entry := oci.IndexEntry{Namespace: repo.Namespace, Name: repo.Name, Digest: head.Digest, Version: head.Version, Deleted: head.Deleted}
+		current, inIndex := indexed[key]
+		switch {
+		case !inIndex:
+			indexIssues = append(indexIssues, issue(model.FsckUnindexed, key, "latest is %s", head.Digest))
+			indexSet = append(indexSet, entry)
+		case current.Digest != head.Digest || current.Deleted != head.Deleted:
+			indexIssues = append(indexIssues, issue(model.FsckIndexStale, key, "index has %s (deleted=%t), latest is %s (deleted=%t)",
+				current.Digest, current.Deleted, head.Digest, head.Deleted))
+			indexSet = append(indexSet, entry)
+		}
+	}
+	if listed {
+		for key := range indexed {
+			if !seen[key] {
+				indexIssues = append(indexIssues, issue(model.FsckIndexOrphan, key, "no such repository in the registry"))
+				indexRemove = append(indexRemove, key)
+			}
+		}
+	}
+
+	published, err := cm.publishedResources(ctx)
+	if err != nil {
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("reading published catalog: %v", err))
+	} else {
+		var extra []string
+		for key := range live {
+			if _, ok := published[key]; !ok {
+				catalogIssues = append(catalogIssues, issue(model.FsckCatalogMissing, key, ""))
+			}
+		}
+		for key := range published {
+			if !live[key] {
+				catalogIssues = append(catalogIssues, issue(model.FsckCatalogExtra, key, ""))
+				extra = append(extra, key)
+			}
+		}
+
+		if fix && len(catalogIssues) > 0 {
+			// Resources the registry no longer holds as live are dropped
+			// from memory first; Restore brings back soft-deleted ones.
+			for _, key := range extra {
+				namespace, name, _ := strings.Cut(key, "/")
+				cm.Delete(namespace, name)
+			}
+			if err := cm.Restore(ctx); err != nil {
+				resp.Warnings = append(resp.Warnings, fmt.Sprintf("republishing catalog: %v", err))
+			} else {
+				markFixed(&resp, catalogIssues)
+			}
+		}
+	}
+
+	if fix && checkIndex && (len(indexSet) > 0 || len(indexRemove) > 0 || (listed && !indexComplete)) {
+		if err := cm.ociClient.RepairIndex(ctx, indexSet, indexRemove, listed); err != nil {
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("repairing resource index: %v", err))
+		} else {
+			markFixed(&resp, indexIssues)
+		}
+	}
+
+	sort.SliceStable(resp.Issues, func(i, j int) bool { return resp.Issues[i].Resource < resp.Issues[j].Resource })
+	return resp, nil
+}
+
+// fsckLatest handles a repository without a latest tag. If it holds
+// versions, the newest is reported and, with fix, tagged latest.
+func (cm *CatalogManager) fsckLatest(ctx context.Context, resp *model.FsckResponse, repo oci.ResourceInfo, fix bool, issue func(kind, key, format string, args ...any) int) (oci.ResourceHead, bool) {
+	key := repo.Namespace + "/" + repo.Name
+	tags, err := cm.ociClient.ResourceTags(ctx, repo.Namespace, repo.Name)
+	if err != nil {
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("listing tags of %s: %v", key, err))
+		return oci.ResourceHead{}, false
+	}
+	var versions []string
+	for _, tag := range tags {
+		if tag != "latest" {
+			versions = append(versions, tag)
+		}
+	}
+	if len(versions) == 0 {
+		issue(model.FsckOrphanedRepo, key, "repository has no tags")
+		return oci.ResourceHead{}, false
+	}
+
+	// Version tags sort lexically in creation order.
+	newest := versions[len(versions)-1]
+	i := issue(model.FsckMissingLatest, key, "newest version is %s", newest)
+	if !fix {
+		return oci.ResourceHead{}, false
+	}
+	if err := cm.ociClient.TagResourceLatest(ctx, repo.Namespace, repo.Name, newest); err != nil {
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("tagging %s latest: %v", key, err))
+		return oci.ResourceHead{}, false
+	}
+	resp.Issues[i].Fixed = true
+	head, ok, err := cm.ociClient.HeadResource(ctx, repo.Namespace, repo.Name)
+	if err != nil {
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("resolving %s: %v", key, err))
+		return oci.ResourceHead{}, false
+	}
+	return head, ok
+}
+
+// publishedResources returns the resources of the latest published
+// catalog, including those in its shards or pinned bundles.
+func (cm *CatalogManager) publishedResources(ctx context.Context) (map[string][]byte, error) {
+	_, tarGz, err := cm.ociClient.PullCatalog(ctx, "latest")
+	if err != nil {
+		return nil, err
+	}
+	defer tarGz.Close()
+
+	resources, err := readCatalogTarGz(tarGz.Reader())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cm.expandCatalog(ctx, tarGz, resources); err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+func markFixed(resp *model.FsckResponse, issues []int) {
+	for _, i := range issues {
+		resp.Issues[i].Fixed = true
+	}
+}
+
+// RunFsck handles POST /api/v1/admin/fsck.
+// It reports inconsistencies between the resource index, the resource
+// repositories and the published catalog; with ?fix=true it repairs them.
+// With ?async=true it runs as a background job.
+func (h *Handler) RunFsck(w http.ResponseWriter, r *http.Request) {
+	fix := r.URL.Query().Get("fix") == "true"
+	if fix && !h.checkFreeze(w, r, "") {
+		return
+	}
+
+	if async(r) {
+		h.startJob(w, r, "fsck", func(ctx context.Context) (any, error) {
+			return h.fsck(ctx, fix)
+		})
+		return
+	}
+	resp, err := h.fsck(r.Context(), fix)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "fsck: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// fsck runs Fsck and logs the outcome for the audit trail.
+func (h *Handler) fsck(ctx context.Context, fix bool) (model.FsckResponse, error) {
+	resp, err := h.catalog.Fsck(ctx, fix)
+	if err != nil {
+		return model.FsckResponse{}, err
+	}
+
+	fixed := 0
+	for _, i := range resp.Issues {
+		if i.Fixed {
+			fixed++
+		}
+	}
+	log.Printf("Audit: fsck by %s: %d repositories, %d issues, %d fixed (fix=%t)",
+		auth.Actor(ctx), resp.Repositories, len(resp.Issues), fixed, fix)
+	return resp, nil
+}
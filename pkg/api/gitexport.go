@@ -0,0 +1,247 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/gitsource"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"sigs.k8s.io/yaml"
+)
+
+// gitExportRetryInterval is how long a failed export waits before it is
+// retried, unless a newer catalog change comes first.
+const gitExportRetryInterval = time.Minute
+
+// GitExportOptions configures a GitExporter.
+type GitExportOptions struct {
+	// Mirror is the repository and branch resources are committed to.
+	Mirror *gitsource.Mirror
+
+	// EmailDomain completes the author email of callers whose user name
+	// is not an email address. Defaults to "users.noreply.gitops-squared".
+	EmailDomain string
+}
+
+// GitExporter mirrors the published catalog into a Git repository, one
+// file per resource at <namespace>/<name>.yaml under the mirror's
+// directory. Every catalog push queues a snapshot; Run commits them in the
+// background, coalescing snapshots that arrive while a commit is running,
+// with the callers that caused them as authors.
+type GitExporter struct {
+	mirror      *gitsource.Mirror
+	emailDomain string
+
+	mu      sync.Mutex
+	pending map[string][]byte // latest snapshot not yet committed; nil if none
+	actors  []string          // callers whose changes are in pending
+	wake    chan struct{}
+}
+
+// NewGitExporter creates an exporter. Call Run to start committing.
+func NewGitExporter(opts GitExportOptions) *GitExporter {
+	if opts.EmailDomain == "" {
+		opts.EmailDomain = "users.noreply.gitops-squared"
+	}
+	return &GitExporter{
+		mirror:      opts.Mirror,
+		emailDomain: opts.EmailDomain,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Mirror returns the repository resources are committed to.
+func (e *GitExporter) Mirror() *gitsource.Mirror {
+	return e.mirror
+}
+
+// Enqueue queues a snapshot of the catalog's resources for export. The
+// caller in ctx is recorded as an author.
+func (e *GitExporter) Enqueue(ctx context.Context, resources map[string][]byte) {
+	e.mu.Lock()
+	e.pending = resources
+	if actor := auth.Actor(ctx); actor != "anonymous" && !slices.Contains(e.actors, actor) {
+		e.actors = append(e.actors, actor)
+	}
+	e.mu.Unlock()
+
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run commits queued snapshots until ctx is cancelled. A failed commit is
+// retried after gitExportRetryInterval or with the next snapshot.
+func (e *GitExporter) Run(ctx context.Context) {
+	retry := time.NewTimer(0)
+	if !retry.Stop() {
+		<-retry.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.wake:
+		case <-retry.C:
+		}
+
+		e.mu.Lock()
+		resources, actors := e.pending, e.actors
+		e.pending, e.actors = nil, nil
+		e.mu.Unlock()
+		if resources == nil {
+			continue
+		}
+
+		if err := e.export(ctx, resources, actors); err != nil {
+			log.Printf("Warning: failed to export catalog to %s, retrying in %s: %v", e.mirror.URL(), gitExportRetryInterval, err)
+			e.mu.Lock()
+			if e.pending == nil {
+				e.pending = resources
+			}
+			for _, actor := range actors {
+				if !slices.Contains(e.actors, actor) {
+					e.actors = append(e.actors, actor)
+				}
+			}
+			e.mu.Unlock()
+			retry.Reset(gitExportRetryInterval)
+		}
+	}
+}
+
+// export commits one snapshot, replacing every file under the mirror's
+// directory.
+func (e *GitExporter) export(ctx context.Context, resources map[string][]byte, actors []string) error {
+	files := make(map[string][]byte, len(resources))
+	for key, manifest := range resources {
+		files[resourceFilePath(key)] = manifest
+	}
+
+	var author gitsource.Signature
+	var coAuthors []gitsource.Signature
+	for i, actor := range actors {
+		sig := e.signature(actor)
+		if i == 0 {
+			author = sig
+		} else {
+			coAuthors = append(coAuthors, sig)
+		}
+	}
+
+	commit, changes, err := e.mirror.Commit(ctx, gitsource.Commit{
+		Files:   files,
+		Replace: true,
+		Author:  author,
+		Message: func(changes []gitsource.Change) string {
+			return exportMessage(changes, resources, coAuthors)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if commit != "" {
+		log.Printf("Exported %d resource changes to %s@%s (%.12s)", len(changes), e.mirror.URL(), e.mirror.Branch(), commit)
+	}
+	return nil
+}
+
+// signature turns a caller's user name into a commit signature.
+func (e *GitExporter) signature(actor string) gitsource.Signature {
+	if strings.Contains(actor, "@") {
+		name, _, _ := strings.Cut(actor, "@")
+		return gitsource.Signature{Name: name, Email: actor}
+	}
+	return gitsource.Signature{Name: actor, Email: actor + "@" + e.emailDomain}
+}
+
+// resourceFilePath is the mirror path of a "namespace/name" resource.
+func resourceFilePath(key string) string {
+	return key + ".yaml"
+}
+
+// resourceKeyOfPath is the inverse of resourceFilePath.
+func resourceKeyOfPath(path string) string {
+	return strings.TrimSuffix(path, ".yaml")
+}
+
+// exportMessage writes a conventional commit message for changes: "feat"
+// for added resources and "chore" otherwise, scoped to the namespace when
+// all changes are in one.
+//
+//	feat(team-a): add orders-db
+//
+//	Version: v3
+func exportMessage(changes []gitsource.Change, resources map[string][]byte, coAuthors []gitsource.Signature) string {
+	verbs := map[gitsource.ChangeOp]string{
+		gitsource.ChangeAdd:    "add",
+		gitsource.ChangeModify: "update",
+		gitsource.ChangeDelete: "remove",
+	}
+
+	scope := ""
+	for i, c := range changes {
+		namespace, _, _ := strings.Cut(resourceKeyOfPath(c.Path), "/")
+		if i == 0 {
+			scope = namespace
+		} else if namespace != scope {
+			scope = ""
+			break
+		}
+	}
+	kind := "chore"
+	if !slices.ContainsFunc(changes, func(c gitsource.Change) bool { return c.Op != gitsource.ChangeAdd }) {
+		kind = "feat"
+	}
+	if scope != "" {
+		kind += "(" + scope + ")"
+	}
+
+	var b strings.Builder
+	if len(changes) == 1 {
+		c := changes[0]
+		_, name, _ := strings.Cut(resourceKeyOfPath(c.Path), "/")
+		fmt.Fprintf(&b, "%s: %s %s\n", kind, verbs[c.Op], name)
+		if version := manifestVersion(resources[resourceKeyOfPath(c.Path)]); version != "" {
+			fmt.Fprintf(&b, "\nVersion: %s\n", version)
+		}
+	} else {
+		fmt.Fprintf(&b, "%s: sync %d resources\n\n", kind, len(changes))
+		for _, c := range changes {
+			key := resourceKeyOfPath(c.Path)
+			fmt.Fprintf(&b, "- %s %s", verbs[c.Op], key)
+			if version := manifestVersion(resources[key]); version != "" {
+				fmt.Fprintf(&b, " (%s)", version)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(coAuthors) > 0 {
+		b.WriteString("\n")
+		for _, sig := range coAuthors {
+			fmt.Fprintf(&b, "Co-authored-by: %s <%s>\n", sig.Name, sig.Email)
+		}
+	}
+	return b.String()
+}
+
+// manifestVersion returns the version annotation of a stored manifest, or
+// "" if it has none.
+func manifestVersion(manifest []byte) string {
+	if manifest == nil {
+		return ""
+	}
+	var pr model.PlatformResource
+	if err := yaml.Unmarshal(manifest, &pr); err != nil {
+		return ""
+	}
+	return pr.Metadata.Annotations[model.AnnotationVersion]
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/gitsource"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// ImportGit handles POST /api/v1/admin/import/git.
+// It clones a Git repository and creates every resource found under the
+// requested path, either as platformresource.yaml style files or as
+// PlatformResource manifests. Resources that already exist are skipped
+// unless overwrite is set. The catalog is republished once at the end.
+// With ?async=true it runs as a background job.
+func (h *Handler) ImportGit(w http.ResponseWriter, r *http.Request) {
+	var req model.GitImportRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	if req.URL == "" {
+		writeError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+	if req.Namespace == "" {
+		req.Namespace = "default"
+	}
+	token := req.Auth.Token
+	if req.Auth.TokenEnv != "" {
+		token = os.Getenv(req.Auth.TokenEnv)
+		if token == "" {
+			writeError(w, http.StatusBadRequest, "environment variable %q is not set", req.Auth.TokenEnv)
+			return
+		}
+	}
+
+	if async(r) {
+		h.startJob(w, r, "import", func(ctx context.Context) (any, error) {
+			return h.importGit(ctx, req, token)
+		})
+		return
+	}
+	resp, err := h.importGit(r.Context(), req, token)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// importGit clones and imports a repository. It fails only if the
+// repository can't be cloned or scanned; resources that can't be imported
+// are listed in the response. It stops early if ctx is cancelled.
+func (h *Handler) importGit(ctx context.Context, req model.GitImportRequest, token string) (model.GitImportResponse, error) {
+	ctx = withSource(ctx, SourceImport)
+	checkout, err := gitsource.Clone(ctx, gitsource.CloneOptions{
+		URL:      req.URL,
+		Ref:      req.Ref,
+		Username: req.Auth.Username,
+		Token:    token,
+	})
+	if err != nil {
+		return model.GitImportResponse{}, err
+	}
+	defer checkout.Close()
+	noteProgress(ctx, "cloned %s at %.12s", req.URL, checkout.Commit)
+
+	scan, err := checkout.Scan(req.Path, req.Namespace)
+	if err != nil {
+		return model.GitImportResponse{}, err
+	}
+
+	resp := model.GitImportResponse{
+		Repository: req.URL,
+		Commit:     checkout.Commit,
+		DryRun:     req.DryRun,
+		Imported:   []string{},
+		Failed:     scan.Failed,
+		Ignored:    scan.Ignored,
+	}
+	fail := func(path string, err error) {
+		if resp.Failed == nil {
+			resp.Failed = make(map[string]string)
+		}
+		resp.Failed[path] = err.Error()
+	}
+	skip := func(key, reason string) {
+		if resp.Skipped == nil {
+			resp.Skipped = make(map[string]string)
+		}
+		resp.Skipped[key] = reason
+	}
+
+	seen := make(map[string]string)
+	pushed := 0
+	for _, found := range scan.Resources {
+		if ctx.Err() != nil {
+			break
+		}
+		file := &found.ResourceFile
+		key := file.Namespace + "/" + file.Name
+		if err := model.ValidateNamespace(file.Namespace); err != nil {
+			fail(found.Path, err)
+			continue
+		}
+		if err := h.checkResourceFile(file); err != nil {
+			fail(found.Path, err)
+			continue
+		}
+		if first, ok := seen[key]; ok {
+			skip(key, "also defined in "+first)
+			continue
+		}
+		seen[key] = found.Path
+		if _, exists := h.catalog.Get(file.Namespace, file.Name); exists && !req.Overwrite {
+			skip(key, "already exists")
+			continue
+		}
+		if req.DryRun {
+			resp.Imported = append(resp.Imported, key)
+			continue
+		}
+
+		result, err := h.pushResource(ctx, file.Namespace, &file.ResourceRequest, applyOptions{skipUnchanged: true})
+		if err != nil {
+			fail(found.Path, err)
+			continue
+		}
+		if result.Changed != nil && !*result.Changed {
+			skip(key, "unchanged")
+			continue
+		}
+		resp.Imported = append(resp.Imported, key)
+		pushed++
+	}
+
+	// Publish what was imported even if the import was cancelled.
+	if pushed > 0 {
+		if err := h.catalog.PushCatalog(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("Warning: failed to push catalog: %v", err)
+		}
+	}
+
+	log.Printf("Audit: git import of %s@%.12s by %s: %d imported, %d skipped, %d failed (dryRun=%t)",
+		req.URL, checkout.Commit, auth.Actor(ctx), len(resp.Imported), len(resp.Skipped), len(resp.Failed), req.DryRun)
+	return resp, nil
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/gitsource"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// maxWebhookBody caps the size of a webhook payload.
+const maxWebhookBody = 10 << 20
+
+// GitWebhook handles POST /api/v1/webhooks/git.
+// It accepts GitHub and GitLab push events from configured sources. Every
+// platformresource.yaml the push adds or modifies on the source's branch is
+// fetched and applied; removed files delete their resource. The catalog is
+// republished once per push.
+func (h *Handler) GitWebhook(w http.ResponseWriter, r *http.Request) {
+	if h.gitSources == nil {
+		writeError(w, http.StatusNotFound, "git webhooks are not configured")
+		return
+	}
+
+	provider, event, ok := gitsource.Provider(r.Header)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "not a GitHub or GitLab webhook")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "reading body: %v", err)
+		return
+	}
+
+	repository, err := gitsource.Repository(provider, body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	src, ok := h.gitSources.Match(provider, repository)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no %s source configured for %q", provider, repository)
+		return
+	}
+	if err := src.Verify(r.Header, body); err != nil {
+		writeError(w, http.StatusUnauthorized, "%v", err)
+		return
+	}
+
+	resp := model.GitSyncResponse{Repository: repository, Applied: []string{}, Deleted: []string{}}
+	if !gitsource.IsPush(provider, event) {
+		resp.Ignored = fmt.Sprintf("event %q is not a push", event)
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	push, err := gitsource.ParsePush(provider, body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	resp.Commit = push.After
+	switch {
+	case push.Branch() != src.Branch:
+		resp.Ignored = fmt.Sprintf("ref %q is not branch %q", push.Ref, src.Branch)
+	case push.DeletesBranch():
+		resp.Ignored = "push deletes the branch"
+	default:
+		h.syncPush(r.Context(), src, push, &resp)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+	log.Printf("Git push %s@%.12s: %d applied, %d deleted, %d failed",
+		repository, push.After, len(resp.Applied), len(resp.Deleted), len(resp.Failed))
+}
+
+// syncPush applies the resource files changed by a push and deletes the
+// resources of removed ones.
+func (h *Handler) syncPush(ctx context.Context, src *gitsource.Source, push *gitsource.PushEvent, resp *model.GitSyncResponse) {
+	ctx = withSource(ctx, SourceGitWebhook)
+	fail := func(path string, err error) {
+		if resp.Failed == nil {
+			resp.Failed = make(map[string]string)
+		}
+		resp.Failed[path] = err.Error()
+		log.Printf("Warning: git push %s: %s: %v", push.Repository, path, err)
+	}
+
+	for _, path := range push.Changed {
+		file, err := src.FetchResource(ctx, path, push.After)
+		if err != nil {
+			fail(path, err)
+			continue
+		}
+		if err := h.checkGitResource(src, file); err != nil {
+			fail(path, err)
+			continue
+		}
+		if _, err := h.pushResource(ctx, file.Namespace, &file.ResourceRequest, applyOptions{}); err != nil {
+			fail(path, err)
+			continue
+		}
+		resp.Applied = append(resp.Applied, file.Namespace+"/"+file.Name)
+	}
+
+	for _, path := range push.Removed {
+		// The file is gone at After; its last content says what to delete.
+		file, err := src.FetchResource(ctx, path, push.Before)
+		if err != nil {
+			fail(path, err)
+			continue
+		}
+		if !src.Allows(file.Namespace) {
+			fail(path, fmt.Errorf("namespace %q is not allowed for this source", file.Namespace))
+			continue
+		}
+		if h.frozen(file.Namespace, time.Now()) {
+			fail(path, fmt.Errorf("namespace %q is frozen", file.Namespace))
+			continue
+		}
+		_, err = h.tombstoneResource(ctx, file.Namespace, file.Name, "")
+		if errors.Is(err, errResourceNotFound) {
+			continue
+		}
+		if err != nil {
+			fail(path, err)
+			continue
+		}
+		resp.Deleted = append(resp.Deleted, file.Namespace+"/"+file.Name)
+	}
+
+	if len(resp.Applied) > 0 || len(resp.Deleted) > 0 {
+		if err := h.catalog.PushCatalog(ctx); err != nil {
+			log.Printf("Warning: failed to push catalog: %v", err)
+		}
+	}
+}
+
+// checkGitResource validates a resource file from a source.
+func (h *Handler) checkGitResource(src *gitsource.Source, file *gitsource.ResourceFile) error {
+	if err := model.ValidateNamespace(file.Namespace); err != nil {
+		return err
+	}
+	if !src.Allows(file.Namespace) {
+		return fmt.Errorf("namespace %q is not allowed for this source", file.Namespace)
+	}
+	return h.checkResourceFile(file)
+}
+
+// checkResourceFile validates a resource file with a valid namespace the
+// way CreateResource validates a request body.
+func (h *Handler) checkResourceFile(file *gitsource.ResourceFile) error {
+	if h.frozen(file.Namespace, time.Now()) {
+		return fmt.Errorf("namespace %q is frozen", file.Namespace)
+	}
+	if !h.namespaceExists(file.Namespace) {
+		return fmt.Errorf("namespace %q does not exist", file.Namespace)
+	}
+	h.defaults.Apply(file.Namespace, &file.ResourceRequest)
+	if err := file.ConvertToCurrent(); err != nil {
+		return err
+	}
+	if err := file.Validate(); err != nil {
+		return err
+	}
+	return h.checkSecrets(file.Namespace, &file.ResourceRequest)
+}
@@ -0,0 +1,1751 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/admission"
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/gitsource"
+	"github.com/alfredtm/gitops-squared/pkg/images"
+	"github.com/alfredtm/gitops-squared/pkg/kube"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+	"github.com/alfredtm/gitops-squared/pkg/patch"
+	"github.com/alfredtm/gitops-squared/pkg/secrets"
+	"oras.land/oras-go/v2/errdef"
+	"sigs.k8s.io/yaml"
+)
+
+const defaultNamespace = "default"
+
+// Handler holds HTTP handlers for the resource API.
+type Handler struct {
+	ociClient    oci.Interface
+	catalog      *CatalogManager
+	dryRunner    *kube.Client
+	encryptor    *secrets.SOPSEncryptor
+	companions   model.CompanionsConfig
+	secretStores *secrets.StoresConfig
+	templates    *TemplateStore
+	schedules    *ScheduleStore
+	freezes      *FreezeStore
+	regions      *RegionStore
+	locks        *LockStore
+	namespaces   *NamespaceStore
+	clusters     *ClusterStore
+	timeouts     RequestTimeouts
+	gitSources   *gitsource.Config
+	admission    *admission.Config
+	images       *images.Config
+	lint         *model.LintPolicy
+	cooldowns    *model.CooldownConfig
+	changes      cooldownTracker
+	defaults     *model.DefaultsConfig
+	fluxStatus   *FluxStatusOptions
+
+	proposals    *ProposalStore
+	apiKeys      *APIKeyStore
+	cors         *CORSOptions
+	sessions     *auth.Sessions
+	pullRequests *PullRequestOptions
+	jobs         *JobManager
+	events       *EventLog
+	unmanaged    *unmanagedArtifacts
+	canary       *canaryRollout // nil unless canary promotion is configured
+
+	maintenance   maintenanceMode
+	restore       restoreState
+	adminGroups   []string
+	breakGlass    []string // groups that may override freezes besides admins
+	logs          *LogFilter
+	accessLogOpts *AccessLogOptions
+	debug         bool
+	ui            bool
+	keys          map[string]ManagedKey // by purpose
+	middleware    []Middleware
+	metrics       metrics
+}
+
+// HandlerOptions configures a Handler.
+type HandlerOptions struct {
+	// DryRunner, if set, server-side dry-runs generated manifests against
+	// a cluster before they are pushed.
+	DryRunner *kube.Client
+
+	// Encryptor, if set, allows plaintext secret data by SOPS-encrypting it
+	// before it is pushed.
+	Encryptor *secrets.SOPSEncryptor
+
+	// SecretStores, if set, resolves placeholders in secret data.
+	SecretStores *secrets.StoresConfig
+
+	// Companions configures per-type scaffolding emitted next to each resource.
+	Companions model.CompanionsConfig
+
+	// Templates holds resource blueprints. If nil, an empty store is used.
+	Templates *TemplateStore
+
+	// Schedules holds scheduled resource operations. If nil, an empty
+	// store is used.
+	Schedules *ScheduleStore
+
+	// Freezes holds change-freeze windows. If nil, an empty store is used.
+	Freezes *FreezeStore
+
+	// Regions holds the region catalog. If nil, an empty store is used.
+	Regions *RegionStore
+
+	// Locks holds resource locks. If nil, an empty store is used.
+	Locks *LockStore
+
+	// Namespaces holds managed namespaces. If nil, an empty store is used.
+	Namespaces *NamespaceStore
+
+	// Clusters holds cluster registrations. If nil, an empty store is used.
+	Clusters *ClusterStore
+
+	// GitSources, if set, enables the Git push webhook for these
+	// repositories.
+	GitSources *gitsource.Config
+
+	// Admission, if set, sends every candidate resource to these webhooks,
+	// which may reject or mutate it.
+	Admission *admission.Config
+
+	// LintPolicy assigns namespaces to environments and escalates lint
+	// rules to errors. If nil, every lint rule is reported as a warning.
+	LintPolicy *model.LintPolicy
+
+	// Cooldowns, if set, limits how often a resource may change. Admins
+	// may override them.
+	Cooldowns *model.CooldownConfig
+
+	// Images, if set, resolves every image parameter against its registry
+	// before a write is pushed, rejecting images that don't exist.
+	Images *images.Config
+
+	// Defaults, if set, fills in and normalizes every request before it is
+	// validated.
+	Defaults *model.DefaultsConfig
+
+	// Timeouts bounds API requests; see WithTimeouts. Zero fields use the
+	// defaults of 2m and at most 10m.
+	Timeouts RequestTimeouts
+
+	// Canary, if set, promotes catalogs from the catalog's publish channel
+	// to a target channel once they have soaked on the clusters following
+	// it. The publish channel must be set.
+	Canary *CanaryOptions
+
+	// FluxStatus, if set, enables GET /api/v1/flux/status.
+	FluxStatus *FluxStatusOptions
+
+	// Proposals holds change proposals. If nil, an empty store is used.
+	Proposals *ProposalStore
+
+	// APIKeys holds API keys. If nil, an empty store is used. Requests are
+	// only authenticated with them if it is also among the authenticators
+	// of the auth middleware.
+	APIKeys *APIKeyStore
+
+	// PullRequests, if set, opens a pull request in the Git mirror for
+	// every proposal and enables the proposal webhook.
+	PullRequests *PullRequestOptions
+
+	// Jobs runs admin operations in the background. If nil, a manager
+	// with the default limits is used.
+	Jobs *JobManager
+
+	// ReadOnly starts the API in maintenance mode with ReadOnlyMessage.
+	ReadOnly        bool
+	ReadOnlyMessage string
+
+	// AdminGroups, if set, restricts the settings and maintenance
+	// endpoints to callers in one of these groups.
+	AdminGroups []string
+
+	// BreakGlassGroups, if set, lets callers in one of these groups, as
+	// well as admins, override change freezes with X-Break-Glass.
+	// Otherwise only admins may.
+	BreakGlassGroups []string
+
+	// Logs, if set, lets the settings endpoint change the log level.
+	Logs *LogFilter
+
+	// Debug enables the pprof profiles under /debug/pprof/ and
+	// GET /debug/vars, for admins only.
+	Debug bool
+
+	// UI serves the embedded web console under /ui/.
+	UI bool
+
+	// AccessLog, if set, logs API requests; see AccessLogOptions.
+	AccessLog *AccessLogOptions
+
+	// CORS, if set, lets browser pages on other origins call the API.
+	CORS *CORSOptions
+
+	// Sessions, if set, lets callers exchange their credentials for a
+	// session cookie at POST /api/v1/session. It should also be among the
+	// authenticators of the auth middleware.
+	Sessions *auth.Sessions
+
+	// SigningKey and EncryptionKey, if set, are reported by
+	// GET /api/v1/admin/keys and can be rotated through the API. They
+	// should be the signer and data key provider the OCI client uses.
+	SigningKey    ManagedKey
+	EncryptionKey ManagedKey
+
+	// Middleware wraps every request served through Wrap, outermost
+	// first, such as authentication or request logging. Panic recovery
+	// always comes first and the request deadline last, right around the
+	// routes.
+	Middleware []Middleware
+}
+
+// NewHandler creates a new API handler.
+func NewHandler(ociClient oci.Interface, catalog *CatalogManager, opts HandlerOptions) *Handler {
+	templates := opts.Templates
+	if templates == nil {
+		templates = NewTemplateStore(ociClient)
+	}
+	schedules := opts.Schedules
+	if schedules == nil {
+		schedules = NewScheduleStore(ociClient)
+	}
+	freezes := opts.Freezes
+	if freezes == nil {
+		freezes = NewFreezeStore(ociClient)
+	}
+	regions := opts.Regions
+	if regions == nil {
+		regions = NewRegionStore(ociClient)
+	}
+	locks := opts.Locks
+	if locks == nil {
+		locks = NewLockStore(ociClient)
+	}
+	namespaces := opts.Namespaces
+	if namespaces == nil {
+		namespaces = NewNamespaceStore(ociClient, catalog)
+	}
+	clusters := opts.Clusters
+	if clusters == nil {
+		clusters = NewClusterStore(ociClient, 0)
+	}
+	apiKeys := opts.APIKeys
+	if apiKeys == nil {
+		apiKeys = NewAPIKeyStore(ociClient)
+	}
+	proposals := opts.Proposals
+	if proposals == nil {
+		proposals = NewProposalStore(ociClient)
+	}
+	jobs := opts.Jobs
+	if jobs == nil {
+		jobs = NewJobManager(ociClient, JobOptions{})
+	}
+	h := &Handler{
+		ociClient:    ociClient,
+		catalog:      catalog,
+		dryRunner:    opts.DryRunner,
+		encryptor:    opts.Encryptor,
+		companions:   opts.Companions,
+		secretStores: opts.SecretStores,
+		templates:    templates,
+		schedules:    schedules,
+		freezes:      freezes,
+		regions:      regions,
+		locks:        locks,
+		namespaces:   namespaces,
+		clusters:     clusters,
+		timeouts:     opts.Timeouts.withDefaults(),
+		gitSources:   opts.GitSources,
+		admission:    opts.Admission,
+		images:       opts.Images,
+		lint:         opts.LintPolicy,
+		cooldowns:    opts.Cooldowns,
+		defaults:     opts.Defaults,
+		fluxStatus:   opts.FluxStatus,
+
+		proposals:    proposals,
+		apiKeys:      apiKeys,
+		cors:         opts.CORS,
+		sessions:     opts.Sessions,
+		pullRequests: opts.PullRequests,
+		jobs:         jobs,
+		events:       catalog.events,
+		unmanaged:    newUnmanagedArtifacts(),
+
+		adminGroups: opts.AdminGroups,
+		breakGlass:  opts.BreakGlassGroups,
+		logs:        opts.Logs,
+		debug:       opts.Debug,
+		ui:          opts.UI,
+		middleware:  opts.Middleware,
+		keys:        map[string]ManagedKey{},
+	}
+	if opts.SigningKey != nil {
+		h.keys[KeySigning] = opts.SigningKey
+	}
+	if opts.EncryptionKey != nil {
+		h.keys[KeyEncryption] = opts.EncryptionKey
+	}
+	if opts.AccessLog != nil {
+		accessLog := *opts.AccessLog
+		accessLog.SampleRate = min(max(accessLog.SampleRate, 0), 1)
+		h.accessLogOpts = &accessLog
+	}
+	if h.fluxStatus != nil {
+		status := *h.fluxStatus
+		if status.Source == "" {
+			status.Source = defaultFluxSource
+		}
+		if status.Kustomization == "" {
+			status.Kustomization = defaultFluxKustomization
+		}
+		h.fluxStatus = &status
+	}
+	if opts.Canary != nil {
+		h.canary = newCanaryRollout(*opts.Canary, catalog.tagging.PublishChannel)
+	}
+	if opts.ReadOnly {
+		h.maintenance.set(true, opts.ReadOnlyMessage)
+	}
+	return h
+}
+
+// RegisterRoutes registers all API routes on the given mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/v1/resources", h.mutating(h.CreateResource))
+	mux.HandleFunc("GET /api/v1/resources", h.ListResources)
+	mux.HandleFunc("POST /api/v1/resources/plan", h.PlanResource)
+	mux.HandleFunc("GET /api/v1/resources/{name}", h.GetResource)
+	mux.HandleFunc("PUT /api/v1/resources/{name}", h.mutating(h.ReplaceResource))
+	mux.HandleFunc("PATCH /api/v1/resources/{name}", h.mutating(h.PatchResource))
+	mux.HandleFunc("DELETE /api/v1/resources/{name}", h.mutating(h.DeleteResource))
+	mux.HandleFunc("POST /api/v1/resources/{name}/restore", h.mutating(h.RestoreResource))
+	mux.HandleFunc("POST /api/v1/resources/{name}/clone", h.mutating(h.CloneResource))
+	mux.HandleFunc("POST /api/v1/resources/{name}/adopt", h.mutating(h.AdoptResource))
+	mux.HandleFunc("GET /api/v1/resources/{name}/flux", h.GetResourceFlux)
+	mux.HandleFunc("GET /api/v1/resources/{name}/manifest", h.GetResourceManifest)
+	mux.HandleFunc("PUT /api/v1/resources/{name}/manifest", h.mutating(h.ApplyResourceManifest))
+	mux.HandleFunc("POST /api/v1/resources/{name}/lock", h.mutating(h.LockResource))
+	mux.HandleFunc("GET /api/v1/resources/{name}/lock", h.GetResourceLock)
+	mux.HandleFunc("POST /api/v1/resources/{name}/unlock", h.mutating(h.UnlockResource))
+	mux.HandleFunc("GET /api/v1/resources/{name}/history", h.GetResourceHistory)
+	mux.HandleFunc("GET /api/v1/resources/{name}/diff", h.DiffResourceVersions)
+	mux.HandleFunc("GET /api/v1/resources/{name}/attachments", h.ListAttachments)
+	mux.HandleFunc("PUT /api/v1/resources/{name}/attachments/{key}", h.mutating(h.PutAttachment))
+	mux.HandleFunc("GET /api/v1/resources/{name}/attachments/{key}", h.GetAttachment)
+	mux.HandleFunc("POST /api/v1/resources/{name}/schedule", h.mutating(h.CreateSchedule))
+	mux.HandleFunc("GET /api/v1/resources/{name}/schedule", h.ListResourceSchedules)
+	mux.HandleFunc("DELETE /api/v1/resources/{name}/schedule/{schedule}", h.mutating(h.DeleteSchedule))
+	mux.HandleFunc("GET /api/v1/schedules", h.ListSchedules)
+	mux.HandleFunc("GET /api/v1/catalog", h.GetCatalog)
+	mux.HandleFunc("GET /api/v1/flux/status", h.GetFluxStatus)
+	mux.HandleFunc("POST /api/v1/clusters", h.mutating(h.RegisterCluster))
+	mux.HandleFunc("GET /api/v1/clusters", h.ListClusters)
+	mux.HandleFunc("GET /api/v1/clusters/{cluster}", h.GetCluster)
+	mux.HandleFunc("DELETE /api/v1/clusters/{cluster}", h.mutating(h.DeregisterCluster))
+	mux.HandleFunc("POST /api/v1/clusters/{cluster}/heartbeat", h.ClusterHeartbeat)
+	mux.HandleFunc("GET /api/v1/clusters/{cluster}/flux", h.GetClusterFlux)
+	mux.HandleFunc("GET /api/v1/catalog/contents", h.GetCatalogContents)
+	mux.HandleFunc("GET /api/v1/catalog/contents/{file...}", h.GetCatalogFile)
+	mux.HandleFunc("GET /api/v1/catalog/download", h.DownloadCatalog)
+	mux.HandleFunc("GET /api/v1/catalog/history", h.GetCatalogHistory)
+	mux.HandleFunc("GET /api/v1/catalog/provenance", h.GetCatalogProvenance)
+	mux.HandleFunc("POST /api/v1/catalog/rollback", h.mutating(h.RollbackCatalog))
+	mux.HandleFunc("GET /api/v1/catalog/channels", h.ListCatalogChannels)
+	mux.HandleFunc("POST /api/v1/catalog/channels/{channel}/promote", h.adminOnly(h.mutating(h.PromoteCatalog)))
+	mux.HandleFunc("GET /api/v1/catalog/channels/{channel}/flux", h.GetChannelFlux)
+	mux.HandleFunc("GET /api/v1/catalog/canary", h.GetCanary)
+	mux.HandleFunc("POST /api/v1/catalog/canary/promote", h.adminOnly(h.mutating(h.PromoteCanary)))
+	mux.HandleFunc("POST /api/v1/catalog/canary/hold", h.adminOnly(h.mutating(h.HoldCanary)))
+	mux.HandleFunc("DELETE /api/v1/catalog/canary/hold", h.adminOnly(h.mutating(h.ReleaseCanary)))
+	mux.HandleFunc("POST /api/v1/namespaces", h.mutating(h.CreateNamespace))
+	mux.HandleFunc("GET /api/v1/namespaces", h.ListNamespaces)
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}", h.GetNamespace)
+	mux.HandleFunc("DELETE /api/v1/namespaces/{namespace}", h.mutating(h.DeleteNamespace))
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}/costs", h.GetNamespaceCosts)
+	mux.HandleFunc("GET /api/v1/stats", h.GetStats)
+	mux.HandleFunc("POST /api/v1/templates", h.adminOnly(h.mutating(h.CreateTemplate)))
+	mux.HandleFunc("GET /api/v1/types", h.ListResourceTypes)
+	mux.HandleFunc("GET /api/v1/types/{type}", h.GetResourceType)
+	mux.HandleFunc("GET /api/v1/templates", h.ListTemplates)
+	mux.HandleFunc("GET /api/v1/templates/{name}", h.GetTemplate)
+	mux.HandleFunc("DELETE /api/v1/templates/{name}", h.adminOnly(h.mutating(h.DeleteTemplate)))
+	mux.HandleFunc("POST /api/v1/previews", h.mutating(h.CreatePreview))
+	mux.HandleFunc("GET /api/v1/previews", h.ListPreviews)
+	mux.HandleFunc("GET /api/v1/previews/{id}", h.GetPreview)
+	mux.HandleFunc("DELETE /api/v1/previews/{id}", h.mutating(h.DeletePreview))
+	mux.HandleFunc("POST /api/v1/proposals", h.mutating(h.CreateProposal))
+	mux.HandleFunc("GET /api/v1/proposals", h.ListProposals)
+	mux.HandleFunc("GET /api/v1/proposals/{id}", h.GetProposal)
+	mux.HandleFunc("POST /api/v1/proposals/{id}/merge", h.mutating(h.MergeProposal))
+	mux.HandleFunc("DELETE /api/v1/proposals/{id}", h.mutating(h.CloseProposal))
+	mux.HandleFunc("POST /api/v1/webhooks/git", h.mutating(h.GitWebhook))
+	mux.HandleFunc("POST /api/v1/webhooks/proposals", h.mutating(h.ProposalWebhook))
+	mux.HandleFunc("POST /api/v1/admin/migrate", h.adminOnly(h.mutating(h.MigrateResources)))
+	mux.HandleFunc("POST /api/v1/admin/migrate-types", h.adminOnly(h.mutating(h.MigrateTypes)))
+	mux.HandleFunc("POST /api/v1/admin/migrate-format", h.adminOnly(h.mutating(h.MigrateFormat)))
+	mux.HandleFunc("POST /api/v1/admin/reencrypt", h.adminOnly(h.mutating(h.Reencrypt)))
+	mux.HandleFunc("GET /api/v1/admin/registry", h.adminOnly(h.GetRegistryStatus))
+	mux.HandleFunc("POST /api/v1/admin/fsck", h.adminOnly(h.mutating(h.RunFsck)))
+	mux.HandleFunc("GET /api/v1/admin/quarantine", h.adminOnly(h.GetQuarantine))
+	mux.HandleFunc("GET /api/v1/admin/unmanaged", h.adminOnly(h.ListUnmanaged))
+	mux.HandleFunc("POST /api/v1/admin/sync", h.adminOnly(h.mutating(h.SyncReplica)))
+	mux.HandleFunc("GET /api/v1/events", h.StreamEvents)
+	mux.HandleFunc("GET /api/v1/events/history", h.GetEventHistory)
+	mux.HandleFunc("GET /api/v1/jobs", h.ListJobs)
+	mux.HandleFunc("GET /api/v1/jobs/{id}", h.GetJob)
+	mux.HandleFunc("POST /api/v1/jobs/{id}/cancel", h.mutating(h.CancelJob))
+	mux.HandleFunc("POST /api/v1/admin/import/git", h.adminOnly(h.mutating(h.ImportGit)))
+	mux.HandleFunc("POST /api/v1/admin/freezes", h.adminOnly(h.mutating(h.CreateFreeze)))
+	mux.HandleFunc("DELETE /api/v1/admin/freezes/{name}", h.adminOnly(h.mutating(h.DeleteFreeze)))
+	mux.HandleFunc("GET /api/v1/freezes", h.ListFreezes)
+	mux.HandleFunc("GET /api/v1/freezes/{name}", h.GetFreeze)
+	mux.HandleFunc("POST /api/v1/admin/regions", h.adminOnly(h.mutating(h.PutRegion)))
+	mux.HandleFunc("DELETE /api/v1/admin/regions/{name}", h.adminOnly(h.mutating(h.DeleteRegion)))
+	mux.HandleFunc("GET /api/v1/regions", h.ListRegions)
+	mux.HandleFunc("GET /api/v1/regions/{name}", h.GetRegion)
+	mux.HandleFunc("GET /api/v1/locks", h.ListLocks)
+	mux.HandleFunc("GET /api/v1/admin/maintenance", h.GetMaintenance)
+	mux.HandleFunc("PUT /api/v1/admin/maintenance", h.adminOnly(h.SetMaintenance))
+	mux.HandleFunc("GET /api/v1/admin/settings", h.adminOnly(h.GetSettings))
+	mux.HandleFunc("PUT /api/v1/admin/settings", h.adminOnly(h.UpdateSettings))
+	mux.HandleFunc("POST /api/v1/admin/restore/override", h.adminOnly(h.OverrideRestore))
+	mux.HandleFunc("GET /api/v1/admin/keys", h.adminOnly(h.GetKeys))
+	mux.HandleFunc("POST /api/v1/admin/keys/{purpose}/rotate", h.adminOnly(h.RotateKey))
+	mux.HandleFunc("POST /api/v1/admin/apikeys", h.adminOnly(h.mutating(h.CreateAPIKey)))
+	mux.HandleFunc("GET /api/v1/admin/apikeys", h.adminOnly(h.ListAPIKeys))
+	mux.HandleFunc("GET /api/v1/admin/apikeys/{name}", h.adminOnly(h.GetAPIKey))
+	mux.HandleFunc("DELETE /api/v1/admin/apikeys/{name}", h.adminOnly(h.mutating(h.DeleteAPIKey)))
+	mux.HandleFunc("GET /healthz", h.Healthz)
+	mux.HandleFunc("GET /readyz", h.Readyz)
+	mux.HandleFunc("GET /metrics", h.GetMetrics)
+	if h.debug {
+		h.registerDebugRoutes(mux)
+	}
+	if h.ui {
+		h.registerUIRoutes(mux)
+	}
+	mux.HandleFunc("GET /api/v1/whoami", h.WhoAmI)
+	mux.HandleFunc("GET /api/v1/messages", h.ListMessages)
+	mux.HandleFunc("POST /api/v1/session", h.CreateSession)
+	mux.HandleFunc("DELETE /api/v1/session", h.DeleteSession)
+}
+
+// CreateResource handles POST /api/v1/resources.
+// With ?fromTemplate=<name>, the request's spec fields override the
+// template's. A resource that already exists is only replaced with
+// ?overwrite=true; otherwise the create fails with 409.
+func (h *Handler) CreateResource(w http.ResponseWriter, r *http.Request) {
+	namespace, ok := resourceNamespace(w, r)
+	if !ok || !h.checkFreeze(w, r, namespace) || !h.checkNamespace(w, namespace) {
+		return
+	}
+
+	var req model.ResourceRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+
+	if name := r.URL.Query().Get("fromTemplate"); name != "" {
+		tmpl, ok := h.templates.Get(name)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "template %q not found", name)
+			return
+		}
+		tmpl.Instantiate(&req)
+	}
+
+	h.defaults.Apply(namespace, &req)
+	if err := req.ConvertToCurrent(); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if err := h.checkSecrets(namespace, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	resp, err := h.applyResourceWith(r.Context(), namespace, &req, applyOptions{
+		ifMatch:       r.Header.Get("If-Match"),
+		skipUnchanged: r.URL.Query().Get("force") != "true",
+		create:        r.URL.Query().Get("overwrite") != "true",
+	})
+	if err != nil {
+		writeApplyError(w, err)
+		return
+	}
+
+	if resp.Changed != nil && !*resp.Changed {
+		writeJSON(w, http.StatusOK, resp)
+		log.Printf("Resource %s unchanged (version=%s)", req.Name, resp.Version)
+		return
+	}
+	writeJSON(w, http.StatusCreated, resp)
+	log.Printf("Created resource %s (version=%s, digest=%s)", req.Name, resp.Version, resp.Digest[:19])
+}
+
+// ReplaceResource handles PUT /api/v1/resources/{name}.
+// It replaces the spec of an existing resource. The body's name may be
+// left out; existing secrets are kept unless the body sends its own.
+func (h *Handler) ReplaceResource(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	namespace, ok := resourceNamespace(w, r)
+	if !ok || !h.checkFreeze(w, r, namespace) {
+		return
+	}
+
+	var req model.ResourceRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	if req.Name == "" {
+		req.Name = name
+	} else if req.Name != name {
+		writeError(w, http.StatusBadRequest, "name %q does not match the path %q", req.Name, name)
+		return
+	}
+
+	data, ok := h.catalog.Get(namespace, name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "resource %q not found", name)
+		return
+	}
+
+	h.defaults.Apply(namespace, &req)
+	if err := req.ConvertToCurrent(); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if err := h.checkSecrets(namespace, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	opts := applyOptions{
+		ifMatch:       r.Header.Get("If-Match"),
+		skipUnchanged: r.URL.Query().Get("force") != "true",
+	}
+	if len(req.Secrets) == 0 {
+		opts.extra = secretDocuments(data)
+	}
+	resp, err := h.applyResourceWith(r.Context(), namespace, &req, opts)
+	if err != nil {
+		writeApplyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+	log.Printf("Replaced resource %s (version=%s)", name, resp.Version)
+}
+
+// applyResource pushes a validated resource as a new artifact version and
+// republishes the catalog.
+func (h *Handler) applyResource(ctx context.Context, namespace string, req *model.ResourceRequest) (model.ResourceResponse, error) {
+	return h.applyResourceWith(ctx, namespace, req, applyOptions{})
+}
+
+// applyOptions adjusts how a resource is pushed.
+type applyOptions struct {
+	// extra holds already rendered documents appended to the manifest
+	// (e.g. secrets carried over from a previous version).
+	extra [][]byte
+
+	// ifMatch is the client's If-Match header: the version or digest the
+	// write is based on.
+	ifMatch string
+
+	// skipUnchanged returns the current version instead of pushing a new
+	// one when the rendered manifest is identical. The response's Changed
+	// field reports which happened.
+	skipUnchanged bool
+
+	// create fails the write with an *ExistsError if the resource already
+	// exists.
+	create bool
+
+	// adopt is the digest of an artifact pushed by another tool that the
+	// write adopts. The registry's "latest" may point at it; if it is the
+	// current version already, the write fails with an *ExistsError.
+	adopt string
+}
+
+// applyResourceWith is applyResource with options.
+func (h *Handler) applyResourceWith(ctx context.Context, namespace string, req *model.ResourceRequest, opts applyOptions) (model.ResourceResponse, error) {
+	resp, err := h.pushResource(ctx, namespace, req, opts)
+	if err != nil {
+		return model.ResourceResponse{}, err
+	}
+	if resp.Changed != nil && !*resp.Changed {
+		return resp, nil
+	}
+
+	if err := h.catalog.PushCatalog(ctx); err != nil {
+		log.Printf("Warning: failed to push catalog: %v", err)
+	}
+	return resp, nil
+}
+
+// pushResource renders a resource, pushes it as a new artifact version and
+// records it in the catalog, without republishing the catalog. It fails with
+// a *ConflictError if the write is based on a stale version, with an
+// *ExistsError if a create finds the resource, and with a *LockedError if
+// someone else holds the resource's lock.
+func (h *Handler) pushResource(ctx context.Context, namespace string, req *model.ResourceRequest, opts applyOptions) (model.ResourceResponse, error) {
+	unlock := h.catalog.LockResource(namespace, req.Name)
+	defer unlock()
+
+	if meta, ok := h.catalog.Meta(namespace, req.Name); ok && (opts.create || (opts.adopt != "" && opts.adopt == meta.Digest)) {
+		return model.ResourceResponse{}, &ExistsError{Namespace: namespace, Name: req.Name, CurrentVersion: meta.Version, CurrentDigest: meta.Digest}
+	}
+	if opts.adopt != "" {
+		if err := h.catalog.checkAdoption(ctx, namespace, req.Name, opts.adopt); err != nil {
+			return model.ResourceResponse{}, err
+		}
+	} else if err := h.catalog.CheckConflict(ctx, namespace, req.Name, opts.ifMatch); err != nil {
+		return model.ResourceResponse{}, err
+	}
+	if err := h.checkLock(ctx, namespace, req.Name); err != nil {
+		return model.ResourceResponse{}, err
+	}
+
+	// Defaults are re-applied here for writes that don't go through a
+	// handler (schedules, migrations), so mandatory labels always render.
+	h.defaults.Apply(namespace, req)
+	if err := h.admit(ctx, namespace, req); err != nil {
+		return model.ResourceResponse{}, err
+	}
+	if err := h.checkRegion(namespace, req); err != nil {
+		return model.ResourceResponse{}, err
+	}
+	if err := checkScope(ctx, namespace, req.Spec.Type); err != nil {
+		return model.ResourceResponse{}, err
+	}
+	warnings, err := h.lint.Lint(namespace, req.Spec.WithDefaults())
+	if err != nil {
+		return model.ResourceResponse{}, err
+	}
+
+	companions, err := h.renderCompanions(ctx, namespace, req)
+	if err != nil {
+		return model.ResourceResponse{}, err
+	}
+	companions = append(companions, opts.extra...)
+
+	// Reproducible artifacts leave out the push time, so identical specs
+	// render identical manifests.
+	manifestAnnotations := model.ManifestAnnotations{
+		Version:    "pending", // placeholder; the real version comes from the OCI push
+		PushedAt:   time.Now(),
+		Generation: h.nextGeneration(namespace, req),
+	}
+	if h.ociClient.Reproducible() {
+		manifestAnnotations.PushedAt = time.Time{}
+	}
+
+	crBytes, err := req.ToKubernetesYAML(namespace, manifestAnnotations)
+	if err != nil {
+		return model.ResourceResponse{}, fmt.Errorf("generating YAML: %w", err)
+	}
+	yamlBytes := joinDocuments(append([][]byte{crBytes}, companions...)...)
+
+	expiresAt, ok := req.Expiry(time.Now())
+	if !ok {
+		current, _ := h.catalog.Meta(namespace, req.Name)
+		expiresAt = current.ExpiresAt
+	}
+
+	if opts.skipUnchanged {
+		if resp, ok := h.unchangedResponse(namespace, req, companions, expiresAt); ok {
+			resp.Warnings = warnings
+			return resp, nil
+		}
+	}
+	if err := h.checkCooldown(ctx, namespace, req.Name, req.Spec.Type); err != nil {
+		return model.ResourceResponse{}, err
+	}
+
+	if h.catalog.schemas != nil {
+		if err := h.catalog.schemas.Validate(yamlBytes); err != nil {
+			return model.ResourceResponse{}, err
+		}
+	}
+	if h.dryRunner != nil {
+		if err := h.dryRunner.DryRun(ctx, yamlBytes); err != nil {
+			return model.ResourceResponse{}, fmt.Errorf("dry-run: %w", err)
+		}
+	}
+	resolvedImages, err := h.resolveImages(ctx, namespace, req)
+	if err != nil {
+		return model.ResourceResponse{}, err
+	}
+
+	annotations := h.catalog.resourceAnnotations(ctx, namespace, req.Name, yamlBytes)
+	if resolvedImages != "" {
+		annotations[oci.AnnotationResourceImages] = resolvedImages
+	}
+	if !expiresAt.IsZero() {
+		annotations[oci.AnnotationResourceExpiresAt] = expiresAt.Format(time.RFC3339)
+	}
+	digest, version, err := h.ociClient.PushResource(ctx, namespace, req.Name, yamlBytes, annotations)
+	if err != nil {
+		return model.ResourceResponse{}, fmt.Errorf("pushing to registry: %w", err)
+	}
+
+	// Re-generate YAML with the real version.
+	manifestAnnotations.Version = version
+	crBytes, err = req.ToKubernetesYAML(namespace, manifestAnnotations)
+	if err != nil {
+		return model.ResourceResponse{}, fmt.Errorf("generating YAML: %w", err)
+	}
+	yamlBytes = joinDocuments(append([][]byte{crBytes}, companions...)...)
+
+	_, existed := h.catalog.Get(namespace, req.Name)
+	h.recordChange(namespace, req.Name, req.Spec.Type)
+	h.catalog.Set(namespace, req.Name, yamlBytes, ResourceMeta{
+		Version:   version,
+		Digest:    digest,
+		Cost:      costFromAnnotations(annotations),
+		Images:    imagesFromAnnotations(annotations),
+		ExpiresAt: expiresAt,
+	})
+	noteProgress(ctx, "pushed resource %s/%s version %s", namespace, req.Name, version)
+	event := model.EventResourceCreated
+	if existed {
+		event = model.EventResourceUpdated
+	}
+	h.events.Record(ctx, model.Event{Type: event, Namespace: namespace, Name: req.Name, Version: version, Digest: digest})
+
+	meta, _ := h.catalog.Meta(namespace, req.Name)
+	resp := resourceResponse(namespace, req.Name, yamlBytes, meta)
+	for _, secret := range req.Secrets {
+		resp.Secrets = append(resp.Secrets, secret.Name)
+	}
+	changed := true
+	resp.Changed = &changed
+	resp.Warnings = warnings
+	return resp, nil
+}
+
+// nextGeneration returns the generation of the version about to be pushed
+// for req: the current generation, incremented if the spec changed. New
+// resources start at 1.
+func (h *Handler) nextGeneration(namespace string, req *model.ResourceRequest) int64 {
+	current, ok := h.catalog.Get(namespace, req.Name)
+	if !ok {
+		return 1
+	}
+	var pr model.PlatformResource
+	if err := yaml.Unmarshal(current, &pr); err != nil {
+		return 1
+	}
+	generation := model.ParseManifestAnnotations(pr.Metadata.Annotations).Generation
+	if generation == 0 || !pr.Spec.Equal(req.Spec.WithDefaults()) {
+		generation++
+	}
+	return generation
+}
+
+// unchangedResponse describes the current version of a resource if
+// rendering req at that version (and push time) reproduces its manifest
+// byte for byte and its expiry is unchanged. Manifests carrying
+// SOPS-encrypted secrets never match, since encryption is randomized.
+func (h *Handler) unchangedResponse(namespace string, req *model.ResourceRequest, companions [][]byte, expiresAt time.Time) (model.ResourceResponse, bool) {
+	current, ok := h.catalog.Get(namespace, req.Name)
+	if !ok {
+		return model.ResourceResponse{}, false
+	}
+	meta, _ := h.catalog.Meta(namespace, req.Name)
+	if !meta.ExpiresAt.Equal(expiresAt) {
+		return model.ResourceResponse{}, false
+	}
+
+	// Render with the current manifest's version, push time and generation
+	// annotations so only real changes differ.
+	var pr model.PlatformResource
+	if err := yaml.Unmarshal(current, &pr); err != nil {
+		return model.ResourceResponse{}, false
+	}
+
+	crBytes, err := req.ToKubernetesYAML(namespace, model.ParseManifestAnnotations(pr.Metadata.Annotations))
+	if err != nil {
+		return model.ResourceResponse{}, false
+	}
+	if !bytes.Equal(current, joinDocuments(append([][]byte{crBytes}, companions...)...)) {
+		return model.ResourceResponse{}, false
+	}
+
+	resp := resourceResponse(namespace, req.Name, current, meta)
+	for _, secret := range req.Secrets {
+		resp.Secrets = append(resp.Secrets, secret.Name)
+	}
+	changed := false
+	resp.Changed = &changed
+	return resp, true
+}
+
+// ListResources handles GET /api/v1/resources.
+// Results can be narrowed with ?namespace=, and soft-deleted resources are
+// included with ?includeDeleted=true. By default only names are returned;
+// ?detail=full includes spec, version, digest, labels and timestamps.
+func (h *Handler) ListResources(w http.ResponseWriter, r *http.Request) {
+	namespace := r.URL.Query().Get("namespace")
+	full := r.URL.Query().Get("detail") == "full"
+	all := h.catalog.List()
+
+	resources := make([]model.ResourceResponse, 0, len(all))
+	for key, data := range all {
+		ns, name, ok := strings.Cut(key, "/")
+		if !ok || (namespace != "" && ns != namespace) || !inScope(r.Context(), ns, data) {
+			continue
+		}
+		if full {
+			meta, _ := h.catalog.Meta(ns, name)
+			resources = append(resources, resourceResponse(ns, name, data, meta))
+			continue
+		}
+		resources = append(resources, model.ResourceResponse{
+			Name:      name,
+			Namespace: ns,
+		})
+	}
+
+	if r.URL.Query().Get("includeDeleted") == "true" {
+		for key, deletedAt := range h.catalog.ListDeleted() {
+			ns, name, ok := strings.Cut(key, "/")
+			if !ok || (namespace != "" && ns != namespace) {
+				continue
+			}
+			if data, _, _ := h.catalog.GetDeleted(ns, name); !inScope(r.Context(), ns, data) {
+				continue
+			}
+			resources = append(resources, h.deletedResponse(ns, name, deletedAt))
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"resources": resources,
+		"count":     len(resources),
+	})
+}
+
+// GetResource handles GET /api/v1/resources/{name}.
+func (h *Handler) GetResource(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	namespace, ok := resourceNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	data, ok := h.catalog.Get(namespace, name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "resource %q not found", name)
+		return
+	}
+
+	meta, _ := h.catalog.Meta(namespace, name)
+	resp := resourceResponse(namespace, name, data, meta)
+	h.observeGeneration(r.Context(), data, &resp)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// observeGeneration fills in the generation the cluster has acted on, read
+// from the status of the applied object, if the API reads the cluster. An
+// object Flux hasn't applied yet isn't synced; if the cluster can't be
+// read, both fields are left out.
+func (h *Handler) observeGeneration(ctx context.Context, manifest []byte, resp *model.ResourceResponse) {
+	if h.fluxStatus == nil || resp.Generation == 0 {
+		return
+	}
+	var pr model.PlatformResource
+	if err := yaml.Unmarshal(manifest, &pr); err != nil {
+		return
+	}
+	observed, err := h.fluxStatus.Client.ObservedGeneration(ctx, pr.APIVersion, pr.Kind, pr.Metadata.Namespace, pr.Metadata.Name)
+	if err != nil && !errors.Is(err, kube.ErrNotFound) {
+		log.Printf("Warning: reading status of %s/%s: %v", resp.Namespace, resp.Name, err)
+		return
+	}
+	synced := observed >= resp.Generation
+	resp.ObservedGeneration = observed
+	resp.Synced = &synced
+}
+
+// maxPatchSize bounds a patch body, like an edited manifest.
+const maxPatchSize = maxManifestSize
+
+// PatchResource handles PATCH /api/v1/resources/{name}.
+// The body is an RFC 7386 merge patch (application/merge-patch+json) or an
+// RFC 6902 JSON patch (application/json-patch+json) against {"name", "spec"}.
+// Existing secrets are kept.
+func (h *Handler) PatchResource(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	namespace, ok := resourceNamespace(w, r)
+	if !ok || !h.checkFreeze(w, r, namespace) {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var applyPatch func(doc, patch []byte) ([]byte, error)
+	switch mediaType {
+	case "application/merge-patch+json":
+		applyPatch = patch.MergePatch
+	case "application/json-patch+json":
+		applyPatch = patch.JSONPatch
+	default:
+		writeError(w, http.StatusUnsupportedMediaType, "unsupported patch type %q: use application/merge-patch+json or application/json-patch+json", mediaType)
+		return
+	}
+
+	data, ok := h.catalog.Get(namespace, name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "resource %q not found", name)
+		return
+	}
+
+	var pr model.PlatformResource
+	if err := yaml.Unmarshal(data, &pr); err != nil {
+		writeError(w, http.StatusInternalServerError, "parsing stored manifest: %v", err)
+		return
+	}
+
+	current, err := json.Marshal(model.ResourceRequest{APIVersion: pr.APIVersion, Name: name, Spec: pr.Spec})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "encoding current resource: %v", err)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "patch exceeds %d bytes", maxPatchSize)
+			return
+		}
+		writeError(w, http.StatusBadRequest, "reading body: %v", err)
+		return
+	}
+
+	patched, err := applyPatch(current, body)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "applying patch: %v", err)
+		return
+	}
+
+	var req model.ResourceRequest
+	if err := unmarshalJSON(r, patched, &req); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "patched resource is invalid: %v", err)
+		return
+	}
+	if req.Name != name {
+		writeError(w, http.StatusUnprocessableEntity, "name cannot be changed by a patch")
+		return
+	}
+	if len(req.Secrets) > 0 {
+		writeError(w, http.StatusUnprocessableEntity, "secrets cannot be changed by a patch")
+		return
+	}
+	h.defaults.Apply(namespace, &req)
+	if err := req.ConvertToCurrent(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "%v", err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	resp, err := h.applyResourceWith(r.Context(), namespace, &req, applyOptions{
+		extra:   secretDocuments(data),
+		ifMatch: r.Header.Get("If-Match"),
+	})
+	if err != nil {
+		writeApplyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+	log.Printf("Patched resource %s (version=%s)", name, resp.Version)
+}
+
+// DeleteResource handles DELETE /api/v1/resources/{name}.
+func (h *Handler) DeleteResource(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	namespace, ok := resourceNamespace(w, r)
+	if !ok || !h.checkFreeze(w, r, namespace) {
+		return
+	}
+
+	resp, err := h.deleteResource(r.Context(), namespace, name, r.Header.Get("If-Match"))
+	if errors.Is(err, errResourceNotFound) {
+		writeError(w, http.StatusNotFound, "resource %q not found", name)
+		return
+	}
+	if err != nil {
+		writeApplyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+	log.Printf("Deleted resource %s (tombstone version=%s)", name, resp.Version)
+}
+
+// errResourceNotFound is returned by deleteResource for unknown resources
+// and by restoreResource if there is nothing to restore.
+var errResourceNotFound = errors.New("resource not found")
+
+// deleteResource pushes a tombstone for a live resource, soft-deletes it
+// and republishes the catalog.
+func (h *Handler) deleteResource(ctx context.Context, namespace, name, ifMatch string) (model.ResourceResponse, error) {
+	resp, err := h.tombstoneResource(ctx, namespace, name, ifMatch)
+	if err != nil {
+		return model.ResourceResponse{}, err
+	}
+
+	if err := h.catalog.PushCatalog(ctx); err != nil {
+		log.Printf("Warning: failed to push catalog: %v", err)
+	}
+	return resp, nil
+}
+
+// tombstoneResource pushes a tombstone for a live resource and soft-deletes
+// it, without republishing the catalog.
+func (h *Handler) tombstoneResource(ctx context.Context, namespace, name, ifMatch string) (model.ResourceResponse, error) {
+	unlock := h.catalog.LockResource(namespace, name)
+	defer unlock()
+
+	if err := h.catalog.CheckConflict(ctx, namespace, name, ifMatch); err != nil {
+		return model.ResourceResponse{}, err
+	}
+	if err := h.checkLock(ctx, namespace, name); err != nil {
+		return model.ResourceResponse{}, err
+	}
+
+	data, ok := h.catalog.Get(namespace, name)
+	if !ok {
+		return model.ResourceResponse{}, errResourceNotFound
+	}
+	var current model.PlatformResource
+	_ = yaml.Unmarshal(data, &current)
+	if err := h.checkCooldown(ctx, namespace, name, current.Spec.Type); err != nil {
+		return model.ResourceResponse{}, err
+	}
+
+	// Push tombstone artifact for audit trail.
+	annotations := h.catalog.tombstoneAnnotations(ctx, namespace, name)
+	digest, version, err := h.ociClient.PushTombstone(ctx, namespace, name, data, annotations)
+	if err != nil {
+		return model.ResourceResponse{}, fmt.Errorf("pushing tombstone: %w", err)
+	}
+
+	h.catalog.Delete(namespace, name)
+	h.recordChange(namespace, name, current.Spec.Type)
+	noteProgress(ctx, "pushed tombstone for %s/%s version %s", namespace, name, version)
+	h.events.Record(ctx, model.Event{Type: model.EventResourceDeleted, Namespace: namespace, Name: name, Version: version, Digest: digest})
+
+	resp := h.deletedResponse(namespace, name, time.Now())
+	resp.Version = version
+	resp.Digest = digest
+	resp.CreatedAt = annotations[oci.AnnotationResourceCreatedAt]
+	return resp, nil
+}
+
+// RestoreResource handles POST /api/v1/resources/{name}/restore.
+// It undeletes a soft-deleted resource by pushing its last manifest as a new
+// version. The stored manifest is reused as-is so companion documents such as
+// encrypted secrets survive.
+func (h *Handler) RestoreResource(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	namespace, ok := resourceNamespace(w, r)
+	if !ok || !h.checkFreeze(w, r, namespace) || !h.checkNamespace(w, namespace) {
+		return
+	}
+
+	resp, err := h.restoreResource(r.Context(), namespace, name)
+	if errors.Is(err, errResourceNotFound) {
+		writeError(w, http.StatusNotFound, "no restorable resource %q", name)
+		return
+	}
+	if errors.Is(err, errRestoreMutated) {
+		writeError(w, http.StatusUnprocessableEntity, "%v", err)
+		return
+	}
+	if err != nil {
+		writeApplyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+	log.Printf("Restored resource %s (version=%s)", name, resp.Version)
+}
+
+// restoreResource pushes the last manifest of a soft-deleted resource as a
+// new version and republishes the catalog. It returns errResourceNotFound if
+// there is nothing to restore.
+func (h *Handler) restoreResource(ctx context.Context, namespace, name string) (model.ResourceResponse, error) {
+	unlock := h.catalog.LockResource(namespace, name)
+	defer unlock()
+
+	if err := h.catalog.CheckConflict(ctx, namespace, name, ""); err != nil {
+		return model.ResourceResponse{}, err
+	}
+	if err := h.checkLock(ctx, namespace, name); err != nil {
+		return model.ResourceResponse{}, err
+	}
+
+	data, _, ok := h.catalog.GetDeleted(namespace, name)
+	if !ok {
+		return model.ResourceResponse{}, errResourceNotFound
+	}
+	deleted, _ := h.catalog.DeletedMeta(namespace, name)
+	if err := h.checkRestore(ctx, namespace, name, data); err != nil {
+		return model.ResourceResponse{}, err
+	}
+
+	// An expiry that has already passed is what deleted the resource;
+	// restoring it clears the expiry instead of expiring it again.
+	expiresAt := deleted.ExpiresAt
+	if !expiresAt.After(time.Now()) {
+		expiresAt = time.Time{}
+	}
+
+	annotations := h.catalog.resourceAnnotations(ctx, namespace, name, data)
+	if len(deleted.Images) > 0 {
+		resolved, err := json.Marshal(deleted.Images)
+		if err != nil {
+			return model.ResourceResponse{}, fmt.Errorf("recording images: %w", err)
+		}
+		annotations[oci.AnnotationResourceImages] = string(resolved)
+	}
+	if !expiresAt.IsZero() {
+		annotations[oci.AnnotationResourceExpiresAt] = expiresAt.Format(time.RFC3339)
+	}
+	digest, version, err := h.ociClient.PushResource(ctx, namespace, name, data, annotations)
+	if err != nil {
+		return model.ResourceResponse{}, fmt.Errorf("pushing to registry: %w", err)
+	}
+
+	h.recordChange(namespace, name, manifestType(data))
+	h.catalog.Set(namespace, name, data, ResourceMeta{
+		Version:   version,
+		Digest:    digest,
+		Cost:      costFromAnnotations(annotations),
+		Images:    deleted.Images,
+		ExpiresAt: expiresAt,
+	})
+	h.events.Record(ctx, model.Event{Type: model.EventResourceRestored, Namespace: namespace, Name: name, Version: version, Digest: digest})
+	if err := h.catalog.PushCatalog(ctx); err != nil {
+		log.Printf("Warning: failed to push catalog: %v", err)
+	}
+
+	meta, _ := h.catalog.Meta(namespace, name)
+	return resourceResponse(namespace, name, data, meta), nil
+}
+
+// errRestoreMutated is returned when admission webhooks would change the
+// spec of a resource being restored. Restore pushes the stored manifest
+// as-is, so it can't apply the change.
+var errRestoreMutated = errors.New("admission webhooks would change the spec; recreate the resource instead of restoring it")
+
+// checkRestore runs the checks a write goes through on the manifest of a
+// soft-deleted resource, so a restore can't bring back a spec that current
+// policy rejects: validation, admission, regions, scopes, lint errors,
+// cooldowns, schemas and the server-side dry-run.
+func (h *Handler) checkRestore(ctx context.Context, namespace, name string, manifest []byte) error {
+	var pr model.PlatformResource
+	if err := yaml.Unmarshal(manifest, &pr); err != nil {
+		return fmt.Errorf("parsing stored manifest: %w", err)
+	}
+	req := model.ResourceRequest{APIVersion: pr.APIVersion, Name: name, Spec: pr.Spec}
+	h.defaults.Apply(namespace, &req)
+	if err := req.ConvertToCurrent(); err != nil {
+		return err
+	}
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	spec := req.Spec
+	if err := h.admit(ctx, namespace, &req); err != nil {
+		return err
+	}
+	if !req.Spec.Equal(spec) {
+		return errRestoreMutated
+	}
+	if err := h.checkRegion(namespace, &req); err != nil {
+		return err
+	}
+	if err := checkScope(ctx, namespace, req.Spec.Type); err != nil {
+		return err
+	}
+	if _, err := h.lint.Lint(namespace, req.Spec.WithDefaults()); err != nil {
+		return err
+	}
+	if err := h.checkCooldown(ctx, namespace, name, req.Spec.Type); err != nil {
+		return err
+	}
+
+	if h.catalog.schemas != nil {
+		if err := h.catalog.schemas.Validate(manifest); err != nil {
+			return err
+		}
+	}
+	if h.dryRunner != nil {
+		if err := h.dryRunner.DryRun(ctx, manifest); err != nil {
+			return fmt.Errorf("dry-run: %w", err)
+		}
+	}
+	return nil
+}
+
+// CloneResource handles POST /api/v1/resources/{name}/clone.
+// It copies the source's spec to a new name, optionally in another namespace,
+// applying any spec overrides from the body. Secrets are not copied.
+func (h *Handler) CloneResource(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	namespace, ok := resourceNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	var clone model.CloneRequest
+	if err := decodeJSON(r, &clone); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+
+	targetNamespace := clone.Namespace
+	if targetNamespace == "" {
+		targetNamespace = namespace
+	} else if err := model.ValidateNamespace(targetNamespace); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if !h.checkFreeze(w, r, targetNamespace) || !h.checkNamespace(w, targetNamespace) {
+		return
+	}
+
+	data, ok := h.catalog.Get(namespace, name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "resource %q not found", name)
+		return
+	}
+	var pr model.PlatformResource
+	if err := yaml.Unmarshal(data, &pr); err != nil {
+		writeError(w, http.StatusInternalServerError, "parsing stored manifest: %v", err)
+		return
+	}
+
+	req := model.ResourceRequest{
+		APIVersion: pr.APIVersion,
+		Name:       clone.Name,
+		Spec:       pr.Spec.WithOverrides(clone.Spec),
+	}
+	h.defaults.Apply(targetNamespace, &req)
+	if err := req.ConvertToCurrent(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "%v", err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	resp, err := h.applyResourceWith(r.Context(), targetNamespace, &req, applyOptions{create: true})
+	if err != nil {
+		writeApplyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, resp)
+	log.Printf("Cloned resource %s/%s to %s/%s (version=%s)", namespace, name, targetNamespace, clone.Name, resp.Version)
+}
+
+// GetResourceFlux handles GET /api/v1/resources/{name}/flux.
+// It renders the OCIRepository/Kustomization pair for a resource's own bundle.
+func (h *Handler) GetResourceFlux(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	namespace, ok := resourceNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.catalog.PerResourceArtifacts() {
+		writeError(w, http.StatusNotFound, "per-resource artifacts are not enabled")
+		return
+	}
+
+	if _, ok := h.catalog.Get(namespace, name); !ok {
+		writeError(w, http.StatusNotFound, "resource %q not found", name)
+		return
+	}
+
+	out, err := renderFluxObjects(namespace, name, h.ociClient.ResourceBundleURL(namespace, name))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "rendering flux objects: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
+
+// resourceResponse describes a live resource from its stored manifest and
+// registry metadata.
+func resourceResponse(namespace, name string, manifest []byte, meta ResourceMeta) model.ResourceResponse {
+	resp := model.ResourceResponse{
+		Name:       name,
+		Namespace:  namespace,
+		Version:    meta.Version,
+		Digest:     meta.Digest,
+		Repository: fmt.Sprintf("gitops-squared/resources/%s/%s", namespace, name),
+	}
+	if !meta.CreatedAt.IsZero() {
+		resp.CreatedAt = meta.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	if !meta.UpdatedAt.IsZero() {
+		resp.UpdatedAt = meta.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	resp.Cost = meta.Cost
+	resp.Images = meta.Images
+	if !meta.ExpiresAt.IsZero() {
+		resp.ExpiresAt = meta.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+
+	// Parse the stored YAML to extract the spec.
+	var pr model.PlatformResource
+	if err := yaml.Unmarshal(manifest, &pr); err == nil {
+		resp.Spec = pr.Spec
+		resp.Labels = pr.Metadata.Labels
+		resp.Generation = model.ParseManifestAnnotations(pr.Metadata.Annotations).Generation
+	}
+	return resp
+}
+
+// deletedResponse describes a soft-deleted resource and its restore deadline.
+func (h *Handler) deletedResponse(namespace, name string, deletedAt time.Time) model.ResourceResponse {
+	resp := model.ResourceResponse{
+		Name:      name,
+		Namespace: namespace,
+		Deleted:   true,
+		DeletedAt: deletedAt.UTC().Format(time.RFC3339),
+	}
+	if grace := h.catalog.GracePeriod(); grace > 0 {
+		resp.RestorableUntil = deletedAt.Add(grace).UTC().Format(time.RFC3339)
+	}
+	return resp
+}
+
+// GetCatalog handles GET /api/v1/catalog.
+// It reports the last published catalog digest so clusters can pin to it.
+// With ?selector=, it serves a tarball of the matching resources instead;
+// see serveCatalogSubset.
+func (h *Handler) GetCatalog(w http.ResponseWriter, r *http.Request) {
+	if selector := r.URL.Query().Get("selector"); selector != "" {
+		h.serveCatalogSubset(w, r, selector)
+		return
+	}
+	status := h.catalog.Status()
+	if status.Digest == "" {
+		writeError(w, http.StatusServiceUnavailable, "catalog has not been published yet")
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// GetCatalogContents handles GET /api/v1/catalog/contents.
+// It lists every file in the last published tarball with its size and digest.
+func (h *Handler) GetCatalogContents(w http.ResponseWriter, _ *http.Request) {
+	digest, files, err := h.catalog.Contents()
+	if errors.Is(err, ErrCatalogNotPublished) {
+		writeError(w, http.StatusServiceUnavailable, "%v", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	resp := model.CatalogContentsResponse{
+		Digest: digest,
+		Files:  make([]model.CatalogFile, 0, len(files)),
+	}
+	for _, f := range files {
+		resp.Files = append(resp.Files, model.CatalogFile{
+			Name:   f.name,
+			Size:   len(f.data),
+			Digest: fmt.Sprintf("sha256:%x", sha256.Sum256(f.data)),
+		})
+		resp.TotalSize += len(f.data)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetCatalogFile handles GET /api/v1/catalog/contents/{file...}.
+// The file may be given by its full tarball path or by its base name.
+func (h *Handler) GetCatalogFile(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("file")
+
+	_, files, err := h.catalog.Contents()
+	if errors.Is(err, ErrCatalogNotPublished) {
+		writeError(w, http.StatusServiceUnavailable, "%v", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	for _, f := range files {
+		if f.name == name || path.Base(f.name) == name {
+			w.Header().Set("Content-Type", "application/yaml")
+			w.WriteHeader(http.StatusOK)
+			w.Write(f.data)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "file %q not found in catalog", name)
+}
+
+// DownloadCatalog handles GET /api/v1/catalog/download.
+// It serves the last published tarball, byte-identical to the registry
+// artifact layer, so it can be mirrored without registry credentials.
+func (h *Handler) DownloadCatalog(w http.ResponseWriter, r *http.Request) {
+	digest, tarGz, err := h.catalog.TarGz()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "%v", err)
+		return
+	}
+	defer tarGz.Close()
+
+	filename := "catalog.tar.gz"
+	if version := h.catalog.Status().Version; version != "" {
+		filename = "catalog-" + version + ".tar.gz"
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("ETag", `"`+digest+`"`)
+	w.Header().Set("X-Catalog-Digest", digest)
+	http.ServeContent(w, r, filename, time.Time{}, tarGz.Reader())
+}
+
+// GetCatalogHistory handles GET /api/v1/catalog/history.
+func (h *Handler) GetCatalogHistory(w http.ResponseWriter, r *http.Request) {
+	versions, err := h.ociClient.ListCatalogVersions(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "listing catalog history: %v", err)
+		return
+	}
+
+	current := h.catalog.Status().Digest
+	history := make([]model.CatalogVersionResponse, 0, len(versions))
+	for _, v := range versions {
+		history = append(history, model.CatalogVersionResponse{
+			Version:   v.Version,
+			Digest:    v.Digest,
+			CreatedAt: v.CreatedAt,
+			Current:   v.Digest == current,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"versions": history,
+		"count":    len(history),
+	})
+}
+
+// GetCatalogProvenance handles GET /api/v1/catalog/provenance.
+// It returns the resource artifacts behind the last published catalog, or
+// behind an earlier one selected by ?version= or ?digest=.
+func (h *Handler) GetCatalogProvenance(w http.ResponseWriter, r *http.Request) {
+	reference := r.URL.Query().Get("digest")
+	if version := r.URL.Query().Get("version"); version != "" {
+		if reference != "" {
+			writeError(w, http.StatusBadRequest, "set only one of version or digest")
+			return
+		}
+		reference = version
+	}
+
+	if reference == "" {
+		provenance, ok := h.catalog.Provenance()
+		if !ok {
+			writeError(w, http.StatusServiceUnavailable, "catalog has not been published yet")
+			return
+		}
+		writeJSON(w, http.StatusOK, provenance)
+		return
+	}
+
+	data, digest, err := h.ociClient.PullCatalogProvenance(r.Context(), reference)
+	if errors.Is(err, errdef.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "catalog %q not found", reference)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	if data == nil {
+		writeError(w, http.StatusNotFound, "catalog %s has no provenance; it was published before provenance was recorded", digest)
+		return
+	}
+	var provenance model.CatalogProvenance
+	if err := json.Unmarshal(data, &provenance); err != nil {
+		writeError(w, http.StatusInternalServerError, "parsing catalog provenance: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, provenance)
+}
+
+// GetResourceHistory handles GET /api/v1/resources/{name}/history.
+// It walks the resource's parent-digest chain in the registry, so it also
+// works for deleted resources.
+func (h *Handler) GetResourceHistory(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	namespace, ok := resourceNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.ociClient.ResourceHistory(r.Context(), namespace, name)
+	if err != nil && len(versions) == 0 {
+		if errors.Is(err, errdef.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "resource %q not found", name)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "reading history: %v", err)
+		return
+	}
+
+	resp := model.ResourceHistoryResponse{
+		Name:      name,
+		Namespace: namespace,
+		Versions:  make([]model.ResourceVersionResponse, 0, len(versions)),
+		Count:     len(versions),
+		Verified:  err == nil,
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	for _, v := range versions {
+		resp.Versions = append(resp.Versions, model.ResourceVersionResponse{
+			Version:   v.Version,
+			Digest:    v.Digest,
+			Parent:    v.Parent,
+			CreatedAt: v.CreatedAt,
+			Deleted:   v.Deleted,
+			ChangedBy: v.ChangedBy,
+			Source:    v.Source,
+			UserAgent: v.UserAgent,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Healthz handles GET /healthz.
+func (h *Handler) Healthz(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// WhoAmI handles GET /api/v1/whoami.
+// It returns the caller's identity as established by the auth middleware.
+func (h *Handler) WhoAmI(w http.ResponseWriter, r *http.Request) {
+	id, ok := auth.FromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	writeJSON(w, http.StatusOK, id)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// resourceNamespace returns the namespace selected with ?namespace=,
+// defaulting to "default". It writes a 400 and returns false if invalid.
+func resourceNamespace(w http.ResponseWriter, r *http.Request) (string, bool) {
+	namespace := r.URL.Query().Get("namespace")
+	if namespace == "" {
+		return defaultNamespace, true
+	}
+	if err := model.ValidateNamespace(namespace); err != nil {
+		writeValidationError(w, err)
+		return "", false
+	}
+	return namespace, true
+}
+
+// writeValidationError writes a 400 listing each invalid field.
+func writeValidationError(w http.ResponseWriter, err error) {
+	var invalid *model.ValidationError
+	if !errors.As(err, &invalid) {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]any{
+		"error":   invalid.Error(),
+		"code":    model.CodeValidationFailed,
+		"details": invalid.Errors,
+	})
+}
+
+// writeApplyError maps an applyResource error to a response status:
+// conflicts and existing resources are 409 with the current version, locks 423 with the lock,
+// cooldowns 429 with the time of the next allowed change, cluster and schema rejections are the
+// caller's fault, everything else is ours.
+func writeApplyError(w http.ResponseWriter, err error) {
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		writeJSON(w, http.StatusConflict, map[string]string{
+			"error":          err.Error(),
+			"code":           model.CodeConflict,
+			"currentVersion": conflict.CurrentVersion,
+			"currentDigest":  conflict.CurrentDigest,
+		})
+		return
+	}
+	var exists *ExistsError
+	if errors.As(err, &exists) {
+		writeJSON(w, http.StatusConflict, map[string]string{
+			"error":          err.Error(),
+			"code":           model.CodeAlreadyExists,
+			"currentVersion": exists.CurrentVersion,
+			"currentDigest":  exists.CurrentDigest,
+		})
+		return
+	}
+	var locked *LockedError
+	if errors.As(err, &locked) {
+		writeLockedError(w, locked)
+		return
+	}
+	var outOfScope *ScopeError
+	if errors.As(err, &outOfScope) {
+		writeErrorCode(w, http.StatusForbidden, model.CodeOutOfScope, "%v", err)
+		return
+	}
+	var cooldown *CooldownError
+	if errors.As(err, &cooldown) {
+		writeCooldownError(w, cooldown)
+		return
+	}
+	var linted *model.ValidationError
+	if errors.As(err, &linted) {
+		writeValidationError(w, err)
+		return
+	}
+	if code := rejectionCode(err); code != "" {
+		writeErrorCode(w, http.StatusUnprocessableEntity, code, "%v", err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "%v", err)
+}
+
+// rejectionCode returns the error code of a write rejected by a check
+// outside the API's own validation, or "" for other errors.
+func rejectionCode(err error) string {
+	var rejected *kube.RejectedError
+	var invalid *kube.SchemaError
+	var denied *admission.DeniedError
+	var unresolvable *images.ImageError
+	var missing *secrets.ResolveError
+	switch {
+	case errors.As(err, &rejected):
+		return model.CodeClusterRejected
+	case errors.As(err, &invalid):
+		return model.CodeSchemaInvalid
+	case errors.As(err, &denied):
+		return model.CodeAdmissionDenied
+	case errors.As(err, &unresolvable):
+		return model.CodeImageUnresolvable
+	case errors.As(err, &missing):
+		return model.CodeSecretUnresolvable
+	}
+	return ""
+}
+
+// writeError writes a JSON error with the code of its status. Server errors
+// caused by a deadline become 504 Gateway Timeout.
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeErrorCode(w, status, model.StatusCode(status), format, args...)
+}
+
+// writeErrorCode writes a JSON error with a more specific code than its
+// status's.
+func writeErrorCode(w http.ResponseWriter, status int, code, format string, args ...any) {
+	if status >= http.StatusInternalServerError && timedOut(args) {
+		writeTimeout(w, fmt.Sprintf(format, args...))
+		return
+	}
+	writeJSON(w, status, map[string]string{
+		"error": fmt.Sprintf(format, args...),
+		"code":  code,
+	})
+}
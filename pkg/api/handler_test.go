@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci/ocitest"
+)
+
+// serve sends a request as an admin and returns the response.
+func serve(t *testing.T, mux *http.ServeMux, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(auth.WithIdentity(context.Background(), auth.Identity{User: "alice", Groups: []string{"admins"}}))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// Restoring a soft-deleted resource keeps its expiry and runs the write
+// path's checks, so a region removed from the catalog since the delete
+// can't come back.
+func TestRestoreResource(t *testing.T) {
+	client, _ := ocitest.NewClient("gitops-squared/resources")
+	h := NewHandler(client, NewCatalogManager(client, CatalogOptions{DeleteGracePeriod: time.Hour}), HandlerOptions{AdminGroups: []string{"admins"}})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for _, name := range []string{"db", "cache"} {
+		body := `{"name": "` + name + `", "ttl": "72h", "spec": {"type": "database", "size": "small", "region": "us-east"}}`
+		if rec := serve(t, mux, http.MethodPost, "/api/v1/resources", body); rec.Code != http.StatusCreated {
+			t.Fatalf("creating %s: status %d: %s", name, rec.Code, rec.Body)
+		}
+		if rec := serve(t, mux, http.MethodDelete, "/api/v1/resources/"+name, ""); rec.Code != http.StatusOK {
+			t.Fatalf("deleting %s: status %d: %s", name, rec.Code, rec.Body)
+		}
+	}
+
+	rec := serve(t, mux, http.MethodPost, "/api/v1/resources/db/restore", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("restoring db: status %d: %s", rec.Code, rec.Body)
+	}
+	var resp model.ResourceResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ExpiresAt == "" {
+		t.Errorf("restored db lost its expiry: %s", rec.Body)
+	}
+
+	if rec := serve(t, mux, http.MethodPost, "/api/v1/admin/regions", `{"name": "eu-west"}`); rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("adding a region: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(t, mux, http.MethodPost, "/api/v1/resources/cache/restore", ""); rec.Code != http.StatusUnprocessableEntity && rec.Code != http.StatusBadRequest {
+		t.Errorf("restoring cache into a region that is no longer available: status %d, want a rejection: %s", rec.Code, rec.Body)
+	}
+	if _, ok := h.catalog.Get(defaultNamespace, "cache"); ok {
+		t.Error("cache was restored into a region that is no longer available")
+	}
+}
+
+// Patch bodies are capped like edited manifests.
+func TestPatchResourceTooLarge(t *testing.T) {
+	client, _ := ocitest.NewClient("gitops-squared/resources")
+	h := NewHandler(client, NewCatalogManager(client, CatalogOptions{}), HandlerOptions{})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	if rec := serve(t, mux, http.MethodPost, "/api/v1/resources", `{"name": "db", "spec": {"type": "database", "size": "small"}}`); rec.Code != http.StatusCreated {
+		t.Fatalf("creating db: status %d: %s", rec.Code, rec.Body)
+	}
+
+	body := `{"spec": {"region": "` + strings.Repeat("a", maxPatchSize) + `"}}`
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/resources/db", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized patch: status %d, want %d: %.200s", rec.Code, http.StatusRequestEntityTooLarge, rec.Body)
+	}
+}
+
+// The delete grace period can't drop to or below a scheduled deletion
+// window, and a window that outlived a shorter grace period doesn't open.
+func TestGracePeriodCoversDeleteWindows(t *testing.T) {
+	client, _ := ocitest.NewClient("gitops-squared/resources")
+	h := NewHandler(client, NewCatalogManager(client, CatalogOptions{DeleteGracePeriod: 24 * time.Hour}), HandlerOptions{})
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	if rec := serve(t, mux, http.MethodPost, "/api/v1/resources", `{"name": "db", "spec": {"type": "database", "size": "small"}}`); rec.Code != http.StatusCreated {
+		t.Fatalf("creating db: status %d: %s", rec.Code, rec.Body)
+	}
+	body := `{"name": "nightly-off", "cron": "0 22 * * *", "action": "delete", "duration": "9h"}`
+	if rec := serve(t, mux, http.MethodPost, "/api/v1/resources/db/schedule", body); rec.Code != http.StatusCreated {
+		t.Fatalf("creating the schedule: status %d: %s", rec.Code, rec.Body)
+	}
+
+	for _, grace := range []string{"1h", "9h"} {
+		if rec := serve(t, mux, http.MethodPut, "/api/v1/admin/settings", `{"deleteGracePeriod": "`+grace+`"}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("lowering the grace period to %s: status %d, want 400: %s", grace, rec.Code, rec.Body)
+		}
+	}
+	if got := h.catalog.GracePeriod(); got != 24*time.Hour {
+		t.Fatalf("grace period changed to %s by a rejected update", got)
+	}
+	if rec := serve(t, mux, http.MethodPut, "/api/v1/admin/settings", `{"deleteGracePeriod": "10h"}`); rec.Code != http.StatusOK {
+		t.Fatalf("lowering the grace period to 10h: status %d: %s", rec.Code, rec.Body)
+	}
+
+	// As after a restart with a smaller DELETE_GRACE_PERIOD.
+	h.catalog.SetGracePeriod(time.Hour)
+	s := h.schedules.List("", "")[0]
+	h.runDueSchedules(context.Background(), s.NextRun)
+	if _, ok := h.catalog.Get(defaultNamespace, "db"); !ok {
+		t.Error("db was deleted for a window longer than the grace period")
+	}
+	if s := h.schedules.List("", "")[0]; s.LastError == "" || !s.RestoreAt.IsZero() {
+		t.Errorf("schedule after the refused run: lastError %q, restoreAt %s", s.LastError, s.RestoreAt)
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/hooks"
+)
+
+// runPrePublishHooks runs the pre-publish hooks on the manifests of the
+// catalog about to be published, named as in its manifests/ directory. It
+// returns the first failure.
+func (cm *CatalogManager) runPrePublishHooks(ctx context.Context, resources, namespaces map[string][]byte) error {
+	if cm.hooks == nil || !cm.hooks.Has(hooks.StagePre) {
+		return nil
+	}
+	manifests := make(map[string][]byte, len(resources)+len(namespaces))
+	for name, manifest := range namespaces {
+		manifests[namespaceManifestDir+name+".yaml"] = manifest
+	}
+	for key, manifest := range resources {
+		manifests[resourceManifestPath(key)] = manifest
+	}
+
+	results, err := cm.hooks.PrePublish(ctx, manifests, len(resources))
+	for _, r := range results {
+		if r.Err == nil {
+			log.Printf("Pre-publish hook %s passed in %s", r.Hook, r.Duration.Round(time.Millisecond))
+		}
+	}
+	return err
+}
+
+// runPostPublishHooks runs the post-publish hooks in the background, so
+// slow hooks don't hold up the caller. Failures are logged.
+func (cm *CatalogManager) runPostPublishHooks(ctx context.Context, digest, version string, resources int) {
+	if cm.hooks == nil || !cm.hooks.Has(hooks.StagePost) {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		for _, r := range cm.hooks.PostPublish(ctx, digest, version, resources) {
+			if r.Err != nil {
+				log.Printf("Warning: post-publish hook %s failed for catalog %s: %v", r.Hook, version, r.Err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// resolveImages resolves the image parameters of req against their
+// registries, if image verification is configured, and returns them pinned
+// to their digests as the value of oci.AnnotationResourceImages. It returns
+// "" if there is nothing to record.
+func (h *Handler) resolveImages(ctx context.Context, namespace string, req *model.ResourceRequest) (string, error) {
+	if h.images == nil {
+		return "", nil
+	}
+	refs := req.Spec.Images()
+	if len(refs) == 0 {
+		return "", nil
+	}
+
+	resolved, warnings, err := h.images.ResolveAll(ctx, refs)
+	for _, warning := range warnings {
+		log.Printf("Warning: not verifying image of %s/%s: %s", namespace, req.Name, warning)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(resolved) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(resolved)
+	if err != nil {
+		return "", fmt.Errorf("recording images: %w", err)
+	}
+	return string(data), nil
+}
+
+// imagesFromAnnotations reads resolved images back from resource
+// annotations.
+func imagesFromAnnotations(annotations map[string]string) map[string]string {
+	v, ok := annotations[oci.AnnotationResourceImages]
+	if !ok {
+		return nil
+	}
+	var resolved map[string]string
+	if err := json.Unmarshal([]byte(v), &resolved); err != nil {
+		return nil
+	}
+	return resolved
+}
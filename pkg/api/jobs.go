@@ -0,0 +1,394 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// Default job limits.
+const (
+	defaultJobConcurrency = 2
+	defaultJobRetention   = 100
+)
+
+// JobOptions configures a JobManager.
+type JobOptions struct {
+	// Concurrency is how many jobs run at once; later jobs queue.
+	Concurrency int
+
+	// Retain is how many finished jobs are kept in memory and restored on
+	// startup. Older records stay in the registry.
+	Retain int
+}
+
+// jobFunc runs a job and returns its result. ctx is cancelled when the job
+// is.
+type jobFunc func(ctx context.Context) (any, error)
+
+// job is a job record and the state needed to run it.
+type job struct {
+	rec      model.Job
+	cancel   context.CancelFunc
+	progress *requestProgress
+}
+
+// view returns the job record with its current progress.
+func (j *job) view() model.Job {
+	rec := j.rec
+	if j.progress != nil {
+		j.progress.mu.Lock()
+		rec.Steps = len(j.progress.steps)
+		if rec.Steps > 0 {
+			rec.LastStep = j.progress.steps[rec.Steps-1]
+		}
+		j.progress.mu.Unlock()
+	}
+	return rec
+}
+
+// JobManager runs long operations in the background, at most Concurrency
+// at a time, and persists a record of each to the registry as it changes
+// state.
+type JobManager struct {
+	ociClient oci.Interface
+	slots     chan struct{}
+	retain    int
+
+	mu   sync.RWMutex
+	jobs map[string]*job
+}
+
+// NewJobManager creates a job manager with no jobs.
+func NewJobManager(client oci.Interface, opts JobOptions) *JobManager {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultJobConcurrency
+	}
+	if opts.Retain <= 0 {
+		opts.Retain = defaultJobRetention
+	}
+	return &JobManager{
+		ociClient: client,
+		slots:     make(chan struct{}, opts.Concurrency),
+		retain:    opts.Retain,
+		jobs:      make(map[string]*job),
+	}
+}
+
+// Start queues run as a job of kind on behalf of the caller of ctx. The
+// job keeps ctx's values, such as the caller's identity, but not its
+// deadline or cancellation.
+func (jm *JobManager) Start(ctx context.Context, kind string, run jobFunc) (model.Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return model.Job{}, fmt.Errorf("generating job ID: %w", err)
+	}
+	progress := &requestProgress{}
+	jobCtx, cancel := context.WithCancel(context.WithValue(context.WithoutCancel(ctx), progressKey{}, progress))
+	j := &job{
+		rec: model.Job{
+			ID:        id,
+			Kind:      kind,
+			Status:    model.JobQueued,
+			Actor:     auth.Actor(ctx),
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		},
+		cancel:   cancel,
+		progress: progress,
+	}
+	if err := jm.persist(ctx, j.rec); err != nil {
+		cancel()
+		return model.Job{}, err
+	}
+
+	rec := j.rec
+	jm.mu.Lock()
+	jm.jobs[id] = j
+	jm.trimLocked()
+	jm.mu.Unlock()
+
+	go jm.run(jobCtx, j, run)
+	return rec, nil
+}
+
+// run waits for a free slot, runs the job and records the outcome.
+func (jm *JobManager) run(ctx context.Context, j *job, run jobFunc) {
+	defer j.cancel()
+
+	select {
+	case jm.slots <- struct{}{}:
+		defer func() { <-jm.slots }()
+	case <-ctx.Done():
+		jm.finish(ctx, j, nil, ctx.Err())
+		return
+	}
+
+	jm.update(ctx, j, func(rec *model.Job) {
+		rec.Status = model.JobRunning
+		rec.StartedAt = time.Now().UTC().Format(time.RFC3339)
+	})
+	log.Printf("Job %s (%s) started by %s", j.rec.ID, j.rec.Kind, j.rec.Actor)
+
+	result, err := run(ctx)
+	jm.finish(ctx, j, result, err)
+}
+
+// finish records a job's result or error.
+func (jm *JobManager) finish(ctx context.Context, j *job, result any, err error) {
+	var data json.RawMessage
+	if result != nil {
+		encoded, encErr := json.Marshal(result)
+		if encErr != nil {
+			err = errors.Join(err, fmt.Errorf("encoding result: %w", encErr))
+		} else {
+			data = encoded
+		}
+	}
+
+	rec := jm.update(ctx, j, func(rec *model.Job) {
+		rec.Result = data
+		rec.FinishedAt = time.Now().UTC().Format(time.RFC3339)
+		switch {
+		case ctx.Err() != nil:
+			rec.Status = model.JobCancelled
+		case err != nil:
+			rec.Status = model.JobFailed
+			rec.Error = err.Error()
+		default:
+			rec.Status = model.JobSucceeded
+		}
+	})
+	log.Printf("Job %s (%s) %s after %d steps", rec.ID, rec.Kind, rec.Status, rec.Steps)
+}
+
+// update changes a job's record and persists it. Persisting is best
+// effort: the job carries on, and the record is pushed again on its next
+// state change.
+func (jm *JobManager) update(ctx context.Context, j *job, change func(rec *model.Job)) model.Job {
+	jm.mu.Lock()
+	change(&j.rec)
+	rec := j.view()
+	jm.mu.Unlock()
+
+	if err := jm.persist(context.WithoutCancel(ctx), rec); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return rec
+}
+
+func (jm *JobManager) persist(ctx context.Context, rec model.Job) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encoding job %s: %w", rec.ID, err)
+	}
+	if err := jm.ociClient.PushJob(ctx, rec.ID, data); err != nil {
+		return fmt.Errorf("pushing job %s: %w", rec.ID, err)
+	}
+	return nil
+}
+
+// Retain returns how many finished jobs are kept.
+func (jm *JobManager) Retain() int {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	return jm.retain
+}
+
+// SetRetain changes how many finished jobs are kept, forgetting the oldest
+// beyond the new limit.
+func (jm *JobManager) SetRetain(n int) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.retain = n
+	jm.trimLocked()
+}
+
+// trimLocked forgets the oldest finished jobs beyond the retention limit.
+func (jm *JobManager) trimLocked() {
+	var finished []string
+	for id, j := range jm.jobs {
+		if j.rec.Finished() {
+			finished = append(finished, id)
+		}
+	}
+	if len(finished) <= jm.retain {
+		return
+	}
+	sort.Strings(finished)
+	for _, id := range finished[:len(finished)-jm.retain] {
+		delete(jm.jobs, id)
+	}
+}
+
+// Get returns a job by ID.
+func (jm *JobManager) Get(id string) (model.Job, bool) {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	j, ok := jm.jobs[id]
+	if !ok {
+		return model.Job{}, false
+	}
+	return j.view(), true
+}
+
+// List returns the jobs of kind and with status, newest first. Empty
+// arguments match every job.
+func (jm *JobManager) List(kind, status string) []model.Job {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	list := make([]model.Job, 0, len(jm.jobs))
+	for _, j := range jm.jobs {
+		if (kind == "" || j.rec.Kind == kind) && (status == "" || j.rec.Status == status) {
+			list = append(list, j.view())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	return list
+}
+
+// Errors returned by JobManager.Cancel.
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobFinished = errors.New("job already finished")
+)
+
+// Cancel cancels a queued or running job. The job stops at its next
+// cancellation check; steps it already completed are not undone.
+func (jm *JobManager) Cancel(id string) (model.Job, error) {
+	jm.mu.RLock()
+	j, ok := jm.jobs[id]
+	var rec model.Job
+	if ok {
+		rec = j.view()
+	}
+	jm.mu.RUnlock()
+	if !ok {
+		return model.Job{}, ErrJobNotFound
+	}
+	if rec.Finished() {
+		return rec, ErrJobFinished
+	}
+	j.cancel()
+	return rec, nil
+}
+
+// Restore loads the newest job records from the registry. Jobs that were
+// queued or running when the server stopped are marked failed.
+func (jm *JobManager) Restore(ctx context.Context) error {
+	records, err := jm.ociClient.PullJobs(ctx, jm.Retain())
+	if err != nil {
+		return fmt.Errorf("pulling jobs: %w", err)
+	}
+
+	interrupted := 0
+	for _, data := range records {
+		var rec model.Job
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return fmt.Errorf("parsing job: %w", err)
+		}
+		if !rec.Finished() {
+			rec.Status = model.JobFailed
+			rec.Error = "interrupted by a server restart"
+			rec.FinishedAt = time.Now().UTC().Format(time.RFC3339)
+			if err := jm.persist(ctx, rec); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			interrupted++
+		}
+		jm.mu.Lock()
+		jm.jobs[rec.ID] = &job{rec: rec}
+		jm.mu.Unlock()
+	}
+	log.Printf("Restored %d jobs from registry (%d interrupted)", len(records), interrupted)
+	return nil
+}
+
+// newJobID returns a job ID that sorts in creation order.
+func newJobID() (string, error) {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(b[:]), nil
+}
+
+// async reports whether a request asked to run as a background job with
+// ?async=true.
+func async(r *http.Request) bool {
+	return r.URL.Query().Get("async") == "true"
+}
+
+// startJob runs an admin operation as a background job and responds 202
+// Accepted with the job, which GET /api/v1/jobs/{id} reports on.
+func (h *Handler) startJob(w http.ResponseWriter, r *http.Request, kind string, run jobFunc) {
+	j, err := h.jobs.Start(r.Context(), kind, run)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "starting job: %v", err)
+		return
+	}
+	log.Printf("Audit: %s job %s queued by %s", kind, j.ID, j.Actor)
+	w.Header().Set("Location", "/api/v1/jobs/"+j.ID)
+	writeJSON(w, http.StatusAccepted, j)
+}
+
+// ListJobs handles GET /api/v1/jobs.
+// ?kind= and ?status= filter the list.
+func (h *Handler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	jobs := h.jobs.List(r.URL.Query().Get("kind"), r.URL.Query().Get("status"))
+	writeJSON(w, http.StatusOK, map[string]any{
+		"jobs":  jobs,
+		"count": len(jobs),
+	})
+}
+
+// GetJob handles GET /api/v1/jobs/{id}.
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	j, ok := h.jobs.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "job %q not found", id)
+		return
+	}
+	writeJSON(w, http.StatusOK, j)
+}
+
+// CancelJob handles POST /api/v1/jobs/{id}/cancel.
+// Only the caller who started the job and admins may cancel it.
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	j, ok := h.jobs.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "job %q not found", id)
+		return
+	}
+	caller, ok := auth.FromContext(r.Context())
+	if !(ok && caller.User == j.Actor) && !h.inAdminGroup(r.Context()) {
+		log.Printf("Warning: %s denied cancelling job %s (%s) started by %s", auth.Actor(r.Context()), j.ID, j.Kind, j.Actor)
+		writeErrorCode(w, http.StatusForbidden, model.CodeForbidden, "job %q was started by %s; only they or an admin may cancel it", id, j.Actor)
+		return
+	}
+
+	j, err := h.jobs.Cancel(id)
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		writeError(w, http.StatusNotFound, "job %q not found", id)
+		return
+	case errors.Is(err, ErrJobFinished):
+		writeError(w, http.StatusConflict, "job %q already %s", id, j.Status)
+		return
+	}
+	log.Printf("Audit: job %s (%s) cancelled by %s", j.ID, j.Kind, auth.Actor(r.Context()))
+	writeJSON(w, http.StatusAccepted, j)
+}
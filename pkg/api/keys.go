@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// Key purposes.
+const (
+	KeySigning    = "signing"
+	KeyEncryption = "encryption"
+)
+
+// ManagedKey is a signing or encryption key the admin API reports and
+// rotates. It is satisfied by *signing.Signer and *encryption.Keyring
+// (file keys) and by *kms.Signer and *kms.DataKeys.
+type ManagedKey interface {
+	// Backend returns where the key is kept: file, awskms, gcpkms or vault.
+	Backend() string
+
+	// KeyID returns the key (version) new signatures or artifacts use.
+	KeyID() string
+
+	// Rotate switches to a new key version and returns its ID. File keys
+	// are reloaded from disk; KMS keys get a new version in the service.
+	// Errors wrap errors.ErrUnsupported if the key can't be rotated.
+	Rotate(ctx context.Context) (string, error)
+}
+
+// GetKeys handles GET /api/v1/admin/keys.
+func (h *Handler) GetKeys(w http.ResponseWriter, _ *http.Request) {
+	resp := model.KeysResponse{Keys: []model.KeyStatus{}}
+	for _, purpose := range []string{KeySigning, KeyEncryption} {
+		if key := h.keys[purpose]; key != nil {
+			resp.Keys = append(resp.Keys, model.KeyStatus{Purpose: purpose, Backend: key.Backend(), KeyID: key.KeyID()})
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RotateKey handles POST /api/v1/admin/keys/{purpose}/rotate.
+// New signatures or artifacts use the new key version at once. Existing
+// ones keep theirs, which stays usable; POST /api/v1/admin/reencrypt
+// rewrites artifacts with the new encryption key.
+func (h *Handler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	purpose := r.PathValue("purpose")
+	key := h.keys[purpose]
+	if key == nil {
+		writeError(w, http.StatusNotFound, "no %s key is configured", purpose)
+		return
+	}
+
+	previous := key.KeyID()
+	keyID, err := key.Rotate(r.Context())
+	if errors.Is(err, errors.ErrUnsupported) {
+		writeError(w, http.StatusConflict, "rotating %s key: %v", purpose, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "rotating %s key: %v", purpose, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, model.KeyRotationResponse{
+		KeyStatus:     model.KeyStatus{Purpose: purpose, Backend: key.Backend(), KeyID: keyID},
+		PreviousKeyID: previous,
+	})
+	log.Printf("Audit: %s key rotated from %s to %s by %s", purpose, previous, keyID, auth.Actor(r.Context()))
+}
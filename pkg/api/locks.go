@@ -0,0 +1,299 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// LockedError is returned for a change to a resource locked by someone
+// else.
+type LockedError struct {
+	Lock model.ResourceLock
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s/%s is locked by %s since %s: %s", e.Lock.Namespace, e.Lock.Name, e.Lock.Holder, e.Lock.LockedAt, e.Lock.Reason)
+}
+
+// errNotLocked is returned by LockStore.Unlock for a resource without a
+// lock.
+var errNotLocked = errors.New("resource is not locked")
+
+// LockStore holds resource locks in memory and persists them to the
+// registry as a single JSON document on every change.
+type LockStore struct {
+	ociClient oci.Interface
+	mu        sync.RWMutex
+	locks     map[string]model.ResourceLock // "namespace/name" -> lock
+}
+
+// NewLockStore creates an empty lock store.
+func NewLockStore(client oci.Interface) *LockStore {
+	return &LockStore{
+		ociClient: client,
+		locks:     make(map[string]model.ResourceLock),
+	}
+}
+
+// Get returns the lock on a resource, if any.
+func (ls *LockStore) Get(namespace, name string) (model.ResourceLock, bool) {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	l, ok := ls.locks[namespace+"/"+name]
+	return l, ok
+}
+
+// List returns the locks in namespace, or all locks if namespace is empty,
+// sorted by namespace and name.
+func (ls *LockStore) List(namespace string) []model.ResourceLock {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	list := make([]model.ResourceLock, 0, len(ls.locks))
+	for _, l := range ls.locks {
+		if namespace == "" || l.Namespace == namespace {
+			list = append(list, l)
+		}
+	}
+	sortLocks(list)
+	return list
+}
+
+// Lock takes or renews the lock l and persists the set. It fails with a
+// *LockedError if someone other than l.Holder holds the lock already. It
+// reports whether the lock is new.
+func (ls *LockStore) Lock(ctx context.Context, l model.ResourceLock) (bool, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	key := l.Namespace + "/" + l.Name
+	prev, existed := ls.locks[key]
+	if existed && prev.Holder != l.Holder {
+		return false, &LockedError{Lock: prev}
+	}
+	ls.locks[key] = l
+	if err := ls.persistLocked(ctx); err != nil {
+		if existed {
+			ls.locks[key] = prev
+		} else {
+			delete(ls.locks, key)
+		}
+		return false, err
+	}
+	return !existed, nil
+}
+
+// Unlock releases the lock on a resource and persists the set. Only the
+// holder may release it, unless force is set. It returns the released lock,
+// errNotLocked, or a *LockedError if caller doesn't hold the lock.
+func (ls *LockStore) Unlock(ctx context.Context, namespace, name, caller string, force bool) (model.ResourceLock, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	key := namespace + "/" + name
+	l, ok := ls.locks[key]
+	if !ok {
+		return model.ResourceLock{}, errNotLocked
+	}
+	if l.Holder != caller && !force {
+		return model.ResourceLock{}, &LockedError{Lock: l}
+	}
+	delete(ls.locks, key)
+	if err := ls.persistLocked(ctx); err != nil {
+		ls.locks[key] = l
+		return model.ResourceLock{}, err
+	}
+	return l, nil
+}
+
+func (ls *LockStore) persistLocked(ctx context.Context) error {
+	list := make([]model.ResourceLock, 0, len(ls.locks))
+	for _, l := range ls.locks {
+		list = append(list, l)
+	}
+	sortLocks(list)
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("encoding resource locks: %w", err)
+	}
+	if err := ls.ociClient.PushLocks(ctx, data); err != nil {
+		return fmt.Errorf("pushing resource locks: %w", err)
+	}
+	return nil
+}
+
+// Restore loads resource locks from the registry.
+func (ls *LockStore) Restore(ctx context.Context) error {
+	data, err := ls.ociClient.PullLocks(ctx)
+	if err != nil {
+		return fmt.Errorf("pulling resource locks: %w", err)
+	}
+	if data == nil {
+		return nil
+	}
+
+	var list []model.ResourceLock
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("parsing resource locks: %w", err)
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	for _, l := range list {
+		ls.locks[l.Namespace+"/"+l.Name] = l
+	}
+	log.Printf("Restored %d resource locks from registry", len(list))
+	return nil
+}
+
+func sortLocks(list []model.ResourceLock) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Namespace != list[j].Namespace {
+			return list[i].Namespace < list[j].Namespace
+		}
+		return list[i].Name < list[j].Name
+	})
+}
+
+// checkLock returns a *LockedError if a resource is locked by someone
+// other than the caller. Schema migrations only rewrite the stored format,
+// so they pass.
+func (h *Handler) checkLock(ctx context.Context, namespace, name string) error {
+	l, ok := h.locks.Get(namespace, name)
+	if !ok || l.Holder == auth.Actor(ctx) || requestOrigin(ctx).source == SourceMigration {
+		return nil
+	}
+	return &LockedError{Lock: l}
+}
+
+// locked reports whether background jobs must leave a resource alone.
+func (h *Handler) locked(namespace, name string) bool {
+	_, ok := h.locks.Get(namespace, name)
+	return ok
+}
+
+// writeLockedError writes a 423 with the lock.
+func writeLockedError(w http.ResponseWriter, e *LockedError) {
+	writeJSON(w, http.StatusLocked, map[string]any{
+		"error": e.Error(),
+		"code":  model.CodeResourceLocked,
+		"lock":  e.Lock,
+	})
+}
+
+// LockResource handles POST /api/v1/resources/{name}/lock.
+// It locks a live resource for the caller, so that changes by anyone else
+// are rejected with 423 until it is unlocked. The holder may lock it again
+// to change the reason.
+func (h *Handler) LockResource(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	namespace, ok := resourceNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.LockRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if _, ok := h.catalog.Get(namespace, name); !ok {
+		writeError(w, http.StatusNotFound, "resource %q not found", name)
+		return
+	}
+
+	l := model.ResourceLock{
+		Namespace: namespace,
+		Name:      name,
+		Holder:    auth.Actor(r.Context()),
+		Reason:    req.Reason,
+		LockedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+	created, err := h.locks.Lock(r.Context(), l)
+	var locked *LockedError
+	if errors.As(err, &locked) {
+		writeLockedError(w, locked)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, l)
+	log.Printf("Audit: locked %s/%s by %s: %s", namespace, name, l.Holder, l.Reason)
+}
+
+// UnlockResource handles POST /api/v1/resources/{name}/unlock.
+// The holder may release a lock; admins may release anyone's.
+func (h *Handler) UnlockResource(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	namespace, ok := resourceNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	actor := auth.Actor(r.Context())
+	l, err := h.locks.Unlock(r.Context(), namespace, name, actor, h.inAdminGroup(r.Context()))
+	var locked *LockedError
+	switch {
+	case errors.Is(err, errNotLocked):
+		writeError(w, http.StatusNotFound, "resource %q is not locked", name)
+		return
+	case errors.As(err, &locked):
+		writeLockedError(w, locked)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	if l.Holder != actor {
+		log.Printf("Audit: lock on %s/%s held by %s released by %s", namespace, name, l.Holder, actor)
+		return
+	}
+	log.Printf("Audit: unlocked %s/%s by %s", namespace, name, actor)
+}
+
+// GetResourceLock handles GET /api/v1/resources/{name}/lock.
+func (h *Handler) GetResourceLock(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	namespace, ok := resourceNamespace(w, r)
+	if !ok {
+		return
+	}
+	l, ok := h.locks.Get(namespace, name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "resource %q is not locked", name)
+		return
+	}
+	writeJSON(w, http.StatusOK, l)
+}
+
+// ListLocks handles GET /api/v1/locks.
+// With ?namespace= only the locks in that namespace are listed.
+func (h *Handler) ListLocks(w http.ResponseWriter, r *http.Request) {
+	locks := h.locks.List(r.URL.Query().Get("namespace"))
+	writeJSON(w, http.StatusOK, map[string]any{
+		"locks": locks,
+		"count": len(locks),
+	})
+}
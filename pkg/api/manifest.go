@@ -0,0 +1,138 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"sigs.k8s.io/yaml"
+)
+
+// maxManifestSize bounds an edited manifest.
+const maxManifestSize = 1 << 20
+
+// GetResourceManifest handles GET /api/v1/resources/{name}/manifest.
+// It returns the resource's PlatformResource document as stored, for a
+// client to edit and send back to PUT on the same path. The ETag is the
+// resource's digest.
+func (h *Handler) GetResourceManifest(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	namespace, ok := resourceNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	data, ok := h.catalog.Get(namespace, name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "resource %q not found", name)
+		return
+	}
+	meta, _ := h.catalog.Meta(namespace, name)
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("ETag", `"`+meta.Digest+`"`)
+	w.Header().Set("X-Resource-Version", meta.Version)
+	w.WriteHeader(http.StatusOK)
+	w.Write(splitDocuments(data)[0])
+}
+
+// ApplyResourceManifest handles PUT /api/v1/resources/{name}/manifest.
+// The body is an edited PlatformResource document (application/yaml) and
+// If-Match must carry the digest or version it was fetched at, so edits
+// made against a stale copy fail with 409. Only the apiVersion and spec
+// are applied: the rest of the metadata is generated, and the resource's
+// secrets are kept.
+func (h *Handler) ApplyResourceManifest(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	namespace, ok := resourceNamespace(w, r)
+	if !ok || !h.checkFreeze(w, r, namespace) {
+		return
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		writeError(w, http.StatusPreconditionRequired, "If-Match is required: send the ETag of GET /api/v1/resources/%s/manifest", name)
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/yaml" {
+		writeError(w, http.StatusUnsupportedMediaType, "unsupported content type %q: use application/yaml", mediaType)
+		return
+	}
+
+	data, ok := h.catalog.Get(namespace, name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "resource %q not found", name)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxManifestSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "manifest exceeds %d bytes", maxManifestSize)
+			return
+		}
+		writeError(w, http.StatusBadRequest, "reading body: %v", err)
+		return
+	}
+	if docs := splitDocuments(body); len(docs) != 1 {
+		writeError(w, http.StatusBadRequest, "expected a single PlatformResource document, got %d", len(docs))
+		return
+	}
+	doc, err := yaml.YAMLToJSON(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid YAML: %v", err)
+		return
+	}
+	var pr model.PlatformResource
+	if err := unmarshalJSON(r, doc, &pr); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid manifest: %v", err)
+		return
+	}
+
+	var e model.ValidationError
+	if pr.Kind != "PlatformResource" {
+		e.Errors = append(e.Errors, model.NewFieldError("kind", model.CodeWrongKind, model.Params{"kind": "PlatformResource"}))
+	}
+	if pr.Metadata.Name != name {
+		e.Errors = append(e.Errors, model.NewFieldError("metadata.name", model.CodeImmutable, nil))
+	}
+	if pr.Metadata.Namespace != "" && pr.Metadata.Namespace != namespace {
+		e.Errors = append(e.Errors, model.NewFieldError("metadata.namespace", model.CodeImmutable, nil))
+	}
+	if len(e.Errors) > 0 {
+		writeValidationError(w, &e)
+		return
+	}
+
+	req := model.ResourceRequest{APIVersion: pr.APIVersion, Name: name, Spec: pr.Spec}
+	h.defaults.Apply(namespace, &req)
+	if err := req.ConvertToCurrent(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "%v", err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	resp, err := h.applyResourceWith(r.Context(), namespace, &req, applyOptions{
+		extra:         secretDocuments(data),
+		ifMatch:       ifMatch,
+		skipUnchanged: true,
+	})
+	if err != nil {
+		writeApplyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+	if resp.Changed != nil && !*resp.Changed {
+		log.Printf("Resource %s unchanged (version=%s)", name, resp.Version)
+		return
+	}
+	log.Printf("Applied edited manifest of resource %s (version=%s)", name, resp.Version)
+}
@@ -0,0 +1,18 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// ListMessages handles GET /api/v1/messages.
+// It returns the message catalog: the English template of every field
+// error and lint warning code, for clients that translate them.
+func (h *Handler) ListMessages(w http.ResponseWriter, _ *http.Request) {
+	messages := model.Messages()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"messages": messages,
+		"count":    len(messages),
+	})
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"sigs.k8s.io/yaml"
+)
+
+// metrics counts events worth alerting on.
+type metrics struct {
+	panics atomic.Int64
+}
+
+// catalogMetrics records when resources last changed and how the last
+// catalog publish went, so alerts can catch a catalog that falls behind
+// its resources.
+type catalogMetrics struct {
+	mu            sync.Mutex
+	lastChange    time.Time     // latest resource write or delete
+	lastPublish   time.Time     // latest catalog that became current
+	buildDuration time.Duration // build and push of the last published catalog
+	size          int64         // tarball size of the last published catalog
+	failures      int64         // failed publishes
+	changes       int64         // resource writes and deletes, for snapshots
+}
+
+// changed notes a resource write or delete at t.
+func (m *catalogMetrics) changed(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.changes++
+	if t.After(m.lastChange) {
+		m.lastChange = t
+	}
+}
+
+// published notes that a catalog of size bytes became current.
+func (m *catalogMetrics) published(size int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastPublish = time.Now()
+	m.size = size
+}
+
+// built notes how long a publish took and whether it failed.
+func (m *catalogMetrics) built(d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.failures++
+		return
+	}
+	m.buildDuration = d
+}
+
+// unixSeconds formats t for a timestamp gauge; the zero time is 0.
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
+
+// GetMetrics handles GET /metrics.
+// It reports the metrics in the Prometheus text format.
+func (h *Handler) GetMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintf(w, "# HELP gitops_squared_http_panics_total Panics recovered while serving requests.\n")
+	fmt.Fprintf(w, "# TYPE gitops_squared_http_panics_total counter\n")
+	fmt.Fprintf(w, "gitops_squared_http_panics_total %d\n", h.metrics.panics.Load())
+
+	fmt.Fprintf(w, "# HELP gitops_squared_resources Live resources by namespace and type.\n")
+	fmt.Fprintf(w, "# TYPE gitops_squared_resources gauge\n")
+	for _, c := range h.resourceCounts() {
+		fmt.Fprintf(w, "gitops_squared_resources{namespace=%q,type=%q} %d\n", c.namespace, c.resourceType, c.count)
+	}
+
+	m := &h.catalog.metrics
+	m.mu.Lock()
+	lastChange, lastPublish := m.lastChange, m.lastPublish
+	buildDuration, size, failures := m.buildDuration, m.size, m.failures
+	m.mu.Unlock()
+	status := h.catalog.Status()
+
+	fmt.Fprintf(w, "# HELP gitops_squared_resource_last_change_timestamp_seconds Time of the latest resource write or delete.\n")
+	fmt.Fprintf(w, "# TYPE gitops_squared_resource_last_change_timestamp_seconds gauge\n")
+	fmt.Fprintf(w, "gitops_squared_resource_last_change_timestamp_seconds %g\n", unixSeconds(lastChange))
+	fmt.Fprintf(w, "# HELP gitops_squared_catalog_last_publish_timestamp_seconds Time the current catalog was published, or 0 if none has been.\n")
+	fmt.Fprintf(w, "# TYPE gitops_squared_catalog_last_publish_timestamp_seconds gauge\n")
+	fmt.Fprintf(w, "gitops_squared_catalog_last_publish_timestamp_seconds %g\n", unixSeconds(lastPublish))
+	fmt.Fprintf(w, "# HELP gitops_squared_catalog_build_duration_seconds Time taken to build and push the last published catalog.\n")
+	fmt.Fprintf(w, "# TYPE gitops_squared_catalog_build_duration_seconds gauge\n")
+	fmt.Fprintf(w, "gitops_squared_catalog_build_duration_seconds %g\n", buildDuration.Seconds())
+	fmt.Fprintf(w, "# HELP gitops_squared_catalog_size_bytes Size of the current catalog tarball.\n")
+	fmt.Fprintf(w, "# TYPE gitops_squared_catalog_size_bytes gauge\n")
+	fmt.Fprintf(w, "gitops_squared_catalog_size_bytes %d\n", size)
+	fmt.Fprintf(w, "# HELP gitops_squared_catalog_resources Resources in the current catalog.\n")
+	fmt.Fprintf(w, "# TYPE gitops_squared_catalog_resources gauge\n")
+	fmt.Fprintf(w, "gitops_squared_catalog_resources %d\n", status.ResourceCount)
+	fmt.Fprintf(w, "# HELP gitops_squared_catalog_publish_failures_total Catalog publishes that failed.\n")
+	fmt.Fprintf(w, "# TYPE gitops_squared_catalog_publish_failures_total counter\n")
+	fmt.Fprintf(w, "gitops_squared_catalog_publish_failures_total %d\n", failures)
+}
+
+// resourceCount is the number of live resources of a type in a namespace.
+type resourceCount struct {
+	namespace    string
+	resourceType string
+	count        int
+}
+
+// resourceCounts counts live resources by namespace and type, sorted.
+func (h *Handler) resourceCounts() []resourceCount {
+	counts := make(map[[2]string]int)
+	for key, data := range h.catalog.List() {
+		ns, _, _ := strings.Cut(key, "/")
+		var pr model.PlatformResource
+		_ = yaml.Unmarshal(data, &pr)
+		counts[[2]string{ns, pr.Spec.Type}]++
+	}
+
+	list := make([]resourceCount, 0, len(counts))
+	for k, n := range counts {
+		list = append(list, resourceCount{namespace: k[0], resourceType: k[1], count: n})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].namespace != list[j].namespace {
+			return list[i].namespace < list[j].namespace
+		}
+		return list[i].resourceType < list[j].resourceType
+	})
+	return list
+}
@@ -0,0 +1,43 @@
+package api
+
+import "net/http"
+
+// Middleware wraps an http.Handler, for example to authenticate, log or
+// rate-limit requests.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps next in middleware. The first middleware is the outermost:
+// it sees a request first and its response last. Nil entries are skipped.
+func Chain(next http.Handler, middleware ...Middleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		if middleware[i] != nil {
+			next = middleware[i](next)
+		}
+	}
+	return next
+}
+
+// Wrap wraps next, usually the mux the routes are registered on, in the
+// handler's middleware stack: Recover, TrackOrigin, the access log if
+// enabled, CORS if configured, HandlerOptions.Middleware in order, the
+// caller's scopes, then the request deadline of WithTimeouts.
+func (h *Handler) Wrap(next http.Handler) http.Handler {
+	stack := []Middleware{h.Recover, TrackOrigin}
+	// The access log sits outside the configured middleware so it also
+	// sees requests they reject, and learns the caller from noteCaller
+	// inside them.
+	if h.accessLogOpts != nil {
+		routes, _ := next.(*http.ServeMux)
+		stack = append(stack, h.accessLog(routes))
+	}
+	// Preflight requests carry no credentials, so CORS answers them
+	// before authentication.
+	if h.cors != nil {
+		stack = append(stack, h.CORS)
+	}
+	stack = append(stack, h.middleware...)
+	if h.accessLogOpts != nil {
+		stack = append(stack, noteCaller)
+	}
+	return Chain(next, append(stack, h.enforceScopes, h.WithTimeouts)...)
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// NamespaceStore holds managed namespaces in memory and persists them to
+// the registry as a single JSON document on every change. It keeps the
+// catalog's Namespace manifests in sync; callers republish the catalog.
+type NamespaceStore struct {
+	ociClient  oci.Interface
+	catalog    *CatalogManager
+	mu         sync.RWMutex
+	namespaces map[string]model.Namespace
+}
+
+// NewNamespaceStore creates an empty namespace store.
+func NewNamespaceStore(client oci.Interface, catalog *CatalogManager) *NamespaceStore {
+	return &NamespaceStore{
+		ociClient:  client,
+		catalog:    catalog,
+		namespaces: make(map[string]model.Namespace),
+	}
+}
+
+// Get returns a namespace by name.
+func (ns *NamespaceStore) Get(name string) (model.Namespace, bool) {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+	n, ok := ns.namespaces[name]
+	return n, ok
+}
+
+// List returns all namespaces sorted by name.
+func (ns *NamespaceStore) List() []model.Namespace {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+	list := make([]model.Namespace, 0, len(ns.namespaces))
+	for _, n := range ns.namespaces {
+		list = append(list, n)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Put creates or replaces a namespace and persists the set. The creation
+// time of an existing namespace is kept.
+func (ns *NamespaceStore) Put(ctx context.Context, n model.Namespace) (model.Namespace, error) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	prev, existed := ns.namespaces[n.Name]
+	if existed {
+		n.CreatedAt = prev.CreatedAt
+	} else {
+		n.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	ns.namespaces[n.Name] = n
+	if err := ns.persistLocked(ctx); err != nil {
+		if existed {
+			ns.namespaces[n.Name] = prev
+		} else {
+			delete(ns.namespaces, n.Name)
+		}
+		return model.Namespace{}, err
+	}
+	return n, ns.syncCatalogLocked()
+}
+
+// Delete removes a namespace and persists the set. It reports whether the
+// namespace existed.
+func (ns *NamespaceStore) Delete(ctx context.Context, name string) (bool, error) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	prev, ok := ns.namespaces[name]
+	if !ok {
+		return false, nil
+	}
+	delete(ns.namespaces, name)
+	if err := ns.persistLocked(ctx); err != nil {
+		ns.namespaces[name] = prev
+		return true, err
+	}
+	return true, ns.syncCatalogLocked()
+}
+
+func (ns *NamespaceStore) persistLocked(ctx context.Context) error {
+	list := make([]model.Namespace, 0, len(ns.namespaces))
+	for _, n := range ns.namespaces {
+		list = append(list, n)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("encoding namespaces: %w", err)
+	}
+	if err := ns.ociClient.PushNamespaces(ctx, data); err != nil {
+		return fmt.Errorf("pushing namespaces: %w", err)
+	}
+	return nil
+}
+
+// syncCatalogLocked renders every namespace into the catalog.
+func (ns *NamespaceStore) syncCatalogLocked() error {
+	manifests := make(map[string][]byte, len(ns.namespaces))
+	for name, n := range ns.namespaces {
+		manifest, err := n.ToKubernetesYAML()
+		if err != nil {
+			return fmt.Errorf("rendering namespace %s: %w", name, err)
+		}
+		manifests[name] = manifest
+	}
+	ns.catalog.SetNamespaces(manifests)
+	return nil
+}
+
+// Restore loads namespaces from the registry and hands their manifests to
+// the catalog. Call it before the catalog is restored, so the first
+// catalog published after startup still contains them.
+func (ns *NamespaceStore) Restore(ctx context.Context) error {
+	data, err := ns.ociClient.PullNamespaces(ctx)
+	if err != nil {
+		return fmt.Errorf("pulling namespaces: %w", err)
+	}
+	if data == nil {
+		return nil
+	}
+
+	var list []model.Namespace
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("parsing namespaces: %w", err)
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	for _, n := range list {
+		ns.namespaces[n.Name] = n
+	}
+	log.Printf("Restored %d namespaces from registry", len(list))
+	return ns.syncCatalogLocked()
+}
+
+// namespaceExists reports whether resources may be created in namespace:
+// it is managed, is "default", belongs to a preview, or already holds
+// resources from before namespaces were managed.
+func (h *Handler) namespaceExists(namespace string) bool {
+	if namespace == defaultNamespace || strings.HasPrefix(namespace, model.PreviewNamespacePrefix) {
+		return true
+	}
+	if _, ok := h.namespaces.Get(namespace); ok {
+		return true
+	}
+	return h.namespaceResourceCount(namespace) > 0
+}
+
+// checkNamespace writes a 422 and returns false if namespace does not exist.
+func (h *Handler) checkNamespace(w http.ResponseWriter, namespace string) bool {
+	if h.namespaceExists(namespace) {
+		return true
+	}
+	writeError(w, http.StatusUnprocessableEntity, "namespace %q does not exist; create it with POST /api/v1/namespaces", namespace)
+	return false
+}
+
+// namespaceResourceCount counts the live resources in a namespace.
+func (h *Handler) namespaceResourceCount(namespace string) int {
+	count := 0
+	for key := range h.catalog.List() {
+		if ns, _, _ := strings.Cut(key, "/"); ns == namespace {
+			count++
+		}
+	}
+	return count
+}
+
+// CreateNamespace handles POST /api/v1/namespaces.
+// It creates or updates a namespace and republishes the catalog with its
+// Namespace manifest.
+func (h *Handler) CreateNamespace(w http.ResponseWriter, r *http.Request) {
+	var n model.Namespace
+	if err := decodeJSON(r, &n); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	if err := n.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if !h.checkFreeze(w, r, n.Name) {
+		return
+	}
+
+	_, existed := h.namespaces.Get(n.Name)
+	n, err := h.namespaces.Put(r.Context(), n)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	if err := h.catalog.PushCatalog(r.Context()); err != nil {
+		log.Printf("Warning: failed to push catalog: %v", err)
+	}
+
+	status := http.StatusCreated
+	if existed {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, model.NamespaceResponse{Namespace: n, ResourceCount: h.namespaceResourceCount(n.Name)})
+	log.Printf("Saved namespace %s", n.Name)
+}
+
+// ListNamespaces handles GET /api/v1/namespaces.
+func (h *Handler) ListNamespaces(w http.ResponseWriter, _ *http.Request) {
+	namespaces := h.namespaces.List()
+	resp := make([]model.NamespaceResponse, 0, len(namespaces))
+	for _, n := range namespaces {
+		resp = append(resp, model.NamespaceResponse{Namespace: n, ResourceCount: h.namespaceResourceCount(n.Name)})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"namespaces": resp,
+		"count":      len(resp),
+	})
+}
+
+// GetNamespace handles GET /api/v1/namespaces/{namespace}.
+func (h *Handler) GetNamespace(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("namespace")
+	n, ok := h.namespaces.Get(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "namespace %q not found", name)
+		return
+	}
+	writeJSON(w, http.StatusOK, model.NamespaceResponse{Namespace: n, ResourceCount: h.namespaceResourceCount(name)})
+}
+
+// DeleteNamespace handles DELETE /api/v1/namespaces/{namespace}.
+// Only empty namespaces can be deleted; the Namespace manifest is pruned
+// from the catalog.
+func (h *Handler) DeleteNamespace(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("namespace")
+	if _, ok := h.namespaces.Get(name); !ok {
+		writeError(w, http.StatusNotFound, "namespace %q not found", name)
+		return
+	}
+	if !h.checkFreeze(w, r, name) {
+		return
+	}
+	if count := h.namespaceResourceCount(name); count > 0 {
+		writeError(w, http.StatusConflict, "namespace %q still has %d resources", name, count)
+		return
+	}
+
+	if _, err := h.namespaces.Delete(r.Context(), name); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	if err := h.catalog.PushCatalog(r.Context()); err != nil {
+		log.Printf("Warning: failed to push catalog: %v", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("Deleted namespace %s", name)
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/notify"
+)
+
+// RunNotifications sends every event recorded from now on to the
+// configured notifiers until ctx is cancelled. Deliveries are best effort:
+// failures are logged and not retried. If delivery falls behind the event
+// stream, it catches up from the event history.
+func (h *Handler) RunNotifications(ctx context.Context, cfg *notify.Config) {
+	last := h.events.LastID()
+	deliver := func(e model.Event) {
+		if e.ID <= last {
+			return
+		}
+		last = e.ID
+		for _, err := range cfg.Notify(ctx, e) {
+			log.Printf("Warning: event %d (%s): %v", e.ID, e.Type, err)
+		}
+	}
+
+	for ctx.Err() == nil {
+		events, unsubscribe := h.events.Subscribe()
+		for _, e := range h.events.Since(last, time.Time{}) {
+			deliver(e)
+		}
+	stream:
+		for {
+			select {
+			case <-ctx.Done():
+				break stream
+			case e, ok := <-events:
+				if !ok {
+					break stream
+				}
+				deliver(e)
+			}
+		}
+		unsubscribe()
+	}
+}
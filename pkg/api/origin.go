@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// changeSourceHeader lets a client name the channel it is, e.g. "cli" or
+// "terraform". It must be a DNS-1123 label; otherwise the change is
+// recorded as coming from the API.
+const changeSourceHeader = "X-Change-Source"
+
+// sourceName matches a DNS-1123 label.
+var sourceName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// maxUserAgent bounds the user agent recorded on a resource version.
+const maxUserAgent = 256
+
+// Channels a change can come through, recorded on every resource version.
+// Clients of the API may name themselves with X-Change-Source instead of
+// SourceAPI.
+const (
+	SourceAPI        = "api"
+	SourceGitWebhook = "git-webhook"
+	SourceImport     = "import"
+	SourceAdoption   = "adoption"
+	SourceMigration  = "migration"
+	SourcePreview    = "preview"
+	SourceProposal   = "proposal"
+	SourceSchedule   = "schedule"
+	SourceExpiry     = "expiry"
+)
+
+type originKey struct{}
+
+// origin is where an API request came from.
+type origin struct {
+	source    string
+	userAgent string
+}
+
+// TrackOrigin records each request's change source and user agent, so
+// resource versions it pushes are annotated with them. Wrap includes it.
+func TrackOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		o := origin{source: SourceAPI, userAgent: r.UserAgent()}
+		if s := r.Header.Get(changeSourceHeader); s != "" && sourceName.MatchString(s) {
+			o.source = s
+		}
+		if len(o.userAgent) > maxUserAgent || !printableOrSpace(o.userAgent) {
+			o.userAgent = ""
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), originKey{}, o)))
+	})
+}
+
+// requestOrigin returns the origin recorded by TrackOrigin. Outside a
+// request it is the API with no user agent.
+func requestOrigin(ctx context.Context) origin {
+	if o, ok := ctx.Value(originKey{}).(origin); ok {
+		return o
+	}
+	return origin{source: SourceAPI}
+}
+
+func printableOrSpace(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// withSource records that changes made with ctx come through source, for
+// work that doesn't start with an API request or that the API hands off.
+func withSource(ctx context.Context, source string) context.Context {
+	o := requestOrigin(ctx)
+	o.source = source
+	return context.WithValue(ctx, originKey{}, o)
+}
+
+// changeAnnotations records on a resource version who made the change,
+// through which channel and with which client.
+func changeAnnotations(ctx context.Context) map[string]string {
+	o := requestOrigin(ctx)
+	annotations := map[string]string{
+		oci.AnnotationResourceChangedBy:    auth.Actor(ctx),
+		oci.AnnotationResourceChangeSource: o.source,
+	}
+	if o.userAgent != "" {
+		annotations[oci.AnnotationResourceUserAgent] = o.userAgent
+	}
+	return annotations
+}
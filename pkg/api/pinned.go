@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// pinnedManifestDir holds the per-resource Flux objects inside a pinned
+// root catalog's manifests/ directory, as <namespace>/<name>.yaml.
+const pinnedManifestDir = "resources/"
+
+// pushPinnedBundles publishes the bundle of every resource that changed
+// since it was last pushed, and returns the digest of each resource's
+// bundle for the root catalog to pin. Unlike pushBundles, a failure fails
+// the publish: the catalog can't reference a bundle that isn't there.
+// Bundles of removed resources are emptied afterwards by pushBundles.
+func (cm *CatalogManager) pushPinnedBundles(ctx context.Context, resources map[string][]byte) (map[string]string, error) {
+	digests := make(map[string]string, len(resources))
+	pushed := 0
+	for key, manifest := range resources {
+		cm.mu.RLock()
+		published, digest := cm.bundles[key], cm.bundleDigests[key]
+		cm.mu.RUnlock()
+		if digest != "" && bytes.Equal(published, manifest) {
+			digests[key] = digest
+			continue
+		}
+
+		tarGz, err := cm.buildTarGz(map[string][]byte{key: manifest}, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("building bundle for %s: %w", key, err)
+		}
+		namespace, name, _ := strings.Cut(key, "/")
+		digest, _, err = cm.ociClient.PushResourceBundle(ctx, namespace, name, tarGz)
+		tarGz.Close()
+		if err != nil {
+			return nil, fmt.Errorf("pushing bundle for %s: %w", key, err)
+		}
+
+		cm.mu.Lock()
+		cm.bundles[key] = manifest
+		cm.bundleDigests[key] = digest
+		cm.mu.Unlock()
+		digests[key] = digest
+		pushed++
+		noteProgress(ctx, "pushed bundle for %s", key)
+	}
+
+	log.Printf("Pushed %d of %d pinned resource bundles", pushed, len(resources))
+	return digests, nil
+}
+
+// pinnedObjects renders the Flux objects for every resource bundle, keyed
+// by their path in the root catalog.
+func (cm *CatalogManager) pinnedObjects(digests map[string]string) (map[string][]byte, error) {
+	objects := make(map[string][]byte, len(digests))
+	for key, digest := range digests {
+		namespace, name, _ := strings.Cut(key, "/")
+		out, err := renderPinnedFluxObjects(namespace, name, cm.ociClient.ResourceBundleURL(namespace, name), digest)
+		if err != nil {
+			return nil, fmt.Errorf("rendering flux objects for %s: %w", key, err)
+		}
+		objects[pinnedManifestDir+key+".yaml"] = out
+	}
+	return objects, nil
+}
+
+// expandPinned adds the resource of every bundle referenced by a pinned
+// root catalog tarball to resources, pulling each bundle at the digest the
+// root pins.
+func (cm *CatalogManager) expandPinned(ctx context.Context, tarGz *oci.Spool, resources map[string][]byte) error {
+	files, err := readTarGzFiles(tarGz.Reader())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		filename, ok := strings.CutPrefix(f.name, "manifests/"+pinnedManifestDir)
+		if !ok {
+			continue
+		}
+		key := strings.TrimSuffix(filename, ".yaml")
+		namespace, name, _ := strings.Cut(key, "/")
+
+		digest, err := pinnedDigest(f.data)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", f.name, err)
+		}
+		if digest == "" {
+			return fmt.Errorf("bundle of %s is not pinned to a digest", key)
+		}
+
+		_, bundleTarGz, err := cm.ociClient.PullResourceBundle(ctx, namespace, name, digest)
+		if err != nil {
+			return fmt.Errorf("pulling bundle of %s: %w", key, err)
+		}
+		bundleResources, err := readCatalogTarGz(bundleTarGz.Reader())
+		bundleTarGz.Close()
+		if err != nil {
+			return fmt.Errorf("reading bundle of %s: %w", key, err)
+		}
+		for key, manifest := range bundleResources {
+			resources[key] = manifest
+		}
+	}
+	return nil
+}
+
+// expandCatalog adds the resources a root catalog tarball references,
+// through shards or pinned bundles, to the resources read from it. It
+// returns the shard digests.
+func (cm *CatalogManager) expandCatalog(ctx context.Context, tarGz *oci.Spool, resources map[string][]byte) (map[string]string, error) {
+	shards, err := cm.expandShards(ctx, tarGz, resources)
+	if err != nil {
+		return nil, err
+	}
+	if err := cm.expandPinned(ctx, tarGz, resources); err != nil {
+		return nil, err
+	}
+	return shards, nil
+}
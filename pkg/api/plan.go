@@ -0,0 +1,242 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/alfredtm/gitops-squared/pkg/diff"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+	"oras.land/oras-go/v2/errdef"
+	"sigs.k8s.io/yaml"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// planManifest strips a PlatformResource document of its annotations,
+// which change on every push, so only real changes show in a diff.
+func planManifest(doc []byte) ([]byte, error) {
+	var pr model.PlatformResource
+	if err := yaml.Unmarshal(doc, &pr); err != nil {
+		return nil, err
+	}
+	pr.Metadata.Annotations = nil
+	return yaml.Marshal(pr)
+}
+
+// plan compares the live resource with what applying req would publish.
+// A nil req plans deleting the resource. Nothing is pushed.
+func (h *Handler) plan(namespace, name string, req *model.ResourceRequest) (model.PlanResponse, error) {
+	var old, new []byte
+	if data, ok := h.catalog.Get(namespace, name); ok {
+		doc, err := planManifest(splitDocuments(data)[0])
+		if err != nil {
+			return model.PlanResponse{}, fmt.Errorf("parsing stored manifest: %w", err)
+		}
+		old = doc
+	}
+	if req != nil {
+		proposed := *req
+		rendered, err := proposed.ToKubernetesYAML(namespace, model.ManifestAnnotations{})
+		if err != nil {
+			return model.PlanResponse{}, fmt.Errorf("generating YAML: %w", err)
+		}
+		if new, err = planManifest(rendered); err != nil {
+			return model.PlanResponse{}, fmt.Errorf("generating YAML: %w", err)
+		}
+	}
+
+	changes, err := diff.Fields(toJSON(old), toJSON(new))
+	if err != nil {
+		return model.PlanResponse{}, err
+	}
+
+	p := model.PlanResponse{Name: name, Namespace: namespace, Changes: changes}
+	switch {
+	case old == nil:
+		p.Action = model.PlanCreate
+	case new == nil:
+		p.Action = model.PlanDelete
+	case len(changes) == 0:
+		p.Action = model.PlanNone
+	default:
+		p.Action = model.PlanUpdate
+	}
+	if p.Changes == nil {
+		p.Changes = []diff.Change{}
+	}
+	path := resourceFilePath(namespace + "/" + name)
+	p.Diff = diff.Unified("a/"+path, "b/"+path, old, new, diffContext)
+	return p, nil
+}
+
+// toJSON converts a document rendered by planManifest to JSON, keeping nil
+// as nil. Rendered documents always convert.
+func toJSON(doc []byte) []byte {
+	if doc == nil {
+		return nil
+	}
+	data, _ := yaml.YAMLToJSON(doc)
+	return data
+}
+
+// PlanResource handles POST /api/v1/resources/plan.
+// The body is a create request. The response tells whether applying it
+// would create, update or leave the resource unchanged, with the changed
+// fields and a unified diff. It is checked like a write, including lint
+// rules escalated to errors, but nothing is pushed. With ?format=diff only
+// the diff is returned, as text/x-diff; ?color=true highlights it for a
+// terminal.
+func (h *Handler) PlanResource(w http.ResponseWriter, r *http.Request) {
+	namespace, ok := resourceNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.ResourceRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	h.defaults.Apply(namespace, &req)
+	if err := req.ConvertToCurrent(); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if err := h.checkRegion(namespace, &req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	warnings, err := h.lint.Lint(namespace, req.Spec.WithDefaults())
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	p, err := h.plan(namespace, req.Name, &req)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	p.Warnings = warnings
+
+	if r.URL.Query().Get("format") == "diff" {
+		writeDiff(w, r, p.Diff)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// writeDiff writes a unified diff as text/x-diff, highlighted with
+// ?color=true.
+func writeDiff(w http.ResponseWriter, r *http.Request, unified string) {
+	if r.URL.Query().Get("color") == "true" {
+		unified = diff.Colorize(unified)
+	}
+	w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(unified))
+}
+
+// DiffResourceVersions handles GET /api/v1/resources/{name}/diff.
+// It compares two stored versions, given as versions or digests: ?to=
+// (default the latest) against ?from= (default the version it replaced).
+// ?format=diff and ?color=true work as for plans.
+func (h *Handler) DiffResourceVersions(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	namespace, ok := resourceNamespace(w, r)
+	if !ok {
+		return
+	}
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		to = "latest"
+	}
+
+	newer, newDoc, err := h.versionManifest(r.Context(), namespace, name, to)
+	if errors.Is(err, errdef.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "version %q of resource %q not found", to, name)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	resp := model.VersionDiffResponse{Name: name, Namespace: namespace, To: newer.Annotations[oci.AnnotationResourceVersion]}
+
+	from := r.URL.Query().Get("from")
+	if from == "" {
+		from = newer.Annotations[oci.AnnotationResourceParent]
+	}
+	var oldDoc []byte
+	if from != "" {
+		older, doc, err := h.versionManifest(r.Context(), namespace, name, from)
+		if errors.Is(err, errdef.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "version %q of resource %q not found", from, name)
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+		resp.From = older.Annotations[oci.AnnotationResourceVersion]
+		oldDoc = doc
+	}
+
+	changes, err := diff.Fields(toJSON(oldDoc), toJSON(newDoc))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	resp.Changes = changes
+	if resp.Changes == nil {
+		resp.Changes = []diff.Change{}
+	}
+	path := resourceFilePath(namespace + "/" + name)
+	resp.Diff = diff.Unified("a/"+path, "b/"+path, oldDoc, newDoc, diffContext)
+
+	if r.URL.Query().Get("format") == "diff" {
+		writeDiff(w, r, resp.Diff)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// versionManifest pulls a stored version of a resource and renders its
+// PlatformResource like planManifest. A tombstone renders as nil.
+func (h *Handler) versionManifest(ctx context.Context, namespace, name, reference string) (oci.ResourceArtifact, []byte, error) {
+	artifact, err := h.ociClient.PullResource(ctx, namespace, name, reference)
+	if err != nil {
+		return oci.ResourceArtifact{}, nil, err
+	}
+	if artifact.Annotations[oci.AnnotationResourceDeleted] == "true" {
+		return artifact, nil, nil
+	}
+	doc, err := planManifest(splitDocuments(artifact.Manifest)[0])
+	if err != nil {
+		return oci.ResourceArtifact{}, nil, fmt.Errorf("parsing %s: %w", artifact.Digest, err)
+	}
+	return artifact, doc, nil
+}
+
+// proposalDiff renders the change a proposal makes as a Markdown diff
+// block for its pull request, or "" if it can't.
+func (h *Handler) proposalDiff(p *model.Proposal) string {
+	plan, err := h.plan(p.Namespace, p.Name, p.Request)
+	if err != nil {
+		log.Printf("Warning: failed to diff proposal %s: %v", p.ID, err)
+		return ""
+	}
+	if plan.Diff == "" {
+		return ""
+	}
+	return "```diff\n" + plan.Diff + "```"
+}
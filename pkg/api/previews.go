@@ -0,0 +1,210 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// CreatePreview handles POST /api/v1/previews.
+// It instantiates every resource of the bundle from its template in the
+// preview's namespace, all expiring together after the TTL. Nothing is pushed
+// unless every resource is valid; if a push fails, the resources already
+// pushed are deleted again.
+func (h *Handler) CreatePreview(w http.ResponseWriter, r *http.Request) {
+	var req model.PreviewRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	namespace := model.PreviewNamespace(req.ID)
+	if !h.checkFreeze(w, r, namespace) {
+		return
+	}
+	if len(h.previewResources(namespace)) > 0 {
+		writeError(w, http.StatusConflict, "preview %q already exists", req.ID)
+		return
+	}
+
+	expiresAt := req.Expiry(time.Now()).Format(time.RFC3339)
+	resources := make([]model.ResourceRequest, 0, len(req.Resources))
+	for i, res := range req.Resources {
+		tmpl, ok := h.templates.Get(res.Template)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "resources[%d]: template %q not found", i, res.Template)
+			return
+		}
+		resource := model.ResourceRequest{Name: res.Name, Spec: res.Spec, ExpiresAt: expiresAt}
+		tmpl.Instantiate(&resource)
+		h.defaults.Apply(namespace, &resource)
+		if err := resource.ConvertToCurrent(); err != nil {
+			writeError(w, http.StatusBadRequest, "resources[%d]: %v", i, err)
+			return
+		}
+		if err := resource.Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, "resources[%d]: %v", i, err)
+			return
+		}
+		if err := h.checkSecrets(namespace, &resource); err != nil {
+			writeError(w, http.StatusBadRequest, "resources[%d]: %v", i, err)
+			return
+		}
+		resources = append(resources, resource)
+	}
+
+	ctx := withSource(r.Context(), SourcePreview)
+	var pushed []string
+	for i := range resources {
+		if _, err := h.pushResource(ctx, namespace, &resources[i], applyOptions{}); err != nil {
+			for _, name := range pushed {
+				if _, err := h.tombstoneResource(ctx, namespace, name, ""); err != nil {
+					log.Printf("Warning: failed to clean up %s/%s: %v", namespace, name, err)
+				}
+			}
+			if err := h.catalog.PushCatalog(ctx); err != nil {
+				log.Printf("Warning: failed to push catalog: %v", err)
+			}
+			writeApplyError(w, fmt.Errorf("resource %q: %w", resources[i].Name, err))
+			return
+		}
+		pushed = append(pushed, resources[i].Name)
+	}
+	if err := h.catalog.PushCatalog(ctx); err != nil {
+		log.Printf("Warning: failed to push catalog: %v", err)
+	}
+
+	writeJSON(w, http.StatusCreated, h.previewResponse(req.ID))
+	log.Printf("Created preview %s (%d resources, expires %s)", req.ID, len(resources), expiresAt)
+}
+
+// ListPreviews handles GET /api/v1/previews.
+func (h *Handler) ListPreviews(w http.ResponseWriter, _ *http.Request) {
+	ids := make(map[string]bool)
+	for key := range h.catalog.List() {
+		ns, _, _ := strings.Cut(key, "/")
+		if id, ok := strings.CutPrefix(ns, model.PreviewNamespacePrefix); ok {
+			ids[id] = true
+		}
+	}
+
+	previews := make([]model.PreviewResponse, 0, len(ids))
+	for id := range ids {
+		previews = append(previews, h.previewResponse(id))
+	}
+	sort.Slice(previews, func(i, j int) bool { return previews[i].ID < previews[j].ID })
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"previews": previews,
+		"count":    len(previews),
+	})
+}
+
+// GetPreview handles GET /api/v1/previews/{id}.
+func (h *Handler) GetPreview(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := model.ValidateNamespace(model.PreviewNamespace(id)); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	resp := h.previewResponse(id)
+	if resp.Count == 0 {
+		writeError(w, http.StatusNotFound, "preview %q not found", id)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// DeletePreview handles DELETE /api/v1/previews/{id}.
+// It deletes every resource in the preview's namespace and republishes the
+// catalog once.
+func (h *Handler) DeletePreview(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	namespace := model.PreviewNamespace(id)
+	if err := model.ValidateNamespace(namespace); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	names := h.previewResources(namespace)
+	if len(names) == 0 {
+		writeError(w, http.StatusNotFound, "preview %q not found", id)
+		return
+	}
+	if !h.checkFreeze(w, r, namespace) {
+		return
+	}
+
+	ctx := withSource(r.Context(), SourcePreview)
+	resp := model.PreviewResponse{ID: id, Namespace: namespace, Resources: []model.ResourceResponse{}}
+	var failed []string
+	for _, name := range names {
+		deleted, err := h.tombstoneResource(ctx, namespace, name, "")
+		if err != nil {
+			log.Printf("Warning: failed to delete %s/%s: %v", namespace, name, err)
+			failed = append(failed, name)
+			continue
+		}
+		resp.Resources = append(resp.Resources, deleted)
+	}
+	resp.Count = len(resp.Resources)
+	if err := h.catalog.PushCatalog(ctx); err != nil {
+		log.Printf("Warning: failed to push catalog: %v", err)
+	}
+
+	if len(failed) > 0 {
+		writeError(w, http.StatusInternalServerError, "failed to delete %s from preview %q", strings.Join(failed, ", "), id)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+	log.Printf("Deleted preview %s (%d resources)", id, resp.Count)
+}
+
+// previewResources returns the sorted names of the live resources in a
+// namespace.
+func (h *Handler) previewResources(namespace string) []string {
+	var names []string
+	for key := range h.catalog.List() {
+		ns, name, _ := strings.Cut(key, "/")
+		if ns == namespace {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// previewResponse describes the preview with the given ID. Its expiry is the
+// earliest of its resources'.
+func (h *Handler) previewResponse(id string) model.PreviewResponse {
+	namespace := model.PreviewNamespace(id)
+	resp := model.PreviewResponse{ID: id, Namespace: namespace, Resources: []model.ResourceResponse{}}
+
+	var expiresAt time.Time
+	for _, name := range h.previewResources(namespace) {
+		data, ok := h.catalog.Get(namespace, name)
+		if !ok {
+			continue
+		}
+		meta, _ := h.catalog.Meta(namespace, name)
+		if !meta.ExpiresAt.IsZero() && (expiresAt.IsZero() || meta.ExpiresAt.Before(expiresAt)) {
+			expiresAt = meta.ExpiresAt
+		}
+		resp.Resources = append(resp.Resources, resourceResponse(namespace, name, data, meta))
+	}
+	resp.Count = len(resp.Resources)
+	if !expiresAt.IsZero() {
+		resp.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+	}
+	return resp
+}
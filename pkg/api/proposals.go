@@ -0,0 +1,570 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/gitsource"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// proposalBranchPrefix prefixes the Git branch of every proposal.
+const proposalBranchPrefix = "proposals/"
+
+// PullRequestOptions opens a pull request in the Git mirror for every
+// proposal. Merging it publishes the proposal.
+type PullRequestOptions struct {
+	// Exporter is the Git export whose repository pull requests are
+	// opened against, targeting its branch.
+	Exporter *GitExporter
+
+	// Client opens the pull requests and verifies their webhooks.
+	Client *gitsource.PullRequests
+}
+
+// ProposalStore holds change proposals in memory and persists them to the
+// registry as a single JSON document on every change.
+type ProposalStore struct {
+	ociClient oci.Interface
+	mu        sync.RWMutex
+	proposals map[string]model.Proposal
+	merging   map[string]bool // IDs being merged, so a merge runs once
+}
+
+// NewProposalStore creates an empty proposal store.
+func NewProposalStore(client oci.Interface) *ProposalStore {
+	return &ProposalStore{
+		ociClient: client,
+		proposals: make(map[string]model.Proposal),
+		merging:   make(map[string]bool),
+	}
+}
+
+// Get returns a proposal by ID.
+func (ps *ProposalStore) Get(id string) (model.Proposal, bool) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	p, ok := ps.proposals[id]
+	return p, ok
+}
+
+// List returns the proposals in namespace and with status, newest first.
+// Empty arguments match every proposal.
+func (ps *ProposalStore) List(namespace, status string) []model.Proposal {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	list := make([]model.Proposal, 0, len(ps.proposals))
+	for _, p := range ps.proposals {
+		if (namespace == "" || p.Namespace == namespace) && (status == "" || p.Status == status) {
+			list = append(list, p)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt != list[j].CreatedAt {
+			return list[i].CreatedAt > list[j].CreatedAt
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// byBranch returns the proposal whose Git branch is branch.
+func (ps *ProposalStore) byBranch(branch string) (model.Proposal, bool) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	for _, p := range ps.proposals {
+		if p.Branch != "" && p.Branch == branch {
+			return p, true
+		}
+	}
+	return model.Proposal{}, false
+}
+
+// Put creates or replaces a proposal and persists the set.
+func (ps *ProposalStore) Put(ctx context.Context, p model.Proposal) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	prev, existed := ps.proposals[p.ID]
+	ps.proposals[p.ID] = p
+	if err := ps.persistLocked(ctx); err != nil {
+		if existed {
+			ps.proposals[p.ID] = prev
+		} else {
+			delete(ps.proposals, p.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// beginMerge marks a proposal as being merged. It returns false if a merge
+// of it is already running.
+func (ps *ProposalStore) beginMerge(id string) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.merging[id] {
+		return false
+	}
+	ps.merging[id] = true
+	return true
+}
+
+func (ps *ProposalStore) endMerge(id string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	delete(ps.merging, id)
+}
+
+func (ps *ProposalStore) persistLocked(ctx context.Context) error {
+	list := make([]model.Proposal, 0, len(ps.proposals))
+	for _, p := range ps.proposals {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("encoding proposals: %w", err)
+	}
+	if err := ps.ociClient.PushProposals(ctx, data); err != nil {
+		return fmt.Errorf("pushing proposals: %w", err)
+	}
+	return nil
+}
+
+// Restore loads proposals from the registry.
+func (ps *ProposalStore) Restore(ctx context.Context) error {
+	data, err := ps.ociClient.PullProposals(ctx)
+	if err != nil {
+		return fmt.Errorf("pulling proposals: %w", err)
+	}
+	if data == nil {
+		return nil
+	}
+
+	var list []model.Proposal
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("parsing proposals: %w", err)
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for _, p := range list {
+		ps.proposals[p.ID] = p
+	}
+	log.Printf("Restored %d proposals from registry", len(list))
+	return nil
+}
+
+// newProposalID returns a random proposal ID.
+func newProposalID() (string, error) {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// CreateProposal handles POST /api/v1/proposals.
+// It validates the change like a direct request would, renders it into a
+// draft artifact without touching the resource and, if pull requests are
+// configured, opens one in the Git mirror. Nothing is published until the
+// proposal is merged.
+func (h *Handler) CreateProposal(w http.ResponseWriter, r *http.Request) {
+	namespace, ok := resourceNamespace(w, r)
+	if !ok || !h.checkNamespace(w, namespace) {
+		return
+	}
+
+	var req model.ProposalRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	if req.Action == "" {
+		req.Action = model.ProposalActionApply
+	}
+	if err := req.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	id, err := newProposalID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "generating proposal ID: %v", err)
+		return
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	p := model.Proposal{
+		ID:          id,
+		Title:       req.Title,
+		Description: req.Description,
+		Action:      req.Action,
+		Namespace:   namespace,
+		Name:        req.Resource.Name,
+		Status:      model.ProposalOpen,
+		Author:      auth.Actor(ctx),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if meta, ok := h.catalog.Meta(namespace, p.Name); ok {
+		p.BaseDigest = meta.Digest
+	}
+
+	var manifest []byte
+	switch req.Action {
+	case model.ProposalActionApply:
+		resource := req.Resource
+		h.defaults.Apply(namespace, &resource)
+		if err := resource.ConvertToCurrent(); err != nil {
+			writeError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		if err := resource.Validate(); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		if resource.HasPlaintextSecrets() {
+			writeError(w, http.StatusBadRequest, "proposals cannot carry plaintext secret data; use external secrets instead")
+			return
+		}
+		if err := h.checkSecrets(namespace, &resource); err != nil {
+			writeError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		manifest, err = h.renderDraft(ctx, namespace, &resource)
+		if err != nil {
+			writeApplyError(w, err)
+			return
+		}
+		p.Request = &resource
+		if p.DraftDigest, err = h.ociClient.PushDraft(ctx, id, namespace, p.Name, manifest); err != nil {
+			writeError(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+	case model.ProposalActionDelete:
+		if _, ok := h.catalog.Get(namespace, p.Name); !ok {
+			writeError(w, http.StatusNotFound, "resource %q not found", p.Name)
+			return
+		}
+	}
+
+	if h.pullRequests != nil {
+		if err := h.openPullRequest(ctx, &p, manifest); err != nil {
+			writeError(w, http.StatusBadGateway, "%v", err)
+			return
+		}
+	}
+
+	if err := h.proposals.Put(ctx, p); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, model.ProposalResponse{Proposal: p, Manifest: string(manifest)})
+	log.Printf("Audit: proposal %s to %s %s/%s by %s", p.ID, p.Action, namespace, p.Name, p.Author)
+}
+
+// renderDraft renders the manifest a proposal would publish, with a
+// placeholder version.
+func (h *Handler) renderDraft(ctx context.Context, namespace string, req *model.ResourceRequest) ([]byte, error) {
+	companions, err := h.renderCompanions(ctx, namespace, req)
+	if err != nil {
+		return nil, err
+	}
+	cr, err := req.ToKubernetesYAML(namespace, model.ManifestAnnotations{
+		Version:    "proposed",
+		Generation: h.nextGeneration(namespace, req),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("generating YAML: %w", err)
+	}
+	return joinDocuments(append([][]byte{cr}, companions...)...), nil
+}
+
+// openPullRequest commits a proposal's change to its own branch of the Git
+// mirror and opens a pull request for it. A nil manifest deletes the
+// resource's file.
+func (h *Handler) openPullRequest(ctx context.Context, p *model.Proposal, manifest []byte) error {
+	exporter := h.pullRequests.Exporter
+	mirror := exporter.Mirror()
+	key := p.Namespace + "/" + p.Name
+	branch := proposalBranchPrefix + p.ID
+
+	commit, _, err := mirror.Commit(ctx, gitsource.Commit{
+		Branch: branch,
+		Files:  map[string][]byte{resourceFilePath(key): manifest},
+		Author: exporter.signature(p.Author),
+		Message: func(changes []gitsource.Change) string {
+			return exportMessage(changes, map[string][]byte{key: nil}, nil)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("committing proposal: %w", err)
+	}
+	if commit == "" {
+		return fmt.Errorf("the proposal doesn't change %s in %s", mirror.Path(resourceFilePath(key)), mirror.URL())
+	}
+
+	body := p.Description
+	if body != "" {
+		body += "\n\n"
+	}
+	body += fmt.Sprintf("Proposal `%s` by %s to %s `%s`. Merging this pull request publishes the change; closing it rejects the proposal.",
+		p.ID, p.Author, p.Action, key)
+	if d := h.proposalDiff(p); d != "" {
+		body += "\n\n" + d
+	}
+	pr, err := h.pullRequests.Client.Open(ctx, branch, mirror.Branch(), p.Title, body)
+	if err != nil {
+		return err
+	}
+	p.Branch = branch
+	p.PullRequest = &model.ProposalPullRequest{Number: pr.Number, URL: pr.URL}
+	return nil
+}
+
+// ListProposals handles GET /api/v1/proposals.
+// ?namespace= and ?status= filter the list.
+func (h *Handler) ListProposals(w http.ResponseWriter, r *http.Request) {
+	proposals := h.proposals.List(r.URL.Query().Get("namespace"), r.URL.Query().Get("status"))
+	writeJSON(w, http.StatusOK, map[string]any{
+		"proposals": proposals,
+		"count":     len(proposals),
+	})
+}
+
+// GetProposal handles GET /api/v1/proposals/{id}.
+// The response includes the draft manifest.
+func (h *Handler) GetProposal(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	p, ok := h.proposals.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "proposal %q not found", id)
+		return
+	}
+	resp := model.ProposalResponse{Proposal: p}
+	if p.DraftDigest != "" {
+		manifest, err := h.ociClient.PullDraft(r.Context(), id)
+		if err != nil {
+			log.Printf("Warning: failed to pull draft of proposal %s: %v", id, err)
+		}
+		resp.Manifest = string(manifest)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// MergeProposal handles POST /api/v1/proposals/{id}/merge.
+// It publishes an open proposal without a pull request, or retries one
+// whose publishing failed. Proposals with an open pull request are merged
+// in Git.
+func (h *Handler) MergeProposal(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	p, ok := h.proposals.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "proposal %q not found", id)
+		return
+	}
+	switch {
+	case p.Status == model.ProposalOpen && p.PullRequest != nil:
+		writeError(w, http.StatusConflict, "proposal %q is reviewed in %s; merge the pull request instead", id, p.PullRequest.URL)
+		return
+	case p.Status != model.ProposalOpen && p.Status != model.ProposalFailed:
+		writeError(w, http.StatusConflict, "proposal %q is %s", id, p.Status)
+		return
+	}
+	if !h.checkFreeze(w, r, p.Namespace) {
+		return
+	}
+
+	p, err := h.mergeProposal(r.Context(), id, auth.Actor(r.Context()))
+	if errors.Is(err, errMergeRunning) {
+		writeError(w, http.StatusConflict, "%v", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	status := http.StatusOK
+	if p.Status == model.ProposalFailed {
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, p)
+}
+
+// errMergeRunning is returned by mergeProposal if the proposal is already
+// being merged.
+var errMergeRunning = errors.New("proposal is already being merged")
+
+// mergeProposal publishes a proposal and records the outcome: merged, or
+// failed with the reason. The returned error is only set if the outcome
+// could not be recorded.
+func (h *Handler) mergeProposal(ctx context.Context, id, mergedBy string) (model.Proposal, error) {
+	ctx = withSource(ctx, SourceProposal)
+	if !h.proposals.beginMerge(id) {
+		return model.Proposal{}, errMergeRunning
+	}
+	defer h.proposals.endMerge(id)
+	p, ok := h.proposals.Get(id)
+	if !ok {
+		return model.Proposal{}, fmt.Errorf("proposal %q not found", id)
+	}
+
+	var resp model.ResourceResponse
+	var err error
+	switch {
+	case h.frozen(p.Namespace, time.Now()):
+		err = fmt.Errorf("namespace %q is frozen", p.Namespace)
+	case p.Action == model.ProposalActionDelete:
+		resp, err = h.deleteResource(ctx, p.Namespace, p.Name, p.BaseDigest)
+	default:
+		req := *p.Request
+		resp, err = h.applyResourceWith(ctx, p.Namespace, &req, applyOptions{ifMatch: p.BaseDigest})
+	}
+
+	p.MergedBy = mergedBy
+	p.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if err != nil {
+		p.Status = model.ProposalFailed
+		p.Error = err.Error()
+		log.Printf("Warning: merging proposal %s failed: %v", id, err)
+	} else {
+		p.Status = model.ProposalMerged
+		p.Version = resp.Version
+		p.Error = ""
+		log.Printf("Audit: merged proposal %s (%s %s/%s, version %s) by %s", id, p.Action, p.Namespace, p.Name, p.Version, mergedBy)
+		h.events.Record(ctx, model.Event{
+			Type:      model.EventProposalMerged,
+			Namespace: p.Namespace,
+			Name:      p.Name,
+			Version:   p.Version,
+			Actor:     mergedBy,
+			Message:   p.Title,
+		})
+	}
+	if err := h.proposals.Put(ctx, p); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// CloseProposal handles DELETE /api/v1/proposals/{id}.
+// It rejects an open or failed proposal and closes its pull request.
+func (h *Handler) CloseProposal(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	p, ok := h.proposals.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "proposal %q not found", id)
+		return
+	}
+	if p.Status != model.ProposalOpen && p.Status != model.ProposalFailed {
+		writeError(w, http.StatusConflict, "proposal %q is %s", id, p.Status)
+		return
+	}
+
+	if p.PullRequest != nil && p.Status == model.ProposalOpen && h.pullRequests != nil {
+		if err := h.pullRequests.Client.Close(r.Context(), p.PullRequest.Number); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	p.Status = model.ProposalClosed
+	p.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := h.proposals.Put(r.Context(), p); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+	log.Printf("Audit: closed proposal %s by %s", id, auth.Actor(r.Context()))
+}
+
+// ProposalWebhook handles POST /api/v1/webhooks/proposals.
+// It receives pull request events from the Git mirror's provider: merging
+// a proposal's pull request publishes it and closing one rejects it.
+func (h *Handler) ProposalWebhook(w http.ResponseWriter, r *http.Request) {
+	if h.pullRequests == nil {
+		writeError(w, http.StatusNotFound, "proposal pull requests are not configured")
+		return
+	}
+	client := h.pullRequests.Client
+
+	provider, event, ok := gitsource.Provider(r.Header)
+	if !ok || provider != client.Provider {
+		writeError(w, http.StatusBadRequest, "not a %s webhook", client.Provider)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "reading body: %v", err)
+		return
+	}
+	if err := client.Verify(r.Header, body); err != nil {
+		writeError(w, http.StatusUnauthorized, "%v", err)
+		return
+	}
+
+	ignore := func(format string, args ...any) {
+		writeJSON(w, http.StatusOK, map[string]string{"ignored": fmt.Sprintf(format, args...)})
+	}
+	if !gitsource.IsPullRequest(provider, event) {
+		ignore("event %q is not about a pull request", event)
+		return
+	}
+	pr, err := gitsource.ParsePullRequest(provider, body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	if !strings.EqualFold(pr.Repository, client.Repository) {
+		ignore("repository %q is not the Git mirror", pr.Repository)
+		return
+	}
+	p, ok := h.proposals.byBranch(pr.Head)
+	switch {
+	case !ok:
+		ignore("branch %q belongs to no proposal", pr.Head)
+		return
+	case p.Status != model.ProposalOpen:
+		ignore("proposal %s is %s", p.ID, p.Status)
+		return
+	case !pr.Merged && !pr.Closed:
+		ignore("pull request %d was neither merged nor closed", pr.Number)
+		return
+	}
+
+	if pr.Closed {
+		p.Status = model.ProposalClosed
+		p.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+		if err := h.proposals.Put(r.Context(), p); err != nil {
+			writeError(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, p)
+		log.Printf("Audit: closed proposal %s with pull request %d", p.ID, pr.Number)
+		return
+	}
+
+	merged, err := h.mergeProposal(r.Context(), p.ID, fmt.Sprintf("pull request %d", pr.Number))
+	if errors.Is(err, errMergeRunning) {
+		ignore("%v", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, merged)
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+	"sigs.k8s.io/yaml"
+)
+
+// RegionStore holds the region catalog in memory and persists it to the
+// registry as a single JSON document on every change. While it is empty,
+// regions are not checked.
+type RegionStore struct {
+	ociClient oci.Interface
+	mu        sync.RWMutex
+	regions   map[string]model.Region
+}
+
+// NewRegionStore creates an empty region store.
+func NewRegionStore(client oci.Interface) *RegionStore {
+	return &RegionStore{
+		ociClient: client,
+		regions:   make(map[string]model.Region),
+	}
+}
+
+// Get returns a region by name.
+func (rs *RegionStore) Get(name string) (model.Region, bool) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	r, ok := rs.regions[name]
+	return r, ok
+}
+
+// List returns all regions sorted by name.
+func (rs *RegionStore) List() []model.Region {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	list := make([]model.Region, 0, len(rs.regions))
+	for _, r := range rs.regions {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Put creates or replaces a region and persists the catalog.
+func (rs *RegionStore) Put(ctx context.Context, r model.Region) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	prev, existed := rs.regions[r.Name]
+	rs.regions[r.Name] = r
+	if err := rs.persistLocked(ctx); err != nil {
+		if existed {
+			rs.regions[r.Name] = prev
+		} else {
+			delete(rs.regions, r.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a region and persists the catalog. It reports whether the
+// region existed.
+func (rs *RegionStore) Delete(ctx context.Context, name string) (bool, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	prev, ok := rs.regions[name]
+	if !ok {
+		return false, nil
+	}
+	delete(rs.regions, name)
+	if err := rs.persistLocked(ctx); err != nil {
+		rs.regions[name] = prev
+		return true, err
+	}
+	return true, nil
+}
+
+func (rs *RegionStore) persistLocked(ctx context.Context) error {
+	list := make([]model.Region, 0, len(rs.regions))
+	for _, r := range rs.regions {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("encoding regions: %w", err)
+	}
+	if err := rs.ociClient.PushRegions(ctx, data); err != nil {
+		return fmt.Errorf("pushing regions: %w", err)
+	}
+	return nil
+}
+
+// Restore loads the region catalog from the registry.
+func (rs *RegionStore) Restore(ctx context.Context) error {
+	data, err := rs.ociClient.PullRegions(ctx)
+	if err != nil {
+		return fmt.Errorf("pulling regions: %w", err)
+	}
+	if data == nil {
+		return nil
+	}
+
+	var list []model.Region
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("parsing regions: %w", err)
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for _, r := range list {
+		rs.regions[r.Name] = r
+	}
+	log.Printf("Restored %d regions from registry", len(list))
+	return nil
+}
+
+// availableRegions returns the regions that may be used in namespace. ok
+// is false while the catalog is empty and any region may be used.
+func (h *Handler) availableRegions(namespace string) (regions []model.Region, ok bool) {
+	all := h.regions.List()
+	if len(all) == 0 {
+		return nil, false
+	}
+	env := h.lint.Environment(namespace)
+	regions = []model.Region{}
+	for _, r := range all {
+		if r.AvailableIn(namespace, env) {
+			regions = append(regions, r)
+		}
+	}
+	return regions, true
+}
+
+// checkRegion rejects a write that places a resource in a region not
+// available in its namespace. Resources keeping the region of their
+// current version are let through, so shrinking the catalog doesn't block
+// updates to what already runs there.
+func (h *Handler) checkRegion(namespace string, req *model.ResourceRequest) error {
+	region := req.Spec.Region
+	if region == "" {
+		return nil
+	}
+	regions, ok := h.availableRegions(namespace)
+	if !ok {
+		return nil
+	}
+	allowed := make([]string, 0, len(regions))
+	for _, r := range regions {
+		if r.Name == region {
+			return nil
+		}
+		allowed = append(allowed, r.Name)
+	}
+	if current, ok := h.catalog.Get(namespace, req.Name); ok {
+		var pr model.PlatformResource
+		if err := yaml.Unmarshal(current, &pr); err == nil && pr.Spec.Region == region {
+			return nil
+		}
+	}
+	return model.RegionError(namespace, region, allowed)
+}
+
+// PutRegion handles POST /api/v1/admin/regions.
+// It creates or replaces a region.
+func (h *Handler) PutRegion(w http.ResponseWriter, r *http.Request) {
+	var region model.Region
+	if err := decodeJSON(r, &region); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	if err := region.Validate(h.lint); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := h.regions.Put(r.Context(), region); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, region)
+	log.Printf("Audit: saved region %s (environments=%v, namespaces=%v) by %s", region.Name, region.Environments, region.Namespaces, auth.Actor(r.Context()))
+}
+
+// DeleteRegion handles DELETE /api/v1/admin/regions/{name}.
+// Resources already in the region keep it.
+func (h *Handler) DeleteRegion(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	existed, err := h.regions.Delete(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	if !existed {
+		writeError(w, http.StatusNotFound, "region %q not found", name)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("Audit: deleted region %s by %s", name, auth.Actor(r.Context()))
+}
+
+// ListRegions handles GET /api/v1/regions.
+// With ?namespace= only the regions available in that namespace are
+// listed. "restricted" is false while the catalog is empty and any region
+// is accepted.
+func (h *Handler) ListRegions(w http.ResponseWriter, r *http.Request) {
+	regions := h.regions.List()
+	if namespace := r.URL.Query().Get("namespace"); namespace != "" {
+		regions, _ = h.availableRegions(namespace)
+		if regions == nil {
+			regions = []model.Region{}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"regions":    regions,
+		"count":      len(regions),
+		"restricted": len(h.regions.List()) > 0,
+	})
+}
+
+// GetRegion handles GET /api/v1/regions/{name}.
+func (h *Handler) GetRegion(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	region, ok := h.regions.Get(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "region %q not found", name)
+		return
+	}
+	writeJSON(w, http.StatusOK, region)
+}
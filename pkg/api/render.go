@@ -0,0 +1,195 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/secrets"
+	"sigs.k8s.io/yaml"
+)
+
+// renderCompanions renders the per-type scaffolding (ServiceAccount,
+// NetworkPolicy, ResourceQuota) followed by the resource's secrets.
+func (h *Handler) renderCompanions(ctx context.Context, namespace string, req *model.ResourceRequest) ([][]byte, error) {
+	docs, err := h.companions[req.Spec.Type].Render(namespace, req.Name)
+	if err != nil {
+		return nil, fmt.Errorf("rendering companions: %w", err)
+	}
+
+	secretDocs, err := h.renderSecrets(ctx, namespace, req)
+	if err != nil {
+		return nil, err
+	}
+	return append(docs, secretDocs...), nil
+}
+
+// renderSecrets renders a resource's secrets as companion manifests.
+// Plaintext secrets, and those whose placeholders the API resolves, are
+// SOPS-encrypted here, before anything is pushed.
+func (h *Handler) renderSecrets(ctx context.Context, namespace string, req *model.ResourceRequest) ([][]byte, error) {
+	docs := make([][]byte, 0, len(req.Secrets))
+	for i := range req.Secrets {
+		doc, err := h.renderSecret(ctx, namespace, req.Name, &req.Secrets[i])
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+func (h *Handler) renderSecret(ctx context.Context, namespace, resource string, secret *model.SecretSpec) ([]byte, error) {
+	if secret.External != nil {
+		doc, err := secret.ToKubernetesYAML(namespace, resource)
+		if err != nil {
+			return nil, fmt.Errorf("rendering secret %q: %w", secret.Name, err)
+		}
+		return doc, nil
+	}
+
+	placeholders := secret.Placeholders()
+	stores, err := h.secretStoresFor(namespace, secret, placeholders)
+	if err != nil {
+		return nil, err
+	}
+	if len(stores) > 0 && !stores[0].Resolved() {
+		store := stores[0]
+		doc, err := secret.ToTemplatedExternalSecretYAML(namespace, resource, store.SecretStore, store.StoreKind, func(path string) string {
+			return store.RemoteKey(namespace, path)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("rendering secret %q: %w", secret.Name, err)
+		}
+		return doc, nil
+	}
+
+	if h.encryptor == nil {
+		return nil, fmt.Errorf("secret %q has plaintext data but SOPS encryption is not configured", secret.Name)
+	}
+	values := make(map[string]string, len(placeholders))
+	for i, p := range placeholders {
+		if values[p.Text], err = stores[i].Resolve(ctx, namespace, p.Path, p.Key); err != nil {
+			return nil, fmt.Errorf("resolving secret %q: %w", secret.Name, err)
+		}
+	}
+	expanded := secret.Expand(func(p model.SecretPlaceholder) string { return values[p.Text] })
+	doc, err := expanded.ToKubernetesYAML(namespace, resource)
+	if err != nil {
+		return nil, fmt.Errorf("rendering secret %q: %w", secret.Name, err)
+	}
+	doc, err = h.encryptor.Encrypt(ctx, doc)
+	if err != nil {
+		return nil, fmt.Errorf("encrypting secret %q: %w", secret.Name, err)
+	}
+	return doc, nil
+}
+
+// secretStoresFor returns the store each placeholder of a secret resolves
+// from in namespace. A secret resolved by the External Secrets Operator
+// can't mix in other stores or plaintext, which would end up in the
+// artifact.
+func (h *Handler) secretStoresFor(namespace string, secret *model.SecretSpec, placeholders []model.SecretPlaceholder) ([]*secrets.Store, error) {
+	stores := make([]*secrets.Store, len(placeholders))
+	for i, p := range placeholders {
+		store, ok := h.secretStores.Lookup(p.Store, namespace)
+		if !ok {
+			return nil, fmt.Errorf("secret %q: no secret store %q for namespace %s", secret.Name, p.Store, namespace)
+		}
+		if i > 0 && store != stores[0] && (!store.Resolved() || !stores[0].Resolved()) {
+			external := store
+			if store.Resolved() {
+				external = stores[0]
+			}
+			return nil, fmt.Errorf("secret %q: placeholders of external secret store %q can't be mixed with other stores", secret.Name, external.Name)
+		}
+		stores[i] = store
+	}
+	if len(stores) > 0 && !stores[0].Resolved() && secret.HasPlaintext() {
+		return nil, fmt.Errorf("secret %q: placeholders of external secret store %q can't be mixed with plaintext data", secret.Name, stores[0].Name)
+	}
+	return stores, nil
+}
+
+// checkSecrets checks that a request's secrets can be rendered: that their
+// placeholders name stores configured for namespace, and that SOPS is
+// configured for those the API has to encrypt.
+func (h *Handler) checkSecrets(namespace string, req *model.ResourceRequest) error {
+	for i := range req.Secrets {
+		secret := &req.Secrets[i]
+		if secret.External != nil {
+			continue
+		}
+		placeholders := secret.Placeholders()
+		stores, err := h.secretStoresFor(namespace, secret, placeholders)
+		if err != nil {
+			return err
+		}
+		if len(stores) > 0 && !stores[0].Resolved() {
+			continue
+		}
+		if h.encryptor == nil {
+			if secret.HasPlaintext() {
+				return fmt.Errorf("plaintext secret data requires SOPS encryption; use external secrets instead")
+			}
+			return fmt.Errorf("secret %q: values resolved from secret store %q require SOPS encryption", secret.Name, stores[0].Name)
+		}
+	}
+	return nil
+}
+
+// secretDocuments returns the Secret and ExternalSecret documents of a stored
+// manifest, so they can be carried over when the spec is re-rendered.
+func secretDocuments(manifest []byte) [][]byte {
+	var docs [][]byte
+	for _, doc := range splitDocuments(manifest) {
+		var obj struct {
+			Kind string `json:"kind"`
+		}
+		if err := yaml.Unmarshal(doc, &obj); err != nil {
+			continue
+		}
+		if obj.Kind == "Secret" || obj.Kind == "ExternalSecret" {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// splitDocuments splits a multi-document YAML stream, dropping empty documents.
+// Each returned document keeps its trailing newline.
+func splitDocuments(data []byte) [][]byte {
+	var docs [][]byte
+	stream := append([]byte("\n"), data...)
+	for _, doc := range bytes.Split(stream, []byte("\n---\n")) {
+		doc = bytes.TrimPrefix(doc, []byte("\n"))
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		if !bytes.HasSuffix(doc, []byte("\n")) {
+			doc = append(doc, '\n')
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// schemaVersionOf returns the schema version of a stored manifest's
+// PlatformResource, defaulting to v1alpha1 if it cannot be determined.
+func schemaVersionOf(manifest []byte) string {
+	var pr model.PlatformResource
+	if err := yaml.Unmarshal(manifest, &pr); err != nil {
+		return model.SchemaV1Alpha1
+	}
+	version, err := model.ParseSchemaVersion(pr.APIVersion)
+	if err != nil {
+		return model.SchemaV1Alpha1
+	}
+	return version
+}
+
+// joinDocuments concatenates YAML documents into a single multi-document stream.
+func joinDocuments(docs ...[]byte) []byte {
+	return bytes.Join(docs, []byte("---\n"))
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// Sync picks up changes other replicas made through the registry.
+// Resources whose latest artifact differs from the one this replica knows
+// are reloaded, a catalog published elsewhere becomes the current one, and
+// catalog channels are re-read.
+// Unless repositories are discovered through the registry's catalog API,
+// nothing is listed while the resource index is unchanged.
+func (cm *CatalogManager) Sync(ctx context.Context) (model.SyncResponse, error) {
+	cm.syncMu.Lock()
+	defer cm.syncMu.Unlock()
+
+	resources, err := cm.syncResources(ctx)
+	resp := model.SyncResponse{Resources: resources}
+	if err != nil {
+		return resp, err
+	}
+	if resp.Catalog, err = cm.syncCatalog(ctx); err != nil {
+		return resp, err
+	}
+	if err := cm.refreshChannels(ctx); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// syncResources reloads the resources changed by other replicas and
+// returns their keys.
+func (cm *CatalogManager) syncResources(ctx context.Context) ([]string, error) {
+	refreshed := []string{}
+	var index string
+	if cm.ociClient.Discovery() != oci.RepoDiscoveryCatalog {
+		head, err := cm.ociClient.IndexHead(ctx)
+		if err != nil {
+			return refreshed, fmt.Errorf("resolving resource index: %w", err)
+		}
+		if head == cm.syncedIndex {
+			return refreshed, nil
+		}
+		index = head
+	}
+
+	repos, err := cm.ociClient.ListResourceRepos(ctx)
+	if err != nil {
+		return refreshed, fmt.Errorf("listing resource repos: %w", err)
+	}
+	failed := 0
+	for _, repo := range repos {
+		if ctx.Err() != nil {
+			return refreshed, ctx.Err()
+		}
+		changed, err := cm.syncResource(ctx, repo)
+		if err != nil {
+			log.Printf("Warning: failed to sync %s/%s: %v", repo.Namespace, repo.Name, err)
+			failed++
+			continue
+		}
+		if changed {
+			refreshed = append(refreshed, repo.Namespace+"/"+repo.Name)
+		}
+	}
+	// Failed resources are retried at the next sync, even if the index
+	// doesn't change again.
+	if failed == 0 {
+		cm.syncedIndex = index
+	}
+	return refreshed, nil
+}
+
+// syncResource reloads one resource if its latest artifact isn't the one
+// this replica knows. Deletions are only picked up for live resources.
+func (cm *CatalogManager) syncResource(ctx context.Context, repo oci.ResourceInfo) (bool, error) {
+	unlock := cm.LockResource(repo.Namespace, repo.Name)
+	defer unlock()
+
+	digest, deleted := repo.Digest, repo.Deleted
+	if digest == "" {
+		head, ok, err := cm.ociClient.HeadResource(ctx, repo.Namespace, repo.Name)
+		if err != nil || !ok {
+			return false, err
+		}
+		digest, deleted = head.Digest, head.Deleted
+	}
+
+	meta, live := cm.Meta(repo.Namespace, repo.Name)
+	switch {
+	case digest == cm.quarantinedDigest(repo.Namespace, repo.Name):
+		return false, nil
+	case deleted && !live:
+		return false, nil
+	case !deleted && live && digest == meta.Digest:
+		return false, nil
+	}
+	if err := cm.refreshResource(ctx, repo.Namespace, repo.Name); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// syncCatalog adopts the latest published catalog if another replica
+// published it, and returns its digest.
+func (cm *CatalogManager) syncCatalog(ctx context.Context) (string, error) {
+	cm.publishMu.Lock()
+	defer cm.publishMu.Unlock()
+
+	head, err := cm.ociClient.CatalogHead(ctx)
+	if err != nil {
+		return "", fmt.Errorf("resolving catalog: %w", err)
+	}
+	if head == "" || head == cm.Status().Digest {
+		return "", nil
+	}
+
+	digest, tarGz, err := cm.ociClient.PullCatalog(ctx, head)
+	if err != nil {
+		return "", fmt.Errorf("pulling catalog %s: %w", head, err)
+	}
+	resources, err := readCatalogTarGz(tarGz.Reader())
+	if err != nil {
+		tarGz.Close()
+		return "", fmt.Errorf("reading catalog %s: %w", digest, err)
+	}
+	shards, err := cm.expandCatalog(ctx, tarGz, resources)
+	if err != nil {
+		tarGz.Close()
+		return "", fmt.Errorf("reading catalog %s: %w", digest, err)
+	}
+
+	var provenance model.CatalogProvenance
+	data, _, err := cm.ociClient.PullCatalogProvenance(ctx, digest)
+	if err != nil {
+		tarGz.Close()
+		return "", fmt.Errorf("pulling catalog provenance: %w", err)
+	}
+	if data != nil {
+		if err := json.Unmarshal(data, &provenance); err != nil {
+			tarGz.Close()
+			return "", fmt.Errorf("parsing catalog provenance: %w", err)
+		}
+	}
+
+	// The publishing replica already pushed the signature.
+	status := cm.catalogStatus(digest, provenance.Version, resources, shards)
+	if _, err := cm.signStatus(ctx, &status); err != nil {
+		log.Printf("Warning: failed to sign synced catalog %s: %v", digest, err)
+	}
+	if provenance.BuiltAt != "" {
+		status.BuiltAt = provenance.BuiltAt
+	}
+	cm.setStatus(status, provenance, tarGz, resources)
+	return digest, nil
+}
+
+// RunSync syncs with other replicas every interval until ctx is cancelled.
+func (cm *CatalogManager) RunSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			resp, err := cm.Sync(ctx)
+			if err != nil {
+				log.Printf("Warning: replica sync: %v", err)
+			}
+			if len(resp.Resources) > 0 {
+				log.Printf("Synced %d resources changed by other replicas", len(resp.Resources))
+			}
+			if resp.Catalog != "" {
+				log.Printf("Adopted catalog %s published by another replica", resp.Catalog)
+			}
+		}
+	}
+}
+
+// SyncReplica handles POST /api/v1/admin/sync.
+// It syncs with other replicas now, e.g. when notified of a change through
+// a message bus, instead of waiting for the next poll.
+func (h *Handler) SyncReplica(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.catalog.Sync(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "syncing with the registry: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
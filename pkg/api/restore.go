@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// Default restore retry backoff.
+const (
+	defaultRestoreBackoff    = 5 * time.Second
+	defaultMaxRestoreBackoff = 5 * time.Minute
+)
+
+// RestoreStep loads one piece of state from the registry, such as the
+// catalog or the lock store.
+type RestoreStep struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// RestoreOptions configures Restore.
+type RestoreOptions struct {
+	// Backoff is the wait before the first retry, doubling after each
+	// failed round up to MaxBackoff. Zero fields use 5s and 5m.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// restoreState tracks the startup restore. A handler that never runs
+// Restore counts as restored.
+type restoreState struct {
+	mu         sync.RWMutex
+	started    bool
+	pending    []string
+	attempts   int
+	lastErr    string
+	nextRetry  time.Time
+	overridden bool
+}
+
+// ready reports whether changes may be made: everything is restored or an
+// operator said not to wait.
+func (s *restoreState) ready() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !s.started || len(s.pending) == 0 || s.overridden
+}
+
+func (s *restoreState) status() model.RestoreStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st := model.RestoreStatus{
+		Restored:   !s.started || len(s.pending) == 0,
+		Overridden: s.overridden,
+		Pending:    append([]string(nil), s.pending...),
+		Attempts:   s.attempts,
+		LastError:  s.lastErr,
+	}
+	st.Ready = st.Restored || s.overridden
+	if !st.Restored && !s.nextRetry.IsZero() {
+		st.NextRetry = s.nextRetry.UTC().Format(time.RFC3339)
+	}
+	return st
+}
+
+// Restore loads state from the registry with steps, in order. It makes
+// one attempt before returning, so a healthy registry is fully restored by
+// the time the API serves. Steps that fail are retried in the background
+// with exponential backoff until they succeed or ctx ends; meanwhile
+// mutating endpoints return 503 and background jobs don't write, so an
+// empty in-memory state is never published over the registry's.
+func (h *Handler) Restore(ctx context.Context, steps []RestoreStep, opts RestoreOptions) {
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultRestoreBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultMaxRestoreBackoff
+	}
+
+	s := &h.restore
+	s.mu.Lock()
+	s.started = true
+	for _, step := range steps {
+		s.pending = append(s.pending, step.Name)
+	}
+	s.mu.Unlock()
+
+	remaining := h.restoreRound(ctx, steps)
+	if len(remaining) == 0 {
+		return
+	}
+	log.Printf("Warning: changes are refused until the restore succeeds (POST /api/v1/admin/restore/override accepts them anyway)")
+	backoff := opts.Backoff
+	s.retryAt(time.Now().Add(backoff))
+	go func() {
+		for len(remaining) > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			remaining = h.restoreRound(ctx, remaining)
+			backoff = min(2*backoff, opts.MaxBackoff)
+			s.retryAt(time.Now().Add(backoff))
+		}
+		log.Printf("Restored all state from registry")
+	}()
+}
+
+// retryAt records when the next retry is due.
+func (s *restoreState) retryAt(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextRetry = t
+}
+
+// restoreRound runs steps once each and returns those that failed.
+func (h *Handler) restoreRound(ctx context.Context, steps []RestoreStep) []RestoreStep {
+	var failed []RestoreStep
+	var lastErr string
+	for _, step := range steps {
+		if err := step.Run(ctx); err != nil {
+			log.Printf("Warning: failed to restore %s from registry: %v", step.Name, err)
+			failed = append(failed, step)
+			lastErr = fmt.Sprintf("%s: %v", step.Name, err)
+		}
+	}
+
+	s := &h.restore
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	s.lastErr = lastErr
+	s.pending = s.pending[:0]
+	for _, step := range failed {
+		s.pending = append(s.pending, step.Name)
+	}
+	return failed
+}
+
+// writeRestoring writes the 503 for a change attempted before the restore
+// finished.
+func writeRestoring(w http.ResponseWriter, st model.RestoreStatus) {
+	if st.NextRetry != "" {
+		if next, err := time.Parse(time.RFC3339, st.NextRetry); err == nil {
+			w.Header().Set("Retry-After", strconv.Itoa(max(int(time.Until(next).Seconds())+1, 1)))
+		}
+	}
+	writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+		"error":     fmt.Sprintf("state is still being restored from the registry (pending: %s); changes are not accepted yet", strings.Join(st.Pending, ", ")),
+		"code":      model.CodeRestoring,
+		"restoring": true,
+		"pending":   st.Pending,
+	})
+}
+
+// Readyz handles GET /readyz.
+// It answers 200 once state is restored from the registry, or an operator
+// overrode the wait, and 503 with what is still pending before that.
+func (h *Handler) Readyz(w http.ResponseWriter, _ *http.Request) {
+	st := h.restore.status()
+	status := http.StatusOK
+	if !st.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, st)
+}
+
+// OverrideRestore handles POST /api/v1/admin/restore/override.
+// It lets changes through before the restore finished. Retries continue,
+// but documents not yet restored, such as schedules, are written without
+// their registry contents, so it is for when the registry's state is known
+// to be lost or unwanted.
+func (h *Handler) OverrideRestore(w http.ResponseWriter, r *http.Request) {
+	s := &h.restore
+	s.mu.Lock()
+	s.overridden = true
+	pending := append([]string(nil), s.pending...)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, h.restore.status())
+	log.Printf("Audit: restore wait overridden by %s (pending: %s)", auth.Actor(r.Context()), strings.Join(pending, ", "))
+}
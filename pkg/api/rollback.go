@@ -0,0 +1,207 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// RollbackError is returned when a catalog rollback fails after some
+// resources were already rolled back. The catalog isn't re-tagged, so
+// rolling back again finishes the job: resources that already match the
+// catalog are skipped.
+type RollbackError struct {
+	Reference string
+	Restored  []string
+	Removed   []string
+	Pending   []string
+	Err       error
+}
+
+func (e *RollbackError) Error() string {
+	return fmt.Sprintf("rolling back to %s: %v (%d resources rolled back, %d pending)",
+		e.Reference, e.Err, len(e.Restored)+len(e.Removed), len(e.Pending))
+}
+
+func (e *RollbackError) Unwrap() error { return e.Err }
+
+// RollbackCatalog handles POST /api/v1/catalog/rollback.
+func (h *Handler) RollbackCatalog(w http.ResponseWriter, r *http.Request) {
+	var req model.CatalogRollbackRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+
+	reference := req.Version
+	if reference == "" {
+		reference = req.Digest
+	} else if req.Digest != "" {
+		writeError(w, http.StatusBadRequest, "only one of version or digest may be set")
+		return
+	}
+	if reference == "" {
+		writeError(w, http.StatusBadRequest, "version or digest is required")
+		return
+	}
+	if !h.checkFreeze(w, r, "") {
+		return
+	}
+
+	resp, err := h.rollbackCatalog(r.Context(), reference)
+	var partial *RollbackError
+	if errors.As(err, &partial) {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":    err.Error(),
+			"code":     model.CodeInternal,
+			"restored": partial.Restored,
+			"removed":  partial.Removed,
+			"pending":  partial.Pending,
+		})
+		return
+	}
+	if err != nil {
+		writeApplyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+	log.Printf("Audit: rolled back catalog to %s (%d restored, %d removed) by %s", reference, len(resp.Restored), len(resp.Removed), auth.Actor(r.Context()))
+}
+
+// rollbackCatalog makes a previously published catalog version current
+// again. Resource artifacts are re-pushed (or tombstoned) to match it, and
+// every one of them goes through the lock, scope and cooldown checks of
+// any other write before the first is pushed. A push failing partway
+// returns a *RollbackError.
+func (h *Handler) rollbackCatalog(ctx context.Context, reference string) (model.CatalogRollbackResponse, error) {
+	rb, err := h.catalog.pullRollback(ctx, reference)
+	if err != nil {
+		return model.CatalogRollbackResponse{}, err
+	}
+
+	// Hold every resource either catalog holds, in order, so no write
+	// lands between the checks and the pushes.
+	current := h.catalog.List()
+	keys := make([]string, 0, len(current)+len(rb.target))
+	for key := range current {
+		keys = append(keys, key)
+	}
+	for key := range rb.target {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		namespace, name, _ := strings.Cut(key, "/")
+		defer h.catalog.LockResource(namespace, name)()
+	}
+
+	// Re-read under the locks and keep the resources that change.
+	current = h.catalog.List()
+	var changed []string
+	for _, key := range keys {
+		manifest, ok := rb.target[key]
+		if !ok || !bytes.Equal(current[key], manifest) {
+			changed = append(changed, key)
+		}
+	}
+	for _, key := range changed {
+		if err := h.checkRollback(ctx, key, current[key], rb.target[key]); err != nil {
+			rb.tarGz.Close()
+			return model.CatalogRollbackResponse{}, err
+		}
+	}
+
+	resp := model.CatalogRollbackResponse{Restored: []string{}, Removed: []string{}}
+	for i, key := range changed {
+		namespace, name, _ := strings.Cut(key, "/")
+		manifest, restore := rb.target[key]
+		if restore {
+			err = h.rollbackResource(ctx, namespace, name, manifest)
+		} else {
+			err = h.rollbackRemove(ctx, namespace, name, current[key])
+		}
+		if err != nil {
+			rb.tarGz.Close()
+			return model.CatalogRollbackResponse{}, &RollbackError{
+				Reference: reference,
+				Restored:  resp.Restored,
+				Removed:   resp.Removed,
+				Pending:   changed[i:],
+				Err:       err,
+			}
+		}
+		if restore {
+			resp.Restored = append(resp.Restored, key)
+			noteProgress(ctx, "restored %s", key)
+		} else {
+			resp.Removed = append(resp.Removed, key)
+			noteProgress(ctx, "removed %s", key)
+		}
+	}
+
+	status, err := h.catalog.finishRollback(ctx, rb)
+	if err != nil {
+		return model.CatalogRollbackResponse{}, &RollbackError{
+			Reference: reference,
+			Restored:  resp.Restored,
+			Removed:   resp.Removed,
+			Pending:   []string{},
+			Err:       err,
+		}
+	}
+	resp.CatalogResponse = status
+	return resp, nil
+}
+
+// checkRollback checks that a rollback may change a resource from its
+// current manifest to the target one (nil removes it).
+func (h *Handler) checkRollback(ctx context.Context, key string, current, target []byte) error {
+	namespace, name, _ := strings.Cut(key, "/")
+	manifest := target
+	if manifest == nil {
+		manifest = current
+	}
+	resourceType := manifestType(manifest)
+
+	if err := h.checkLock(ctx, namespace, name); err != nil {
+		return err
+	}
+	if err := checkScope(ctx, namespace, resourceType); err != nil {
+		return err
+	}
+	return h.checkCooldown(ctx, namespace, name, resourceType)
+}
+
+// rollbackResource re-pushes a resource with a rolled back manifest.
+func (h *Handler) rollbackResource(ctx context.Context, namespace, name string, manifest []byte) error {
+	annotations := h.catalog.resourceAnnotations(ctx, namespace, name, manifest)
+	digest, version, err := h.ociClient.PushResource(ctx, namespace, name, manifest, annotations)
+	if err != nil {
+		return fmt.Errorf("restoring %s/%s: %w", namespace, name, err)
+	}
+	h.catalog.Set(namespace, name, manifest, ResourceMeta{Version: version, Digest: digest, Cost: costFromAnnotations(annotations)})
+	h.recordChange(namespace, name, manifestType(manifest))
+	return nil
+}
+
+// rollbackRemove tombstones a resource the rolled back catalog doesn't hold.
+func (h *Handler) rollbackRemove(ctx context.Context, namespace, name string, manifest []byte) error {
+	annotations := h.catalog.tombstoneAnnotations(ctx, namespace, name)
+	if _, _, err := h.ociClient.PushTombstone(ctx, namespace, name, manifest, annotations); err != nil {
+		return fmt.Errorf("removing %s/%s: %w", namespace, name, err)
+	}
+	h.catalog.Delete(namespace, name)
+	h.recordChange(namespace, name, manifestType(manifest))
+	return nil
+}
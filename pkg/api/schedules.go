@@ -0,0 +1,395 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+	"sigs.k8s.io/yaml"
+)
+
+// ScheduleStore holds resource schedules in memory and persists them to the
+// registry as a single JSON document on every change.
+type ScheduleStore struct {
+	ociClient oci.Interface
+	mu        sync.RWMutex
+	schedules map[string]model.Schedule // "namespace/resource/name"
+}
+
+// NewScheduleStore creates an empty schedule store.
+func NewScheduleStore(client oci.Interface) *ScheduleStore {
+	return &ScheduleStore{
+		ociClient: client,
+		schedules: make(map[string]model.Schedule),
+	}
+}
+
+func scheduleKey(namespace, resource, name string) string {
+	return namespace + "/" + resource + "/" + name
+}
+
+// List returns the schedules of one resource, or all schedules if resource
+// is empty, sorted by namespace, resource and name.
+func (ss *ScheduleStore) List(namespace, resource string) []model.Schedule {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	list := make([]model.Schedule, 0, len(ss.schedules))
+	for _, s := range ss.schedules {
+		if resource == "" || (s.Namespace == namespace && s.Resource == resource) {
+			list = append(list, s)
+		}
+	}
+	sortSchedules(list)
+	return list
+}
+
+// Put creates or replaces a schedule and persists the set.
+func (ss *ScheduleStore) Put(ctx context.Context, s model.Schedule) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	key := scheduleKey(s.Namespace, s.Resource, s.Name)
+	prev, existed := ss.schedules[key]
+	ss.schedules[key] = s
+	if err := ss.persistLocked(ctx); err != nil {
+		if existed {
+			ss.schedules[key] = prev
+		} else {
+			delete(ss.schedules, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a schedule and persists the set. It reports whether the
+// schedule existed.
+func (ss *ScheduleStore) Delete(ctx context.Context, namespace, resource, name string) (bool, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	key := scheduleKey(namespace, resource, name)
+	prev, ok := ss.schedules[key]
+	if !ok {
+		return false, nil
+	}
+	delete(ss.schedules, key)
+	if err := ss.persistLocked(ctx); err != nil {
+		ss.schedules[key] = prev
+		return true, err
+	}
+	return true, nil
+}
+
+// longestDeleteWindow returns the delete schedule with the longest
+// deletion window, and false if there is none.
+func (ss *ScheduleStore) longestDeleteWindow() (model.Schedule, bool) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	var longest model.Schedule
+	found := false
+	for _, s := range ss.schedules {
+		if s.Action == model.ScheduleActionDelete && (!found || s.DeleteDuration() > longest.DeleteDuration()) {
+			longest, found = s, true
+		}
+	}
+	return longest, found
+}
+
+// due returns the schedules with a run or a restore due at now.
+func (ss *ScheduleStore) due(now time.Time) []model.Schedule {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	var list []model.Schedule
+	for _, s := range ss.schedules {
+		runDue := !s.NextRun.IsZero() && !now.Before(s.NextRun)
+		restoreDue := !s.RestoreAt.IsZero() && !now.Before(s.RestoreAt)
+		if runDue || restoreDue {
+			list = append(list, s)
+		}
+	}
+	sortSchedules(list)
+	return list
+}
+
+// record stores the run state of executed schedules and persists the set.
+// Schedules deleted while they ran are not brought back.
+func (ss *ScheduleStore) record(ctx context.Context, ran []model.Schedule) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for _, s := range ran {
+		key := scheduleKey(s.Namespace, s.Resource, s.Name)
+		if _, ok := ss.schedules[key]; ok {
+			ss.schedules[key] = s
+		}
+	}
+	return ss.persistLocked(ctx)
+}
+
+func (ss *ScheduleStore) persistLocked(ctx context.Context) error {
+	list := make([]model.Schedule, 0, len(ss.schedules))
+	for _, s := range ss.schedules {
+		list = append(list, s)
+	}
+	sortSchedules(list)
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("encoding schedules: %w", err)
+	}
+	if err := ss.ociClient.PushSchedules(ctx, data); err != nil {
+		return fmt.Errorf("pushing schedules: %w", err)
+	}
+	return nil
+}
+
+// Restore loads schedules from the registry.
+func (ss *ScheduleStore) Restore(ctx context.Context) error {
+	data, err := ss.ociClient.PullSchedules(ctx)
+	if err != nil {
+		return fmt.Errorf("pulling schedules: %w", err)
+	}
+	if data == nil {
+		return nil
+	}
+
+	var list []model.Schedule
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("parsing schedules: %w", err)
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for _, s := range list {
+		ss.schedules[scheduleKey(s.Namespace, s.Resource, s.Name)] = s
+	}
+	log.Printf("Restored %d schedules from registry", len(list))
+	return nil
+}
+
+func sortSchedules(list []model.Schedule) {
+	sort.Slice(list, func(i, j int) bool {
+		return scheduleKey(list[i].Namespace, list[i].Resource, list[i].Name) <
+			scheduleKey(list[j].Namespace, list[j].Resource, list[j].Name)
+	})
+}
+
+// CreateSchedule handles POST /api/v1/resources/{name}/schedule.
+// It creates or replaces a named schedule on a live resource.
+func (h *Handler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	namespace, ok := resourceNamespace(w, r)
+	if !ok {
+		return
+	}
+	data, ok := h.catalog.Get(namespace, name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "resource %q not found", name)
+		return
+	}
+
+	var req model.ScheduleRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if req.Action == model.ScheduleActionScale {
+		if err := model.CheckReplicas("replicas", manifestType(data), req.Replicas); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+	}
+	if req.Action == model.ScheduleActionDelete {
+		if grace := h.catalog.GracePeriod(); req.DeleteDuration() >= grace {
+			writeError(w, http.StatusBadRequest, "deletion window %s must be shorter than the delete grace period (%s)", req.Duration, grace)
+			return
+		}
+	}
+
+	s := model.Schedule{
+		ScheduleRequest: req,
+		Namespace:       namespace,
+		Resource:        name,
+		NextRun:         req.Next(time.Now()),
+	}
+	if s.NextRun.IsZero() {
+		writeError(w, http.StatusBadRequest, "cron expression %q never matches", req.Cron)
+		return
+	}
+	if err := h.schedules.Put(r.Context(), s); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, s)
+	log.Printf("Scheduled %s of %s/%s (%s, next run %s)", req.Action, namespace, name, req.Cron, s.NextRun.Format(time.RFC3339))
+}
+
+// ListResourceSchedules handles GET /api/v1/resources/{name}/schedule.
+func (h *Handler) ListResourceSchedules(w http.ResponseWriter, r *http.Request) {
+	namespace, ok := resourceNamespace(w, r)
+	if !ok {
+		return
+	}
+	schedules := h.schedules.List(namespace, r.PathValue("name"))
+	writeJSON(w, http.StatusOK, map[string]any{
+		"schedules": schedules,
+		"count":     len(schedules),
+	})
+}
+
+// DeleteSchedule handles DELETE /api/v1/resources/{name}/schedule/{schedule}.
+// An open deletion window is abandoned: the resource stays deleted.
+func (h *Handler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	name, schedule := r.PathValue("name"), r.PathValue("schedule")
+	namespace, ok := resourceNamespace(w, r)
+	if !ok {
+		return
+	}
+	existed, err := h.schedules.Delete(r.Context(), namespace, name, schedule)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	if !existed {
+		writeError(w, http.StatusNotFound, "schedule %q not found", schedule)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("Deleted schedule %s of %s/%s", schedule, namespace, name)
+}
+
+// ListSchedules handles GET /api/v1/schedules.
+func (h *Handler) ListSchedules(w http.ResponseWriter, _ *http.Request) {
+	schedules := h.schedules.List("", "")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"schedules": schedules,
+		"count":     len(schedules),
+	})
+}
+
+// RunSchedules executes due schedules every interval until ctx is done.
+// A run missed while the server was down, read-only, the namespace was
+// frozen or the resource locked is executed once on the next tick.
+func (h *Handler) RunSchedules(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.runDueSchedules(ctx, time.Now().UTC().Truncate(time.Second))
+		}
+	}
+}
+
+func (h *Handler) runDueSchedules(ctx context.Context, now time.Time) {
+	if h.readOnly() {
+		return
+	}
+	var due []model.Schedule
+	for _, s := range h.schedules.due(now) {
+		if !h.frozen(s.Namespace, now) && !h.locked(s.Namespace, s.Resource) {
+			due = append(due, s)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+	for i := range due {
+		h.runSchedule(ctx, &due[i], now)
+	}
+	if err := h.schedules.record(ctx, due); err != nil {
+		log.Printf("Warning: failed to save schedules: %v", err)
+	}
+}
+
+// runSchedule closes an expired deletion window and executes a due run,
+// updating s's run state.
+func (h *Handler) runSchedule(ctx context.Context, s *model.Schedule, now time.Time) {
+	ctx = withSource(ctx, SourceSchedule)
+	key := scheduleKey(s.Namespace, s.Resource, s.Name)
+
+	if !s.RestoreAt.IsZero() && !now.Before(s.RestoreAt) {
+		_, err := h.restoreResource(ctx, s.Namespace, s.Resource)
+		if err != nil && !errors.Is(err, errResourceNotFound) {
+			s.LastError = fmt.Sprintf("restoring: %v", err)
+			log.Printf("Warning: schedule %s: %s", key, s.LastError)
+			return // retried on the next tick
+		}
+		s.RestoreAt = time.Time{}
+		log.Printf("Schedule %s: restored %s/%s", key, s.Namespace, s.Resource)
+	}
+
+	if s.NextRun.IsZero() || now.Before(s.NextRun) {
+		return
+	}
+	s.LastRun = now
+	s.NextRun = s.Next(now)
+	s.LastError = ""
+
+	var err error
+	switch s.Action {
+	case model.ScheduleActionScale:
+		err = h.scaleResource(ctx, s.Namespace, s.Resource, s.Replicas)
+	case model.ScheduleActionDelete:
+		if !s.RestoreAt.IsZero() {
+			return // the window is still open
+		}
+		// The grace period may have been shortened since the schedule was
+		// created; a window outliving it would purge the resource for good.
+		if grace := h.catalog.GracePeriod(); s.DeleteDuration() >= grace {
+			err = fmt.Errorf("deletion window %s is not shorter than the delete grace period (%s)", s.Duration, grace)
+			break
+		}
+		_, err = h.deleteResource(ctx, s.Namespace, s.Resource, "")
+		if err == nil {
+			s.RestoreAt = now.Add(s.DeleteDuration())
+		}
+	}
+	if err != nil {
+		s.LastError = err.Error()
+		log.Printf("Warning: schedule %s: %v", key, err)
+		return
+	}
+	log.Printf("Schedule %s: ran %s on %s/%s", key, s.Action, s.Namespace, s.Resource)
+}
+
+// scaleResource pushes a new version of a live resource with the given
+// replicas, keeping its secrets. It is a no-op if nothing changes.
+func (h *Handler) scaleResource(ctx context.Context, namespace, name string, replicas int) error {
+	data, ok := h.catalog.Get(namespace, name)
+	if !ok {
+		return errResourceNotFound
+	}
+	var pr model.PlatformResource
+	if err := yaml.Unmarshal(data, &pr); err != nil {
+		return fmt.Errorf("parsing stored manifest: %w", err)
+	}
+	if pr.Spec.Replicas == replicas {
+		return nil
+	}
+
+	req := model.ResourceRequest{APIVersion: pr.APIVersion, Name: name, Spec: pr.Spec}
+	req.Spec.Replicas = replicas
+	if err := req.ConvertToCurrent(); err != nil {
+		return err
+	}
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	_, err := h.applyResourceWith(ctx, namespace, &req, applyOptions{extra: secretDocuments(data)})
+	return err
+}
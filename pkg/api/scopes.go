@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"sigs.k8s.io/yaml"
+)
+
+// ScopeError is returned for a request outside the caller's scopes.
+type ScopeError struct {
+	User   string
+	Reason string
+}
+
+func (e *ScopeError) Error() string {
+	return fmt.Sprintf("%s is not allowed to %s", e.User, e.Reason)
+}
+
+// scopedReadPaths are the paths besides /api/v1/resources that identities
+// limited to namespaces or types may read. They don't reveal resources.
+var scopedReadPaths = []string{"/api/v1/whoami", "/api/v1/types", "/api/v1/templates", "/api/v1/messages", "/api/v1/regions"}
+
+// enforceScopes rejects requests outside the scopes of the caller's
+// identity, such as an API key's, with 403. It runs after authentication.
+// Resource writes are checked against the type they write in pushResource.
+func (h *Handler) enforceScopes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := auth.FromContext(r.Context())
+		if ok && id.Scopes != nil {
+			if err := h.requestInScope(r, id.User, id.Scopes); err != nil {
+				log.Printf("Warning: %s denied access to %s %s: %v", id.User, r.Method, r.URL.Path, err)
+				writeErrorCode(w, http.StatusForbidden, model.CodeOutOfScope, "%v", err)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) requestInScope(r *http.Request, user string, scopes *auth.Scopes) error {
+	read := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if scopes.ReadOnly && !read {
+		return &ScopeError{User: user, Reason: "make changes with a read-only key"}
+	}
+	if !scopes.Limited() {
+		return nil
+	}
+
+	path := r.URL.Path
+	rest, ok := strings.CutPrefix(path, "/api/v1/resources")
+	if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+		for _, prefix := range scopedReadPaths {
+			if read && (path == prefix || strings.HasPrefix(path, prefix+"/")) {
+				return nil
+			}
+		}
+		return &ScopeError{User: user, Reason: fmt.Sprintf("use %s %s, only resources", r.Method, path)}
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	if namespace == "" {
+		if rest == "" && read {
+			return nil // ListResources only lists resources in scope
+		}
+		namespace = defaultNamespace
+	}
+	if !scopes.AllowsNamespace(namespace) {
+		return &ScopeError{User: user, Reason: fmt.Sprintf("access namespace %q", namespace)}
+	}
+
+	name, _, _ := strings.Cut(strings.TrimPrefix(rest, "/"), "/")
+	if name == "" || name == "plan" {
+		return nil
+	}
+	data, ok := h.catalog.Get(namespace, name)
+	if !ok {
+		data, _, ok = h.catalog.GetDeleted(namespace, name)
+	}
+	if t := manifestType(data); ok && !scopes.AllowsType(t) {
+		return &ScopeError{User: user, Reason: fmt.Sprintf("access %s resources", t)}
+	}
+	return nil
+}
+
+// checkScope returns a *ScopeError if the caller's scopes don't cover a
+// resource of type t in namespace.
+func checkScope(ctx context.Context, namespace, t string) error {
+	id, ok := auth.FromContext(ctx)
+	if !ok || id.Scopes == nil {
+		return nil
+	}
+	if !id.Scopes.AllowsNamespace(namespace) {
+		return &ScopeError{User: id.User, Reason: fmt.Sprintf("access namespace %q", namespace)}
+	}
+	if !id.Scopes.AllowsType(t) {
+		return &ScopeError{User: id.User, Reason: fmt.Sprintf("access %s resources", t)}
+	}
+	return nil
+}
+
+// inScope reports whether the caller's scopes cover a stored resource.
+func inScope(ctx context.Context, namespace string, manifest []byte) bool {
+	id, ok := auth.FromContext(ctx)
+	if !ok || id.Scopes == nil {
+		return true
+	}
+	return id.Scopes.AllowsNamespace(namespace) && (len(id.Scopes.Types) == 0 || id.Scopes.AllowsType(manifestType(manifest)))
+}
+
+// manifestType returns the resource type of a stored manifest.
+func manifestType(manifest []byte) string {
+	var pr model.PlatformResource
+	if err := yaml.Unmarshal(manifest, &pr); err != nil {
+		return ""
+	}
+	return pr.Spec.Type
+}
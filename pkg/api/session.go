@@ -0,0 +1,50 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// CreateSession handles POST /api/v1/session.
+// It exchanges the caller's credentials, such as an API key or the proxy's
+// identity headers, for a session cookie, so a browser UI doesn't have to
+// hold them.
+func (h *Handler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	if h.sessions == nil {
+		writeError(w, http.StatusNotFound, "sessions are not enabled")
+		return
+	}
+	id, ok := auth.FromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	csrf, expiresAt, err := h.sessions.Issue(w, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	id.Method = "session"
+	writeJSON(w, http.StatusCreated, model.SessionResponse{
+		Identity:  id,
+		CSRFToken: csrf,
+		ExpiresAt: expiresAt.UTC().Format(time.RFC3339),
+	})
+	log.Printf("Started session for %s until %s", id.User, expiresAt.UTC().Format(time.RFC3339))
+}
+
+// DeleteSession handles DELETE /api/v1/session.
+// It clears the session cookie.
+func (h *Handler) DeleteSession(w http.ResponseWriter, _ *http.Request) {
+	if h.sessions == nil {
+		writeError(w, http.StatusNotFound, "sessions are not enabled")
+		return
+	}
+	h.sessions.Clear(w)
+	w.WriteHeader(http.StatusNoContent)
+}
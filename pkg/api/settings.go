@@ -0,0 +1,232 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// LogFilter is the standard logger's output with an adjustable level. At
+// the warning level it only passes warnings, errors and audit records.
+type LogFilter struct {
+	out io.Writer
+
+	mu    sync.Mutex
+	level string
+}
+
+// NewLogFilter creates a filter writing to out at level, info by default.
+func NewLogFilter(out io.Writer, level string) (*LogFilter, error) {
+	f := &LogFilter{out: out}
+	if level == "" {
+		level = model.LogLevelInfo
+	}
+	if err := f.SetLevel(level); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Install makes f the standard logger's output. f writes the timestamp
+// itself, after deciding whether to keep a line.
+func (f *LogFilter) Install() {
+	log.SetFlags(0)
+	log.SetOutput(f)
+}
+
+// Level returns the current level.
+func (f *LogFilter) Level() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.level
+}
+
+// SetLevel changes the level.
+func (f *LogFilter) SetLevel(level string) error {
+	if level != model.LogLevelInfo && level != model.LogLevelWarning {
+		return fmt.Errorf("invalid log level %q (want info or warning)", level)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.level = level
+	return nil
+}
+
+// Write writes one log line, unless the level filters it out.
+func (f *LogFilter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.level == model.LogLevelWarning &&
+		!bytes.HasPrefix(p, []byte("Warning:")) &&
+		!bytes.HasPrefix(p, []byte("Error")) &&
+		!bytes.HasPrefix(p, []byte("Audit:")) {
+		return len(p), nil
+	}
+	line := append([]byte(time.Now().Format("2006/01/02 15:04:05 ")), p...)
+	if _, err := f.out.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// adminOnly wraps a handler so that only members of the admin groups may
+// call it. Without admin groups, every caller may.
+func (h *Handler) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(h.adminGroups) == 0 {
+			next(w, r)
+			return
+		}
+		id, ok := auth.FromContext(r.Context())
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		for _, group := range id.Groups {
+			if slices.Contains(h.adminGroups, group) {
+				next(w, r)
+				return
+			}
+		}
+		log.Printf("Warning: %s denied access to %s %s: not in an admin group", id.User, r.Method, r.URL.Path)
+		writeError(w, http.StatusForbidden, "%s is not in an admin group", id.User)
+	}
+}
+
+// inAdminGroup reports whether the caller may do what adminOnly guards.
+func (h *Handler) inAdminGroup(ctx context.Context) bool {
+	if len(h.adminGroups) == 0 {
+		return true
+	}
+	id, ok := auth.FromContext(ctx)
+	return ok && slices.ContainsFunc(id.Groups, func(group string) bool {
+		return slices.Contains(h.adminGroups, group)
+	})
+}
+
+// settings returns the current runtime settings.
+func (h *Handler) settings() model.Settings {
+	maintenance := h.maintenance.status()
+	s := model.Settings{
+		CatalogPublishDebounce: h.catalog.PublishDebounce().String(),
+		DeleteGracePeriod:      h.catalog.GracePeriod().String(),
+		JobRetention:           h.jobs.Retain(),
+		ReadOnly:               maintenance.ReadOnly,
+		ReadOnlyMessage:        maintenance.Message,
+		LogLevel:               model.LogLevelInfo,
+	}
+	if h.logs != nil {
+		s.LogLevel = h.logs.Level()
+	}
+	return s
+}
+
+// GetSettings handles GET /api/v1/admin/settings.
+func (h *Handler) GetSettings(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.settings())
+}
+
+// UpdateSettings handles PUT /api/v1/admin/settings.
+// It changes the settings present in the body and leaves the others alone.
+// Changes take effect immediately and last until the server restarts.
+func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req model.SettingsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+
+	// Validate everything before changing anything.
+	parseDuration := func(field string, v *string) (time.Duration, bool) {
+		if v == nil {
+			return 0, true
+		}
+		d, err := time.ParseDuration(*v)
+		if err != nil || d < 0 {
+			writeError(w, http.StatusBadRequest, "invalid %s %q: want a non-negative duration such as 30s", field, *v)
+			return 0, false
+		}
+		return d, true
+	}
+	debounce, ok := parseDuration("catalogPublishDebounce", req.CatalogPublishDebounce)
+	if !ok {
+		return
+	}
+	grace, ok := parseDuration("deleteGracePeriod", req.DeleteGracePeriod)
+	if !ok {
+		return
+	}
+	if req.DeleteGracePeriod != nil {
+		if longest, ok := h.schedules.longestDeleteWindow(); ok && longest.DeleteDuration() >= grace {
+			writeError(w, http.StatusBadRequest, "invalid deleteGracePeriod %s: schedule %s has a %s deletion window, the grace period must be longer",
+				grace, scheduleKey(longest.Namespace, longest.Resource, longest.Name), longest.Duration)
+			return
+		}
+	}
+	if req.JobRetention != nil && *req.JobRetention < 1 {
+		writeError(w, http.StatusBadRequest, "invalid jobRetention %d: want a positive integer", *req.JobRetention)
+		return
+	}
+	if req.LogLevel != nil {
+		if h.logs == nil {
+			writeError(w, http.StatusBadRequest, "the log level is not adjustable on this server")
+			return
+		}
+		if *req.LogLevel != model.LogLevelInfo && *req.LogLevel != model.LogLevelWarning {
+			writeError(w, http.StatusBadRequest, "invalid logLevel %q: want info or warning", *req.LogLevel)
+			return
+		}
+	}
+
+	before := h.settings()
+	if req.CatalogPublishDebounce != nil {
+		h.catalog.SetPublishDebounce(debounce)
+	}
+	if req.DeleteGracePeriod != nil {
+		h.catalog.SetGracePeriod(grace)
+	}
+	if req.JobRetention != nil {
+		h.jobs.SetRetain(*req.JobRetention)
+	}
+	if req.ReadOnly != nil || req.ReadOnlyMessage != nil {
+		readOnly, message := before.ReadOnly, before.ReadOnlyMessage
+		if req.ReadOnly != nil {
+			readOnly = *req.ReadOnly
+		}
+		if req.ReadOnlyMessage != nil {
+			message = *req.ReadOnlyMessage
+		}
+		h.maintenance.set(readOnly, message)
+	}
+	if req.LogLevel != nil {
+		h.logs.SetLevel(*req.LogLevel)
+	}
+	after := h.settings()
+
+	var changes []string
+	change := func(field string, from, to any) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s %v -> %v", field, from, to))
+		}
+	}
+	change("catalogPublishDebounce", before.CatalogPublishDebounce, after.CatalogPublishDebounce)
+	change("deleteGracePeriod", before.DeleteGracePeriod, after.DeleteGracePeriod)
+	change("jobRetention", before.JobRetention, after.JobRetention)
+	change("readOnly", before.ReadOnly, after.ReadOnly)
+	change("readOnlyMessage", fmt.Sprintf("%q", before.ReadOnlyMessage), fmt.Sprintf("%q", after.ReadOnlyMessage))
+	change("logLevel", before.LogLevel, after.LogLevel)
+	if len(changes) > 0 {
+		log.Printf("Audit: settings changed (%s) by %s", strings.Join(changes, ", "), auth.Actor(r.Context()))
+	}
+	writeJSON(w, http.StatusOK, after)
+}
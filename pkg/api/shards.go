@@ -0,0 +1,223 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+	"sigs.k8s.io/yaml"
+)
+
+// shardManifestDir holds the per-shard Flux objects inside a partitioned
+// root catalog's manifests/ directory.
+const shardManifestDir = "shards/"
+
+// maxShardBuckets bounds hash partitioning.
+const maxShardBuckets = 256
+
+// Sharding describes how the catalog is partitioned into separately
+// published shards. The zero value publishes a single catalog.
+type Sharding struct {
+	ByNamespace bool
+	Buckets     int // hash buckets; zero unless partitioning by hash
+}
+
+// ParseSharding parses a CATALOG_SHARDING value: "none" (or empty),
+// "namespace", or "hash:N".
+func ParseSharding(s string) (Sharding, error) {
+	switch {
+	case s == "" || s == "none":
+		return Sharding{}, nil
+	case s == "namespace":
+		return Sharding{ByNamespace: true}, nil
+	case strings.HasPrefix(s, "hash:"):
+		n, err := strconv.Atoi(strings.TrimPrefix(s, "hash:"))
+		if err != nil || n < 1 || n > maxShardBuckets {
+			return Sharding{}, fmt.Errorf("hash sharding needs between 1 and %d buckets, got %q", maxShardBuckets, s)
+		}
+		return Sharding{Buckets: n}, nil
+	}
+	return Sharding{}, fmt.Errorf("unknown catalog sharding %q (want none, namespace or hash:N)", s)
+}
+
+// Enabled reports whether the catalog is partitioned.
+func (s Sharding) Enabled() bool {
+	return s.ByNamespace || s.Buckets > 0
+}
+
+// String returns the CATALOG_SHARDING form of s.
+func (s Sharding) String() string {
+	switch {
+	case s.ByNamespace:
+		return "namespace"
+	case s.Buckets > 0:
+		return fmt.Sprintf("hash:%d", s.Buckets)
+	}
+	return "none"
+}
+
+// shardOf returns the shard holding the resource with the given
+// "namespace/name" key.
+func (s Sharding) shardOf(key string) string {
+	if s.ByNamespace {
+		namespace, _, _ := strings.Cut(key, "/")
+		return namespace
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return fmt.Sprintf("bucket-%03d", h.Sum32()%uint32(s.Buckets))
+}
+
+// publishedShard is the last version of a shard pushed to the registry.
+type publishedShard struct {
+	sum    [sha256.Size]byte // hash of the shard's resources
+	digest string
+}
+
+// pushShards partitions resources into shards and publishes every shard
+// whose contents changed since it was last pushed. It returns the current
+// digest of each shard. Shards that no longer hold any resources are simply
+// left out, so Flux prunes their Kustomization and everything it applied.
+func (cm *CatalogManager) pushShards(ctx context.Context, resources map[string][]byte) (map[string]string, error) {
+	groups := make(map[string]map[string][]byte)
+	for key, manifest := range resources {
+		shard := cm.sharding.shardOf(key)
+		if groups[shard] == nil {
+			groups[shard] = make(map[string][]byte)
+		}
+		groups[shard][key] = manifest
+	}
+
+	digests := make(map[string]string, len(groups))
+	pushed := 0
+	for shard, group := range groups {
+		sum := shardSum(group)
+
+		cm.mu.RLock()
+		published, ok := cm.shards[shard]
+		cm.mu.RUnlock()
+		if ok && published.sum == sum {
+			digests[shard] = published.digest
+			continue
+		}
+
+		tarGz, err := cm.buildTarGz(group, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("building shard %s: %w", shard, err)
+		}
+		digest, _, err := cm.ociClient.PushCatalogShard(ctx, shard, tarGz)
+		tarGz.Close()
+		if err != nil {
+			return nil, fmt.Errorf("pushing shard %s: %w", shard, err)
+		}
+
+		cm.mu.Lock()
+		cm.shards[shard] = publishedShard{sum: sum, digest: digest}
+		cm.mu.Unlock()
+		digests[shard] = digest
+		pushed++
+		noteProgress(ctx, "pushed catalog shard %s", shard)
+	}
+
+	log.Printf("Pushed %d of %d catalog shards", pushed, len(groups))
+	return digests, nil
+}
+
+// shardObjects renders the Flux objects for every shard, keyed by their path
+// in the root catalog.
+func (cm *CatalogManager) shardObjects(digests map[string]string) (map[string][]byte, error) {
+	objects := make(map[string][]byte, len(digests))
+	for shard, digest := range digests {
+		out, err := renderShardFluxObjects(shard, cm.ociClient.CatalogShardURL(shard), digest)
+		if err != nil {
+			return nil, fmt.Errorf("rendering flux objects for shard %s: %w", shard, err)
+		}
+		objects[shardManifestDir+shard+".yaml"] = out
+	}
+	return objects, nil
+}
+
+// expandShards adds the resources of every shard referenced by a root
+// catalog tarball to resources, pulling each shard at the digest the root
+// pins. It returns the shard digests.
+func (cm *CatalogManager) expandShards(ctx context.Context, tarGz *oci.Spool, resources map[string][]byte) (map[string]string, error) {
+	files, err := readTarGzFiles(tarGz.Reader())
+	if err != nil {
+		return nil, err
+	}
+
+	digests := make(map[string]string)
+	for _, f := range files {
+		filename, ok := strings.CutPrefix(f.name, "manifests/"+shardManifestDir)
+		if !ok {
+			continue
+		}
+		shard := strings.TrimSuffix(filename, ".yaml")
+
+		digest, err := pinnedDigest(f.data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", f.name, err)
+		}
+		if digest == "" {
+			return nil, fmt.Errorf("shard %s is not pinned to a digest", shard)
+		}
+
+		_, shardTarGz, err := cm.ociClient.PullCatalogShard(ctx, shard, digest)
+		if err != nil {
+			return nil, fmt.Errorf("pulling shard %s: %w", shard, err)
+		}
+		shardResources, err := readCatalogTarGz(shardTarGz.Reader())
+		shardTarGz.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading shard %s: %w", shard, err)
+		}
+		for key, manifest := range shardResources {
+			resources[key] = manifest
+		}
+		digests[shard] = digest
+	}
+	return digests, nil
+}
+
+// pinnedDigest returns the digest the OCIRepository in a rendered Flux
+// object pair is pinned to, or "" if it follows a tag.
+func pinnedDigest(objects []byte) (string, error) {
+	// The OCIRepository is the first document.
+	first, _, _ := bytes.Cut(objects, []byte("\n---\n"))
+	var ociRepo struct {
+		Spec struct {
+			Ref struct {
+				Digest string `json:"digest"`
+			} `json:"ref"`
+		} `json:"spec"`
+	}
+	if err := yaml.Unmarshal(first, &ociRepo); err != nil {
+		return "", err
+	}
+	return ociRepo.Spec.Ref.Digest, nil
+}
+
+// shardSum hashes a shard's resources in key order.
+func shardSum(resources map[string][]byte) [sha256.Size]byte {
+	keys := make([]string, 0, len(resources))
+	for key := range resources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s\n%d\n", key, len(resources[key]))
+		h.Write(resources[key])
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
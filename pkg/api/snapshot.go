@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// snapshotFormat is the version of the catalog snapshot file.
+const snapshotFormat = 1
+
+// catalogSnapshot is the on-disk copy of the catalog's resources.
+type catalogSnapshot struct {
+	Format    int                `json:"format"`
+	TakenAt   time.Time          `json:"takenAt"`
+	Resources []snapshotResource `json:"resources"`
+}
+
+// snapshotResource is one live or soft-deleted resource in a snapshot.
+type snapshotResource struct {
+	Namespace string       `json:"namespace"`
+	Name      string       `json:"name"`
+	Manifest  []byte       `json:"manifest"`
+	Meta      ResourceMeta `json:"meta"`
+	DeletedAt *time.Time   `json:"deletedAt,omitempty"`
+}
+
+// SaveSnapshot writes the catalog's live and soft-deleted resources to
+// path, replacing it atomically. Manifests may hold secrets, so the file
+// is only readable by its owner.
+func (cm *CatalogManager) SaveSnapshot(path string) (int, error) {
+	snap := catalogSnapshot{Format: snapshotFormat, TakenAt: time.Now().UTC()}
+	cm.mu.RLock()
+	for key, manifest := range cm.resources {
+		ns, name, _ := strings.Cut(key, "/")
+		snap.Resources = append(snap.Resources, snapshotResource{Namespace: ns, Name: name, Manifest: manifest, Meta: cm.meta[key]})
+	}
+	for key, entry := range cm.deleted {
+		ns, name, _ := strings.Cut(key, "/")
+		deletedAt := entry.deletedAt
+		snap.Resources = append(snap.Resources, snapshotResource{Namespace: ns, Name: name, Manifest: entry.manifest, Meta: entry.meta, DeletedAt: &deletedAt})
+	}
+	cm.mu.RUnlock()
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return 0, fmt.Errorf("encoding catalog snapshot: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("writing catalog snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("writing catalog snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, fmt.Errorf("writing catalog snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, fmt.Errorf("writing catalog snapshot: %w", err)
+	}
+	return len(snap.Resources), nil
+}
+
+// LoadSnapshot fills the catalog from a snapshot written by SaveSnapshot,
+// so it can serve reads before the registry is reached. A missing file is
+// not an error. Restore later replaces the snapshot's contents with the
+// registry's.
+func (cm *CatalogManager) LoadSnapshot(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading catalog snapshot: %w", err)
+	}
+	var snap catalogSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return 0, fmt.Errorf("parsing catalog snapshot: %w", err)
+	}
+	if snap.Format != snapshotFormat {
+		return 0, fmt.Errorf("unsupported catalog snapshot format %d", snap.Format)
+	}
+
+	for _, r := range snap.Resources {
+		cm.Set(r.Namespace, r.Name, r.Manifest, r.Meta)
+		if r.DeletedAt != nil {
+			cm.markDeleted(r.Namespace, r.Name, *r.DeletedAt)
+		}
+	}
+	log.Printf("Loaded %d resources from catalog snapshot taken at %s", len(snap.Resources), snap.TakenAt.Format(time.RFC3339))
+	return len(snap.Resources), nil
+}
+
+// RunSnapshots saves a snapshot to path every interval while the catalog
+// has changed since the last one, until ctx is done.
+func (cm *CatalogManager) RunSnapshots(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	saved := int64(-1) // changes covered by the last snapshot
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cm.metrics.mu.Lock()
+		changes := cm.metrics.changes
+		cm.metrics.mu.Unlock()
+		if changes == saved {
+			continue
+		}
+		if _, err := cm.SaveSnapshot(path); err != nil {
+			log.Printf("Warning: failed to save catalog snapshot: %v", err)
+			continue
+		}
+		saved = changes
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"sigs.k8s.io/yaml"
+)
+
+// GetStats handles GET /api/v1/stats.
+// It summarises live resources by type, size, region and namespace, counts
+// resource creations per ?bucket=day|week|month (default day), and reports
+// catalog publish frequency from the catalog's version tags.
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = "day"
+	}
+	period, err := periodFunc(bucket)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	stats := model.StatsResponse{
+		ByType:      map[string]int{},
+		BySize:      map[string]int{},
+		ByRegion:    map[string]int{},
+		ByNamespace: map[string]int{},
+		Bucket:      bucket,
+		Deleted:     len(h.catalog.ListDeleted()),
+	}
+
+	created := map[string]int{}
+	for key, data := range h.catalog.List() {
+		ns, name, _ := strings.Cut(key, "/")
+		stats.Resources++
+		stats.ByNamespace[ns]++
+
+		var pr model.PlatformResource
+		if err := yaml.Unmarshal(data, &pr); err == nil {
+			stats.ByType[pr.Spec.Type]++
+			stats.BySize[pr.Spec.Size]++
+			region := pr.Spec.Region
+			if region == "" {
+				region = "unspecified"
+			}
+			stats.ByRegion[region]++
+		}
+
+		if meta, ok := h.catalog.Meta(ns, name); ok && !meta.CreatedAt.IsZero() {
+			created[period(meta.CreatedAt)]++
+		}
+	}
+	stats.Created = sortedPeriods(created)
+
+	stats.Catalog = h.catalogStats(r, period)
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// catalogStats derives publish frequency from the catalog's version tags.
+func (h *Handler) catalogStats(r *http.Request, period func(time.Time) string) model.CatalogStats {
+	stats := model.CatalogStats{ByPeriod: []model.PeriodCount{}}
+
+	versions, err := h.ociClient.ListCatalogVersions(r.Context())
+	if err != nil {
+		stats.Error = fmt.Sprintf("listing catalog history: %v", err)
+		return stats
+	}
+
+	var first, last time.Time
+	counts := map[string]int{}
+	for _, v := range versions {
+		t, err := time.Parse(time.RFC3339, v.CreatedAt)
+		if err != nil {
+			continue
+		}
+		stats.Publishes++
+		counts[period(t)]++
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	if stats.Publishes == 0 {
+		return stats
+	}
+
+	stats.First = first.UTC().Format(time.RFC3339)
+	stats.Last = last.UTC().Format(time.RFC3339)
+	days := last.Sub(first).Hours() / 24
+	if days < 1 {
+		days = 1
+	}
+	stats.PerDay = math.Round(float64(stats.Publishes)/days*100) / 100
+	stats.ByPeriod = sortedPeriods(counts)
+	return stats
+}
+
+// periodFunc returns a function naming the bucket a time falls into.
+func periodFunc(bucket string) (func(time.Time) string, error) {
+	switch bucket {
+	case "day":
+		return func(t time.Time) string { return t.UTC().Format("2006-01-02") }, nil
+	case "week":
+		return func(t time.Time) string {
+			year, week := t.UTC().ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}, nil
+	case "month":
+		return func(t time.Time) string { return t.UTC().Format("2006-01") }, nil
+	default:
+		return nil, fmt.Errorf("invalid bucket %q: must be day, week or month", bucket)
+	}
+}
+
+func sortedPeriods(counts map[string]int) []model.PeriodCount {
+	out := make([]model.PeriodCount, 0, len(counts))
+	for p, n := range counts {
+		out = append(out, model.PeriodCount{Period: p, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Period < out[j].Period })
+	return out
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// maxCatalogSubsets bounds how many filtered catalogs are cached for the
+// current catalog. Beyond it, the cache is emptied and refilled on demand.
+const maxCatalogSubsets = 64
+
+// catalogSubset is a tarball of the resources of the published catalog
+// whose labels match a selector.
+type catalogSubset struct {
+	tarGz     []byte
+	digest    string // sha256 of tarGz
+	resources int
+}
+
+// CatalogSubset returns the digest of the published catalog and a tarball
+// of its resources carrying every label in labels, with the Namespace
+// manifests they need. Subsets are built on first request and cached until
+// the next catalog is published.
+func (cm *CatalogManager) CatalogSubset(labels map[string]string) (string, catalogSubset, error) {
+	key := model.FormatLabelSelector(labels)
+
+	cm.mu.RLock()
+	catalogDigest := cm.status.Digest
+	published := cm.published
+	subset, ok := cm.subsets[key]
+	namespaces := cm.namespaces
+	cm.mu.RUnlock()
+	if catalogDigest == "" || published == nil {
+		return "", catalogSubset{}, ErrCatalogNotPublished
+	}
+	if ok {
+		return catalogDigest, subset, nil
+	}
+
+	selector := model.ClusterSelector{MatchLabels: labels}
+	selected := selectClusterResources(selector, published)
+	subsetNamespaces := make(map[string][]byte)
+	for namespace, manifest := range namespaces {
+		if clusterHoldsNamespace(selector, namespace, selected) {
+			subsetNamespaces[namespace] = manifest
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeCatalogTarGz(&buf, selected, subsetNamespaces, nil, cm.gzipLevel); err != nil {
+		return "", catalogSubset{}, fmt.Errorf("building catalog subset: %w", err)
+	}
+	subset = catalogSubset{
+		tarGz:     buf.Bytes(),
+		digest:    fmt.Sprintf("sha256:%x", sha256.Sum256(buf.Bytes())),
+		resources: len(selected),
+	}
+
+	cm.mu.Lock()
+	// Don't cache a subset of a catalog replaced while it was built.
+	if cm.status.Digest == catalogDigest {
+		if cm.subsets == nil || len(cm.subsets) >= maxCatalogSubsets {
+			cm.subsets = make(map[string]catalogSubset)
+		}
+		cm.subsets[key] = subset
+	}
+	cm.mu.Unlock()
+	return catalogDigest, subset, nil
+}
+
+// serveCatalogSubset handles GET /api/v1/catalog?selector=team%3Dpayments.
+// It serves the published catalog filtered to the resources matching an
+// equality-based label selector, laid out like the full catalog so a
+// team-scoped Flux Kustomization can apply it.
+func (h *Handler) serveCatalogSubset(w http.ResponseWriter, r *http.Request, selector string) {
+	labels, err := model.ParseLabelSelector(selector)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid selector: %v", err)
+		return
+	}
+
+	catalogDigest, subset, err := h.catalog.CatalogSubset(labels)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "%v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="catalog.tar.gz"`)
+	w.Header().Set("ETag", `"`+subset.digest+`"`)
+	w.Header().Set("X-Catalog-Digest", catalogDigest)
+	w.Header().Set("X-Catalog-Resources", strconv.Itoa(subset.resources))
+	http.ServeContent(w, r, "catalog.tar.gz", time.Time{}, bytes.NewReader(subset.tarGz))
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// TemplateStore holds resource templates in memory and persists them to the
+// registry as a single JSON document on every change.
+type TemplateStore struct {
+	ociClient oci.Interface
+	mu        sync.RWMutex
+	templates map[string]model.Template
+}
+
+// NewTemplateStore creates an empty template store.
+func NewTemplateStore(client oci.Interface) *TemplateStore {
+	return &TemplateStore{
+		ociClient: client,
+		templates: make(map[string]model.Template),
+	}
+}
+
+// Get returns a template by name.
+func (ts *TemplateStore) Get(name string) (model.Template, bool) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	t, ok := ts.templates[name]
+	return t, ok
+}
+
+// List returns all templates sorted by name.
+func (ts *TemplateStore) List() []model.Template {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	list := make([]model.Template, 0, len(ts.templates))
+	for _, t := range ts.templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Put creates or replaces a template and persists the set.
+func (ts *TemplateStore) Put(ctx context.Context, t model.Template) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	prev, existed := ts.templates[t.Name]
+	ts.templates[t.Name] = t
+	if err := ts.persistLocked(ctx); err != nil {
+		if existed {
+			ts.templates[t.Name] = prev
+		} else {
+			delete(ts.templates, t.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a template and persists the set. It reports whether the
+// template existed.
+func (ts *TemplateStore) Delete(ctx context.Context, name string) (bool, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	prev, ok := ts.templates[name]
+	if !ok {
+		return false, nil
+	}
+	delete(ts.templates, name)
+	if err := ts.persistLocked(ctx); err != nil {
+		ts.templates[name] = prev
+		return true, err
+	}
+	return true, nil
+}
+
+func (ts *TemplateStore) persistLocked(ctx context.Context) error {
+	list := make([]model.Template, 0, len(ts.templates))
+	for _, t := range ts.templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("encoding templates: %w", err)
+	}
+	if err := ts.ociClient.PushTemplates(ctx, data); err != nil {
+		return fmt.Errorf("pushing templates: %w", err)
+	}
+	return nil
+}
+
+// Restore loads templates from the registry.
+func (ts *TemplateStore) Restore(ctx context.Context) error {
+	data, err := ts.ociClient.PullTemplates(ctx)
+	if err != nil {
+		return fmt.Errorf("pulling templates: %w", err)
+	}
+	if data == nil {
+		return nil
+	}
+
+	var list []model.Template
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("parsing templates: %w", err)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for _, t := range list {
+		ts.templates[t.Name] = t
+	}
+	log.Printf("Restored %d templates from registry", len(list))
+	return nil
+}
+
+// CreateTemplate handles POST /api/v1/templates.
+// It creates or replaces a template.
+func (h *Handler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var t model.Template
+	if err := decodeJSON(r, &t); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: %v", err)
+		return
+	}
+	if err := t.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := h.templates.Put(r.Context(), t); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, t)
+	log.Printf("Saved template %s", t.Name)
+}
+
+// ListTemplates handles GET /api/v1/templates.
+func (h *Handler) ListTemplates(w http.ResponseWriter, _ *http.Request) {
+	templates := h.templates.List()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"templates": templates,
+		"count":     len(templates),
+	})
+}
+
+// GetTemplate handles GET /api/v1/templates/{name}.
+func (h *Handler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	t, ok := h.templates.Get(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "template %q not found", name)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// DeleteTemplate handles DELETE /api/v1/templates/{name}.
+// Resources created from the template are unaffected.
+func (h *Handler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	existed, err := h.templates.Delete(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	if !existed {
+		writeError(w, http.StatusNotFound, "template %q not found", name)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("Deleted template %s", name)
+}
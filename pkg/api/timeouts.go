@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// Default request time limits.
+const (
+	defaultRequestTimeout    = 2 * time.Minute
+	defaultMaxRequestTimeout = 10 * time.Minute
+)
+
+// RequestTimeouts bounds how long an API request may run. Clients can ask
+// for a shorter or longer limit with ?timeout=<duration>, up to Max.
+type RequestTimeouts struct {
+	Default time.Duration
+	Max     time.Duration
+}
+
+// withDefaults fills in zero fields.
+func (t RequestTimeouts) withDefaults() RequestTimeouts {
+	if t.Default <= 0 {
+		t.Default = defaultRequestTimeout
+	}
+	if t.Max <= 0 {
+		t.Max = defaultMaxRequestTimeout
+	}
+	if t.Default > t.Max {
+		t.Default = t.Max
+	}
+	return t
+}
+
+// WithTimeouts bounds every /api/ request with a deadline and records its
+// progress, so a request stalled on the registry fails with 504 Gateway
+// Timeout and a list of the steps it completed instead of hanging.
+func (h *Handler) WithTimeouts(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		timeout := h.timeouts.Default
+		if v := r.URL.Query().Get("timeout"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				writeError(w, http.StatusBadRequest, "invalid timeout %q", v)
+				return
+			}
+			timeout = min(d, h.timeouts.Max)
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		progress := &requestProgress{}
+		ctx = context.WithValue(ctx, progressKey{}, progress)
+		next.ServeHTTP(&progressWriter{ResponseWriter: w, progress: progress}, r.WithContext(ctx))
+	})
+}
+
+// requestProgress lists the externally visible steps a request completed.
+type requestProgress struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+type progressKey struct{}
+
+// noteProgress records a completed step of the request handling ctx, such
+// as a push to the registry. It does nothing outside a request.
+func noteProgress(ctx context.Context, format string, args ...any) {
+	p, ok := ctx.Value(progressKey{}).(*requestProgress)
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.steps = append(p.steps, fmt.Sprintf(format, args...))
+}
+
+// progressWriter carries a request's progress to writeError.
+type progressWriter struct {
+	http.ResponseWriter
+	progress *requestProgress
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (pw *progressWriter) Unwrap() http.ResponseWriter {
+	return pw.ResponseWriter
+}
+
+// writeTimeout writes a 504 listing the steps completed before the
+// deadline, so the client knows what may need retrying.
+func writeTimeout(w http.ResponseWriter, msg string) {
+	completed := []string{}
+	if pw, ok := w.(*progressWriter); ok {
+		pw.progress.mu.Lock()
+		completed = append(completed, pw.progress.steps...)
+		pw.progress.mu.Unlock()
+	}
+	writeJSON(w, http.StatusGatewayTimeout, map[string]any{
+		"error":     msg,
+		"code":      model.CodeTimeout,
+		"completed": completed,
+	})
+}
+
+// timedOut reports whether any of args is an error caused by a deadline.
+func timedOut(args []any) bool {
+	for _, arg := range args {
+		if err, ok := arg.(error); ok && errors.Is(err, context.DeadlineExceeded) {
+			return true
+		}
+	}
+	return false
+}
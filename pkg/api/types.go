@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// ListResourceTypes handles GET /api/v1/types.
+// The response also lists the sizes, with their deprecations.
+func (h *Handler) ListResourceTypes(w http.ResponseWriter, _ *http.Request) {
+	types := model.ResourceTypes()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"types": types,
+		"count": len(types),
+		"sizes": model.Sizes(),
+	})
+}
+
+// GetResourceType handles GET /api/v1/types/{type}.
+func (h *Handler) GetResourceType(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("type")
+	t, ok := model.LookupResourceType(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "resource type %q not found", name)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed ui
+var uiFiles embed.FS
+
+// uiCSP only lets the console load its own files and call its own API.
+const uiCSP = "default-src 'self'; frame-ancestors 'none'; base-uri 'none'; form-action 'self'"
+
+// registerUIRoutes serves the embedded console under /ui/. It is static:
+// everything it shows comes from the API, with the caller's credentials.
+func (h *Handler) registerUIRoutes(mux *http.ServeMux) {
+	files, _ := fs.Sub(uiFiles, "ui")
+	static := http.StripPrefix("/ui/", http.FileServerFS(files))
+	mux.Handle("GET /ui/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", uiCSP)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "no-cache")
+		static.ServeHTTP(w, r)
+	}))
+	mux.Handle("GET /ui", http.RedirectHandler("/ui/", http.StatusMovedPermanently))
+}
@@ -0,0 +1,371 @@
+// gitops-squared console: a thin client of the API it is served by.
+// Everything it shows comes from /api/v1, with the caller's own
+// credentials.
+"use strict";
+
+const eventTypes = [
+  "resource.created", "resource.updated", "resource.deleted", "resource.restored",
+  "resource.quarantined", "resource.unmanaged", "resource.admission_denied", "proposal.merged",
+  "catalog.published", "catalog.publish_failed", "catalog.rolled_back",
+  "catalog.promoted",
+];
+const maxEvents = 200;
+const csrfKey = "gitops-squared.csrf";
+
+const $ = (id) => document.getElementById(id);
+let selected = null; // {namespace, name, deleted}
+let refreshTimer = null;
+
+// api calls the API and returns the decoded JSON body, or the text for
+// non-JSON responses. Failed requests throw with the API's error message.
+async function api(method, path, body) {
+  const headers = {};
+  const csrf = localStorage.getItem(csrfKey);
+  if (csrf && method !== "GET") headers["X-CSRF-Token"] = csrf;
+  if (body !== undefined) headers["Content-Type"] = "application/json";
+  const resp = await fetch(path, {
+    method,
+    headers,
+    credentials: "same-origin",
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+  const type = resp.headers.get("Content-Type") || "";
+  const data = resp.status === 204 ? null : type.startsWith("application/json") ? await resp.json() : await resp.text();
+  if (!resp.ok) {
+    const err = new Error((data && data.error) || `${resp.status} ${resp.statusText}`);
+    err.status = resp.status;
+    err.code = data && data.code;
+    err.details = data && data.details;
+    throw err;
+  }
+  return data;
+}
+
+function showError(err) {
+  const box = $("error");
+  let text = err.message || String(err);
+  if (err.details) text = err.details.map((d) => `${d.field}: ${d.message}`).join("\n");
+  if (err.code) text += ` [${err.code}]`;
+  box.textContent = text;
+  box.hidden = false;
+  clearTimeout(showError.timer);
+  showError.timer = setTimeout(() => { box.hidden = true; }, 8000);
+}
+
+function query(params) {
+  const q = new URLSearchParams();
+  for (const [k, v] of Object.entries(params)) if (v) q.set(k, v);
+  const s = q.toString();
+  return s ? "?" + s : "";
+}
+
+function cell(row, text) {
+  const td = document.createElement("td");
+  td.textContent = text == null ? "" : String(text);
+  row.appendChild(td);
+  return td;
+}
+
+// Identity and sessions.
+
+async function loadIdentity() {
+  let id = null;
+  try {
+    id = await api("GET", "/api/v1/whoami");
+  } catch (err) {
+    if (err.status !== 401) throw err;
+  }
+  const sessionWithoutToken = id && id.method === "session" && !localStorage.getItem(csrfKey);
+  $("whoami").textContent = id ? `${id.user} (${id.method})` : "anonymous";
+  $("logout").hidden = !id || id.method !== "session";
+  $("login").hidden = !!id && !sessionWithoutToken;
+}
+
+async function login(ev) {
+  ev.preventDefault();
+  const token = ev.target.token.value.trim();
+  try {
+    const resp = await fetch("/api/v1/session", {
+      method: "POST",
+      headers: { Authorization: "Bearer " + token },
+      credentials: "same-origin",
+    });
+    const data = await resp.json();
+    if (!resp.ok) throw new Error(resp.status === 404 ? "Sessions are not enabled on this server (set SESSION_SECRET)." : data.error);
+    localStorage.setItem(csrfKey, data.csrfToken);
+    ev.target.reset();
+    await start();
+  } catch (err) {
+    showError(err);
+  }
+}
+
+async function logout() {
+  try {
+    await api("DELETE", "/api/v1/session");
+  } catch (err) {
+    showError(err);
+  }
+  localStorage.removeItem(csrfKey);
+  location.reload();
+}
+
+// Catalog status.
+
+async function loadCatalog() {
+  const el = $("catalog");
+  try {
+    const c = await api("GET", "/api/v1/catalog");
+    el.textContent = `catalog ${c.version || c.digest.slice(0, 19)} · ${c.resourceCount} resources · built ${c.builtAt}`;
+  } catch (err) {
+    el.textContent = err.status === 503 ? "catalog not published yet" : "catalog unavailable";
+  }
+}
+
+// Resources.
+
+async function loadNamespaces() {
+  try {
+    const data = await api("GET", "/api/v1/namespaces");
+    const select = $("namespace");
+    for (const ns of data.namespaces) {
+      const opt = document.createElement("option");
+      opt.value = opt.textContent = ns.name;
+      select.appendChild(opt);
+    }
+  } catch (err) {
+    // Scoped API keys may not list namespaces; the filter stays at All.
+  }
+}
+
+// deprecationNote describes a deprecated type or size for its option.
+function deprecationNote(d) {
+  return d ? ` (deprecated${d.replacement ? `, use ${d.replacement}` : ""})` : "";
+}
+
+async function loadTypes() {
+  const data = await api("GET", "/api/v1/types");
+  const select = $("create-type");
+  select.replaceChildren();
+  for (const t of data.types) {
+    if (t.deprecated && t.deprecated.removed) continue;
+    const opt = document.createElement("option");
+    opt.value = t.name;
+    opt.dataset.minReplicas = (t.replicas && t.replicas.min) || 1;
+    opt.dataset.maxReplicas = (t.replicas && t.replicas.max) || 10;
+    opt.textContent = (t.description ? `${t.name} — ${t.description}` : t.name) + deprecationNote(t.deprecated);
+    select.appendChild(opt);
+  }
+  updateReplicaBounds();
+  const sizes = $("create-size");
+  for (const s of data.sizes || []) {
+    const opt = [...sizes.options].find((o) => o.value === s.name);
+    if (!opt) continue;
+    if (s.deprecated && s.deprecated.removed) opt.remove();
+    else if (s.deprecated) {
+      opt.value = s.name; // an option without a value takes its text
+      opt.textContent = s.name + deprecationNote(s.deprecated);
+    }
+  }
+}
+
+// updateReplicaBounds limits the replicas field to the selected type's
+// bounds.
+function updateReplicaBounds() {
+  const opt = $("create-type").selectedOptions[0];
+  if (!opt) return;
+  $("create-replicas").min = opt.dataset.minReplicas;
+  $("create-replicas").max = opt.dataset.maxReplicas;
+}
+
+// loadRegions offers the regions available in the create form's namespace.
+async function loadRegions() {
+  const data = await api("GET", "/api/v1/regions" + query({ namespace: $("create-namespace").value.trim() }));
+  const list = $("create-regions");
+  list.replaceChildren();
+  for (const r of data.regions) {
+    const opt = document.createElement("option");
+    opt.value = r.name;
+    if (r.description) opt.label = `${r.name} — ${r.description}`;
+    list.appendChild(opt);
+  }
+}
+
+async function loadResources() {
+  const data = await api("GET", "/api/v1/resources" + query({
+    detail: "full",
+    namespace: $("namespace").value,
+    includeDeleted: $("include-deleted").checked ? "true" : "",
+  }));
+  const rows = data.resources.sort((a, b) =>
+    (a.namespace + "/" + a.name).localeCompare(b.namespace + "/" + b.name));
+  const tbody = $("resources");
+  tbody.replaceChildren();
+  for (const r of rows) {
+    const tr = document.createElement("tr");
+    tr.className = "clickable" + (r.deleted ? " deleted" : "");
+    cell(tr, r.namespace);
+    cell(tr, r.name);
+    cell(tr, r.spec && r.spec.type);
+    cell(tr, r.spec && r.spec.size);
+    cell(tr, r.version);
+    cell(tr, r.deleted ? `deleted ${r.deletedAt}` : r.updatedAt);
+    tr.addEventListener("click", () => openResource(r.namespace, r.name, r.deleted));
+    tbody.appendChild(tr);
+  }
+}
+
+function scheduleRefresh() {
+  clearTimeout(refreshTimer);
+  refreshTimer = setTimeout(() => {
+    loadResources().catch(showError);
+    loadCatalog();
+    if (selected) loadVersions().catch(showError);
+  }, 300);
+}
+
+async function openResource(namespace, name, deleted) {
+  selected = { namespace, name, deleted };
+  $("create").hidden = true;
+  $("detail").hidden = false;
+  $("diff").hidden = true;
+  $("detail-title").textContent = `${namespace}/${name}`;
+  $("delete-resource").hidden = !!deleted;
+  $("detail-spec").textContent = "";
+  if (!deleted) {
+    try {
+      const r = await api("GET", `/api/v1/resources/${encodeURIComponent(name)}` + query({ namespace }));
+      $("detail-spec").textContent = JSON.stringify(r, null, 2);
+    } catch (err) {
+      showError(err);
+    }
+  }
+  await loadVersions().catch(showError);
+}
+
+async function loadVersions() {
+  const { namespace, name } = selected;
+  const data = await api("GET", `/api/v1/resources/${encodeURIComponent(name)}/history` + query({ namespace }));
+  const tbody = $("versions");
+  tbody.replaceChildren();
+  for (const v of data.versions) {
+    const tr = document.createElement("tr");
+    if (v.deleted) tr.className = "deleted";
+    cell(tr, v.version);
+    cell(tr, v.createdAt);
+    cell(tr, v.changedBy);
+    cell(tr, v.source);
+    const td = cell(tr, "");
+    const btn = document.createElement("button");
+    btn.className = "link";
+    btn.textContent = "Diff";
+    btn.addEventListener("click", () => showDiff(v.digest).catch(showError));
+    td.appendChild(btn);
+    tbody.appendChild(tr);
+  }
+  if (data.verified === false) showError(new Error("History could not be verified: " + (data.error || "broken chain")));
+}
+
+async function showDiff(digest) {
+  const { namespace, name } = selected;
+  const text = await api("GET", `/api/v1/resources/${encodeURIComponent(name)}/diff` + query({ namespace, to: digest, format: "diff" }));
+  const pre = $("diff");
+  pre.replaceChildren();
+  for (const line of (text || "(no changes)\n").split("\n")) {
+    const span = document.createElement("span");
+    if (line.startsWith("@@")) span.className = "hunk";
+    else if (line.startsWith("+") && !line.startsWith("+++")) span.className = "add";
+    else if (line.startsWith("-") && !line.startsWith("---")) span.className = "del";
+    span.textContent = line + "\n";
+    pre.appendChild(span);
+  }
+  pre.hidden = false;
+}
+
+async function deleteResource() {
+  const { namespace, name } = selected;
+  if (!confirm(`Delete ${namespace}/${name}?`)) return;
+  try {
+    await api("DELETE", `/api/v1/resources/${encodeURIComponent(name)}` + query({ namespace }));
+    $("detail").hidden = true;
+    selected = null;
+    await loadResources();
+  } catch (err) {
+    showError(err);
+  }
+}
+
+async function createResource(ev) {
+  ev.preventDefault();
+  // Read fields through elements: a field called "name" would otherwise
+  // be shadowed by the form's own property.
+  const field = (name) => ev.target.elements.namedItem(name).value.trim();
+  const namespace = field("namespace");
+  const spec = { type: field("type"), size: field("size") };
+  if (field("region")) spec.region = field("region");
+  if (field("replicas")) spec.replicas = Number(field("replicas"));
+  try {
+    if (field("parameters")) spec.parameters = JSON.parse(field("parameters"));
+    const r = await api("POST", "/api/v1/resources" + query({ namespace }), { name: field("name"), spec });
+    $("create").hidden = true;
+    await loadResources();
+    await openResource(r.namespace || namespace, r.name, false);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+// Events.
+
+function streamEvents() {
+  const since = new Date(Date.now() - 24 * 3600 * 1000).toISOString().replace(/\.\d+Z$/, "Z");
+  const source = new EventSource("/api/v1/events" + query({ since }));
+  const state = $("events-state");
+  source.onopen = () => { state.textContent = "live"; };
+  source.onerror = () => { state.textContent = "reconnecting…"; };
+  for (const type of eventTypes) {
+    source.addEventListener(type, (msg) => {
+      addEvent(JSON.parse(msg.data));
+      scheduleRefresh();
+    });
+  }
+}
+
+function addEvent(e) {
+  const list = $("events");
+  const li = document.createElement("li");
+  const what = [e.type, [e.namespace, e.name].filter(Boolean).join("/"), e.version].filter(Boolean).join(" ");
+  li.textContent = `${e.time} ${what}` + (e.actor ? ` by ${e.actor}` : "") + (e.message ? `: ${e.message}` : "");
+  list.prepend(li);
+  while (list.children.length > maxEvents) list.lastChild.remove();
+}
+
+async function start() {
+  await loadIdentity();
+  $("app").hidden = false;
+  await Promise.all([loadCatalog(), loadNamespaces(), loadTypes().catch(showError)]);
+  await loadResources().catch(showError);
+  if (!start.streaming) {
+    start.streaming = true;
+    streamEvents();
+  }
+}
+
+document.addEventListener("DOMContentLoaded", () => {
+  $("login-form").addEventListener("submit", login);
+  $("logout").addEventListener("click", logout);
+  $("namespace").addEventListener("change", () => loadResources().catch(showError));
+  $("include-deleted").addEventListener("change", () => loadResources().catch(showError));
+  $("close-detail").addEventListener("click", () => { $("detail").hidden = true; selected = null; });
+  $("delete-resource").addEventListener("click", deleteResource);
+  $("new-resource").addEventListener("click", () => {
+    $("detail").hidden = true;
+    $("create").hidden = false;
+    loadRegions().catch(showError);
+  });
+  $("create-namespace").addEventListener("change", () => loadRegions().catch(showError));
+  $("create-type").addEventListener("change", updateReplicaBounds);
+  $("cancel-create").addEventListener("click", () => { $("create").hidden = true; });
+  $("create-form").addEventListener("submit", createResource);
+  start().catch(showError);
+});
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// WatchOptions configures RunWatch.
+type WatchOptions struct {
+	// Interval is how often resource repositories are checked.
+	Interval time.Duration
+
+	// Adopt adopts artifacts pushed by other tools, as
+	// POST /api/v1/resources/{name}/adopt would, instead of only flagging
+	// them as unmanaged.
+	Adopt bool
+}
+
+// unmanagedArtifacts are the resource repositories whose latest artifact
+// wasn't pushed through the API, by "namespace/name".
+type unmanagedArtifacts struct {
+	mu        sync.Mutex
+	artifacts map[string]model.UnmanagedArtifact
+}
+
+func newUnmanagedArtifacts() *unmanagedArtifacts {
+	return &unmanagedArtifacts{artifacts: make(map[string]model.UnmanagedArtifact)}
+}
+
+// set records an unmanaged artifact and reports whether its digest is new.
+func (u *unmanagedArtifacts) set(a model.UnmanagedArtifact) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	key := a.Namespace + "/" + a.Name
+	prev, ok := u.artifacts[key]
+	if ok && prev.Digest == a.Digest {
+		a.DetectedAt = prev.DetectedAt
+	}
+	u.artifacts[key] = a
+	return !ok || prev.Digest != a.Digest
+}
+
+// retain drops the artifacts whose keys aren't in keep.
+func (u *unmanagedArtifacts) retain(keep map[string]bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for key := range u.artifacts {
+		if !keep[key] {
+			delete(u.artifacts, key)
+		}
+	}
+}
+
+// list returns the unmanaged artifacts sorted by namespace and name.
+func (u *unmanagedArtifacts) list() []model.UnmanagedArtifact {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	list := make([]model.UnmanagedArtifact, 0, len(u.artifacts))
+	for _, a := range u.artifacts {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Namespace != list[j].Namespace {
+			return list[i].Namespace < list[j].Namespace
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// RunWatch checks resource repositories for out-of-band pushes every
+// opts.Interval until ctx is done. See watchRepositories.
+func (h *Handler) RunWatch(ctx context.Context, opts WatchOptions) {
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	suspects := make(map[string]string) // "namespace/name" -> digest seen last check
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := h.watchRepositories(ctx, opts, suspects); err != nil {
+				log.Printf("Warning: watching resource repositories: %v", err)
+			}
+		}
+	}
+}
+
+// watchRepositories lists every resource repository and looks for a latest
+// artifact no replica pushed: one that is neither the version this replica
+// knows nor the one in the resource index. Replicas record their pushes in
+// the index right after pushing, so a digest is only taken for an
+// out-of-band push once it was seen on two checks in a row. It is then
+// flagged as unmanaged, with a resource.unmanaged event, and with
+// opts.Adopt adopted. Adoption waits while the API is read-only, the
+// namespace frozen or the resource locked; failures are retried on the
+// next check. Repositories whose latest is known again are unflagged.
+func (h *Handler) watchRepositories(ctx context.Context, opts WatchOptions, suspects map[string]string) error {
+	// Tools that push on their own don't update the index, so new
+	// repositories only show up in the registry's listing.
+	repos, err := h.ociClient.ListRegistryRepos(ctx)
+	if err != nil {
+		log.Printf("Warning: listing repositories failed, only watching indexed ones: %v", err)
+		if repos, err = h.ociClient.ListResourceRepos(ctx); err != nil {
+			return fmt.Errorf("listing resource repos: %w", err)
+		}
+	}
+	indexed := make(map[string]string)
+	if h.ociClient.Discovery() != oci.RepoDiscoveryCatalog {
+		entries, _, err := h.ociClient.Index(ctx)
+		if err != nil {
+			return fmt.Errorf("reading resource index: %w", err)
+		}
+		for _, e := range entries {
+			indexed[e.Namespace+"/"+e.Name] = e.Digest
+		}
+	}
+
+	now := time.Now()
+	flagged := make(map[string]bool)
+	seen := make(map[string]bool, len(repos))
+	for _, repo := range repos {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		key := repo.Namespace + "/" + repo.Name
+		seen[key] = true
+		head, ok, err := h.ociClient.HeadResource(ctx, repo.Namespace, repo.Name)
+		if err != nil {
+			log.Printf("Warning: resolving %s: %v", key, err)
+			flagged[key] = true // keep a flag we can't check
+			continue
+		}
+		meta, _ := h.catalog.Meta(repo.Namespace, repo.Name)
+		if !ok || head.Deleted || head.Digest == meta.Digest || head.Digest == indexed[key] {
+			delete(suspects, key)
+			continue
+		}
+		if suspects[key] != head.Digest {
+			suspects[key] = head.Digest
+			continue
+		}
+
+		flagged[key] = true
+		artifact := model.UnmanagedArtifact{
+			Namespace:    repo.Namespace,
+			Name:         repo.Name,
+			Digest:       head.Digest,
+			ArtifactType: head.Type,
+			DetectedAt:   now.UTC().Format(time.RFC3339),
+		}
+		if opts.Adopt && !h.readOnly() && !h.frozen(repo.Namespace, now) && !h.locked(repo.Namespace, repo.Name) {
+			resp, err := h.adoptArtifact(ctx, repo.Namespace, repo.Name, head.Digest)
+			if err == nil {
+				delete(suspects, key)
+				delete(flagged, key)
+				log.Printf("Audit: adopted %s from out-of-band push %s (version=%s, dropped=%v) by watcher", key, head.Digest, resp.Version, resp.Dropped)
+				continue
+			}
+			artifact.Error = err.Error()
+		}
+		if h.unmanaged.set(artifact) {
+			log.Printf("Warning: %s was pushed out of band (digest=%s, type=%q)", key, head.Digest, head.Type)
+			h.events.Record(ctx, model.Event{
+				Type:      model.EventResourceUnmanaged,
+				Namespace: repo.Namespace,
+				Name:      repo.Name,
+				Digest:    head.Digest,
+				Message:   artifact.Error,
+			})
+		}
+	}
+	for key := range suspects {
+		if !seen[key] {
+			delete(suspects, key)
+		}
+	}
+	h.unmanaged.retain(flagged)
+	return nil
+}
+
+// ListUnmanaged handles GET /api/v1/admin/unmanaged.
+// It lists the resource repositories whose latest artifact was pushed out
+// of band, as found by the watcher.
+func (h *Handler) ListUnmanaged(w http.ResponseWriter, _ *http.Request) {
+	artifacts := h.unmanaged.list()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"artifacts": artifacts,
+		"count":     len(artifacts),
+	})
+}
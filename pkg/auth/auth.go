@@ -0,0 +1,225 @@
+// Package auth establishes who is calling the API. Authenticators turn a
+// request into an Identity, which the middleware stores in the request
+// context for handlers and audit logs.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Identity is an authenticated caller.
+type Identity struct {
+	User   string   `json:"user"`
+	Groups []string `json:"groups,omitempty"`
+
+	// Method names the authenticator that established the identity, e.g.
+	// "proxy".
+	Method string `json:"method"`
+
+	// Scopes, if set, narrow what the identity may do.
+	Scopes *Scopes `json:"scopes,omitempty"`
+
+	// APIKey names the API key the identity was established with, directly
+	// or through a session issued for it. APIKeyID is that key's random
+	// ID, which a key created again under the same name doesn't share.
+	APIKey   string `json:"apiKey,omitempty"`
+	APIKeyID string `json:"apiKeyId,omitempty"`
+
+	// ImpersonatedBy is the user who authenticated, if they impersonate
+	// this identity.
+	ImpersonatedBy string `json:"impersonatedBy,omitempty"`
+}
+
+// Impersonation headers, as kubectl sends them. Impersonate-Group may be
+// repeated.
+const (
+	ImpersonateUserHeader  = "Impersonate-User"
+	ImpersonateGroupHeader = "Impersonate-Group"
+)
+
+// Scopes narrow what an identity may do on top of what its groups allow.
+// Empty fields don't narrow anything.
+type Scopes struct {
+	// ReadOnly allows only GET and HEAD requests.
+	ReadOnly bool `json:"readOnly,omitempty"`
+
+	// Namespaces are globs of the namespaces the identity may address.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Types are the resource types the identity may address.
+	Types []string `json:"types,omitempty"`
+}
+
+// Limited reports whether the scopes limit namespaces or types, so the
+// identity may only address resources.
+func (s *Scopes) Limited() bool {
+	return len(s.Namespaces) > 0 || len(s.Types) > 0
+}
+
+// AllowsNamespace reports whether namespace is within the scopes.
+func (s *Scopes) AllowsNamespace(namespace string) bool {
+	if len(s.Namespaces) == 0 {
+		return true
+	}
+	for _, glob := range s.Namespaces {
+		if ok, _ := path.Match(glob, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsType reports whether resource type t is within the scopes.
+func (s *Scopes) AllowsType(t string) bool {
+	return len(s.Types) == 0 || slices.Contains(s.Types, t)
+}
+
+// Authenticator establishes the identity of a request's caller. It returns
+// false if the request carries no credentials it understands, and an error
+// if it carries credentials that are invalid.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Identity, bool, error)
+}
+
+type contextKey struct{}
+
+// WithIdentity returns a copy of ctx carrying id.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the caller's identity, if the request was
+// authenticated.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(Identity)
+	return id, ok
+}
+
+// Actor describes the caller for audit logs: the user name, or "anonymous".
+func Actor(ctx context.Context) string {
+	if id, ok := FromContext(ctx); ok {
+		return id.User
+	}
+	return "anonymous"
+}
+
+// Options configures Middleware.
+type Options struct {
+	// Authenticators are tried in order; the first that recognizes the
+	// request wins.
+	Authenticators []Authenticator
+
+	// Required rejects unauthenticated requests with 401, except for paths
+	// starting with one of Public.
+	Required bool
+	Public   []string
+
+	// Impersonators are the groups whose members may act as another
+	// identity with the impersonation headers. Without any, impersonation
+	// is refused.
+	Impersonators []string
+
+	// PrivilegedGroups, such as admin and break-glass groups, may only be
+	// asserted while impersonating by callers who are members themselves.
+	// The Impersonators groups are always privileged.
+	PrivilegedGroups []string
+}
+
+// Middleware authenticates every request and stores the identity in its
+// context.
+func Middleware(next http.Handler, opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, a := range opts.Authenticators {
+			id, ok, err := a.Authenticate(r)
+			if err != nil {
+				log.Printf("Warning: rejected credentials for %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+				writeError(w, http.StatusUnauthorized, codeUnauthenticated, "invalid credentials")
+				return
+			}
+			if ok {
+				if id, ok = impersonate(w, r, id, opts); ok {
+					next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), id)))
+				}
+				return
+			}
+		}
+
+		if r.Header.Get(ImpersonateUserHeader) != "" || r.Header.Get(ImpersonateGroupHeader) != "" {
+			writeError(w, http.StatusUnauthorized, codeUnauthenticated, "impersonation requires authentication")
+			return
+		}
+		if opts.Required && !isPublic(r.URL.Path, opts.Public) {
+			writeError(w, http.StatusUnauthorized, codeUnauthenticated, "authentication required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// impersonate returns the identity named by the impersonation headers, if
+// any, in place of the authenticated id. It writes an error and returns
+// false if id may not impersonate, or asserts a privileged group it isn't
+// in itself. The impersonated identity keeps the scopes and API key of id,
+// so a scoped API key can't shed them and sessions end with the key.
+func impersonate(w http.ResponseWriter, r *http.Request, id Identity, opts Options) (Identity, bool) {
+	user := strings.TrimSpace(r.Header.Get(ImpersonateUserHeader))
+	groups := r.Header.Values(ImpersonateGroupHeader)
+	if user == "" {
+		if len(groups) > 0 {
+			writeError(w, http.StatusBadRequest, codeBadRequest, ImpersonateGroupHeader+" requires "+ImpersonateUserHeader)
+			return Identity{}, false
+		}
+		return id, true
+	}
+	if !slices.ContainsFunc(id.Groups, func(group string) bool { return slices.Contains(opts.Impersonators, group) }) {
+		log.Printf("Warning: %s denied impersonating %s for %s %s: not in an impersonation group", id.User, user, r.Method, r.URL.Path)
+		writeError(w, http.StatusForbidden, codeForbidden, id.User+" may not impersonate other users")
+		return Identity{}, false
+	}
+
+	as := Identity{User: user, Method: id.Method, Scopes: id.Scopes, APIKey: id.APIKey, APIKeyID: id.APIKeyID, ImpersonatedBy: id.User}
+	for _, group := range groups {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			continue
+		}
+		privileged := slices.Contains(opts.Impersonators, group) || slices.Contains(opts.PrivilegedGroups, group)
+		if privileged && !slices.Contains(id.Groups, group) {
+			log.Printf("Warning: %s denied impersonating %s in group %s for %s %s: not a member", id.User, user, group, r.Method, r.URL.Path)
+			writeError(w, http.StatusForbidden, codeForbidden, id.User+" may not grant group "+group+" without being in it")
+			return Identity{}, false
+		}
+		as.Groups = append(as.Groups, group)
+	}
+	log.Printf("Audit: %s impersonating %s (groups=%v) for %s %s", id.User, as.User, as.Groups, r.Method, r.URL.Path)
+	return as, true
+}
+
+func isPublic(path string, public []string) bool {
+	for _, prefix := range public {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Error codes of the middleware's responses, as in the model package's
+// message catalog.
+const (
+	codeBadRequest      = "bad_request"
+	codeUnauthenticated = "unauthenticated"
+	codeForbidden       = "forbidden"
+)
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg, "code": code})
+}
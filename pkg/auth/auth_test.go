@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// staticAuth authenticates every request as id.
+type staticAuth struct{ id Identity }
+
+func (a staticAuth) Authenticate(*http.Request) (Identity, bool, error) {
+	return a.id, true, nil
+}
+
+func TestImpersonateGroups(t *testing.T) {
+	opts := Options{
+		Impersonators:    []string{"pipelines", "deployers"},
+		PrivilegedGroups: []string{"admins", "break-glass"},
+	}
+	tests := []struct {
+		name       string
+		caller     []string
+		groups     []string
+		wantStatus int
+	}{
+		{"ordinary groups", []string{"pipelines"}, []string{"team-orders", "team-billing"}, http.StatusOK},
+		{"no groups", []string{"pipelines"}, nil, http.StatusOK},
+		{"admin group it isn't in", []string{"pipelines"}, []string{"team-orders", "admins"}, http.StatusForbidden},
+		{"break-glass group it isn't in", []string{"pipelines"}, []string{"break-glass"}, http.StatusForbidden},
+		{"impersonation group it isn't in", []string{"pipelines"}, []string{"deployers"}, http.StatusForbidden},
+		{"its own impersonation group", []string{"pipelines"}, []string{"pipelines"}, http.StatusOK},
+		{"admin group it is in", []string{"pipelines", "admins"}, []string{"admins"}, http.StatusOK},
+		{"not an impersonator", []string{"team-orders"}, []string{"team-orders"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Identity
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = FromContext(r.Context())
+			})
+			opts := opts
+			opts.Authenticators = []Authenticator{staticAuth{Identity{User: "ci", Groups: tt.caller, Method: "apikey"}}}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/resources", nil)
+			req.Header.Set(ImpersonateUserHeader, "alice")
+			for _, group := range tt.groups {
+				req.Header.Add(ImpersonateGroupHeader, group)
+			}
+			rec := httptest.NewRecorder()
+			Middleware(next, opts).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got.User != "alice" || got.ImpersonatedBy != "ci" || !slices.Equal(got.Groups, tt.groups) {
+				t.Errorf("identity = %+v, want alice in %v impersonated by ci", got, tt.groups)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Default headers set by oauth2-proxy and most identity-aware ingresses.
+const (
+	DefaultUserHeader   = "X-Forwarded-User"
+	DefaultGroupsHeader = "X-Forwarded-Groups"
+)
+
+// ProxyHeaders trusts identity headers set by an authenticating reverse
+// proxy, such as oauth2-proxy or a cloud IAP, in front of the API. Headers
+// are only honored on connections from TrustedCIDRs; anyone else could set
+// them too.
+type ProxyHeaders struct {
+	UserHeader   string
+	GroupsHeader string // comma-separated group list
+	TrustedCIDRs []netip.Prefix
+}
+
+// ParseCIDRs parses a comma-separated list of CIDRs. A bare address is
+// treated as a single-address prefix.
+func ParseCIDRs(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted address %q: %w", field, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted CIDR %q: %w", field, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// Authenticate reads the identity headers of a request from a trusted
+// proxy. Requests from other addresses are treated as carrying no
+// credentials, and their identity headers are ignored.
+func (p *ProxyHeaders) Authenticate(r *http.Request) (Identity, bool, error) {
+	user := strings.TrimSpace(r.Header.Get(p.userHeader()))
+	if user == "" {
+		return Identity{}, false, nil
+	}
+	if !p.trusted(r.RemoteAddr) {
+		log.Printf("Warning: ignoring %s header from untrusted address %s", p.userHeader(), r.RemoteAddr)
+		return Identity{}, false, nil
+	}
+
+	id := Identity{User: user, Method: "proxy"}
+	for _, group := range strings.Split(r.Header.Get(p.groupsHeader()), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			id.Groups = append(id.Groups, group)
+		}
+	}
+	return id, true, nil
+}
+
+func (p *ProxyHeaders) trusted(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range p.TrustedCIDRs {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ProxyHeaders) userHeader() string {
+	if p.UserHeader != "" {
+		return p.UserHeader
+	}
+	return DefaultUserHeader
+}
+
+func (p *ProxyHeaders) groupsHeader() string {
+	if p.GroupsHeader != "" {
+		return p.GroupsHeader
+	}
+	return DefaultGroupsHeader
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Session cookie defaults.
+const (
+	DefaultSessionCookie = "gitops_squared_session"
+	DefaultSessionTTL    = 8 * time.Hour
+)
+
+// CSRFHeader carries a session's CSRF token. Requests authenticated by a
+// session cookie must send it for anything but GET, HEAD and OPTIONS, which
+// a page on another origin can't do without passing CORS.
+const CSRFHeader = "X-CSRF-Token"
+
+// Sessions issues signed session cookies for browsers and authenticates
+// requests that carry one. Sessions are stateless: a cookie stays valid
+// until it expires, unless Check rejects it.
+type Sessions struct {
+	Secret []byte // HMAC key; at least 32 bytes
+	TTL    time.Duration
+	Cookie string
+
+	// Check, if set, is called with the identity of every session
+	// presented. An error rejects the session, e.g. once the credentials
+	// it was issued for are revoked.
+	Check func(id Identity) error
+
+	// CrossSite sets SameSite=None, so a UI on another origin can send the
+	// cookie with credentialed requests. Otherwise it is SameSite=Lax.
+	CrossSite bool
+
+	// Insecure leaves out the Secure attribute, for local development over
+	// plain HTTP. Browsers refuse SameSite=None cookies without it.
+	Insecure bool
+}
+
+// session is the signed cookie payload.
+type session struct {
+	Identity  Identity `json:"id"`
+	CSRF      string   `json:"csrf"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// NewSessions returns a session issuer signing with secret.
+func NewSessions(secret []byte) (*Sessions, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("session secret must be at least 32 bytes")
+	}
+	return &Sessions{Secret: secret, TTL: DefaultSessionTTL, Cookie: DefaultSessionCookie}, nil
+}
+
+// Issue sets a session cookie for id and returns its CSRF token and expiry.
+func (s *Sessions) Issue(w http.ResponseWriter, id Identity) (csrf string, expiresAt time.Time, err error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", time.Time{}, fmt.Errorf("generating CSRF token: %w", err)
+	}
+	id.Method = "session"
+	expiresAt = time.Now().Add(s.TTL).Truncate(time.Second)
+	payload, err := json.Marshal(session{
+		Identity:  id,
+		CSRF:      base64.RawURLEncoding.EncodeToString(nonce),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	http.SetCookie(w, s.cookie(encoded+"."+s.sign(encoded), expiresAt))
+	return base64.RawURLEncoding.EncodeToString(nonce), expiresAt, nil
+}
+
+// Clear expires the session cookie.
+func (s *Sessions) Clear(w http.ResponseWriter) {
+	c := s.cookie("", time.Unix(0, 0))
+	c.MaxAge = -1
+	http.SetCookie(w, c)
+}
+
+func (s *Sessions) cookie(value string, expires time.Time) *http.Cookie {
+	c := &http.Cookie{
+		Name:     s.Cookie,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   !s.Insecure,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if s.CrossSite {
+		c.SameSite = http.SameSiteNoneMode
+	}
+	return c
+}
+
+func (s *Sessions) sign(encoded string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Authenticate reads the session cookie. Tampered or expired cookies,
+// sessions Check rejects, and unsafe requests without the session's CSRF
+// token are rejected.
+func (s *Sessions) Authenticate(r *http.Request) (Identity, bool, error) {
+	c, err := r.Cookie(s.Cookie)
+	if err != nil || c.Value == "" {
+		return Identity{}, false, nil
+	}
+	encoded, sig, ok := strings.Cut(c.Value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign(encoded))) {
+		return Identity{}, false, errors.New("invalid session cookie")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Identity{}, false, errors.New("invalid session cookie")
+	}
+	var sess session
+	if err := json.Unmarshal(payload, &sess); err != nil {
+		return Identity{}, false, errors.New("invalid session cookie")
+	}
+	if time.Now().Unix() >= sess.ExpiresAt {
+		return Identity{}, false, errors.New("session expired")
+	}
+	if s.Check != nil {
+		if err := s.Check(sess.Identity); err != nil {
+			return Identity{}, false, err
+		}
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		if !hmac.Equal([]byte(r.Header.Get(CSRFHeader)), []byte(sess.CSRF)) {
+			return Identity{}, false, fmt.Errorf("missing or wrong %s header", CSRFHeader)
+		}
+	}
+	return sess.Identity, true, nil
+}
@@ -0,0 +1,70 @@
+// Package cost estimates the monthly cost of platform resources.
+package cost
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"sigs.k8s.io/yaml"
+)
+
+// Estimator estimates what a resource will cost per month.
+type Estimator interface {
+	Estimate(ctx context.Context, namespace string, spec model.ResourceSpec) (model.CostEstimate, error)
+}
+
+// PriceTable is a static price list: a monthly price per type and size,
+// scaled by replicas and an optional per-region multiplier.
+//
+//	currency: USD
+//	prices:
+//	  vm: {small: 15, medium: 30, large: 60}
+//	  database: {small: 50, medium: 120, large: 300}
+//	regionMultipliers:
+//	  eu-west-1: 1.1
+type PriceTable struct {
+	Currency          string                        `json:"currency,omitempty"`
+	Prices            map[string]map[string]float64 `json:"prices"`
+	RegionMultipliers map[string]float64            `json:"regionMultipliers,omitempty"`
+}
+
+// LoadPriceTable reads a PriceTable from a YAML file.
+func LoadPriceTable(path string) (*PriceTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading price table: %w", err)
+	}
+	var pt PriceTable
+	if err := yaml.UnmarshalStrict(data, &pt); err != nil {
+		return nil, fmt.Errorf("parsing price table: %w", err)
+	}
+	if pt.Currency == "" {
+		pt.Currency = "USD"
+	}
+	return &pt, nil
+}
+
+// Estimate looks up the spec's type and size. Unknown combinations are an
+// error rather than a zero estimate.
+func (pt *PriceTable) Estimate(_ context.Context, _ string, spec model.ResourceSpec) (model.CostEstimate, error) {
+	price, ok := pt.Prices[spec.Type][spec.Size]
+	if !ok {
+		return model.CostEstimate{}, fmt.Errorf("no price for %s/%s", spec.Type, spec.Size)
+	}
+
+	replicas := spec.Replicas
+	if replicas < 1 {
+		replicas = 1
+	}
+	multiplier := 1.0
+	if m, ok := pt.RegionMultipliers[spec.Region]; ok {
+		multiplier = m
+	}
+
+	return model.CostEstimate{
+		Monthly:  price * float64(replicas) * multiplier,
+		Currency: pt.Currency,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package cost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+)
+
+// WebhookEstimator asks an external service (e.g. an Infracost wrapper) for
+// estimates. It POSTs {"namespace": ..., "spec": {...}} and expects
+// {"monthly": 123.45, "currency": "USD"} back.
+type WebhookEstimator struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewWebhookEstimator creates an estimator that calls url.
+func NewWebhookEstimator(url string) *WebhookEstimator {
+	return &WebhookEstimator{
+		url:        url,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type webhookRequest struct {
+	Namespace string             `json:"namespace"`
+	Spec      model.ResourceSpec `json:"spec"`
+}
+
+// Estimate calls the webhook.
+func (e *WebhookEstimator) Estimate(ctx context.Context, namespace string, spec model.ResourceSpec) (model.CostEstimate, error) {
+	body, err := json.Marshal(webhookRequest{Namespace: namespace, Spec: spec})
+	if err != nil {
+		return model.CostEstimate{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return model.CostEstimate{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return model.CostEstimate{}, fmt.Errorf("calling cost webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return model.CostEstimate{}, fmt.Errorf("cost webhook returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var estimate model.CostEstimate
+	if err := json.NewDecoder(resp.Body).Decode(&estimate); err != nil {
+		return model.CostEstimate{}, fmt.Errorf("parsing cost webhook response: %w", err)
+	}
+	if estimate.Currency == "" {
+		estimate.Currency = "USD"
+	}
+	return estimate, nil
+}
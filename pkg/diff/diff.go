@@ -0,0 +1,108 @@
+// Package diff compares resource manifests, as a list of changed fields for
+// machines and as a unified diff for people.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Change operations.
+const (
+	OpAdded   = "added"
+	OpRemoved = "removed"
+	OpChanged = "changed"
+)
+
+// Change is one field that differs between two documents. Path is
+// dot-separated, with [i] for array elements and ["key"] for keys that
+// contain dots, e.g. spec.parameters.image or metadata.labels["app.kubernetes.io/part-of"].
+type Change struct {
+	Path string `json:"path"`
+	Op   string `json:"op"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// Fields compares two JSON documents and returns the changed leaf fields,
+// sorted by path. A nil document is empty, so every field of the other is
+// added or removed.
+func Fields(old, new []byte) ([]Change, error) {
+	var a, b any
+	if old != nil {
+		if err := json.Unmarshal(old, &a); err != nil {
+			return nil, fmt.Errorf("parsing old document: %w", err)
+		}
+	}
+	if new != nil {
+		if err := json.Unmarshal(new, &b); err != nil {
+			return nil, fmt.Errorf("parsing new document: %w", err)
+		}
+	}
+	var changes []Change
+	compare("", a, b, &changes)
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// compare appends the differences between a and b at path to changes.
+// Objects and arrays are compared member by member; a side that is missing
+// one compares as empty.
+func compare(path string, a, b any, changes *[]Change) {
+	am, aObj := a.(map[string]any)
+	bm, bObj := b.(map[string]any)
+	if (aObj || a == nil) && (bObj || b == nil) && (aObj || bObj) {
+		keys := make(map[string]bool, len(am)+len(bm))
+		for k := range am {
+			keys[k] = true
+		}
+		for k := range bm {
+			keys[k] = true
+		}
+		for k := range keys {
+			compare(join(path, k), am[k], bm[k], changes)
+		}
+		return
+	}
+
+	as, aArr := a.([]any)
+	bs, bArr := b.([]any)
+	if (aArr || a == nil) && (bArr || b == nil) && (aArr || bArr) {
+		for i := 0; i < max(len(as), len(bs)); i++ {
+			var x, y any
+			if i < len(as) {
+				x = as[i]
+			}
+			if i < len(bs) {
+				y = bs[i]
+			}
+			compare(path+"["+strconv.Itoa(i)+"]", x, y, changes)
+		}
+		return
+	}
+
+	switch {
+	case a == nil && b == nil:
+	case a == nil:
+		*changes = append(*changes, Change{Path: path, Op: OpAdded, New: b})
+	case b == nil:
+		*changes = append(*changes, Change{Path: path, Op: OpRemoved, Old: a})
+	case !reflect.DeepEqual(a, b):
+		*changes = append(*changes, Change{Path: path, Op: OpChanged, Old: a, New: b})
+	}
+}
+
+// join appends key to path.
+func join(path, key string) string {
+	if strings.ContainsAny(key, ".[]\"") || key == "" {
+		return path + "[" + strconv.Quote(key) + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,182 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxCells bounds the line-by-line comparison table. Larger inputs are
+// diffed as a whole replacement.
+const maxCells = 1 << 22
+
+// ANSI escapes used by Colorize.
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiCyan  = "\x1b[36m"
+)
+
+// edit is one line of a line diff: ' ' kept, '-' removed or '+' added.
+type edit struct {
+	op   byte
+	line string
+}
+
+// Unified returns a unified diff of old and new, labeled oldName and
+// newName, with context lines of context around each change. It is empty
+// if the texts are equal.
+func Unified(oldName, newName string, old, new []byte, context int) string {
+	edits := lineDiff(splitLines(string(old)), splitLines(string(new)))
+
+	var b strings.Builder
+	for start := 0; start < len(edits); {
+		first := nextChange(edits, start)
+		if first < 0 {
+			break
+		}
+		// Extend the hunk while the next change is close enough for the
+		// context around both to touch.
+		last := first
+		for {
+			next := nextChange(edits, last+1)
+			if next < 0 || next-last > 2*context+1 {
+				break
+			}
+			last = next
+		}
+		from := max(first-context, start)
+		to := min(last+context+1, len(edits))
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		oldStart, newStart := position(edits[:from])
+		oldLen, newLen := position(edits[from:to])
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldLen), hunkRange(newStart, newLen))
+		for _, e := range edits[from:to] {
+			b.WriteByte(e.op)
+			b.WriteString(e.line)
+			b.WriteByte('\n')
+		}
+		start = to
+	}
+	return b.String()
+}
+
+// Colorize highlights a unified diff for a terminal: file headers bold,
+// hunk headers cyan, removed lines red and added lines green.
+func Colorize(unified string) string {
+	var b strings.Builder
+	for _, line := range splitLines(unified) {
+		color := ""
+		switch {
+		case strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+			color = ansiBold
+		case strings.HasPrefix(line, "@@"):
+			color = ansiCyan
+		case strings.HasPrefix(line, "-"):
+			color = ansiRed
+		case strings.HasPrefix(line, "+"):
+			color = ansiGreen
+		}
+		if color == "" {
+			b.WriteString(line + "\n")
+			continue
+		}
+		b.WriteString(color + line + ansiReset + "\n")
+	}
+	return b.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// lineDiff returns the edits turning a into b, keeping a longest common
+// subsequence of lines.
+func lineDiff(a, b []string) []edit {
+	edits := make([]edit, 0, len(a)+len(b))
+	if len(a)*len(b) > maxCells {
+		for _, line := range a {
+			edits = append(edits, edit{'-', line})
+		}
+		for _, line := range b {
+			edits = append(edits, edit{'+', line})
+		}
+		return edits
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		default:
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		}
+	}
+	return edits
+}
+
+// nextChange returns the index of the first changed line at or after
+// start, or -1.
+func nextChange(edits []edit, start int) int {
+	for i := start; i < len(edits); i++ {
+		if edits[i].op != ' ' {
+			return i
+		}
+	}
+	return -1
+}
+
+// position counts the old and new lines in edits.
+func position(edits []edit) (old, new int) {
+	for _, e := range edits {
+		if e.op != '+' {
+			old++
+		}
+		if e.op != '-' {
+			new++
+		}
+	}
+	return old, new
+}
+
+// hunkRange formats the start line (after skip lines) and length of a
+// hunk side. An empty side starts at the line before it.
+func hunkRange(skip, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", skip)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", skip+1)
+	}
+	return fmt.Sprintf("%d,%d", skip+1, length)
+}
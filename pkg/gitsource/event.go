@@ -0,0 +1,188 @@
+package gitsource
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrUnauthorized is returned when a webhook's signature or token does not
+// match the source's secret.
+var ErrUnauthorized = errors.New("invalid webhook signature")
+
+// zeroCommit is the before/after SHA of a push that creates or deletes a
+// branch.
+const zeroCommit = "0000000000000000000000000000000000000000"
+
+// PushEvent is the provider-neutral part of a push webhook.
+type PushEvent struct {
+	Provider   string
+	Repository string
+	Ref        string
+	Before     string
+	After      string
+
+	// Changed and Removed hold the resource files the push adds or
+	// modifies and the ones it removes, after all of its commits.
+	Changed []string
+	Removed []string
+}
+
+// Branch returns the pushed branch, or "" for tags.
+func (e *PushEvent) Branch() string {
+	branch, _ := strings.CutPrefix(e.Ref, "refs/heads/")
+	if branch == e.Ref {
+		return ""
+	}
+	return branch
+}
+
+// DeletesBranch reports whether the push deletes its branch.
+func (e *PushEvent) DeletesBranch() bool {
+	return e.After == zeroCommit
+}
+
+// pushPayload covers the fields GitHub and GitLab push payloads share.
+type pushPayload struct {
+	Ref     string `json:"ref"`
+	Before  string `json:"before"`
+	After   string `json:"after"`
+	Commits []struct {
+		Added    []string `json:"added"`
+		Modified []string `json:"modified"`
+		Removed  []string `json:"removed"`
+	} `json:"commits"`
+
+	// GitHub
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+
+	// GitLab
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+}
+
+// Provider identifies the sending provider and event from the request
+// headers. ok is false if the request is from neither.
+func Provider(header http.Header) (provider, event string, ok bool) {
+	if event := header.Get("X-GitHub-Event"); event != "" {
+		return ProviderGitHub, event, true
+	}
+	if event := header.Get("X-Gitlab-Event"); event != "" {
+		return ProviderGitLab, event, true
+	}
+	return "", "", false
+}
+
+// IsPush reports whether a provider event is a push.
+func IsPush(provider, event string) bool {
+	switch provider {
+	case ProviderGitHub:
+		return event == "push"
+	case ProviderGitLab:
+		return event == "Push Hook"
+	}
+	return false
+}
+
+// Repository returns the repository a webhook payload is about.
+func Repository(provider string, body []byte) (string, error) {
+	var p pushPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return "", fmt.Errorf("parsing webhook payload: %w", err)
+	}
+	if provider == ProviderGitLab {
+		return p.Project.PathWithNamespace, nil
+	}
+	return p.Repository.FullName, nil
+}
+
+// ParsePush parses a push payload and folds its commits into the net set of
+// changed and removed resource files.
+func ParsePush(provider string, body []byte) (*PushEvent, error) {
+	var p pushPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("parsing push payload: %w", err)
+	}
+
+	event := &PushEvent{
+		Provider:   provider,
+		Repository: p.Repository.FullName,
+		Ref:        p.Ref,
+		Before:     p.Before,
+		After:      p.After,
+	}
+	if provider == ProviderGitLab {
+		event.Repository = p.Project.PathWithNamespace
+	}
+
+	// Commits are oldest first; the last change to a path wins.
+	removed := make(map[string]bool)
+	var order []string
+	track := func(path string, gone bool) {
+		if !IsResourceFile(path) {
+			return
+		}
+		if _, seen := removed[path]; !seen {
+			order = append(order, path)
+		}
+		removed[path] = gone
+	}
+	for _, c := range p.Commits {
+		for _, path := range c.Added {
+			track(path, false)
+		}
+		for _, path := range c.Modified {
+			track(path, false)
+		}
+		for _, path := range c.Removed {
+			track(path, true)
+		}
+	}
+	for _, path := range order {
+		if removed[path] {
+			event.Removed = append(event.Removed, path)
+		} else {
+			event.Changed = append(event.Changed, path)
+		}
+	}
+	return event, nil
+}
+
+// Verify checks a webhook against the source's secret: GitHub's
+// X-Hub-Signature-256 HMAC or GitLab's X-Gitlab-Token.
+func (s *Source) Verify(header http.Header, body []byte) error {
+	return verify(s.Provider, s.secret, header, body)
+}
+
+func verify(provider, secret string, header http.Header, body []byte) error {
+	switch provider {
+	case ProviderGitHub:
+		sig, ok := strings.CutPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+		if !ok {
+			return ErrUnauthorized
+		}
+		got, err := hex.DecodeString(sig)
+		if err != nil {
+			return ErrUnauthorized
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		if !hmac.Equal(got, mac.Sum(nil)) {
+			return ErrUnauthorized
+		}
+	case ProviderGitLab:
+		if subtle.ConstantTimeCompare([]byte(header.Get("X-Gitlab-Token")), []byte(secret)) != 1 {
+			return ErrUnauthorized
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package gitsource
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"sigs.k8s.io/yaml"
+)
+
+// maxFileSize caps how much of a fetched file is read.
+const maxFileSize = 1 << 20
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// ResourceFile is the content of a platformresource.yaml: a resource request
+// with an optional namespace.
+//
+//	apiVersion: gitops-squared.io/v1beta1
+//	name: orders-db
+//	namespace: team-a
+//	spec:
+//	  type: database
+//	  size: medium
+type ResourceFile struct {
+	Namespace string `json:"namespace,omitempty"`
+	model.ResourceRequest
+}
+
+// FetchFile returns a file's content at a commit using the provider's API.
+func (s *Source) FetchFile(ctx context.Context, path, ref string) ([]byte, error) {
+	var endpoint string
+	switch s.Provider {
+	case ProviderGitHub:
+		endpoint = fmt.Sprintf("%s/repos/%s/contents/%s?ref=%s", s.APIURL, s.Repository, escapePath(path), url.QueryEscape(ref))
+	case ProviderGitLab:
+		endpoint = fmt.Sprintf("%s/projects/%s/repository/files/%s/raw?ref=%s",
+			s.APIURL, url.PathEscape(s.Repository), url.PathEscape(path), url.QueryEscape(ref))
+	default:
+		return nil, fmt.Errorf("unknown provider %q", s.Provider)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch s.Provider {
+	case ProviderGitHub:
+		req.Header.Set("Accept", "application/vnd.github.raw")
+		if s.token != "" {
+			req.Header.Set("Authorization", "Bearer "+s.token)
+		}
+	case ProviderGitLab:
+		if s.token != "" {
+			req.Header.Set("PRIVATE-TOKEN", s.token)
+		}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s@%s: %w", path, ref, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s@%s: %s", path, ref, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s@%s: %w", path, ref, err)
+	}
+	if len(data) > maxFileSize {
+		return nil, fmt.Errorf("fetching %s@%s: file larger than %d bytes", path, ref, maxFileSize)
+	}
+	return data, nil
+}
+
+// FetchResource fetches and parses a resource file at a commit. The
+// namespace defaults to the source's.
+func (s *Source) FetchResource(ctx context.Context, path, ref string) (*ResourceFile, error) {
+	data, err := s.FetchFile(ctx, path, ref)
+	if err != nil {
+		return nil, err
+	}
+	var file ResourceFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if file.Namespace == "" {
+		file.Namespace = s.Namespace
+	}
+	return &file, nil
+}
+
+// escapePath escapes each segment of a repository path.
+func escapePath(path string) string {
+	u := url.URL{Path: path}
+	return u.EscapedPath()
+}
@@ -0,0 +1,337 @@
+package gitsource
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxPushAttempts bounds how often Commit refetches and retries when the
+// branch moved while it was committing.
+const maxPushAttempts = 3
+
+// Signature identifies the author or committer of a commit.
+type Signature struct {
+	Name  string
+	Email string
+}
+
+// MirrorOptions configures a Mirror.
+type MirrorOptions struct {
+	// URL is the repository's clone URL, and Branch the branch written to
+	// by default. Branch defaults to main.
+	URL    string
+	Branch string
+
+	// Dir is the directory of the repository the mirror writes files
+	// under. Files outside it are never touched. Defaults to the root.
+	Dir string
+
+	// Username and Token authenticate over HTTPS, as for Clone.
+	Username string
+	Token    string
+
+	// Committer is recorded as the committer of every commit.
+	Committer Signature
+
+	// Protocols are the transports git may use. Defaults to https and ssh.
+	Protocols []string
+}
+
+// Mirror writes files to a Git repository through a local working copy.
+// Each commit starts from a fresh shallow fetch of the branch, so other
+// writers to the repository are not overwritten.
+type Mirror struct {
+	opts MirrorOptions
+	env  []string
+
+	mu      sync.Mutex // serializes use of the working copy
+	workdir string
+}
+
+// NewMirror creates a working copy for the repository in a temporary
+// directory. Nothing is fetched until the first commit.
+func NewMirror(opts MirrorOptions) (*Mirror, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("repository URL is required")
+	}
+	if opts.Branch == "" {
+		opts.Branch = "main"
+	}
+	opts.Dir = strings.Trim(opts.Dir, "/")
+	if opts.Dir != "" && !fs.ValidPath(opts.Dir) {
+		return nil, fmt.Errorf("invalid directory %q", opts.Dir)
+	}
+	if len(opts.Protocols) == 0 {
+		opts.Protocols = []string{"https", "ssh"}
+	}
+
+	dir, err := os.MkdirTemp("", "gitops-squared-mirror-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating working copy: %w", err)
+	}
+	m := &Mirror{opts: opts, env: gitEnv(opts.Protocols, opts.Username, opts.Token), workdir: dir}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"remote", "add", "origin", opts.URL},
+	} {
+		if _, err := runGit(context.Background(), dir, m.env, args...); err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// URL returns the repository's URL.
+func (m *Mirror) URL() string {
+	return m.opts.URL
+}
+
+// Branch returns the branch written to by default.
+func (m *Mirror) Branch() string {
+	return m.opts.Branch
+}
+
+// Path returns the repository path of a file relative to the mirror's
+// directory.
+func (m *Mirror) Path(name string) string {
+	return path.Join(m.opts.Dir, name)
+}
+
+// Change is a file a commit adds, modifies or deletes. Path is relative to
+// the mirror's directory.
+type Change struct {
+	Path string
+	Op   ChangeOp
+}
+
+// ChangeOp is what a commit does to a file.
+type ChangeOp string
+
+const (
+	ChangeAdd    ChangeOp = "add"
+	ChangeModify ChangeOp = "modify"
+	ChangeDelete ChangeOp = "delete"
+)
+
+// Commit describes the files to write in one commit.
+type Commit struct {
+	// Branch is the branch to commit to; it defaults to the mirror's. If
+	// it doesn't exist yet, it is started from Base (default the mirror's
+	// branch), or as an empty branch if that doesn't exist either.
+	Branch string
+	Base   string
+
+	// Files maps paths relative to the mirror's directory to their new
+	// content; a nil content deletes the file. With Replace, every other
+	// file under the directory is deleted too.
+	Files   map[string][]byte
+	Replace bool
+
+	Author Signature
+
+	// Message returns the commit message for the changes that remain
+	// after comparing Files with the branch.
+	Message func(changes []Change) string
+}
+
+// Commit writes c's files on top of the latest commit of its branch,
+// commits and pushes. It returns the new commit and its changes, or an
+// empty commit if the branch already holds the files. If the push is
+// rejected because the branch moved, it starts over from the new head.
+func (m *Mirror) Commit(ctx context.Context, c Commit) (string, []Change, error) {
+	if c.Branch == "" {
+		c.Branch = m.opts.Branch
+	}
+	if c.Base == "" {
+		c.Base = m.opts.Branch
+	}
+	for name := range c.Files {
+		if !fs.ValidPath(name) || name == "." {
+			return "", nil, fmt.Errorf("invalid file path %q", name)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var err error
+	for attempt := 1; attempt <= maxPushAttempts; attempt++ {
+		var commit string
+		var changes []Change
+		commit, changes, err = m.commitOnce(ctx, c)
+		if !errors.Is(err, errPushRejected) {
+			return commit, changes, err
+		}
+	}
+	return "", nil, err
+}
+
+// errPushRejected is returned when the remote branch moved during a commit.
+var errPushRejected = errors.New("push rejected: branch moved")
+
+func (m *Mirror) commitOnce(ctx context.Context, c Commit) (string, []Change, error) {
+	if err := m.checkout(ctx, c.Branch, c.Base); err != nil {
+		return "", nil, err
+	}
+
+	changes, err := m.apply(c.Files, c.Replace)
+	if err != nil || len(changes) == 0 {
+		return "", nil, err
+	}
+
+	message := "Update files"
+	if c.Message != nil {
+		message = c.Message(changes)
+	}
+	author := c.Author
+	if author.Name == "" {
+		author = m.opts.Committer
+	}
+	env := append(m.env[:len(m.env):len(m.env)],
+		"GIT_AUTHOR_NAME="+author.Name,
+		"GIT_AUTHOR_EMAIL="+author.Email,
+		"GIT_COMMITTER_NAME="+m.opts.Committer.Name,
+		"GIT_COMMITTER_EMAIL="+m.opts.Committer.Email,
+	)
+	dir := m.opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+	if _, err := runGit(ctx, m.workdir, env, "add", "--all", "--", dir); err != nil {
+		return "", nil, err
+	}
+	if _, err := runGit(ctx, m.workdir, env, "commit", "--quiet", "--no-verify", "-m", message); err != nil {
+		return "", nil, err
+	}
+	if _, err := runGit(ctx, m.workdir, m.env, "push", "--quiet", "origin", "HEAD:refs/heads/"+c.Branch); err != nil {
+		if strings.Contains(err.Error(), "[rejected]") || strings.Contains(err.Error(), "fetch first") {
+			return "", nil, fmt.Errorf("%w: %v", errPushRejected, err)
+		}
+		return "", nil, err
+	}
+	out, err := runGit(ctx, m.workdir, m.env, "rev-parse", "HEAD")
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.TrimSpace(string(out)), changes, nil
+}
+
+// checkout resets the working copy to the head of branch, or of base if
+// branch doesn't exist, or to an empty branch if neither does.
+func (m *Mirror) checkout(ctx context.Context, branch, base string) error {
+	out, err := runGit(ctx, m.workdir, m.env, "ls-remote", "--heads", "origin", branch, base)
+	if err != nil {
+		return err
+	}
+	heads := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if _, ref, ok := strings.Cut(line, "\t"); ok {
+			heads[strings.TrimPrefix(ref, "refs/heads/")] = true
+		}
+	}
+	start := branch
+	if !heads[start] {
+		start = base
+	}
+
+	var steps [][]string
+	if heads[start] {
+		steps = [][]string{
+			{"fetch", "--quiet", "--depth", "1", "origin", "refs/heads/" + start},
+			{"checkout", "--quiet", "--force", "-B", branch, "FETCH_HEAD"},
+		}
+	} else {
+		steps = [][]string{
+			{"symbolic-ref", "HEAD", "refs/heads/" + branch},
+			{"update-ref", "-d", "refs/heads/" + branch},
+			{"rm", "-r", "--quiet", "--cached", "--ignore-unmatch", "."},
+		}
+	}
+	steps = append(steps, []string{"clean", "--quiet", "-d", "--force", "-x"})
+	for _, args := range steps {
+		if _, err := runGit(ctx, m.workdir, m.env, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply writes files into the working copy and returns what changed.
+func (m *Mirror) apply(files map[string][]byte, replace bool) ([]Change, error) {
+	root := filepath.Join(m.workdir, filepath.FromSlash(m.opts.Dir))
+	if replace {
+		files = maps.Clone(files)
+		if files == nil {
+			files = make(map[string][]byte)
+		}
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) && p == root {
+				return filepath.SkipDir
+			}
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			if _, ok := files[filepath.ToSlash(rel)]; !ok {
+				files[filepath.ToSlash(rel)] = nil
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", m.opts.Dir, err)
+		}
+	}
+
+	var changes []Change
+	for name, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(name))
+		current, err := os.ReadFile(full)
+		exists := err == nil
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		switch {
+		case content == nil && exists:
+			if err := os.Remove(full); err != nil {
+				return nil, err
+			}
+			changes = append(changes, Change{Path: name, Op: ChangeDelete})
+		case content == nil, exists && bytes.Equal(current, content):
+		default:
+			if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+				return nil, err
+			}
+			if err := os.WriteFile(full, content, 0o644); err != nil {
+				return nil, err
+			}
+			op := ChangeAdd
+			if exists {
+				op = ChangeModify
+			}
+			changes = append(changes, Change{Path: name, Op: op})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
@@ -0,0 +1,232 @@
+package gitsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PullRequests opens and closes pull requests (GitHub) or merge requests
+// (GitLab) in one repository, and verifies the webhooks reporting what
+// happened to them.
+type PullRequests struct {
+	Provider   string
+	Repository string
+	APIURL     string
+
+	token  string
+	secret string
+}
+
+// NewPullRequests returns a client for a repository: "owner/repo" on GitHub
+// or the project path on GitLab. apiURL defaults to the provider's public
+// API. The token needs permission to open pull requests and secret
+// authenticates their webhooks.
+func NewPullRequests(provider, repository, apiURL, token, secret string) (*PullRequests, error) {
+	switch provider {
+	case ProviderGitHub:
+		if apiURL == "" {
+			apiURL = "https://api.github.com"
+		}
+	case ProviderGitLab:
+		if apiURL == "" {
+			apiURL = "https://gitlab.com/api/v4"
+		}
+	default:
+		return nil, fmt.Errorf("unknown provider %q (want github or gitlab)", provider)
+	}
+	if repository == "" {
+		return nil, fmt.Errorf("repository is required")
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("webhook secret is required")
+	}
+	return &PullRequests{
+		Provider:   provider,
+		Repository: repository,
+		APIURL:     strings.TrimSuffix(apiURL, "/"),
+		token:      token,
+		secret:     secret,
+	}, nil
+}
+
+// PullRequest identifies an opened pull request.
+type PullRequest struct {
+	Number int
+	URL    string
+}
+
+// Open opens a pull request merging head into base.
+func (p *PullRequests) Open(ctx context.Context, head, base, title, body string) (PullRequest, error) {
+	var endpoint string
+	var payload any
+	switch p.Provider {
+	case ProviderGitHub:
+		endpoint = fmt.Sprintf("%s/repos/%s/pulls", p.APIURL, p.Repository)
+		payload = map[string]string{"title": title, "head": head, "base": base, "body": body}
+	case ProviderGitLab:
+		endpoint = fmt.Sprintf("%s/projects/%s/merge_requests", p.APIURL, url.PathEscape(p.Repository))
+		payload = map[string]any{
+			"title":                title,
+			"source_branch":        head,
+			"target_branch":        base,
+			"description":          body,
+			"remove_source_branch": true,
+		}
+	}
+
+	var created struct {
+		Number  int    `json:"number"`   // GitHub
+		HTMLURL string `json:"html_url"` // GitHub
+		IID     int    `json:"iid"`      // GitLab
+		WebURL  string `json:"web_url"`  // GitLab
+	}
+	if err := p.do(ctx, http.MethodPost, endpoint, payload, &created); err != nil {
+		return PullRequest{}, fmt.Errorf("opening pull request: %w", err)
+	}
+	if p.Provider == ProviderGitLab {
+		return PullRequest{Number: created.IID, URL: created.WebURL}, nil
+	}
+	return PullRequest{Number: created.Number, URL: created.HTMLURL}, nil
+}
+
+// Close closes a pull request without merging it.
+func (p *PullRequests) Close(ctx context.Context, number int) error {
+	var err error
+	switch p.Provider {
+	case ProviderGitHub:
+		endpoint := fmt.Sprintf("%s/repos/%s/pulls/%d", p.APIURL, p.Repository, number)
+		err = p.do(ctx, http.MethodPatch, endpoint, map[string]string{"state": "closed"}, nil)
+	case ProviderGitLab:
+		endpoint := fmt.Sprintf("%s/projects/%s/merge_requests/%d", p.APIURL, url.PathEscape(p.Repository), number)
+		err = p.do(ctx, http.MethodPut, endpoint, map[string]string{"state_event": "close"}, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("closing pull request %d: %w", number, err)
+	}
+	return nil
+}
+
+// do sends a JSON request to the provider's API and decodes the response
+// into out, if set.
+func (p *PullRequests) do(ctx context.Context, method, endpoint string, payload, out any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch p.Provider {
+	case ProviderGitHub:
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if p.token != "" {
+			req.Header.Set("Authorization", "Bearer "+p.token)
+		}
+	case ProviderGitLab:
+		if p.token != "" {
+			req.Header.Set("PRIVATE-TOKEN", p.token)
+		}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxFileSize)).Decode(out)
+}
+
+// Verify checks a webhook against the secret, as Source.Verify does.
+func (p *PullRequests) Verify(header http.Header, body []byte) error {
+	return verify(p.Provider, p.secret, header, body)
+}
+
+// PullRequestEvent is the provider-neutral part of a pull request webhook.
+type PullRequestEvent struct {
+	Repository string
+	Number     int
+	Head       string // source branch
+
+	// Merged is set when the pull request was merged, and Closed when it
+	// was closed without merging. Both are false for other actions.
+	Merged bool
+	Closed bool
+}
+
+// IsPullRequest reports whether a provider event is about a pull request.
+func IsPullRequest(provider, event string) bool {
+	switch provider {
+	case ProviderGitHub:
+		return event == "pull_request"
+	case ProviderGitLab:
+		return event == "Merge Request Hook"
+	}
+	return false
+}
+
+// ParsePullRequest parses a pull request (GitHub) or merge request
+// (GitLab) webhook payload.
+func ParsePullRequest(provider string, body []byte) (*PullRequestEvent, error) {
+	var p struct {
+		// GitHub
+		Action      string `json:"action"`
+		PullRequest struct {
+			Number int  `json:"number"`
+			Merged bool `json:"merged"`
+			Head   struct {
+				Ref string `json:"ref"`
+			} `json:"head"`
+		} `json:"pull_request"`
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+
+		// GitLab
+		ObjectAttributes struct {
+			IID          int    `json:"iid"`
+			Action       string `json:"action"`
+			SourceBranch string `json:"source_branch"`
+		} `json:"object_attributes"`
+		Project struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("parsing pull request payload: %w", err)
+	}
+
+	if provider == ProviderGitLab {
+		a := p.ObjectAttributes
+		return &PullRequestEvent{
+			Repository: p.Project.PathWithNamespace,
+			Number:     a.IID,
+			Head:       a.SourceBranch,
+			Merged:     a.Action == "merge",
+			Closed:     a.Action == "close",
+		}, nil
+	}
+	pr := p.PullRequest
+	closed := p.Action == "closed"
+	return &PullRequestEvent{
+		Repository: p.Repository.FullName,
+		Number:     pr.Number,
+		Head:       pr.Head.Ref,
+		Merged:     closed && pr.Merged,
+		Closed:     closed && !pr.Merged,
+	}, nil
+}
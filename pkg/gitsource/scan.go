@@ -0,0 +1,196 @@
+package gitsource
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"sigs.k8s.io/yaml"
+)
+
+// FoundResource is a resource found in a checkout. Path is the file it came
+// from, with "#n" appended for the n-th document of a multi-document file.
+type FoundResource struct {
+	Path string
+	ResourceFile
+}
+
+// ScanResult lists what Scan found. Failed maps a path to why it could not
+// be read; Ignored counts YAML documents that are not resources.
+type ScanResult struct {
+	Resources []FoundResource
+	Failed    map[string]string
+	Ignored   int
+}
+
+// Scan reads every .yaml and .yml file under dir (a path relative to the
+// checkout, "" for its root) and collects the resources in them, in either
+// form:
+//
+//   - platformresource.yaml style files: name, spec and optional
+//     namespace and apiVersion, without a kind.
+//   - PlatformResource manifests, as rendered by the API or written for a
+//     classic GitOps repository.
+//
+// Other documents are ignored. Resources without a namespace get
+// namespace. Symlinks can't escape the checkout and hidden directories
+// such as .git are skipped.
+func (c *Checkout) Scan(dir, namespace string) (*ScanResult, error) {
+	dir = strings.Trim(dir, "/")
+	if dir == "" {
+		dir = "."
+	}
+	if !fs.ValidPath(dir) {
+		return nil, fmt.Errorf("invalid path %q", dir)
+	}
+
+	root, err := os.OpenRoot(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	fsys := root.FS()
+
+	result := &ScanResult{}
+	fail := func(p string, err error) {
+		if result.Failed == nil {
+			result.Failed = make(map[string]string)
+		}
+		result.Failed[p] = err.Error()
+	}
+
+	err = fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir {
+				return fmt.Errorf("reading %s: %w", dir, err)
+			}
+			fail(p, err)
+			return nil
+		}
+		if d.IsDir() {
+			if p != dir && strings.HasPrefix(d.Name(), ".") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		ext := path.Ext(p)
+		if !d.Type().IsRegular() || (ext != ".yaml" && ext != ".yml") {
+			return nil
+		}
+
+		data, err := readFile(fsys, p)
+		if err != nil {
+			fail(p, err)
+			return nil
+		}
+		docs := splitYAML(data)
+		for i, doc := range docs {
+			loc := p
+			if len(docs) > 1 {
+				loc = fmt.Sprintf("%s#%d", p, i+1)
+			}
+			file, ok, err := parseResourceDocument(doc)
+			switch {
+			case err != nil:
+				fail(loc, err)
+			case !ok:
+				result.Ignored++
+			default:
+				if file.Namespace == "" {
+					file.Namespace = namespace
+				}
+				result.Resources = append(result.Resources, FoundResource{Path: loc, ResourceFile: *file})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// readFile reads a file of at most maxFileSize bytes.
+func readFile(fsys fs.FS, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFileSize {
+		return nil, fmt.Errorf("file larger than %d bytes", maxFileSize)
+	}
+	return data, nil
+}
+
+// parseResourceDocument parses one YAML document. ok is false if it is
+// neither a resource file nor a PlatformResource manifest.
+func parseResourceDocument(doc []byte) (file *ResourceFile, ok bool, err error) {
+	var head struct {
+		Kind       string          `json:"kind"`
+		APIVersion string          `json:"apiVersion"`
+		Spec       json.RawMessage `json:"spec"`
+	}
+	if err := yaml.Unmarshal(doc, &head); err != nil {
+		return nil, false, fmt.Errorf("parsing YAML: %w", err)
+	}
+
+	switch {
+	case head.Kind == "PlatformResource":
+		if group, _, _ := strings.Cut(head.APIVersion, "/"); group != model.GroupName {
+			return nil, false, nil
+		}
+		var pr model.PlatformResource
+		if err := yaml.Unmarshal(doc, &pr); err != nil {
+			return nil, false, fmt.Errorf("parsing PlatformResource: %w", err)
+		}
+		return &ResourceFile{
+			Namespace: pr.Metadata.Namespace,
+			ResourceRequest: model.ResourceRequest{
+				APIVersion: pr.APIVersion,
+				Name:       pr.Metadata.Name,
+				Spec:       pr.Spec,
+			},
+		}, true, nil
+	case head.Kind == "" && head.Spec != nil:
+		var f ResourceFile
+		if err := yaml.UnmarshalStrict(doc, &f); err != nil {
+			return nil, false, fmt.Errorf("parsing resource file: %w", err)
+		}
+		return &f, true, nil
+	}
+	return nil, false, nil
+}
+
+// splitYAML splits a multi-document YAML stream at "---" lines, dropping
+// empty documents.
+func splitYAML(data []byte) [][]byte {
+	var docs [][]byte
+	var doc []byte
+	flush := func() {
+		if len(bytes.TrimSpace(doc)) > 0 {
+			docs = append(docs, doc)
+		}
+		doc = nil
+	}
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		trimmed := bytes.TrimRight(line, "\r\n")
+		if bytes.Equal(trimmed, []byte("---")) || bytes.HasPrefix(trimmed, []byte("--- ")) {
+			flush()
+			continue
+		}
+		doc = append(doc, line...)
+	}
+	flush()
+	return docs
+}
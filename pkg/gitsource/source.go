@@ -0,0 +1,140 @@
+// Package gitsource ingests resource specs kept in Git. Push webhooks from
+// GitHub or GitLab name the changed platformresource.yaml files, which are
+// fetched from the provider's API and applied. Whole repositories can also
+// be cloned and scanned for resources to import.
+package gitsource
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Providers supported by a Source.
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+)
+
+// ResourceFileName is the name of the files a push is scanned for.
+const ResourceFileName = "platformresource.yaml"
+
+// Source is a Git repository allowed to submit resources.
+//
+//	sources:
+//	  - provider: github
+//	    repository: acme/platform-specs
+//	    branch: main
+//	    namespace: team-a
+//	    tokenEnv: GITHUB_TOKEN
+//	    secretEnv: GITHUB_WEBHOOK_SECRET
+type Source struct {
+	Provider string `json:"provider"`
+
+	// Repository is "owner/repo" on GitHub or the project path on GitLab.
+	Repository string `json:"repository"`
+
+	// Branch is the only branch whose pushes are applied. Defaults to main.
+	Branch string `json:"branch,omitempty"`
+
+	// Namespace is used for files that do not set one. Defaults to "default".
+	Namespace string `json:"namespace,omitempty"`
+
+	// Namespaces are the namespaces files may target. Defaults to just
+	// Namespace, so a team's repository cannot write elsewhere.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// APIURL overrides the provider's API endpoint, e.g. for GitHub
+	// Enterprise or a self-hosted GitLab.
+	APIURL string `json:"apiURL,omitempty"`
+
+	// TokenEnv and SecretEnv name the environment variables holding the
+	// API token used to fetch files and the webhook secret.
+	TokenEnv  string `json:"tokenEnv,omitempty"`
+	SecretEnv string `json:"secretEnv,omitempty"`
+
+	token  string
+	secret string
+}
+
+// Config lists the configured sources.
+type Config struct {
+	Sources []Source `json:"sources"`
+}
+
+// LoadConfig reads a Config from a YAML file, applies defaults and resolves
+// tokens and secrets from the environment. Every source must have a
+// webhook secret.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading git sources: %w", err)
+	}
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing git sources: %w", err)
+	}
+
+	for i := range cfg.Sources {
+		src := &cfg.Sources[i]
+		switch src.Provider {
+		case ProviderGitHub:
+			if src.APIURL == "" {
+				src.APIURL = "https://api.github.com"
+			}
+		case ProviderGitLab:
+			if src.APIURL == "" {
+				src.APIURL = "https://gitlab.com/api/v4"
+			}
+		default:
+			return nil, fmt.Errorf("git source %d: unknown provider %q (want github or gitlab)", i, src.Provider)
+		}
+		if src.Repository == "" {
+			return nil, fmt.Errorf("git source %d: repository is required", i)
+		}
+		src.APIURL = strings.TrimSuffix(src.APIURL, "/")
+		if src.Branch == "" {
+			src.Branch = "main"
+		}
+		if src.Namespace == "" {
+			src.Namespace = "default"
+		}
+		if len(src.Namespaces) == 0 {
+			src.Namespaces = []string{src.Namespace}
+		}
+		if src.TokenEnv != "" {
+			src.token = os.Getenv(src.TokenEnv)
+		}
+		if src.SecretEnv != "" {
+			src.secret = os.Getenv(src.SecretEnv)
+		}
+		if src.secret == "" {
+			return nil, fmt.Errorf("git source %s: webhook secret is required (set secretEnv)", src.Repository)
+		}
+	}
+	return &cfg, nil
+}
+
+// Match returns the source for a provider's repository.
+func (c *Config) Match(provider, repository string) (*Source, bool) {
+	for i := range c.Sources {
+		src := &c.Sources[i]
+		if src.Provider == provider && strings.EqualFold(src.Repository, repository) {
+			return src, true
+		}
+	}
+	return nil, false
+}
+
+// Allows reports whether files from the source may target namespace.
+func (s *Source) Allows(namespace string) bool {
+	return slices.Contains(s.Namespaces, namespace)
+}
+
+// IsResourceFile reports whether a changed path is a resource spec.
+func IsResourceFile(path string) bool {
+	return path == ResourceFileName || strings.HasSuffix(path, "/"+ResourceFileName)
+}
@@ -0,0 +1,286 @@
+// Package hooks runs configured steps around catalog publishing: pre-publish
+// hooks check the assembled manifests and can stop the publish, post-publish
+// hooks are told about the new catalog.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Stages of the publish pipeline.
+const (
+	StagePre  = "pre"
+	StagePost = "post"
+)
+
+// defaultTimeout bounds a hook that sets no timeout.
+const defaultTimeout = 30 * time.Second
+
+// maxOutput bounds how much of a failed hook's output is reported.
+const maxOutput = 4096
+
+// Hook is one step of the publish pipeline. It runs a command or calls a
+// webhook.
+//
+//	hooks:
+//	  - name: kubeconform
+//	    stage: pre
+//	    command: [kubeconform, -strict, -summary, -ignore-missing-schemas]
+//	    timeout: 2m
+//	  - name: ci
+//	    stage: post
+//	    url: https://ci.example.com/hooks/catalog
+type Hook struct {
+	Name string `json:"name"`
+
+	// Stage is pre or post.
+	Stage string `json:"stage"`
+
+	// Command is run with the directory holding the manifests appended as
+	// its last argument. Its environment also has GITOPS_SQUARED_STAGE,
+	// GITOPS_SQUARED_MANIFESTS and, after publishing,
+	// GITOPS_SQUARED_CATALOG_DIGEST and GITOPS_SQUARED_CATALOG_VERSION. A
+	// non-zero exit fails the hook.
+	Command []string `json:"command,omitempty"`
+
+	// URL receives a Payload as a JSON POST. A non-2xx status fails the
+	// hook.
+	URL string `json:"url,omitempty"`
+
+	// Timeout is a Go duration. Defaults to 30s.
+	Timeout string `json:"timeout,omitempty"`
+
+	timeout time.Duration
+}
+
+// Config lists the configured hooks in the order they run.
+type Config struct {
+	Hooks []Hook `json:"hooks"`
+
+	httpClient *http.Client
+}
+
+// LoadConfig reads a Config from a YAML file and applies defaults.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading publish hooks: %w", err)
+	}
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing publish hooks: %w", err)
+	}
+
+	for i := range cfg.Hooks {
+		h := &cfg.Hooks[i]
+		if h.Name == "" {
+			return nil, fmt.Errorf("publish hook %d: name is required", i)
+		}
+		if h.Stage != StagePre && h.Stage != StagePost {
+			return nil, fmt.Errorf("publish hook %s: unknown stage %q (want pre or post)", h.Name, h.Stage)
+		}
+		if (len(h.Command) == 0) == (h.URL == "") {
+			return nil, fmt.Errorf("publish hook %s: exactly one of command and url is required", h.Name)
+		}
+		h.timeout = defaultTimeout
+		if h.Timeout != "" {
+			if h.timeout, err = time.ParseDuration(h.Timeout); err != nil || h.timeout <= 0 {
+				return nil, fmt.Errorf("publish hook %s: invalid timeout %q", h.Name, h.Timeout)
+			}
+		}
+	}
+	cfg.httpClient = &http.Client{}
+	return &cfg, nil
+}
+
+// Payload describes a catalog to a hook. It is POSTed to webhooks.
+type Payload struct {
+	Stage string `json:"stage"`
+
+	// Digest and Version are set after publishing.
+	Digest  string `json:"digest,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	Resources int `json:"resources"`
+
+	// Manifests maps file names, as in the catalog's manifests/
+	// directory, to their contents. Set before publishing.
+	Manifests map[string]string `json:"manifests,omitempty"`
+}
+
+// Result is the outcome of one hook.
+type Result struct {
+	Hook     string
+	Duration time.Duration
+	Err      error
+}
+
+// HookError is returned when a pre-publish hook fails.
+type HookError struct {
+	Hook string
+	Err  error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("pre-publish hook %s failed: %v", e.Hook, e.Err)
+}
+
+func (e *HookError) Unwrap() error { return e.Err }
+
+// Has reports whether any hook runs at stage.
+func (c *Config) Has(stage string) bool {
+	for _, h := range c.Hooks {
+		if h.Stage == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// PrePublish runs the pre-publish hooks in order on the manifests about to
+// be published, keyed by file name. It stops at the first failure and
+// returns it as a *HookError.
+func (c *Config) PrePublish(ctx context.Context, manifests map[string][]byte, resources int) ([]Result, error) {
+	dir, err := writeManifests(manifests)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	payload := Payload{Stage: StagePre, Resources: resources, Manifests: make(map[string]string, len(manifests))}
+	for name, data := range manifests {
+		payload.Manifests[name] = string(data)
+	}
+
+	var results []Result
+	for i := range c.Hooks {
+		h := &c.Hooks[i]
+		if h.Stage != StagePre {
+			continue
+		}
+		result := c.run(ctx, h, dir, payload)
+		results = append(results, result)
+		if result.Err != nil {
+			return results, &HookError{Hook: h.Name, Err: result.Err}
+		}
+	}
+	return results, nil
+}
+
+// PostPublish runs every post-publish hook in order. A failing hook does
+// not stop the others; failures are in the results.
+func (c *Config) PostPublish(ctx context.Context, digest, version string, resources int) []Result {
+	payload := Payload{Stage: StagePost, Digest: digest, Version: version, Resources: resources}
+	var results []Result
+	for i := range c.Hooks {
+		h := &c.Hooks[i]
+		if h.Stage == StagePost {
+			results = append(results, c.run(ctx, h, "", payload))
+		}
+	}
+	return results
+}
+
+func (c *Config) run(ctx context.Context, h *Hook, dir string, payload Payload) Result {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	var err error
+	if len(h.Command) > 0 {
+		err = runCommand(ctx, h, dir, payload)
+	} else {
+		err = c.post(ctx, h, payload)
+	}
+	return Result{Hook: h.Name, Duration: time.Since(start), Err: err}
+}
+
+func runCommand(ctx context.Context, h *Hook, dir string, payload Payload) error {
+	args := h.Command[1:]
+	if dir != "" {
+		args = append(args[:len(args):len(args)], dir)
+	}
+	cmd := exec.CommandContext(ctx, h.Command[0], args...)
+	cmd.Env = append(os.Environ(),
+		"GITOPS_SQUARED_STAGE="+payload.Stage,
+		"GITOPS_SQUARED_MANIFESTS="+dir,
+		"GITOPS_SQUARED_CATALOG_DIGEST="+payload.Digest,
+		"GITOPS_SQUARED_CATALOG_VERSION="+payload.Version,
+	)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("timed out after %s", h.timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("%s: %s", exitErr, tail(out))
+	}
+	return err
+}
+
+func (c *Config) post(ctx context.Context, h *Hook, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutput))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// writeManifests writes manifests to a new temporary directory.
+func writeManifests(manifests map[string][]byte) (string, error) {
+	dir, err := os.MkdirTemp("", "gitops-squared-hooks-")
+	if err != nil {
+		return "", fmt.Errorf("creating manifest directory: %w", err)
+	}
+	for name, data := range manifests {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("writing manifests: %w", err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("writing manifests: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+// tail returns the end of a command's output.
+func tail(out []byte) string {
+	out = bytes.TrimSpace(out)
+	if len(out) > maxOutput {
+		out = append([]byte("..."), out[len(out)-maxOutput:]...)
+	}
+	return string(out)
+}
@@ -0,0 +1,228 @@
+// Package images checks that the container images a resource refers to
+// exist, by resolving each reference against its registry, so a typo'd
+// image fails the write instead of the rollout.
+package images
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/errcode"
+	"oras.land/oras-go/v2/registry/remote/retry"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultTimeout bounds resolving one image if the config sets no timeout.
+const defaultTimeout = 10 * time.Second
+
+// dockerHub is the registry of image references without a registry host.
+const dockerHub = "docker.io"
+
+// Config configures image verification.
+//
+//	timeout: 5s
+//	allowUnreachable: false
+//	registries:
+//	  - host: registry.example.com
+//	    usernameEnv: REGISTRY_USERNAME
+//	    passwordEnv: REGISTRY_PASSWORD
+//	  - host: zot.internal:5000
+//	    plainHTTP: true
+type Config struct {
+	// Timeout is a Go duration bounding the lookup of one image. Defaults
+	// to 10s.
+	Timeout string `json:"timeout,omitempty"`
+
+	// AllowUnreachable accepts images whose registry can't be reached,
+	// with a warning, rather than rejecting the write.
+	AllowUnreachable bool `json:"allowUnreachable,omitempty"`
+
+	// Registries holds credentials and transport settings per registry
+	// host. Other registries are queried anonymously over HTTPS.
+	Registries []Registry `json:"registries,omitempty"`
+
+	timeout time.Duration
+	client  *auth.Client
+}
+
+// Registry configures access to one registry.
+type Registry struct {
+	Host string `json:"host"`
+
+	// PlainHTTP talks HTTP instead of HTTPS.
+	PlainHTTP bool `json:"plainHTTP,omitempty"`
+
+	// UsernameEnv and PasswordEnv name the environment variables holding
+	// the registry's basic auth credentials.
+	UsernameEnv string `json:"usernameEnv,omitempty"`
+	PasswordEnv string `json:"passwordEnv,omitempty"`
+
+	username string
+	password string
+}
+
+// ImageError lists the image references that could not be resolved.
+type ImageError struct {
+	Problems []string
+}
+
+func (e *ImageError) Error() string {
+	return "unresolvable images: " + strings.Join(e.Problems, "; ")
+}
+
+// LoadConfig reads a Config from a YAML file and resolves credentials from
+// the environment.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading image verification config: %w", err)
+	}
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing image verification config: %w", err)
+	}
+
+	cfg.timeout = defaultTimeout
+	if cfg.Timeout != "" {
+		if cfg.timeout, err = time.ParseDuration(cfg.Timeout); err != nil || cfg.timeout <= 0 {
+			return nil, fmt.Errorf("image verification config: invalid timeout %q", cfg.Timeout)
+		}
+	}
+	for i := range cfg.Registries {
+		r := &cfg.Registries[i]
+		if r.Host == "" {
+			return nil, fmt.Errorf("image verification config: registry %d: host is required", i)
+		}
+		if r.UsernameEnv != "" {
+			r.username = os.Getenv(r.UsernameEnv)
+		}
+		if r.PasswordEnv != "" {
+			r.password = os.Getenv(r.PasswordEnv)
+		}
+	}
+	cfg.client = &auth.Client{
+		Client:     retry.DefaultClient,
+		Cache:      auth.NewCache(),
+		Credential: cfg.credential,
+	}
+	return &cfg, nil
+}
+
+// registry returns the settings of the registry at host, if configured.
+func (c *Config) registry(host string) (Registry, bool) {
+	if host == "registry-1.docker.io" {
+		host = dockerHub
+	}
+	for _, r := range c.Registries {
+		if r.Host == host {
+			return r, true
+		}
+	}
+	return Registry{}, false
+}
+
+func (c *Config) credential(_ context.Context, hostport string) (auth.Credential, error) {
+	r, ok := c.registry(hostport)
+	if !ok || r.username == "" {
+		return auth.EmptyCredential, nil
+	}
+	return auth.Credential{Username: r.username, Password: r.password}, nil
+}
+
+// Normalize expands an image reference the way container runtimes do:
+// references without a registry host are on Docker Hub, single-component
+// Docker Hub repositories are under library/, and references without a tag
+// or digest mean :latest.
+func Normalize(image string) string {
+	host, rest, ok := strings.Cut(image, "/")
+	if !ok || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		host, rest = dockerHub, image
+	}
+	if host == dockerHub && !strings.Contains(rest, "/") {
+		rest = "library/" + rest
+	}
+	name, _, _ := strings.Cut(rest, "@")
+	if !strings.Contains(rest, "@") && !strings.Contains(name, ":") {
+		rest += ":latest"
+	}
+	return host + "/" + rest
+}
+
+// Resolve looks image up in its registry and returns its normalized
+// reference pinned to the digest of its manifest (or index). A reference
+// that carries a digest keeps it, once the registry has confirmed it.
+func (c *Config) Resolve(ctx context.Context, image string) (string, error) {
+	ref, err := registry.ParseReference(Normalize(image))
+	if err != nil {
+		return "", fmt.Errorf("invalid reference %q: %w", image, err)
+	}
+	repo := &remote.Repository{Reference: ref, Client: c.client}
+	if r, ok := c.registry(ref.Registry); ok {
+		repo.PlainHTTP = r.PlainHTTP
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	desc, err := repo.Resolve(ctx, ref.Reference)
+	if err != nil {
+		return "", err
+	}
+
+	name := ref.Registry + "/" + ref.Repository
+	if _, err := ref.Digest(); err != nil {
+		name += ":" + ref.Reference
+	}
+	return name + "@" + string(desc.Digest), nil
+}
+
+// ResolveAll resolves every image in images, keyed by field, and returns
+// the pinned references under the same keys. Images that don't exist, or
+// that the registry refuses to show, fail with an *ImageError naming each;
+// so do unreachable registries, unless AllowUnreachable is set, in which
+// case those images are left out with a warning in warnings.
+func (c *Config) ResolveAll(ctx context.Context, images map[string]string) (resolved map[string]string, warnings []string, err error) {
+	fields := make([]string, 0, len(images))
+	for field := range images {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	resolved = make(map[string]string, len(images))
+	var problems []string
+	for _, field := range fields {
+		// Registry errors already name the image.
+		pinned, err := c.Resolve(ctx, images[field])
+		switch {
+		case err == nil:
+			resolved[field] = pinned
+		case rejected(err) || !c.AllowUnreachable:
+			problems = append(problems, fmt.Sprintf("%s: %v", field, err))
+		default:
+			warnings = append(warnings, fmt.Sprintf("%s: %v", field, err))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, warnings, &ImageError{Problems: problems}
+	}
+	return resolved, warnings, nil
+}
+
+// rejected reports whether err is the registry's answer about an image,
+// such as not found or unauthorized, rather than a failure to ask it.
+func rejected(err error) bool {
+	var resp *errcode.ErrorResponse
+	if errors.As(err, &resp) {
+		return resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < http.StatusInternalServerError
+	}
+	return errors.Is(err, errdef.ErrNotFound) || errors.Is(err, errdef.ErrInvalidReference)
+}
@@ -0,0 +1,201 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	fieldManager      = "gitops-squared"
+)
+
+// Client talks to a Kubernetes API server. It only implements what
+// gitops-squared needs, so it avoids pulling in client-go.
+type Client struct {
+	server     string
+	token      string
+	httpClient *http.Client
+}
+
+// RejectedError is returned when the API server refuses a manifest,
+// e.g. because of schema validation or an admission webhook.
+type RejectedError struct {
+	Kind    string
+	Name    string
+	Code    int
+	Message string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("cluster rejected %s %s: %s", e.Kind, e.Name, e.Message)
+}
+
+// NewClient creates a client for the given API server URL, bearer token file,
+// and CA bundle file. Empty token or CA paths are skipped.
+func NewClient(server, tokenFile, caFile string) (*Client, error) {
+	c := &Client{
+		server:     strings.TrimSuffix(server, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	if tokenFile != "" {
+		token, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading token: %w", err)
+		}
+		c.token = strings.TrimSpace(string(token))
+	}
+
+	if caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		c.httpClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}
+	}
+
+	return c, nil
+}
+
+// NewInClusterClient creates a client from the pod's service account.
+func NewInClusterClient() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a cluster")
+	}
+	return NewClient("https://"+host+":"+port, serviceAccountDir+"/token", serviceAccountDir+"/ca.crt")
+}
+
+// DryRun server-side applies each document in manifests with dryRun=All,
+// so schema validation and admission webhooks run without persisting anything.
+// SOPS-encrypted documents are skipped: the API server rejects their sops
+// field and encrypted data, which only Flux decrypts.
+func (c *Client) DryRun(ctx context.Context, manifests []byte) error {
+	for _, doc := range splitDocuments(manifests) {
+		if err := c.dryRunObject(ctx, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) dryRunObject(ctx context.Context, doc []byte) error {
+	var obj struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Metadata   struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		SOPS json.RawMessage `json:"sops"`
+	}
+	if err := yaml.Unmarshal(doc, &obj); err != nil {
+		return fmt.Errorf("parsing manifest: %w", err)
+	}
+	if obj.SOPS != nil {
+		return nil
+	}
+
+	url := fmt.Sprintf("%s%s?dryRun=All&fieldManager=%s&force=true",
+		c.server, objectPath(obj.APIVersion, obj.Kind, obj.Metadata.Namespace, obj.Metadata.Name), fieldManager)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url, bytes.NewReader(doc))
+	if err != nil {
+		return fmt.Errorf("creating dry-run request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/apply-patch+yaml")
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("dry-run request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var status struct {
+		Message string `json:"message"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil || status.Message == "" {
+		status.Message = strings.TrimSpace(string(body))
+	}
+
+	// 401/403 are our own credentials failing, not a verdict on the manifest.
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("dry-run not permitted (HTTP %d): %s", resp.StatusCode, status.Message)
+	}
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("dry-run failed (HTTP %d): %s", resp.StatusCode, status.Message)
+	}
+
+	return &RejectedError{
+		Kind:    obj.Kind,
+		Name:    obj.Metadata.Name,
+		Code:    resp.StatusCode,
+		Message: status.Message,
+	}
+}
+
+// objectPath builds the REST path for an object, e.g.
+// /apis/gitops-squared.io/v1alpha1/namespaces/default/platformresources/web-server.
+func objectPath(apiVersion, kind, namespace, name string) string {
+	prefix := "/apis/" + apiVersion
+	if !strings.Contains(apiVersion, "/") {
+		prefix = "/api/" + apiVersion
+	}
+	if namespace != "" {
+		prefix += "/namespaces/" + namespace
+	}
+	return prefix + "/" + pluralize(kind) + "/" + name
+}
+
+// pluralize derives the resource name for a kind the way Kubernetes does for
+// the kinds gitops-squared emits.
+func pluralize(kind string) string {
+	plural := strings.ToLower(kind)
+	switch {
+	case strings.HasSuffix(plural, "y"):
+		return strings.TrimSuffix(plural, "y") + "ies"
+	case strings.HasSuffix(plural, "s"):
+		return plural + "es"
+	default:
+		return plural + "s"
+	}
+}
+
+// splitDocuments splits a multi-document YAML stream, dropping empty documents.
+func splitDocuments(data []byte) [][]byte {
+	var docs [][]byte
+	for _, doc := range bytes.Split(data, []byte("\n---\n")) {
+		if len(bytes.TrimSpace(doc)) > 0 {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
@@ -0,0 +1,87 @@
+package kube
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testResource = `apiVersion: gitops-squared.io/v1alpha1
+kind: PlatformResource
+metadata:
+  name: db
+  namespace: default
+spec:
+  type: postgres
+`
+
+const testSOPSSecret = `apiVersion: v1
+kind: Secret
+metadata:
+  name: db-credentials
+  namespace: default
+type: Opaque
+data:
+  password: ENC[AES256_GCM,data:Zm9v,iv:YmFy,tag:YmF6,type:str]
+sops:
+  age:
+  - recipient: age1qyqszqgpqyqszqgpqyqszqgpqyqszqgpqyqszqgpqyqszqgpqyqs3290gq
+  lastmodified: "2026-10-16T00:00:00Z"
+  version: 3.9.0
+`
+
+func TestDryRunSkipsSOPSEncryptedDocuments(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		paths = append(paths, r.URL.Path)
+		if r.URL.Query().Get("dryRun") != "All" {
+			t.Errorf("%s: dryRun = %q, want All", r.URL.Path, r.URL.Query().Get("dryRun"))
+		}
+		if strings.Contains(string(body), "sops:") {
+			// What a real API server answers for the unknown field.
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"message":"unknown field \"sops\"","reason":"BadRequest"}`)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c, err := NewClient(server.URL, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DryRun(context.Background(), []byte(testResource+"---\n"+testSOPSSecret)); err != nil {
+		t.Fatalf("DryRun: %v", err)
+	}
+
+	want := "/apis/gitops-squared.io/v1alpha1/namespaces/default/platformresources/db"
+	if len(paths) != 1 || paths[0] != want {
+		t.Errorf("dry-run requests = %v, want only %s", paths, want)
+	}
+}
+
+func TestDryRunRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		io.WriteString(w, `{"message":"spec.type: Unsupported value","reason":"Invalid"}`)
+	}))
+	defer server.Close()
+
+	c, err := NewClient(server.URL, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.DryRun(context.Background(), []byte(testResource))
+	rejected, ok := err.(*RejectedError)
+	if !ok {
+		t.Fatalf("DryRun error = %v, want *RejectedError", err)
+	}
+	if rejected.Kind != "PlatformResource" || rejected.Name != "db" {
+		t.Errorf("rejected %s %s, want PlatformResource db", rejected.Kind, rejected.Name)
+	}
+}
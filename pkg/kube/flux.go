@@ -0,0 +1,143 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrNotFound is returned when an object does not exist.
+var ErrNotFound = errors.New("not found")
+
+// FluxStatus summarizes the status of a Flux OCIRepository or Kustomization.
+type FluxStatus struct {
+	Ready   bool
+	Reason  string
+	Message string
+
+	// Revision is the artifact an OCIRepository last pulled, or the one a
+	// Kustomization last applied, e.g. "latest@sha256:...".
+	Revision string
+
+	// AttemptedRevision is the revision a Kustomization last tried to apply.
+	AttemptedRevision string
+
+	// Stale is true if the controller hasn't observed the latest spec yet.
+	Stale bool
+}
+
+// fluxObject holds the status fields shared by Flux objects.
+type fluxObject struct {
+	Metadata struct {
+		Generation int64 `json:"generation"`
+	} `json:"metadata"`
+	Status struct {
+		ObservedGeneration int64 `json:"observedGeneration"`
+		Conditions         []struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+		Artifact *struct {
+			Revision string `json:"revision"`
+		} `json:"artifact"`
+		LastAppliedRevision   string `json:"lastAppliedRevision"`
+		LastAttemptedRevision string `json:"lastAttemptedRevision"`
+	} `json:"status"`
+}
+
+// OCIRepositoryStatus reads the status of a Flux OCIRepository.
+func (c *Client) OCIRepositoryStatus(ctx context.Context, namespace, name string) (FluxStatus, error) {
+	var obj fluxObject
+	if err := c.get(ctx, objectPath("source.toolkit.fluxcd.io/v1", "OCIRepository", namespace, name), &obj); err != nil {
+		return FluxStatus{}, err
+	}
+	status := obj.status()
+	if obj.Status.Artifact != nil {
+		status.Revision = obj.Status.Artifact.Revision
+	}
+	return status, nil
+}
+
+// KustomizationStatus reads the status of a Flux Kustomization.
+func (c *Client) KustomizationStatus(ctx context.Context, namespace, name string) (FluxStatus, error) {
+	var obj fluxObject
+	if err := c.get(ctx, objectPath("kustomize.toolkit.fluxcd.io/v1", "Kustomization", namespace, name), &obj); err != nil {
+		return FluxStatus{}, err
+	}
+	status := obj.status()
+	status.Revision = obj.Status.LastAppliedRevision
+	status.AttemptedRevision = obj.Status.LastAttemptedRevision
+	return status, nil
+}
+
+func (o *fluxObject) status() FluxStatus {
+	status := FluxStatus{Stale: o.Status.ObservedGeneration < o.Metadata.Generation}
+	for _, cond := range o.Status.Conditions {
+		if cond.Type == "Ready" {
+			status.Ready = cond.Status == "True"
+			status.Reason = cond.Reason
+			status.Message = cond.Message
+		}
+	}
+	return status
+}
+
+// ObservedGeneration reads status.observedGeneration of an object, such as
+// a PlatformResource, whose status is written back from the cluster. It is
+// zero until a status is written.
+func (c *Client) ObservedGeneration(ctx context.Context, apiVersion, kind, namespace, name string) (int64, error) {
+	var obj struct {
+		Status struct {
+			ObservedGeneration int64 `json:"observedGeneration"`
+		} `json:"status"`
+	}
+	if err := c.get(ctx, objectPath(apiVersion, kind, namespace, name), &obj); err != nil {
+		return 0, err
+	}
+	return obj.Status.ObservedGeneration, nil
+}
+
+// RevisionDigest returns the digest part of a Flux revision such as
+// "latest@sha256:...", or the revision itself if it has no tag.
+func RevisionDigest(revision string) string {
+	if i := strings.LastIndex(revision, "@"); i >= 0 {
+		return revision[i+1:]
+	}
+	return revision
+}
+
+// get fetches an object and decodes it into out.
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.server+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("get %s (HTTP %d): %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return nil
+}
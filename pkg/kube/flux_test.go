@@ -0,0 +1,35 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestObservedGeneration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apis/gitops-squared.io/v1alpha1/namespaces/default/platformresources/db":
+			io.WriteString(w, `{"metadata":{"name":"db"},"status":{"observedGeneration":3}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewClient(server.URL, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	observed, err := c.ObservedGeneration(ctx, "gitops-squared.io/v1alpha1", "PlatformResource", "default", "db")
+	if err != nil || observed != 3 {
+		t.Errorf("ObservedGeneration = %d, %v; want 3", observed, err)
+	}
+	if _, err := c.ObservedGeneration(ctx, "gitops-squared.io/v1alpha1", "PlatformResource", "default", "cache"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ObservedGeneration of a missing object: err = %v, want ErrNotFound", err)
+	}
+}
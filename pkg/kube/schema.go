@@ -0,0 +1,413 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"sigs.k8s.io/yaml"
+)
+
+// SchemaConfig configures offline manifest validation.
+//
+//	crds:
+//	  - /etc/gitops-squared/crds/platformresource.yaml
+//	  - /etc/gitops-squared/crds/vendor/
+//	rejectUnknownKinds: false
+type SchemaConfig struct {
+	// CRDs lists CustomResourceDefinition files, or directories searched
+	// for *.yaml, *.yml and *.json files holding them. Other documents are
+	// skipped.
+	CRDs []string `json:"crds"`
+
+	// RejectUnknownKinds fails objects of kinds without a schema. By
+	// default they only get the basic checks.
+	RejectUnknownKinds bool `json:"rejectUnknownKinds,omitempty"`
+}
+
+// SchemaValidator checks manifests offline: every object must parse, have an
+// apiVersion, kind and metadata.name, and match the OpenAPI v3 schema of its
+// CustomResourceDefinition if one was loaded. It catches malformed manifests
+// without a cluster; DryRun additionally catches admission rejections.
+type SchemaValidator struct {
+	schemas      map[string]*Schema // "apiVersion kind" -> schema
+	rejectMissed bool
+}
+
+// SchemaError lists the problems with one object of a manifest.
+type SchemaError struct {
+	Kind     string
+	Name     string
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	object := "manifest"
+	if e.Kind != "" {
+		object = strings.TrimSpace(e.Kind + " " + e.Name)
+	}
+	return fmt.Sprintf("invalid %s: %s", object, strings.Join(e.Problems, "; "))
+}
+
+// LoadSchemaValidator reads a SchemaConfig from a YAML file and loads the
+// schemas of the CRDs it lists.
+func LoadSchemaValidator(path string) (*SchemaValidator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest schemas: %w", err)
+	}
+	var cfg SchemaConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing manifest schemas: %w", err)
+	}
+
+	v := &SchemaValidator{schemas: make(map[string]*Schema), rejectMissed: cfg.RejectUnknownKinds}
+	for _, root := range cfg.CRDs {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			switch filepath.Ext(path) {
+			case ".yaml", ".yml", ".json":
+			default:
+				if path != root {
+					return nil
+				}
+			}
+			return v.loadCRDs(path)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("loading CRDs from %s: %w", root, err)
+		}
+	}
+	return v, nil
+}
+
+// loadCRDs adds the schemas of the CRDs in one file.
+func (v *SchemaValidator) loadCRDs(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	for _, doc := range splitDocuments(data) {
+		var crd struct {
+			Kind string `json:"kind"`
+			Spec struct {
+				Group string `json:"group"`
+				Names struct {
+					Kind string `json:"kind"`
+				} `json:"names"`
+				Versions []struct {
+					Name   string `json:"name"`
+					Schema struct {
+						OpenAPIV3Schema *Schema `json:"openAPIV3Schema"`
+					} `json:"schema"`
+				} `json:"versions"`
+			} `json:"spec"`
+		}
+		if err := yaml.Unmarshal(doc, &crd); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if crd.Kind != "CustomResourceDefinition" {
+			continue
+		}
+		for _, version := range crd.Spec.Versions {
+			schema := version.Schema.OpenAPIV3Schema
+			if schema == nil {
+				continue
+			}
+			if err := schema.compile(); err != nil {
+				return fmt.Errorf("%s: %s/%s %s: %w", path, crd.Spec.Group, version.Name, crd.Spec.Names.Kind, err)
+			}
+			v.schemas[crd.Spec.Group+"/"+version.Name+" "+crd.Spec.Names.Kind] = schema
+		}
+	}
+	return nil
+}
+
+// Kinds lists the apiVersion and kind of every loaded schema, sorted.
+func (v *SchemaValidator) Kinds() []string {
+	kinds := make([]string, 0, len(v.schemas))
+	for k := range v.schemas {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Validate checks every document in manifests. It returns a *SchemaError
+// for the first invalid object.
+func (v *SchemaValidator) Validate(manifests []byte) error {
+	for _, doc := range splitDocuments(manifests) {
+		if err := v.validateObject(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *SchemaValidator) validateObject(doc []byte) error {
+	var obj map[string]any
+	if err := yaml.UnmarshalStrict(doc, &obj); err != nil {
+		return &SchemaError{Problems: []string{err.Error()}}
+	}
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	metadata, _ := obj["metadata"].(map[string]any)
+	name, _ := metadata["name"].(string)
+
+	e := &SchemaError{Kind: kind, Name: name}
+	if apiVersion == "" {
+		e.Problems = append(e.Problems, "apiVersion is required")
+	}
+	if kind == "" {
+		e.Problems = append(e.Problems, "kind is required")
+	}
+	if name == "" {
+		e.Problems = append(e.Problems, "metadata.name is required")
+	}
+
+	if schema, ok := v.schemas[apiVersion+" "+kind]; ok {
+		// The API server validates apiVersion, kind and metadata itself.
+		rest := make(map[string]any, len(obj))
+		for k, val := range obj {
+			switch k {
+			case "apiVersion", "kind", "metadata":
+			default:
+				rest[k] = val
+			}
+		}
+		schema.validate(e, "", rest)
+	} else if v.rejectMissed && apiVersion != "" && kind != "" {
+		e.Problems = append(e.Problems, fmt.Sprintf("no schema for %s %s", apiVersion, kind))
+	}
+
+	if len(e.Problems) > 0 {
+		return e
+	}
+	return nil
+}
+
+// Schema is an OpenAPI v3 schema as found in CRDs. Validation covers the
+// structural keywords and the common value constraints; other keywords are
+// ignored.
+type Schema struct {
+	Type                 string                `json:"type,omitempty"`
+	Properties           map[string]*Schema    `json:"properties,omitempty"`
+	AdditionalProperties *additionalProperties `json:"additionalProperties,omitempty"`
+	Items                *Schema               `json:"items,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	Enum                 []any                 `json:"enum,omitempty"`
+	Minimum              *float64              `json:"minimum,omitempty"`
+	Maximum              *float64              `json:"maximum,omitempty"`
+	MinLength            *int                  `json:"minLength,omitempty"`
+	MaxLength            *int                  `json:"maxLength,omitempty"`
+	MinItems             *int                  `json:"minItems,omitempty"`
+	MaxItems             *int                  `json:"maxItems,omitempty"`
+	Pattern              string                `json:"pattern,omitempty"`
+	Nullable             bool                  `json:"nullable,omitempty"`
+
+	PreserveUnknownFields bool `json:"x-kubernetes-preserve-unknown-fields,omitempty"`
+	IntOrString           bool `json:"x-kubernetes-int-or-string,omitempty"`
+	EmbeddedResource      bool `json:"x-kubernetes-embedded-resource,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// additionalProperties is either a boolean or a schema.
+type additionalProperties struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *additionalProperties) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+func (a additionalProperties) MarshalJSON() ([]byte, error) {
+	if a.Schema != nil {
+		return json.Marshal(a.Schema)
+	}
+	return json.Marshal(a.Allowed)
+}
+
+// compile prepares the schema's patterns.
+func (s *Schema) compile() error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	children := []*Schema{s.Items}
+	for _, p := range s.Properties {
+		children = append(children, p)
+	}
+	if s.AdditionalProperties != nil {
+		children = append(children, s.AdditionalProperties.Schema)
+	}
+	for _, c := range children {
+		if c == nil {
+			continue
+		}
+		if err := c.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate adds a problem to e for every way v violates the schema. path
+// names v in the problems.
+func (s *Schema) validate(e *SchemaError, path string, v any) {
+	problem := func(format string, args ...any) {
+		msg := fmt.Sprintf(format, args...)
+		if path != "" {
+			msg = path + ": " + msg
+		}
+		e.Problems = append(e.Problems, msg)
+	}
+
+	if v == nil {
+		if !s.Nullable && s.Type != "" && !s.PreserveUnknownFields {
+			problem("must not be null")
+		}
+		return
+	}
+	if s.EmbeddedResource {
+		return
+	}
+	if !s.checkType(v) {
+		problem("must be of type %s", s.typeName())
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		problem("%v is not one of %v", v, s.Enum)
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			problem("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			problem("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			problem("%q does not match %s", v, s.Pattern)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			problem("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			problem("must be at most %v", *s.Maximum)
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			problem("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			problem("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(e, fmt.Sprintf("%s[%d]", path, i), item)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				problem("%s is required", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			field := name
+			if path != "" {
+				field = path + "." + name
+			}
+			if p, ok := s.Properties[name]; ok {
+				p.validate(e, field, v[name])
+				continue
+			}
+			switch {
+			case s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil:
+				s.AdditionalProperties.Schema.validate(e, field, v[name])
+			case s.AdditionalProperties != nil && !s.AdditionalProperties.Allowed,
+				len(s.Properties) > 0 && !s.PreserveUnknownFields && s.AdditionalProperties == nil:
+				e.Problems = append(e.Problems, field+": unknown field")
+			}
+		}
+	}
+}
+
+func (s *Schema) typeName() string {
+	if s.IntOrString {
+		return "integer or string"
+	}
+	return s.Type
+}
+
+func (s *Schema) checkType(v any) bool {
+	if s.IntOrString {
+		if _, ok := v.(string); ok {
+			return true
+		}
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	}
+	switch s.Type {
+	case "":
+		return true
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	}
+	return false
+}
+
+func inEnum(enum []any, v any) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(allowed, v) {
+			return true
+		}
+	}
+	return false
+}
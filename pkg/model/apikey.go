@@ -0,0 +1,92 @@
+package model
+
+import (
+	"path"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/auth"
+)
+
+// APIKeyRequest is the JSON body for issuing an API key. Callers using the
+// key are identified as "apikey:<name>" with Groups, narrowed by Scopes.
+type APIKeyRequest struct {
+	Name   string      `json:"name"`
+	Groups []string    `json:"groups,omitempty"`
+	Scopes auth.Scopes `json:"scopes"`
+
+	// TTL (a Go duration such as "720h") or ExpiresAt (RFC 3339) make the
+	// key expire. Keys without either never do.
+	TTL       string `json:"ttl,omitempty"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+}
+
+// Validate checks the key's name, scopes and expiry.
+func (r *APIKeyRequest) Validate() error {
+	var e ValidationError
+	e.checkName("name", r.Name)
+	for _, glob := range r.Scopes.Namespaces {
+		if _, err := path.Match(glob, ""); err != nil || glob == "" {
+			e.add("scopes.namespaces", CodeInvalidGlob, Params{"value": glob})
+		}
+	}
+	for _, t := range r.Scopes.Types {
+		if !knownType(t) {
+			e.add("scopes.types", CodeInvalidType, Params{"value": t, "allowed": typeNames()})
+		}
+	}
+	if r.TTL != "" && r.ExpiresAt != "" {
+		e.add("ttl", CodeMutuallyExclusive, Params{"first": "ttl", "second": "expiresAt"})
+	}
+	if r.TTL != "" {
+		if d, err := time.ParseDuration(r.TTL); err != nil || d <= 0 {
+			e.add("ttl", CodeInvalidDuration, Params{"field": "ttl", "value": r.TTL, "example": "720h"})
+		}
+	}
+	if r.ExpiresAt != "" {
+		if t, err := time.Parse(time.RFC3339, r.ExpiresAt); err != nil {
+			e.add("expiresAt", CodeInvalidTimestamp, Params{"field": "expiresAt", "value": r.ExpiresAt})
+		} else if !t.After(time.Now()) {
+			e.add("expiresAt", CodeInPast, Params{"field": "expiresAt", "value": r.ExpiresAt})
+		}
+	}
+	return e.orNil()
+}
+
+// Expiry returns when the key expires, relative to now for a TTL. ok is
+// false if it never does. Call Validate first.
+func (r *APIKeyRequest) Expiry(now time.Time) (expiresAt time.Time, ok bool) {
+	if r.TTL != "" {
+		d, _ := time.ParseDuration(r.TTL)
+		return now.Add(d).UTC(), true
+	}
+	if r.ExpiresAt != "" {
+		t, _ := time.Parse(time.RFC3339, r.ExpiresAt)
+		return t.UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// APIKey describes an issued API key. The key's token is only shown when
+// it is created.
+type APIKey struct {
+	Name       string      `json:"name"`
+	Groups     []string    `json:"groups,omitempty"`
+	Scopes     auth.Scopes `json:"scopes"`
+	CreatedBy  string      `json:"createdBy"`
+	CreatedAt  string      `json:"createdAt"`
+	ExpiresAt  string      `json:"expiresAt,omitempty"`
+	LastUsedAt string      `json:"lastUsedAt,omitempty"`
+	Expired    bool        `json:"expired,omitempty"`
+}
+
+// User returns the user name callers with the key are identified as.
+func (k *APIKey) User() string {
+	return "apikey:" + k.Name
+}
+
+// APIKeyResponse is returned when a key is created. Token is the bearer
+// token to send in the Authorization header.
+type APIKeyResponse struct {
+	APIKey
+	Token string `json:"token"`
+}
@@ -0,0 +1,57 @@
+package model
+
+// Canary states reported by GET /api/v1/catalog/canary.
+const (
+	CanaryStateIdle    = "idle"    // the canary channel holds no catalog the target doesn't
+	CanaryStateWaiting = "waiting" // not every canary cluster runs the catalog and is ready
+	CanaryStateSoaking = "soaking" // every canary cluster is healthy; promotion follows the soak period
+	CanaryStateHeld    = "held"    // automatic promotion is on hold
+)
+
+// CanaryHold pauses automatic promotion of canary catalogs.
+type CanaryHold struct {
+	Reason string `json:"reason"`
+	HeldBy string `json:"heldBy,omitempty"`
+	HeldAt string `json:"heldAt,omitempty"`
+}
+
+// Validate checks that the hold gives a reason.
+func (h *CanaryHold) Validate() error {
+	var e ValidationError
+	if h.Reason == "" {
+		e.add("reason", CodeRequired, nil)
+	}
+	return e.orNil()
+}
+
+// CanaryCluster is a registered cluster following the canary channel.
+// It is healthy while it runs the canary catalog and reports ready.
+type CanaryCluster struct {
+	Name          string `json:"name"`
+	State         string `json:"state"`
+	RunningDigest string `json:"runningDigest,omitempty"`
+	Ready         *bool  `json:"ready,omitempty"`
+	Healthy       bool   `json:"healthy"`
+}
+
+// CanaryStatus describes the rollout of the catalog on the canary channel.
+type CanaryStatus struct {
+	Channel    string `json:"channel"`
+	Target     string `json:"target"`
+	SoakPeriod string `json:"soakPeriod"`
+	State      string `json:"state"`
+
+	// Digest is the catalog on the canary channel and TargetDigest the one
+	// on the target channel.
+	Digest       string `json:"digest,omitempty"`
+	TargetDigest string `json:"targetDigest,omitempty"`
+
+	// SoakingSince is when every canary cluster was first seen healthy on
+	// Digest, and PromoteAt when it is due for promotion.
+	SoakingSince string `json:"soakingSince,omitempty"`
+	PromoteAt    string `json:"promoteAt,omitempty"`
+
+	Message  string          `json:"message,omitempty"`
+	Hold     *CanaryHold     `json:"hold,omitempty"`
+	Clusters []CanaryCluster `json:"clusters"`
+}
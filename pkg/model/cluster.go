@@ -0,0 +1,171 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ClusterConfig lists the target clusters that get their own catalog.
+//
+//	clusters:
+//	  - name: prod-eu
+//	    environment: prod
+//	    selector:
+//	      namespaces: [team-a, team-b]
+//	      matchLabels: {env: prod}
+//	  - name: staging
+//	    selector:
+//	      types: [vm, bucket]
+type ClusterConfig struct {
+	Clusters []Cluster `json:"clusters"`
+}
+
+// Cluster is a Flux-managed cluster fed from its own catalog.
+type Cluster struct {
+	Name        string          `json:"name"`
+	Environment string          `json:"environment,omitempty"`
+	Selector    ClusterSelector `json:"selector,omitempty"`
+}
+
+// ClusterSelector picks the resources published to a cluster. Every
+// non-empty field must match; an empty selector matches everything.
+type ClusterSelector struct {
+	Namespaces  []string          `json:"namespaces,omitempty"`
+	Types       []string          `json:"types,omitempty"`
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+}
+
+// LoadClusterConfig reads a ClusterConfig from a YAML file.
+func LoadClusterConfig(path string) (*ClusterConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cluster config: %w", err)
+	}
+
+	var cfg ClusterConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing cluster config: %w", err)
+	}
+
+	seen := make(map[string]bool, len(cfg.Clusters))
+	for _, c := range cfg.Clusters {
+		if err := c.Validate(); err != nil {
+			return nil, fmt.Errorf("cluster config: cluster %s: %w", c.Name, err)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("cluster config: duplicate cluster %q", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return &cfg, nil
+}
+
+// Validate checks the cluster's name and selector.
+func (c Cluster) Validate() error {
+	var e ValidationError
+	e.checkName("name", c.Name)
+	for _, t := range c.Selector.Types {
+		if !knownType(t) {
+			e.add("selector.types", CodeUnknownType, Params{"value": t})
+		}
+	}
+	for k, v := range c.Selector.MatchLabels {
+		checkLabel(&e, k, v)
+	}
+	return e.orNil()
+}
+
+// Matches reports whether a resource in namespace, of resourceType and
+// carrying labels, belongs to the cluster.
+func (s ClusterSelector) Matches(namespace, resourceType string, labels map[string]string) bool {
+	if len(s.Namespaces) > 0 && !slices.Contains(s.Namespaces, namespace) {
+		return false
+	}
+	if len(s.Types) > 0 && !slices.Contains(s.Types, resourceType) {
+		return false
+	}
+	for k, v := range s.MatchLabels {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Cluster states reported by GET /api/v1/clusters for registered clusters.
+const (
+	ClusterStatePending      = "pending"      // registered, no heartbeat yet
+	ClusterStateCurrent      = "current"      // running the expected catalog
+	ClusterStateStale        = "stale"        // running an older catalog
+	ClusterStateDisconnected = "disconnected" // no recent heartbeat
+)
+
+// ClusterRegistration is a cluster that registered itself with the API.
+type ClusterRegistration struct {
+	Name        string `json:"name"`
+	Environment string `json:"environment,omitempty"`
+
+	// Catalog is the target cluster whose catalog the cluster should run.
+	// Empty means the target cluster of the same name if there is one, and
+	// the main catalog otherwise.
+	Catalog string `json:"catalog,omitempty"`
+
+	// Channel is the channel of the main catalog the cluster follows.
+	// It can't be combined with Catalog.
+	Channel string `json:"channel,omitempty"`
+
+	RegisteredAt string `json:"registeredAt,omitempty"`
+}
+
+// Validate checks the registration's name, catalog name and channel.
+func (r *ClusterRegistration) Validate() error {
+	var e ValidationError
+	e.checkName("name", r.Name)
+	if r.Catalog != "" {
+		e.checkName("catalog", r.Catalog)
+	}
+	if r.Catalog != "" && r.Channel != "" {
+		e.add("channel", CodeMutuallyExclusive, Params{"first": "channel", "second": "catalog"})
+	}
+	return e.orNil()
+}
+
+// ClusterHeartbeat is what a cluster's agent reports periodically.
+type ClusterHeartbeat struct {
+	// Digest is the catalog digest the cluster is running, e.g. its
+	// OCIRepository's artifact revision ("latest@sha256:..." is accepted).
+	Digest string `json:"digest"`
+
+	// Ready, if reported, is the Ready condition of the cluster's
+	// Kustomization. Canary clusters must report it.
+	Ready *bool `json:"ready,omitempty"`
+}
+
+// ClusterResponse describes a target cluster, a registered cluster, or
+// both when they share a name.
+type ClusterResponse struct {
+	Name        string           `json:"name"`
+	Environment string           `json:"environment,omitempty"`
+	Selector    *ClusterSelector `json:"selector,omitempty"`
+
+	// The catalog last published for a target cluster.
+	URL           string `json:"url,omitempty"`
+	Digest        string `json:"digest,omitempty"`
+	Version       string `json:"version,omitempty"`
+	ResourceCount int    `json:"resourceCount,omitempty"`
+	PublishedAt   string `json:"publishedAt,omitempty"`
+
+	// Registration and heartbeat state of a registered cluster.
+	Registered     bool   `json:"registered"`
+	RegisteredAt   string `json:"registeredAt,omitempty"`
+	Catalog        string `json:"catalog,omitempty"`
+	Channel        string `json:"channel,omitempty"`
+	ExpectedDigest string `json:"expectedDigest,omitempty"`
+	RunningDigest  string `json:"runningDigest,omitempty"`
+	Ready          *bool  `json:"ready,omitempty"`
+	LastHeartbeat  string `json:"lastHeartbeat,omitempty"`
+	State          string `json:"state,omitempty"`
+}
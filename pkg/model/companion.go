@@ -0,0 +1,123 @@
+package model
+
+import (
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// CompanionsConfig maps a resource type (vm, database, bucket) to the
+// companion manifests emitted alongside each PlatformResource of that type.
+type CompanionsConfig map[string]CompanionConfig
+
+// CompanionConfig selects which companion manifests to emit for a type.
+type CompanionConfig struct {
+	ServiceAccount bool                 `json:"serviceAccount,omitempty"`
+	NetworkPolicy  *NetworkPolicyConfig `json:"networkPolicy,omitempty"`
+	ResourceQuota  map[string]string    `json:"resourceQuota,omitempty"` // e.g. {"requests.cpu": "4"}
+}
+
+// NetworkPolicyConfig describes the generated NetworkPolicy. It always denies
+// ingress by default; the fields open it up selectively.
+type NetworkPolicyConfig struct {
+	AllowSameNamespace bool  `json:"allowSameNamespace,omitempty"`
+	Ports              []int `json:"ports,omitempty"`
+}
+
+// LoadCompanionsConfig reads a CompanionsConfig from a YAML file.
+func LoadCompanionsConfig(path string) (CompanionsConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading companions config: %w", err)
+	}
+
+	var cfg CompanionsConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing companions config: %w", err)
+	}
+	for t := range cfg {
+		if !knownType(t) {
+			return nil, fmt.Errorf("companions config: unknown resource type %q", t)
+		}
+	}
+	return cfg, nil
+}
+
+// Render returns the companion manifests for a resource, one document each.
+func (c CompanionConfig) Render(namespace, resource string) ([][]byte, error) {
+	metadata := func(name string) PlatformResourceMetadata {
+		return PlatformResourceMetadata{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "gitops-squared",
+				"gitops-squared.io/resource":   resource,
+			},
+		}
+	}
+
+	var objects []map[string]any
+
+	if c.ServiceAccount {
+		objects = append(objects, map[string]any{
+			"apiVersion": "v1",
+			"kind":       "ServiceAccount",
+			"metadata":   metadata(resource),
+		})
+	}
+
+	if np := c.NetworkPolicy; np != nil {
+		var ingress []any
+		if np.AllowSameNamespace {
+			rule := map[string]any{
+				"from": []any{map[string]any{"podSelector": map[string]any{}}},
+			}
+			if len(np.Ports) > 0 {
+				ports := make([]any, 0, len(np.Ports))
+				for _, p := range np.Ports {
+					ports = append(ports, map[string]any{"protocol": "TCP", "port": p})
+				}
+				rule["ports"] = ports
+			}
+			ingress = append(ingress, rule)
+		}
+
+		spec := map[string]any{
+			"podSelector": map[string]any{
+				"matchLabels": map[string]string{"gitops-squared.io/resource": resource},
+			},
+			"policyTypes": []string{"Ingress"},
+		}
+		if ingress != nil {
+			spec["ingress"] = ingress
+		}
+		objects = append(objects, map[string]any{
+			"apiVersion": "networking.k8s.io/v1",
+			"kind":       "NetworkPolicy",
+			"metadata":   metadata(resource),
+			"spec":       spec,
+		})
+	}
+
+	if len(c.ResourceQuota) > 0 {
+		objects = append(objects, map[string]any{
+			"apiVersion": "v1",
+			"kind":       "ResourceQuota",
+			"metadata":   metadata(resource + "-quota"),
+			"spec": map[string]any{
+				"hard": c.ResourceQuota,
+			},
+		})
+	}
+
+	docs := make([][]byte, 0, len(objects))
+	for _, obj := range objects {
+		doc, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
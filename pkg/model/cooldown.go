@@ -0,0 +1,103 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// CooldownConfig limits how often a single resource may change. The first
+// cooldown matching a resource applies.
+//
+//	cooldowns:
+//	  - name: prod
+//	    namespaces: [payments, checkout]
+//	    maxChanges: 1
+//	    window: 10m
+//	  - name: databases
+//	    types: [database]
+//	    maxChanges: 5
+//	    window: 1h
+type CooldownConfig struct {
+	Cooldowns []Cooldown `json:"cooldowns"`
+}
+
+// Cooldown allows at most MaxChanges changes to each matching resource in
+// any Window.
+type Cooldown struct {
+	Name string `json:"name"`
+
+	// Namespaces and Types select the resources the cooldown applies to.
+	// Empty means all.
+	Namespaces []string `json:"namespaces,omitempty"`
+	Types      []string `json:"types,omitempty"`
+
+	MaxChanges int `json:"maxChanges"`
+
+	// Window is a Go duration.
+	Window string `json:"window"`
+
+	window time.Duration
+}
+
+// LoadCooldownConfig reads a CooldownConfig from a YAML file.
+func LoadCooldownConfig(path string) (*CooldownConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cooldown config: %w", err)
+	}
+	var cfg CooldownConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing cooldown config: %w", err)
+	}
+
+	for i := range cfg.Cooldowns {
+		c := &cfg.Cooldowns[i]
+		if err := c.validate(); err != nil {
+			return nil, fmt.Errorf("cooldown config: cooldown %s: %w", c.Name, err)
+		}
+	}
+	return &cfg, nil
+}
+
+func (c *Cooldown) validate() error {
+	var e ValidationError
+	e.checkName("name", c.Name)
+	for _, t := range c.Types {
+		if !knownType(t) {
+			e.add("types", CodeUnknownType, Params{"value": t})
+		}
+	}
+	if c.MaxChanges < 1 {
+		e.add("maxChanges", CodeMinimum, Params{"min": 1})
+	}
+	window, err := time.ParseDuration(c.Window)
+	if err != nil || window <= 0 {
+		e.add("window", CodeInvalidDuration, Params{"field": "window", "value": c.Window, "example": "1h"})
+	}
+	c.window = window
+	return e.orNil()
+}
+
+// WindowDuration returns the parsed Window.
+func (c Cooldown) WindowDuration() time.Duration {
+	return c.window
+}
+
+// For returns the cooldown applying to a resource in namespace of
+// resourceType. A nil config has none.
+func (c *CooldownConfig) For(namespace, resourceType string) (Cooldown, bool) {
+	if c == nil {
+		return Cooldown{}, false
+	}
+	for _, cd := range c.Cooldowns {
+		if (len(cd.Namespaces) == 0 || slices.Contains(cd.Namespaces, namespace)) &&
+			(len(cd.Types) == 0 || slices.Contains(cd.Types, resourceType)) {
+			return cd, true
+		}
+	}
+	return Cooldown{}, false
+}
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultsConfig fills in and normalizes resource requests before they are
+// validated. Namespace defaults take precedence over type defaults, and
+// explicit request fields over both.
+//
+//	sizes:
+//	  s: small
+//	  xl: large
+//	types:
+//	  database:
+//	    replicas: 2
+//	    labels: {backup: daily}
+//	namespaces:
+//	  team-a:
+//	    region: eu-west-1
+//	    labels: {team: a}
+type DefaultsConfig struct {
+	// Sizes maps size aliases (matched case-insensitively) to a valid size.
+	Sizes map[string]string `json:"sizes,omitempty"`
+
+	// Types and Namespaces hold the defaults per resource type and per
+	// namespace.
+	Types      map[string]Defaults `json:"types,omitempty"`
+	Namespaces map[string]Defaults `json:"namespaces,omitempty"`
+}
+
+// Defaults are the values filled into a request. Labels are mandatory: they
+// are always set on the rendered manifest.
+type Defaults struct {
+	Size     string            `json:"size,omitempty"`
+	Region   string            `json:"region,omitempty"`
+	Replicas int               `json:"replicas,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// LoadDefaultsConfig reads a DefaultsConfig from a YAML file.
+func LoadDefaultsConfig(path string) (*DefaultsConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading defaults config: %w", err)
+	}
+
+	var cfg DefaultsConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing defaults config: %w", err)
+	}
+
+	sizes := make(map[string]string, len(cfg.Sizes))
+	for alias, size := range cfg.Sizes {
+		if !validSizes[size] {
+			return nil, fmt.Errorf("defaults config: size alias %q maps to invalid size %q", alias, size)
+		}
+		sizes[strings.ToLower(alias)] = size
+	}
+	cfg.Sizes = sizes
+
+	for t, d := range cfg.Types {
+		if !knownType(t) {
+			return nil, fmt.Errorf("defaults config: unknown resource type %q", t)
+		}
+		if err := d.validate(t); err != nil {
+			return nil, fmt.Errorf("defaults config: type %s: %w", t, err)
+		}
+	}
+	for ns, d := range cfg.Namespaces {
+		if err := d.validate(""); err != nil {
+			return nil, fmt.Errorf("defaults config: namespace %s: %w", ns, err)
+		}
+	}
+	return &cfg, nil
+}
+
+// validate checks the defaults of resource type t, or of a namespace if t
+// is empty.
+func (d Defaults) validate(t string) error {
+	if d.Replicas != 0 {
+		if err := CheckReplicas("replicas", t, d.Replicas); err != nil {
+			return err
+		}
+	}
+	req := ResourceRequest{
+		Name:   "defaults",
+		Spec:   ResourceSpec{Type: "vm", Size: "small", Region: d.Region, Replicas: DefaultMinReplicas},
+		Labels: d.Labels,
+	}
+	if t != "" {
+		rt, _ := LookupResourceType(t)
+		req.Spec.Type = t
+		req.Spec.Replicas, _ = rt.ReplicaRange()
+	}
+	if d.Size != "" {
+		req.Spec.Size = d.Size
+	}
+	return req.Validate()
+}
+
+// Apply normalizes the request's size and fills in empty fields and
+// mandatory labels for its namespace and type. It is idempotent. A nil
+// config does nothing.
+func (c *DefaultsConfig) Apply(namespace string, req *ResourceRequest) {
+	if c == nil {
+		return
+	}
+
+	if size, ok := c.Sizes[strings.ToLower(req.Spec.Size)]; ok {
+		req.Spec.Size = size
+	}
+
+	// Namespace defaults are applied first so they win.
+	for _, d := range []Defaults{c.Namespaces[namespace], c.Types[req.Spec.Type]} {
+		if req.Spec.Size == "" {
+			req.Spec.Size = d.Size
+		}
+		if req.Spec.Region == "" {
+			req.Spec.Region = d.Region
+		}
+		if req.Spec.Replicas == 0 {
+			req.Spec.Replicas = d.Replicas
+		}
+	}
+
+	// Namespace labels are applied last so they win.
+	for _, d := range []Defaults{c.Types[req.Spec.Type], c.Namespaces[namespace]} {
+		for k, v := range d.Labels {
+			if req.Labels == nil {
+				req.Labels = make(map[string]string)
+			}
+			req.Labels[k] = v
+		}
+	}
+}
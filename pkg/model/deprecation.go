@@ -0,0 +1,215 @@
+package model
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Deprecation marks a resource type or size as on its way out. Deprecated
+// values are accepted with a lint warning; removed ones are rejected.
+// Either way clients are pointed at the replacement.
+type Deprecation struct {
+	Removed     bool   `json:"removed,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+
+	// Message is extra guidance, such as a link to a migration guide.
+	Message string `json:"message,omitempty"`
+}
+
+// params returns the message parameters of a deprecation of value.
+func (d *Deprecation) params(value string) Params {
+	p := Params{"value": value}
+	if d.Replacement != "" {
+		p["replacement"] = d.Replacement
+	}
+	if d.Message != "" {
+		p["message"] = d.Message
+	}
+	return p
+}
+
+// Size is a resource size and its deprecation, if any.
+type Size struct {
+	Name       string       `json:"name"`
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+}
+
+// sizeDeprecations holds the deprecated sizes in effect, by name.
+var sizeDeprecations atomic.Pointer[map[string]*Deprecation]
+
+// Sizes returns the resource sizes, smallest first.
+func Sizes() []Size {
+	deprecations := sizeDeprecations.Load()
+	list := make([]Size, 0, len(sizeNames))
+	for _, name := range sizeNames {
+		s := Size{Name: name}
+		if deprecations != nil {
+			s.Deprecated = (*deprecations)[name]
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+// sizeDeprecation returns the deprecation of size, or nil.
+func sizeDeprecation(size string) *Deprecation {
+	deprecations := sizeDeprecations.Load()
+	if deprecations == nil {
+		return nil
+	}
+	return (*deprecations)[size]
+}
+
+// typeDeprecation returns the deprecation of resource type t, or nil.
+func typeDeprecation(t string) *Deprecation {
+	rt, ok := LookupResourceType(t)
+	if !ok {
+		return nil
+	}
+	return rt.Deprecated
+}
+
+// checkRemoved rejects a spec naming a removed type or size.
+func (e *ValidationError) checkRemoved(spec ResourceSpec) {
+	if d := typeDeprecation(spec.Type); d != nil && d.Removed {
+		e.add("spec.type", CodeTypeRemoved, d.params(spec.Type))
+	}
+	if d := sizeDeprecation(spec.Size); d != nil && d.Removed {
+		e.add("spec.size", CodeSizeRemoved, d.params(spec.Size))
+	}
+}
+
+// checkDeprecations checks the deprecations of a resource types config:
+// replacements must exist and not be removed themselves.
+func (cfg *ResourceTypesConfig) checkDeprecations() error {
+	types := make(map[string]*Deprecation, len(builtinTypes)+len(cfg.Types))
+	for _, t := range builtinTypes {
+		types[t.Name] = nil
+	}
+	for _, t := range cfg.Types {
+		types[t.Name] = t.Deprecated
+	}
+	for _, t := range cfg.Types {
+		if d := t.Deprecated; d != nil && d.Replacement != "" {
+			replacement, ok := types[d.Replacement]
+			switch {
+			case !ok:
+				return fmt.Errorf("resource type %s: unknown replacement %q", t.Name, d.Replacement)
+			case d.Replacement == t.Name || (replacement != nil && replacement.Removed):
+				return fmt.Errorf("resource type %s: replacement %q is removed or the type itself", t.Name, d.Replacement)
+			}
+		}
+	}
+
+	seen := make(map[string]bool, len(cfg.Sizes))
+	for _, s := range cfg.Sizes {
+		if !validSizes[s.Name] {
+			return fmt.Errorf("size %q: must be one of %v", s.Name, sizeNames)
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("size %s: defined twice", s.Name)
+		}
+		seen[s.Name] = true
+		if s.Deprecated == nil {
+			return fmt.Errorf("size %s: deprecated is required", s.Name)
+		}
+	}
+	for _, s := range cfg.Sizes {
+		if r := s.Deprecated.Replacement; r != "" {
+			if !validSizes[r] || r == s.Name {
+				return fmt.Errorf("size %s: invalid replacement %q", s.Name, r)
+			}
+			for _, other := range cfg.Sizes {
+				if other.Name == r && other.Deprecated.Removed {
+					return fmt.Errorf("size %s: replacement %q is removed", s.Name, r)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// TypeMigrationRequest is the JSON body for moving resources off a
+// deprecated type or size. Resources of Type (if set) and Size (if set)
+// get ToType and ToSize, which default to the configured replacements.
+type TypeMigrationRequest struct {
+	Type   string `json:"type,omitempty"`
+	Size   string `json:"size,omitempty"`
+	ToType string `json:"toType,omitempty"`
+	ToSize string `json:"toSize,omitempty"`
+}
+
+// WithReplacements fills in ToType and ToSize from the replacements of a
+// deprecated Type and Size.
+func (r TypeMigrationRequest) WithReplacements() TypeMigrationRequest {
+	if d := typeDeprecation(r.Type); r.ToType == "" && d != nil {
+		r.ToType = d.Replacement
+	}
+	if d := sizeDeprecation(r.Size); r.ToSize == "" && d != nil {
+		r.ToSize = d.Replacement
+	}
+	return r
+}
+
+// Validate checks that a migration has a source and a usable target.
+func (r *TypeMigrationRequest) Validate() error {
+	var e ValidationError
+	if r.Type == "" && r.Size == "" {
+		e.add("type", CodeAtLeastOne, Params{"item": "of type or size"})
+	}
+	if r.Type != "" {
+		switch d := typeDeprecation(r.ToType); {
+		case r.ToType == "":
+			e.add("toType", CodeRequired, nil)
+		case !knownType(r.ToType):
+			e.add("toType", CodeInvalidType, Params{"value": r.ToType, "allowed": typeNames()})
+		case d != nil && d.Removed:
+			e.add("toType", CodeTypeRemoved, d.params(r.ToType))
+		case r.ToType == r.Type:
+			e.add("toType", CodeSameAsSource, Params{"field": "type"})
+		}
+	} else if r.ToType != "" && r.Size != "" {
+		e.add("type", CodeRequired, nil)
+	}
+	if r.Size != "" {
+		switch d := sizeDeprecation(r.ToSize); {
+		case r.ToSize == "":
+			e.add("toSize", CodeRequired, nil)
+		case !validSizes[r.ToSize]:
+			e.add("toSize", CodeInvalidSize, Params{"value": r.ToSize, "allowed": sizeNames})
+		case d != nil && d.Removed:
+			e.add("toSize", CodeSizeRemoved, d.params(r.ToSize))
+		case r.ToSize == r.Size:
+			e.add("toSize", CodeSameAsSource, Params{"field": "size"})
+		}
+	} else if r.ToSize != "" && r.Type != "" {
+		e.add("size", CodeRequired, nil)
+	}
+	return e.orNil()
+}
+
+// Matches reports whether spec is one the migration moves.
+func (r TypeMigrationRequest) Matches(spec ResourceSpec) bool {
+	return (r.Type != "" || r.Size != "") &&
+		(r.Type == "" || spec.Type == r.Type) &&
+		(r.Size == "" || spec.Size == r.Size)
+}
+
+// Apply returns spec moved to the migration's targets.
+func (r TypeMigrationRequest) Apply(spec ResourceSpec) ResourceSpec {
+	if r.Type != "" {
+		spec.Type = r.ToType
+	}
+	if r.Size != "" {
+		spec.Size = r.ToSize
+	}
+	return spec
+}
+
+// TypeMigrationResponse summarises a type or size migration run.
+type TypeMigrationResponse struct {
+	TypeMigrationRequest
+	DryRun   bool              `json:"dryRun,omitempty"`
+	Migrated []string          `json:"migrated"`
+	Failed   map[string]string `json:"failed,omitempty"`
+}
@@ -0,0 +1,3 @@
+// Package model defines the resources managed by gitops-squared, their
+// validation and schema versions, and the JSON bodies of the HTTP API.
+package model
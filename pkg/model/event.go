@@ -0,0 +1,31 @@
+package model
+
+// Event types.
+const (
+	EventResourceCreated      = "resource.created"
+	EventResourceUpdated      = "resource.updated"
+	EventResourceDeleted      = "resource.deleted"
+	EventResourceRestored     = "resource.restored"
+	EventResourceQuarantined  = "resource.quarantined"
+	EventResourceUnmanaged    = "resource.unmanaged"
+	EventAdmissionDenied      = "resource.admission_denied"
+	EventProposalMerged       = "proposal.merged"
+	EventCatalogPublished     = "catalog.published"
+	EventCatalogPublishFailed = "catalog.publish_failed"
+	EventCatalogRolledBack    = "catalog.rolled_back"
+	EventCatalogPromoted      = "catalog.promoted"
+)
+
+// Event is a change to a resource or the catalog. IDs increase
+// monotonically, so a client can resume after the last ID it saw.
+type Event struct {
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`
+	Time      string `json:"time"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	Actor     string `json:"actor,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
@@ -0,0 +1,38 @@
+package model
+
+// FluxStatusResponse compares the published catalog with what Flux has
+// pulled and applied.
+type FluxStatusResponse struct {
+	CatalogDigest string `json:"catalogDigest"`
+
+	// Converged is true once every source has pulled and every
+	// Kustomization has applied the latest catalog (and shards).
+	Converged bool `json:"converged"`
+
+	Source        FluxObjectStatus  `json:"source"`
+	Kustomization FluxObjectStatus  `json:"kustomization"`
+	Shards        []FluxShardStatus `json:"shards,omitempty"`
+}
+
+// FluxShardStatus is the Flux status of one catalog shard.
+type FluxShardStatus struct {
+	Shard         string           `json:"shard"`
+	Digest        string           `json:"digest"`
+	Source        FluxObjectStatus `json:"source"`
+	Kustomization FluxObjectStatus `json:"kustomization"`
+}
+
+// FluxObjectStatus is the status of one OCIRepository or Kustomization.
+type FluxObjectStatus struct {
+	Name     string `json:"name"`
+	Found    bool   `json:"found"`
+	Ready    bool   `json:"ready"`
+	Revision string `json:"revision,omitempty"`
+
+	// Current is true if Revision is the latest published digest: pulled,
+	// for a source, or applied, for a Kustomization.
+	Current bool   `json:"current"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
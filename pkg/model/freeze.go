@@ -0,0 +1,139 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/schedule"
+)
+
+// FreezeWindow is a change freeze on a set of namespaces, given by name
+// or by the lint policy environment they are in. It is either recurring (Cron marks each start, Duration its length, e.g. "0 18 * * 5"
+// and "62h" for Friday 18:00 to Monday 08:00) or one-off (Start and End).
+type FreezeWindow struct {
+	Name string `json:"name"`
+
+	// Namespaces are the frozen namespaces; "*" freezes all of them.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Environments freezes the namespaces of these lint policy
+	// environments.
+	Environments []string `json:"environments,omitempty"`
+
+	Cron     string `json:"cron,omitempty"`
+	Duration string `json:"duration,omitempty"`
+
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+
+	Reason string `json:"reason,omitempty"`
+}
+
+// FreezeStatus is a freeze window with its state at a point in time.
+type FreezeStatus struct {
+	FreezeWindow
+	Active bool `json:"active"`
+
+	// ActiveUntil is set while the window is active; NextStart while it
+	// is not and another start is coming.
+	ActiveUntil string `json:"activeUntil,omitempty"`
+	NextStart   string `json:"nextStart,omitempty"`
+}
+
+// Validate checks the window name, scope and timing. Environments must be
+// defined by policy.
+func (f *FreezeWindow) Validate(policy *LintPolicy) error {
+	var e ValidationError
+	e.checkName("name", f.Name)
+	if len(f.Namespaces) == 0 && len(f.Environments) == 0 {
+		e.add("namespaces", CodeAtLeastOne, Params{"item": `namespace (or "*") or environment`})
+	}
+	for i, ns := range f.Namespaces {
+		if ns != "*" {
+			e.checkName(fmt.Sprintf("namespaces[%d]", i), ns)
+		}
+	}
+	for i, env := range f.Environments {
+		if !policy.HasEnvironment(env) {
+			e.add(fmt.Sprintf("environments[%d]", i), CodeUnknownEnvironment, Params{"value": env})
+		}
+	}
+
+	recurring := f.Cron != "" || f.Duration != ""
+	oneOff := f.Start != "" || f.End != ""
+	switch {
+	case recurring && oneOff:
+		e.add("cron", CodeMutuallyExclusive, Params{"first": "cron/duration", "second": "start/end"})
+	case recurring:
+		if _, err := schedule.Parse(f.Cron); err != nil {
+			e.add("cron", CodeInvalidCron, Params{"error": err.Error()})
+		}
+		if d, err := time.ParseDuration(f.Duration); err != nil || d <= 0 {
+			e.add("duration", CodeInvalidDuration, Params{"field": "duration", "value": f.Duration, "example": "62h"})
+		}
+	case oneOff:
+		start, err := time.Parse(time.RFC3339, f.Start)
+		if err != nil {
+			e.add("start", CodeInvalidTimestamp, Params{"field": "start", "value": f.Start})
+		}
+		end, err2 := time.Parse(time.RFC3339, f.End)
+		if err2 != nil {
+			e.add("end", CodeInvalidTimestamp, Params{"field": "end", "value": f.End})
+		}
+		if err == nil && err2 == nil && !end.After(start) {
+			e.add("end", CodeNotAfterStart, nil)
+		}
+	default:
+		e.add("cron", CodeFreezeWindowRequired, nil)
+	}
+	return e.orNil()
+}
+
+// Covers reports whether the window applies to namespace, whose
+// environment is env ("" for none). An empty namespace stands for a change
+// to all namespaces, which every window covers.
+func (f *FreezeWindow) Covers(namespace, env string) bool {
+	return namespace == "" || slices.Contains(f.Namespaces, "*") || slices.Contains(f.Namespaces, namespace) ||
+		(env != "" && slices.Contains(f.Environments, env))
+}
+
+// Status returns the window's state at now. Call Validate first.
+func (f *FreezeWindow) Status(now time.Time) FreezeStatus {
+	s := FreezeStatus{FreezeWindow: *f}
+	var start, end time.Time
+	if f.Cron != "" {
+		c, err := schedule.Parse(f.Cron)
+		if err != nil {
+			return s
+		}
+		d, _ := time.ParseDuration(f.Duration)
+		// The latest start that is still open is the first one after now-d.
+		start = c.Next(now.Add(-d))
+		end = start.Add(d)
+		if start.IsZero() || start.After(now) {
+			s.NextStart = formatTime(start)
+			return s
+		}
+	} else {
+		start, _ = time.Parse(time.RFC3339, f.Start)
+		end, _ = time.Parse(time.RFC3339, f.End)
+		if now.Before(start) {
+			s.NextStart = formatTime(start)
+			return s
+		}
+		if !now.Before(end) {
+			return s
+		}
+	}
+	s.Active = true
+	s.ActiveUntil = formatTime(end)
+	return s
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,40 @@
+package model
+
+import "encoding/json"
+
+// Job states.
+const (
+	JobQueued    = "queued"    // waiting for a free slot
+	JobRunning   = "running"   // in progress; see Steps
+	JobSucceeded = "succeeded" // finished; see Result
+	JobFailed    = "failed"    // finished with an error; see Error
+	JobCancelled = "cancelled" // cancelled before it finished
+)
+
+// Job is a long-running operation, such as a bulk import or fsck, run in
+// the background.
+type Job struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+	Actor  string `json:"actor"`
+
+	// Steps counts the externally visible steps completed so far, such as
+	// pushes to the registry; LastStep describes the latest.
+	Steps    int    `json:"steps"`
+	LastStep string `json:"lastStep,omitempty"`
+
+	// Result is the response the operation would have returned
+	// synchronously.
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+
+	CreatedAt  string `json:"createdAt"`
+	StartedAt  string `json:"startedAt,omitempty"`
+	FinishedAt string `json:"finishedAt,omitempty"`
+}
+
+// Finished reports whether the job has stopped for good.
+func (j Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCancelled
+}
@@ -0,0 +1,232 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// Schema is the subset of JSON Schema used to describe resource
+// parameters: types, properties, required, additionalProperties, items,
+// enum, numeric bounds, string lengths, patterns and the image format.
+type Schema struct {
+	// Type is object, array, string, integer, number or boolean. Empty
+	// accepts any type.
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+
+	// AdditionalProperties, if false, rejects properties not listed in
+	// Properties.
+	AdditionalProperties *bool `json:"additionalProperties,omitempty"`
+
+	Items *Schema `json:"items,omitempty"`
+	Enum  []any   `json:"enum,omitempty"`
+
+	Minimum   *float64 `json:"minimum,omitempty"`
+	Maximum   *float64 `json:"maximum,omitempty"`
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+
+	// Format "image" marks a string as a container image reference, which
+	// must be well-formed and may be resolved against its registry.
+	Format string `json:"format,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// FormatImage is the Format of container image reference parameters.
+const FormatImage = "image"
+
+// imageReference matches a container image reference: an optional registry
+// host, a repository path, and an optional tag and digest.
+var imageReference = regexp.MustCompile(`^(?:[a-zA-Z0-9](?:[a-zA-Z0-9.-]*[a-zA-Z0-9])?(?::[0-9]+)?/)?[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*(?::[A-Za-z0-9_][A-Za-z0-9_.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`)
+
+// compile checks the schema and prepares its patterns.
+func (s *Schema) compile(path string) error {
+	switch s.Type {
+	case "", "object", "array", "string", "integer", "number", "boolean":
+	default:
+		return fmt.Errorf("%s: unknown type %q", path, s.Type)
+	}
+	switch s.Format {
+	case "":
+	case FormatImage:
+		if s.Type != "string" {
+			return fmt.Errorf("%s: format %s needs type string", path, s.Format)
+		}
+	default:
+		return fmt.Errorf("%s: unknown format %q", path, s.Format)
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", path, err)
+		}
+		s.pattern = re
+	}
+	for name, p := range s.Properties {
+		if p == nil {
+			return fmt.Errorf("%s.%s: empty schema", path, name)
+		}
+		if err := p.compile(path + "." + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.compile(path + "[]"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate adds an error to e for every way v violates the schema. field
+// names v in the errors.
+func (s *Schema) validate(e *ValidationError, field string, v any) {
+	if !s.checkType(v) {
+		e.add(field, CodeWrongJSONType, Params{"type": s.Type})
+		return
+	}
+	if len(s.Enum) > 0 && !s.inEnum(v) {
+		e.add(field, CodeNotAllowedValue, Params{"value": v, "allowed": s.Enum})
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			e.add(field, CodeTooShort, Params{"min": *s.MinLength})
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			e.add(field, CodeTooLong, Params{"max": *s.MaxLength})
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			e.add(field, CodePatternMismatch, Params{"value": v, "pattern": s.Pattern})
+		}
+		if s.Format == FormatImage && !imageReference.MatchString(v) {
+			e.add(field, CodeInvalidImage, Params{"value": v})
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				e.add(field+"."+name, CodeRequired, nil)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p, ok := s.Properties[name]
+			switch {
+			case ok:
+				p.validate(e, field+"."+name, v[name])
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				e.add(field+"."+name, CodeUnknownParameter, nil)
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(e, fmt.Sprintf("%s[%d]", field, i), item)
+			}
+		}
+	default:
+		if f, ok := number(v); ok {
+			if s.Minimum != nil && f < *s.Minimum {
+				e.add(field, CodeMinimum, Params{"min": *s.Minimum})
+			}
+			if s.Maximum != nil && f > *s.Maximum {
+				e.add(field, CodeMaximum, Params{"max": *s.Maximum})
+			}
+		}
+	}
+}
+
+// images adds every image reference in v, which must be valid, to out,
+// keyed by its field.
+func (s *Schema) images(field string, v any, out map[string]string) {
+	switch v := v.(type) {
+	case string:
+		if s.Format == FormatImage {
+			out[field] = v
+		}
+	case map[string]any:
+		for name, p := range s.Properties {
+			if pv, ok := v[name]; ok {
+				p.images(field+"."+name, pv, out)
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.images(fmt.Sprintf("%s[%d]", field, i), item, out)
+			}
+		}
+	}
+}
+
+func (s *Schema) checkType(v any) bool {
+	switch s.Type {
+	case "":
+		return true
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "number":
+		_, ok := number(v)
+		return ok
+	case "integer":
+		f, ok := number(v)
+		return ok && f == math.Trunc(f)
+	}
+	return false
+}
+
+func (s *Schema) inEnum(v any) bool {
+	f, isNumber := number(v)
+	for _, allowed := range s.Enum {
+		if g, ok := number(allowed); ok && isNumber && f == g {
+			return true
+		}
+		if reflect.DeepEqual(allowed, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// number returns v as a float64 if it is a JSON number, whether decoded
+// (float64) or set in Go.
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,19 @@
+package model
+
+// KeyStatus describes a signing or encryption key.
+type KeyStatus struct {
+	Purpose string `json:"purpose"` // signing or encryption
+	Backend string `json:"backend"` // file, awskms, gcpkms or vault
+	KeyID   string `json:"keyId"`
+}
+
+// KeysResponse lists the configured keys.
+type KeysResponse struct {
+	Keys []KeyStatus `json:"keys"`
+}
+
+// KeyRotationResponse reports a key rotation.
+type KeyRotationResponse struct {
+	KeyStatus
+	PreviousKeyID string `json:"previousKeyId"`
+}
@@ -0,0 +1,215 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+// LintWarning is a discouraged but valid choice in a resource spec.
+type LintWarning struct {
+	Rule    string `json:"rule"`
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Params  Params `json:"params,omitempty"`
+}
+
+// lintRule checks one discouraged pattern. env is the namespace's
+// environment, or "" if it has none. Its message is the catalog's for
+// "lint." and its name.
+type lintRule struct {
+	name  string
+	field string
+	check func(spec ResourceSpec, env string) (Params, bool)
+}
+
+// envParams passes env to a lint message, if the namespace has one.
+func envParams(env string) Params {
+	if env == "" {
+		return nil
+	}
+	return Params{"environment": env}
+}
+
+// lintRules are the built-in lint rules, checked in order.
+var lintRules = []lintRule{
+	{
+		name:  "single-replica-database",
+		field: "spec.replicas",
+		check: func(spec ResourceSpec, env string) (Params, bool) {
+			return envParams(env), spec.Type == "database" && spec.Replicas == 1
+		},
+	},
+	{
+		name:  "small-database",
+		field: "spec.size",
+		check: func(spec ResourceSpec, env string) (Params, bool) {
+			return envParams(env), spec.Type == "database" && spec.Size == "small"
+		},
+	},
+	{
+		name:  "missing-region",
+		field: "spec.region",
+		check: func(spec ResourceSpec, env string) (Params, bool) {
+			return nil, spec.Region == ""
+		},
+	},
+	{
+		name:  "deprecated-type",
+		field: "spec.type",
+		check: func(spec ResourceSpec, env string) (Params, bool) {
+			d := typeDeprecation(spec.Type)
+			if d == nil || d.Removed {
+				return nil, false
+			}
+			return d.params(spec.Type), true
+		},
+	},
+	{
+		name:  "deprecated-size",
+		field: "spec.size",
+		check: func(spec ResourceSpec, env string) (Params, bool) {
+			d := sizeDeprecation(spec.Size)
+			if d == nil || d.Removed {
+				return nil, false
+			}
+			return d.params(spec.Size), true
+		},
+	},
+}
+
+// LintRules returns the names of the built-in lint rules.
+func LintRules() []string {
+	names := make([]string, 0, len(lintRules))
+	for _, r := range lintRules {
+		names = append(names, r.name)
+	}
+	return names
+}
+
+// LintPolicy assigns namespaces to environments, turns lint rules off and
+// escalates rules to errors per environment. The zero value reports every
+// rule as a warning.
+//
+//	environments:
+//	  prod: [payments, checkout]
+//	  staging: [payments-staging]
+//	disabled: [missing-region]
+//	errors:
+//	  prod: [single-replica-database]
+//	  "*": [small-database]
+type LintPolicy struct {
+	// Environments lists the namespaces of each environment.
+	Environments map[string][]string `json:"environments,omitempty"`
+
+	// Disabled rules are not checked.
+	Disabled []string `json:"disabled,omitempty"`
+
+	// Errors lists, per environment, the rules that reject a write rather
+	// than warn. "*" applies to every namespace.
+	Errors map[string][]string `json:"errors,omitempty"`
+
+	environments map[string]string // namespace -> environment
+}
+
+// LoadLintPolicy reads a LintPolicy from a YAML file.
+func LoadLintPolicy(path string) (*LintPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading lint policy: %w", err)
+	}
+	var p LintPolicy
+	if err := yaml.UnmarshalStrict(data, &p); err != nil {
+		return nil, fmt.Errorf("parsing lint policy: %w", err)
+	}
+
+	rules := LintRules()
+	for _, name := range p.Disabled {
+		if !slices.Contains(rules, name) {
+			return nil, fmt.Errorf("lint policy: unknown rule %q", name)
+		}
+	}
+	for env, names := range p.Errors {
+		if _, ok := p.Environments[env]; !ok && env != "*" {
+			return nil, fmt.Errorf("lint policy: errors: unknown environment %q", env)
+		}
+		for _, name := range names {
+			if !slices.Contains(rules, name) {
+				return nil, fmt.Errorf("lint policy: errors: %s: unknown rule %q", env, name)
+			}
+		}
+	}
+
+	envs := make([]string, 0, len(p.Environments))
+	for env := range p.Environments {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	p.environments = make(map[string]string)
+	for _, env := range envs {
+		for _, ns := range p.Environments[env] {
+			if other, ok := p.environments[ns]; ok {
+				return nil, fmt.Errorf("lint policy: namespace %s is in both %s and %s", ns, other, env)
+			}
+			p.environments[ns] = env
+		}
+	}
+	return &p, nil
+}
+
+// Environment returns the environment of namespace, or "" if it has none.
+// A nil policy has no environments.
+func (p *LintPolicy) Environment(namespace string) string {
+	if p == nil {
+		return ""
+	}
+	return p.environments[namespace]
+}
+
+// Lint checks spec, as written to namespace, against the lint rules. It
+// returns the warnings, and a *ValidationError holding the rules escalated
+// to errors in the namespace's environment, if any matched. A nil policy
+// reports every rule as a warning.
+func (p *LintPolicy) Lint(namespace string, spec ResourceSpec) ([]LintWarning, error) {
+	env := p.Environment(namespace)
+	var warnings []LintWarning
+	var e ValidationError
+	for _, rule := range lintRules {
+		if p != nil && slices.Contains(p.Disabled, rule.name) {
+			continue
+		}
+		params, ok := rule.check(spec, env)
+		if !ok {
+			continue
+		}
+		code := "lint." + rule.name
+		if p.escalated(env, rule.name) {
+			fe := NewFieldError(rule.field, code, params)
+			fe.Message += " (lint rule " + rule.name + ")"
+			e.Errors = append(e.Errors, fe)
+			continue
+		}
+		warnings = append(warnings, LintWarning{Rule: rule.name, Field: rule.field, Code: code, Message: Message(code, params), Params: params})
+	}
+	return warnings, e.orNil()
+}
+
+// HasEnvironment reports whether the policy defines env.
+func (p *LintPolicy) HasEnvironment(env string) bool {
+	if p == nil {
+		return false
+	}
+	_, ok := p.Environments[env]
+	return ok
+}
+
+func (p *LintPolicy) escalated(env, rule string) bool {
+	if p == nil {
+		return false
+	}
+	return slices.Contains(p.Errors["*"], rule) || (env != "" && slices.Contains(p.Errors[env], rule))
+}
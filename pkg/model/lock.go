@@ -0,0 +1,25 @@
+package model
+
+// LockRequest is the JSON body for locking a resource.
+type LockRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Validate checks that the lock says why it is held.
+func (r *LockRequest) Validate() error {
+	var e ValidationError
+	if r.Reason == "" {
+		e.add("reason", CodeRequired, nil)
+	}
+	return e.orNil()
+}
+
+// ResourceLock reserves a resource for its holder: changes by anyone else
+// are rejected until the holder, or an admin, unlocks it.
+type ResourceLock struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Holder    string `json:"holder"`
+	Reason    string `json:"reason"`
+	LockedAt  string `json:"lockedAt"`
+}
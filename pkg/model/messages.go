@@ -0,0 +1,269 @@
+package model
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"text/template"
+)
+
+// Error codes. Every error response carries one in "code", and every
+// FieldError and LintWarning in its own "code", so clients can translate
+// messages and link to documentation without parsing English. Codes are
+// stable; messages may change.
+const (
+	CodeBadRequest           = "bad_request"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthenticated      = "unauthenticated"
+	CodeForbidden            = "forbidden"
+	CodeOutOfScope           = "out_of_scope"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodeAlreadyExists        = "already_exists"
+	CodeGone                 = "gone"
+	CodePreconditionFailed   = "precondition_failed"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeUnprocessable        = "unprocessable"
+	CodeLocked               = "locked"
+	CodeResourceLocked       = "resource_locked"
+	CodeChangeFrozen         = "change_frozen"
+	CodePreconditionRequired = "precondition_required"
+	CodeTooManyRequests      = "too_many_requests"
+	CodeCooldown             = "cooldown"
+	CodeInternal             = "internal_error"
+	CodeNotImplemented       = "not_implemented"
+	CodeBadGateway           = "bad_gateway"
+	CodeUnavailable          = "unavailable"
+	CodeReadOnly             = "read_only"
+	CodeRestoring            = "restoring"
+	CodeTimeout              = "timeout"
+	CodeAdmissionDenied      = "admission_denied"
+	CodeSchemaInvalid        = "schema_invalid"
+	CodeClusterRejected      = "cluster_rejected"
+	CodeImageUnresolvable    = "image_unresolvable"
+	CodeSecretUnresolvable   = "secret_unresolvable"
+)
+
+// Codes of field errors and lint warnings, with a message template in the
+// catalog. Lint warnings use "lint." and the rule's name.
+const (
+	CodeRequired                  = "required"
+	CodeMutuallyExclusive         = "mutually_exclusive"
+	CodeNamePathSeparator         = "name_path_separator"
+	CodeNameTooLong               = "name_too_long"
+	CodeInvalidName               = "invalid_name"
+	CodeReservedPrefix            = "reserved_prefix"
+	CodeReservedName              = "reserved_name"
+	CodePreviewPrefix             = "preview_prefix"
+	CodeReservedLabel             = "reserved_label"
+	CodeInvalidLabelPrefix        = "invalid_label_prefix"
+	CodeInvalidLabelName          = "invalid_label_name"
+	CodeInvalidLabelValue         = "invalid_label_value"
+	CodeInvalidLabel              = "invalid_label"
+	CodeInvalidOwner              = "invalid_owner"
+	CodeInvalidQuota              = "invalid_quota"
+	CodeInvalidGlob               = "invalid_glob"
+	CodeInvalidType               = "invalid_type"
+	CodeUnknownType               = "unknown_type"
+	CodeInvalidSize               = "invalid_size"
+	CodeInvalidRegion             = "invalid_region"
+	CodeReplicasOutOfRange        = "replicas_out_of_range"
+	CodeInvalidDuration           = "invalid_duration"
+	CodeInvalidTimestamp          = "invalid_timestamp"
+	CodeInPast                    = "in_past"
+	CodeNotAfterStart             = "not_after_start"
+	CodeInvalidCron               = "invalid_cron"
+	CodeFreezeWindowRequired      = "freeze_window_required"
+	CodeAtLeastOne                = "at_least_one"
+	CodeDuplicate                 = "duplicate"
+	CodeInvalidAction             = "invalid_action"
+	CodeNotApplicable             = "not_applicable"
+	CodeMinimum                   = "minimum"
+	CodeMaximum                   = "maximum"
+	CodeWrongJSONType             = "wrong_json_type"
+	CodeNotAllowedValue           = "not_allowed_value"
+	CodeTooShort                  = "too_short"
+	CodeTooLong                   = "too_long"
+	CodePatternMismatch           = "pattern_mismatch"
+	CodeInvalidImage              = "invalid_image"
+	CodeUnknownParameter          = "unknown_parameter"
+	CodeNoParameters              = "no_parameters"
+	CodeSecretSource              = "secret_source"
+	CodeInvalidStoreKind          = "invalid_store_kind"
+	CodeMalformedPlaceholder      = "malformed_placeholder"
+	CodePlaceholderTraversal      = "placeholder_traversal"
+	CodeTemplatePlaintext         = "template_plaintext_secret"
+	CodeUnsupportedSelector       = "unsupported_selector"
+	CodeConflictingSelector       = "conflicting_selector"
+	CodeEmpty                     = "empty"
+	CodeWrongKind                 = "wrong_kind"
+	CodeImmutable                 = "immutable"
+	CodeRepositoryMismatch        = "repository_mismatch"
+	CodeTypeRemoved               = "type_removed"
+	CodeSizeRemoved               = "size_removed"
+	CodeSameAsSource              = "same_as_source"
+	CodeUnknownEnvironment        = "unknown_environment"
+	CodeRegionNotAllowed          = "region_not_allowed"
+	CodeLintSingleReplicaDatabase = "lint.single-replica-database"
+	CodeLintSmallDatabase         = "lint.small-database"
+	CodeLintMissingRegion         = "lint.missing-region"
+	CodeLintDeprecatedType        = "lint.deprecated-type"
+	CodeLintDeprecatedSize        = "lint.deprecated-size"
+)
+
+// Params are the values a message template refers to by name, so a
+// translation can place them wherever its language needs.
+type Params map[string]any
+
+// messages is the English message catalog of field errors and lint
+// warnings. Templates use text/template; q quotes a value like %q.
+var messages = map[string]string{
+	CodeRequired:                  `is required`,
+	CodeMutuallyExclusive:         `{{.first}} and {{.second}} are mutually exclusive`,
+	CodeNamePathSeparator:         `{{q .value}} must not contain path separators or '..'`,
+	CodeNameTooLong:               `{{q .value}} must be at most {{.max}} characters`,
+	CodeInvalidName:               `{{q .value}} must be a DNS-1123 label: lowercase alphanumerics and '-', starting and ending with an alphanumeric`,
+	CodeReservedPrefix:            `{{q .value}} uses reserved prefix {{q .prefix}}`,
+	CodeReservedName:              `{{q .value}} is reserved`,
+	CodePreviewPrefix:             `{{q .value}} uses prefix {{q .prefix}}, which is managed by the preview API`,
+	CodeReservedLabel:             `label {{q .key}} is reserved`,
+	CodeInvalidLabelPrefix:        `invalid label prefix {{q .prefix}}`,
+	CodeInvalidLabelName:          `invalid label name {{q .name}}`,
+	CodeInvalidLabelValue:         `invalid label value {{q .value}}`,
+	CodeInvalidLabel:              `invalid label {{q .key}}={{q .value}}`,
+	CodeInvalidOwner:              `invalid owner {{q .value}}`,
+	CodeInvalidQuota:              `invalid quota {{q .key}}={{q .value}}`,
+	CodeInvalidGlob:               `invalid namespace glob {{q .value}}`,
+	CodeInvalidType:               `invalid type {{q .value}}: must be one of {{join .allowed}}`,
+	CodeUnknownType:               `unknown resource type {{q .value}}`,
+	CodeInvalidSize:               `invalid size {{q .value}}: must be one of {{join .allowed}}`,
+	CodeInvalidRegion:             `invalid region {{q .value}}: must be lowercase alphanumerics and '-'`,
+	CodeReplicasOutOfRange:        `replicas must be between {{.min}} and {{.max}}{{with .type}} for type {{.}}{{end}}`,
+	CodeInvalidDuration:           `invalid {{.field}} {{q .value}}: must be a positive duration such as {{.example}}`,
+	CodeInvalidTimestamp:          `invalid {{.field}} {{q .value}}: must be an RFC 3339 timestamp`,
+	CodeInPast:                    `{{.field}} {{q .value}} is in the past`,
+	CodeNotAfterStart:             `must be after start`,
+	CodeInvalidCron:               `{{.error}}`,
+	CodeFreezeWindowRequired:      `either cron and duration or start and end are required`,
+	CodeAtLeastOne:                `at least one {{.item}} is required`,
+	CodeDuplicate:                 `duplicate {{.item}} {{q .value}}`,
+	CodeInvalidAction:             `invalid action {{q .value}}: must be one of {{join .allowed}}`,
+	CodeNotApplicable:             `only applies to the {{.action}} action`,
+	CodeMinimum:                   `must be at least {{.min}}`,
+	CodeMaximum:                   `must be at most {{.max}}`,
+	CodeWrongJSONType:             `must be of type {{.type}}`,
+	CodeNotAllowedValue:           `{{.value}} is not one of the allowed values {{.allowed}}`,
+	CodeTooShort:                  `must be at least {{.min}} characters`,
+	CodeTooLong:                   `must be at most {{.max}} characters`,
+	CodePatternMismatch:           `{{q .value}} does not match {{.pattern}}`,
+	CodeInvalidImage:              `{{q .value}} is not a valid image reference`,
+	CodeUnknownParameter:          `unknown parameter`,
+	CodeNoParameters:              `type {{.type}} takes no parameters`,
+	CodeSecretSource:              `secret {{q .name}} must set exactly one of data or external`,
+	CodeInvalidStoreKind:          `must be SecretStore or ClusterSecretStore`,
+	CodeMalformedPlaceholder:      `malformed secret placeholder: want ${<store>:<path>#<key>}`,
+	CodePlaceholderTraversal:      `secret placeholder {{.value}} may not contain '..'`,
+	CodeTemplatePlaintext:         `templates cannot carry plaintext secret data; use placeholders or external secrets`,
+	CodeUnsupportedSelector:       `{{q .value}}: only equality requirements (key=value) are supported`,
+	CodeConflictingSelector:       `label {{q .key}} is required to equal both {{q .first}} and {{q .second}}`,
+	CodeEmpty:                     `is empty`,
+	CodeWrongKind:                 `must be {{.kind}}`,
+	CodeImmutable:                 `cannot be changed`,
+	CodeRepositoryMismatch:        `must be {{q .value}}, as in the repository path`,
+	CodeTypeRemoved:               `type {{q .value}} has been removed{{with .replacement}}; use {{q .}} instead{{end}}{{with .message}}. {{.}}{{end}}`,
+	CodeSizeRemoved:               `size {{q .value}} has been removed{{with .replacement}}; use {{q .}} instead{{end}}{{with .message}}. {{.}}{{end}}`,
+	CodeSameAsSource:              `must differ from {{.field}}`,
+	CodeUnknownEnvironment:        `unknown environment {{q .value}}`,
+	CodeRegionNotAllowed:          `region {{q .value}} is not available in namespace {{.namespace}}{{with .allowed}}: must be one of {{join .}}{{else}}: no regions are{{end}}`,
+	CodeLintSingleReplicaDatabase: `replicas=1 for type=database{{with .environment}} in {{.}}{{end}} is discouraged: a single replica has no failover`,
+	CodeLintSmallDatabase:         `size=small for type=database{{with .environment}} in {{.}}{{end}} is meant for development`,
+	CodeLintMissingRegion:         `no region is set, so the platform's default region applies`,
+	CodeLintDeprecatedType:        `type {{q .value}} is deprecated{{with .replacement}}; use {{q .}} instead{{end}}{{with .message}}. {{.}}{{end}}`,
+	CodeLintDeprecatedSize:        `size {{q .value}} is deprecated{{with .replacement}}; use {{q .}} instead{{end}}{{with .message}}. {{.}}{{end}}`,
+}
+
+var messageTemplates = func() map[string]*template.Template {
+	funcs := template.FuncMap{
+		"q": func(v any) string { return fmt.Sprintf("%q", v) },
+		"join": func(v any) string {
+			if list, ok := v.([]string); ok {
+				return strings.Join(list, ", ")
+			}
+			return fmt.Sprint(v)
+		},
+	}
+	parsed := make(map[string]*template.Template, len(messages))
+	for code, text := range messages {
+		parsed[code] = template.Must(template.New(code).Funcs(funcs).Option("missingkey=zero").Parse(text))
+	}
+	return parsed
+}()
+
+// Message renders the English message of code with params. Codes without
+// a template render as the code itself.
+func Message(code string, params Params) string {
+	t, ok := messageTemplates[code]
+	if !ok {
+		return code
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, params); err != nil {
+		return code
+	}
+	return b.String()
+}
+
+// Messages returns the message catalog: the English template of each
+// field error and lint warning code.
+func Messages() map[string]string {
+	return maps.Clone(messages)
+}
+
+// StatusCode returns the code of an error response with HTTP status
+// status that has no more specific one.
+func StatusCode(status int) string {
+	switch status {
+	case 400:
+		return CodeBadRequest
+	case 401:
+		return CodeUnauthenticated
+	case 403:
+		return CodeForbidden
+	case 404:
+		return CodeNotFound
+	case 405:
+		return CodeMethodNotAllowed
+	case 409:
+		return CodeConflict
+	case 410:
+		return CodeGone
+	case 412:
+		return CodePreconditionFailed
+	case 413:
+		return CodePayloadTooLarge
+	case 415:
+		return CodeUnsupportedMediaType
+	case 422:
+		return CodeUnprocessable
+	case 423:
+		return CodeLocked
+	case 428:
+		return CodePreconditionRequired
+	case 429:
+		return CodeTooManyRequests
+	case 501:
+		return CodeNotImplemented
+	case 502:
+		return CodeBadGateway
+	case 503:
+		return CodeUnavailable
+	case 504:
+		return CodeTimeout
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// AnnotationNamespaceOwners lists a namespace's owners on its manifest.
+const AnnotationNamespaceOwners = "gitops-squared.io/owners"
+
+// Namespace is a namespace managed as a first-class object. It is rendered
+// into the catalog as a Namespace manifest, plus a ResourceQuota if Quota
+// is set.
+type Namespace struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Owners []string          `json:"owners,omitempty"`
+
+	// Quota is the namespace's default ResourceQuota, e.g.
+	// {"requests.cpu": "8", "requests.memory": "16Gi"}.
+	Quota map[string]string `json:"quota,omitempty"`
+
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+// NamespaceResponse is a namespace with the number of live resources in it.
+type NamespaceResponse struct {
+	Namespace
+	ResourceCount int `json:"resourceCount"`
+}
+
+// Validate checks the namespace name, labels, owners and quota.
+func (n *Namespace) Validate() error {
+	var e ValidationError
+	e.checkNamespace("name", n.Name)
+	if strings.HasPrefix(n.Name, PreviewNamespacePrefix) {
+		e.add("name", CodePreviewPrefix, Params{"value": n.Name, "prefix": PreviewNamespacePrefix})
+	}
+	for k, v := range n.Labels {
+		if k == "" || len(v) > MaxNameLength {
+			e.add("labels", CodeInvalidLabel, Params{"key": k, "value": v})
+		}
+	}
+	for i, owner := range n.Owners {
+		if strings.TrimSpace(owner) == "" || strings.Contains(owner, ",") {
+			e.add(fmt.Sprintf("owners[%d]", i), CodeInvalidOwner, Params{"value": owner})
+		}
+	}
+	for k, v := range n.Quota {
+		if k == "" || v == "" {
+			e.add("quota", CodeInvalidQuota, Params{"key": k, "value": v})
+		}
+	}
+	return e.orNil()
+}
+
+// ToKubernetesYAML renders the Namespace manifest, followed by its
+// ResourceQuota if one is set.
+func (n *Namespace) ToKubernetesYAML() ([]byte, error) {
+	labels := map[string]string{}
+	for k, v := range n.Labels {
+		labels[k] = v
+	}
+	labels["app.kubernetes.io/managed-by"] = "gitops-squared"
+
+	metadata := map[string]any{
+		"name":   n.Name,
+		"labels": labels,
+	}
+	if len(n.Owners) > 0 {
+		metadata["annotations"] = map[string]string{
+			AnnotationNamespaceOwners: strings.Join(n.Owners, ","),
+		}
+	}
+	objects := []map[string]any{{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   metadata,
+	}}
+	if len(n.Quota) > 0 {
+		objects = append(objects, map[string]any{
+			"apiVersion": "v1",
+			"kind":       "ResourceQuota",
+			"metadata": map[string]any{
+				"name":      "default-quota",
+				"namespace": n.Name,
+				"labels":    map[string]string{"app.kubernetes.io/managed-by": "gitops-squared"},
+			},
+			"spec": map[string]any{"hard": n.Quota},
+		})
+	}
+
+	docs := make([][]byte, 0, len(objects))
+	for _, obj := range objects {
+		doc, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return bytes.Join(docs, []byte("---\n")), nil
+}
@@ -0,0 +1,39 @@
+package model
+
+import "github.com/alfredtm/gitops-squared/pkg/diff"
+
+// Plan actions.
+const (
+	PlanCreate = "create"
+	PlanUpdate = "update"
+	PlanDelete = "delete"
+	PlanNone   = "none" // the request matches the current resource
+)
+
+// PlanResponse describes what applying a request would change, without
+// changing anything.
+type PlanResponse struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Action    string `json:"action"`
+
+	// Changes lists the changed fields of the PlatformResource.
+	Changes []diff.Change `json:"changes"`
+
+	// Diff is a unified diff of the PlatformResource manifest, without
+	// the annotations that change on every push.
+	Diff string `json:"diff,omitempty"`
+
+	Warnings []LintWarning `json:"warnings,omitempty"`
+}
+
+// VersionDiffResponse compares two stored versions of a resource. A
+// deleted version compares as absent.
+type VersionDiffResponse struct {
+	Name      string        `json:"name"`
+	Namespace string        `json:"namespace"`
+	From      string        `json:"from,omitempty"` // empty if To is the first version
+	To        string        `json:"to"`
+	Changes   []diff.Change `json:"changes"`
+	Diff      string        `json:"diff,omitempty"`
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// PreviewNamespacePrefix starts the namespace of every preview environment.
+const PreviewNamespacePrefix = "preview-"
+
+// DefaultPreviewTTL is how long a preview environment lives if the request
+// sets no TTL.
+const DefaultPreviewTTL = 72 * time.Hour
+
+// PreviewRequest is the JSON body for creating a preview environment: a
+// bundle of resources instantiated from templates in an ephemeral namespace,
+// e.g. one per pull request.
+type PreviewRequest struct {
+	// ID names the preview (e.g. "pr-123"); its namespace is "preview-<id>".
+	ID        string            `json:"id"`
+	TTL       string            `json:"ttl,omitempty"`
+	Resources []PreviewResource `json:"resources"`
+}
+
+// PreviewResource is one resource of a preview environment. Name defaults to
+// the template name; non-zero Spec fields override the template.
+type PreviewResource struct {
+	Name     string       `json:"name,omitempty"`
+	Template string       `json:"template"`
+	Spec     ResourceSpec `json:"spec,omitempty"`
+}
+
+// PreviewResponse describes a preview environment.
+type PreviewResponse struct {
+	ID        string             `json:"id"`
+	Namespace string             `json:"namespace"`
+	ExpiresAt string             `json:"expiresAt,omitempty"`
+	Resources []ResourceResponse `json:"resources"`
+	Count     int                `json:"count"`
+}
+
+// PreviewNamespace returns the namespace of the preview with the given ID.
+func PreviewNamespace(id string) string {
+	return PreviewNamespacePrefix + id
+}
+
+// Validate checks the preview ID, TTL and resource list, and defaults
+// resource names to their template.
+func (p *PreviewRequest) Validate() error {
+	var e ValidationError
+	e.checkName("id", p.ID)
+	if len(PreviewNamespace(p.ID)) > MaxNameLength {
+		e.add("id", CodeNameTooLong, Params{"value": p.ID, "max": MaxNameLength - len(PreviewNamespacePrefix)})
+	}
+	if p.TTL != "" {
+		if d, err := time.ParseDuration(p.TTL); err != nil || d <= 0 {
+			e.add("ttl", CodeInvalidDuration, Params{"field": "ttl", "value": p.TTL, "example": "72h"})
+		}
+	}
+	if len(p.Resources) == 0 {
+		e.add("resources", CodeAtLeastOne, Params{"item": "resource"})
+	}
+	seen := make(map[string]bool, len(p.Resources))
+	for i := range p.Resources {
+		res := &p.Resources[i]
+		field := fmt.Sprintf("resources[%d]", i)
+		if res.Template == "" {
+			e.add(field+".template", CodeRequired, nil)
+			continue
+		}
+		if res.Name == "" {
+			res.Name = res.Template
+		}
+		if seen[res.Name] {
+			e.add(field+".name", CodeDuplicate, Params{"item": "resource", "value": res.Name})
+		}
+		seen[res.Name] = true
+	}
+	return e.orNil()
+}
+
+// Expiry returns when the preview expires, relative to now. Call Validate
+// first.
+func (p *PreviewRequest) Expiry(now time.Time) time.Time {
+	ttl := DefaultPreviewTTL
+	if p.TTL != "" {
+		ttl, _ = time.ParseDuration(p.TTL)
+	}
+	return now.Add(ttl).UTC()
+}
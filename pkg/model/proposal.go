@@ -0,0 +1,93 @@
+package model
+
+// Proposal actions.
+const (
+	// ProposalActionApply creates or updates the resource.
+	ProposalActionApply = "apply"
+
+	// ProposalActionDelete deletes the resource.
+	ProposalActionDelete = "delete"
+)
+
+// Proposal states.
+const (
+	ProposalOpen   = "open"   // awaiting review
+	ProposalMerged = "merged" // approved and published
+	ProposalClosed = "closed" // withdrawn or rejected
+	ProposalFailed = "failed" // approved, but publishing failed; see Error
+)
+
+// ProposalRequest is the JSON body for proposing a change to a resource.
+// For the delete action only Resource.Name is used.
+type ProposalRequest struct {
+	Title       string          `json:"title"`
+	Description string          `json:"description,omitempty"`
+	Action      string          `json:"action,omitempty"` // default apply
+	Resource    ResourceRequest `json:"resource"`
+}
+
+// Validate checks the title and action. The resource itself is validated
+// like a create request.
+func (r *ProposalRequest) Validate() error {
+	var e ValidationError
+	if r.Title == "" {
+		e.add("title", CodeRequired, nil)
+	}
+	switch r.Action {
+	case ProposalActionApply, ProposalActionDelete:
+	default:
+		e.add("action", CodeInvalidAction, Params{"value": r.Action, "allowed": []string{ProposalActionApply, ProposalActionDelete}})
+	}
+	e.checkName("resource.name", r.Resource.Name)
+	return e.orNil()
+}
+
+// Proposal is a staged change awaiting review. Merging it publishes the
+// change like a direct API call would.
+type Proposal struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Action      string `json:"action"`
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+
+	// Request is the validated resource request published on merge.
+	Request *ResourceRequest `json:"request,omitempty"`
+
+	// BaseDigest is the resource's digest when the change was proposed, or
+	// empty for a new resource. Merging fails if the resource changed since.
+	BaseDigest string `json:"baseDigest,omitempty"`
+
+	// DraftDigest is the digest of the draft artifact holding the rendered
+	// manifest.
+	DraftDigest string `json:"draftDigest,omitempty"`
+
+	Status    string `json:"status"`
+	Author    string `json:"author"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+
+	// Branch and PullRequest are set when the proposal was opened as a
+	// pull request in the Git mirror.
+	Branch      string               `json:"branch,omitempty"`
+	PullRequest *ProposalPullRequest `json:"pullRequest,omitempty"`
+
+	// MergedBy, Version and Error record the outcome of a merge.
+	MergedBy string `json:"mergedBy,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ProposalPullRequest identifies a proposal's pull request (GitHub) or
+// merge request (GitLab).
+type ProposalPullRequest struct {
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+}
+
+// ProposalResponse is a proposal with its draft manifest.
+type ProposalResponse struct {
+	Proposal
+	Manifest string `json:"manifest,omitempty"`
+}
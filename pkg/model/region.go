@@ -0,0 +1,50 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Region is a region resources may be placed in. A region without
+// Environments or Namespaces is available everywhere; otherwise only in
+// the namespaces of the listed lint policy environments and in the listed
+// namespaces.
+type Region struct {
+	Name         string   `json:"name"`
+	Description  string   `json:"description,omitempty"`
+	Environments []string `json:"environments,omitempty"`
+	Namespaces   []string `json:"namespaces,omitempty"`
+}
+
+// Validate checks the region name and where it is available. Environments
+// must be defined by policy.
+func (r *Region) Validate(policy *LintPolicy) error {
+	var e ValidationError
+	e.checkName("name", r.Name)
+	for i, env := range r.Environments {
+		if !policy.HasEnvironment(env) {
+			e.add(fmt.Sprintf("environments[%d]", i), CodeUnknownEnvironment, Params{"value": env})
+		}
+	}
+	for i, ns := range r.Namespaces {
+		e.checkName(fmt.Sprintf("namespaces[%d]", i), ns)
+	}
+	return e.orNil()
+}
+
+// AvailableIn reports whether the region may be used in namespace, whose
+// environment is env ("" for none).
+func (r Region) AvailableIn(namespace, env string) bool {
+	if len(r.Environments) == 0 && len(r.Namespaces) == 0 {
+		return true
+	}
+	return slices.Contains(r.Namespaces, namespace) || (env != "" && slices.Contains(r.Environments, env))
+}
+
+// RegionError rejects a region not available in a namespace. Allowed are
+// the regions that are.
+func RegionError(namespace, region string, allowed []string) error {
+	var e ValidationError
+	e.add("spec.region", CodeRegionNotAllowed, Params{"value": region, "namespace": namespace, "allowed": allowed})
+	return e.orNil()
+}
@@ -0,0 +1,663 @@
+package model
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"strconv"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ResourceSpec is the user-facing spec for a platform resource.
+type ResourceSpec struct {
+	Type     string `json:"type"`
+	Size     string `json:"size"`
+	Region   string `json:"region,omitempty"`
+	Replicas int    `json:"replicas,omitempty"`
+
+	// Parameters are type-specific settings, validated against the
+	// resource type's parameter schema.
+	Parameters map[string]any `json:"parameters,omitempty"`
+}
+
+// ResourceRequest is the JSON body for creating/updating a resource via the API.
+type ResourceRequest struct {
+	// APIVersion selects the request schema; empty means v1alpha1.
+	APIVersion string       `json:"apiVersion,omitempty"`
+	Name       string       `json:"name"`
+	Spec       ResourceSpec `json:"spec"`
+	Secrets    []SecretSpec `json:"secrets,omitempty"`
+
+	// TTL (a Go duration such as "72h") or ExpiresAt (RFC 3339) make the
+	// resource expire. If neither is set, an existing expiry is kept.
+	TTL       string `json:"ttl,omitempty"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+
+	// Labels are extra labels rendered into the manifest. They come from
+	// the defaults config, not from clients.
+	Labels map[string]string `json:"-"`
+}
+
+// CloneRequest is the JSON body for cloning a resource. Namespace defaults to
+// the source's namespace; non-zero Spec fields override the source spec.
+type CloneRequest struct {
+	Name      string       `json:"name"`
+	Namespace string       `json:"namespace,omitempty"`
+	Spec      ResourceSpec `json:"spec,omitempty"`
+}
+
+// AdoptResponse is the JSON response to adopting an artifact pushed by
+// another tool.
+type AdoptResponse struct {
+	ResourceResponse
+
+	// AdoptedDigest is the adopted artifact, now the new version's parent.
+	AdoptedDigest string `json:"adoptedDigest"`
+
+	// Dropped lists the documents of the artifact that were not carried
+	// over, as "Kind/name".
+	Dropped []string `json:"dropped,omitempty"`
+}
+
+// ResourceResponse is the JSON response from the API.
+type ResourceResponse struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace,omitempty"`
+	Version         string            `json:"version,omitempty"`
+	Digest          string            `json:"digest,omitempty"`
+	Repository      string            `json:"repository,omitempty"`
+	Spec            ResourceSpec      `json:"spec"`
+	CreatedAt       string            `json:"createdAt,omitempty"`
+	UpdatedAt       string            `json:"updatedAt,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Deleted         bool              `json:"deleted,omitempty"`
+	DeletedAt       string            `json:"deletedAt,omitempty"`
+	RestorableUntil string            `json:"restorableUntil,omitempty"`
+	Secrets         []string          `json:"secrets,omitempty"`
+	Cost            *CostEstimate     `json:"cost,omitempty"`
+	ExpiresAt       string            `json:"expiresAt,omitempty"`
+	Generation      int64             `json:"generation,omitempty"`
+
+	// ObservedGeneration is the generation the cluster last acted on, and
+	// Synced whether it is the current one. Both are only set by GET
+	// /api/v1/resources/{name} when the API reads the cluster.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	Synced             *bool `json:"synced,omitempty"`
+
+	// Images maps each image parameter to the digest its reference
+	// resolved to when the version was written, if images are verified.
+	Images map[string]string `json:"images,omitempty"`
+
+	// Warnings are set on write responses: the lint rules the spec
+	// triggered without being rejected.
+	Warnings []LintWarning `json:"warnings,omitempty"`
+
+	// Changed is set on write responses: false means the request matched
+	// the current version and nothing was pushed.
+	Changed *bool `json:"changed,omitempty"`
+}
+
+// CatalogResponse describes the last published catalog artifact.
+type CatalogResponse struct {
+	Digest             string   `json:"digest"`
+	Version            string   `json:"version,omitempty"`
+	Reference          string   `json:"reference"`
+	ResourceCount      int      `json:"resourceCount"`
+	BuiltAt            string   `json:"builtAt"`
+	Resources          []string `json:"resources"`
+	Signature          string   `json:"signature,omitempty"`
+	SignatureAlgorithm string   `json:"signatureAlgorithm,omitempty"`
+	SigningKey         string   `json:"signingKey,omitempty"`
+	PublicKey          string   `json:"publicKey,omitempty"`
+
+	// Shards maps each catalog shard to its digest when the catalog is
+	// partitioned.
+	Shards map[string]string `json:"shards,omitempty"`
+}
+
+// CatalogProvenance is the inventory attached to every published catalog:
+// the resource artifact each manifest in it was taken from. Flux reports
+// the catalog digest it applied, so cluster state traces back to exact
+// resource digests.
+type CatalogProvenance struct {
+	Catalog   string `json:"catalog"` // catalog manifest digest
+	Version   string `json:"version,omitempty"`
+	Reference string `json:"reference"`
+	BuiltAt   string `json:"builtAt"`
+	Builder   string `json:"builder"`
+
+	Resources []ProvenanceResource `json:"resources"`
+
+	// Shards maps each catalog shard to its digest when the catalog is
+	// partitioned.
+	Shards map[string]string `json:"shards,omitempty"`
+}
+
+// ProvenanceResource is one resource in a catalog's provenance.
+type ProvenanceResource struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+
+	// Digest and Reference identify the resource artifact, and
+	// ManifestDigest the sha256 of the manifest as written to the catalog.
+	Digest         string `json:"digest,omitempty"`
+	Reference      string `json:"reference,omitempty"`
+	ManifestDigest string `json:"manifestDigest"`
+}
+
+// CatalogFile describes one file inside the published catalog tarball.
+type CatalogFile struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	Digest string `json:"digest"`
+}
+
+// CatalogContentsResponse lists the files of the published catalog tarball.
+type CatalogContentsResponse struct {
+	Digest    string        `json:"digest"`
+	Files     []CatalogFile `json:"files"`
+	TotalSize int           `json:"totalSize"`
+}
+
+// CatalogVersionResponse describes one entry in the catalog history.
+type CatalogVersionResponse struct {
+	Version   string `json:"version"`
+	Digest    string `json:"digest"`
+	CreatedAt string `json:"createdAt,omitempty"`
+	Current   bool   `json:"current,omitempty"`
+}
+
+// ResourceVersionResponse describes one version in a resource's history.
+type ResourceVersionResponse struct {
+	Version   string `json:"version"`
+	Digest    string `json:"digest"`
+	Parent    string `json:"parent,omitempty"`
+	CreatedAt string `json:"createdAt,omitempty"`
+	Deleted   bool   `json:"deleted,omitempty"`
+	ChangedBy string `json:"changedBy,omitempty"`
+	Source    string `json:"source,omitempty"`
+	UserAgent string `json:"userAgent,omitempty"`
+}
+
+// ResourceHistoryResponse is a resource's version chain, newest first.
+// Verified is false if the chain is broken; Error says where.
+type ResourceHistoryResponse struct {
+	Name      string                    `json:"name"`
+	Namespace string                    `json:"namespace"`
+	Versions  []ResourceVersionResponse `json:"versions"`
+	Count     int                       `json:"count"`
+	Verified  bool                      `json:"verified"`
+	Error     string                    `json:"error,omitempty"`
+}
+
+// AttachmentResponse describes an auxiliary file attached to a resource.
+// Resource is the digest of the version it was attached to.
+type AttachmentResponse struct {
+	Key       string `json:"key"`
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	Resource  string `json:"resource"`
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+// CatalogChannel is a catalog channel and the catalog its tag points at.
+type CatalogChannel struct {
+	Name      string `json:"name"`
+	Digest    string `json:"digest,omitempty"`
+	Reference string `json:"reference,omitempty"`
+
+	// Publish is set on the channel every new catalog is tagged with.
+	Publish bool `json:"publish,omitempty"`
+}
+
+// CatalogPromoteRequest is the JSON body for promoting a catalog to a
+// channel. Exactly one of From (another channel), Version or Digest must
+// be set.
+type CatalogPromoteRequest struct {
+	From    string `json:"from,omitempty"`
+	Version string `json:"version,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+}
+
+// CatalogRollbackRequest is the JSON body for rolling back the catalog.
+// Exactly one of Version or Digest must be set.
+type CatalogRollbackRequest struct {
+	Version string `json:"version,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+}
+
+// CatalogRollbackResponse is the catalog after a rollback, and the
+// resources re-pushed with its manifests or removed because it doesn't
+// hold them.
+type CatalogRollbackResponse struct {
+	CatalogResponse
+	Restored []string `json:"restored"`
+	Removed  []string `json:"removed"`
+}
+
+// MigrationResponse summarises a schema migration run.
+type MigrationResponse struct {
+	TargetVersion string            `json:"targetVersion"`
+	DryRun        bool              `json:"dryRun,omitempty"`
+	Migrated      []string          `json:"migrated"`
+	Unchanged     int               `json:"unchanged"`
+	Failed        map[string]string `json:"failed,omitempty"`
+}
+
+// FormatMigrationResponse summarises an artifact format migration run.
+type FormatMigrationResponse struct {
+	TargetFormat string            `json:"targetFormat"`
+	DryRun       bool              `json:"dryRun,omitempty"`
+	Migrated     []string          `json:"migrated"`
+	Unchanged    int               `json:"unchanged"`
+	Failed       map[string]string `json:"failed,omitempty"`
+}
+
+// ReencryptionResponse summarises a re-encryption run.
+type ReencryptionResponse struct {
+	KeyID       string            `json:"keyId"`
+	DryRun      bool              `json:"dryRun,omitempty"`
+	Reencrypted []string          `json:"reencrypted"`
+	Unchanged   int               `json:"unchanged"`
+	Failed      map[string]string `json:"failed,omitempty"`
+}
+
+// Kinds of inconsistency reported by fsck.
+const (
+	FsckMissingLatest  = "missing-latest"  // repository has versions but no latest tag
+	FsckOrphanedRepo   = "orphaned-repo"   // repository holds no resource versions
+	FsckUnindexed      = "unindexed"       // resource missing from the index
+	FsckIndexStale     = "index-stale"     // index entry differs from the repository
+	FsckIndexOrphan    = "index-orphan"    // index entry without a repository
+	FsckCatalogMissing = "catalog-missing" // live resource missing from the published catalog
+	FsckCatalogExtra   = "catalog-extra"   // catalog holds a deleted or unknown resource
+)
+
+// FsckIssue is one inconsistency found by fsck.
+type FsckIssue struct {
+	Kind     string `json:"kind"`
+	Resource string `json:"resource"`
+	Detail   string `json:"detail,omitempty"`
+	Fixed    bool   `json:"fixed"`
+}
+
+// FsckResponse summarises a consistency check of the index, the resource
+// repositories and the published catalog.
+type FsckResponse struct {
+	Fix          bool        `json:"fix"`
+	Repositories int         `json:"repositories"`
+	Issues       []FsckIssue `json:"issues"`
+	Warnings     []string    `json:"warnings,omitempty"`
+}
+
+// GitSyncResponse summarises the resources applied from a Git push.
+// Failed maps a file path to why it was not applied.
+type GitSyncResponse struct {
+	Repository string            `json:"repository"`
+	Commit     string            `json:"commit"`
+	Applied    []string          `json:"applied"`
+	Deleted    []string          `json:"deleted"`
+	Failed     map[string]string `json:"failed,omitempty"`
+	Ignored    string            `json:"ignored,omitempty"`
+}
+
+// GitImportRequest is the JSON body for importing resources from a Git
+// repository.
+type GitImportRequest struct {
+	URL string `json:"url"`
+
+	// Ref is a branch, tag or commit; empty means the default branch.
+	Ref string `json:"ref,omitempty"`
+
+	// Path limits the import to a directory of the repository.
+	Path string `json:"path,omitempty"`
+
+	// Namespace is used for resources that do not set one. Defaults to
+	// "default".
+	Namespace string `json:"namespace,omitempty"`
+
+	Auth GitAuth `json:"auth,omitempty"`
+
+	// Overwrite updates resources that already exist instead of skipping
+	// them.
+	Overwrite bool `json:"overwrite,omitempty"`
+
+	// DryRun validates and reports without pushing anything.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// GitAuth authenticates an HTTPS clone with a token, given directly or as
+// the name of an environment variable of the API server.
+type GitAuth struct {
+	Username string `json:"username,omitempty"`
+	Token    string `json:"token,omitempty"`
+	TokenEnv string `json:"tokenEnv,omitempty"`
+}
+
+// GitImportResponse summarises an import. Imported and Skipped hold
+// "namespace/name" keys; Skipped maps them to why they were not written.
+// Failed maps a file path to why it was not imported, and Ignored counts
+// YAML documents that are not resources.
+type GitImportResponse struct {
+	Repository string            `json:"repository"`
+	Commit     string            `json:"commit"`
+	DryRun     bool              `json:"dryRun,omitempty"`
+	Imported   []string          `json:"imported"`
+	Skipped    map[string]string `json:"skipped,omitempty"`
+	Failed     map[string]string `json:"failed,omitempty"`
+	Ignored    int               `json:"ignored"`
+}
+
+// MaintenanceRequest is the JSON body for switching read-only mode.
+type MaintenanceRequest struct {
+	ReadOnly bool   `json:"readOnly"`
+	Message  string `json:"message,omitempty"`
+}
+
+// MaintenanceStatus reports whether the API is read-only.
+type MaintenanceStatus struct {
+	ReadOnly bool   `json:"readOnly"`
+	Message  string `json:"message,omitempty"`
+	Since    string `json:"since,omitempty"`
+}
+
+// SyncResponse reports what a sync picked up from other replicas.
+type SyncResponse struct {
+	// Resources lists the resources reloaded from the registry.
+	Resources []string `json:"resources"`
+
+	// Catalog is the digest of the catalog adopted as current, if another
+	// replica published one.
+	Catalog string `json:"catalog,omitempty"`
+}
+
+// CostEstimate is an estimated monthly cost.
+type CostEstimate struct {
+	Monthly  float64 `json:"monthly"`
+	Currency string  `json:"currency"`
+}
+
+// ResourceCost is one resource's estimate in a namespace cost report.
+type ResourceCost struct {
+	Name     string  `json:"name"`
+	Monthly  float64 `json:"monthly"`
+	Currency string  `json:"currency"`
+}
+
+// NamespaceCostsResponse aggregates the estimated cost of a namespace's
+// live resources. Totals are per currency; resources without an estimate
+// are listed in Unestimated.
+type NamespaceCostsResponse struct {
+	Namespace   string             `json:"namespace"`
+	Totals      map[string]float64 `json:"totals"`
+	Resources   []ResourceCost     `json:"resources"`
+	Unestimated []string           `json:"unestimated,omitempty"`
+}
+
+// PeriodCount is a count for one time bucket, e.g. "2026-10-16".
+type PeriodCount struct {
+	Period string `json:"period"`
+	Count  int    `json:"count"`
+}
+
+// CatalogStats describes how often the catalog is published.
+type CatalogStats struct {
+	Publishes int           `json:"publishes"`
+	First     string        `json:"first,omitempty"`
+	Last      string        `json:"last,omitempty"`
+	PerDay    float64       `json:"perDay"`
+	ByPeriod  []PeriodCount `json:"byPeriod"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// StatsResponse summarises resources for capacity planning.
+type StatsResponse struct {
+	Resources   int            `json:"resources"`
+	Deleted     int            `json:"deleted"`
+	ByType      map[string]int `json:"byType"`
+	BySize      map[string]int `json:"bySize"`
+	ByRegion    map[string]int `json:"byRegion"`
+	ByNamespace map[string]int `json:"byNamespace"`
+	Bucket      string         `json:"bucket"`
+	Created     []PeriodCount  `json:"created"`
+	Catalog     CatalogStats   `json:"catalog"`
+}
+
+// QuarantinedArtifact is a resource whose latest artifact failed
+// verification (digest, size or signature) and was not loaded.
+type QuarantinedArtifact struct {
+	Namespace     string `json:"namespace"`
+	Name          string `json:"name"`
+	Digest        string `json:"digest,omitempty"`
+	Reason        string `json:"reason"`
+	QuarantinedAt string `json:"quarantinedAt"`
+}
+
+// UnmanagedArtifact is the latest artifact of a resource repository that
+// was pushed by another tool rather than through the API.
+type UnmanagedArtifact struct {
+	Namespace    string `json:"namespace"`
+	Name         string `json:"name"`
+	Digest       string `json:"digest"`
+	ArtifactType string `json:"artifactType,omitempty"`
+	DetectedAt   string `json:"detectedAt"`
+
+	// Error is why the last attempt to adopt it failed, if adoption is on.
+	Error string `json:"error,omitempty"`
+}
+
+// RegistryStatusResponse reports storage backend health and capabilities.
+type RegistryStatusResponse struct {
+	Host            string `json:"host"`
+	Backend         string `json:"backend,omitempty"`
+	Reachable       bool   `json:"reachable"`
+	Error           string `json:"error,omitempty"`
+	APIVersion      string `json:"apiVersion,omitempty"`
+	ReferrersAPI    *bool  `json:"referrersAPI,omitempty"`
+	RepositoryCount int    `json:"repositoryCount"`
+	LastPush        string `json:"lastPush,omitempty"`
+	LastPull        string `json:"lastPull,omitempty"`
+	ArtifactFormat  string `json:"artifactFormat"`
+	EncryptionKey   string `json:"encryptionKey,omitempty"`
+}
+
+// PlatformResource is the Kubernetes CRD representation.
+type PlatformResource struct {
+	APIVersion string                   `json:"apiVersion"`
+	Kind       string                   `json:"kind"`
+	Metadata   PlatformResourceMetadata `json:"metadata"`
+	Spec       ResourceSpec             `json:"spec"`
+}
+
+// PlatformResourceMetadata holds Kubernetes object metadata fields.
+type PlatformResourceMetadata struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+var validSizes = map[string]bool{"small": true, "medium": true, "large": true}
+
+// sizeNames lists the valid sizes for error messages.
+var sizeNames = []string{"small", "medium", "large"}
+
+// Validate checks the resource request for required fields and valid values.
+// It reports every problem at once as a *ValidationError.
+func (r *ResourceRequest) Validate() error {
+	var e ValidationError
+	e.checkName("name", r.Name)
+	if t, ok := LookupResourceType(r.Spec.Type); ok {
+		t.validateParameters(&e, r.Spec.Parameters)
+	} else {
+		e.add("spec.type", CodeInvalidType, Params{"value": r.Spec.Type, "allowed": typeNames()})
+	}
+	if !validSizes[r.Spec.Size] {
+		e.add("spec.size", CodeInvalidSize, Params{"value": r.Spec.Size, "allowed": sizeNames})
+	}
+	e.checkRemoved(r.Spec)
+	if r.Spec.Region != "" && (len(r.Spec.Region) > MaxNameLength || !dnsLabel.MatchString(r.Spec.Region)) {
+		e.add("spec.region", CodeInvalidRegion, Params{"value": r.Spec.Region})
+	}
+	e.checkReplicas("spec.replicas", r.Spec.Type, r.Spec.Replicas)
+	for k, v := range r.Labels {
+		checkLabel(&e, k, v)
+	}
+	if r.TTL != "" && r.ExpiresAt != "" {
+		e.add("ttl", CodeMutuallyExclusive, Params{"first": "ttl", "second": "expiresAt"})
+	}
+	if r.TTL != "" {
+		if d, err := time.ParseDuration(r.TTL); err != nil || d <= 0 {
+			e.add("ttl", CodeInvalidDuration, Params{"field": "ttl", "value": r.TTL, "example": "72h"})
+		}
+	}
+	if r.ExpiresAt != "" {
+		if t, err := time.Parse(time.RFC3339, r.ExpiresAt); err != nil {
+			e.add("expiresAt", CodeInvalidTimestamp, Params{"field": "expiresAt", "value": r.ExpiresAt})
+		} else if !t.After(time.Now()) {
+			e.add("expiresAt", CodeInPast, Params{"field": "expiresAt", "value": r.ExpiresAt})
+		}
+	}
+	seen := make(map[string]bool, len(r.Secrets))
+	for i := range r.Secrets {
+		field := fmt.Sprintf("secrets[%d]", i)
+		r.Secrets[i].validate(&e, field)
+		if seen[r.Secrets[i].Name] {
+			e.add(field+".name", CodeDuplicate, Params{"item": "secret", "value": r.Secrets[i].Name})
+		}
+		seen[r.Secrets[i].Name] = true
+	}
+	return e.orNil()
+}
+
+// Expiry returns when the request asks the resource to expire, relative to
+// now for a TTL. ok is false if it sets neither TTL nor ExpiresAt. Call
+// Validate first.
+func (r *ResourceRequest) Expiry(now time.Time) (expiresAt time.Time, ok bool) {
+	if r.TTL != "" {
+		d, _ := time.ParseDuration(r.TTL)
+		return now.Add(d).UTC(), true
+	}
+	if r.ExpiresAt != "" {
+		t, _ := time.Parse(time.RFC3339, r.ExpiresAt)
+		return t.UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// WithOverrides returns a copy of s with the non-zero fields of o applied.
+func (s ResourceSpec) WithOverrides(o ResourceSpec) ResourceSpec {
+	if o.Type != "" {
+		s.Type = o.Type
+	}
+	if o.Size != "" {
+		s.Size = o.Size
+	}
+	if o.Region != "" {
+		s.Region = o.Region
+	}
+	if o.Replicas != 0 {
+		s.Replicas = o.Replicas
+	}
+	if len(o.Parameters) > 0 {
+		params := make(map[string]any, len(s.Parameters)+len(o.Parameters))
+		maps.Copy(params, s.Parameters)
+		maps.Copy(params, o.Parameters)
+		s.Parameters = params
+	}
+	return s
+}
+
+// Equal reports whether s and o are the same spec. Empty and nil
+// parameters are the same.
+func (s ResourceSpec) Equal(o ResourceSpec) bool {
+	if len(s.Parameters) == 0 && len(o.Parameters) == 0 {
+		s.Parameters, o.Parameters = nil, nil
+	}
+	return reflect.DeepEqual(s, o)
+}
+
+// HasPlaintextSecrets reports whether any secret carries plaintext data:
+// data values that are not secret store placeholders.
+func (r *ResourceRequest) HasPlaintextSecrets() bool {
+	for _, s := range r.Secrets {
+		if s.HasPlaintext() {
+			return true
+		}
+	}
+	return false
+}
+
+// Annotation keys rendered into PlatformResource manifests.
+const (
+	AnnotationVersion    = "gitops-squared.io/version"
+	AnnotationPushedAt   = "gitops-squared.io/pushed-at"
+	AnnotationGeneration = "gitops-squared.io/generation"
+)
+
+// ManifestAnnotations are the gitops-squared annotations rendered into a
+// PlatformResource manifest.
+type ManifestAnnotations struct {
+	Version    string
+	PushedAt   time.Time // zero leaves out pushed-at, for reproducible builds
+	Generation int64     // incremented on spec changes; zero leaves it out
+}
+
+// ParseManifestAnnotations reads the gitops-squared annotations of a
+// rendered manifest.
+func ParseManifestAnnotations(annotations map[string]string) ManifestAnnotations {
+	pushedAt, _ := time.Parse(time.RFC3339, annotations[AnnotationPushedAt])
+	generation, _ := strconv.ParseInt(annotations[AnnotationGeneration], 10, 64)
+	return ManifestAnnotations{
+		Version:    annotations[AnnotationVersion],
+		PushedAt:   pushedAt,
+		Generation: generation,
+	}
+}
+
+// WithDefaults returns the spec with defaults applied, as rendered into
+// manifests.
+func (s ResourceSpec) WithDefaults() ResourceSpec {
+	if s.Replicas == 0 {
+		s.Replicas = 1
+	}
+	return s
+}
+
+// ToKubernetesYAML converts a resource request into a PlatformResource CRD YAML.
+func (r *ResourceRequest) ToKubernetesYAML(namespace string, annotations ManifestAnnotations) ([]byte, error) {
+	r.Spec = r.Spec.WithDefaults()
+
+	pr := PlatformResource{
+		APIVersion: GroupName + "/" + CurrentSchemaVersion,
+		Kind:       "PlatformResource",
+		Metadata: PlatformResourceMetadata{
+			Name:      r.Name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				managedByLabel: "gitops-squared",
+			},
+			Annotations: map[string]string{
+				AnnotationVersion: annotations.Version,
+			},
+		},
+		Spec: r.Spec,
+	}
+	for k, v := range r.Labels {
+		if k != managedByLabel {
+			pr.Metadata.Labels[k] = v
+		}
+	}
+	if !annotations.PushedAt.IsZero() {
+		pr.Metadata.Annotations[AnnotationPushedAt] = annotations.PushedAt.UTC().Format(time.RFC3339)
+	}
+	if annotations.Generation > 0 {
+		pr.Metadata.Annotations[AnnotationGeneration] = strconv.FormatInt(annotations.Generation, 10)
+	}
+
+	return yaml.Marshal(pr)
+}
@@ -0,0 +1,14 @@
+package model
+
+// RestoreStatus reports whether the state kept in the registry has been
+// loaded since startup. Until it has, or an operator overrides the wait,
+// the API refuses changes.
+type RestoreStatus struct {
+	Ready      bool     `json:"ready"`
+	Restored   bool     `json:"restored"`
+	Overridden bool     `json:"overridden,omitempty"`
+	Pending    []string `json:"pending,omitempty"`
+	Attempts   int      `json:"attempts"`
+	LastError  string   `json:"lastError,omitempty"`
+	NextRetry  string   `json:"nextRetry,omitempty"`
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/schedule"
+)
+
+// Scheduled actions.
+const (
+	// ScheduleActionScale sets the resource's replicas.
+	ScheduleActionScale = "scale"
+
+	// ScheduleActionDelete deletes the resource and restores it after
+	// Duration: a temporary deletion window.
+	ScheduleActionDelete = "delete"
+)
+
+// ScheduleRequest is the JSON body for scheduling an operation on a
+// resource, e.g. scaling down every weekday evening.
+type ScheduleRequest struct {
+	Name     string `json:"name"`
+	Cron     string `json:"cron"`
+	Action   string `json:"action"`
+	Replicas int    `json:"replicas,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// Schedule is a stored ScheduleRequest and its run state. RestoreAt is set
+// while a deletion window is open.
+type Schedule struct {
+	ScheduleRequest
+	Namespace string    `json:"namespace"`
+	Resource  string    `json:"resource"`
+	NextRun   time.Time `json:"nextRun,omitzero"`
+	LastRun   time.Time `json:"lastRun,omitzero"`
+	LastError string    `json:"lastError,omitempty"`
+	RestoreAt time.Time `json:"restoreAt,omitzero"`
+}
+
+// Validate checks the schedule name, cron expression and action arguments.
+func (r *ScheduleRequest) Validate() error {
+	var e ValidationError
+	e.checkName("name", r.Name)
+	if _, err := schedule.Parse(r.Cron); err != nil {
+		e.add("cron", CodeInvalidCron, Params{"error": err.Error()})
+	}
+	switch r.Action {
+	case ScheduleActionScale:
+		if r.Replicas < DefaultMinReplicas {
+			e.add("replicas", CodeMinimum, Params{"min": DefaultMinReplicas})
+		}
+		if r.Duration != "" {
+			e.add("duration", CodeNotApplicable, Params{"action": "delete"})
+		}
+	case ScheduleActionDelete:
+		if d, err := time.ParseDuration(r.Duration); err != nil || d <= 0 {
+			e.add("duration", CodeInvalidDuration, Params{"field": "duration", "value": r.Duration, "example": "12h"})
+		}
+		if r.Replicas != 0 {
+			e.add("replicas", CodeNotApplicable, Params{"action": "scale"})
+		}
+	default:
+		e.add("action", CodeInvalidAction, Params{"value": r.Action, "allowed": []string{"scale", "delete"}})
+	}
+	return e.orNil()
+}
+
+// DeleteDuration returns how long a delete action keeps the resource
+// deleted. Call Validate first.
+func (r *ScheduleRequest) DeleteDuration() time.Duration {
+	d, _ := time.ParseDuration(r.Duration)
+	return d
+}
+
+// Next returns the first run after t, or the zero time if the cron
+// expression never matches. Call Validate first.
+func (r *ScheduleRequest) Next(t time.Time) time.Time {
+	c, err := schedule.Parse(r.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	return c.Next(t)
+}
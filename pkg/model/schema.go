@@ -0,0 +1,78 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GroupName is the API group of the PlatformResource CRD.
+const GroupName = "gitops-squared.io"
+
+// Schema versions of the PlatformResource spec.
+const (
+	SchemaV1Alpha1 = "v1alpha1"
+	SchemaV1Beta1  = "v1beta1"
+
+	// CurrentSchemaVersion is the version new manifests are rendered with.
+	CurrentSchemaVersion = SchemaV1Beta1
+)
+
+// ParseSchemaVersion accepts "v1beta1" or "gitops-squared.io/v1beta1" and
+// returns the bare version. An empty string means v1alpha1, the original
+// request shape that predates apiVersion.
+func ParseSchemaVersion(apiVersion string) (string, error) {
+	if apiVersion == "" {
+		return SchemaV1Alpha1, nil
+	}
+	version := strings.TrimPrefix(apiVersion, GroupName+"/")
+	switch version {
+	case SchemaV1Alpha1, SchemaV1Beta1:
+		return version, nil
+	}
+	return "", fmt.Errorf("unsupported apiVersion %q: must be %s/%s or %s/%s",
+		apiVersion, GroupName, SchemaV1Alpha1, GroupName, SchemaV1Beta1)
+}
+
+// ConvertSpec converts a spec from one schema version to another.
+func ConvertSpec(spec ResourceSpec, from, to string) (ResourceSpec, error) {
+	if from == to {
+		return spec, nil
+	}
+	switch {
+	case from == SchemaV1Alpha1 && to == SchemaV1Beta1:
+		return convertV1Alpha1ToV1Beta1(spec), nil
+	case from == SchemaV1Beta1 && to == SchemaV1Alpha1:
+		return convertV1Beta1ToV1Alpha1(spec), nil
+	}
+	return spec, fmt.Errorf("no conversion from %s to %s", from, to)
+}
+
+// convertV1Alpha1ToV1Beta1 applies the v1beta1 rules: replicas is always
+// explicit (v1alpha1 left it implied as 1) and regions are lowercase.
+func convertV1Alpha1ToV1Beta1(spec ResourceSpec) ResourceSpec {
+	if spec.Replicas == 0 {
+		spec.Replicas = 1
+	}
+	spec.Region = strings.ToLower(spec.Region)
+	return spec
+}
+
+// convertV1Beta1ToV1Alpha1 is lossless: every v1beta1 spec is a valid v1alpha1 spec.
+func convertV1Beta1ToV1Alpha1(spec ResourceSpec) ResourceSpec {
+	return spec
+}
+
+// ConvertToCurrent converts the request to CurrentSchemaVersion in place.
+func (r *ResourceRequest) ConvertToCurrent() error {
+	from, err := ParseSchemaVersion(r.APIVersion)
+	if err != nil {
+		return err
+	}
+	spec, err := ConvertSpec(r.Spec, from, CurrentSchemaVersion)
+	if err != nil {
+		return err
+	}
+	r.Spec = spec
+	r.APIVersion = GroupName + "/" + CurrentSchemaVersion
+	return nil
+}
@@ -0,0 +1,223 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// SecretSpec attaches secret material to a resource. Exactly one of Data or
+// External must be set. Plaintext Data is only accepted when the server can
+// SOPS-encrypt it, so it never reaches the registry unencrypted. Data values
+// may instead reference a configured secret store with placeholders such as
+// "${vault:databases/app#password}", resolved when the secret is rendered.
+type SecretSpec struct {
+	Name     string             `json:"name"`
+	Data     map[string]string  `json:"data,omitempty"`
+	External *ExternalSecretRef `json:"external,omitempty"`
+}
+
+// ExternalSecretRef points at a key in an External Secrets Operator store.
+type ExternalSecretRef struct {
+	Store     string `json:"store"`
+	StoreKind string `json:"storeKind,omitempty"`
+	Key       string `json:"key"`
+}
+
+// Validate checks the secret for required fields.
+func (s *SecretSpec) Validate() error {
+	var e ValidationError
+	s.validate(&e, "secret")
+	return e.orNil()
+}
+
+func (s *SecretSpec) validate(e *ValidationError, field string) {
+	e.checkName(field+".name", s.Name)
+	if (len(s.Data) == 0) == (s.External == nil) {
+		e.add(field, CodeSecretSource, Params{"name": s.Name})
+	}
+	if s.External != nil {
+		if s.External.Store == "" {
+			e.add(field+".external.store", CodeRequired, nil)
+		}
+		if s.External.Key == "" {
+			e.add(field+".external.key", CodeRequired, nil)
+		}
+		if kind := s.External.StoreKind; kind != "" && kind != "SecretStore" && kind != "ClusterSecretStore" {
+			e.add(field+".external.storeKind", CodeInvalidStoreKind, nil)
+		}
+	}
+	for _, key := range sortedKeys(s.Data) {
+		if rest := secretPlaceholder.ReplaceAllString(s.Data[key], ""); strings.Contains(rest, "${") {
+			e.add(field+".data."+key, CodeMalformedPlaceholder, nil)
+		}
+	}
+	for _, p := range s.Placeholders() {
+		if slices.Contains(strings.Split(p.Path, "/"), "..") {
+			e.add(field+".data", CodePlaceholderTraversal, Params{"value": p.Text})
+		}
+	}
+}
+
+// secretPlaceholder matches "${<store>:<path>#<key>}".
+var secretPlaceholder = regexp.MustCompile(`\$\{([a-z0-9][a-z0-9-]*):([^}#]+)#([^}#]+)\}`)
+
+// SecretPlaceholder references a key of a secret in a configured store.
+type SecretPlaceholder struct {
+	Text  string // as written
+	Store string
+	Path  string
+	Key   string
+}
+
+// Placeholders returns the distinct placeholders in the secret's data, in
+// order of data key.
+func (s *SecretSpec) Placeholders() []SecretPlaceholder {
+	var placeholders []SecretPlaceholder
+	seen := map[string]bool{}
+	for _, key := range sortedKeys(s.Data) {
+		for _, m := range secretPlaceholder.FindAllStringSubmatch(s.Data[key], -1) {
+			if seen[m[0]] {
+				continue
+			}
+			seen[m[0]] = true
+			placeholders = append(placeholders, SecretPlaceholder{Text: m[0], Store: m[1], Path: m[2], Key: m[3]})
+		}
+	}
+	return placeholders
+}
+
+// HasPlaintext reports whether any data value is a literal, without a
+// placeholder.
+func (s *SecretSpec) HasPlaintext() bool {
+	for _, v := range s.Data {
+		if !secretPlaceholder.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand returns a copy of the secret with each placeholder in its data
+// replaced by value(placeholder).
+func (s *SecretSpec) Expand(value func(SecretPlaceholder) string) SecretSpec {
+	expanded := *s
+	expanded.Data = make(map[string]string, len(s.Data))
+	for key, v := range s.Data {
+		expanded.Data[key] = secretPlaceholder.ReplaceAllStringFunc(v, func(text string) string {
+			m := secretPlaceholder.FindStringSubmatch(text)
+			return value(SecretPlaceholder{Text: m[0], Store: m[1], Path: m[2], Key: m[3]})
+		})
+	}
+	return expanded
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// SecretName returns the Kubernetes name of the secret owned by resource.
+func (s *SecretSpec) SecretName(resource string) string {
+	return resource + "-" + s.Name
+}
+
+// ToKubernetesYAML renders the secret as an ExternalSecret, or as a plain
+// Secret for Data. Plain Secrets must be encrypted before leaving the process.
+func (s *SecretSpec) ToKubernetesYAML(namespace, resource string) ([]byte, error) {
+	metadata := s.metadata(namespace, resource)
+	if s.External == nil {
+		return yaml.Marshal(map[string]any{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   metadata,
+			"type":       "Opaque",
+			"stringData": s.Data,
+		})
+	}
+
+	storeKind := s.External.StoreKind
+	if storeKind == "" {
+		storeKind = "ClusterSecretStore"
+	}
+	return yaml.Marshal(map[string]any{
+		"apiVersion": "external-secrets.io/v1beta1",
+		"kind":       "ExternalSecret",
+		"metadata":   metadata,
+		"spec": map[string]any{
+			"refreshInterval": "1h",
+			"secretStoreRef": map[string]any{
+				"name": s.External.Store,
+				"kind": storeKind,
+			},
+			"target": map[string]any{
+				"name": s.SecretName(resource),
+			},
+			"dataFrom": []any{
+				map[string]any{"extract": map[string]any{"key": s.External.Key}},
+			},
+		},
+	})
+}
+
+// ToTemplatedExternalSecretYAML renders a secret whose placeholders all
+// reference one External Secrets Operator store as an ExternalSecret. It
+// fetches each referenced key, remoteKey(path) in the store, and templates
+// the data values from them, so the values only ever exist in the cluster.
+func (s *SecretSpec) ToTemplatedExternalSecretYAML(namespace, resource, store, storeKind string, remoteKey func(path string) string) ([]byte, error) {
+	if storeKind == "" {
+		storeKind = "ClusterSecretStore"
+	}
+	placeholders := s.Placeholders()
+	refs := make([]any, 0, len(placeholders))
+	vars := make(map[string]string, len(placeholders))
+	for i, p := range placeholders {
+		vars[p.Text] = fmt.Sprintf("v%d", i)
+		refs = append(refs, map[string]any{
+			"secretKey": vars[p.Text],
+			"remoteRef": map[string]any{"key": remoteKey(p.Path), "property": p.Key},
+		})
+	}
+	templated := s.Expand(func(p SecretPlaceholder) string {
+		return "{{ ." + vars[p.Text] + " }}"
+	})
+
+	return yaml.Marshal(map[string]any{
+		"apiVersion": "external-secrets.io/v1beta1",
+		"kind":       "ExternalSecret",
+		"metadata":   s.metadata(namespace, resource),
+		"spec": map[string]any{
+			"refreshInterval": "1h",
+			"secretStoreRef": map[string]any{
+				"name": store,
+				"kind": storeKind,
+			},
+			"target": map[string]any{
+				"name": s.SecretName(resource),
+				"template": map[string]any{
+					"engineVersion": "v2",
+					"data":          templated.Data,
+				},
+			},
+			"data": refs,
+		},
+	})
+}
+
+func (s *SecretSpec) metadata(namespace, resource string) PlatformResourceMetadata {
+	return PlatformResourceMetadata{
+		Name:      s.SecretName(resource),
+		Namespace: namespace,
+		Labels: map[string]string{
+			"app.kubernetes.io/managed-by": "gitops-squared",
+			"gitops-squared.io/resource":   resource,
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"sort"
+	"strings"
+)
+
+// ParseLabelSelector parses an equality-based Kubernetes label selector,
+// such as "team=payments,env==prod", into the labels a resource must carry.
+// Set-based requirements and inequality are not supported.
+func ParseLabelSelector(selector string) (map[string]string, error) {
+	var e ValidationError
+	labels := make(map[string]string)
+	for _, req := range strings.Split(selector, ",") {
+		req = strings.TrimSpace(req)
+		if req == "" {
+			continue
+		}
+		if strings.Contains(req, "!=") {
+			e.add("selector", CodeUnsupportedSelector, Params{"value": req})
+			continue
+		}
+		key, value, ok := strings.Cut(req, "=")
+		if !ok {
+			e.add("selector", CodeUnsupportedSelector, Params{"value": req})
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+		if old, dup := labels[key]; dup && old != value {
+			e.add("selector", CodeConflictingSelector, Params{"key": key, "first": old, "second": value})
+			continue
+		}
+		checkLabel(&e, key, value)
+		labels[key] = value
+	}
+	if len(labels) == 0 && len(e.Errors) == 0 {
+		e.add("selector", CodeEmpty, nil)
+	}
+	if err := e.orNil(); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// FormatLabelSelector formats labels as a selector with sorted keys, so
+// equivalent selectors format identically.
+func FormatLabelSelector(labels map[string]string) string {
+	reqs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		reqs = append(reqs, k+"="+v)
+	}
+	sort.Strings(reqs)
+	return strings.Join(reqs, ",")
+}
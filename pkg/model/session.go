@@ -0,0 +1,12 @@
+package model
+
+import "github.com/alfredtm/gitops-squared/pkg/auth"
+
+// SessionResponse is returned when a browser session is created. Requests
+// made with the session cookie must send CSRFToken in the X-CSRF-Token
+// header for anything but reads.
+type SessionResponse struct {
+	Identity  auth.Identity `json:"identity"`
+	CSRFToken string        `json:"csrfToken"`
+	ExpiresAt string        `json:"expiresAt"`
+}
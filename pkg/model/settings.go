@@ -0,0 +1,38 @@
+package model
+
+// Log levels.
+const (
+	LogLevelInfo    = "info"    // everything
+	LogLevelWarning = "warning" // warnings, errors and audit records only
+)
+
+// Settings are the runtime settings adjustable through the admin API.
+// Durations are Go duration strings such as "2s" or "24h".
+type Settings struct {
+	// CatalogPublishDebounce is how long a catalog publish waits so that
+	// further changes go out with it.
+	CatalogPublishDebounce string `json:"catalogPublishDebounce"`
+
+	// DeleteGracePeriod is how long deleted resources stay restorable.
+	DeleteGracePeriod string `json:"deleteGracePeriod"`
+
+	// JobRetention is how many finished background jobs are kept.
+	JobRetention int `json:"jobRetention"`
+
+	ReadOnly        bool   `json:"readOnly"`
+	ReadOnlyMessage string `json:"readOnlyMessage,omitempty"`
+
+	// LogLevel is info or warning.
+	LogLevel string `json:"logLevel"`
+}
+
+// SettingsRequest is the JSON body of PUT /api/v1/admin/settings. Omitted
+// fields keep their current value.
+type SettingsRequest struct {
+	CatalogPublishDebounce *string `json:"catalogPublishDebounce,omitempty"`
+	DeleteGracePeriod      *string `json:"deleteGracePeriod,omitempty"`
+	JobRetention           *int    `json:"jobRetention,omitempty"`
+	ReadOnly               *bool   `json:"readOnly,omitempty"`
+	ReadOnlyMessage        *string `json:"readOnlyMessage,omitempty"`
+	LogLevel               *string `json:"logLevel,omitempty"`
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Template is a reusable resource blueprint defined by platform admins,
+// e.g. "standard-postgres" = database, medium, eu-west-1, 3 replicas.
+// Fields left empty must be supplied when the template is instantiated.
+// Secrets are added to every instance; since templates are stored as they
+// are, their data may only hold secret store placeholders.
+type Template struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Spec        ResourceSpec `json:"spec"`
+	Secrets     []SecretSpec `json:"secrets,omitempty"`
+}
+
+// Validate checks the template name and any spec fields it sets.
+func (t *Template) Validate() error {
+	var e ValidationError
+	e.checkName("name", t.Name)
+	if t.Spec.Type != "" && !knownType(t.Spec.Type) {
+		e.add("spec.type", CodeInvalidType, Params{"value": t.Spec.Type, "allowed": typeNames()})
+	}
+	if t.Spec.Size != "" && !validSizes[t.Spec.Size] {
+		e.add("spec.size", CodeInvalidSize, Params{"value": t.Spec.Size, "allowed": sizeNames})
+	}
+	e.checkRemoved(t.Spec)
+	if t.Spec.Region != "" && (len(t.Spec.Region) > MaxNameLength || !dnsLabel.MatchString(t.Spec.Region)) {
+		e.add("spec.region", CodeInvalidRegion, Params{"value": t.Spec.Region})
+	}
+	if t.Spec.Replicas != 0 {
+		e.checkReplicas("spec.replicas", t.Spec.Type, t.Spec.Replicas)
+	}
+	seen := make(map[string]bool, len(t.Secrets))
+	for i := range t.Secrets {
+		field := fmt.Sprintf("secrets[%d]", i)
+		t.Secrets[i].validate(&e, field)
+		if t.Secrets[i].HasPlaintext() {
+			e.add(field+".data", CodeTemplatePlaintext, nil)
+		}
+		if seen[t.Secrets[i].Name] {
+			e.add(field+".name", CodeDuplicate, Params{"item": "secret", "value": t.Secrets[i].Name})
+		}
+		seen[t.Secrets[i].Name] = true
+	}
+	return e.orNil()
+}
+
+// Instantiate applies a resource request's spec on top of the template and
+// adds the template's secrets the request doesn't define itself.
+func (t *Template) Instantiate(req *ResourceRequest) {
+	req.Spec = t.Spec.WithOverrides(req.Spec)
+	for _, secret := range t.Secrets {
+		if !slices.ContainsFunc(req.Secrets, func(s SecretSpec) bool { return s.Name == secret.Name }) {
+			req.Secrets = append(req.Secrets, secret)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync/atomic"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ResourceType is a kind of resource that can be requested.
+type ResourceType struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// Parameters is the JSON Schema of spec.parameters. A type without one
+	// takes no parameters.
+	Parameters *Schema `json:"parameters,omitempty"`
+
+	// Replicas bounds spec.replicas. Without it, or for the bound it
+	// leaves out, DefaultMinReplicas and DefaultMaxReplicas apply.
+	Replicas *ReplicaBounds `json:"replicas,omitempty"`
+
+	// Deprecated marks the type as deprecated or removed.
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+}
+
+// ReplicaBounds are the fewest and most replicas a resource type allows.
+type ReplicaBounds struct {
+	Min int `json:"min,omitempty"`
+	Max int `json:"max,omitempty"`
+}
+
+// Replica bounds of types that don't configure their own.
+const (
+	DefaultMinReplicas = 1
+	DefaultMaxReplicas = 10
+)
+
+// ReplicaRange returns the fewest and most replicas the type allows.
+func (t ResourceType) ReplicaRange() (min, max int) {
+	min, max = DefaultMinReplicas, DefaultMaxReplicas
+	if t.Replicas != nil {
+		if t.Replicas.Min != 0 {
+			min = t.Replicas.Min
+		}
+		if t.Replicas.Max != 0 {
+			max = t.Replicas.Max
+		}
+	}
+	return min, max
+}
+
+// checkReplicas rejects replicas outside the bounds of resource type t. An
+// unknown or empty type only requires at least one replica.
+func (e *ValidationError) checkReplicas(field, t string, replicas int) {
+	rt, ok := LookupResourceType(t)
+	if !ok {
+		if replicas < DefaultMinReplicas {
+			e.add(field, CodeMinimum, Params{"min": DefaultMinReplicas})
+		}
+		return
+	}
+	min, max := rt.ReplicaRange()
+	if replicas < min || replicas > max {
+		params := Params{"min": min, "max": max, "value": replicas}
+		if rt.Replicas != nil {
+			params["type"] = t
+		}
+		e.add(field, CodeReplicasOutOfRange, params)
+	}
+}
+
+// CheckReplicas returns a *ValidationError for field if replicas are
+// outside the bounds of resource type t.
+func CheckReplicas(field, t string, replicas int) error {
+	var e ValidationError
+	e.checkReplicas(field, t, replicas)
+	return e.orNil()
+}
+
+// builtinTypes are the resource types available without configuration.
+var builtinTypes = []ResourceType{
+	{Name: "vm", Description: "Virtual machine"},
+	{Name: "database", Description: "Managed database"},
+	{Name: "bucket", Description: "Object storage bucket"},
+}
+
+// resourceTypes holds the types in effect, by name.
+var resourceTypes atomic.Pointer[map[string]ResourceType]
+
+func init() {
+	types := make(map[string]ResourceType, len(builtinTypes))
+	for _, t := range builtinTypes {
+		types[t.Name] = t
+	}
+	resourceTypes.Store(&types)
+}
+
+// ResourceTypesConfig adds resource types, or parameter schemas to the
+// built-in ones (vm, database, bucket), and deprecates types and sizes.
+//
+//	types:
+//	  - name: database
+//	    replicas: {min: 1, max: 5}
+//	    parameters:
+//	      type: object
+//	      additionalProperties: false
+//	      properties:
+//	        engine: {type: string, enum: [postgres, mysql]}
+//	        version: {type: string, pattern: "^[0-9]+(\\.[0-9]+)*$"}
+//	      required: [engine]
+//	  - name: queue
+//	    description: Message queue
+//	    parameters:
+//	      type: object
+//	      properties:
+//	        retentionHours: {type: integer, minimum: 1, maximum: 336}
+//	  - name: bucket
+//	    deprecated:
+//	      replacement: objectstore
+//	      message: See https://wiki.example.com/objectstore-migration
+//	sizes:
+//	  - name: small
+//	    deprecated: {removed: true, replacement: medium}
+type ResourceTypesConfig struct {
+	Types []ResourceType `json:"types"`
+	Sizes []Size         `json:"sizes,omitempty"`
+}
+
+// LoadResourceTypesConfig reads a ResourceTypesConfig from a YAML file and
+// checks its schemas.
+func LoadResourceTypesConfig(path string) (*ResourceTypesConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading resource types: %w", err)
+	}
+	var cfg ResourceTypesConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing resource types: %w", err)
+	}
+
+	seen := make(map[string]bool, len(cfg.Types))
+	for i := range cfg.Types {
+		t := &cfg.Types[i]
+		var e ValidationError
+		e.checkName("name", t.Name)
+		if err := e.orNil(); err != nil {
+			return nil, fmt.Errorf("resource type %d: %w", i, err)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("resource type %s: defined twice", t.Name)
+		}
+		seen[t.Name] = true
+		if b := t.Replicas; b != nil {
+			if min, max := t.ReplicaRange(); b.Min < 0 || min < 1 || max < min {
+				return nil, fmt.Errorf("resource type %s: replicas: min must be at least 1 and max at least min", t.Name)
+			}
+		}
+		if t.Parameters == nil {
+			continue
+		}
+		if t.Parameters.Type == "" {
+			t.Parameters.Type = "object"
+		}
+		if t.Parameters.Type != "object" {
+			return nil, fmt.Errorf("resource type %s: parameters must be an object schema", t.Name)
+		}
+		if err := t.Parameters.compile("parameters"); err != nil {
+			return nil, fmt.Errorf("resource type %s: %w", t.Name, err)
+		}
+	}
+	if err := cfg.checkDeprecations(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// SetResourceTypes makes the configured types available next to the
+// built-in ones, replacing built-in types of the same name, and applies
+// the size deprecations. Call it at startup, before other configuration
+// that names types is loaded.
+func SetResourceTypes(cfg *ResourceTypesConfig) {
+	types := make(map[string]ResourceType, len(builtinTypes)+len(cfg.Types))
+	for _, t := range builtinTypes {
+		types[t.Name] = t
+	}
+	for _, t := range cfg.Types {
+		if t.Description == "" {
+			t.Description = types[t.Name].Description
+		}
+		types[t.Name] = t
+	}
+	resourceTypes.Store(&types)
+
+	sizes := make(map[string]*Deprecation, len(cfg.Sizes))
+	for _, s := range cfg.Sizes {
+		sizes[s.Name] = s.Deprecated
+	}
+	sizeDeprecations.Store(&sizes)
+}
+
+// ResourceTypes returns the available resource types, sorted by name.
+func ResourceTypes() []ResourceType {
+	types := *resourceTypes.Load()
+	list := make([]ResourceType, 0, len(types))
+	for _, t := range types {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// LookupResourceType returns the resource type called name.
+func LookupResourceType(name string) (ResourceType, bool) {
+	t, ok := (*resourceTypes.Load())[name]
+	return t, ok
+}
+
+// knownType reports whether name is an available resource type.
+func knownType(name string) bool {
+	_, ok := LookupResourceType(name)
+	return ok
+}
+
+// typeNames lists the available resource types for error messages.
+func typeNames() []string {
+	var names []string
+	for _, t := range ResourceTypes() {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// validateParameters checks spec.parameters against the type's schema.
+// Images returns the container image references among a valid spec's
+// parameters, keyed by field (such as "spec.parameters.image").
+func (s ResourceSpec) Images() map[string]string {
+	t, ok := LookupResourceType(s.Type)
+	if !ok || t.Parameters == nil {
+		return nil
+	}
+	images := make(map[string]string)
+	t.Parameters.images("spec.parameters", map[string]any(s.Parameters), images)
+	return images
+}
+
+func (t ResourceType) validateParameters(e *ValidationError, params map[string]any) {
+	if t.Parameters == nil {
+		if len(params) > 0 {
+			e.add("spec.parameters", CodeNoParameters, Params{"type": t.Name})
+		}
+		return
+	}
+	if params == nil {
+		params = map[string]any{}
+	}
+	t.Parameters.validate(e, "spec.parameters", params)
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MaxNameLength is the longest allowed resource, namespace or secret name.
+// Names end up in label values, which Kubernetes caps at 63 characters.
+const MaxNameLength = 63
+
+// reservedPrefixes may not start a resource or namespace name; they belong
+// to Kubernetes and the reconciler.
+var reservedPrefixes = []string{"kube-", "flux-system"}
+
+// reservedNamespaces may not be used as namespace names; they name the
+// directories the catalog keeps its own files in, next to one directory of
+// resource manifests per namespace.
+var reservedNamespaces = []string{"namespaces", "resources", "shards"}
+
+var dnsLabel = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// labelName and labelValue match Kubernetes label key names (after any
+// prefix) and values.
+var (
+	labelName  = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	labelValue = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+)
+
+// managedByLabel marks objects rendered by gitops-squared. It can't be
+// overridden.
+const managedByLabel = "app.kubernetes.io/managed-by"
+
+// FieldError is a validation failure for a single request field. Message
+// is rendered from the message catalog's template for Code and Params.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Params  Params `json:"params,omitempty"`
+}
+
+// NewFieldError returns the error with code for field.
+func NewFieldError(field, code string, params Params) FieldError {
+	return FieldError{Field: field, Code: code, Message: Message(code, params), Params: params}
+}
+
+// ValidationError collects every FieldError found in a request.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Field+": "+fe.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) add(field, code string, params Params) {
+	e.Errors = append(e.Errors, NewFieldError(field, code, params))
+}
+
+// orNil returns e if it holds any errors, and nil otherwise.
+func (e *ValidationError) orNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// checkName validates a name that becomes part of a Kubernetes object name
+// and of an OCI repository path.
+func (e *ValidationError) checkName(field, name string) {
+	switch {
+	case name == "":
+		e.add(field, CodeRequired, nil)
+	case strings.ContainsAny(name, `/\`) || strings.Contains(name, ".."):
+		e.add(field, CodeNamePathSeparator, Params{"value": name})
+	case len(name) > MaxNameLength:
+		e.add(field, CodeNameTooLong, Params{"value": name, "max": MaxNameLength})
+	case !dnsLabel.MatchString(name):
+		e.add(field, CodeInvalidName, Params{"value": name})
+	default:
+		for _, prefix := range reservedPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				e.add(field, CodeReservedPrefix, Params{"value": name, "prefix": prefix})
+				break
+			}
+		}
+	}
+}
+
+// checkLabel validates a Kubernetes label.
+func checkLabel(e *ValidationError, key, value string) {
+	field := "labels." + key
+	prefix, name, hasPrefix := strings.Cut(key, "/")
+	if !hasPrefix {
+		name, prefix = prefix, ""
+	}
+	switch {
+	case key == managedByLabel:
+		e.add(field, CodeReservedLabel, Params{"key": key})
+	case hasPrefix && (prefix == "" || len(prefix) > 253):
+		e.add(field, CodeInvalidLabelPrefix, Params{"prefix": prefix})
+	case len(name) > MaxNameLength || !labelName.MatchString(name):
+		e.add(field, CodeInvalidLabelName, Params{"name": name})
+	case len(value) > MaxNameLength || !labelValue.MatchString(value):
+		e.add(field, CodeInvalidLabelValue, Params{"value": value})
+	}
+}
+
+// ValidateNamespace checks that namespace is a valid, non-reserved namespace name.
+func ValidateNamespace(namespace string) error {
+	var e ValidationError
+	e.checkNamespace("namespace", namespace)
+	return e.orNil()
+}
+
+// checkNamespace validates a namespace name: a name that is not one of the
+// reserved namespaces.
+func (e *ValidationError) checkNamespace(field, namespace string) {
+	if slices.Contains(reservedNamespaces, namespace) {
+		e.add(field, CodeReservedName, Params{"value": namespace})
+		return
+	}
+	e.checkName(field, namespace)
+}
@@ -0,0 +1,134 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateNamespace(t *testing.T) {
+	tests := []struct {
+		name string
+		code string // "" for valid
+	}{
+		{"default", ""},
+		{"team-a", ""},
+		{"a", ""},
+		{"0ops", ""},
+		{strings.Repeat("a", MaxNameLength), ""},
+		{"", CodeRequired},
+		{strings.Repeat("a", MaxNameLength+1), CodeNameTooLong},
+		{"Team", CodeInvalidName},
+		{"team_a", CodeInvalidName},
+		{"team.a", CodeInvalidName},
+		{"-team", CodeInvalidName},
+		{"team-", CodeInvalidName},
+		{"team a", CodeInvalidName},
+		{"../etc", CodeNamePathSeparator},
+		{"a/b", CodeNamePathSeparator},
+		{`a\b`, CodeNamePathSeparator},
+		{"a..b", CodeNamePathSeparator},
+		{"kube-system", CodeReservedPrefix},
+		{"kube-anything", CodeReservedPrefix},
+		{"flux-system", CodeReservedPrefix},
+		{"flux-systems", CodeReservedPrefix},
+		{"kube", ""},
+		{"flux", ""},
+		{"namespaces", CodeReservedName},
+		{"resources", CodeReservedName},
+		{"shards", CodeReservedName},
+		{"shard", ""},
+	}
+	for _, tt := range tests {
+		err := ValidateNamespace(tt.name)
+		if tt.code == "" {
+			if err != nil {
+				t.Errorf("ValidateNamespace(%q) = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+		var ve *ValidationError
+		if !errors.As(err, &ve) || len(ve.Errors) != 1 {
+			t.Errorf("ValidateNamespace(%q) = %v, want one %s error", tt.name, err, tt.code)
+			continue
+		}
+		if fe := ve.Errors[0]; fe.Field != "namespace" || fe.Code != tt.code || fe.Message == "" {
+			t.Errorf("ValidateNamespace(%q) = %+v, want field namespace, code %s and a message", tt.name, fe, tt.code)
+		}
+	}
+}
+
+func TestResourceRequestValidateFieldErrors(t *testing.T) {
+	req := ResourceRequest{
+		Name: "kube-db",
+		Spec: ResourceSpec{Type: "database", Size: "huge", Region: "US_EAST", Replicas: 1},
+		Labels: map[string]string{
+			"app.kubernetes.io/managed-by": "me",
+			"team":                         "bad value",
+		},
+	}
+	err := req.Validate()
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("Validate = %v, want a *ValidationError", err)
+	}
+
+	got := map[string]FieldError{}
+	for _, fe := range ve.Errors {
+		got[fe.Field] = fe
+	}
+	want := map[string]string{
+		"name":                                CodeReservedPrefix,
+		"spec.size":                           CodeInvalidSize,
+		"spec.region":                         CodeInvalidRegion,
+		"labels.app.kubernetes.io/managed-by": CodeReservedLabel,
+		"labels.team":                         CodeInvalidLabelValue,
+	}
+	for field, code := range want {
+		fe, ok := got[field]
+		if !ok {
+			t.Errorf("no error for %s; got %v", field, ve.Errors)
+			continue
+		}
+		if fe.Code != code {
+			t.Errorf("%s: code %s, want %s", field, fe.Code, code)
+		}
+		if fe.Message == "" || !strings.Contains(ve.Error(), field+": "+fe.Message) {
+			t.Errorf("%s: message %q missing from %q", field, fe.Message, ve.Error())
+		}
+	}
+	if fe := got["name"]; fe.Params["prefix"] != "kube-" || fe.Params["value"] != "kube-db" {
+		t.Errorf("name params = %v, want prefix kube- and value kube-db", fe.Params)
+	}
+	if len(ve.Errors) != len(want) {
+		t.Errorf("got %d errors, want %d: %v", len(ve.Errors), len(want), ve.Errors)
+	}
+}
+
+func TestCheckLabel(t *testing.T) {
+	tests := []struct {
+		key, value string
+		code       string
+	}{
+		{"team", "payments", ""},
+		{"example.com/team", "payments", ""},
+		{"team", "", ""},
+		{"app.kubernetes.io/managed-by", "x", CodeReservedLabel},
+		{"/team", "x", CodeInvalidLabelPrefix},
+		{strings.Repeat("a", 254) + "/team", "x", CodeInvalidLabelPrefix},
+		{"-team", "x", CodeInvalidLabelName},
+		{strings.Repeat("a", MaxNameLength+1), "x", CodeInvalidLabelName},
+		{"team", "-x", CodeInvalidLabelValue},
+		{"team", strings.Repeat("a", MaxNameLength+1), CodeInvalidLabelValue},
+	}
+	for _, tt := range tests {
+		var e ValidationError
+		checkLabel(&e, tt.key, tt.value)
+		switch {
+		case tt.code == "" && len(e.Errors) != 0:
+			t.Errorf("checkLabel(%q, %q) = %v, want no error", tt.key, tt.value, e.Errors)
+		case tt.code != "" && (len(e.Errors) != 1 || e.Errors[0].Code != tt.code):
+			t.Errorf("checkLabel(%q, %q) = %v, want %s", tt.key, tt.value, e.Errors, tt.code)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Package notify sends resource and catalog events to Slack, Microsoft
+// Teams and e-mail, so operators can follow changes without running their
+// own webhook consumer.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/alfredtm/gitops-squared/pkg/model"
+	"sigs.k8s.io/yaml"
+)
+
+// Notifier types.
+const (
+	TypeSlack = "slack"
+	TypeTeams = "teams"
+	TypeEmail = "email"
+)
+
+// defaultTemplate renders an event as one line.
+const defaultTemplate = `[{{.Type}}]{{if .Name}} {{.Namespace}}/{{.Name}}{{end}}{{with .Version}} {{.}}{{end}} by {{.Actor}}{{with .Message}}: {{.}}{{end}}`
+
+// defaultSubject is the subject template of e-mail notifications.
+const defaultSubject = `gitops-squared: {{.Type}}{{if .Name}} {{.Namespace}}/{{.Name}}{{end}}`
+
+// sendTimeout bounds a single delivery.
+const sendTimeout = 10 * time.Second
+
+// Notifier delivers matching events to one destination.
+//
+//	notifiers:
+//	  - name: prod-changes
+//	    type: slack
+//	    urlEnv: SLACK_PROD_WEBHOOK
+//	    namespaces: [prod]
+//	    events: [resource.created, resource.deleted]
+//	    template: ":rocket: {{.Type}} {{.Namespace}}/{{.Name}} by {{.Actor}}"
+//	  - name: on-call
+//	    type: email
+//	    events: [catalog.publish_failed]
+//	    email:
+//	      host: smtp.example.com
+//	      from: gitops@example.com
+//	      to: [oncall@example.com]
+//	      passwordEnv: SMTP_PASSWORD
+type Notifier struct {
+	Name string `json:"name"`
+
+	// Type is slack, teams or email.
+	Type string `json:"type"`
+
+	// URL, or the environment variable URLEnv names, is the incoming
+	// webhook of a slack or teams notifier.
+	URL    string `json:"url,omitempty"`
+	URLEnv string `json:"urlEnv,omitempty"`
+
+	// Namespaces and Events limit the events sent. Empty means all.
+	// Catalog events have no namespace and are only sent if Namespaces is
+	// empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+	Events     []string `json:"events,omitempty"`
+
+	// Template is a Go text/template rendering the message from a
+	// model.Event. Defaults to a one-line summary.
+	Template string `json:"template,omitempty"`
+
+	// Email configures an email notifier.
+	Email *Email `json:"email,omitempty"`
+
+	message *template.Template
+	subject *template.Template
+}
+
+// Email configures delivery through an SMTP server.
+type Email struct {
+	Host string `json:"host"`
+
+	// Port defaults to 587.
+	Port int `json:"port,omitempty"`
+
+	// Username and the password in the environment variable PasswordEnv
+	// authenticate with PLAIN auth, if set.
+	Username    string `json:"username,omitempty"`
+	PasswordEnv string `json:"passwordEnv,omitempty"`
+
+	From string   `json:"from"`
+	To   []string `json:"to"`
+
+	// Subject is a template like Notifier.Template.
+	Subject string `json:"subject,omitempty"`
+
+	password string
+}
+
+// Config lists the configured notifiers.
+type Config struct {
+	Notifiers []Notifier `json:"notifiers"`
+
+	httpClient *http.Client
+	sendMail   func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// LoadConfig reads a Config from a YAML file, parses the templates and
+// resolves URLs and passwords from the environment.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading notifiers: %w", err)
+	}
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing notifiers: %w", err)
+	}
+
+	for i := range cfg.Notifiers {
+		n := &cfg.Notifiers[i]
+		if n.Name == "" {
+			return nil, fmt.Errorf("notifier %d: name is required", i)
+		}
+		if err := n.init(); err != nil {
+			return nil, fmt.Errorf("notifier %s: %w", n.Name, err)
+		}
+	}
+	cfg.httpClient = &http.Client{Timeout: sendTimeout}
+	cfg.sendMail = smtp.SendMail
+	return &cfg, nil
+}
+
+func (n *Notifier) init() error {
+	switch n.Type {
+	case TypeSlack, TypeTeams:
+		if n.URLEnv != "" {
+			n.URL = os.Getenv(n.URLEnv)
+		}
+		if n.URL == "" {
+			return errors.New("url or urlEnv is required")
+		}
+	case TypeEmail:
+		e := n.Email
+		if e == nil || e.Host == "" || e.From == "" || len(e.To) == 0 {
+			return errors.New("email.host, email.from and email.to are required")
+		}
+		if e.Port == 0 {
+			e.Port = 587
+		}
+		if e.PasswordEnv != "" {
+			e.password = os.Getenv(e.PasswordEnv)
+		}
+		subject := e.Subject
+		if subject == "" {
+			subject = defaultSubject
+		}
+		var err error
+		if n.subject, err = template.New("subject").Parse(subject); err != nil {
+			return fmt.Errorf("parsing email.subject: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown type %q (want slack, teams or email)", n.Type)
+	}
+
+	text := n.Template
+	if text == "" {
+		text = defaultTemplate
+	}
+	var err error
+	if n.message, err = template.New("message").Parse(text); err != nil {
+		return fmt.Errorf("parsing template: %w", err)
+	}
+	return nil
+}
+
+// Applies reports whether the notifier sends event e.
+func (n *Notifier) Applies(e model.Event) bool {
+	return (len(n.Namespaces) == 0 || slices.Contains(n.Namespaces, e.Namespace)) &&
+		(len(n.Events) == 0 || slices.Contains(n.Events, e.Type))
+}
+
+// Notify sends e to every notifier it applies to. It returns one error per
+// failed delivery, each naming its notifier.
+func (c *Config) Notify(ctx context.Context, e model.Event) []error {
+	var errs []error
+	for i := range c.Notifiers {
+		n := &c.Notifiers[i]
+		if !n.Applies(e) {
+			continue
+		}
+		if err := c.send(ctx, n, e); err != nil {
+			errs = append(errs, fmt.Errorf("notifier %s: %w", n.Name, err))
+		}
+	}
+	return errs
+}
+
+func (c *Config) send(ctx context.Context, n *Notifier, e model.Event) error {
+	message, err := render(n.message, e)
+	if err != nil {
+		return err
+	}
+	switch n.Type {
+	case TypeSlack, TypeTeams:
+		// Slack and Teams incoming webhooks both accept a plain text
+		// payload.
+		return c.post(ctx, n.URL, map[string]string{"text": message})
+	default:
+		subject, err := render(n.subject, e)
+		if err != nil {
+			return err
+		}
+		return c.mail(n.Email, subject, message)
+	}
+}
+
+func render(t *template.Template, e model.Event) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, e); err != nil {
+		return "", fmt.Errorf("rendering template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func (c *Config) post(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func (c *Config) mail(e *Email, subject, body string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.ReplaceAll(subject, "\n", " "))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		auth = smtp.PlainAuth("", e.Username, e.password, e.Host)
+	}
+	addr := net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+	if err := c.sendMail(addr, auth, e.From, e.To, []byte(msg.String())); err != nil {
+		return fmt.Errorf("sending mail: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// attachmentTagPrefix starts the tag an attachment is pushed under in its
+// resource's repository. Attachments are keyed by resource, not version,
+// so they carry over to every later version.
+const attachmentTagPrefix = "attachment."
+
+// Attachment describes an auxiliary file attached to a resource.
+type Attachment struct {
+	Key       string
+	MediaType string
+	Digest    string
+	Size      int64
+
+	// Resource is the digest of the resource version the file was
+	// attached to; the attachment is an OCI referrer of it.
+	Resource  string
+	CreatedAt string
+}
+
+// PushAttachment attaches data to a resource under key, replacing any
+// attachment with the same key. It fails with errdef.ErrNotFound if the
+// resource has no versions.
+func (c *Client) PushAttachment(ctx context.Context, namespace, name, key, mediaType string, data []byte) (Attachment, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, c.resourceRepoPath(namespace, name))
+	if err != nil {
+		return Attachment{}, err
+	}
+	subject, err := repo.Resolve(ctx, "latest")
+	if err != nil {
+		return Attachment{}, fmt.Errorf("resolving %s/%s: %w", namespace, name, err)
+	}
+
+	layerDesc, err := pushBytes(ctx, repo, mediaType, data)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("pushing attachment layer: %w", err)
+	}
+	layerDesc.Annotations = map[string]string{ocispec.AnnotationTitle: key}
+
+	created := c.createdAnnotation()
+	packOpts := oras.PackManifestOptions{
+		Subject: &subject,
+		Layers:  []ocispec.Descriptor{layerDesc},
+		ManifestAnnotations: map[string]string{
+			ocispec.AnnotationCreated:   created,
+			AnnotationResourceName:      name,
+			AnnotationResourceNamespace: namespace,
+			AnnotationAttachmentKey:     key,
+		},
+	}
+	manifestDesc, err := pushManifest(ctx, repo, ArtifactTypeAttachment, packOpts, attachmentTagPrefix+key)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("pushing attachment to registry: %w", err)
+	}
+	if err := c.signResource(ctx, repo, manifestDesc); err != nil {
+		return Attachment{}, err
+	}
+
+	c.recordPush()
+	return Attachment{
+		Key:       key,
+		MediaType: mediaType,
+		Digest:    string(layerDesc.Digest),
+		Size:      layerDesc.Size,
+		Resource:  string(subject.Digest),
+		CreatedAt: created,
+	}, nil
+}
+
+// PullAttachment returns the content of a resource's attachment. It fails
+// with errdef.ErrNotFound if there is none under key.
+func (c *Client) PullAttachment(ctx context.Context, namespace, name, key string) ([]byte, Attachment, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, c.resourceRepoPath(namespace, name))
+	if err != nil {
+		return nil, Attachment{}, err
+	}
+	manifest, desc, err := c.fetchManifest(ctx, repo, attachmentTagPrefix+key)
+	if err != nil {
+		return nil, Attachment{}, fmt.Errorf("fetching attachment %s: %w", key, err)
+	}
+	attachment, err := attachmentOf(key, manifest)
+	if err != nil {
+		return nil, Attachment{}, err
+	}
+	data, err := readVerified(ctx, repo, manifest.Layers[0])
+	if err != nil {
+		return nil, Attachment{}, err
+	}
+	if err := c.verifySignature(ctx, repo, string(desc.Digest)); err != nil {
+		return nil, Attachment{}, err
+	}
+
+	c.recordPull()
+	return data, attachment, nil
+}
+
+// ListAttachments returns the attachments of a resource, sorted by key.
+func (c *Client) ListAttachments(ctx context.Context, namespace, name string) ([]Attachment, error) {
+	tags, err := c.ResourceTags(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, c.resourceRepoPath(namespace, name))
+	if err != nil {
+		return nil, err
+	}
+	var attachments []Attachment
+	for _, tag := range tags {
+		key, ok := strings.CutPrefix(tag, attachmentTagPrefix)
+		if !ok {
+			continue
+		}
+		manifest, _, err := c.fetchManifest(ctx, repo, tag)
+		if err != nil {
+			if errors.Is(err, errdef.ErrNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("fetching attachment %s: %w", key, err)
+		}
+		attachment, err := attachmentOf(key, manifest)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].Key < attachments[j].Key })
+	return attachments, nil
+}
+
+// attachmentOf describes the attachment stored in manifest.
+func attachmentOf(key string, manifest ocispec.Manifest) (Attachment, error) {
+	if manifest.ArtifactType != ArtifactTypeAttachment || len(manifest.Layers) == 0 {
+		return Attachment{}, fmt.Errorf("tag %s%s is not an attachment", attachmentTagPrefix, key)
+	}
+	layer := manifest.Layers[0]
+	attachment := Attachment{
+		Key:       key,
+		MediaType: layer.MediaType,
+		Digest:    string(layer.Digest),
+		Size:      layer.Size,
+		CreatedAt: manifest.Annotations[ocispec.AnnotationCreated],
+	}
+	if manifest.Subject != nil {
+		attachment.Resource = string(manifest.Subject.Digest)
+	}
+	return attachment, nil
+}
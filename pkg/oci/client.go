@@ -0,0 +1,1444 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	oras "oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// catalogRepoPath is the repository holding the Flux-consumable catalog.
+const catalogRepoPath = "gitops-squared/catalog"
+
+// bundleRepoPrefix is where per-resource Flux bundles are published.
+const bundleRepoPrefix = "gitops-squared/bundles"
+
+// shardRepoPrefix is where catalog shards are published when the catalog
+// is partitioned.
+const shardRepoPrefix = "gitops-squared/catalog-shards"
+
+// clusterRepoPrefix is where the catalogs of individual target clusters
+// are published.
+const clusterRepoPrefix = "gitops-squared/clusters"
+
+// templatesRepoPath holds the resource templates document.
+const templatesRepoPath = "gitops-squared/templates"
+
+// schedulesRepoPath holds the resource schedules document.
+const schedulesRepoPath = "gitops-squared/schedules"
+
+// freezesRepoPath holds the change-freeze windows document.
+const freezesRepoPath = "gitops-squared/freezes"
+
+// locksRepoPath holds the resource locks document.
+const locksRepoPath = "gitops-squared/locks"
+
+// namespacesRepoPath holds the managed namespaces document.
+const namespacesRepoPath = "gitops-squared/namespaces"
+
+// clusterRegistrationsRepoPath holds the cluster registrations document.
+const clusterRegistrationsRepoPath = "gitops-squared/cluster-registrations"
+
+// proposalsRepoPath holds the change proposals document.
+const proposalsRepoPath = "gitops-squared/proposals"
+
+// apiKeysRepoPath holds the API keys document.
+const apiKeysRepoPath = "gitops-squared/apikeys"
+
+// regionsRepoPath holds the region catalog document.
+const regionsRepoPath = "gitops-squared/regions"
+
+// canaryRepoPath holds the hold on canary promotion.
+const canaryRepoPath = "gitops-squared/canary"
+
+// eventsRepoPath holds the recent events document.
+const eventsRepoPath = "gitops-squared/events"
+
+// draftsRepoPath holds the draft manifest of every proposal, tagged with
+// the proposal ID.
+const draftsRepoPath = "gitops-squared/drafts"
+
+// Client wraps oras-go operations against an OCI registry.
+type Client struct {
+	registryHost string
+	repoPrefix   string // e.g. "gitops-squared/resources"
+	storage      Storage
+	versions     VersionGenerator
+	reproducible bool
+	opTimeout    time.Duration
+	spoolSize    int64
+	discovery    RepoDiscovery
+	signer       ArtifactSigner
+	verifier     ArtifactVerifier
+	dataKeys     DataKeyProvider
+	format       ArtifactFormat
+
+	indexMu       sync.Mutex
+	index         map[string]IndexEntry // "namespace/name" -> entry, as last synced
+	indexPending  map[string]IndexEntry // entries not yet pushed
+	indexDigest   string                // digest of the index version last synced
+	indexComplete bool                  // index known to list every resource
+
+	catalogMu       sync.Mutex
+	catalogVersions map[string]CatalogVersion // by version tag, which never moves
+
+	activityMu sync.Mutex
+	lastPush   time.Time
+	lastPull   time.Time
+	pushes     int64
+	pulls      int64
+}
+
+// ResourceInfo holds metadata about a resource artifact in the registry.
+type ResourceInfo struct {
+	Repository string
+	Namespace  string
+	Name       string
+	Digest     string
+	Version    string
+	Deleted    bool
+}
+
+// NewClient creates a new OCI client backed by the registry at registryHost.
+func NewClient(registryHost, repoPrefix string) *Client {
+	return NewClientWithStorage(registryHost, repoPrefix, NewRegistryStorage(registryHost, RegistryOptions{}))
+}
+
+// NewClientWithStorage creates an OCI client that stores artifacts in the
+// given backend. registryHost is still used to build the oci:// URLs handed
+// to Flux.
+func NewClientWithStorage(registryHost, repoPrefix string, storage Storage) *Client {
+	return &Client{
+		registryHost: registryHost,
+		repoPrefix:   repoPrefix,
+		storage:      storage,
+		versions:     &unixVersions{},
+		discovery:    RepoDiscoveryAuto,
+		spoolSize:    DefaultSpoolThreshold,
+		format:       DefaultArtifactFormat,
+	}
+}
+
+// SetVersionGenerator replaces the default v<unix seconds> version tags.
+// Call it before the client is used.
+func (c *Client) SetVersionGenerator(g VersionGenerator) {
+	c.versions = g
+}
+
+// SetOperationTimeout bounds each registry operation (a push, pull, tag or
+// listing), on top of any deadline of the caller's context. Zero means no
+// limit. Call it before the client is used.
+func (c *Client) SetOperationTimeout(d time.Duration) {
+	c.opTimeout = d
+}
+
+// withTimeout derives the context of one registry operation. Cancelling it
+// aborts any copy in flight.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.opTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.opTimeout)
+}
+
+// SetSpoolThreshold sets how much of a built or pulled tarball is buffered
+// in memory before it spills to a temporary file. Zero or less keeps
+// tarballs in memory. Call it before the client is used.
+func (c *Client) SetSpoolThreshold(n int64) {
+	c.spoolSize = n
+}
+
+// NewSpool returns an empty spool with the client's threshold, for building
+// a tarball to push.
+func (c *Client) NewSpool() *Spool {
+	return NewSpool(c.spoolSize)
+}
+
+func (c *Client) newRepo(ctx context.Context, repoPath string) (Repository, error) {
+	return c.storage.Repository(ctx, repoPath)
+}
+
+func (c *Client) resourceRepoPath(namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", c.repoPrefix, namespace, name)
+}
+
+// PushResource pushes a resource manifest as an OCI artifact. Extra
+// annotations are added to the manifest alongside the standard ones.
+// Returns the digest and version tag.
+func (c *Client) PushResource(ctx context.Context, namespace, name string, manifest []byte, annotations map[string]string) (string, string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repoPath := c.resourceRepoPath(namespace, name)
+	repo, err := c.newRepo(ctx, repoPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	version := c.versions.Next()
+	seal, err := c.newSealer(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Each document gets its own layer, so companions that don't change
+	// between versions share blobs (unless encrypted).
+	var layers []ocispec.Descriptor
+	for i, doc := range splitManifest(manifest) {
+		blob, err := seal.seal(doc, layerAAD(repoPath, i))
+		if err != nil {
+			return "", "", err
+		}
+		layerDesc, err := pushBytes(ctx, repo, c.format.layerMediaType(i), blob)
+		if err != nil {
+			return "", "", fmt.Errorf("pushing layer to registry: %w", err)
+		}
+		if i == 0 {
+			layerDesc.Annotations = map[string]string{
+				ocispec.AnnotationTitle:     "platformresource.yaml",
+				AnnotationResourceName:      name,
+				AnnotationResourceNamespace: namespace,
+				AnnotationResourceVersion:   version,
+			}
+		} else {
+			layerDesc.Annotations = map[string]string{
+				ocispec.AnnotationTitle: companionTitle(doc, i),
+			}
+		}
+		layers = append(layers, layerDesc)
+	}
+
+	packOpts := oras.PackManifestOptions{
+		Layers: layers,
+		ManifestAnnotations: map[string]string{
+			ocispec.AnnotationCreated:   c.createdAnnotation(),
+			AnnotationResourceName:      name,
+			AnnotationResourceNamespace: namespace,
+		},
+	}
+	for k, v := range annotations {
+		packOpts.ManifestAnnotations[k] = v
+	}
+	seal.annotate(packOpts.ManifestAnnotations)
+	if c.reproducible {
+		stripVolatile(layers[0].Annotations)
+		stripVolatile(packOpts.ManifestAnnotations)
+	} else if err := c.setParent(ctx, repo, packOpts.ManifestAnnotations); err != nil {
+		return "", "", err
+	}
+
+	manifestDesc, err := pushManifest(ctx, repo, c.format.artifactType(), packOpts, version, "latest")
+	if err != nil {
+		return "", "", fmt.Errorf("pushing to registry: %w", err)
+	}
+	if err := c.signResource(ctx, repo, manifestDesc); err != nil {
+		return "", "", err
+	}
+
+	c.recordIndex(ctx, IndexEntry{Namespace: namespace, Name: name, Digest: string(manifestDesc.Digest), Version: version})
+	c.recordPush()
+	return string(manifestDesc.Digest), version, nil
+}
+
+// PushTombstone pushes a deletion marker artifact for a resource.
+// The tombstone layer carries the last manifest so a soft-deleted resource
+// can still be restored after a restart. Extra annotations are added to
+// the manifest.
+func (c *Client) PushTombstone(ctx context.Context, namespace, name string, manifest []byte, annotations map[string]string) (string, string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repoPath := c.resourceRepoPath(namespace, name)
+	repo, err := c.newRepo(ctx, repoPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	version := c.versions.Next()
+
+	seal, err := c.newSealer(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	tombstone := append([]byte(fmt.Sprintf("# deleted: %s/%s\n", namespace, name)), manifest...)
+	blob, err := seal.seal(tombstone, layerAAD(repoPath, 0))
+	if err != nil {
+		return "", "", err
+	}
+	layerDesc, err := pushBytes(ctx, repo, c.format.layerMediaType(0), blob)
+	if err != nil {
+		return "", "", fmt.Errorf("pushing tombstone layer to registry: %w", err)
+	}
+
+	layerDesc.Annotations = map[string]string{
+		AnnotationResourceName:      name,
+		AnnotationResourceNamespace: namespace,
+		AnnotationResourceDeleted:   "true",
+		AnnotationResourceVersion:   version,
+	}
+
+	packOpts := oras.PackManifestOptions{
+		Layers: []ocispec.Descriptor{layerDesc},
+		ManifestAnnotations: map[string]string{
+			ocispec.AnnotationCreated: time.Now().UTC().Format(time.RFC3339),
+			AnnotationResourceDeleted: "true",
+		},
+	}
+	for k, v := range annotations {
+		packOpts.ManifestAnnotations[k] = v
+	}
+	seal.annotate(packOpts.ManifestAnnotations)
+	if err := c.setParent(ctx, repo, packOpts.ManifestAnnotations); err != nil {
+		return "", "", err
+	}
+
+	manifestDesc, err := pushManifest(ctx, repo, c.format.artifactType(), packOpts, version, "latest")
+	if err != nil {
+		return "", "", fmt.Errorf("pushing tombstone to registry: %w", err)
+	}
+	if err := c.signResource(ctx, repo, manifestDesc); err != nil {
+		return "", "", err
+	}
+
+	c.recordIndex(ctx, IndexEntry{Namespace: namespace, Name: name, Digest: string(manifestDesc.Digest), Version: version, Deleted: true})
+	c.recordPush()
+	return string(manifestDesc.Digest), version, nil
+}
+
+// ResourceArtifact is a resource pulled from the registry.
+type ResourceArtifact struct {
+	Manifest    []byte
+	Annotations map[string]string // manifest and layer annotations, merged
+	Digest      string
+	Format      ArtifactFormat
+	KeyID       string // encryption key of the layers, "" if not encrypted
+}
+
+// PullResource pulls the resource YAML and manifest annotations for a given reference (tag or digest).
+func (c *Client) PullResource(ctx context.Context, namespace, name, reference string) (ResourceArtifact, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repoPath := c.resourceRepoPath(namespace, name)
+	repo, err := c.newRepo(ctx, repoPath)
+	if err != nil {
+		return ResourceArtifact{}, err
+	}
+
+	// Fetch and parse the OCI manifest to find layers.
+	manifest, desc, err := c.fetchManifest(ctx, repo, reference)
+	if err != nil {
+		return ResourceArtifact{}, err
+	}
+
+	format, err := resourceFormat(manifest)
+	if err != nil {
+		return ResourceArtifact{}, fmt.Errorf("reading %s: %w", desc.Digest, err)
+	}
+	layers := manifestLayers(manifest, format)
+	if len(layers) == 0 {
+		return ResourceArtifact{}, fmt.Errorf("manifest %s has no layers", desc.Digest)
+	}
+
+	// Pull the manifest documents, checking each layer against its
+	// descriptor and the artifact against its signature. The first layer
+	// is the PlatformResource and carries the layer annotations.
+	open, err := c.newOpener(ctx, manifest.Annotations)
+	if err != nil {
+		return ResourceArtifact{}, fmt.Errorf("reading %s: %w", desc.Digest, err)
+	}
+	docs := make([][]byte, 0, len(layers))
+	for i, layer := range layers {
+		doc, err := readVerified(ctx, repo, layer)
+		if err != nil {
+			return ResourceArtifact{}, err
+		}
+		if doc, err = open.open(doc, layerAAD(repoPath, i)); err != nil {
+			return ResourceArtifact{}, fmt.Errorf("reading %s: %w", desc.Digest, err)
+		}
+		docs = append(docs, doc)
+	}
+	layerDesc := layers[0]
+	layerBytes := joinLayers(docs)
+	if err := c.verifySignature(ctx, repo, string(desc.Digest)); err != nil {
+		return ResourceArtifact{}, err
+	}
+
+	// Merge manifest and layer annotations.
+	annotations := make(map[string]string)
+	for k, v := range manifest.Annotations {
+		annotations[k] = v
+	}
+	for k, v := range layerDesc.Annotations {
+		annotations[k] = v
+	}
+	// The data key belongs to this artifact only.
+	delete(annotations, AnnotationEncryptionKeyID)
+	delete(annotations, AnnotationEncryptionDataKey)
+
+	// Reproducible artifacts record neither a creation time nor a version.
+	if annotations[ocispec.AnnotationCreated] == reproducibleEpoch {
+		delete(annotations, ocispec.AnnotationCreated)
+	}
+	if annotations[AnnotationResourceVersion] == "" {
+		version, err := c.versionOf(ctx, repo, reference, desc)
+		if err != nil {
+			return ResourceArtifact{}, err
+		}
+		annotations[AnnotationResourceVersion] = version
+	}
+
+	c.versions.Observe(annotations[AnnotationResourceVersion])
+	c.recordPull()
+	return ResourceArtifact{
+		Manifest:    layerBytes,
+		Annotations: annotations,
+		Digest:      string(desc.Digest),
+		Format:      format,
+		KeyID:       manifest.Annotations[AnnotationEncryptionKeyID],
+	}, nil
+}
+
+// setParent points a new resource version at the repository's current
+// "latest", if any.
+func (c *Client) setParent(ctx context.Context, repo Repository, annotations map[string]string) error {
+	desc, err := repo.Resolve(ctx, "latest")
+	if err != nil {
+		if errors.Is(err, errdef.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("resolving parent version: %w", err)
+	}
+	annotations[AnnotationResourceParent] = string(desc.Digest)
+	return nil
+}
+
+// maxHistoryDepth bounds how far ResourceHistory walks a parent chain.
+const maxHistoryDepth = 1000
+
+// ResourceVersion is one link in a resource's version chain.
+type ResourceVersion struct {
+	Version   string
+	Digest    string
+	Parent    string
+	CreatedAt string
+	Deleted   bool
+
+	// ChangedBy, Source and UserAgent say who pushed the version, through
+	// which channel and with which client. Versions pushed before they
+	// were recorded leave them empty.
+	ChangedBy string
+	Source    string
+	UserAgent string
+}
+
+// ResourceHistory walks a resource's version chain from "latest" through
+// the parent annotations, newest first. Every manifest is fetched by digest
+// and verified against it, so an intact chain proves the history has not
+// been rewritten. A broken link (missing parent, or a version belonging to
+// another resource) ends the walk with a non-nil error alongside the
+// versions read so far. Versions pushed before parents were recorded end
+// the chain early without an error.
+func (c *Client) ResourceHistory(ctx context.Context, namespace, name string) ([]ResourceVersion, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, c.resourceRepoPath(namespace, name))
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []ResourceVersion
+	ref := "latest"
+	for len(versions) < maxHistoryDepth {
+		manifest, desc, err := c.fetchManifest(ctx, repo, ref)
+		if err != nil {
+			if ref == "latest" {
+				return nil, err
+			}
+			return versions, fmt.Errorf("broken chain at %s: %w", ref, err)
+		}
+
+		if n := manifest.Annotations[AnnotationResourceName]; n != "" && n != name {
+			return versions, fmt.Errorf("broken chain at %s: belongs to resource %q", desc.Digest, n)
+		}
+
+		v := ResourceVersion{
+			Digest:    string(desc.Digest),
+			Parent:    manifest.Annotations[AnnotationResourceParent],
+			CreatedAt: createdAt(manifest.Annotations),
+			Deleted:   manifest.Annotations[AnnotationResourceDeleted] == "true",
+			ChangedBy: manifest.Annotations[AnnotationResourceChangedBy],
+			Source:    manifest.Annotations[AnnotationResourceChangeSource],
+			UserAgent: manifest.Annotations[AnnotationResourceUserAgent],
+		}
+		if len(manifest.Layers) > 0 {
+			v.Version = manifest.Layers[0].Annotations[AnnotationResourceVersion]
+		}
+		if v.Version == "" {
+			if v.Version, err = c.versionOf(ctx, repo, ref, desc); err != nil {
+				return versions, err
+			}
+		}
+		versions = append(versions, v)
+
+		if v.Parent == "" {
+			break
+		}
+		ref = v.Parent
+	}
+
+	c.recordPull()
+	return versions, nil
+}
+
+// ResourceHead is the current "latest" of a resource repository.
+type ResourceHead struct {
+	Digest  string
+	Version string
+	Deleted bool
+	Format  ArtifactFormat // zero if unsupported
+	Type    string         // artifact type of the manifest
+	KeyID   string         // encryption key, "" if not encrypted
+}
+
+// HeadResource resolves a resource's "latest" tag without pulling its
+// layer. ok is false if the repository has no "latest".
+func (c *Client) HeadResource(ctx context.Context, namespace, name string) (head ResourceHead, ok bool, err error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, c.resourceRepoPath(namespace, name))
+	if err != nil {
+		return ResourceHead{}, false, err
+	}
+
+	manifest, desc, err := c.fetchManifest(ctx, repo, "latest")
+	if err != nil {
+		if errors.Is(err, errdef.ErrNotFound) {
+			return ResourceHead{}, false, nil
+		}
+		return ResourceHead{}, false, err
+	}
+
+	// The version is recorded on the layer, the deleted marker on both.
+	head = ResourceHead{
+		Digest:  string(desc.Digest),
+		Deleted: manifest.Annotations[AnnotationResourceDeleted] == "true",
+		KeyID:   manifest.Annotations[AnnotationEncryptionKeyID],
+		Type:    manifest.ArtifactType,
+	}
+	head.Format, _ = resourceFormat(manifest)
+	if len(manifest.Layers) > 0 {
+		head.Version = manifest.Layers[0].Annotations[AnnotationResourceVersion]
+	}
+	c.versions.Observe(head.Version)
+	return head, true, nil
+}
+
+// ResourceTags lists the tags of a resource repository, sorted.
+func (c *Client) ResourceTags(ctx context.Context, namespace, name string) ([]string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, c.resourceRepoPath(namespace, name))
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	if err := repo.Tags(ctx, "", func(page []string) error {
+		tags = append(tags, page...)
+		return nil
+	}); err != nil {
+		if errors.Is(err, errdef.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("listing tags: %w", err)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// TagResourceLatest points a resource's "latest" tag at one of its
+// versions.
+func (c *Client) TagResourceLatest(ctx context.Context, namespace, name, version string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, c.resourceRepoPath(namespace, name))
+	if err != nil {
+		return err
+	}
+	desc, err := repo.Resolve(ctx, version)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", version, err)
+	}
+	if err := repo.Tag(ctx, desc, "latest"); err != nil {
+		return fmt.Errorf("tagging latest: %w", err)
+	}
+	c.recordPush()
+	return nil
+}
+
+// ListResourceRepos lists all resource repositories, from the resource
+// index or by listing the registry under the configured prefix, depending
+// on the discovery mode (see RepoDiscovery). Entries from the index carry
+// the digest and version of each resource's latest push.
+func (c *Client) ListResourceRepos(ctx context.Context) ([]ResourceInfo, error) {
+	return c.listResources(ctx)
+}
+
+// ListRegistryRepos lists resource repositories through the registry's
+// catalog API (filtering to only those under the configured prefix,
+// excluding the catalog), whatever the discovery mode.
+func (c *Client) ListRegistryRepos(ctx context.Context) ([]ResourceInfo, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repoNames, err := c.storage.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing repositories: %w", err)
+	}
+
+	var repos []ResourceInfo
+	for _, r := range repoNames {
+		namespace, name, ok := c.parseResourceRepo(r)
+		if !ok {
+			continue
+		}
+		repos = append(repos, ResourceInfo{
+			Repository: r,
+			Namespace:  namespace,
+			Name:       name,
+		})
+	}
+
+	return repos, nil
+}
+
+// parseResourceRepo splits a resource repository path under the prefix into
+// namespace and name.
+func (c *Client) parseResourceRepo(repoPath string) (namespace, name string, ok bool) {
+	suffix, ok := strings.CutPrefix(repoPath, c.repoPrefix+"/")
+	if !ok {
+		return "", "", false
+	}
+	namespace, name, ok = strings.Cut(suffix, "/")
+	return namespace, name, ok
+}
+
+// CatalogReference returns the digest-pinned OCI URL of a catalog artifact.
+func (c *Client) CatalogReference(digest string) string {
+	return fmt.Sprintf("oci://%s/%s@%s", c.registryHost, catalogRepoPath, digest)
+}
+
+// CatalogURL returns the OCI URL Flux uses to pull the catalog.
+func (c *Client) CatalogURL() string {
+	return fmt.Sprintf("oci://%s/%s", c.registryHost, catalogRepoPath)
+}
+
+// PushCatalog pushes a tar.gz catalog artifact for Flux consumption.
+// It is tagged with a timestamped version for history, as latest and with
+// any extra tags. A channel, if set, is recorded as an annotation and
+// tagged too. Returns the digest and version tag.
+func (c *Client) PushCatalog(ctx context.Context, tarGz *Spool, channel string, tags ...string) (string, string, error) {
+	var annotations map[string]string
+	if channel != "" {
+		annotations = map[string]string{AnnotationCatalogChannel: channel}
+		tags = append(tags, channel)
+	}
+	return c.pushFluxArtifact(ctx, catalogRepoPath, tarGz, annotations, tags...)
+}
+
+// PushResourceBundle pushes a Flux-consumable tarball holding a single resource,
+// for clusters that pull each resource through its own OCIRepository.
+func (c *Client) PushResourceBundle(ctx context.Context, namespace, name string, tarGz *Spool) (string, string, error) {
+	return c.pushFluxArtifact(ctx, c.bundleRepoPath(namespace, name), tarGz, nil)
+}
+
+// PullResourceBundle fetches a resource bundle tarball by tag or digest.
+func (c *Client) PullResourceBundle(ctx context.Context, namespace, name, reference string) (string, *Spool, error) {
+	return c.pullFluxArtifact(ctx, c.bundleRepoPath(namespace, name), reference)
+}
+
+// ResourceBundleURL returns the OCI URL Flux uses to pull a resource bundle.
+func (c *Client) ResourceBundleURL(namespace, name string) string {
+	return fmt.Sprintf("oci://%s/%s", c.registryHost, c.bundleRepoPath(namespace, name))
+}
+
+func (c *Client) bundleRepoPath(namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", bundleRepoPrefix, namespace, name)
+}
+
+// PushCatalogShard pushes the Flux-consumable tarball for one catalog shard.
+func (c *Client) PushCatalogShard(ctx context.Context, shard string, tarGz *Spool) (string, string, error) {
+	return c.pushFluxArtifact(ctx, shardRepoPrefix+"/"+shard, tarGz, nil)
+}
+
+// PullCatalogShard fetches a catalog shard tarball by tag or digest.
+func (c *Client) PullCatalogShard(ctx context.Context, shard, reference string) (string, *Spool, error) {
+	return c.pullFluxArtifact(ctx, shardRepoPrefix+"/"+shard, reference)
+}
+
+// CatalogShardURL returns the OCI URL Flux uses to pull a catalog shard.
+func (c *Client) CatalogShardURL(shard string) string {
+	return fmt.Sprintf("oci://%s/%s/%s", c.registryHost, shardRepoPrefix, shard)
+}
+
+// PushClusterCatalog pushes the Flux-consumable catalog of one target
+// cluster.
+func (c *Client) PushClusterCatalog(ctx context.Context, cluster string, tarGz *Spool) (string, string, error) {
+	return c.pushFluxArtifact(ctx, clusterRepoPrefix+"/"+cluster, tarGz, nil)
+}
+
+// ClusterCatalogURL returns the OCI URL a target cluster's Flux pulls its
+// catalog from.
+func (c *Client) ClusterCatalogURL(cluster string) string {
+	return fmt.Sprintf("oci://%s/%s/%s", c.registryHost, clusterRepoPrefix, cluster)
+}
+
+// pushFluxArtifact pushes a tar.gz with Flux's content and config media types,
+// tagged with a timestamped version, as latest and with any extra tags. The
+// content is streamed from the spool straight to the repository.
+func (c *Client) pushFluxArtifact(ctx context.Context, repoPath string, tarGz *Spool, annotations map[string]string, tags ...string) (string, string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, repoPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	version := c.versions.Next()
+
+	layerDesc := ocispec.Descriptor{
+		MediaType: MediaTypeFluxContent,
+		Digest:    tarGz.Digest(),
+		Size:      tarGz.Size(),
+	}
+	if err := pushBlob(ctx, repo, layerDesc, tarGz.Reader()); err != nil {
+		return "", "", fmt.Errorf("pushing content of %s: %w", repoPath, err)
+	}
+
+	// Push an empty config blob with Flux's expected config media type.
+	configDesc, err := pushBytes(ctx, repo, MediaTypeFluxConfig, []byte("{}"))
+	if err != nil {
+		return "", "", fmt.Errorf("pushing config of %s: %w", repoPath, err)
+	}
+
+	manifestAnnotations := map[string]string{ocispec.AnnotationCreated: c.createdAnnotation()}
+	for k, v := range annotations {
+		manifestAnnotations[k] = v
+	}
+	packOpts := oras.PackManifestOptions{
+		Layers:              []ocispec.Descriptor{layerDesc},
+		ConfigDescriptor:    &configDesc,
+		ManifestAnnotations: manifestAnnotations,
+	}
+
+	manifestDesc, err := pushManifest(ctx, repo, MediaTypeFluxConfig, packOpts, append([]string{version, "latest"}, tags...)...)
+	if err != nil {
+		return "", "", fmt.Errorf("pushing %s manifest to registry: %w", repoPath, err)
+	}
+
+	c.recordPush()
+	return string(manifestDesc.Digest), version, nil
+}
+
+// pushBlob pushes a blob unless the repository already has it.
+func pushBlob(ctx context.Context, repo Repository, desc ocispec.Descriptor, r io.Reader) error {
+	exists, err := repo.Exists(ctx, desc)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	if err := repo.Push(ctx, desc, r); err != nil && !errors.Is(err, errdef.ErrAlreadyExists) {
+		return err
+	}
+	return nil
+}
+
+// pushBytes pushes data as a blob straight to the repository, without
+// staging it in a memory store first.
+func pushBytes(ctx context.Context, repo Repository, mediaType string, data []byte) (ocispec.Descriptor, error) {
+	desc := content.NewDescriptorFromBytes(mediaType, data)
+	return desc, pushBlob(ctx, repo, desc, bytes.NewReader(data))
+}
+
+// pushManifest packs a manifest over blobs already in the repository,
+// pushes it and applies tags.
+func pushManifest(ctx context.Context, repo Repository, artifactType string, opts oras.PackManifestOptions, tags ...string) (ocispec.Descriptor, error) {
+	desc, err := oras.PackManifest(ctx, existingBlobs{repo}, oras.PackManifestVersion1_1, artifactType, opts)
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("packing manifest: %w", err)
+	}
+	for _, tag := range tags {
+		if err := repo.Tag(ctx, desc, tag); err != nil {
+			return ocispec.Descriptor{}, fmt.Errorf("tagging %s: %w", tag, err)
+		}
+	}
+	return desc, nil
+}
+
+// existingBlobs treats pushing content the repository already has as
+// success, as identical reproducible manifests are pushed again.
+type existingBlobs struct {
+	Repository
+}
+
+func (r existingBlobs) Push(ctx context.Context, desc ocispec.Descriptor, content io.Reader) error {
+	if err := r.Repository.Push(ctx, desc, content); err != nil && !errors.Is(err, errdef.ErrAlreadyExists) {
+		return err
+	}
+	return nil
+}
+
+// CatalogVersion describes a timestamped catalog artifact in the registry.
+type CatalogVersion struct {
+	Version   string
+	Digest    string
+	CreatedAt string
+}
+
+// ListCatalogVersions lists all timestamped catalog versions, newest first.
+func (c *Client) ListCatalogVersions(ctx context.Context) ([]CatalogVersion, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, catalogRepoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := catalogVersionTags(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Version tags never move, so each manifest is only fetched once.
+	c.catalogMu.Lock()
+	defer c.catalogMu.Unlock()
+	seen := make(map[string]CatalogVersion, len(tags))
+	versions := make([]CatalogVersion, 0, len(tags))
+	for _, tag := range tags {
+		v, ok := c.catalogVersions[tag]
+		if !ok {
+			manifest, desc, err := c.fetchManifest(ctx, repo, tag)
+			if err != nil {
+				return nil, err
+			}
+			v = CatalogVersion{
+				Version:   tag,
+				Digest:    string(desc.Digest),
+				CreatedAt: createdAt(manifest.Annotations),
+			}
+		}
+		seen[tag] = v
+		versions = append(versions, v)
+	}
+	c.catalogVersions = seen
+
+	// Versions created in the same second are ordered by their tags.
+	sort.Slice(versions, func(i, j int) bool {
+		if versions[i].CreatedAt != versions[j].CreatedAt {
+			return versions[i].CreatedAt > versions[j].CreatedAt
+		}
+		return versions[i].Version > versions[j].Version
+	})
+	for _, v := range versions {
+		c.versions.Observe(v.Version)
+	}
+
+	c.recordPull()
+	return versions, nil
+}
+
+// catalogVersionTags lists the version tags of the catalog repository.
+func catalogVersionTags(ctx context.Context, repo Repository) ([]string, error) {
+	var tags []string
+	err := repo.Tags(ctx, "", func(page []string) error {
+		for _, t := range page {
+			if strings.HasPrefix(t, "v") {
+				tags = append(tags, t)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing catalog tags: %w", err)
+	}
+	return tags, nil
+}
+
+// CheckVersionFormat returns an error if the catalog has versions in
+// another format than the client's version generator. Tags of different
+// formats don't sort in the order they were pushed, so history, rollback
+// and reproducible artifacts would pick the wrong latest version.
+func (c *Client) CheckVersionFormat(ctx context.Context) error {
+	want := generatorFormat(c.versions)
+	if want == "" {
+		return nil
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	repo, err := c.newRepo(ctx, catalogRepoPath)
+	if err != nil {
+		return err
+	}
+	tags, err := catalogVersionTags(ctx, repo)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if got := VersionFormat(tag); got != "" && got != want {
+			return fmt.Errorf("catalog version %s is in the %s format, not %s: the version format of an existing registry can't be changed", tag, got, want)
+		}
+	}
+	return nil
+}
+
+// ResolveCatalog returns the digest of the catalog a tag or digest refers
+// to, or "" if there is none.
+func (c *Client) ResolveCatalog(ctx context.Context, reference string) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, catalogRepoPath)
+	if err != nil {
+		return "", err
+	}
+	desc, err := repo.Resolve(ctx, reference)
+	if err != nil {
+		if errors.Is(err, errdef.ErrNotFound) {
+			return "", nil
+		}
+		return "", fmt.Errorf("resolving catalog %s: %w", reference, err)
+	}
+	return string(desc.Digest), nil
+}
+
+// CatalogHead returns the digest of the latest published catalog, or "" if
+// none has been published.
+func (c *Client) CatalogHead(ctx context.Context) (string, error) {
+	return c.resolveDocument(ctx, catalogRepoPath)
+}
+
+// PullCatalog pulls the catalog tarball for a given reference (tag or digest).
+// Returns the manifest digest and the tar.gz content.
+func (c *Client) PullCatalog(ctx context.Context, reference string) (string, *Spool, error) {
+	return c.pullFluxArtifact(ctx, catalogRepoPath, reference)
+}
+
+// pullFluxArtifact fetches the content layer of a Flux artifact by tag or
+// digest into a spool, verifying its digest on the way.
+func (c *Client) pullFluxArtifact(ctx context.Context, repoPath, reference string) (string, *Spool, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, repoPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	manifest, desc, err := c.fetchManifest(ctx, repo, reference)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(manifest.Layers) == 0 {
+		return "", nil, fmt.Errorf("%s@%s has no layers", repoPath, desc.Digest)
+	}
+
+	layerRC, err := repo.Fetch(ctx, manifest.Layers[0])
+	if err != nil {
+		return "", nil, fmt.Errorf("fetching %s layer: %w", repoPath, err)
+	}
+	defer layerRC.Close()
+
+	tarGz := c.NewSpool()
+	vr := content.NewVerifyReader(layerRC, manifest.Layers[0])
+	if _, err := io.Copy(tarGz, vr); err != nil {
+		tarGz.Close()
+		return "", nil, fmt.Errorf("reading %s layer: %w", repoPath, err)
+	}
+	if err := vr.Verify(); err != nil {
+		tarGz.Close()
+		return "", nil, fmt.Errorf("verifying %s layer: %w", repoPath, err)
+	}
+
+	c.recordPull()
+	return string(desc.Digest), tarGz, nil
+}
+
+// TagCatalog points the given tag at an existing catalog digest.
+func (c *Client) TagCatalog(ctx context.Context, digest, tag string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, catalogRepoPath)
+	if err != nil {
+		return err
+	}
+
+	desc, err := repo.Resolve(ctx, digest)
+	if err != nil {
+		return fmt.Errorf("resolving catalog %s: %w", digest, err)
+	}
+
+	if err := repo.Tag(ctx, desc, tag); err != nil {
+		return fmt.Errorf("tagging catalog %s: %w", tag, err)
+	}
+	c.recordPush()
+	return nil
+}
+
+func (c *Client) fetchManifest(ctx context.Context, repo Repository, reference string) (ocispec.Manifest, ocispec.Descriptor, error) {
+	var manifest ocispec.Manifest
+
+	desc, err := repo.Resolve(ctx, reference)
+	if err != nil {
+		return manifest, desc, fmt.Errorf("resolving manifest %s: %w", reference, err)
+	}
+
+	manifestBytes, err := content.FetchAll(ctx, repo, desc)
+	if err != nil {
+		if isVerificationError(err) {
+			return manifest, desc, integrityError(desc, err)
+		}
+		return manifest, desc, fmt.Errorf("fetching manifest %s: %w", reference, err)
+	}
+
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return manifest, desc, fmt.Errorf("parsing manifest: %w", err)
+	}
+	return manifest, desc, nil
+}
+
+// PushCatalogSignature attaches a signature to a catalog manifest as an OCI
+// referrer. It is also tagged "<alg>-<hex>.sig" for registries without the
+// referrers API.
+func (c *Client) PushCatalogSignature(ctx context.Context, digest, algorithm, keyID string, signature []byte) error {
+	annotations := map[string]string{AnnotationSignatureAlgorithm: algorithm, AnnotationSignatureKeyID: keyID}
+	if err := c.pushCatalogReferrer(ctx, digest, ArtifactTypeSignature, MediaTypeSignature, ".sig", signature, annotations); err != nil {
+		return fmt.Errorf("pushing signature to registry: %w", err)
+	}
+	return nil
+}
+
+// PushCatalogProvenance attaches a provenance document (JSON) to a catalog
+// manifest as an OCI referrer. It is also tagged "<alg>-<hex>.provenance"
+// for registries without the referrers API.
+func (c *Client) PushCatalogProvenance(ctx context.Context, digest string, data []byte) error {
+	if err := c.pushCatalogReferrer(ctx, digest, ArtifactTypeProvenance, MediaTypeProvenance, ".provenance", data, nil); err != nil {
+		return fmt.Errorf("pushing provenance to registry: %w", err)
+	}
+	return nil
+}
+
+// pushCatalogReferrer pushes a single-layer artifact whose subject is the
+// catalog manifest at digest, tagged after the digest with suffix.
+func (c *Client) pushCatalogReferrer(ctx context.Context, digest, artifactType, mediaType, suffix string, data []byte, annotations map[string]string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, catalogRepoPath)
+	if err != nil {
+		return err
+	}
+
+	subject, err := repo.Resolve(ctx, digest)
+	if err != nil {
+		return fmt.Errorf("resolving catalog %s: %w", digest, err)
+	}
+
+	if err := pushReferrer(ctx, repo, subject, artifactType, mediaType, suffix, data, annotations); err != nil {
+		return err
+	}
+	c.recordPush()
+	return nil
+}
+
+// pushReferrer pushes a single-layer artifact whose subject is the manifest
+// subject, tagged after the subject's digest with suffix for registries
+// without the referrers API.
+func pushReferrer(ctx context.Context, repo Repository, subject ocispec.Descriptor, artifactType, mediaType, suffix string, data []byte, annotations map[string]string) error {
+	layerDesc, err := pushBytes(ctx, repo, mediaType, data)
+	if err != nil {
+		return fmt.Errorf("pushing layer: %w", err)
+	}
+
+	manifestAnnotations := map[string]string{
+		ocispec.AnnotationCreated: time.Now().UTC().Format(time.RFC3339),
+	}
+	maps.Copy(manifestAnnotations, annotations)
+	packOpts := oras.PackManifestOptions{
+		Subject:             &subject,
+		Layers:              []ocispec.Descriptor{layerDesc},
+		ManifestAnnotations: manifestAnnotations,
+	}
+
+	tag := strings.Replace(string(subject.Digest), ":", "-", 1) + suffix
+	_, err = pushManifest(ctx, repo, artifactType, packOpts, tag)
+	return err
+}
+
+// PullCatalogProvenance returns the provenance document of the catalog at
+// reference (a version tag or digest) and the catalog's digest. The
+// document is nil if the catalog has none, such as catalogs published
+// before provenance was recorded.
+func (c *Client) PullCatalogProvenance(ctx context.Context, reference string) ([]byte, string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, catalogRepoPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	catalog, err := repo.Resolve(ctx, reference)
+	if err != nil {
+		return nil, "", fmt.Errorf("resolving catalog %s: %w", reference, err)
+	}
+
+	tag := strings.Replace(string(catalog.Digest), ":", "-", 1) + ".provenance"
+	manifest, _, err := c.fetchManifest(ctx, repo, tag)
+	if err != nil {
+		if errors.Is(err, errdef.ErrNotFound) {
+			return nil, string(catalog.Digest), nil
+		}
+		return nil, "", err
+	}
+	if len(manifest.Layers) == 0 {
+		return nil, "", fmt.Errorf("provenance of catalog %s has no layers", catalog.Digest)
+	}
+
+	data, err := content.FetchAll(ctx, repo, manifest.Layers[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("fetching provenance layer: %w", err)
+	}
+
+	c.recordPull()
+	return data, string(catalog.Digest), nil
+}
+
+// ResourceReference returns the digest-pinned OCI URL of a resource
+// artifact.
+func (c *Client) ResourceReference(namespace, name, digest string) string {
+	return fmt.Sprintf("oci://%s/%s@%s", c.registryHost, c.resourceRepoPath(namespace, name), digest)
+}
+
+// PushTemplates stores the resource templates document (JSON) as a new
+// version and tags it latest.
+func (c *Client) PushTemplates(ctx context.Context, data []byte) error {
+	return c.pushDocument(ctx, templatesRepoPath, ArtifactTypeTemplates, MediaTypeTemplates, data)
+}
+
+// PullTemplates returns the latest templates document, or nil if none has
+// been pushed yet.
+func (c *Client) PullTemplates(ctx context.Context) ([]byte, error) {
+	return c.pullDocument(ctx, templatesRepoPath)
+}
+
+// PushSchedules stores the resource schedules document (JSON) as a new
+// version and tags it latest.
+func (c *Client) PushSchedules(ctx context.Context, data []byte) error {
+	return c.pushDocument(ctx, schedulesRepoPath, ArtifactTypeSchedules, MediaTypeSchedules, data)
+}
+
+// PullSchedules returns the latest schedules document, or nil if none has
+// been pushed yet.
+func (c *Client) PullSchedules(ctx context.Context) ([]byte, error) {
+	return c.pullDocument(ctx, schedulesRepoPath)
+}
+
+// PushFreezes stores the change-freeze windows document (JSON) as a new
+// version and tags it latest.
+func (c *Client) PushFreezes(ctx context.Context, data []byte) error {
+	return c.pushDocument(ctx, freezesRepoPath, ArtifactTypeFreezes, MediaTypeFreezes, data)
+}
+
+// PullFreezes returns the latest change-freeze windows document, or nil if
+// none has been pushed yet.
+func (c *Client) PullFreezes(ctx context.Context) ([]byte, error) {
+	return c.pullDocument(ctx, freezesRepoPath)
+}
+
+// PushLocks stores the resource locks document (JSON) as a new version and
+// tags it latest.
+func (c *Client) PushLocks(ctx context.Context, data []byte) error {
+	return c.pushDocument(ctx, locksRepoPath, ArtifactTypeLocks, MediaTypeLocks, data)
+}
+
+// PullLocks returns the latest resource locks document, or nil if none has
+// been pushed yet.
+func (c *Client) PullLocks(ctx context.Context) ([]byte, error) {
+	return c.pullDocument(ctx, locksRepoPath)
+}
+
+// PushNamespaces stores the managed namespaces document (JSON) as a new
+// version and tags it latest.
+func (c *Client) PushNamespaces(ctx context.Context, data []byte) error {
+	return c.pushDocument(ctx, namespacesRepoPath, ArtifactTypeNamespaces, MediaTypeNamespaces, data)
+}
+
+// PullNamespaces returns the latest managed namespaces document, or nil if
+// none has been pushed yet.
+func (c *Client) PullNamespaces(ctx context.Context) ([]byte, error) {
+	return c.pullDocument(ctx, namespacesRepoPath)
+}
+
+// PushClusterRegistrations stores the cluster registrations document (JSON)
+// as a new version and tags it latest.
+func (c *Client) PushClusterRegistrations(ctx context.Context, data []byte) error {
+	return c.pushDocument(ctx, clusterRegistrationsRepoPath, ArtifactTypeClusters, MediaTypeClusters, data)
+}
+
+// PullClusterRegistrations returns the latest cluster registrations
+// document, or nil if none has been pushed yet.
+func (c *Client) PullClusterRegistrations(ctx context.Context) ([]byte, error) {
+	return c.pullDocument(ctx, clusterRegistrationsRepoPath)
+}
+
+// PushProposals stores the change proposals document (JSON) as a new
+// version and tags it latest.
+func (c *Client) PushProposals(ctx context.Context, data []byte) error {
+	return c.pushDocument(ctx, proposalsRepoPath, ArtifactTypeProposals, MediaTypeProposals, data)
+}
+
+// PullProposals returns the latest change proposals document, or nil if
+// none has been pushed yet.
+func (c *Client) PullProposals(ctx context.Context) ([]byte, error) {
+	return c.pullDocument(ctx, proposalsRepoPath)
+}
+
+// PushAPIKeys stores the API keys document (JSON) as a new version and
+// tags it latest.
+func (c *Client) PushAPIKeys(ctx context.Context, data []byte) error {
+	return c.pushDocument(ctx, apiKeysRepoPath, ArtifactTypeAPIKeys, MediaTypeAPIKeys, data)
+}
+
+// PullAPIKeys returns the latest API keys document, or nil if none has been
+// pushed yet.
+func (c *Client) PullAPIKeys(ctx context.Context) ([]byte, error) {
+	return c.pullDocument(ctx, apiKeysRepoPath)
+}
+
+// PushRegions stores the region catalog document (JSON) as a new version
+// and tags it latest.
+func (c *Client) PushRegions(ctx context.Context, data []byte) error {
+	return c.pushDocument(ctx, regionsRepoPath, ArtifactTypeRegions, MediaTypeRegions, data)
+}
+
+// PullRegions returns the latest region catalog document, or nil if none
+// has been pushed yet.
+func (c *Client) PullRegions(ctx context.Context) ([]byte, error) {
+	return c.pullDocument(ctx, regionsRepoPath)
+}
+
+// PushCanaryHold stores the canary hold document (JSON) as a new version
+// and tags it latest.
+func (c *Client) PushCanaryHold(ctx context.Context, data []byte) error {
+	return c.pushDocument(ctx, canaryRepoPath, ArtifactTypeCanaryHold, MediaTypeCanaryHold, data)
+}
+
+// PullCanaryHold returns the latest canary hold document, or nil if none
+// has been pushed yet.
+func (c *Client) PullCanaryHold(ctx context.Context) ([]byte, error) {
+	return c.pullDocument(ctx, canaryRepoPath)
+}
+
+// PushEvents stores the recent events document (JSON) as a new version
+// and tags it latest.
+func (c *Client) PushEvents(ctx context.Context, data []byte) error {
+	return c.pushDocument(ctx, eventsRepoPath, ArtifactTypeEvents, MediaTypeEvents, data)
+}
+
+// PullEvents returns the latest recent events document, or nil if none
+// has been pushed yet.
+func (c *Client) PullEvents(ctx context.Context) ([]byte, error) {
+	return c.pullDocument(ctx, eventsRepoPath)
+}
+
+// PushDraft stores a proposal's draft manifest, tagged with the proposal
+// ID, and returns its digest.
+func (c *Client) PushDraft(ctx context.Context, id, namespace, name string, manifest []byte) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, draftsRepoPath)
+	if err != nil {
+		return "", err
+	}
+	seal, err := c.newSealer(ctx)
+	if err != nil {
+		return "", err
+	}
+	blob, err := seal.seal(manifest, layerAAD(draftsRepoPath+":"+id, 0))
+	if err != nil {
+		return "", err
+	}
+	layerDesc, err := pushBytes(ctx, repo, MediaTypeResourceYAML, blob)
+	if err != nil {
+		return "", fmt.Errorf("pushing draft layer to registry: %w", err)
+	}
+	packOpts := oras.PackManifestOptions{
+		Layers: []ocispec.Descriptor{layerDesc},
+		ManifestAnnotations: map[string]string{
+			ocispec.AnnotationCreated:   c.createdAnnotation(),
+			AnnotationResourceName:      name,
+			AnnotationResourceNamespace: namespace,
+		},
+	}
+	seal.annotate(packOpts.ManifestAnnotations)
+	manifestDesc, err := pushManifest(ctx, repo, ArtifactTypeDraft, packOpts, id)
+	if err != nil {
+		return "", fmt.Errorf("pushing draft to registry: %w", err)
+	}
+
+	c.recordPush()
+	return string(manifestDesc.Digest), nil
+}
+
+// PullDraft returns the draft manifest of a proposal.
+func (c *Client) PullDraft(ctx context.Context, id string) ([]byte, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, draftsRepoPath)
+	if err != nil {
+		return nil, err
+	}
+	manifest, desc, err := c.fetchManifest(ctx, repo, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching draft %s: %w", id, err)
+	}
+	if len(manifest.Layers) == 0 {
+		return nil, fmt.Errorf("draft manifest %s has no layers", desc.Digest)
+	}
+	data, err := content.FetchAll(ctx, repo, manifest.Layers[0])
+	if err != nil {
+		return nil, fmt.Errorf("fetching draft layer: %w", err)
+	}
+	open, err := c.newOpener(ctx, manifest.Annotations)
+	if err != nil {
+		return nil, fmt.Errorf("reading draft %s: %w", id, err)
+	}
+	if data, err = open.open(data, layerAAD(draftsRepoPath+":"+id, 0)); err != nil {
+		return nil, fmt.Errorf("reading draft %s: %w", id, err)
+	}
+
+	c.recordPull()
+	return data, nil
+}
+
+// pushDocument stores a single-layer document as a new version of repoPath
+// and tags it latest.
+func (c *Client) pushDocument(ctx context.Context, repoPath, artifactType, mediaType string, data []byte) error {
+	_, err := c.pushDocumentDigest(ctx, repoPath, artifactType, mediaType, data)
+	return err
+}
+
+// pushDocumentDigest is pushDocument, returning the digest of the new
+// version.
+func (c *Client) pushDocumentDigest(ctx context.Context, repoPath, artifactType, mediaType string, data []byte) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	version := c.versions.Next()
+
+	layerDesc, err := pushBytes(ctx, repo, mediaType, data)
+	if err != nil {
+		return "", fmt.Errorf("pushing document layer to registry: %w", err)
+	}
+
+	packOpts := oras.PackManifestOptions{
+		Layers: []ocispec.Descriptor{layerDesc},
+		ManifestAnnotations: map[string]string{
+			ocispec.AnnotationCreated: c.createdAnnotation(),
+		},
+	}
+	manifestDesc, err := pushManifest(ctx, repo, artifactType, packOpts, version, "latest")
+	if err != nil {
+		return "", fmt.Errorf("pushing to registry: %w", err)
+	}
+
+	c.recordPush()
+	return string(manifestDesc.Digest), nil
+}
+
+// pullDocument returns the latest document pushed to repoPath, or nil if
+// none has been pushed yet.
+func (c *Client) pullDocument(ctx context.Context, repoPath string) ([]byte, error) {
+	data, _, err := c.pullDocumentDigest(ctx, repoPath)
+	return data, err
+}
+
+// pullDocumentDigest is pullDocument, also returning the digest of the
+// version pulled.
+func (c *Client) pullDocumentDigest(ctx context.Context, repoPath string) ([]byte, string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, repoPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	manifest, desc, err := c.fetchManifest(ctx, repo, "latest")
+	if err != nil {
+		if errors.Is(err, errdef.ErrNotFound) {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+	if len(manifest.Layers) == 0 {
+		return nil, "", fmt.Errorf("document manifest %s has no layers", desc.Digest)
+	}
+
+	data, err := content.FetchAll(ctx, repo, manifest.Layers[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("fetching document layer: %w", err)
+	}
+
+	c.recordPull()
+	return data, string(desc.Digest), nil
+}
+
+// resolveDocument returns the digest of the latest version of a document,
+// or "" if none has been pushed yet.
+func (c *Client) resolveDocument(ctx context.Context, repoPath string) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, repoPath)
+	if err != nil {
+		return "", err
+	}
+	desc, err := repo.Resolve(ctx, "latest")
+	if err != nil {
+		if errors.Is(err, errdef.ErrNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(desc.Digest), nil
+}
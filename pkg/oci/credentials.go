@@ -0,0 +1,137 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// refreshMargin is how long before expiry a cached token is replaced, so
+// requests in flight never carry a token that is about to lapse.
+const refreshMargin = 5 * time.Minute
+
+// CredentialProvider exchanges an ambient identity for registry
+// credentials. It returns the credential and when it expires.
+type CredentialProvider interface {
+	Credential(ctx context.Context) (auth.Credential, time.Time, error)
+}
+
+// ExecProvider runs a command that prints a registry password or token on
+// stdout, e.g. `aws ecr get-login-password`. The token is assumed valid
+// for TTL.
+type ExecProvider struct {
+	Command  []string
+	Username string
+	TTL      time.Duration
+}
+
+// Credential runs the command and returns its output as the password.
+func (p *ExecProvider) Credential(ctx context.Context) (auth.Credential, time.Time, error) {
+	if len(p.Command) == 0 {
+		return auth.EmptyCredential, time.Time{}, fmt.Errorf("no credential command configured")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return auth.EmptyCredential, time.Time{}, fmt.Errorf("running %s: %w: %s", p.Command[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return auth.EmptyCredential, time.Time{}, fmt.Errorf("%s printed no token", p.Command[0])
+	}
+	return auth.Credential{Username: p.Username, Password: token}, time.Now().Add(p.TTL), nil
+}
+
+// NewCloudProvider returns a provider for a managed registry using the
+// cloud's CLI and the identity it is configured with (instance profile,
+// workload identity, managed identity, ...):
+//
+//	ecr  aws ecr get-login-password           (12h tokens)
+//	gcr  gcloud auth print-access-token       (1h tokens; also Artifact Registry)
+//	acr  az acr login --expose-token          (3h tokens)
+func NewCloudProvider(kind, registryHost string) (CredentialProvider, error) {
+	host, _, _ := strings.Cut(registryHost, ":")
+	switch kind {
+	case "ecr":
+		// <account>.dkr.ecr.<region>.amazonaws.com
+		parts := strings.Split(host, ".")
+		if len(parts) < 6 || parts[1] != "dkr" || parts[2] != "ecr" {
+			return nil, fmt.Errorf("%q is not an ECR registry host", registryHost)
+		}
+		return &ExecProvider{
+			Command:  []string{"aws", "ecr", "get-login-password", "--region", parts[3]},
+			Username: "AWS",
+			TTL:      12 * time.Hour,
+		}, nil
+	case "gcr":
+		return &ExecProvider{
+			Command:  []string{"gcloud", "auth", "print-access-token"},
+			Username: "oauth2accesstoken",
+			TTL:      time.Hour,
+		}, nil
+	case "acr":
+		name, _, _ := strings.Cut(host, ".")
+		return &ExecProvider{
+			Command:  []string{"az", "acr", "login", "--name", name, "--expose-token", "--output", "tsv", "--query", "accessToken"},
+			Username: "00000000-0000-0000-0000-000000000000",
+			TTL:      3 * time.Hour,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown credential provider %q (want ecr, gcr or acr)", kind)
+	}
+}
+
+// refreshingCredential caches a provider's credential and fetches a new one
+// shortly before it expires. oras retries a 401 by asking for credentials
+// again, so a token revoked early is also replaced on the next request.
+type refreshingCredential struct {
+	host     string
+	provider CredentialProvider
+
+	mu        sync.Mutex
+	cred      auth.Credential
+	expiresAt time.Time
+}
+
+func newRefreshingCredential(host string, provider CredentialProvider) *refreshingCredential {
+	return &refreshingCredential{host: host, provider: provider}
+}
+
+// credential implements auth.CredentialFunc. Other hosts (e.g. a token
+// service on a different domain) get no credentials.
+func (rc *refreshingCredential) credential(ctx context.Context, hostport string) (auth.Credential, error) {
+	if hostport != rc.host {
+		return auth.EmptyCredential, nil
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if !rc.expiresAt.IsZero() && time.Until(rc.expiresAt) > refreshMargin {
+		return rc.cred, nil
+	}
+
+	cred, expiresAt, err := rc.provider.Credential(ctx)
+	if err != nil {
+		if !rc.expiresAt.IsZero() && time.Now().Before(rc.expiresAt) {
+			log.Printf("Warning: refreshing registry credentials failed, using cached token: %v", err)
+			return rc.cred, nil
+		}
+		return auth.EmptyCredential, fmt.Errorf("fetching registry credentials: %w", err)
+	}
+	log.Printf("Refreshed registry credentials for %s (expires %s)", rc.host, expiresAt.UTC().Format(time.RFC3339))
+
+	rc.cred, rc.expiresAt = cred, expiresAt
+	return cred, nil
+}
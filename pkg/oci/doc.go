@@ -0,0 +1,7 @@
+// Package oci stores resources, catalogs and the API's own state as OCI
+// artifacts. Client implements the artifact layout on top of a Storage,
+// which is either a registry (NewRegistryStorage) or OCI image layouts on
+// disk (NewLayoutStorage); package ocitest provides an in-memory one for
+// tests. Interface is the subset of Client the API calls, so callers can
+// wrap or fake it.
+package oci
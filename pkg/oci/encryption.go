@@ -0,0 +1,158 @@
+package oci
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrNoDataKeys is returned when pulling an encrypted artifact with a
+// client that has no DataKeyProvider.
+var ErrNoDataKeys = errors.New("artifact is encrypted but no encryption keys are configured")
+
+// DataKeyProvider supplies the keys of encrypted resource artifacts. Each
+// artifact's layers are sealed with a fresh data key (envelope
+// encryption); the artifact stores that key wrapped by a key-encryption
+// key, which never leaves the provider. It is satisfied by
+// *encryption.Keyring.
+type DataKeyProvider interface {
+	// DataKey returns a new 256-bit data key, the same key wrapped by the
+	// current key-encryption key, and that key's ID.
+	DataKey(ctx context.Context) (key, wrapped []byte, keyID string, err error)
+
+	// UnwrapDataKey recovers a data key wrapped by the key keyID, which
+	// need not be the current one.
+	UnwrapDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+
+	// KeyID returns the ID of the current key-encryption key.
+	KeyID() string
+}
+
+// SetDataKeyProvider encrypts the layers of every resource artifact,
+// tombstone and proposal draft pushed from now on, and decrypts them on
+// pull. Artifacts pushed without encryption stay readable. Call it before
+// the client is used.
+func (c *Client) SetDataKeyProvider(p DataKeyProvider) {
+	c.dataKeys = p
+}
+
+// EncryptionKeyID returns the ID of the key new artifacts are encrypted
+// with, or "" if encryption is off.
+func (c *Client) EncryptionKeyID() string {
+	if c.dataKeys == nil {
+		return ""
+	}
+	return c.dataKeys.KeyID()
+}
+
+// sealer encrypts the layers of one artifact under one data key.
+type sealer struct {
+	aead    cipher.AEAD
+	keyID   string
+	wrapped []byte
+}
+
+// newSealer returns a sealer with a fresh data key, or nil if encryption
+// is off.
+func (c *Client) newSealer(ctx context.Context) (*sealer, error) {
+	if c.dataKeys == nil {
+		return nil, nil
+	}
+	key, wrapped, keyID, err := c.dataKeys.DataKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating data key: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &sealer{aead: aead, keyID: keyID, wrapped: wrapped}, nil
+}
+
+// seal encrypts a layer. aad binds it to its place, so a layer can't be
+// moved to another artifact or position. A nil sealer passes data through.
+func (s *sealer) seal(data []byte, aad string) ([]byte, error) {
+	if s == nil {
+		return data, nil
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+	return s.aead.Seal(nonce, nonce, data, []byte(aad)), nil
+}
+
+// annotate records the wrapped data key on an artifact's manifest.
+func (s *sealer) annotate(annotations map[string]string) {
+	if s == nil {
+		return
+	}
+	annotations[AnnotationEncryptionKeyID] = s.keyID
+	annotations[AnnotationEncryptionDataKey] = base64.StdEncoding.EncodeToString(s.wrapped)
+}
+
+// opener decrypts the layers of one artifact.
+type opener struct {
+	aead  cipher.AEAD
+	keyID string
+}
+
+// newOpener returns an opener for an artifact with the given manifest
+// annotations, or nil if the artifact is not encrypted.
+func (c *Client) newOpener(ctx context.Context, annotations map[string]string) (*opener, error) {
+	keyID, ok := annotations[AnnotationEncryptionKeyID]
+	if !ok {
+		return nil, nil
+	}
+	if c.dataKeys == nil {
+		return nil, fmt.Errorf("%w (key %q)", ErrNoDataKeys, keyID)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(annotations[AnnotationEncryptionDataKey])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid data key annotation: %v", ErrIntegrity, err)
+	}
+	key, err := c.dataKeys.UnwrapDataKey(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping data key: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &opener{aead: aead, keyID: keyID}, nil
+}
+
+// open decrypts a layer sealed with the same aad. A nil opener passes data
+// through. Content that fails authentication was tampered with.
+func (o *opener) open(data []byte, aad string) ([]byte, error) {
+	if o == nil {
+		return data, nil
+	}
+	n := o.aead.NonceSize()
+	if len(data) < n {
+		return nil, fmt.Errorf("%w: encrypted layer too short", ErrIntegrity)
+	}
+	plaintext, err := o.aead.Open(nil, data[:n], data[n:], []byte(aad))
+	if err != nil {
+		return nil, fmt.Errorf("%w: decrypting layer: %v", ErrIntegrity, err)
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// layerAAD is the additional data of the i-th layer of an artifact in
+// repoPath.
+func layerAAD(repoPath string, i int) string {
+	return fmt.Sprintf("%s#%d", repoPath, i)
+}
@@ -0,0 +1,86 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// maxForeignLayerSize bounds the layers of an artifact being adopted, which
+// may be anything another tool pushed.
+const maxForeignLayerSize = 1 << 20
+
+// ForeignArtifact is an artifact pushed to a resource repository by another
+// tool, read for adoption.
+type ForeignArtifact struct {
+	Manifest     []byte // the YAML documents of its layers, joined
+	Digest       string
+	ArtifactType string
+}
+
+// PullForeignResource reads the artifact at reference (tag or digest) in a
+// resource repository, whoever pushed it. Artifacts in a format of this
+// client yield their manifest documents; other artifacts, such as files
+// pushed with the oras CLI, yield every layer in order. Layers are checked
+// against their descriptors, but signatures are not verified: an adopted
+// artifact is pushed again, signed, as a new version.
+func (c *Client) PullForeignResource(ctx context.Context, namespace, name, reference string) (ForeignArtifact, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repoPath := c.resourceRepoPath(namespace, name)
+	repo, err := c.newRepo(ctx, repoPath)
+	if err != nil {
+		return ForeignArtifact{}, err
+	}
+
+	manifest, desc, err := c.fetchManifest(ctx, repo, reference)
+	if err != nil {
+		return ForeignArtifact{}, err
+	}
+	if manifest.Annotations[AnnotationResourceDeleted] == "true" {
+		return ForeignArtifact{}, fmt.Errorf("%s is a tombstone", desc.Digest)
+	}
+
+	layers := manifest.Layers
+	format, err := resourceFormat(manifest)
+	if err != nil && !errors.Is(err, ErrUnsupportedFormat) {
+		return ForeignArtifact{}, err
+	}
+	if err == nil {
+		layers = manifestLayers(manifest, format)
+	}
+	if len(layers) == 0 {
+		return ForeignArtifact{}, fmt.Errorf("manifest %s has no layers", desc.Digest)
+	}
+
+	open, err := c.newOpener(ctx, manifest.Annotations)
+	if err != nil {
+		return ForeignArtifact{}, fmt.Errorf("reading %s: %w", desc.Digest, err)
+	}
+	docs := make([][]byte, 0, len(layers))
+	for i, layer := range layers {
+		if layer.Size > maxForeignLayerSize {
+			return ForeignArtifact{}, fmt.Errorf("layer %s exceeds %d bytes", layer.Digest, maxForeignLayerSize)
+		}
+		doc, err := readVerified(ctx, repo, layer)
+		if err != nil {
+			return ForeignArtifact{}, err
+		}
+		if doc, err = open.open(doc, layerAAD(repoPath, i)); err != nil {
+			return ForeignArtifact{}, fmt.Errorf("reading %s: %w", desc.Digest, err)
+		}
+		if len(doc) > 0 && !bytes.HasSuffix(doc, []byte("\n")) {
+			doc = append(doc, '\n')
+		}
+		docs = append(docs, doc)
+	}
+
+	c.recordPull()
+	return ForeignArtifact{
+		Manifest:     joinLayers(docs),
+		Digest:       string(desc.Digest),
+		ArtifactType: manifest.ArtifactType,
+	}, nil
+}
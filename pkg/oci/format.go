@@ -0,0 +1,144 @@
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ErrUnsupportedFormat is returned when a resource artifact was pushed in
+// a format this version can't read, typically by a newer server.
+var ErrUnsupportedFormat = errors.New("unsupported artifact format")
+
+// ArtifactFormat is the layout of resource artifacts. Every format can be
+// read; new versions are written in the client's format.
+type ArtifactFormat int
+
+const (
+	// FormatV1 stores each manifest document in a manifest.v1+yaml layer.
+	// The first layer is the PlatformResource.
+	FormatV1 ArtifactFormat = 1
+
+	// FormatV2 types the layers: the PlatformResource is a resource.v2+yaml
+	// layer and companions are companion.v2+yaml layers. Layers of other
+	// media types are ignored by readers, so later versions can add
+	// metadata layers.
+	FormatV2 ArtifactFormat = 2
+)
+
+// DefaultArtifactFormat is the format written unless SetArtifactFormat is
+// called. It stays at FormatV1 until every supported server reads FormatV2.
+const DefaultArtifactFormat = FormatV1
+
+// ParseArtifactFormat parses "v1" or "v2".
+func ParseArtifactFormat(s string) (ArtifactFormat, error) {
+	switch s {
+	case "v1":
+		return FormatV1, nil
+	case "v2":
+		return FormatV2, nil
+	}
+	return 0, fmt.Errorf("unknown artifact format %q (want v1 or v2)", s)
+}
+
+func (f ArtifactFormat) String() string {
+	return fmt.Sprintf("v%d", int(f))
+}
+
+// artifactType returns the OCI artifact type of resources in format f.
+func (f ArtifactFormat) artifactType() string {
+	if f == FormatV2 {
+		return ArtifactTypeResourceV2
+	}
+	return ArtifactTypeResource
+}
+
+// layerMediaType returns the media type of the i-th manifest document in
+// format f.
+func (f ArtifactFormat) layerMediaType(i int) string {
+	switch {
+	case f != FormatV2:
+		return MediaTypeResourceYAML
+	case i == 0:
+		return MediaTypeResourceV2YAML
+	default:
+		return MediaTypeCompanionV2YAML
+	}
+}
+
+// resourceFormat returns the format of a resource artifact. Artifacts
+// without an artifact type predate it and are FormatV1.
+func resourceFormat(manifest ocispec.Manifest) (ArtifactFormat, error) {
+	switch manifest.ArtifactType {
+	case ArtifactTypeResource, "":
+		return FormatV1, nil
+	case ArtifactTypeResourceV2:
+		return FormatV2, nil
+	}
+	return 0, fmt.Errorf("%w: artifact type %q", ErrUnsupportedFormat, manifest.ArtifactType)
+}
+
+// SetArtifactFormat sets the format new resource versions are written in.
+// Call it before the client is used.
+func (c *Client) SetArtifactFormat(f ArtifactFormat) {
+	c.format = f
+}
+
+// ArtifactFormat returns the format new resource versions are written in.
+func (c *Client) ArtifactFormat() ArtifactFormat {
+	return c.format
+}
+
+// RepackResource pushes the latest version of a resource again in the
+// client's format, as a new version with the same documents and
+// annotations, except that extra annotations are added or replaced. It
+// pushes nothing and returns ok false if the latest version is already in
+// that format or is a tombstone.
+func (c *Client) RepackResource(ctx context.Context, namespace, name string, extra map[string]string) (digest, version string, ok bool, err error) {
+	return c.repackResource(ctx, namespace, name, extra, func(artifact ResourceArtifact) bool {
+		return artifact.Format != c.format
+	})
+}
+
+// ReencryptResource pushes the latest version of a resource again, like
+// RepackResource, if its layers are not encrypted with the current key:
+// after a key rotation, or to encrypt a resource pushed before encryption
+// was turned on. Earlier versions keep their key.
+func (c *Client) ReencryptResource(ctx context.Context, namespace, name string, extra map[string]string) (digest, version string, ok bool, err error) {
+	return c.repackResource(ctx, namespace, name, extra, func(artifact ResourceArtifact) bool {
+		return artifact.KeyID != c.EncryptionKeyID()
+	})
+}
+
+// repackResource pushes the latest version of a resource again if needed
+// says so and it is not a tombstone.
+func (c *Client) repackResource(ctx context.Context, namespace, name string, extra map[string]string, needed func(ResourceArtifact) bool) (digest, version string, ok bool, err error) {
+	artifact, err := c.PullResource(ctx, namespace, name, "latest")
+	if err != nil {
+		return "", "", false, err
+	}
+	if !needed(artifact) || artifact.Annotations[AnnotationResourceDeleted] == "true" {
+		return "", "", false, nil
+	}
+
+	// PushResource sets these itself.
+	annotations := make(map[string]string, len(artifact.Annotations))
+	for k, v := range artifact.Annotations {
+		switch k {
+		case ocispec.AnnotationCreated, ocispec.AnnotationTitle, AnnotationResourceName,
+			AnnotationResourceNamespace, AnnotationResourceVersion, AnnotationResourceParent:
+			continue
+		}
+		annotations[k] = v
+	}
+	for k, v := range extra {
+		annotations[k] = v
+	}
+	digest, version, err = c.PushResource(ctx, namespace, name, artifact.Manifest, annotations)
+	if err != nil {
+		return "", "", false, err
+	}
+	return digest, version, true, nil
+}
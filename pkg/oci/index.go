@@ -0,0 +1,293 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// indexRepoPath holds the resource index: every resource with the digest of
+// its latest version, so Restore doesn't have to enumerate repositories.
+const indexRepoPath = "gitops-squared/index"
+
+// IndexEntry is one resource in the index.
+type IndexEntry struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Digest    string `json:"digest"`
+	Version   string `json:"version,omitempty"`
+	Deleted   bool   `json:"deleted,omitempty"`
+}
+
+// indexDocument is the JSON layer of the index artifact. Complete is set
+// once the index was reconciled against a full repository listing; before
+// that it only holds resources written since it was introduced.
+type indexDocument struct {
+	Complete  bool         `json:"complete"`
+	Resources []IndexEntry `json:"resources"`
+}
+
+// RepoDiscovery selects how ListResourceRepos finds resource repositories.
+type RepoDiscovery string
+
+const (
+	// RepoDiscoveryAuto uses the index once it is complete. Until then it
+	// lists repositories through the registry's catalog API, adds those
+	// missing from the index and marks it complete. If listing fails, the
+	// incomplete index is used.
+	RepoDiscoveryAuto RepoDiscovery = "auto"
+
+	// RepoDiscoveryCatalog only uses the registry's catalog API and doesn't
+	// maintain the index.
+	RepoDiscoveryCatalog RepoDiscovery = "catalog"
+
+	// RepoDiscoveryIndex only uses the index, for registries where the
+	// catalog API is forbidden or incomplete.
+	RepoDiscoveryIndex RepoDiscovery = "index"
+)
+
+// ParseRepoDiscovery parses a REPO_DISCOVERY value. Empty means auto.
+func ParseRepoDiscovery(s string) (RepoDiscovery, error) {
+	switch d := RepoDiscovery(s); d {
+	case "":
+		return RepoDiscoveryAuto, nil
+	case RepoDiscoveryAuto, RepoDiscoveryCatalog, RepoDiscoveryIndex:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown repository discovery %q (want auto, catalog or index)", s)
+}
+
+// SetRepoDiscovery selects how resource repositories are found on restore.
+// Call it before the client is used.
+func (c *Client) SetRepoDiscovery(d RepoDiscovery) {
+	c.discovery = d
+}
+
+// Discovery returns how resource repositories are found on restore.
+func (c *Client) Discovery() RepoDiscovery {
+	return c.discovery
+}
+
+// recordIndex updates a resource's entry in the index after a push. A
+// failed update is logged and retried with the next write, since the
+// resource artifact itself is already stored.
+func (c *Client) recordIndex(ctx context.Context, entry IndexEntry) {
+	if c.discovery == RepoDiscoveryCatalog {
+		return
+	}
+
+	c.indexMu.Lock()
+	defer c.indexMu.Unlock()
+	if c.indexPending == nil {
+		c.indexPending = make(map[string]IndexEntry)
+	}
+	c.indexPending[entry.Namespace+"/"+entry.Name] = entry
+	if err := c.flushIndexLocked(ctx, false); err != nil {
+		log.Printf("Warning: failed to update the resource index, retrying with the next write: %v", err)
+	}
+}
+
+// flushIndexLocked pushes pending entries, and the complete flag if
+// complete is set, as a new index version. If another replica pushed the
+// index since it was last synced, that version is pulled first and the
+// pending entries are applied on top of it. The registry offers no
+// compare-and-swap, so two replicas writing at the same instant can still
+// drop each other's entry; fsck repairs that.
+func (c *Client) flushIndexLocked(ctx context.Context, complete bool) error {
+	head, err := c.resolveDocument(ctx, indexRepoPath)
+	if err != nil {
+		return fmt.Errorf("resolving resource index: %w", err)
+	}
+	if c.index == nil || head != c.indexDigest {
+		if err := c.loadIndexLocked(ctx); err != nil {
+			return err
+		}
+	}
+	if len(c.indexPending) == 0 && (!complete || c.indexComplete) {
+		return nil
+	}
+
+	merged := make(map[string]IndexEntry, len(c.index)+len(c.indexPending))
+	for key, entry := range c.index {
+		merged[key] = entry
+	}
+	for key, entry := range c.indexPending {
+		merged[key] = entry
+	}
+	if err := c.pushIndexLocked(ctx, merged, c.indexComplete || complete); err != nil {
+		return err
+	}
+	c.indexPending = nil
+	return nil
+}
+
+// pushIndexLocked pushes entries as the new index version.
+func (c *Client) pushIndexLocked(ctx context.Context, entries map[string]IndexEntry, complete bool) error {
+	doc := indexDocument{Complete: complete, Resources: []IndexEntry{}}
+	for _, entry := range entries {
+		doc.Resources = append(doc.Resources, entry)
+	}
+	sort.Slice(doc.Resources, func(i, j int) bool {
+		a, b := doc.Resources[i], doc.Resources[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("encoding resource index: %w", err)
+	}
+	digest, err := c.pushDocumentDigest(ctx, indexRepoPath, ArtifactTypeIndex, MediaTypeIndex, data)
+	if err != nil {
+		return fmt.Errorf("pushing resource index: %w", err)
+	}
+
+	c.index = entries
+	c.indexDigest = digest
+	c.indexComplete = complete
+	return nil
+}
+
+// loadIndexLocked replaces the in-memory index with the latest version in
+// the registry.
+func (c *Client) loadIndexLocked(ctx context.Context) error {
+	data, digest, err := c.pullDocumentDigest(ctx, indexRepoPath)
+	if err != nil {
+		return fmt.Errorf("pulling resource index: %w", err)
+	}
+
+	var doc indexDocument
+	if data != nil {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("parsing resource index: %w", err)
+		}
+	}
+	c.index = make(map[string]IndexEntry, len(doc.Resources))
+	for _, entry := range doc.Resources {
+		c.index[entry.Namespace+"/"+entry.Name] = entry
+	}
+	c.indexDigest = digest
+	c.indexComplete = doc.Complete
+	return nil
+}
+
+// IndexHead returns the digest of the latest resource index, or "" if
+// there is none. It changes with every write to any resource.
+func (c *Client) IndexHead(ctx context.Context) (string, error) {
+	return c.resolveDocument(ctx, indexRepoPath)
+}
+
+// Index returns the resources in the index and whether it is complete.
+func (c *Client) Index(ctx context.Context) ([]IndexEntry, bool, error) {
+	c.indexMu.Lock()
+	defer c.indexMu.Unlock()
+	if err := c.loadIndexLocked(ctx); err != nil {
+		return nil, false, err
+	}
+	return c.indexEntriesLocked(), c.indexComplete, nil
+}
+
+func (c *Client) indexEntriesLocked() []IndexEntry {
+	entries := make([]IndexEntry, 0, len(c.index))
+	for _, entry := range c.index {
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// listResources finds resource repositories according to the discovery
+// mode.
+func (c *Client) listResources(ctx context.Context) ([]ResourceInfo, error) {
+	if c.discovery == RepoDiscoveryCatalog {
+		return c.ListRegistryRepos(ctx)
+	}
+
+	c.indexMu.Lock()
+	defer c.indexMu.Unlock()
+	if err := c.loadIndexLocked(ctx); err != nil {
+		return nil, err
+	}
+	if c.discovery == RepoDiscoveryIndex || c.indexComplete {
+		if !c.indexComplete {
+			log.Printf("Warning: the resource index is incomplete; resources pushed before it existed are not restored")
+		}
+		return c.indexResourcesLocked(), nil
+	}
+
+	listed, err := c.ListRegistryRepos(ctx)
+	if err != nil {
+		log.Printf("Warning: listing repositories failed, falling back to the incomplete resource index: %v", err)
+		return c.indexResourcesLocked(), nil
+	}
+
+	// Backfill the index with resources pushed before it existed.
+	if c.indexPending == nil {
+		c.indexPending = make(map[string]IndexEntry)
+	}
+	for _, r := range listed {
+		key := r.Namespace + "/" + r.Name
+		if _, ok := c.index[key]; ok {
+			continue
+		}
+		if _, ok := c.indexPending[key]; ok {
+			continue
+		}
+		head, ok, err := c.HeadResource(ctx, r.Namespace, r.Name)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %s: %w", key, err)
+		}
+		if !ok {
+			continue
+		}
+		c.indexPending[key] = IndexEntry{Namespace: r.Namespace, Name: r.Name, Digest: head.Digest, Version: head.Version, Deleted: head.Deleted}
+	}
+	if err := c.flushIndexLocked(ctx, true); err != nil {
+		log.Printf("Warning: failed to backfill the resource index: %v", err)
+		return listed, nil
+	}
+	log.Printf("Backfilled the resource index with %d resources", len(c.index))
+	return c.indexResourcesLocked(), nil
+}
+
+func (c *Client) indexResourcesLocked() []ResourceInfo {
+	repos := make([]ResourceInfo, 0, len(c.index))
+	for _, entry := range c.indexEntriesLocked() {
+		repos = append(repos, ResourceInfo{
+			Repository: c.resourceRepoPath(entry.Namespace, entry.Name),
+			Namespace:  entry.Namespace,
+			Name:       entry.Name,
+			Digest:     entry.Digest,
+			Version:    entry.Version,
+			Deleted:    entry.Deleted,
+		})
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Repository < repos[j].Repository })
+	return repos
+}
+
+// RepairIndex sets and removes index entries, on top of the latest index
+// in the registry, and marks the index complete if complete is set. Remove
+// holds "namespace/name" keys.
+func (c *Client) RepairIndex(ctx context.Context, set []IndexEntry, remove []string, complete bool) error {
+	c.indexMu.Lock()
+	defer c.indexMu.Unlock()
+	if err := c.flushIndexLocked(ctx, false); err != nil {
+		return err
+	}
+
+	entries := make(map[string]IndexEntry, len(c.index)+len(set))
+	for key, entry := range c.index {
+		entries[key] = entry
+	}
+	for _, entry := range set {
+		entries[entry.Namespace+"/"+entry.Name] = entry
+	}
+	for _, key := range remove {
+		delete(entries, key)
+	}
+	return c.pushIndexLocked(ctx, entries, c.indexComplete || complete)
+}
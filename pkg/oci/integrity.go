@@ -0,0 +1,136 @@
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// ErrIntegrity is returned when a pulled artifact doesn't match its
+// descriptors or its signature. Such artifacts are never loaded.
+var ErrIntegrity = errors.New("artifact failed verification")
+
+// ErrVerifierUnavailable is wrapped by verifier errors that say nothing
+// about the signature, such as a key service being unreachable. They fail
+// the pull without marking the artifact as tampered with.
+var ErrVerifierUnavailable = errors.New("signature verification unavailable")
+
+// ArtifactSigner signs artifact digests. It is satisfied by
+// *signing.Signer and *kms.Signer.
+type ArtifactSigner interface {
+	Algorithm() string
+
+	// Sign signs a digest string and returns the signature and the ID of
+	// the key (version) that made it.
+	Sign(ctx context.Context, digest string) (signature []byte, keyID string, err error)
+}
+
+// ArtifactVerifier checks artifact signatures. keyID is the key recorded
+// with the signature, "" for signatures made before key IDs were recorded.
+// It is satisfied by *signing.Verifier and *kms.Verifier.
+type ArtifactVerifier interface {
+	Verify(ctx context.Context, keyID, digest string, signature []byte) error
+}
+
+// SetSigner signs every resource artifact (tombstones included) as it is
+// pushed. The signature is attached as an OCI referrer, also tagged
+// "<alg>-<hex>.sig". Call it before the client is used.
+func (c *Client) SetSigner(s ArtifactSigner) {
+	c.signer = s
+}
+
+// SetVerifier requires every pulled resource artifact to carry a valid
+// signature. Call it before the client is used.
+func (c *Client) SetVerifier(v ArtifactVerifier) {
+	c.verifier = v
+}
+
+// signatureTag is the tag a signature of digest is pushed under.
+func signatureTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1) + ".sig"
+}
+
+// signResource attaches a signature to a freshly pushed resource artifact.
+func (c *Client) signResource(ctx context.Context, repo Repository, subject ocispec.Descriptor) error {
+	if c.signer == nil {
+		return nil
+	}
+	signature, keyID, err := c.signer.Sign(ctx, string(subject.Digest))
+	if err != nil {
+		return fmt.Errorf("signing %s: %w", subject.Digest, err)
+	}
+	annotations := map[string]string{
+		AnnotationSignatureAlgorithm: c.signer.Algorithm(),
+		AnnotationSignatureKeyID:     keyID,
+	}
+	if err := pushReferrer(ctx, repo, subject, ArtifactTypeSignature, MediaTypeSignature, ".sig", signature, annotations); err != nil {
+		return fmt.Errorf("signing %s: %w", subject.Digest, err)
+	}
+	return nil
+}
+
+// verifySignature checks the signature attached to a resource artifact.
+// Missing and invalid signatures are integrity errors.
+func (c *Client) verifySignature(ctx context.Context, repo Repository, digest string) error {
+	if c.verifier == nil {
+		return nil
+	}
+	manifest, _, err := c.fetchManifest(ctx, repo, signatureTag(digest))
+	if errors.Is(err, errdef.ErrNotFound) {
+		return fmt.Errorf("%w: %s is not signed", ErrIntegrity, digest)
+	}
+	if err != nil {
+		return err
+	}
+	if len(manifest.Layers) == 0 {
+		return fmt.Errorf("%w: signature of %s has no layers", ErrIntegrity, digest)
+	}
+	signature, err := readVerified(ctx, repo, manifest.Layers[0])
+	if err != nil {
+		return err
+	}
+	if err := c.verifier.Verify(ctx, manifest.Annotations[AnnotationSignatureKeyID], digest, signature); errors.Is(err, ErrVerifierUnavailable) {
+		return fmt.Errorf("verifying signature of %s: %w", digest, err)
+	} else if err != nil {
+		return fmt.Errorf("%w: signature of %s: %v", ErrIntegrity, digest, err)
+	}
+	return nil
+}
+
+// readVerified fetches a blob, checking its size and digest against desc.
+// A mismatch is an integrity error.
+func readVerified(ctx context.Context, repo Repository, desc ocispec.Descriptor) ([]byte, error) {
+	rc, err := repo.Fetch(ctx, desc)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", desc.Digest, err)
+	}
+	defer rc.Close()
+
+	data, err := content.ReadAll(rc, desc)
+	if err != nil {
+		return nil, integrityError(desc, err)
+	}
+	return data, nil
+}
+
+// integrityError marks errors of oras' content verification as integrity
+// errors.
+func integrityError(desc ocispec.Descriptor, err error) error {
+	if isVerificationError(err) {
+		return fmt.Errorf("%w: %s (%d bytes): %v", ErrIntegrity, desc.Digest, desc.Size, err)
+	}
+	return fmt.Errorf("reading %s: %w", desc.Digest, err)
+}
+
+// isVerificationError reports whether err means content didn't match its
+// descriptor.
+func isVerificationError(err error) bool {
+	return errors.Is(err, content.ErrMismatchedDigest) || errors.Is(err, content.ErrTrailingData) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, content.ErrInvalidDescriptorSize)
+}
@@ -0,0 +1,111 @@
+package oci
+
+import "context"
+
+// Interface is the registry access the API needs: resources, catalogs and
+// the state stored alongside them. *Client implements it; embedders may
+// wrap a Client or substitute a fake. Configuration, such as signing,
+// encryption and version generation, stays on Client.
+type Interface interface {
+	// Spools buffer artifact content.
+	NewSpool() *Spool
+
+	// Resources.
+	PushResource(ctx context.Context, namespace, name string, manifest []byte, annotations map[string]string) (string, string, error)
+	PushTombstone(ctx context.Context, namespace, name string, manifest []byte, annotations map[string]string) (string, string, error)
+	PullResource(ctx context.Context, namespace, name, reference string) (ResourceArtifact, error)
+	ResourceHistory(ctx context.Context, namespace, name string) ([]ResourceVersion, error)
+	HeadResource(ctx context.Context, namespace, name string) (head ResourceHead, ok bool, err error)
+	ResourceTags(ctx context.Context, namespace, name string) ([]string, error)
+	TagResourceLatest(ctx context.Context, namespace, name, version string) error
+	ListResourceRepos(ctx context.Context) ([]ResourceInfo, error)
+	ListRegistryRepos(ctx context.Context) ([]ResourceInfo, error)
+	ResourceReference(namespace, name, digest string) string
+
+	// Per-resource bundles.
+	PushResourceBundle(ctx context.Context, namespace, name string, tarGz *Spool) (string, string, error)
+	PullResourceBundle(ctx context.Context, namespace, name, reference string) (string, *Spool, error)
+	ResourceBundleURL(namespace, name string) string
+
+	// Catalogs, shards and cluster catalogs.
+	CatalogReference(digest string) string
+	CatalogURL() string
+	PushCatalog(ctx context.Context, tarGz *Spool, channel string, tags ...string) (string, string, error)
+	PushCatalogShard(ctx context.Context, shard string, tarGz *Spool) (string, string, error)
+	PullCatalogShard(ctx context.Context, shard, reference string) (string, *Spool, error)
+	CatalogShardURL(shard string) string
+	PushClusterCatalog(ctx context.Context, cluster string, tarGz *Spool) (string, string, error)
+	ClusterCatalogURL(cluster string) string
+	ListCatalogVersions(ctx context.Context) ([]CatalogVersion, error)
+	CheckVersionFormat(ctx context.Context) error
+	ResolveCatalog(ctx context.Context, reference string) (string, error)
+	CatalogHead(ctx context.Context) (string, error)
+	PullCatalog(ctx context.Context, reference string) (string, *Spool, error)
+	TagCatalog(ctx context.Context, digest, tag string) error
+	PushCatalogSignature(ctx context.Context, digest, algorithm, keyID string, signature []byte) error
+	PushCatalogProvenance(ctx context.Context, digest string, data []byte) error
+	PullCatalogProvenance(ctx context.Context, reference string) ([]byte, string, error)
+
+	// State artifacts, as JSON documents.
+	PushTemplates(ctx context.Context, data []byte) error
+	PullTemplates(ctx context.Context) ([]byte, error)
+	PushSchedules(ctx context.Context, data []byte) error
+	PullSchedules(ctx context.Context) ([]byte, error)
+	PushFreezes(ctx context.Context, data []byte) error
+	PullFreezes(ctx context.Context) ([]byte, error)
+	PushLocks(ctx context.Context, data []byte) error
+	PullLocks(ctx context.Context) ([]byte, error)
+	PushNamespaces(ctx context.Context, data []byte) error
+	PullNamespaces(ctx context.Context) ([]byte, error)
+	PushClusterRegistrations(ctx context.Context, data []byte) error
+	PullClusterRegistrations(ctx context.Context) ([]byte, error)
+	PushProposals(ctx context.Context, data []byte) error
+	PullProposals(ctx context.Context) ([]byte, error)
+	PushAPIKeys(ctx context.Context, data []byte) error
+	PullAPIKeys(ctx context.Context) ([]byte, error)
+	PushRegions(ctx context.Context, data []byte) error
+	PullRegions(ctx context.Context) ([]byte, error)
+	PushCanaryHold(ctx context.Context, data []byte) error
+	PullCanaryHold(ctx context.Context) ([]byte, error)
+	PushEvents(ctx context.Context, data []byte) error
+	PullEvents(ctx context.Context) ([]byte, error)
+
+	// Drafts for external editing.
+	PushDraft(ctx context.Context, id, namespace, name string, manifest []byte) (string, error)
+	PullDraft(ctx context.Context, id string) ([]byte, error)
+
+	// Attachments.
+	PushAttachment(ctx context.Context, namespace, name, key, mediaType string, data []byte) (Attachment, error)
+	PullAttachment(ctx context.Context, namespace, name, key string) ([]byte, Attachment, error)
+	ListAttachments(ctx context.Context, namespace, name string) ([]Attachment, error)
+
+	// Artifacts pushed by other tools.
+	PullForeignResource(ctx context.Context, namespace, name, reference string) (ForeignArtifact, error)
+
+	// Artifact formats.
+	ArtifactFormat() ArtifactFormat
+	RepackResource(ctx context.Context, namespace, name string, extra map[string]string) (digest, version string, ok bool, err error)
+	ReencryptResource(ctx context.Context, namespace, name string, extra map[string]string) (digest, version string, ok bool, err error)
+
+	// Encryption at rest.
+	EncryptionKeyID() string
+
+	// The resource index.
+	Discovery() RepoDiscovery
+	IndexHead(ctx context.Context) (string, error)
+	Index(ctx context.Context) ([]IndexEntry, bool, error)
+	RepairIndex(ctx context.Context, set []IndexEntry, remove []string, complete bool) error
+
+	// Jobs.
+	PushJob(ctx context.Context, id string, data []byte) error
+	PullJobs(ctx context.Context, limit int) ([][]byte, error)
+
+	// Registry health.
+	RegistryStatus(ctx context.Context) RegistryStatus
+	Stats() ClientStats
+
+	// Reproducible builds.
+	Reproducible() bool
+}
+
+var _ Interface = (*Client)(nil)
@@ -0,0 +1,89 @@
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// jobsRepoPath holds one artifact per background job, tagged with the job
+// ID. Every state change pushes a new record under the same tag.
+const jobsRepoPath = "gitops-squared/jobs"
+
+// PushJob stores a job record (JSON) tagged with its ID.
+func (c *Client) PushJob(ctx context.Context, id string, data []byte) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, jobsRepoPath)
+	if err != nil {
+		return err
+	}
+	layerDesc, err := pushBytes(ctx, repo, MediaTypeJob, data)
+	if err != nil {
+		return fmt.Errorf("pushing job layer to registry: %w", err)
+	}
+	packOpts := oras.PackManifestOptions{
+		Layers: []ocispec.Descriptor{layerDesc},
+		ManifestAnnotations: map[string]string{
+			ocispec.AnnotationCreated: c.createdAnnotation(),
+		},
+	}
+	if _, err := pushManifest(ctx, repo, ArtifactTypeJob, packOpts, id); err != nil {
+		return fmt.Errorf("pushing job %s to registry: %w", id, err)
+	}
+
+	c.recordPush()
+	return nil
+}
+
+// PullJobs returns the records of the newest limit jobs, oldest first.
+// Job IDs sort in creation order.
+func (c *Client) PullJobs(ctx context.Context, limit int) ([][]byte, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := c.newRepo(ctx, jobsRepoPath)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	if err := repo.Tags(ctx, "", func(page []string) error {
+		ids = append(ids, page...)
+		return nil
+	}); err != nil {
+		if errors.Is(err, errdef.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("listing jobs: %w", err)
+	}
+	sort.Strings(ids)
+	if len(ids) > limit {
+		ids = ids[len(ids)-limit:]
+	}
+
+	records := make([][]byte, 0, len(ids))
+	for _, id := range ids {
+		manifest, desc, err := c.fetchManifest(ctx, repo, id)
+		if err != nil {
+			return nil, fmt.Errorf("fetching job %s: %w", id, err)
+		}
+		if len(manifest.Layers) == 0 {
+			return nil, fmt.Errorf("job manifest %s has no layers", desc.Digest)
+		}
+		data, err := content.FetchAll(ctx, repo, manifest.Layers[0])
+		if err != nil {
+			return nil, fmt.Errorf("fetching job %s: %w", id, err)
+		}
+		records = append(records, data)
+	}
+
+	c.recordPull()
+	return records, nil
+}
@@ -0,0 +1,77 @@
+package oci
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// documentSeparator separates the documents of a multi-document manifest.
+const documentSeparator = "---\n"
+
+// splitManifest splits a multi-document manifest into the layers of a
+// resource artifact, one per document, such that joinLayers restores it
+// byte for byte. A manifest that can't be split that way, such as one with
+// empty documents, is kept as a single layer.
+func splitManifest(manifest []byte) [][]byte {
+	var docs [][]byte
+	rest := manifest
+	for {
+		i := bytes.Index(rest, []byte("\n"+documentSeparator))
+		if i < 0 {
+			break
+		}
+		docs = append(docs, rest[:i+1])
+		rest = rest[i+1+len(documentSeparator):]
+	}
+	docs = append(docs, rest)
+
+	for _, doc := range docs {
+		if len(bytes.TrimSpace(doc)) == 0 {
+			return [][]byte{manifest}
+		}
+	}
+	return docs
+}
+
+// joinLayers is the inverse of splitManifest.
+func joinLayers(layers [][]byte) []byte {
+	return bytes.Join(layers, []byte(documentSeparator))
+}
+
+// companionTitle names the layer of a companion document after its kind
+// and name, e.g. "secret-db-credentials.yaml".
+func companionTitle(doc []byte, index int) string {
+	var obj struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}
+	if err := yaml.Unmarshal(doc, &obj); err != nil || obj.Kind == "" || obj.Metadata.Name == "" {
+		return fmt.Sprintf("document-%d.yaml", index)
+	}
+	return strings.ToLower(obj.Kind) + "-" + obj.Metadata.Name + ".yaml"
+}
+
+// manifestLayers returns the layers of a resource artifact that hold its
+// manifest documents, in order, the PlatformResource first. Layers of other
+// media types are skipped. FormatV1 artifacts pushed before documents were
+// split have one such layer.
+func manifestLayers(manifest ocispec.Manifest, format ArtifactFormat) []ocispec.Descriptor {
+	var layers []ocispec.Descriptor
+	for i, layer := range manifest.Layers {
+		switch {
+		case format == FormatV1 && (i == 0 || layer.MediaType == MediaTypeResourceYAML):
+			layers = append(layers, layer)
+		case format == FormatV2 && layer.MediaType == MediaTypeResourceV2YAML:
+			layers = append([]ocispec.Descriptor{layer}, layers...)
+		case format == FormatV2 && layer.MediaType == MediaTypeCompanionV2YAML:
+			layers = append(layers, layer)
+		}
+	}
+	return layers
+}
@@ -0,0 +1,198 @@
+package oci
+
+const (
+	// ArtifactTypeResource is the OCI artifact type for platform resources.
+	ArtifactTypeResource = "application/vnd.gitops-squared.resource.v1"
+
+	// ArtifactTypeResourceV2 is the OCI artifact type for platform resources
+	// in FormatV2.
+	ArtifactTypeResourceV2 = "application/vnd.gitops-squared.resource.v2"
+
+	// ArtifactTypeCatalog is the OCI artifact type for the Flux catalog.
+	ArtifactTypeCatalog = "application/vnd.gitops-squared.catalog.v1"
+
+	// ArtifactTypeSignature is the OCI artifact type for catalog signatures.
+	ArtifactTypeSignature = "application/vnd.gitops-squared.signature.v1"
+
+	// ArtifactTypeProvenance is the OCI artifact type for catalog
+	// provenance documents.
+	ArtifactTypeProvenance = "application/vnd.gitops-squared.provenance.v1"
+
+	// ArtifactTypeTemplates is the OCI artifact type for resource templates.
+	ArtifactTypeTemplates = "application/vnd.gitops-squared.templates.v1"
+
+	// ArtifactTypeSchedules is the OCI artifact type for resource schedules.
+	ArtifactTypeSchedules = "application/vnd.gitops-squared.schedules.v1"
+
+	// ArtifactTypeFreezes is the OCI artifact type for change-freeze windows.
+	ArtifactTypeFreezes = "application/vnd.gitops-squared.freezes.v1"
+
+	// ArtifactTypeLocks is the OCI artifact type for resource locks.
+	ArtifactTypeLocks = "application/vnd.gitops-squared.locks.v1"
+
+	// ArtifactTypeNamespaces is the OCI artifact type for managed namespaces.
+	ArtifactTypeNamespaces = "application/vnd.gitops-squared.namespaces.v1"
+
+	// ArtifactTypeClusters is the OCI artifact type for cluster registrations.
+	ArtifactTypeClusters = "application/vnd.gitops-squared.clusters.v1"
+
+	// ArtifactTypeIndex is the OCI artifact type for the resource index.
+	ArtifactTypeIndex = "application/vnd.gitops-squared.index.v1"
+
+	// ArtifactTypeProposals is the OCI artifact type for change proposals.
+	ArtifactTypeProposals = "application/vnd.gitops-squared.proposals.v1"
+
+	// ArtifactTypeAPIKeys is the OCI artifact type for API keys.
+	ArtifactTypeAPIKeys = "application/vnd.gitops-squared.apikeys.v1"
+
+	// ArtifactTypeRegions is the OCI artifact type for the region catalog.
+	ArtifactTypeRegions = "application/vnd.gitops-squared.regions.v1"
+
+	// ArtifactTypeCanaryHold is the OCI artifact type for the hold on
+	// canary promotion.
+	ArtifactTypeCanaryHold = "application/vnd.gitops-squared.canary-hold.v1"
+
+	// ArtifactTypeDraft is the OCI artifact type for a proposal's draft
+	// manifest.
+	ArtifactTypeDraft = "application/vnd.gitops-squared.draft.v1"
+
+	// ArtifactTypeEvents is the OCI artifact type for recent events.
+	ArtifactTypeEvents = "application/vnd.gitops-squared.events.v1"
+
+	// ArtifactTypeJob is the OCI artifact type for background job records.
+	ArtifactTypeJob = "application/vnd.gitops-squared.job.v1"
+
+	// ArtifactTypeAttachment is the OCI artifact type for auxiliary files
+	// attached to a resource.
+	ArtifactTypeAttachment = "application/vnd.gitops-squared.attachment.v1"
+
+	// MediaTypeResourceYAML is the media type for resource YAML layers.
+	MediaTypeResourceYAML = "application/vnd.gitops-squared.manifest.v1+yaml"
+
+	// MediaTypeResourceV2YAML is the media type of the PlatformResource
+	// layer in FormatV2.
+	MediaTypeResourceV2YAML = "application/vnd.gitops-squared.resource.v2+yaml"
+
+	// MediaTypeCompanionV2YAML is the media type of companion document
+	// layers in FormatV2.
+	MediaTypeCompanionV2YAML = "application/vnd.gitops-squared.companion.v2+yaml"
+
+	// MediaTypeTemplates is the media type for the templates JSON layer.
+	MediaTypeTemplates = "application/vnd.gitops-squared.templates.v1+json"
+
+	// MediaTypeSchedules is the media type for the schedules JSON layer.
+	MediaTypeSchedules = "application/vnd.gitops-squared.schedules.v1+json"
+
+	// MediaTypeFreezes is the media type for the freeze windows JSON layer.
+	MediaTypeFreezes = "application/vnd.gitops-squared.freezes.v1+json"
+
+	// MediaTypeLocks is the media type for the resource locks JSON layer.
+	MediaTypeLocks = "application/vnd.gitops-squared.locks.v1+json"
+
+	// MediaTypeNamespaces is the media type for the namespaces JSON layer.
+	MediaTypeNamespaces = "application/vnd.gitops-squared.namespaces.v1+json"
+
+	// MediaTypeClusters is the media type for the cluster registrations JSON
+	// layer.
+	MediaTypeClusters = "application/vnd.gitops-squared.clusters.v1+json"
+
+	// MediaTypeIndex is the media type for the resource index JSON layer.
+	MediaTypeIndex = "application/vnd.gitops-squared.index.v1+json"
+
+	// MediaTypeProposals is the media type for the proposals JSON layer.
+	MediaTypeProposals = "application/vnd.gitops-squared.proposals.v1+json"
+
+	// MediaTypeAPIKeys is the media type for the API keys JSON layer.
+	MediaTypeAPIKeys = "application/vnd.gitops-squared.apikeys.v1+json"
+
+	// MediaTypeRegions is the media type for the region catalog JSON layer.
+	MediaTypeRegions = "application/vnd.gitops-squared.regions.v1+json"
+
+	// MediaTypeCanaryHold is the media type for the canary hold JSON layer.
+	MediaTypeCanaryHold = "application/vnd.gitops-squared.canary-hold.v1+json"
+
+	// MediaTypeEvents is the media type for the recent events JSON layer.
+	MediaTypeEvents = "application/vnd.gitops-squared.events.v1+json"
+
+	// MediaTypeJob is the media type for a job record JSON layer.
+	MediaTypeJob = "application/vnd.gitops-squared.job.v1+json"
+
+	// MediaTypeProvenance is the media type for the catalog provenance JSON
+	// layer.
+	MediaTypeProvenance = "application/vnd.gitops-squared.provenance.v1+json"
+
+	// MediaTypeSignature is the media type for raw signature layers.
+	MediaTypeSignature = "application/vnd.gitops-squared.signature.v1+octet-stream"
+
+	// MediaTypeFluxContent is the media type Flux expects for OCI source tarballs.
+	MediaTypeFluxContent = "application/vnd.cncf.flux.content.v1.tar+gzip"
+
+	// MediaTypeFluxConfig is the config media type Flux uses for OCI artifacts.
+	MediaTypeFluxConfig = "application/vnd.cncf.flux.config.v1+json"
+
+	// AnnotationResourceName is the annotation key for the resource name.
+	AnnotationResourceName = "io.gitops-squared.resource.name"
+
+	// AnnotationResourceNamespace is the annotation key for the resource namespace.
+	AnnotationResourceNamespace = "io.gitops-squared.resource.namespace"
+
+	// AnnotationResourceVersion is the annotation key for the resource version.
+	AnnotationResourceVersion = "io.gitops-squared.resource.version"
+
+	// AnnotationResourceSchemaVersion records the PlatformResource schema
+	// version (e.g. v1beta1) of a resource artifact.
+	AnnotationResourceSchemaVersion = "io.gitops-squared.resource.schema-version"
+
+	// AnnotationResourceCreatedAt records when a resource was first created;
+	// it is carried forward unchanged on every later version.
+	AnnotationResourceCreatedAt = "io.gitops-squared.resource.created-at"
+
+	// AnnotationResourceCostMonthly and AnnotationResourceCostCurrency record
+	// the estimated monthly cost of a resource version.
+	AnnotationResourceCostMonthly  = "io.gitops-squared.resource.cost.monthly"
+	AnnotationResourceCostCurrency = "io.gitops-squared.resource.cost.currency"
+
+	// AnnotationResourceExpiresAt records when a resource expires and is
+	// deleted automatically.
+	AnnotationResourceExpiresAt = "io.gitops-squared.resource.expires-at"
+
+	// AnnotationResourceParent records the digest of the version a resource
+	// artifact replaced, linking versions (tombstones included) into a chain.
+	AnnotationResourceParent = "io.gitops-squared.resource.parent"
+
+	// AnnotationResourceChangedBy, AnnotationResourceChangeSource and
+	// AnnotationResourceUserAgent record who pushed a resource version,
+	// through which channel (api, git-webhook, import, ...) and with which
+	// client.
+	AnnotationResourceChangedBy    = "io.gitops-squared.resource.changed-by"
+	AnnotationResourceChangeSource = "io.gitops-squared.resource.change-source"
+	AnnotationResourceUserAgent    = "io.gitops-squared.resource.user-agent"
+
+	// AnnotationResourceImages records the container images a resource
+	// version refers to, as a JSON object from parameter field to the
+	// image's reference pinned to the digest it resolved to.
+	AnnotationResourceImages = "io.gitops-squared.resource.images"
+
+	// AnnotationResourceDeleted marks a tombstone artifact.
+	AnnotationResourceDeleted = "io.gitops-squared.resource.deleted"
+
+	// AnnotationCatalogChannel records the channel a catalog was published
+	// to, such as canary.
+	AnnotationCatalogChannel = "io.gitops-squared.catalog.channel"
+
+	// AnnotationAttachmentKey records the key of an attachment artifact.
+	AnnotationAttachmentKey = "io.gitops-squared.attachment.key"
+
+	// AnnotationSignatureAlgorithm records the algorithm used for a signature artifact.
+	AnnotationSignatureAlgorithm = "io.gitops-squared.signature.algorithm"
+
+	// AnnotationSignatureKeyID records the key (version) that made a
+	// signature, so it can be verified after the key is rotated.
+	AnnotationSignatureKeyID = "io.gitops-squared.signature.key-id"
+
+	// AnnotationEncryptionKeyID and AnnotationEncryptionDataKey mark an
+	// encrypted artifact: the ID of the key-encryption key and the
+	// artifact's data key wrapped by it, base64-encoded.
+	AnnotationEncryptionKeyID   = "io.gitops-squared.encryption.key-id"
+	AnnotationEncryptionDataKey = "io.gitops-squared.encryption.data-key"
+)
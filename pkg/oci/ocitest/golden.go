@@ -0,0 +1,84 @@
+package ocitest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// UpdateEnv is the environment variable that makes AssertGolden rewrite
+// golden files instead of comparing against them:
+//
+//	UPDATE_GOLDEN=1 go test ./...
+const UpdateEnv = "UPDATE_GOLDEN"
+
+// AssertGolden compares got with testdata/<name>.golden, relative to the
+// test's working directory. When UPDATE_GOLDEN is set, the file is
+// (re)written instead.
+func AssertGolden(t testing.TB, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("creating golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("writing golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with %s=1 to create it): %v", UpdateEnv, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match golden file:\n--- got ---\n%s\n--- want ---\n%s", name, got, want)
+	}
+}
+
+// AssertGoldenTarGz unpacks a catalog or bundle tarball and compares each
+// regular file with testdata/<name>/<path>.golden.
+func AssertGoldenTarGz(t testing.TB, name string, tarGz []byte) {
+	t.Helper()
+	for path, data := range TarGzFiles(t, tarGz) {
+		AssertGolden(t, filepath.Join(name, path), data)
+	}
+}
+
+// TarGzFiles returns the regular files in a tar.gz keyed by path.
+func TarGzFiles(t testing.TB, tarGz []byte) map[string][]byte {
+	t.Helper()
+
+	gr, err := gzip.NewReader(bytes.NewReader(tarGz))
+	if err != nil {
+		t.Fatalf("opening gzip: %v", err)
+	}
+	defer gr.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading tar: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("reading %s: %v", hdr.Name, err)
+		}
+		files[hdr.Name] = data
+	}
+	return files
+}
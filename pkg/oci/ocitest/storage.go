@@ -0,0 +1,206 @@
+// Package ocitest provides an in-memory oci.Storage and golden-file helpers
+// for testing code built on the oci, api and catalog packages without a
+// running registry.
+package ocitest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/errdef"
+
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+)
+
+// DefaultRegistryHost is the host used in oci:// URLs by NewClient.
+const DefaultRegistryHost = "registry.test"
+
+// Storage is an in-memory oci.Storage. The zero value is not usable; create
+// one with NewStorage. It is safe for concurrent use.
+type Storage struct {
+	mu    sync.Mutex
+	repos map[string]*Repository
+}
+
+// NewStorage creates an empty in-memory Storage.
+func NewStorage() *Storage {
+	return &Storage{repos: make(map[string]*Repository)}
+}
+
+// NewClient returns an oci.Client backed by a fresh in-memory Storage,
+// along with the Storage so tests can inspect what was pushed.
+func NewClient(repoPrefix string) (*oci.Client, *Storage) {
+	s := NewStorage()
+	return oci.NewClientWithStorage(DefaultRegistryHost, repoPrefix, s), s
+}
+
+// Repository returns the repository at repoPath, creating it if needed.
+func (s *Storage) Repository(_ context.Context, repoPath string) (oci.Repository, error) {
+	return s.repo(repoPath), nil
+}
+
+// Repo is like Repository but returns the concrete type, for assertions.
+func (s *Storage) Repo(repoPath string) *Repository {
+	return s.repo(repoPath)
+}
+
+func (s *Storage) repo(repoPath string) *Repository {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.repos[repoPath]
+	if !ok {
+		r = newRepository()
+		s.repos[repoPath] = r
+	}
+	return r
+}
+
+// List returns the paths of all repositories that hold at least one tag,
+// sorted.
+func (s *Storage) List(_ context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var repos []string
+	for path, r := range s.repos {
+		if len(r.TagNames()) > 0 {
+			repos = append(repos, path)
+		}
+	}
+	sort.Strings(repos)
+	return repos, nil
+}
+
+// Repository is a single in-memory repository.
+type Repository struct {
+	mu    sync.Mutex
+	blobs map[digest.Digest][]byte
+	descs map[digest.Digest]ocispec.Descriptor
+	tags  map[string]ocispec.Descriptor
+}
+
+func newRepository() *Repository {
+	return &Repository{
+		blobs: make(map[digest.Digest][]byte),
+		descs: make(map[digest.Digest]ocispec.Descriptor),
+		tags:  make(map[string]ocispec.Descriptor),
+	}
+}
+
+// Fetch returns the content identified by target.
+func (r *Repository) Fetch(_ context.Context, target ocispec.Descriptor) (io.ReadCloser, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, ok := r.blobs[target.Digest]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", target.Digest, errdef.ErrNotFound)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Push stores content, verifying it against expected.
+func (r *Repository) Push(_ context.Context, expected ocispec.Descriptor, content io.Reader) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) != expected.Size || digest.FromBytes(data) != expected.Digest {
+		return fmt.Errorf("%s: content does not match descriptor", expected.Digest)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.blobs[expected.Digest]; ok {
+		return fmt.Errorf("%s: %w", expected.Digest, errdef.ErrAlreadyExists)
+	}
+	r.blobs[expected.Digest] = data
+	r.descs[expected.Digest] = expected
+	return nil
+}
+
+// Exists reports whether target is stored.
+func (r *Repository) Exists(_ context.Context, target ocispec.Descriptor) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.blobs[target.Digest]
+	return ok, nil
+}
+
+// Delete removes target and any tags pointing at it.
+func (r *Repository) Delete(_ context.Context, target ocispec.Descriptor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.blobs[target.Digest]; !ok {
+		return fmt.Errorf("%s: %w", target.Digest, errdef.ErrNotFound)
+	}
+	delete(r.blobs, target.Digest)
+	delete(r.descs, target.Digest)
+	for tag, desc := range r.tags {
+		if desc.Digest == target.Digest {
+			delete(r.tags, tag)
+		}
+	}
+	return nil
+}
+
+// Resolve resolves a tag or digest to a descriptor.
+func (r *Repository) Resolve(_ context.Context, reference string) (ocispec.Descriptor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if desc, ok := r.tags[reference]; ok {
+		return desc, nil
+	}
+	if desc, ok := r.descs[digest.Digest(reference)]; ok {
+		return desc, nil
+	}
+	return ocispec.Descriptor{}, fmt.Errorf("%s: %w", reference, errdef.ErrNotFound)
+}
+
+// Tag points reference at desc, which must already be stored.
+func (r *Repository) Tag(_ context.Context, desc ocispec.Descriptor, reference string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.blobs[desc.Digest]; !ok {
+		return fmt.Errorf("%s: %w", desc.Digest, errdef.ErrNotFound)
+	}
+	r.tags[reference] = desc
+	return nil
+}
+
+// Tags lists tags in lexical order, starting after last.
+func (r *Repository) Tags(_ context.Context, last string, fn func(tags []string) error) error {
+	var tags []string
+	for _, tag := range r.TagNames() {
+		if tag > last {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return fn(tags)
+}
+
+// TagNames returns all tags, sorted.
+func (r *Repository) TagNames() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tags := make([]string, 0, len(r.tags))
+	for tag := range r.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// BlobCount returns the number of stored blobs and manifests.
+func (r *Repository) BlobCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.blobs)
+}
@@ -0,0 +1,173 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// ProbeResult is what a storage backend reports about itself.
+type ProbeResult struct {
+	Backend      string
+	APIVersion   string
+	ReferrersAPI *bool // nil when not applicable or unknown
+}
+
+// Prober is implemented by storage backends that can check their own
+// reachability and capabilities.
+type Prober interface {
+	Probe(ctx context.Context) (ProbeResult, error)
+}
+
+// RegistryStatus describes the health of the client's storage backend.
+type RegistryStatus struct {
+	Host            string
+	Backend         string
+	Reachable       bool
+	Error           string
+	APIVersion      string
+	ReferrersAPI    *bool
+	RepositoryCount int
+	LastPush        time.Time
+	LastPull        time.Time
+}
+
+// RegistryStatus probes the storage backend and counts repositories under
+// the client's prefix. Failures are reported in the result, not returned.
+func (c *Client) RegistryStatus(ctx context.Context) RegistryStatus {
+	status := RegistryStatus{Host: c.registryHost, Reachable: true}
+	status.LastPush, status.LastPull = c.activity()
+
+	if prober, ok := c.storage.(Prober); ok {
+		result, err := prober.Probe(ctx)
+		if err != nil {
+			status.Reachable = false
+			status.Error = err.Error()
+			return status
+		}
+		status.Backend = result.Backend
+		status.APIVersion = result.APIVersion
+		status.ReferrersAPI = result.ReferrersAPI
+	}
+
+	repos, err := c.storage.List(ctx)
+	if err != nil {
+		status.Reachable = false
+		status.Error = fmt.Sprintf("listing repositories: %v", err)
+		return status
+	}
+	for _, r := range repos {
+		if strings.HasPrefix(r, c.repoPrefix+"/") {
+			status.RepositoryCount++
+		}
+	}
+	return status
+}
+
+// recordPush and recordPull note successful registry operations for
+// RegistryStatus and Stats.
+func (c *Client) recordPush() {
+	c.activityMu.Lock()
+	c.lastPush = time.Now()
+	c.pushes++
+	c.activityMu.Unlock()
+}
+
+func (c *Client) recordPull() {
+	c.activityMu.Lock()
+	c.lastPull = time.Now()
+	c.pulls++
+	c.activityMu.Unlock()
+}
+
+// ClientStats counts a client's successful registry operations since it
+// was created.
+type ClientStats struct {
+	Pushes           int64     `json:"pushes"`
+	Pulls            int64     `json:"pulls"`
+	LastPush         time.Time `json:"lastPush"`
+	LastPull         time.Time `json:"lastPull"`
+	OperationTimeout string    `json:"operationTimeout"`
+}
+
+// Stats returns the client's operation counts, without contacting the
+// registry.
+func (c *Client) Stats() ClientStats {
+	c.activityMu.Lock()
+	defer c.activityMu.Unlock()
+	return ClientStats{
+		Pushes:           c.pushes,
+		Pulls:            c.pulls,
+		LastPush:         c.lastPush,
+		LastPull:         c.lastPull,
+		OperationTimeout: c.opTimeout.String(),
+	}
+}
+
+func (c *Client) activity() (lastPush, lastPull time.Time) {
+	c.activityMu.Lock()
+	defer c.activityMu.Unlock()
+	return c.lastPush, c.lastPull
+}
+
+// Probe pings /v2/ for the distribution API version, then asks for the
+// referrers of a digest that cannot exist: registries implementing the
+// referrers API answer with an empty index, others with 404.
+func (s *RegistryStorage) Probe(ctx context.Context) (ProbeResult, error) {
+	result := ProbeResult{Backend: "registry"}
+
+	resp, err := s.get(ctx, "/v2/", catalogRepoPath)
+	if err != nil {
+		return result, fmt.Errorf("pinging registry: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("pinging registry: unexpected status %s", resp.Status)
+	}
+	result.APIVersion = resp.Header.Get("Docker-Distribution-API-Version")
+
+	probe := digest.FromString("gitops-squared referrers probe")
+	resp, err = s.get(ctx, fmt.Sprintf("/v2/%s/referrers/%s", catalogRepoPath, probe), catalogRepoPath)
+	if err == nil {
+		resp.Body.Close()
+		supported := resp.StatusCode == http.StatusOK &&
+			strings.HasPrefix(resp.Header.Get("Content-Type"), ocispec.MediaTypeImageIndex)
+		result.ReferrersAPI = &supported
+	}
+	return result, nil
+}
+
+// get issues an authenticated GET with pull scope on repoPath.
+func (s *RegistryStorage) get(ctx context.Context, path, repoPath string) (*http.Response, error) {
+	scheme := "https"
+	if s.plainHTTP {
+		scheme = "http"
+	}
+	ctx = auth.AppendRepositoryScope(ctx, registry.Reference{Registry: s.host, Repository: repoPath}, auth.ActionPull)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+s.host+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := s.client
+	if client == nil {
+		client = auth.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// Probe checks that the layout root is still accessible.
+func (s *LayoutStorage) Probe(_ context.Context) (ProbeResult, error) {
+	result := ProbeResult{Backend: "filesystem"}
+	if _, err := os.Stat(s.root); err != nil {
+		return result, fmt.Errorf("checking storage root: %w", err)
+	}
+	return result, nil
+}
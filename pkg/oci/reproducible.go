@@ -0,0 +1,91 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// reproducibleEpoch is the creation time recorded on artifacts pushed in
+// reproducible mode. oras always sets a creation annotation, so a fixed
+// value keeps it out of the digest.
+const reproducibleEpoch = "1970-01-01T00:00:00Z"
+
+// SetReproducible makes pushes of identical content produce identical
+// digests. Resource, catalog and document manifests then carry a fixed
+// creation time and no version number, creation time or parent link.
+// Tombstones keep their timestamp, which deletion grace periods depend on.
+// Call it before the client is used.
+func (c *Client) SetReproducible(reproducible bool) {
+	c.reproducible = reproducible
+}
+
+// Reproducible reports whether the client builds reproducible artifacts.
+func (c *Client) Reproducible() bool {
+	return c.reproducible
+}
+
+// createdAnnotation returns the creation time to record on a new manifest.
+func (c *Client) createdAnnotation() string {
+	if c.reproducible {
+		return reproducibleEpoch
+	}
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// stripVolatile removes the annotations that differ between pushes of the
+// same content.
+func stripVolatile(annotations map[string]string) {
+	delete(annotations, AnnotationResourceVersion)
+	delete(annotations, AnnotationResourceCreatedAt)
+	delete(annotations, AnnotationResourceParent)
+	delete(annotations, AnnotationResourceChangedBy)
+	delete(annotations, AnnotationResourceChangeSource)
+	delete(annotations, AnnotationResourceUserAgent)
+}
+
+// createdAt returns a manifest's creation time, or "" if it was pushed in
+// reproducible mode and so has none.
+func createdAt(annotations map[string]string) string {
+	if created := annotations[ocispec.AnnotationCreated]; created != reproducibleEpoch {
+		return created
+	}
+	return ""
+}
+
+// versionOf finds the version tag of a reproducible artifact, which doesn't
+// record its own. Identical content pushed more than once shares a digest,
+// so the newest matching tag wins.
+func (c *Client) versionOf(ctx context.Context, repo Repository, reference string, desc ocispec.Descriptor) (string, error) {
+	if reference != "latest" && reference != string(desc.Digest) {
+		return reference, nil
+	}
+
+	var matches []string
+	err := repo.Tags(ctx, "", func(tags []string) error {
+		for _, tag := range tags {
+			if tag == "latest" {
+				continue
+			}
+			d, err := repo.Resolve(ctx, tag)
+			if err != nil {
+				return err
+			}
+			if d.Digest == desc.Digest {
+				matches = append(matches, tag)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("listing tags: %w", err)
+	}
+	if len(matches) == 0 {
+		return "", nil
+	}
+	sort.Strings(matches)
+	return matches[len(matches)-1], nil
+}
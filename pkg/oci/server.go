@@ -0,0 +1,150 @@
+package oci
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// RegistryServer serves the pull side of the OCI distribution API from a
+// Storage, so Flux can read artifacts straight from the API server when
+// there is no external registry. Pushes go through the API, never /v2/.
+type RegistryServer struct {
+	storage Storage
+}
+
+// NewRegistryServer creates a read-only distribution API over storage.
+func NewRegistryServer(storage Storage) *RegistryServer {
+	return &RegistryServer{storage: storage}
+}
+
+// RegisterRoutes mounts the distribution API under /v2/.
+func (s *RegistryServer) RegisterRoutes(mux *http.ServeMux) {
+	mux.Handle("/v2/", s)
+}
+
+// ServeHTTP implements http.Handler. Repository names contain slashes, so
+// the path is split on the last /manifests/, /blobs/ or /tags/ segment
+// rather than with mux patterns.
+func (s *RegistryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeRegistryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "this registry is read-only; push through the gitops-squared API")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+	switch {
+	case path == "":
+		writeRegistryJSON(w, http.StatusOK, struct{}{})
+	case path == "_catalog":
+		s.serveCatalog(w, r)
+	case strings.HasSuffix(path, "/tags/list"):
+		s.serveTags(w, r, strings.TrimSuffix(path, "/tags/list"))
+	case strings.Contains(path, "/manifests/"):
+		i := strings.LastIndex(path, "/manifests/")
+		s.serveContent(w, r, path[:i], path[i+len("/manifests/"):], "MANIFEST_UNKNOWN")
+	case strings.Contains(path, "/blobs/"):
+		i := strings.LastIndex(path, "/blobs/")
+		ref := path[i+len("/blobs/"):]
+		if _, err := digest.Parse(ref); err != nil {
+			writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", err.Error())
+			return
+		}
+		s.serveContent(w, r, path[:i], ref, "BLOB_UNKNOWN")
+	default:
+		writeRegistryError(w, http.StatusNotFound, "NAME_UNKNOWN", "unknown path")
+	}
+}
+
+func (s *RegistryServer) serveCatalog(w http.ResponseWriter, r *http.Request) {
+	repos, err := s.storage.List(r.Context())
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+	if repos == nil {
+		repos = []string{}
+	}
+	writeRegistryJSON(w, http.StatusOK, map[string]any{"repositories": repos})
+}
+
+func (s *RegistryServer) serveTags(w http.ResponseWriter, r *http.Request, name string) {
+	repo, err := s.storage.Repository(r.Context(), name)
+	if err != nil {
+		writeRegistryError(w, http.StatusNotFound, "NAME_UNKNOWN", err.Error())
+		return
+	}
+
+	tags := []string{}
+	err = repo.Tags(r.Context(), r.URL.Query().Get("last"), func(page []string) error {
+		tags = append(tags, page...)
+		return nil
+	})
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+	writeRegistryJSON(w, http.StatusOK, map[string]any{"name": name, "tags": tags})
+}
+
+// serveContent resolves reference (a tag or digest) in the named repository
+// and writes the content with the headers clients use to verify it.
+func (s *RegistryServer) serveContent(w http.ResponseWriter, r *http.Request, name, reference, unknownCode string) {
+	ctx := r.Context()
+	repo, err := s.storage.Repository(ctx, name)
+	if err != nil {
+		writeRegistryError(w, http.StatusNotFound, "NAME_UNKNOWN", err.Error())
+		return
+	}
+
+	desc, err := repo.Resolve(ctx, reference)
+	if err != nil {
+		if errors.Is(err, errdef.ErrNotFound) {
+			writeRegistryError(w, http.StatusNotFound, unknownCode, fmt.Sprintf("%s not found in %s", reference, name))
+			return
+		}
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", desc.MediaType)
+	w.Header().Set("Content-Length", strconv.FormatInt(desc.Size, 10))
+	w.Header().Set("Docker-Content-Digest", desc.Digest.String())
+	w.Header().Set("ETag", `"`+desc.Digest.String()+`"`)
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	rc, err := repo.Fetch(ctx, desc)
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+	defer rc.Close()
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, rc)
+}
+
+// writeRegistryError writes an error in the distribution spec's format.
+func writeRegistryError(w http.ResponseWriter, status int, code, message string) {
+	writeRegistryJSON(w, status, map[string]any{
+		"errors": []map[string]string{{"code": code, "message": message}},
+	})
+}
+
+func writeRegistryJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
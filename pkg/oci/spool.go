@@ -0,0 +1,149 @@
+package oci
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// DefaultSpoolThreshold is how much of a tarball is buffered in memory
+// before it spills to a temporary file.
+const DefaultSpoolThreshold = 4 << 20
+
+// spoolBuffers recycles the in-memory buffers of closed spools, so steady
+// publishing doesn't allocate a fresh buffer per tarball.
+var spoolBuffers = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// Spool holds a blob, such as a catalog tarball, while it is built, pushed
+// or pulled. Content is kept in memory up to a threshold and in an unlinked
+// temporary file beyond it, and is hashed as it is written, so large
+// artifacts are streamed to and from the registry without being held in
+// memory whole. Write it once, then read it any number of times, also
+// concurrently, through Reader. A spool is released when it has been closed
+// once for NewSpool and once for every Retain.
+type Spool struct {
+	threshold int64
+	buf       *bytes.Buffer
+	file      *os.File
+	size      int64
+	hash      hash.Hash
+	refs      atomic.Int32 // references besides the creator's
+}
+
+// NewSpool creates an empty spool that spills to disk beyond threshold
+// bytes. A threshold of zero or less keeps everything in memory.
+func NewSpool(threshold int64) *Spool {
+	buf := spoolBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	return &Spool{threshold: threshold, buf: buf, hash: sha256.New()}
+}
+
+// Write appends p to the spool.
+func (s *Spool) Write(p []byte) (int, error) {
+	if s.file == nil && s.threshold > 0 && int64(s.buf.Len()+len(p)) > s.threshold {
+		if err := s.spill(); err != nil {
+			return 0, err
+		}
+	}
+
+	var n int
+	var err error
+	if s.file != nil {
+		n, err = s.file.Write(p)
+	} else {
+		n, err = s.buf.Write(p)
+	}
+	s.hash.Write(p[:n])
+	s.size += int64(n)
+	return n, err
+}
+
+// spill moves the buffered content to a temporary file. The file is
+// unlinked right away; it lives as long as the spool is open.
+func (s *Spool) spill() error {
+	f, err := os.CreateTemp("", "gitops-squared-spool-*")
+	if err != nil {
+		return fmt.Errorf("creating spool file: %w", err)
+	}
+	if err := os.Remove(f.Name()); err != nil {
+		f.Close()
+		return fmt.Errorf("unlinking spool file: %w", err)
+	}
+	if _, err := f.Write(s.buf.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("writing spool file: %w", err)
+	}
+	s.file = f
+	s.releaseBuffer()
+	return nil
+}
+
+// Size returns the number of bytes written.
+func (s *Spool) Size() int64 {
+	return s.size
+}
+
+// Digest returns the sha256 digest of the bytes written.
+func (s *Spool) Digest() digest.Digest {
+	return digest.NewDigest(digest.SHA256, s.hash)
+}
+
+// OnDisk reports whether the spool spilled to a temporary file.
+func (s *Spool) OnDisk() bool {
+	return s.file != nil
+}
+
+// Reader returns a new reader over the spool's content.
+func (s *Spool) Reader() *io.SectionReader {
+	if s.file != nil {
+		return io.NewSectionReader(s.file, 0, s.size)
+	}
+	return io.NewSectionReader(bytes.NewReader(s.buf.Bytes()), 0, s.size)
+}
+
+// Retain adds a reference to the spool, which keeps it readable until the
+// matching Close, and returns it.
+func (s *Spool) Retain() *Spool {
+	s.refs.Add(1)
+	return s
+}
+
+// Close drops a reference to the spool. The last one releases the
+// temporary file or the in-memory buffer; readers must not be used after
+// their own reference is closed.
+func (s *Spool) Close() error {
+	if s.refs.Add(-1) >= 0 {
+		return nil
+	}
+	if s.file == nil {
+		s.releaseBuffer()
+		return nil
+	}
+	return s.file.Close()
+}
+
+// releaseBuffer returns the in-memory buffer to the pool. Buffers that grew
+// past the threshold (or the default, for memory-only spools) are dropped
+// rather than kept alive.
+func (s *Spool) releaseBuffer() {
+	if s.buf == nil {
+		return
+	}
+	limit := s.threshold
+	if limit <= 0 {
+		limit = DefaultSpoolThreshold
+	}
+	if int64(s.buf.Cap()) <= limit {
+		spoolBuffers.Put(s.buf)
+	}
+	s.buf = nil
+}
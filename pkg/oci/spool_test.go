@@ -0,0 +1,38 @@
+package oci
+
+import (
+	"io"
+	"testing"
+)
+
+func TestSpoolRetain(t *testing.T) {
+	for _, threshold := range []int64{0, 1} {
+		s := NewSpool(threshold)
+		if _, err := s.Write([]byte("catalog")); err != nil {
+			t.Fatal(err)
+		}
+		if got := s.OnDisk(); got != (threshold > 0) {
+			t.Fatalf("threshold %d: OnDisk = %v", threshold, got)
+		}
+
+		r := s.Retain()
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r.Reader())
+		if err != nil || string(data) != "catalog" {
+			t.Fatalf("threshold %d: reading a retained spool = %q, %v", threshold, data, err)
+		}
+
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if s.OnDisk() {
+			if _, err := s.file.Stat(); err == nil {
+				t.Errorf("threshold %d: spool file still open after the last Close", threshold)
+			}
+		} else if s.buf != nil {
+			t.Errorf("threshold %d: spool buffer kept after the last Close", threshold)
+		}
+	}
+}
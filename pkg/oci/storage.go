@@ -0,0 +1,171 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/oci"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+// Repository is a single artifact repository: it can push, pull (fetch),
+// delete and tag content, and list its tags.
+type Repository interface {
+	content.Storage
+	content.Deleter
+	content.TagResolver
+	registry.TagLister
+}
+
+// Storage is the backend a Client stores artifacts in.
+type Storage interface {
+	// Repository returns the repository at repoPath, creating it if needed.
+	Repository(ctx context.Context, repoPath string) (Repository, error)
+
+	// List returns the paths of all repositories in the store.
+	List(ctx context.Context) ([]string, error)
+}
+
+// RegistryStorage stores artifacts in a remote OCI distribution registry.
+type RegistryStorage struct {
+	host      string
+	plainHTTP bool
+	client    remote.Client
+}
+
+// RegistryOptions configures a RegistryStorage. The zero value talks plain
+// HTTP without credentials, which suits an in-cluster Zot.
+type RegistryOptions struct {
+	// TLS uses HTTPS, as managed cloud registries require.
+	TLS bool
+
+	// Credentials, if set, authenticates every request. Tokens are
+	// refreshed before they expire.
+	Credentials CredentialProvider
+}
+
+// NewRegistryStorage creates a Storage backed by the registry at host.
+func NewRegistryStorage(host string, opts RegistryOptions) *RegistryStorage {
+	s := &RegistryStorage{host: host, plainHTTP: !opts.TLS}
+	if opts.Credentials != nil {
+		s.client = &auth.Client{
+			Client:     retry.DefaultClient,
+			Cache:      auth.NewCache(),
+			Credential: newRefreshingCredential(host, opts.Credentials).credential,
+		}
+	}
+	return s
+}
+
+// Repository returns a reference to a repository in the registry.
+func (s *RegistryStorage) Repository(_ context.Context, repoPath string) (Repository, error) {
+	ref := fmt.Sprintf("%s/%s", s.host, repoPath)
+	repo, err := remote.NewRepository(ref)
+	if err != nil {
+		return nil, fmt.Errorf("creating repository reference %s: %w", ref, err)
+	}
+	repo.PlainHTTP = s.plainHTTP
+	if s.client != nil {
+		repo.Client = s.client
+	}
+	return repo, nil
+}
+
+// List lists repositories through the registry's _catalog API.
+func (s *RegistryStorage) List(ctx context.Context) ([]string, error) {
+	reg, err := remote.NewRegistry(s.host)
+	if err != nil {
+		return nil, fmt.Errorf("creating registry: %w", err)
+	}
+	reg.PlainHTTP = s.plainHTTP
+	if s.client != nil {
+		reg.Client = s.client
+	}
+
+	var repos []string
+	err = reg.Repositories(ctx, "", func(names []string) error {
+		repos = append(repos, names...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// LayoutStorage stores each repository as an OCI image layout directory
+// under a root path, e.g. <root>/gitops-squared/catalog/index.json.
+// It needs no registry, which makes it useful for local development.
+type LayoutStorage struct {
+	root  string
+	mu    sync.Mutex
+	repos map[string]*oci.Store
+}
+
+// NewLayoutStorage creates a Storage rooted at the given directory.
+func NewLayoutStorage(root string) (*LayoutStorage, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("creating storage root: %w", err)
+	}
+	return &LayoutStorage{root: root, repos: make(map[string]*oci.Store)}, nil
+}
+
+// Root returns the directory the layouts are stored under.
+func (s *LayoutStorage) Root() string {
+	return s.root
+}
+
+// Repository opens (or creates) the OCI layout for repoPath. Stores are
+// cached so concurrent callers share one index.
+func (s *LayoutStorage) Repository(ctx context.Context, repoPath string) (Repository, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if store, ok := s.repos[repoPath]; ok {
+		return store, nil
+	}
+
+	dir := filepath.Join(s.root, filepath.FromSlash(repoPath))
+	if rel, err := filepath.Rel(s.root, dir); err != nil || strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("repository path %q escapes storage root", repoPath)
+	}
+
+	store, err := oci.NewWithContext(ctx, dir)
+	if err != nil {
+		return nil, fmt.Errorf("opening OCI layout %s: %w", dir, err)
+	}
+	s.repos[repoPath] = store
+	return store, nil
+}
+
+// List finds every OCI layout under the root.
+func (s *LayoutStorage) List(_ context.Context) ([]string, error) {
+	var repos []string
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != "oci-layout" {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		repos = append(repos, filepath.ToSlash(rel))
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking %s: %w", s.root, err)
+	}
+	return repos, nil
+}
@@ -0,0 +1,217 @@
+package oci
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VersionGenerator produces the v-prefixed version tags pushed alongside
+// "latest". Tags must be unique and sort in the order they were generated.
+type VersionGenerator interface {
+	// Next returns a new version, greater than any returned or observed
+	// before.
+	Next() string
+
+	// Observe records a version read from the registry, possibly written
+	// by another replica, so later versions sort after it even if this
+	// replica's clock is behind.
+	Observe(version string)
+}
+
+// NewVersionGenerator returns the generator for a version format:
+//
+//	unix  v<unix seconds>, bumped past the last version on collision (default)
+//	ulid  v<ULID>, millisecond time plus randomness, unique across replicas
+//	hlc   v<unix millis>-<counter>, a hybrid logical clock
+func NewVersionGenerator(format string) (VersionGenerator, error) {
+	switch format {
+	case "", "unix":
+		return &unixVersions{}, nil
+	case "ulid":
+		return &ulidVersions{}, nil
+	case "hlc":
+		return &hlcVersions{}, nil
+	default:
+		return nil, fmt.Errorf("unknown version format %q (want unix, ulid or hlc)", format)
+	}
+}
+
+// VersionFormat returns the format (unix, ulid or hlc) a version tag is
+// in, or "" if it is in none of them.
+func VersionFormat(version string) string {
+	v, ok := strings.CutPrefix(version, "v")
+	switch {
+	case !ok:
+		return ""
+	case digitsOnly(v):
+		return "unix"
+	case len(v) == 20 && v[13] == '-' && digitsOnly(v[:13]) && digitsOnly(v[14:]):
+		return "hlc"
+	case len(v) == 26 && strings.Trim(v, crockford) == "":
+		return "ulid"
+	}
+	return ""
+}
+
+func digitsOnly(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// generatorFormat returns the format of the generators NewVersionGenerator
+// returns, or "" for others.
+func generatorFormat(g VersionGenerator) string {
+	switch g.(type) {
+	case *unixVersions:
+		return "unix"
+	case *ulidVersions:
+		return "ulid"
+	case *hlcVersions:
+		return "hlc"
+	}
+	return ""
+}
+
+// unixVersions keeps the original v<unix seconds> format. Two writes in the
+// same second get consecutive numbers instead of the same tag.
+type unixVersions struct {
+	mu   sync.Mutex
+	last int64
+}
+
+func (g *unixVersions) Next() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now().Unix()
+	if now <= g.last {
+		now = g.last + 1
+	}
+	g.last = now
+	return fmt.Sprintf("v%d", now)
+}
+
+func (g *unixVersions) Observe(version string) {
+	n, err := strconv.ParseInt(strings.TrimPrefix(version, "v"), 10, 64)
+	if err != nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if n > g.last {
+		g.last = n
+	}
+}
+
+// hlcMaxCounter is the largest counter that fits the six digits of an hlc
+// tag.
+const hlcMaxCounter = 999999
+
+// hlcVersions is a hybrid logical clock: the physical part follows the
+// wall clock, and the counter orders events within a millisecond or while
+// the local clock lags a version observed from another replica. Both parts
+// are zero-padded so tags sort lexically; a counter that would outgrow its
+// six digits moves the clock on to the next millisecond instead.
+type hlcVersions struct {
+	mu      sync.Mutex
+	millis  int64
+	counter int64
+}
+
+func (g *hlcVersions) Next() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if now := time.Now().UnixMilli(); now > g.millis {
+		g.millis, g.counter = now, 0
+	} else if g.counter < hlcMaxCounter {
+		g.counter++
+	} else {
+		g.millis, g.counter = g.millis+1, 0
+	}
+	return fmt.Sprintf("v%013d-%06d", g.millis, g.counter)
+}
+
+func (g *hlcVersions) Observe(version string) {
+	ms, c, ok := strings.Cut(strings.TrimPrefix(version, "v"), "-")
+	if !ok {
+		return
+	}
+	millis, err1 := strconv.ParseInt(ms, 10, 64)
+	counter, err2 := strconv.ParseInt(c, 10, 64)
+	if err1 != nil || err2 != nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if millis > g.millis || (millis == g.millis && counter > g.counter) {
+		g.millis, g.counter = millis, counter
+	}
+}
+
+// ulidVersions generates ULIDs (https://github.com/ulid/spec): a 48-bit
+// millisecond timestamp followed by 80 random bits, Crockford base32
+// encoded. Within one millisecond the random part is incremented, so
+// versions from one replica stay strictly ordered.
+type ulidVersions struct {
+	mu     sync.Mutex
+	millis int64
+	last   [16]byte
+}
+
+func (g *ulidVersions) Next() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	if now > g.millis {
+		g.millis = now
+		var id [16]byte
+		for i := 0; i < 6; i++ {
+			id[i] = byte(now >> (40 - 8*i))
+		}
+		if _, err := rand.Read(id[6:]); err != nil {
+			panic(fmt.Sprintf("reading random bytes: %v", err))
+		}
+		g.last = id
+	} else {
+		// Same (or earlier) millisecond: increment the 80-bit random part.
+		for i := 15; i >= 6; i-- {
+			g.last[i]++
+			if g.last[i] != 0 {
+				break
+			}
+		}
+	}
+	return "v" + encodeULID(g.last)
+}
+
+// Observe is a no-op: ULIDs from different replicas are unique thanks to
+// their random part, and ordering across replicas is only as good as their
+// clocks.
+func (g *ulidVersions) Observe(string) {}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// encodeULID writes 128 bits as 26 base32 characters, most significant
+// first (the leading character only carries 3 bits).
+func encodeULID(id [16]byte) string {
+	var out [26]byte
+	bit := 130 - 5 // offset of the current 5-bit group from the LSB, padded to 130 bits
+	for i := range out {
+		var v byte
+		for b := 0; b < 5; b++ {
+			pos := bit + 4 - b // bit position from LSB
+			if pos < 128 {
+				byteIdx := 15 - pos/8
+				if id[byteIdx]>>(pos%8)&1 == 1 {
+					v |= 1 << (4 - b)
+				}
+			}
+		}
+		out[i] = crockford[v]
+		bit -= 5
+	}
+	return string(out[:])
+}
@@ -0,0 +1,47 @@
+package oci_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alfredtm/gitops-squared/pkg/oci"
+	"github.com/alfredtm/gitops-squared/pkg/oci/ocitest"
+)
+
+func TestCheckVersionFormat(t *testing.T) {
+	ctx := context.Background()
+	storage := ocitest.NewStorage()
+	client := func(format string) *oci.Client {
+		t.Helper()
+		c := oci.NewClientWithStorage("registry.example", "gitops-squared/resources", storage)
+		g, err := oci.NewVersionGenerator(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetVersionGenerator(g)
+		return c
+	}
+
+	// An empty registry takes any format.
+	for _, format := range []string{"unix", "ulid", "hlc"} {
+		if err := client(format).CheckVersionFormat(ctx); err != nil {
+			t.Errorf("%s on an empty registry: %v", format, err)
+		}
+	}
+
+	spool := oci.NewSpool(0)
+	spool.Write([]byte("catalog"))
+	defer spool.Close()
+	if _, _, err := client("hlc").PushCatalog(ctx, spool, "stable"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client("hlc").CheckVersionFormat(ctx); err != nil {
+		t.Errorf("hlc after an hlc publish: %v", err)
+	}
+	for _, format := range []string{"unix", "ulid"} {
+		if err := client(format).CheckVersionFormat(ctx); err == nil {
+			t.Errorf("%s after an hlc publish succeeded, want an error", format)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package oci
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVersionGenerators(t *testing.T) {
+	for _, format := range []string{"unix", "ulid", "hlc"} {
+		t.Run(format, func(t *testing.T) {
+			g, err := NewVersionGenerator(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A burst well within one second (and, for ulid and hlc, many
+			// versions per millisecond) must still give unique tags in
+			// lexical order.
+			const n = 5000
+			versions := make([]string, n)
+			for i := range versions {
+				versions[i] = g.Next()
+			}
+			checkOrdered(t, versions)
+			for _, v := range versions {
+				if got := VersionFormat(v); got != format {
+					t.Fatalf("VersionFormat(%s) = %q, want %s", v, got, format)
+				}
+			}
+		})
+	}
+}
+
+func TestVersionGeneratorsConcurrent(t *testing.T) {
+	for _, format := range []string{"unix", "ulid", "hlc"} {
+		t.Run(format, func(t *testing.T) {
+			g, _ := NewVersionGenerator(format)
+			var mu sync.Mutex
+			seen := map[string]bool{}
+			var wg sync.WaitGroup
+			for w := 0; w < 8; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 200; i++ {
+						v := g.Next()
+						mu.Lock()
+						if seen[v] {
+							t.Errorf("duplicate version %s", v)
+						}
+						seen[v] = true
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+// checkOrdered fails unless versions are unique and sort lexically in the
+// order they were generated.
+func checkOrdered(t *testing.T, versions []string) {
+	t.Helper()
+	for i := 1; i < len(versions); i++ {
+		if versions[i] <= versions[i-1] {
+			t.Fatalf("version %d (%s) doesn't sort after version %d (%s)", i, versions[i], i-1, versions[i-1])
+		}
+	}
+	if !sort.StringsAreSorted(versions) {
+		t.Fatal("versions are not sorted")
+	}
+}
+
+// A replica whose clock is ahead wrote a version; this replica's next one
+// must still sort after it.
+func TestVersionGeneratorsObserveClockSkew(t *testing.T) {
+	ahead := time.Now().Add(time.Hour)
+	tests := []struct {
+		format   string
+		observed string
+	}{
+		{"unix", fmt.Sprintf("v%d", ahead.Unix())},
+		{"hlc", fmt.Sprintf("v%013d-%06d", ahead.UnixMilli(), 7)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			g, _ := NewVersionGenerator(tt.format)
+			before := g.Next()
+			g.Observe(tt.observed)
+			next := g.Next()
+			checkOrdered(t, []string{before, tt.observed, next})
+
+			// Observing an older version doesn't move the clock back.
+			g.Observe(before)
+			checkOrdered(t, []string{next, g.Next()})
+
+			// Versions in other formats are ignored.
+			g.Observe("v01HZY0000000000000000000")
+			g.Observe("latest")
+			checkOrdered(t, []string{next, g.Next()})
+		})
+	}
+}
+
+func TestHLCCounterRollsOver(t *testing.T) {
+	future := time.Now().Add(time.Hour).UnixMilli()
+	g := &hlcVersions{}
+	g.Observe(fmt.Sprintf("v%013d-%06d", future, hlcMaxCounter-1))
+
+	versions := []string{g.Next(), g.Next(), g.Next()}
+	checkOrdered(t, versions)
+	want := []string{
+		fmt.Sprintf("v%013d-%06d", future, hlcMaxCounter),
+		fmt.Sprintf("v%013d-%06d", future+1, 0),
+		fmt.Sprintf("v%013d-%06d", future+1, 1),
+	}
+	for i := range want {
+		if versions[i] != want[i] {
+			t.Errorf("version %d = %s, want %s", i, versions[i], want[i])
+		}
+	}
+}
+
+func TestULIDEncoding(t *testing.T) {
+	g := &ulidVersions{}
+	v := strings.TrimPrefix(g.Next(), "v")
+	if len(v) != 26 {
+		t.Fatalf("ULID %s has %d characters, want 26", v, len(v))
+	}
+	// The first 10 characters encode the millisecond timestamp.
+	var millis int64
+	for _, c := range v[:10] {
+		millis = millis<<5 | int64(strings.IndexRune(crockford, c))
+	}
+	if d := time.Since(time.UnixMilli(millis)); d < 0 || d > time.Minute {
+		t.Errorf("ULID %s encodes %s, want about now", v, time.UnixMilli(millis))
+	}
+
+	var max [16]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	if got := encodeULID(max); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Errorf("encodeULID(max) = %s", got)
+	}
+	if got := encodeULID([16]byte{}); got != strings.Repeat("0", 26) {
+		t.Errorf("encodeULID(zero) = %s", got)
+	}
+}
+
+func TestVersionFormat(t *testing.T) {
+	tests := []struct {
+		version, want string
+	}{
+		{"v1792184025", "unix"},
+		{"v1792184025123-000042", "hlc"},
+		{"v01HZY3M6W5Q2X8N0ABCDEFGHJK", "ulid"},
+		{"1792184025", ""},
+		{"latest", ""},
+		{"v", ""},
+		{"v1792184025123-42", ""},
+		{"v01HZY3M6W5Q2X8N0ABCDEFGHJ", ""},  // 25 characters
+		{"v01HZY3M6W5Q2X8N0ABCDEFGHIL", ""}, // I and L aren't Crockford
+	}
+	for _, tt := range tests {
+		if got := VersionFormat(tt.version); got != tt.want {
+			t.Errorf("VersionFormat(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestNewVersionGeneratorUnknownFormat(t *testing.T) {
+	if _, err := NewVersionGenerator("uuid"); err == nil {
+		t.Error("NewVersionGenerator(uuid) succeeded, want an error")
+	}
+	g, err := NewVersionGenerator("")
+	if err != nil || generatorFormat(g) != "unix" {
+		t.Errorf("NewVersionGenerator(\"\") = %T, %v; want the unix format", g, err)
+	}
+	if _, err := strconv.Atoi(strings.TrimPrefix(g.Next(), "v")); err != nil {
+		t.Errorf("default version isn't unix seconds: %v", err)
+	}
+}
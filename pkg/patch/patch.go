@@ -0,0 +1,347 @@
+// Package patch applies RFC 7386 JSON Merge Patches and RFC 6902 JSON
+// Patches to JSON documents.
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MergePatch applies an RFC 7386 JSON Merge Patch to a JSON document.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("parsing document: %w", err)
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("parsing merge patch: %w", err)
+	}
+	return json.Marshal(mergeValue(target, p))
+}
+
+func mergeValue(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+		targetObj[k] = mergeValue(targetObj[k], v)
+	}
+	return targetObj
+}
+
+// Operation is a single RFC 6902 JSON Patch operation. Value is kept
+// undecoded so an operation without one can be told from a null value.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// value decodes the operation's value, which add, replace and test require.
+func (op Operation) value() (any, error) {
+	if op.Value == nil {
+		return nil, fmt.Errorf("missing value")
+	}
+	var v any
+	if err := json.Unmarshal(op.Value, &v); err != nil {
+		return nil, fmt.Errorf("parsing value: %w", err)
+	}
+	return v, nil
+}
+
+// JSONPatch applies an RFC 6902 JSON Patch to a JSON document.
+// Operations are applied in order; the first failure aborts the whole patch.
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+	var target any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("parsing document: %w", err)
+	}
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("parsing JSON patch: %w", err)
+	}
+
+	for i, op := range ops {
+		var err error
+		target, err = apply(target, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func apply(doc any, op Operation) (any, error) {
+	switch op.Op {
+	case "add":
+		value, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		return set(doc, op.Path, value, true)
+	case "replace":
+		value, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := get(doc, op.Path); err != nil {
+			return nil, err
+		}
+		return set(doc, op.Path, value, false)
+	case "remove":
+		return remove(doc, op.Path)
+	case "test":
+		value, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		v, err := get(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(v, value) {
+			return nil, fmt.Errorf("test failed")
+		}
+		return doc, nil
+	case "copy":
+		v, err := get(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		// The copy must not share maps or slices with its source, or
+		// later operations on one would show up in the other.
+		return set(doc, op.Path, deepCopy(v), true)
+	case "move":
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("cannot move %q into its own child %q", op.From, op.Path)
+		}
+		v, err := get(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		doc, err = remove(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return set(doc, op.Path, deepCopy(v), true)
+	default:
+		return nil, fmt.Errorf("unsupported op %q", op.Op)
+	}
+}
+
+// deepCopy copies a decoded JSON value, so the copy shares no maps or
+// slices with v.
+func deepCopy(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for k, e := range v {
+			c[k] = deepCopy(e)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, e := range v {
+			c[i] = deepCopy(e)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// arrayIndex parses an RFC 6901 array index: "0" or digits without a
+// leading zero. It returns -1 for anything else.
+func arrayIndex(t string) int {
+	if t == "" || (len(t) > 1 && t[0] == '0') {
+		return -1
+	}
+	for _, c := range t {
+		if c < '0' || c > '9' {
+			return -1
+		}
+	}
+	i, err := strconv.Atoi(t)
+	if err != nil {
+		return -1
+	}
+	return i
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens.
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid pointer %q", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func get(doc any, path string) (any, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	cur := doc
+	for _, t := range tokens {
+		switch node := cur.(type) {
+		case map[string]any:
+			v, ok := node[t]
+			if !ok {
+				return nil, fmt.Errorf("path %q not found", path)
+			}
+			cur = v
+		case []any:
+			i := arrayIndex(t)
+			if i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("path %q not found", path)
+			}
+			cur = node[i]
+		default:
+			return nil, fmt.Errorf("path %q not found", path)
+		}
+	}
+	return cur, nil
+}
+
+// set writes value at path. With insert, array indexes insert (and "-"
+// appends) rather than replace.
+func set(doc any, path string, value any, insert bool) (any, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return setIn(doc, tokens, value, insert, path)
+}
+
+func setIn(node any, tokens []string, value any, insert bool, path string) (any, error) {
+	t := tokens[0]
+	last := len(tokens) == 1
+
+	switch n := node.(type) {
+	case map[string]any:
+		if last {
+			n[t] = value
+			return n, nil
+		}
+		child, ok := n[t]
+		if !ok {
+			return nil, fmt.Errorf("path %q not found", path)
+		}
+		updated, err := setIn(child, tokens[1:], value, insert, path)
+		if err != nil {
+			return nil, err
+		}
+		n[t] = updated
+		return n, nil
+	case []any:
+		if last && insert && t == "-" {
+			return append(n, value), nil
+		}
+		i := arrayIndex(t)
+		if i < 0 || i > len(n) || (i == len(n) && !(last && insert)) {
+			return nil, fmt.Errorf("path %q not found", path)
+		}
+		if last {
+			if insert {
+				n = append(n[:i], append([]any{value}, n[i:]...)...)
+				return n, nil
+			}
+			n[i] = value
+			return n, nil
+		}
+		updated, err := setIn(n[i], tokens[1:], value, insert, path)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = updated
+		return n, nil
+	default:
+		return nil, fmt.Errorf("path %q not found", path)
+	}
+}
+
+func remove(doc any, path string) (any, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("cannot remove the document root")
+	}
+	return removeIn(doc, tokens, path)
+}
+
+func removeIn(node any, tokens []string, path string) (any, error) {
+	t := tokens[0]
+	last := len(tokens) == 1
+
+	switch n := node.(type) {
+	case map[string]any:
+		child, ok := n[t]
+		if !ok {
+			return nil, fmt.Errorf("path %q not found", path)
+		}
+		if last {
+			delete(n, t)
+			return n, nil
+		}
+		updated, err := removeIn(child, tokens[1:], path)
+		if err != nil {
+			return nil, err
+		}
+		n[t] = updated
+		return n, nil
+	case []any:
+		i := arrayIndex(t)
+		if i < 0 || i >= len(n) {
+			return nil, fmt.Errorf("path %q not found", path)
+		}
+		if last {
+			return append(n[:i], n[i+1:]...), nil
+		}
+		updated, err := removeIn(n[i], tokens[1:], path)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = updated
+		return n, nil
+	default:
+		return nil, fmt.Errorf("path %q not found", path)
+	}
+}
+
+// jsonEqual compares two decoded JSON values, normalising numbers.
+func jsonEqual(a, b any) bool {
+	aj, err1 := json.Marshal(a)
+	bj, err2 := json.Marshal(b)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	var an, bn any
+	json.Unmarshal(aj, &an)
+	json.Unmarshal(bj, &bn)
+	return reflect.DeepEqual(an, bn)
+}
@@ -0,0 +1,46 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// SOPSEncryptor encrypts Secret manifests with the sops CLI using age recipients.
+type SOPSEncryptor struct {
+	binary     string
+	recipients string // comma-separated age public keys
+}
+
+// NewSOPSEncryptor creates an encryptor for the given age recipients.
+// It fails if the sops binary is not on PATH.
+func NewSOPSEncryptor(recipients string) (*SOPSEncryptor, error) {
+	binary, err := exec.LookPath("sops")
+	if err != nil {
+		return nil, fmt.Errorf("locating sops: %w", err)
+	}
+	return &SOPSEncryptor{binary: binary, recipients: recipients}, nil
+}
+
+// Encrypt encrypts the data and stringData fields of a Secret manifest.
+// The result can be decrypted by Flux's kustomize-controller.
+func (e *SOPSEncryptor) Encrypt(ctx context.Context, manifest []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, e.binary,
+		"--encrypt",
+		"--age", e.recipients,
+		"--encrypted-regex", "^(data|stringData)$",
+		"--input-type", "yaml",
+		"--output-type", "yaml",
+		"/dev/stdin",
+	)
+	cmd.Stdin = bytes.NewReader(manifest)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("sops: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,274 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Store kinds.
+const (
+	// StoreVault reads a KV version 2 secrets engine of HashiCorp Vault.
+	StoreVault = "vault"
+	// StoreEnv reads the API's environment.
+	StoreEnv = "env"
+	// StoreExternalSecret leaves resolution to the External Secrets
+	// Operator in the cluster.
+	StoreExternalSecret = "externalSecret"
+)
+
+// vaultTimeout bounds a single Vault read.
+const vaultTimeout = 10 * time.Second
+
+// Store is a place secret placeholders are resolved from. Stores with the
+// same name can be scoped to different namespaces, so one placeholder
+// resolves per environment.
+//
+//	stores:
+//	  - name: db
+//	    kind: vault
+//	    namespaces: ["prod-*"]
+//	    address: https://vault.prod.internal
+//	    tokenEnv: VAULT_PROD_TOKEN
+//	    mount: secret
+//	    pathPrefix: apps/{namespace}
+//	  - name: db
+//	    kind: env
+//	    prefix: DEV_
+//	  - name: cluster
+//	    kind: externalSecret
+//	    secretStore: vault-backend
+type Store struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+
+	// Namespaces are globs limiting the store to some namespaces. Empty
+	// means all. The first store with a placeholder's name that matches the
+	// resource's namespace is used.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// PathPrefix is prepended to placeholder paths of vault and
+	// externalSecret stores. "{namespace}" is replaced by the resource's
+	// namespace.
+	PathPrefix string `json:"pathPrefix,omitempty"`
+
+	// Address, TokenEnv and VaultNamespace reach Vault. They default to
+	// VAULT_ADDR, the variable VAULT_TOKEN and VAULT_NAMESPACE. Mount is the
+	// KV engine's mount, "secret" by default.
+	Address        string `json:"address,omitempty"`
+	TokenEnv       string `json:"tokenEnv,omitempty"`
+	VaultNamespace string `json:"vaultNamespace,omitempty"`
+	Mount          string `json:"mount,omitempty"`
+
+	// Prefix is prepended to the variable names of env stores. It is
+	// required.
+	Prefix string `json:"prefix,omitempty"`
+
+	// SecretStore and StoreKind name the External Secrets Operator store of
+	// externalSecret stores. StoreKind defaults to ClusterSecretStore.
+	SecretStore string `json:"secretStore,omitempty"`
+	StoreKind   string `json:"storeKind,omitempty"`
+
+	token  string
+	client *http.Client
+}
+
+// StoresConfig lists the configured secret stores.
+type StoresConfig struct {
+	Stores []Store `json:"stores"`
+}
+
+// LoadStores reads a StoresConfig from a YAML file and applies defaults.
+func LoadStores(file string) (*StoresConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading secret stores: %w", err)
+	}
+	var cfg StoresConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing secret stores: %w", err)
+	}
+
+	for i := range cfg.Stores {
+		s := &cfg.Stores[i]
+		if s.Name == "" {
+			return nil, fmt.Errorf("secret store %d: name is required", i)
+		}
+		switch s.Kind {
+		case StoreVault:
+			if s.Address == "" {
+				s.Address = os.Getenv("VAULT_ADDR")
+			}
+			if s.Address == "" {
+				return nil, fmt.Errorf("secret store %s: address is required (or set VAULT_ADDR)", s.Name)
+			}
+			s.Address = strings.TrimSuffix(s.Address, "/")
+			if s.TokenEnv == "" {
+				s.TokenEnv = "VAULT_TOKEN"
+			}
+			if s.token = os.Getenv(s.TokenEnv); s.token == "" {
+				return nil, fmt.Errorf("secret store %s: %s is not set", s.Name, s.TokenEnv)
+			}
+			if s.VaultNamespace == "" {
+				s.VaultNamespace = os.Getenv("VAULT_NAMESPACE")
+			}
+			if s.Mount == "" {
+				s.Mount = "secret"
+			}
+			s.client = &http.Client{Timeout: vaultTimeout}
+		case StoreEnv:
+			// Without a prefix, placeholders could read any variable of the
+			// API, its own credentials included.
+			if s.Prefix == "" {
+				return nil, fmt.Errorf("secret store %s: prefix is required", s.Name)
+			}
+		case StoreExternalSecret:
+			if s.SecretStore == "" {
+				return nil, fmt.Errorf("secret store %s: secretStore is required", s.Name)
+			}
+			if s.StoreKind != "" && s.StoreKind != "SecretStore" && s.StoreKind != "ClusterSecretStore" {
+				return nil, fmt.Errorf("secret store %s: storeKind must be SecretStore or ClusterSecretStore", s.Name)
+			}
+		default:
+			return nil, fmt.Errorf("secret store %s: unknown kind %q (want vault, env or externalSecret)", s.Name, s.Kind)
+		}
+		for _, glob := range s.Namespaces {
+			if _, err := path.Match(glob, ""); err != nil {
+				return nil, fmt.Errorf("secret store %s: invalid namespace glob %q", s.Name, glob)
+			}
+		}
+	}
+	return &cfg, nil
+}
+
+// Lookup returns the store a placeholder naming store resolves from in
+// namespace. A nil config has no stores.
+func (c *StoresConfig) Lookup(name, namespace string) (*Store, bool) {
+	if c == nil {
+		return nil, false
+	}
+	for i := range c.Stores {
+		s := &c.Stores[i]
+		if s.Name == name && s.matches(namespace) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Store) matches(namespace string) bool {
+	if len(s.Namespaces) == 0 {
+		return true
+	}
+	for _, glob := range s.Namespaces {
+		if ok, _ := path.Match(glob, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolved reports whether the API resolves the store's placeholders
+// itself, rather than the External Secrets Operator.
+func (s *Store) Resolved() bool {
+	return s.Kind != StoreExternalSecret
+}
+
+// RemoteKey returns the key in the store of a placeholder path.
+func (s *Store) RemoteKey(namespace, p string) string {
+	prefix := strings.ReplaceAll(s.PathPrefix, "{namespace}", namespace)
+	if prefix == "" {
+		return p
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(p, "/")
+}
+
+// ResolveError reports a placeholder the store has no value for.
+type ResolveError struct {
+	Store string
+	Key   string
+}
+
+func (e *ResolveError) Error() string {
+	return fmt.Sprintf("secret store %s has no value for %s", e.Store, e.Key)
+}
+
+// Resolve returns the value of key in the secret at path p, for a resource
+// in namespace. Missing values fail with a *ResolveError.
+func (s *Store) Resolve(ctx context.Context, namespace, p, key string) (string, error) {
+	switch s.Kind {
+	case StoreVault:
+		return s.resolveVault(ctx, s.RemoteKey(namespace, p), key)
+	case StoreEnv:
+		name := envName(s.Prefix + p + "_" + key)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", &ResolveError{Store: s.Name, Key: name}
+		}
+		return value, nil
+	default:
+		return "", fmt.Errorf("secret store %s is resolved in the cluster", s.Name)
+	}
+}
+
+// envName turns a path into an environment variable name: uppercase, with
+// anything but letters and digits replaced by '_'.
+func envName(p string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, p)
+}
+
+// resolveVault reads a key of a KV version 2 secret.
+func (s *Store) resolveVault(ctx context.Context, p, key string) (string, error) {
+	url := fmt.Sprintf("%s/v1/%s/data/%s", s.Address, s.Mount, strings.TrimPrefix(p, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+	if s.VaultNamespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.VaultNamespace)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("reading %s from Vault: %w", p, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", &ResolveError{Store: s.Name, Key: p + "#" + key}
+	}
+	var out struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+		Errors []string `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("reading %s from Vault: decoding response: %w", p, err)
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("reading %s from Vault: %s: %s", p, resp.Status, strings.Join(out.Errors, "; "))
+	}
+	value, ok := out.Data.Data[key].(string)
+	if !ok {
+		return "", &ResolveError{Store: s.Name, Key: p + "#" + key}
+	}
+	return value, nil
+}